@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+)
+
+// normalizeAddress makes host:port entries comparable ("Web:080" == "web:80").
+func normalizeAddress(address string) string {
+	h, p, err := net.SplitHostPort(address)
+	if err != nil {
+		return strings.ToLower(address)
+	}
+	var n int
+	if _, err := fmt.Sscanf(p, "%d", &n); err == nil {
+		p = fmt.Sprint(n)
+	}
+	return net.JoinHostPort(strings.ToLower(h), p)
+}
+
+// mergeBaseline adds approved services that are not already monitored, so
+// approved-but-down services are detected too.
+func mergeBaseline(services, approved []string) []string {
+	seen := map[string]bool{}
+	for _, s := range services {
+		seen[normalizeAddress(s)] = true
+	}
+	for _, a := range approved {
+		if !seen[normalizeAddress(a)] {
+			seen[normalizeAddress(a)] = true
+			services = append(services, a)
+		}
+	}
+	return services
+}
+
+// writeDrift compares local probe results with the approved baseline:
+// responding services missing from the baseline are unapproved exposures,
+// approved services that did not respond are reported as missing.
+func writeDrift(results []ServiceCheckResult, approved []string, output io.Writer) {
+	fmt.Fprintf(output, "--- Exposure Drift (baseline: %s) ---\n\n", baselineFile)
+	approvedSet := map[string]bool{}
+	for _, a := range approved {
+		approvedSet[normalizeAddress(a)] = true
+	}
+
+	var unapproved, missing []string
+	for _, r := range results {
+		if vantageOf(r) != "local" {
+			continue
+		}
+		key := normalizeAddress(r.Address)
+		up := responding(r)
+		switch {
+		case up && !approvedSet[key]:
+			unapproved = append(unapproved, r.Address)
+		case !up && approvedSet[key]:
+			missing = append(missing, fmt.Sprintf("%s (%s)", r.Address, r.Status))
+		}
+	}
+
+	sort.Strings(unapproved)
+	sort.Strings(missing)
+	if len(unapproved) == 0 && len(missing) == 0 {
+		fmt.Fprintln(output, "No drift detected: every responding service is approved and every approved service responded.")
+		fmt.Fprintln(output, "------------------------------")
+		return
+	}
+	fmt.Fprintf(output, "Unapproved Exposures (responding, not in baseline): %d\n", len(unapproved))
+	for _, a := range unapproved {
+		fmt.Fprintf(output, "  [UNAPPROVED] %s\n", a)
+	}
+	fmt.Fprintf(output, "Approved Services Not Responding: %d\n", len(missing))
+	for _, m := range missing {
+		fmt.Fprintf(output, "  [MISSING] %s\n", m)
+	}
+	fmt.Fprintln(output, "------------------------------")
+}
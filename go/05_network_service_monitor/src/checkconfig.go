@@ -0,0 +1,384 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkConfig validates the flags and input files without probing anything
+// and writes the effective monitoring plan: every service with its probe,
+// latency limits, vantage points and escalation, followed by the schedule,
+// outputs and notification targets. It returns the exit status: 0 if the
+// configuration is usable, 1 if any problem was found.
+func checkConfig(w io.Writer) int {
+	var problems, warnings []string
+	problem := func(format string, args ...interface{}) { problems = append(problems, fmt.Sprintf(format, args...)) }
+	warning := func(format string, args ...interface{}) { warnings = append(warnings, fmt.Sprintf(format, args...)) }
+	loadErr := func(err error) { problem("%s", strings.TrimPrefix(err.Error(), "[ERROR] ")) }
+
+	if timeoutSec <= 0 {
+		problem("--timeout must be at least 1 second, got %d", timeoutSec)
+	}
+	if intervalSec < 0 || roundCount < 0 {
+		problem("--interval and --count must not be negative")
+	}
+	var cron *cronSchedule
+	if cronExpr != "" {
+		var err error
+		if cron, err = parseCron(cronExpr, reportTZ); err != nil {
+			problem("invalid --cron expression %q: %v", cronExpr, err)
+		} else if cron.next(time.Now()).IsZero() {
+			problem("--cron expression %q never fires", cronExpr)
+		}
+		if intervalSec > 0 {
+			problem("--cron and --interval both set the schedule; use one of them")
+		}
+	}
+	if jitterMax < 0 || maxChecks < 0 {
+		problem("--jitter and --max-concurrency must not be negative")
+	} else if jitterMax > 0 && !daemonMode() {
+		warning("--jitter has no effect without --interval or --cron")
+	} else if intervalSec > 0 && jitterMax >= time.Duration(intervalSec)*time.Second {
+		warning("--jitter (%s) is not shorter than --interval, so rounds can run back to back", jitterMax)
+	}
+	if roundCount > 0 && !daemonMode() {
+		warning("--count has no effect without --interval or --cron")
+	}
+	if spreadMode && cronExpr == "" && spreadWindow(time.Duration(intervalSec)*time.Second, time.Duration(timeoutSec)*time.Second) == 0 {
+		warning("--spread has no effect unless --interval is longer than --timeout")
+	}
+	if warnMs < 0 || critMs < 0 {
+		problem("--warn-ms and --crit-ms must not be negative")
+	}
+	if seriesFormat != "csv" && seriesFormat != "influx" {
+		problem("invalid --series-format %q (use csv or influx)", seriesFormat)
+	}
+	if synMode && runtime.GOOS != "linux" {
+		problem("--syn is only supported on Linux")
+	} else if synMode && os.Geteuid() != 0 {
+		warning("--syn needs a raw socket; not running as root, so the binary needs CAP_NET_RAW")
+	}
+	if len(viaHosts) > 0 {
+		if _, err := exec.LookPath("ssh"); err != nil {
+			problem("--via needs the ssh client, which is not in PATH")
+		}
+	}
+	if sloMs < 0 || sloTarget <= 0 || sloTarget > 100 || sloWindow < time.Minute {
+		problem("--slo-ms must not be negative, --slo-target must be in (0, 100] and --slo-window at least 1m")
+	} else if sloMs > 0 && !daemonMode() {
+		problem("--slo-ms needs --interval or --cron")
+	} else if sloMs > 0 && time.Duration(intervalSec)*time.Second > sloWindow/sloSlots {
+		warning("--interval is longer than a %s slice of --slo-window, so the window moves in round-sized steps", sloWindow/sloSlots)
+	}
+	if controlAddr != "" {
+		if !daemonMode() {
+			problem("--control needs --interval or --cron")
+		}
+		if os.Getenv(controlTokenEnv) == "" {
+			problem("--control needs a token in $%s", controlTokenEnv)
+		}
+		if h, _, err := net.SplitHostPort(controlAddr); err != nil {
+			problem("invalid --control address %q: %v", controlAddr, err)
+		} else if ip := net.ParseIP(h); h != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			warning("--control %s is reachable from other hosts; only the token protects it", controlAddr)
+		}
+	}
+	if notifyTmpl != "" {
+		if _, err := newAlertNotifier(nil, notifyTmpl); err != nil {
+			problem("%v", err)
+		}
+	}
+	if templateFile != "" {
+		if _, err := loadReportTemplate(templateFile); err != nil {
+			problem("%v", err)
+		}
+	}
+	if emitOpen && templateFile != "" {
+		problem("--emit-open and --template both replace the report; use one of them")
+	}
+
+	defaults := latencyThreshold{Warn: time.Duration(warnMs) * time.Millisecond, Crit: time.Duration(critMs) * time.Millisecond}
+	var services []string
+	switch {
+	case inputFile != "":
+		loaded, limits, snmp, err := loadServicesFromFile(inputFile, defaults)
+		if err != nil {
+			loadErr(err)
+			break
+		}
+		services, thresholds, snmpTargets = loaded, limits, snmp
+		if escalation, err = loadEscalation(inputFile); err != nil {
+			loadErr(err)
+		}
+	case host != "" && port != 0:
+		services = []string{net.JoinHostPort(host, strconv.Itoa(port))}
+	default:
+		problem("no services: give an input file (-i) or a host (-h) and port (-p)")
+	}
+	if scriptFile != "" {
+		loaded, err := loadProbeScripts(scriptFile)
+		if err != nil {
+			loadErr(err)
+		}
+		scripts = loaded
+	}
+	approved := map[string]bool{}
+	if baselineFile != "" {
+		loaded, _, _, err := loadServicesFromFile(baselineFile, latencyThreshold{})
+		if err != nil {
+			loadErr(err)
+		}
+		for _, a := range loaded {
+			approved[a] = true
+		}
+		services = mergeBaseline(services, loaded)
+	}
+
+	seen := map[string]bool{}
+	for _, s := range services {
+		if seen[s] {
+			warning("service %s is listed more than once and will be probed each time", s)
+			continue
+		}
+		seen[s] = true
+		if err := validateAddress(s); err != nil {
+			problem("service %s: %v", s, err)
+		}
+		if t, ok := thresholds[s]; ok && t.Warn > 0 && t.Crit > 0 && t.Warn > t.Crit {
+			warning("service %s: warn= (%s) is above crit= (%s), so it never applies", s, t.Warn, t.Crit)
+		}
+		if snmpTargets[s] != nil && scripts[s] != nil {
+			warning("service %s: probe script is ignored for an SNMP service", s)
+		}
+	}
+	for address := range scripts {
+		if !seen[address] {
+			warning("probe script for %s matches no monitored service", address)
+		}
+	}
+	if warnMs > 0 && critMs > 0 && warnMs > critMs {
+		warning("--warn-ms (%d) is above --crit-ms (%d), so it never applies", warnMs, critMs)
+	}
+
+	fmt.Fprintln(w, "--- Network Service Monitor Configuration Check ---")
+	fmt.Fprintln(w)
+	probes := 0
+	printed := map[string]bool{}
+	for _, s := range services {
+		vantages := []string{"local"}
+		if snmpTargets[s] == nil {
+			vantages = append(vantages, viaHosts...)
+		}
+		probes += len(vantages)
+		if printed[s] {
+			continue
+		}
+		printed[s] = true
+		fmt.Fprintf(w, "Service: %s\n", s)
+		if baselineFile != "" && approved[s] {
+			fmt.Fprintln(w, "  Baseline: approved")
+		}
+		fmt.Fprintf(w, "  Probe: %s\n", describeProbe(s))
+		limits, ok := thresholds[s]
+		if !ok {
+			limits = defaults
+		}
+		fmt.Fprintf(w, "  Latency Limits: %s\n", describeLimits(limits))
+		fmt.Fprintf(w, "  Vantage Points: %s\n", strings.Join(vantages, "; "))
+		if escalation != nil {
+			name, ok := escalation.assigned[s]
+			if !ok {
+				name = "default"
+			}
+			if steps := escalation.policies[name]; len(steps) > 0 {
+				fmt.Fprintf(w, "  Escalation: %s\n", name)
+				for _, step := range steps {
+					fmt.Fprintf(w, "    Level %d: %s\n", step.Level, describeStep(step))
+				}
+			}
+		}
+		fmt.Fprintln(w, "------------------------------")
+	}
+
+	schedule := "single pass"
+	every := fmt.Sprintf("every %s", time.Duration(intervalSec)*time.Second)
+	if cron != nil {
+		every = fmt.Sprintf("cron %q (next at %s)", cronExpr, stamp(cron.next(time.Now())))
+	}
+	switch {
+	case daemonMode() && roundCount > 0:
+		schedule = fmt.Sprintf("%s, %d round(s)", every, roundCount)
+	case daemonMode():
+		schedule = every + " until interrupted"
+	}
+	if window := spreadWindow(time.Duration(intervalSec)*time.Second, time.Duration(timeoutSec)*time.Second); spreadMode && window > 0 {
+		schedule += fmt.Sprintf(", probes spread over %s", window)
+	} else if spreadMode && cron != nil {
+		schedule += ", probes spread until the next tick"
+	}
+	if jitterMax > 0 && daemonMode() {
+		schedule += fmt.Sprintf(", start delayed by up to %s", jitterMax)
+	}
+	if maxChecks > 0 {
+		schedule += fmt.Sprintf(", at most %d check(s) at once", maxChecks)
+	}
+	fmt.Fprintf(w, "Schedule: %s, %d probe(s) per round, timeout %s\n", schedule, probes, time.Duration(timeoutSec)*time.Second)
+	if noDNSCache {
+		fmt.Fprintln(w, "DNS: resolved inside every probe")
+	} else {
+		fmt.Fprintln(w, "DNS: resolved once per round, cached for the record TTL")
+	}
+	report := outputFile
+	if report == "" || report == "-" {
+		report = "stdout"
+	}
+	if templateFile != "" {
+		report += " (rendered with " + templateFile + ")"
+	}
+	if emitOpen {
+		report += " (open services as a host:port list)"
+	}
+	fmt.Fprintf(w, "Report: %s\n", report)
+	if seriesFile != "" {
+		fmt.Fprintf(w, "Time Series: %s (%s)\n", seriesFile, seriesFormat)
+	}
+	if eventsFile != "" {
+		fmt.Fprintf(w, "Event Log: %s\n", eventsFile)
+	}
+	if len(notifyTargets) == 0 {
+		fmt.Fprintln(w, "Status Change Alerts: none")
+	}
+	for _, t := range notifyTargets {
+		fmt.Fprintf(w, "Status Change Alerts: %s\n", describeTarget(t))
+	}
+	if notifyTmpl != "" {
+		fmt.Fprintf(w, "Notify Template: %s\n", notifyTmpl)
+	}
+	if sloMs > 0 {
+		fmt.Fprintf(w, "Latency SLO: %g%% of probes within %dms over %s\n", sloTarget, sloMs, sloWindow)
+	}
+	if controlAddr != "" {
+		fmt.Fprintf(w, "Control API: %s (token from $%s)\n", controlAddr, controlTokenEnv)
+	}
+
+	for _, msg := range warnings {
+		fmt.Fprintf(w, "Warning: %s\n", msg)
+	}
+	for _, msg := range problems {
+		fmt.Fprintf(w, "Problem: %s\n", msg)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "\nConfiguration check FAILED: %d problem(s), %d warning(s).\n", len(problems), len(warnings))
+		return 1
+	}
+	fmt.Fprintf(w, "\nConfiguration OK: %d service(s), %d warning(s).\n", len(printed), len(warnings))
+	return 0
+}
+
+// validateAddress checks that a service is a host:port the probes can use.
+// The port may be a number or a service name from /etc/services.
+func validateAddress(address string) error {
+	hostname, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("expected host:port: %w", err)
+	}
+	if n, err := strconv.Atoi(port); err == nil {
+		if n < 1 || n > 65535 {
+			return fmt.Errorf("port %d out of range (1-65535)", n)
+		}
+	} else if _, err := net.LookupPort("tcp", port); err != nil {
+		return fmt.Errorf("unknown port %q", port)
+	}
+	if net.ParseIP(hostname) != nil {
+		return nil
+	}
+	if hostname == "" || len(hostname) > 253 {
+		return fmt.Errorf("invalid hostname %q", hostname)
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(hostname, "."), ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("invalid hostname %q", hostname)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return fmt.Errorf("invalid hostname %q", hostname)
+			}
+		}
+	}
+	return nil
+}
+
+// describeProbe summarizes how a service is probed, leaving out SNMP
+// communities and passphrases.
+func describeProbe(address string) string {
+	if t := snmpTargets[address]; t != nil {
+		if t.Version != "3" {
+			return "SNMP v2c GET sysDescr/sysUpTime (UDP)"
+		}
+		level := "noAuthNoPriv"
+		switch {
+		case t.PrivProto != "":
+			level = fmt.Sprintf("authPriv (%s, %s)", t.AuthProto, t.PrivProto)
+		case t.AuthProto != "":
+			level = fmt.Sprintf("authNoPriv (%s)", t.AuthProto)
+		}
+		return fmt.Sprintf("SNMP v3 GET sysDescr/sysUpTime (UDP), user %s, %s", t.User, level)
+	}
+	if steps, ok := scripts[address]; ok {
+		names := make([]string, len(steps))
+		for i, s := range steps {
+			names[i] = s.String()
+		}
+		return fmt.Sprintf("script of %d step(s): %s", len(steps), strings.Join(names, ", "))
+	}
+	if synMode {
+		return "TCP SYN (half-open)"
+	}
+	return "TCP connect"
+}
+
+func describeLimits(t latencyThreshold) string {
+	var parts []string
+	if t.Warn > 0 {
+		parts = append(parts, "warn above "+t.Warn.String())
+	}
+	if t.Crit > 0 {
+		parts = append(parts, "crit above "+t.Crit.String())
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeStep(s *escalationStep) string {
+	when := fmt.Sprintf("after %d failed round(s)", s.AfterRounds)
+	if s.AfterRounds == 0 {
+		when = "after " + s.AfterTime.String() + " down"
+	}
+	targets := make([]string, len(s.Targets))
+	for i, t := range s.Targets {
+		targets[i] = describeTarget(t)
+	}
+	return when + " -> " + strings.Join(targets, "; ")
+}
+
+// describeTarget names a notify target without the secrets carried by
+// webhook paths and SMTP credentials.
+func describeTarget(target string) string {
+	kind, u, err := parseNotifyTarget(target)
+	if err != nil {
+		return target
+	}
+	if kind == "smtp" {
+		return fmt.Sprintf("smtp via %s from %s to %s", u.Host, u.Query().Get("from"), u.Query().Get("to"))
+	}
+	return fmt.Sprintf("%s %s://%s/...", kind, u.Scheme, u.Host)
+}
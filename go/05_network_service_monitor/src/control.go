@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// controlTokenEnv names the environment variable holding the bearer token
+// every control API request must present.
+const controlTokenEnv = "CONTROL_TOKEN"
+
+// controlCommand asks the monitor loop, between rounds, either to probe now
+// or to reload its configuration. The loop answers on done.
+type controlCommand struct {
+	reload   bool
+	services []string // Services to probe; nil probes all of them
+	done     chan controlReply
+}
+
+// controlReply is the loop's answer: the results of a triggered round, or
+// the service count after a reload.
+type controlReply struct {
+	Round    int           `json:"round,omitempty"`
+	Services int           `json:"services,omitempty"`
+	Results  []stateRecord `json:"results,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// stateRecord is the last known result of a service from one vantage point.
+type stateRecord struct {
+	Service    string    `json:"service"`
+	Vantage    string    `json:"vantage"`
+	Status     string    `json:"status"`
+	Checked    time.Time `json:"checked"`
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"` // See errclass.go
+	LatencyMs  float64   `json:"latency_ms,omitempty"`
+	Note       string    `json:"latency_note,omitempty"` // Which latency threshold was exceeded
+	Script     string    `json:"script,omitempty"`
+	SNMP       string    `json:"snmp,omitempty"`
+}
+
+func newStateRecord(ts time.Time, r ServiceCheckResult) stateRecord {
+	rec := stateRecord{Service: r.Address, Vantage: vantageOf(r), Status: r.Status, Checked: ts.In(reportTZ), Error: errorText(r), ErrorClass: classifyError(r.Error),
+		Note: r.LatencyNote, Script: r.Script, SNMP: r.SNMP}
+	if r.Latency > 0 {
+		rec.LatencyMs = float64(r.Latency.Microseconds()) / 1000
+	}
+	return rec
+}
+
+// monitorState is what GET /state returns. The loop updates it after every
+// round; handlers only read a copy.
+type monitorState struct {
+	mu        sync.Mutex
+	Round     int           `json:"round"`
+	LastRound time.Time     `json:"last_round"`
+	NextRound time.Time     `json:"next_round"`
+	Services  []string      `json:"services"`
+	Results   []stateRecord `json:"results"`
+	byKey     map[string]int
+}
+
+// record stores the results of a round, replacing earlier results of the
+// same service and vantage so a partial round keeps the others.
+func (s *monitorState) record(round int, ts time.Time, results []ServiceCheckResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Round, s.LastRound = round, ts.In(reportTZ)
+	if s.byKey == nil {
+		s.byKey = map[string]int{}
+	}
+	for _, r := range results {
+		key := r.Address + " via " + vantageOf(r)
+		if i, ok := s.byKey[key]; ok {
+			s.Results[i] = newStateRecord(ts, r)
+			continue
+		}
+		s.byKey[key] = len(s.Results)
+		s.Results = append(s.Results, newStateRecord(ts, r))
+	}
+}
+
+// schedule records the monitored services and when the next round is due.
+func (s *monitorState) schedule(services []string, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Services, s.NextRound = append([]string(nil), services...), next.In(reportTZ)
+}
+
+// records returns a copy of the last result of every service.
+func (s *monitorState) records() []stateRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]stateRecord(nil), s.Results...)
+}
+
+func (s *monitorState) marshal() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// controlServer is the --control API. Every request needs
+// "Authorization: Bearer <token>":
+//
+//	GET  /state                    last result of every service, as JSON
+//	POST /probe[?service=host:port] probe all services (or one) now and return the results
+//	POST /reload                   re-read the services, script and baseline files
+type controlServer struct {
+	token    string
+	commands chan controlCommand
+	state    *monitorState
+	server   *http.Server
+}
+
+// startControl listens on addr and serves the control API until ctx ends.
+func startControl(ctx context.Context, addr, token string, state *monitorState) (*controlServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("control API: %w", err)
+	}
+	c := &controlServer{token: token, commands: make(chan controlCommand), state: state}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", c.handleState)
+	mux.HandleFunc("/probe", c.handleProbe)
+	mux.HandleFunc("/reload", c.handleReload)
+	c.server = &http.Server{Handler: c.authorize(mux), ReadHeaderTimeout: 10 * time.Second}
+	go c.server.Serve(ln)
+	go func() {
+		<-ctx.Done()
+		c.server.Close()
+	}()
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Control API listening on %s\n", ln.Addr())
+	}
+	return c, nil
+}
+
+func (c *controlServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(c.token)) != 1 {
+			debugf("Control API: rejected %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, controlReply{Error: "missing or wrong token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *controlServer) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, controlReply{Error: "use GET"})
+		return
+	}
+	data, err := c.state.marshal()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, controlReply{Error: err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+func (c *controlServer) handleProbe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, controlReply{Error: "use POST"})
+		return
+	}
+	cmd := controlCommand{done: make(chan controlReply, 1)}
+	if svc := r.URL.Query().Get("service"); svc != "" {
+		cmd.services = []string{svc}
+	}
+	c.run(w, r, cmd)
+}
+
+func (c *controlServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, controlReply{Error: "use POST"})
+		return
+	}
+	c.run(w, r, controlCommand{reload: true, done: make(chan controlReply, 1)})
+}
+
+// run hands cmd to the monitor loop, which takes it once the round in
+// progress is over, and writes the reply.
+func (c *controlServer) run(w http.ResponseWriter, r *http.Request, cmd controlCommand) {
+	select {
+	case c.commands <- cmd:
+	case <-r.Context().Done():
+		return
+	}
+	select {
+	case reply := <-cmd.done:
+		status := http.StatusOK
+		if reply.Error != "" {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, reply)
+	case <-r.Context().Done():
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// knownService returns the monitored service that address names, compared
+// the way the baseline compares addresses.
+func knownService(services []string, address string) (string, error) {
+	want := normalizeAddress(address)
+	for _, s := range services {
+		if normalizeAddress(s) == want {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a monitored service", address)
+}
+
+// monitorConfig is everything read from the files named on the command
+// line, loaded at start and again on reload.
+type monitorConfig struct {
+	services    []string
+	approved    []string
+	scripts     map[string][]probeStep
+	thresholds  map[string]latencyThreshold
+	snmpTargets map[string]*snmpTarget
+	escalation  *escalator
+}
+
+// loadMonitorConfig reads the probe scripts, the services file (or the
+// -h/-p service) and the baseline. Errors carry the usual "[ERROR]" prefix.
+func loadMonitorConfig() (*monitorConfig, error) {
+	cfg := &monitorConfig{}
+	var err error
+	if scriptFile != "" {
+		if cfg.scripts, err = loadProbeScripts(scriptFile); err != nil {
+			return nil, err
+		}
+	}
+	if inputFile != "" {
+		defaults := latencyThreshold{Warn: time.Duration(warnMs) * time.Millisecond, Crit: time.Duration(critMs) * time.Millisecond}
+		if cfg.services, cfg.thresholds, cfg.snmpTargets, err = loadServicesFromFile(inputFile, defaults); err != nil {
+			return nil, err
+		}
+		if cfg.escalation, err = loadEscalation(inputFile); err != nil {
+			return nil, err
+		}
+	} else {
+		cfg.services = []string{net.JoinHostPort(host, fmt.Sprintf("%d", port))}
+	}
+	if baselineFile != "" {
+		if cfg.approved, _, _, err = loadServicesFromFile(baselineFile, latencyThreshold{}); err != nil {
+			return nil, err
+		}
+		cfg.services = mergeBaseline(cfg.services, cfg.approved)
+	}
+	return cfg, nil
+}
+
+// apply makes cfg the configuration probes run with. It is only called
+// between rounds, while no probe reads these globals.
+func (cfg *monitorConfig) apply() {
+	scripts, thresholds, snmpTargets, escalation = cfg.scripts, cfg.thresholds, cfg.snmpTargets, cfg.escalation
+}
+
+// reloadConfig re-reads the configuration between rounds and makes it the
+// running one; if anything fails to load, the old one stays. Open
+// escalations start over, as the policies are loaded afresh.
+func reloadConfig(services, approved *[]string, state *monitorState, next time.Time) error {
+	cfg, err := loadMonitorConfig()
+	if err != nil {
+		return err
+	}
+	cfg.apply()
+	*services, *approved = cfg.services, cfg.approved
+	state.schedule(cfg.services, next)
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Configuration reloaded: monitoring %d service(s).\n", len(cfg.services))
+	}
+	return nil
+}
+
+// errorReply turns a loader error into a control API reply.
+func errorReply(err error) controlReply {
+	return controlReply{Error: strings.TrimPrefix(err.Error(), "[ERROR] ")}
+}
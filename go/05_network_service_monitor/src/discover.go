@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listeningSocket is a local socket in the LISTEN (TCP) or unconnected (UDP) state.
+type listeningSocket struct {
+	Proto   string
+	IP      net.IP
+	Port    int
+	Inode   string
+	Process string
+}
+
+// discoverListeningSockets parses /proc/net/{tcp,tcp6,udp,udp6} (Linux).
+func discoverListeningSockets() ([]listeningSocket, error) {
+	var sockets []listeningSocket
+	found := false
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		entries, err := parseProcNet("/proc/net/"+proto, strings.TrimSuffix(proto, "6"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		sockets = append(sockets, entries...)
+	}
+	if !found {
+		return nil, fmt.Errorf("--discover-local requires /proc/net (Linux)")
+	}
+	names := socketProcesses()
+	for i := range sockets {
+		sockets[i].Process = names[sockets[i].Inode]
+	}
+	sort.Slice(sockets, func(i, j int) bool {
+		if sockets[i].Proto != sockets[j].Proto {
+			return sockets[i].Proto < sockets[j].Proto
+		}
+		if sockets[i].Port != sockets[j].Port {
+			return sockets[i].Port < sockets[j].Port
+		}
+		return sockets[i].IP.String() < sockets[j].IP.String()
+	})
+	return sockets, nil
+}
+
+func parseProcNet(path, proto string) ([]listeningSocket, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// TCP_LISTEN is 0A; unconnected UDP sockets report TCP_CLOSE (07).
+	wantState := "0A"
+	if proto == "udp" {
+		wantState = "07"
+	}
+	var sockets []listeningSocket
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != wantState {
+			continue
+		}
+		ip, port, err := parseProcAddress(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		sockets = append(sockets, listeningSocket{Proto: proto, IP: ip, Port: port, Inode: fields[9]})
+	}
+	return sockets, scanner.Err()
+}
+
+// parseProcAddress decodes "0100007F:0016": the address is stored as
+// host-order (little-endian) 32-bit words, the port as big-endian hex.
+func parseProcAddress(s string) (net.IP, int, error) {
+	addrHex, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, 0, fmt.Errorf("malformed address %q", s)
+	}
+	raw, err := hex.DecodeString(addrHex)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, fmt.Errorf("malformed address %q", s)
+	}
+	for i := 0; i < len(raw); i += 4 {
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed port in %q", s)
+	}
+	return net.IP(raw), int(port), nil
+}
+
+// socketProcesses maps socket inodes to process names by reading
+// /proc/<pid>/fd links. Processes of other users are skipped unless run as root.
+func socketProcesses() map[string]string {
+	names := map[string]string{}
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		target, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+		if _, ok := names[inode]; ok {
+			continue
+		}
+		pidDir := filepath.Dir(filepath.Dir(fd))
+		if comm, err := os.ReadFile(filepath.Join(pidDir, "comm")); err == nil {
+			names[inode] = strings.TrimSpace(string(comm))
+		}
+	}
+	return names
+}
+
+// writeDiscoveredServices emits a services input file. Wildcard listeners are
+// probed via loopback; UDP sockets are listed as comments because probes are
+// TCP-only.
+func writeDiscoveredServices(sockets []listeningSocket, output io.Writer) {
+	hostname, _ := os.Hostname()
+	fmt.Fprintf(output, "# Services discovered on %s at %s by --discover-local\n", hostname, stamp(time.Now()))
+	seen := map[string]bool{}
+	for _, s := range sockets {
+		ip := s.IP
+		if ip.IsUnspecified() {
+			if ip.To4() != nil {
+				ip = net.IPv4(127, 0, 0, 1)
+			} else {
+				ip = net.IPv6loopback
+			}
+		}
+		address := net.JoinHostPort(ip.String(), strconv.Itoa(s.Port))
+		key := s.Proto + " " + address
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		comment := ""
+		if s.Process != "" {
+			comment = "  # " + s.Process
+		}
+		if s.Proto == "udp" {
+			fmt.Fprintf(output, "# udp %s%s\n", address, strings.Replace(comment, "  #", " -", 1))
+			continue
+		}
+		fmt.Fprintf(output, "%s%s\n", address, comment)
+	}
+}
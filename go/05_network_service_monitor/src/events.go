@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// stateEvent is one line of the --events log: a service (as seen from one
+// vantage point) moving from one status to another.
+type stateEvent struct {
+	Time       time.Time `json:"time"`
+	Service    string    `json:"service"`
+	Vantage    string    `json:"vantage"`
+	OldState   string    `json:"old_state,omitempty"` // Absent the first time a service is seen
+	NewState   string    `json:"new_state"`
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"` // See errclass.go
+	LatencyMs  float64   `json:"latency_ms,omitempty"`
+}
+
+// eventLog appends state transitions to an NDJSON file. It is never
+// rewritten: the last known state of each service is recovered from the
+// existing lines on open, so separate runs (e.g. from cron) continue one
+// timeline and only real transitions are added.
+type eventLog struct {
+	file *os.File
+	last map[string]string // Key: service via vantage
+}
+
+func openEventLog(path string) (*eventLog, error) {
+	l := &eventLog{last: map[string]string{}}
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		bad := 0
+		for scanner.Scan() {
+			var e stateEvent
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Service == "" {
+				bad++
+				continue
+			}
+			l.last[e.Service+" via "+e.Vantage] = e.NewState
+		}
+		err := scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event log %s: %w", path, err)
+		}
+		if bad > 0 {
+			warnf("Event log %s: skipped %d unreadable line(s) while restoring service states.", path, bad)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open event log %s: %w", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log %s: %w", path, err)
+	}
+	l.file = file
+	return l, nil
+}
+
+// write appends an event for every result whose status differs from the
+// last one recorded for it, stamped with the round's time, and syncs the
+// file so the timeline survives a crash.
+func (l *eventLog) write(ts time.Time, results []ServiceCheckResult) error {
+	var buf []byte
+	for _, r := range results {
+		key := r.Address + " via " + vantageOf(r)
+		prev, seen := l.last[key]
+		if seen && prev == r.Status {
+			continue
+		}
+		l.last[key] = r.Status
+		e := stateEvent{Time: ts.In(reportTZ), Service: r.Address, Vantage: vantageOf(r), OldState: prev, NewState: r.Status, Error: errorText(r), ErrorClass: classifyError(r.Error)}
+		if r.Latency > 0 {
+			e.LatencyMs = float64(r.Latency.Microseconds()) / 1000
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	if _, err := l.file.Write(buf); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+func (l *eventLog) close() {
+	l.file.Close()
+}
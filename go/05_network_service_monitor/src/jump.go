@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// viaList collects repeated --via flags; each entry is one vantage point,
+// written as a comma-separated chain of SSH jump hosts (user@bastion[,user@next]).
+type viaList []string
+
+func (v *viaList) String() string { return strings.Join(*v, " ") }
+
+func (v *viaList) Set(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("empty jump host")
+	}
+	*v = append(*v, value)
+	return nil
+}
+
+// checkServiceVia tests whether address is reachable from the last host of
+// the jump chain. It uses the system OpenSSH client's stdio forwarding
+// (ssh -W), which asks the jump host to open a direct-tcpip channel to the
+// target; the tools are standard-library only, so there is no Go SSH client
+// to open the channel directly. No shell or tools are needed on the jump
+// host and ~/.ssh/config (keys, ports, host keys) applies as usual.
+//
+// ssh gets no input, so once the channel is open it forwards an immediate
+// EOF and exits 0 when the target closes its side. If the jump host cannot
+// connect, ssh exits 255; its error message (printed at the default log
+// level) then tells a refused connect from one that timed out. Still running
+// at the deadline is no verdict: a login that stalls looks the same as a
+// target that ignores the EOF.
+func checkServiceVia(parent context.Context, address, via string, timeout time.Duration) ServiceCheckResult {
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Checking service: %s via %s\n", address, via)
+	}
+	result := ServiceCheckResult{Address: address, Vantage: via}
+	hops := strings.Split(via, ",")
+
+	args := []string{"-o", "BatchMode=yes", "-o", fmt.Sprintf("ConnectTimeout=%d", int(timeout.Seconds()+0.5))}
+	if len(hops) > 1 {
+		args = append(args, "-J", strings.Join(hops[:len(hops)-1], ","))
+	}
+	args = append(args, "-W", address, hops[len(hops)-1])
+	debugf("running ssh %s", strings.Join(args, " "))
+
+	// Each hop's login plus the final connect gets one timeout.
+	limit := timeout * time.Duration(len(hops)+1)
+	ctx, cancel := context.WithTimeout(parent, limit)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr        // stdin and stdout stay on the null device
+	cmd.WaitDelay = time.Second // A -J proxy child may outlive a killed ssh
+	err := cmd.Run()
+
+	lastError := ""
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lastError = line
+		}
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.Status = "UP"
+	case parent.Err() != nil:
+		result.Status, result.Error = "UNKNOWN", fmt.Errorf("interrupted")
+	case ctx.Err() != nil:
+		result.Status, result.Error = "UNKNOWN", fmt.Errorf("no verdict from jump host within %s (ssh still running)", limit)
+	case !errors.As(err, &exitErr):
+		result.Status, result.Error = "UNKNOWN", fmt.Errorf("failed to run ssh: %w", err)
+	case strings.Contains(stderr.String(), "open failed:"):
+		_, msg, _ := strings.Cut(stderr.String(), "open failed:")
+		msg, _, _ = strings.Cut(msg, "\n")
+		result.Error = fmt.Errorf("%s", strings.TrimSpace(msg))
+		result.Status = failureStatus(result.Error)
+	case lastError != "":
+		result.Status, result.Error = "UNKNOWN", fmt.Errorf("jump host failed (ssh exit status %d): %s", exitErr.ExitCode(), lastError)
+	default:
+		result.Status, result.Error = "UNKNOWN", fmt.Errorf("jump host failed (ssh exit status %d) without an error message", exitErr.ExitCode())
+	}
+	return result
+}
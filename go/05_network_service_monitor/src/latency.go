@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// latencyThreshold holds the warn/crit limits for one service; zero disables a limit.
+type latencyThreshold struct {
+	Warn time.Duration
+	Crit time.Duration
+}
+
+// parseThresholdOptions reads "warn=<ms>" and "crit=<ms>" options that may
+// follow host:port on a services file line. Values are milliseconds, with an
+// optional "ms" suffix.
+func parseThresholdOptions(fields []string, defaults latencyThreshold) (latencyThreshold, error) {
+	t := defaults
+	for _, f := range fields {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			return t, fmt.Errorf("expected key=value, got %q", f)
+		}
+		ms, err := strconv.Atoi(strings.TrimSuffix(value, "ms"))
+		if err != nil || ms < 0 {
+			return t, fmt.Errorf("invalid %s value %q (milliseconds)", key, value)
+		}
+		switch strings.ToLower(key) {
+		case "warn":
+			t.Warn = time.Duration(ms) * time.Millisecond
+		case "crit":
+			t.Crit = time.Duration(ms) * time.Millisecond
+		default:
+			return t, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return t, nil
+}
+
+// applyLatencyThreshold downgrades an UP result to DEGRADED when its latency
+// exceeds the service's warn or crit limit and records which limit was hit.
+func applyLatencyThreshold(result *ServiceCheckResult, t latencyThreshold) {
+	if result.Status != "UP" || result.Latency == 0 {
+		return
+	}
+	switch {
+	case t.Crit > 0 && result.Latency > t.Crit:
+		result.Status = "DEGRADED"
+		result.LatencyNote = fmt.Sprintf("critical: above %s", t.Crit)
+	case t.Warn > 0 && result.Latency > t.Warn:
+		result.Status = "DEGRADED"
+		result.LatencyNote = fmt.Sprintf("warning: above %s", t.Warn)
+	}
+}
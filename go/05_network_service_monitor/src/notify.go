@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Alert dispatch shared by the monitoring tools: one alertEvent is rendered
+// with a text/template and delivered to every configured --notify target.
+// Every tool carries an identical copy of this file (scripts/check_shared_go.py).
+//
+// Target formats:
+//
+//	https://example.com/hook                  generic webhook (JSON event + "text")
+//	slack:https://hooks.slack.com/...         Slack incoming webhook
+//	teams:https://example.webhook.office.com/... Microsoft Teams incoming webhook
+//	smtp://user@mail.example.com:587?from=a@example.com&to=b@example.com,c@example.com
+//
+// SMTP passwords may be given in the URL or via the SMTP_PASSWORD environment variable.
+
+type alertItem struct {
+	Target     string `json:"target"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"` // See errclass.go
+}
+
+type alertEvent struct {
+	Tool     string      `json:"tool"`
+	Hostname string      `json:"hostname"`
+	Time     time.Time   `json:"time"`
+	Summary  string      `json:"summary"`
+	Items    []alertItem `json:"items"`
+}
+
+const defaultAlertTemplate = `[{{.Tool}}] {{.Summary}} (from {{.Hostname}} at {{.Time.Format "2006-01-02T15:04:05Z07:00"}})
+{{range .Items}}- {{.Target}}: {{.Status}}{{if .Detail}} ({{.Detail}}){{end}}
+{{end}}`
+
+// notifyList collects repeated --notify flags.
+type notifyList []string
+
+func (n *notifyList) String() string { return strings.Join(*n, " ") }
+
+func (n *notifyList) Set(value string) error {
+	if _, _, err := parseNotifyTarget(value); err != nil {
+		return err
+	}
+	*n = append(*n, value)
+	return nil
+}
+
+// parseNotifyTarget splits a target into its kind (webhook, slack, teams,
+// smtp) and the URL to deliver to.
+func parseNotifyTarget(target string) (string, *url.URL, error) {
+	kind, raw := "webhook", target
+	for _, prefix := range []string{"webhook:", "slack:", "teams:"} {
+		if strings.HasPrefix(target, prefix) {
+			kind, raw = strings.TrimSuffix(prefix, ":"), strings.TrimPrefix(target, prefix)
+		}
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid notify target %q: %w", target, err)
+	}
+	switch {
+	case u.Scheme == "smtp" && kind == "webhook":
+		kind = "smtp"
+		if u.Query().Get("to") == "" || u.Query().Get("from") == "" {
+			return "", nil, fmt.Errorf("smtp notify target needs from= and to= parameters")
+		}
+	case u.Scheme != "http" && u.Scheme != "https":
+		return "", nil, fmt.Errorf("invalid notify target %q: expected an http(s) or smtp URL", target)
+	}
+	return kind, u, nil
+}
+
+// alertNotifier renders and delivers alert events with retries.
+type alertNotifier struct {
+	targets  []string
+	tmpl     *template.Template
+	client   *http.Client
+	attempts int
+}
+
+// newAlertNotifier prepares delivery to targets, using the template file
+// if given, or the built-in plain-text template.
+func newAlertNotifier(targets []string, templateFile string) (*alertNotifier, error) {
+	text := defaultAlertTemplate
+	if templateFile != "" {
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read notify template %s: %w", templateFile, err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("alert").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notify template: %w", err)
+	}
+	return &alertNotifier{targets: targets, tmpl: tmpl, client: &http.Client{Timeout: 15 * time.Second}, attempts: 3}, nil
+}
+
+// send delivers event to every target; failures are reported per target
+// but do not stop delivery to the others.
+func (n *alertNotifier) send(event alertEvent) []error {
+	if event.Hostname == "" {
+		event.Hostname, _ = os.Hostname()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Time = event.Time.In(reportTZ)
+	var buf bytes.Buffer
+	if err := n.tmpl.Execute(&buf, event); err != nil {
+		return []error{fmt.Errorf("failed to render alert: %w", err)}
+	}
+	message := buf.String()
+
+	var errs []error
+	for _, target := range n.targets {
+		kind, u, _ := parseNotifyTarget(target)
+		var err error
+		for attempt := 1; attempt <= n.attempts; attempt++ {
+			var retry bool
+			if kind == "smtp" {
+				err = sendSMTPAlert(u, event.Summary, message)
+				retry = err != nil
+			} else {
+				retry, err = n.post(kind, u.String(), event, message)
+			}
+			if err == nil || !retry {
+				break
+			}
+			if attempt < n.attempts {
+				time.Sleep(time.Duration(1<<(attempt-1)) * time.Second) // 1s, 2s backoff
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s notification to %s failed: %w", kind, u.Redacted(), err))
+		}
+	}
+	return errs
+}
+
+// post sends one webhook request and reports whether a failure is worth retrying.
+func (n *alertNotifier) post(kind, endpoint string, event alertEvent, message string) (bool, error) {
+	var payload interface{}
+	switch kind {
+	case "slack":
+		payload = map[string]string{"text": message}
+	case "teams":
+		payload = map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  event.Summary,
+			"title":    fmt.Sprintf("%s: %s", event.Tool, event.Summary),
+			"text":     strings.ReplaceAll(message, "\n", "\n\n"), // Teams collapses single newlines
+		}
+	default:
+		payload = struct {
+			alertEvent
+			Text string `json:"text"`
+		}{event, message}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	resp, err := n.client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return false, nil
+}
+
+// sendSMTPAlert mails message via the server in u (STARTTLS is used when offered).
+func sendSMTPAlert(u *url.URL, subject, message string) error {
+	q := u.Query()
+	from := q.Get("from")
+	to := strings.Split(q.Get("to"), ",")
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "25")
+	}
+
+	var auth smtp.Auth
+	if u.User != nil {
+		password, ok := u.User.Password()
+		if !ok {
+			password = os.Getenv("SMTP_PASSWORD")
+		}
+		auth = smtp.PlainAuth("", u.User.Username(), password, u.Hostname())
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		from, strings.Join(to, ", "), subject, time.Now().Format(time.RFC1123Z), strings.ReplaceAll(message, "\n", "\r\n"))
+	return smtp.SendMail(addr, auth, from, to, []byte(msg))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+)
+
+// A failed TCP probe is reported in the terms of a firewall audit rather
+// than as a plain DOWN: a RST means the host is reachable but nothing
+// listens (CLOSED), while silence or an ICMP unreachable means something
+// drops the traffic or the host is gone (FILTERED). DOWN remains for
+// failures that say nothing about the port, such as a name that does not
+// resolve.
+const (
+	statusClosed   = "CLOSED"
+	statusFiltered = "FILTERED"
+)
+
+// failureStatus maps a failed connection attempt to CLOSED, FILTERED or DOWN.
+// Errors relayed as text (from a jump host) are matched by message, as are
+// Windows socket errors, which the syscall constants do not match.
+func failureStatus(err error) string {
+	msg := strings.ToLower(fmt.Sprint(err))
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), strings.Contains(msg, "connection refused"),
+		strings.Contains(msg, "actively refused"):
+		return statusClosed
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH),
+		strings.Contains(msg, "no route to host"), strings.Contains(msg, "network is unreachable"),
+		strings.Contains(msg, "unreachable host"), strings.Contains(msg, "unreachable network"):
+		return statusFiltered
+	case classifyError(err) == errClassTimeout, strings.Contains(msg, "timed out"):
+		return statusFiltered
+	}
+	return "DOWN"
+}
+
+// responding reports whether a service answered on its port: it is UP or
+// DEGRADED, or only its probe script or SNMP query failed.
+func responding(r ServiceCheckResult) bool {
+	return upValue(r) == 1 || r.Status == "SCRIPT FAILED" || r.Status == "SNMP FAILED"
+}
+
+// portCounts tallies a round's results for the summary line and report
+// templates; anything not open, closed or filtered (DOWN, UNKNOWN) is Other.
+type portCounts struct {
+	Open, Closed, Filtered, Other int
+}
+
+func countPorts(results []ServiceCheckResult) portCounts {
+	var c portCounts
+	for _, r := range results {
+		switch {
+		case responding(r):
+			c.Open++
+		case r.Status == statusClosed:
+			c.Closed++
+		case r.Status == statusFiltered:
+			c.Filtered++
+		default:
+			c.Other++
+		}
+	}
+	return c
+}
+
+// writePortSummary prints one line counting open, closed and filtered
+// services.
+func writePortSummary(results []ServiceCheckResult, output io.Writer) {
+	c := countPorts(results)
+	fmt.Fprintf(output, "Summary: %d open, %d closed, %d filtered", c.Open, c.Closed, c.Filtered)
+	if c.Other > 0 {
+		fmt.Fprintf(output, ", %d other", c.Other)
+	}
+	fmt.Fprintln(output)
+}
+
+// writeOpenServices prints the services that answered locally, one host:port
+// per line in the order they were listed, for --emit-open: the input of the
+// next tool in a pipeline.
+func writeOpenServices(services []string, results []ServiceCheckResult, output io.Writer) {
+	open := map[string]bool{}
+	for _, r := range results {
+		if vantageOf(r) == "local" && responding(r) {
+			open[r.Address] = true
+		}
+	}
+	for _, s := range services {
+		if open[s] {
+			fmt.Fprintln(output, s)
+			delete(open, s) // Listed twice, printed once
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dnsDefaultTTL is how long an answer is reused when its TTL cannot be
+// learned, e.g. for names from /etc/hosts or short names completed by a
+// search domain.
+const dnsDefaultTTL = 30 * time.Second
+
+// dnsEntry is one cached lookup. Failed lookups expire immediately, so they
+// are shared by the probes of one round and retried in the next.
+type dnsEntry struct {
+	ips     []net.IP
+	err     error
+	expires time.Time
+}
+
+// dnsCache resolves the hostnames of all services once per round, in
+// parallel, and serves every probe of the round from those answers: ports on
+// one host are not looked up separately, and a name that flaps mid-round
+// cannot send its probes to different addresses. Addresses come from the
+// system resolver, so /etc/hosts and search domains apply as usual; the TTL
+// comes from a direct query to the nameservers in /etc/resolv.conf, and an
+// answer is reused across rounds until it runs out.
+type dnsCache struct {
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+	servers []string
+}
+
+func newDNSCache() *dnsCache {
+	return &dnsCache{entries: map[string]*dnsEntry{}, servers: resolvConfServers("/etc/resolv.conf")}
+}
+
+// prefetch looks up every hostname among addresses whose cached answer has
+// expired.
+func (c *dnsCache) prefetch(ctx context.Context, addresses []string, timeout time.Duration) {
+	now := time.Now()
+	hosts := map[string]bool{}
+	var stale []string
+	c.mu.Lock()
+	for _, address := range addresses {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil || net.ParseIP(host) != nil || hosts[host] {
+			continue
+		}
+		hosts[host] = true
+		if e := c.entries[host]; e == nil || !now.Before(e.expires) {
+			stale = append(stale, host)
+		}
+	}
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, host := range stale {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			e := c.resolve(ctx, host, timeout)
+			c.mu.Lock()
+			c.entries[host] = e
+			c.mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+	if verboseMode && len(hosts) > 0 {
+		fmt.Fprintf(os.Stderr, "[INFO] DNS: resolved %d hostname(s), %d still cached.\n", len(stale), len(hosts)-len(stale))
+	}
+}
+
+// resolve asks the system resolver for the addresses of host and, at the
+// same time, the nameservers for their TTL.
+func (c *dnsCache) resolve(ctx context.Context, host string, timeout time.Duration) *dnsEntry {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ttls := make(chan time.Duration, 1)
+	go func() { ttls <- c.queryTTL(ctx, host) }()
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		debugf("DNS: %s: %v", host, err)
+		return &dnsEntry{err: err, expires: time.Now()}
+	}
+	ttl := <-ttls
+	if ttl < 0 {
+		ttl = dnsDefaultTTL
+	}
+	debugf("DNS: %s -> %v (TTL %s)", host, ips, ttl)
+	return &dnsEntry{ips: ips, expires: time.Now().Add(ttl)}
+}
+
+// queryTTL returns the lowest TTL among the A (or, failing that, AAAA)
+// records of host and the CNAMEs leading to them, or -1 if no nameserver
+// gave one.
+func (c *dnsCache) queryTTL(ctx context.Context, host string) time.Duration {
+	name := strings.TrimSuffix(host, ".")
+	for _, server := range c.servers {
+		for _, qtype := range []uint16{1, 28} { // A, AAAA
+			ttl, found, err := dnsQueryTTL(ctx, server, name, qtype)
+			if err != nil {
+				debugf("DNS: TTL query for %s to %s: %v", host, server, err)
+				break
+			}
+			if found {
+				return time.Duration(ttl) * time.Second
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return -1
+}
+
+// lookup returns the addresses of host: an IP literal as is, otherwise the
+// answer prefetched for this round (even if its TTL ran out since), or a
+// fresh lookup for hosts that were not prefetched or with --no-dns-cache.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	if c != nil {
+		c.mu.Lock()
+		e := c.entries[host]
+		c.mu.Unlock()
+		if e != nil {
+			return e.ips, e.err
+		}
+	}
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// dialService connects to address through its cached addresses, trying
+// them in turn and giving each an equal share of what is left of the
+// timeout (but at least two seconds), as net.Dialer does.
+func dialService(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ips, err := resolved.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	deadline, _ := ctx.Deadline()
+	var firstErr error
+	for i, ip := range ips {
+		remaining := time.Until(deadline)
+		share := remaining / time.Duration(len(ips)-i)
+		if share < 2*time.Second {
+			share = remaining
+		}
+		conn, err := (&net.Dialer{Timeout: share}).DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+// resolvConfServers returns the nameservers listed in a resolv.conf file,
+// or the local defaults the Go resolver also falls back to.
+func resolvConfServers(path string) []string {
+	var servers []string
+	if file, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+				servers = append(servers, net.JoinHostPort(fields[1], "53"))
+			}
+		}
+		file.Close()
+	}
+	if len(servers) == 0 {
+		servers = []string{"127.0.0.1:53", "[::1]:53"}
+	}
+	return servers
+}
+
+// dnsQueryTTL sends one recursive query for name over UDP and returns the
+// lowest TTL among the answer records of type qtype and the CNAMEs leading
+// to them; found is false when the name has no such records.
+func dnsQueryTTL(ctx context.Context, server, name string, qtype uint16) (ttl uint32, found bool, err error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	id := uint16(randomID())
+	msg := make([]byte, 12, 12+len(name)+6)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	msg[2] = 0x01 // RD: recursion desired
+	msg[5] = 1    // QDCOUNT
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return 0, false, fmt.Errorf("invalid hostname %q", name)
+		}
+		msg = append(append(msg, byte(len(label))), label...)
+	}
+	msg = append(msg, 0, byte(qtype>>8), byte(qtype), 0, 1) // Class IN
+	if _, err := conn.Write(msg); err != nil {
+		return 0, false, err
+	}
+
+	buf := make([]byte, 1232)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, false, err
+		}
+		if n >= 12 && binary.BigEndian.Uint16(buf) == id && buf[2]&0x80 != 0 {
+			return parseDNSTTL(buf[:n], qtype)
+		}
+	}
+}
+
+// parseDNSTTL reads the answer section of a DNS response.
+func parseDNSTTL(msg []byte, qtype uint16) (ttl uint32, found bool, err error) {
+	if msg[2]&0x02 != 0 {
+		return 0, false, fmt.Errorf("truncated response")
+	}
+	if rcode := msg[3] & 0x0f; rcode != 0 {
+		return 0, false, fmt.Errorf("response code %d", rcode)
+	}
+	qdCount := int(binary.BigEndian.Uint16(msg[4:]))
+	anCount := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+	for i := 0; i < qdCount; i++ {
+		if off = skipDNSName(msg, off); off < 0 {
+			return 0, false, fmt.Errorf("malformed response")
+		}
+		off += 4
+	}
+	haveTTL := false
+	for i := 0; i < anCount; i++ {
+		off = skipDNSName(msg, off)
+		if off < 0 || off+10 > len(msg) {
+			return 0, false, fmt.Errorf("malformed response")
+		}
+		rrType := binary.BigEndian.Uint16(msg[off:])
+		rrTTL := binary.BigEndian.Uint32(msg[off+4:])
+		off += 10 + int(binary.BigEndian.Uint16(msg[off+8:]))
+		if off > len(msg) {
+			return 0, false, fmt.Errorf("malformed response")
+		}
+		if rrType != qtype && rrType != 5 { // 5: CNAME
+			continue
+		}
+		if !haveTTL || rrTTL < ttl {
+			ttl, haveTTL = rrTTL, true
+		}
+		found = found || rrType == qtype
+	}
+	return ttl, found, nil
+}
+
+// skipDNSName returns the offset just past the (possibly compressed) name
+// at off, or -1 if it runs off the end of msg.
+func skipDNSName(msg []byte, off int) int {
+	for off >= 0 && off < len(msg) {
+		switch l := int(msg[off]); {
+		case l == 0:
+			return off + 1
+		case l&0xc0 == 0xc0:
+			return off + 2
+		default:
+			off += 1 + l
+		}
+	}
+	return -1
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scheduling for daemon modes: when the next round is due (a fixed interval
+// or a cron expression), a random start delay so that many instances on one
+// schedule do not fire together, and a cap on how many checks run at once.
+// The service monitor is the only tool with a daemon mode; the others run
+// once per invocation and are scheduled from outside (cron, systemd timers).
+
+// schedule returns the first tick strictly after t.
+type schedule interface {
+	next(t time.Time) time.Time
+}
+
+// everySchedule ticks at a fixed interval from the previous tick.
+type everySchedule time.Duration
+
+func (e everySchedule) next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+
+// cronSchedule is a parsed five-field cron expression (minute, hour, day of
+// month, month, day of week), evaluated in a fixed time zone.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // Bit n set: value n matches
+	domAny, dowAny                bool   // Field was "*"
+	loc                           *time.Location
+}
+
+// cronMacros are the shorthands accepted in place of five fields.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+var cronDayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseCron parses expr, e.g. "*/5 * * * *", "0 8-18 * * mon-fri" or
+// "@hourly". Fields accept *, numbers, names (jan, mon), ranges a-b, steps
+// */n and a-b/n, and comma lists. As in Vixie cron, when both day of month
+// and day of week are restricted, a day matching either one is run.
+func parseCron(expr string, loc *time.Location) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+	c := &cronSchedule{loc: loc, domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	specs := []struct {
+		name     string
+		min, max int
+		names    []string
+		bits     *uint64
+	}{
+		{"minute", 0, 59, nil, &c.minute},
+		{"hour", 0, 23, nil, &c.hour},
+		{"day of month", 1, 31, nil, &c.dom},
+		{"month", 1, 12, cronMonthNames, &c.month},
+		{"day of week", 0, 7, cronDayNames, &c.dow},
+	}
+	for i, s := range specs {
+		bits, err := parseCronField(fields[i], s.min, s.max, s.names)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %w", s.name, fields[i], err)
+		}
+		*s.bits = bits
+	}
+	if c.dow&(1<<7) != 0 { // 7 is Sunday too
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = cronValue(a, min, max, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(b, min, max, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = max // "5/15" means 5-max/15
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("range %q runs backwards", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, min, max int, names []string) (int, error) {
+	for i, n := range names {
+		if strings.EqualFold(s, n) {
+			if min == 1 {
+				return i + 1, nil
+			}
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, min, max)
+	}
+	return v, nil
+}
+
+// next walks forward from t a minute at a time, skipping whole hours and days
+// that cannot match. A schedule that can never fire (say, 31 February) gives
+// up after five years and returns the zero time.
+func (c *cronSchedule) next(t time.Time) time.Time {
+	t = t.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 || !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// startJitter returns a random delay below max to add to a tick.
+func startJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// checkLimiter caps the checks running at once; a nil limiter does not.
+type checkLimiter chan struct{}
+
+func newCheckLimiter(n int) checkLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(checkLimiter, n)
+}
+
+// acquire waits for a free slot; it returns false if ctx ends first.
+func (l checkLimiter) acquire(ctx context.Context) bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l checkLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
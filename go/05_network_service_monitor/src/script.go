@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// probeStep is one action of a synthetic transaction. Supported actions:
+//
+//	{"action": "connect"}                     open the TCP connection (implicit if omitted)
+//	{"action": "send", "data": "PING\r\n"}    write a string (JSON escapes allowed)
+//	{"action": "send_hex", "data": "0a0b"}    write raw bytes given as hex
+//	{"action": "expect", "pattern": "^\\+PONG"} read until the regex matches the received data
+//	{"action": "close"}                       close the connection
+type probeStep struct {
+	Action  string `json:"action"`
+	Data    string `json:"data,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	re      *regexp.Regexp
+	raw     []byte
+}
+
+// loadProbeScripts reads a JSON object mapping "host:port" to its step list.
+func loadProbeScripts(filePath string) (map[string][]probeStep, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("[ERROR] Failed to read probe script file %s: %w", filePath, err)
+	}
+	scripts := map[string][]probeStep{}
+	if err := json.Unmarshal(data, &scripts); err != nil {
+		return nil, fmt.Errorf("[ERROR] Failed to parse probe script file %s: %w", filePath, err)
+	}
+	for address, steps := range scripts {
+		opens := false
+		for i := range steps {
+			s := &steps[i]
+			s.Action = strings.ToLower(s.Action)
+			switch s.Action {
+			case "connect", "close":
+			case "send":
+				s.raw = []byte(s.Data)
+			case "send_hex":
+				if s.raw, err = hex.DecodeString(strings.ReplaceAll(s.Data, " ", "")); err != nil {
+					return nil, fmt.Errorf("[ERROR] %s step %d: invalid hex data: %w", address, i+1, err)
+				}
+			case "expect":
+				if s.re, err = regexp.Compile(s.Pattern); err != nil {
+					return nil, fmt.Errorf("[ERROR] %s step %d: invalid pattern: %w", address, i+1, err)
+				}
+			default:
+				return nil, fmt.Errorf("[ERROR] %s step %d: unknown action %q", address, i+1, s.Action)
+			}
+			opens = opens || s.Action != "close"
+		}
+		// A script that never connects would pass without touching the
+		// service and report it UP.
+		if !opens {
+			return nil, fmt.Errorf("[ERROR] %s: probe script never connects (add a connect, send or expect step)", address)
+		}
+	}
+	return scripts, nil
+}
+
+func (s probeStep) String() string {
+	switch s.Action {
+	case "send", "send_hex":
+		return fmt.Sprintf("%s %q", s.Action, s.Data)
+	case "expect":
+		return fmt.Sprintf("expect /%s/", s.Pattern)
+	}
+	return s.Action
+}
+
+// runProbeScript executes a step list against address. Each step gets the full
+// timeout; the returned count is the number of steps that completed and
+// connected reports whether any TCP connection was established.
+func runProbeScript(ctx context.Context, address string, steps []probeStep, timeout time.Duration) (passed int, connected bool, err error) {
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	var received []byte
+	buf := make([]byte, 4096)
+
+	for i, step := range steps {
+		if conn == nil && step.Action != "connect" && step.Action != "close" {
+			// Connect implicitly before the first I/O step.
+			c, dialErr := dialService(ctx, "tcp", address, timeout)
+			if dialErr != nil {
+				return i, connected, fmt.Errorf("step %d (%s): %w", i+1, step, dialErr)
+			}
+			conn, connected = c, true
+		}
+		switch step.Action {
+		case "connect":
+			if conn != nil {
+				conn.Close()
+			}
+			c, dialErr := dialService(ctx, "tcp", address, timeout)
+			if dialErr != nil {
+				return i, connected, fmt.Errorf("step %d (%s): %w", i+1, step, dialErr)
+			}
+			conn, received, connected = c, nil, true
+		case "send", "send_hex":
+			conn.SetWriteDeadline(time.Now().Add(timeout))
+			if _, err := conn.Write(step.raw); err != nil {
+				return i, connected, fmt.Errorf("step %d (%s): %w", i+1, step, err)
+			}
+		case "expect":
+			deadline := time.Now().Add(timeout)
+			for {
+				if loc := step.re.FindIndex(received); loc != nil {
+					received = received[loc[1]:] // Later expects continue after the match
+					break
+				}
+				conn.SetReadDeadline(deadline)
+				n, err := conn.Read(buf)
+				received = append(received, buf[:n]...)
+				if err != nil && step.re.Find(received) == nil {
+					return i, connected, fmt.Errorf("step %d (%s): %v; received %q", i+1, step, err, truncateForReport(received))
+				}
+			}
+		case "close":
+			if conn != nil {
+				conn.Close()
+				conn = nil
+			}
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] %s: step %d (%s) ok\n", address, i+1, step)
+		}
+	}
+	return len(steps), connected, nil
+}
+
+func truncateForReport(b []byte) []byte {
+	if len(b) > 80 {
+		return b[len(b)-80:]
+	}
+	return b
+}
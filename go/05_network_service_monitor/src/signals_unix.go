@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyDaemonSignals relays SIGUSR1 to probe and SIGHUP to reload.
+func notifyDaemonSignals(probe, reload chan<- os.Signal) {
+	signal.Notify(probe, syscall.SIGUSR1)
+	signal.Notify(reload, syscall.SIGHUP)
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// notifyDaemonSignals does nothing: Windows has no SIGUSR1 or SIGHUP, so
+// on-demand probes and reloads go through the control API (--control).
+func notifyDaemonSignals(probe, reload chan<- os.Signal) {}
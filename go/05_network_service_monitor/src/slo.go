@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram kept for
+// every service; slower probes fall into a final overflow bucket.
+var latencyBuckets = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second,
+}
+
+// sloSlots is how many slices the window is cut into; the oldest slice is
+// dropped as a whole, so the window slides in steps of window/sloSlots.
+const sloSlots = 24
+
+// sloSlot counts the probes of one service during one slice of the window.
+type sloSlot struct {
+	start   time.Time
+	buckets []int // One per latencyBuckets entry, plus overflow
+	total   int   // All probes, including failed ones
+	good    int   // Probes that succeeded within the SLO latency
+}
+
+// sloTracker keeps a sliding-window latency histogram per service and
+// vantage point and measures it against the SLO: target percent of probes
+// succeed within limit. A failed probe counts against the SLO.
+type sloTracker struct {
+	limit  time.Duration
+	target float64 // Percent
+	window time.Duration
+	slots  map[string][]*sloSlot // Key: service via vantage, oldest slot first
+	order  []string
+	names  map[string]string // Key to "service (vantage)" as printed
+}
+
+func newSLOTracker(limit time.Duration, target float64, window time.Duration) *sloTracker {
+	return &sloTracker{limit: limit, target: target, window: window, slots: map[string][]*sloSlot{}, names: map[string]string{}}
+}
+
+// record adds a round's results and drops slots that left the window.
+func (t *sloTracker) record(now time.Time, results []ServiceCheckResult) {
+	step := t.window / sloSlots
+	start := now.Truncate(step)
+	for _, r := range results {
+		key := r.Address + " via " + vantageOf(r)
+		slots, seen := t.slots[key]
+		if !seen {
+			t.order = append(t.order, key)
+			t.names[key] = r.Address
+			if r.Vantage != "" {
+				t.names[key] += " (" + r.Vantage + ")"
+			}
+		}
+		for len(slots) > 0 && !slots[0].start.Add(t.window).After(now) {
+			slots = slots[1:]
+		}
+		if len(slots) == 0 || !slots[len(slots)-1].start.Equal(start) {
+			slots = append(slots, &sloSlot{start: start, buckets: make([]int, len(latencyBuckets)+1)})
+		}
+		s := slots[len(slots)-1]
+		s.total++
+		if upValue(r) == 1 {
+			s.buckets[bucketOf(r.Latency)]++
+			if r.Latency <= t.limit {
+				s.good++
+			}
+		}
+		t.slots[key] = slots
+	}
+}
+
+func bucketOf(d time.Duration) int {
+	for i, b := range latencyBuckets {
+		if d <= b {
+			return i
+		}
+	}
+	return len(latencyBuckets)
+}
+
+// percentile returns the bucket bound below which p percent of the
+// successful probes fall, as printed: "<=50ms" or ">5s".
+func percentile(buckets []int, p float64) string {
+	n := 0
+	for _, c := range buckets {
+		n += c
+	}
+	if n == 0 {
+		return "-"
+	}
+	seen := 0
+	for i, c := range buckets {
+		seen += c
+		if float64(seen) >= p/100*float64(n) {
+			if i == len(latencyBuckets) {
+				return ">" + latencyBuckets[i-1].String()
+			}
+			return "<=" + latencyBuckets[i].String()
+		}
+	}
+	return "-"
+}
+
+// write prints the SLO section: compliance and latency percentiles of
+// every service probed within the window.
+func (t *sloTracker) write(now time.Time, output io.Writer) {
+	fmt.Fprintf(output, "\n--- Latency SLO: %g%% of probes within %s over %s ---\n", t.target, t.limit, t.window)
+	fmt.Fprintf(output, "%-40s %7s %7s %7s %11s %8s %8s %8s  %s\n", "Service", "Probes", "Within", "Failed", "Compliance", "p50", "p90", "p99", "SLO")
+	met, breached := 0, 0
+	for _, key := range t.order {
+		sum := make([]int, len(latencyBuckets)+1)
+		total, good, ok := 0, 0, 0
+		for _, s := range t.slots[key] {
+			if !s.start.Add(t.window).After(now) {
+				continue // A service that is no longer probed
+			}
+			total += s.total
+			good += s.good
+			for i, c := range s.buckets {
+				sum[i] += c
+				ok += c
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		compliance := 100 * float64(good) / float64(total)
+		verdict := "MET"
+		if compliance < t.target {
+			verdict = "BREACHED"
+			breached++
+		} else {
+			met++
+		}
+		fmt.Fprintf(output, "%-40s %7d %7d %7d %10.2f%% %8s %8s %8s  %s\n", t.names[key], total, good, total-ok, compliance,
+			percentile(sum, 50), percentile(sum, 90), percentile(sum, 99), verdict)
+	}
+	fmt.Fprintf(output, "SLO met by %d, breached by %d service(s).\n", met, breached)
+}
@@ -0,0 +1,600 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OIDs read by the SNMP probe (SNMPv2-MIB).
+const (
+	oidSysDescr  = "1.3.6.1.2.1.1.1.0"
+	oidSysUpTime = "1.3.6.1.2.1.1.3.0"
+)
+
+// usmReports explains the USM statistics an SNMPv3 agent reports instead of
+// answering (RFC 3414 section 5).
+var usmReports = map[string]string{
+	"1.3.6.1.6.3.15.1.1.1.0": "unsupported security level for this user",
+	"1.3.6.1.6.3.15.1.1.2.0": "request outside the agent's time window",
+	"1.3.6.1.6.3.15.1.1.3.0": "unknown user name",
+	"1.3.6.1.6.3.15.1.1.4.0": "unknown engine ID",
+	"1.3.6.1.6.3.15.1.1.5.0": "wrong authentication passphrase or protocol",
+	"1.3.6.1.6.3.15.1.1.6.0": "decryption failed (wrong privacy passphrase or protocol)",
+}
+
+// snmpTarget configures the SNMP probe of one service, from the options on
+// its services file line:
+//
+//	switch1:161 type=snmp community=monitoring
+//	router1:161 type=snmp version=3 user=nms auth=sha:$SNMP_AUTH priv=aes:$SNMP_PRIV
+//
+// Passphrases starting with $ are read from that environment variable.
+type snmpTarget struct {
+	Version   string // "2c" or "3"
+	Community string
+	User      string
+	AuthProto string // "md5" or "sha" (HMAC-96); "" for noAuthNoPriv
+	AuthPass  string
+	PrivProto string // "aes" (AES-128-CFB); "" for no privacy
+	PrivPass  string
+}
+
+// parseSNMPOptions reads the probe options of a services file line. It
+// returns nil for type=tcp, the default.
+func parseSNMPOptions(fields []string) (*snmpTarget, error) {
+	t := &snmpTarget{Version: "2c", Community: "public"}
+	probeType := "tcp"
+	for _, f := range fields {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected key=value, got %q", f)
+		}
+		switch strings.ToLower(key) {
+		case "type":
+			probeType = strings.ToLower(value)
+		case "version":
+			t.Version = strings.TrimPrefix(strings.ToLower(value), "v")
+		case "community":
+			t.Community = value
+		case "user":
+			t.User = value
+		case "auth", "priv":
+			proto, pass, ok := strings.Cut(value, ":")
+			if !ok {
+				return nil, fmt.Errorf("%s expects <protocol>:<passphrase>, got %q", key, value)
+			}
+			if strings.HasPrefix(pass, "$") {
+				if pass = os.Getenv(pass[1:]); pass == "" {
+					return nil, fmt.Errorf("%s passphrase variable %s is not set", key, value[len(proto)+1:])
+				}
+			}
+			if len(pass) < 8 {
+				return nil, fmt.Errorf("%s passphrase must be at least 8 characters (RFC 3414)", key)
+			}
+			if strings.ToLower(key) == "auth" {
+				t.AuthProto, t.AuthPass = strings.ToLower(proto), pass
+			} else {
+				t.PrivProto, t.PrivPass = strings.ToLower(proto), pass
+			}
+		default:
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	switch {
+	case probeType == "tcp" && len(fields) == 1:
+		return nil, nil
+	case probeType != "snmp":
+		return nil, fmt.Errorf("unknown probe type %q (expected tcp or snmp), or SNMP options without type=snmp", probeType)
+	case t.Version != "2c" && t.Version != "3":
+		return nil, fmt.Errorf("unsupported SNMP version %q (expected 2c or 3)", t.Version)
+	case t.Version == "3" && t.User == "":
+		return nil, fmt.Errorf("SNMPv3 requires user=")
+	case t.AuthProto != "" && t.AuthProto != "md5" && t.AuthProto != "sha":
+		return nil, fmt.Errorf("unsupported auth protocol %q (expected md5 or sha)", t.AuthProto)
+	case t.PrivProto != "" && t.PrivProto != "aes":
+		return nil, fmt.Errorf("unsupported privacy protocol %q (expected aes)", t.PrivProto)
+	case t.PrivProto != "" && t.AuthProto == "":
+		return nil, fmt.Errorf("priv= requires auth= (authPriv)")
+	case t.Version == "2c" && t.User != "":
+		return nil, fmt.Errorf("user=, auth= and priv= need version=3")
+	}
+	return t, nil
+}
+
+// probeSNMP reads sysDescr and sysUpTime from the agent at address over UDP.
+// answered is false when nothing came back, so the device is DOWN; an agent
+// that answers with an error (wrong community is silent, but v3 reports a bad
+// user or passphrase) has answered.
+func probeSNMP(ctx context.Context, address string, t *snmpTarget, timeout time.Duration) (detail string, answered bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := dialService(ctx, "udp", address, timeout)
+	if err != nil {
+		return "", false, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	var vars map[string]berValue
+	if t.Version == "3" {
+		vars, answered, err = snmpV3Get(ctx, conn, t)
+	} else {
+		vars, answered, err = snmpV2cGet(ctx, conn, t.Community)
+	}
+	if err != nil {
+		return "", answered, err
+	}
+	descr := strings.Join(strings.Fields(string(vars[oidSysDescr].Content)), " ")
+	if len(descr) > 120 {
+		descr = descr[:117] + "..."
+	}
+	ticks := time.Duration(berUint(vars[oidSysUpTime].Content)) * 10 * time.Millisecond
+	return fmt.Sprintf("%s (up %s)", descr, formatUptime(ticks)), true, nil
+}
+
+// formatUptime renders an uptime as days plus a rounded duration.
+func formatUptime(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	rest := (d % (24 * time.Hour)).Round(time.Second)
+	if days > 0 {
+		return fmt.Sprintf("%dd %s", days, rest)
+	}
+	return rest.String()
+}
+
+// getRequestPDU builds a GetRequest for sysDescr and sysUpTime.
+func getRequestPDU(requestID int64) []byte {
+	null := []byte{0x05, 0x00}
+	return berTLV(0xa0, bytes.Join([][]byte{berInt(requestID), berInt(0), berInt(0),
+		berSeq(berSeq(berOID(oidSysDescr), null), berSeq(berOID(oidSysUpTime), null))}, nil))
+}
+
+// snmpExchange sends msg and waits for a reply accepted by match, resending
+// twice if nothing arrives (UDP may drop either datagram). ctx bounds the
+// whole exchange.
+func snmpExchange(ctx context.Context, conn net.Conn, msg []byte, match func([]byte) bool) ([]byte, error) {
+	deadline, _ := ctx.Deadline()
+	buf := make([]byte, 65535)
+	for attempt := 0; attempt < 3; attempt++ {
+		if _, err := conn.Write(msg); err != nil {
+			return nil, err
+		}
+		wait := time.Now().Add(time.Second << attempt)
+		if attempt == 2 || wait.After(deadline) {
+			wait = deadline
+		}
+		conn.SetReadDeadline(wait)
+		for {
+			n, err := conn.Read(buf)
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() && ctx.Err() == nil {
+				break // Resend
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, fmt.Errorf("no SNMP response: %w", ctx.Err())
+				}
+				return nil, err
+			}
+			if match(buf[:n]) {
+				return append([]byte(nil), buf[:n]...), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no SNMP response")
+}
+
+// snmpV2cGet performs the GET with a community string.
+func snmpV2cGet(ctx context.Context, conn net.Conn, community string) (map[string]berValue, bool, error) {
+	reqID := randomID()
+	msg := berSeq(berInt(1), berTLV(0x04, []byte(community)), getRequestPDU(reqID))
+	var pdu berValue
+	resp, err := snmpExchange(ctx, conn, msg, func(b []byte) bool {
+		parts, err := berChildrenOf(b, 0)
+		if err != nil || len(parts) != 3 || parts[2].Tag != 0xa2 {
+			return false
+		}
+		pdu = parts[2]
+		fields, err := berChildren(b, pdu)
+		return err == nil && len(fields) == 4 && berInt64(fields[0].Content) == reqID
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, false, fmt.Errorf("%w (agents do not answer a wrong community)", err)
+	} else if err != nil {
+		return nil, false, err
+	}
+	vars, err := responseVarbinds(resp, pdu)
+	return vars, true, err
+}
+
+// responseVarbinds checks a Response PDU's error status and returns its
+// variables by OID.
+func responseVarbinds(msg []byte, pdu berValue) (map[string]berValue, error) {
+	fields, err := berChildren(msg, pdu)
+	if err != nil || len(fields) != 4 {
+		return nil, fmt.Errorf("malformed SNMP response")
+	}
+	if status := berInt64(fields[1].Content); status != 0 {
+		return nil, fmt.Errorf("agent returned error-status %d for variable %d", status, berInt64(fields[2].Content))
+	}
+	binds, err := berChildren(msg, fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("malformed SNMP varbind list")
+	}
+	vars := map[string]berValue{}
+	for _, vb := range binds {
+		kv, err := berChildren(msg, vb)
+		if err != nil || len(kv) != 2 || kv[0].Tag != 0x06 {
+			return nil, fmt.Errorf("malformed SNMP varbind")
+		}
+		oid := berOIDString(kv[0].Content)
+		if kv[1].Tag >= 0x80 && kv[1].Tag <= 0x82 {
+			return nil, fmt.Errorf("agent has no %s (noSuchObject/noSuchInstance)", oid)
+		}
+		vars[oid] = kv[1]
+	}
+	if _, ok := vars[oidSysDescr]; !ok {
+		return nil, fmt.Errorf("response lacks sysDescr")
+	}
+	return vars, nil
+}
+
+// usmHeader is the parsed header and USM security parameters of an SNMPv3 message.
+type usmHeader struct {
+	MsgID      int64
+	Flags      byte
+	EngineID   []byte
+	Boots      int64
+	Time       int64
+	User       string
+	AuthParams berValue // Offset locates the HMAC within the message
+	PrivParams []byte
+	Data       berValue // ScopedPDU, or the encrypted ScopedPDU octet string
+}
+
+// parseV3 parses an SNMPv3 message without verifying or decrypting it.
+func parseV3(msg []byte) (usmHeader, error) {
+	var h usmHeader
+	top, err := berChildrenOf(msg, 0)
+	if err != nil || len(top) != 4 || berInt64(top[0].Content) != 3 {
+		return h, fmt.Errorf("not an SNMPv3 message")
+	}
+	global, err := berChildren(msg, top[1])
+	if err != nil || len(global) != 4 || len(global[2].Content) != 1 {
+		return h, fmt.Errorf("malformed SNMPv3 header")
+	}
+	h.MsgID, h.Flags = berInt64(global[0].Content), global[2].Content[0]
+	usm, err := berChildrenOf(msg, top[2].Offset)
+	if err != nil || len(usm) != 6 {
+		return h, fmt.Errorf("malformed USM security parameters")
+	}
+	h.EngineID, h.Boots, h.Time = usm[0].Content, berInt64(usm[1].Content), berInt64(usm[2].Content)
+	h.User, h.AuthParams, h.PrivParams = string(usm[3].Content), usm[4], usm[5].Content
+	h.Data = top[3]
+	return h, nil
+}
+
+// usmKeys holds the localized keys for one engine.
+type usmKeys struct {
+	hash func() hash.Hash
+	auth []byte
+	priv []byte
+}
+
+// passwordToKey is the RFC 3414 (A.2) password to localized key algorithm:
+// the passphrase is repeated to 1 MiB and hashed, then bound to the engine ID.
+func passwordToKey(h func() hash.Hash, pass string, engineID []byte) []byte {
+	d := h()
+	chunk := make([]byte, 64)
+	for i := 0; i < 1<<20; i += len(chunk) {
+		for j := range chunk {
+			chunk[j] = pass[(i+j)%len(pass)]
+		}
+		d.Write(chunk)
+	}
+	ku := d.Sum(nil)
+	d = h()
+	d.Write(ku)
+	d.Write(engineID)
+	d.Write(ku)
+	return d.Sum(nil)
+}
+
+// sign computes the HMAC-96 of msg with its authentication parameters zeroed.
+func (k *usmKeys) sign(msg []byte, auth berValue) []byte {
+	zeroed := append([]byte(nil), msg...)
+	clear(zeroed[auth.Offset : auth.Offset+len(auth.Content)])
+	m := hmac.New(k.hash, k.auth)
+	m.Write(zeroed)
+	return m.Sum(nil)[:12]
+}
+
+// aesIV builds the RFC 3826 AES-CFB IV from the engine's boots and time and
+// the message salt.
+func aesIV(boots, engineTime int64, salt []byte) []byte {
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv[0:], uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(engineTime))
+	copy(iv[8:], salt)
+	return iv
+}
+
+// snmpV3Get discovers the agent's engine ID, boots and time with an
+// unauthenticated request (RFC 3414 section 4), then performs the GET at the
+// configured security level, re-synchronizing once if the agent reports the
+// request as outside its time window.
+func snmpV3Get(ctx context.Context, conn net.Conn, t *snmpTarget) (map[string]berValue, bool, error) {
+	probe := v3Message(randomID(), 0x04, usmHeader{}, berSeq(berTLV(0x04, nil), berTLV(0x04, nil), berTLV(0xa0,
+		bytes.Join([][]byte{berInt(randomID()), berInt(0), berInt(0), berSeq()}, nil))), nil)
+	resp, err := snmpExchange(ctx, conn, probe, func(b []byte) bool { _, err := parseV3(b); return err == nil })
+	if err != nil {
+		return nil, false, fmt.Errorf("%w (v3 engine discovery)", err)
+	}
+	engine, _ := parseV3(resp)
+	if len(engine.EngineID) == 0 {
+		return nil, true, fmt.Errorf("agent did not report its engine ID")
+	}
+	debugf("SNMP engine %x, boots %d, time %d", engine.EngineID, engine.Boots, engine.Time)
+
+	var keys *usmKeys
+	flags := byte(0x04)
+	if t.AuthProto != "" {
+		keys = &usmKeys{hash: sha1.New}
+		if t.AuthProto == "md5" {
+			keys.hash = md5.New
+		}
+		keys.auth = passwordToKey(keys.hash, t.AuthPass, engine.EngineID)
+		flags |= 0x01
+		if t.PrivProto != "" {
+			keys.priv = passwordToKey(keys.hash, t.PrivPass, engine.EngineID)[:16]
+			flags |= 0x02
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		msgID, reqID := randomID(), randomID()
+		scoped := berSeq(berTLV(0x04, engine.EngineID), berTLV(0x04, nil), getRequestPDU(reqID))
+		h := usmHeader{EngineID: engine.EngineID, Boots: engine.Boots, Time: engine.Time, User: t.User}
+		msg := v3Message(msgID, flags, h, scoped, keys)
+		resp, err := snmpExchange(ctx, conn, msg, func(b []byte) bool {
+			r, err := parseV3(b)
+			return err == nil && r.MsgID == msgID
+		})
+		if err != nil {
+			return nil, true, fmt.Errorf("%w (v3 request; the agent answered discovery)", err)
+		}
+		r, _ := parseV3(resp)
+		// With authentication configured, only a signed response is trusted.
+		// Agents send most reports unsigned (RFC 3414 section 3.2), so an
+		// unsigned report still explains the failure but changes nothing.
+		unsigned := keys != nil && r.Flags&0x01 == 0
+		if keys != nil && !unsigned {
+			if len(r.AuthParams.Content) != 12 || !hmac.Equal(keys.sign(resp, r.AuthParams), r.AuthParams.Content) {
+				return nil, true, fmt.Errorf("response failed authentication")
+			}
+		}
+		scopedMsg, scopedPDU := resp, r.Data
+		if r.Flags&0x02 != 0 {
+			if keys == nil || keys.priv == nil || len(r.PrivParams) != 8 {
+				return nil, true, fmt.Errorf("cannot decrypt response")
+			}
+			block, _ := aes.NewCipher(keys.priv)
+			scopedMsg = make([]byte, len(r.Data.Content))
+			cipher.NewCFBDecrypter(block, aesIV(r.Boots, r.Time, r.PrivParams)).XORKeyStream(scopedMsg, r.Data.Content)
+			if scopedPDU, _, err = berParse(scopedMsg, 0); err != nil {
+				return nil, true, fmt.Errorf("decryption failed (wrong privacy passphrase?)")
+			}
+		}
+		parts, err := berChildren(scopedMsg, scopedPDU)
+		if err != nil || len(parts) != 3 {
+			return nil, true, fmt.Errorf("malformed scoped PDU")
+		}
+		if parts[2].Tag == 0xa8 { // Report
+			oid := ""
+			if fields, err := berChildren(scopedMsg, parts[2]); err == nil && len(fields) == 4 {
+				if binds, err := berChildren(scopedMsg, fields[3]); err == nil && len(binds) > 0 {
+					if kv, err := berChildren(scopedMsg, binds[0]); err == nil && len(kv) == 2 {
+						oid = berOIDString(kv[0].Content)
+					}
+				}
+			}
+			if oid == "1.3.6.1.6.3.15.1.1.2.0" && attempt == 0 && !unsigned {
+				engine.Boots, engine.Time = r.Boots, r.Time
+				continue
+			}
+			if reason, ok := usmReports[oid]; ok {
+				return nil, true, fmt.Errorf("agent rejected the request: %s", reason)
+			}
+			return nil, true, fmt.Errorf("agent sent report %s", oid)
+		}
+		if parts[2].Tag != 0xa2 {
+			return nil, true, fmt.Errorf("unexpected PDU type 0x%x", parts[2].Tag)
+		}
+		if unsigned {
+			return nil, true, fmt.Errorf("response failed authentication (not signed)")
+		}
+		vars, err := responseVarbinds(scopedMsg, parts[2])
+		return vars, true, err
+	}
+}
+
+// v3Message assembles an SNMPv3 message, encrypting the scoped PDU and
+// signing the message when keys are given.
+func v3Message(msgID int64, flags byte, h usmHeader, scoped []byte, keys *usmKeys) []byte {
+	var authParams, privParams []byte
+	if keys != nil {
+		authParams = make([]byte, 12)
+		if keys.priv != nil {
+			privParams = make([]byte, 8)
+			rand.Read(privParams)
+			block, _ := aes.NewCipher(keys.priv)
+			enc := make([]byte, len(scoped))
+			cipher.NewCFBEncrypter(block, aesIV(h.Boots, h.Time, privParams)).XORKeyStream(enc, scoped)
+			scoped = berTLV(0x04, enc)
+		}
+	}
+	usm := berSeq(berTLV(0x04, h.EngineID), berInt(h.Boots), berInt(h.Time), berTLV(0x04, []byte(h.User)),
+		berTLV(0x04, authParams), berTLV(0x04, privParams))
+	msg := berSeq(berInt(3), berSeq(berInt(msgID), berInt(65507), berTLV(0x04, []byte{flags}), berInt(3)),
+		berTLV(0x04, usm), scoped)
+	if keys != nil {
+		r, _ := parseV3(msg)
+		copy(msg[r.AuthParams.Offset:], keys.sign(msg, r.AuthParams))
+	}
+	return msg
+}
+
+// randomID returns a random positive 31-bit message or request ID.
+func randomID() int64 {
+	var b [4]byte
+	rand.Read(b[:])
+	return int64(binary.BigEndian.Uint32(b[:]) >> 1)
+}
+
+// berValue is one decoded BER element; Offset is where its content starts
+// within the decoded message.
+type berValue struct {
+	Tag     byte
+	Content []byte
+	Offset  int
+}
+
+func berTLV(tag byte, content []byte) []byte {
+	n := len(content)
+	switch {
+	case n < 0x80:
+		return append([]byte{tag, byte(n)}, content...)
+	case n < 0x100:
+		return append([]byte{tag, 0x81, byte(n)}, content...)
+	}
+	return append([]byte{tag, 0x82, byte(n >> 8), byte(n)}, content...)
+}
+
+func berSeq(parts ...[]byte) []byte {
+	return berTLV(0x30, bytes.Join(parts, nil))
+}
+
+func berInt(v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return berTLV(0x02, b)
+}
+
+func berOID(oid string) []byte {
+	parts := strings.Split(oid, ".")
+	n := make([]uint64, len(parts))
+	for i, p := range parts {
+		n[i], _ = strconv.ParseUint(p, 10, 32)
+	}
+	out := []byte{byte(n[0]*40 + n[1])}
+	for _, v := range n[2:] {
+		enc := []byte{byte(v & 0x7f)}
+		for v >>= 7; v > 0; v >>= 7 {
+			enc = append([]byte{byte(v&0x7f) | 0x80}, enc...)
+		}
+		out = append(out, enc...)
+	}
+	return berTLV(0x06, out)
+}
+
+func berOIDString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	parts := []string{strconv.Itoa(int(b[0]) / 40), strconv.Itoa(int(b[0]) % 40)}
+	var v uint64
+	for _, c := range b[1:] {
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 == 0 {
+			parts = append(parts, strconv.FormatUint(v, 10))
+			v = 0
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func berInt64(b []byte) int64 {
+	var v int64
+	for i, c := range b {
+		if i == 0 && c&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+func berUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// berParse decodes the element at msg[off:] and returns it with the offset
+// just past it.
+func berParse(msg []byte, off int) (berValue, int, error) {
+	if off+2 > len(msg) {
+		return berValue{}, 0, fmt.Errorf("truncated BER element")
+	}
+	tag, n := msg[off], int(msg[off+1])
+	off += 2
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 3 || off+size > len(msg) {
+			return berValue{}, 0, fmt.Errorf("unsupported BER length")
+		}
+		n = 0
+		for _, c := range msg[off : off+size] {
+			n = n<<8 | int(c)
+		}
+		off += size
+	}
+	if off+n > len(msg) {
+		return berValue{}, 0, fmt.Errorf("truncated BER element")
+	}
+	return berValue{Tag: tag, Content: msg[off : off+n], Offset: off}, off + n, nil
+}
+
+// berChildren decodes the elements nested in a constructed value of msg.
+func berChildren(msg []byte, v berValue) ([]berValue, error) {
+	var out []berValue
+	for off, end := v.Offset, v.Offset+len(v.Content); off < end; {
+		child, next, err := berParse(msg[:end], off)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, child)
+		off = next
+	}
+	return out, nil
+}
+
+// berChildrenOf decodes the element at off and returns its children.
+func berChildrenOf(msg []byte, off int) ([]berValue, error) {
+	v, _, err := berParse(msg, off)
+	if err != nil {
+		return nil, err
+	}
+	return berChildren(msg, v)
+}
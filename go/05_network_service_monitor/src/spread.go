@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+)
+
+// spreadWindow is how much of each round --spread may delay probes by: the
+// interval less one timeout, so the last probe still ends before the next
+// round. Zero (no spreading) if the interval is not longer than the timeout.
+func spreadWindow(interval, timeout time.Duration) time.Duration {
+	if interval <= timeout {
+		return 0
+	}
+	return interval - timeout
+}
+
+// probeOffset is the fixed delay of a service within the spread window,
+// derived from a hash of its address. A service keeps its slot from round to
+// round and across restarts, so its probes stay one interval apart, while
+// thousands of services are spread evenly instead of firing together.
+func probeOffset(address string, window time.Duration) time.Duration {
+	if window <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(normalizeAddress(address)))
+	return time.Duration(h.Sum64() % uint64(window))
+}
+
+// waitOffset sleeps until the service's slot in the round; it returns false
+// if ctx was cancelled first.
+func waitOffset(ctx context.Context, offset time.Duration) bool {
+	if offset <= 0 {
+		return true
+	}
+	timer := time.NewTimer(offset)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
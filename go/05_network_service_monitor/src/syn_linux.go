@@ -0,0 +1,208 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Half-open SYN probes for --syn. Linux only: other builds use
+// syn_other.go, which refuses --syn.
+
+// synKey identifies the reply to one SYN: the target address and port and
+// the source port it was sent from.
+type synKey struct {
+	addr    [4]byte
+	port    uint16
+	srcPort uint16
+}
+
+// synProber tests reachability with half-open (SYN) probes over one raw
+// socket. A SYN-ACK means the port is open; the kernel, which knows nothing
+// of the connection, answers it with a RST, so no handshake is completed and
+// the service never sees a connection. A RST means the port is closed and
+// silence that it is filtered.
+type synProber struct {
+	fd      int
+	mu      sync.Mutex
+	waiters map[synKey]chan byte // TCP flags of the reply
+}
+
+// newSynProber opens the raw socket used by --syn (Linux, IPv4, CAP_NET_RAW).
+func newSynProber() (*synProber, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_TCP)
+	if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+		return nil, fmt.Errorf("--syn needs a raw socket: run as root or grant CAP_NET_RAW (setcap cap_net_raw+ep <binary>)")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open raw socket: %w", err)
+	}
+	p := &synProber{fd: fd, waiters: map[synKey]chan byte{}}
+	go p.receive()
+	return p, nil
+}
+
+// receive reads every TCP segment delivered to the raw socket and hands
+// replies to the probe waiting for them.
+func (p *synProber) receive() {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := syscall.Recvfrom(p.fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return
+		}
+		if n < 20 || buf[0]>>4 != 4 {
+			continue
+		}
+		ihl := int(buf[0]&0x0f) * 4
+		if n < ihl+14 {
+			continue
+		}
+		tcp := buf[ihl:n]
+		var k synKey
+		copy(k.addr[:], buf[12:16])
+		k.port = binary.BigEndian.Uint16(tcp[0:2])
+		k.srcPort = binary.BigEndian.Uint16(tcp[2:4])
+		p.mu.Lock()
+		if ch, ok := p.waiters[k]; ok {
+			select {
+			case ch <- tcp[13]:
+			default:
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// probe sends a SYN to address and waits for the reply, resending once
+// halfway through the timeout. IPv6 targets fall back to a TCP connect.
+func (p *synProber) probe(ctx context.Context, address string, timeout time.Duration) ServiceCheckResult {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return ServiceCheckResult{Address: address, Status: "DOWN", Error: err}
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return ServiceCheckResult{Address: address, Status: "DOWN", Error: fmt.Errorf("invalid port %q", portStr)}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ips, err := resolved.lookup(ctx, host)
+	if err != nil {
+		return ServiceCheckResult{Address: address, Status: "DOWN", Error: err}
+	}
+	var dst net.IP
+	for _, ip := range ips {
+		if dst = ip.To4(); dst != nil {
+			break
+		}
+	}
+	if dst == nil {
+		debugf("%s: no IPv4 address for --syn, using a TCP connect", address)
+		return probeConnect(ctx, address, timeout)
+	}
+	src, err := localAddrFor(dst, port)
+	if err != nil {
+		return ServiceCheckResult{Address: address, Status: "DOWN", Error: err}
+	}
+
+	k := synKey{port: uint16(port)}
+	copy(k.addr[:], dst)
+	ch := make(chan byte, 1)
+	p.mu.Lock()
+	for {
+		var b [2]byte
+		rand.Read(b[:])
+		k.srcPort = 32768 + binary.BigEndian.Uint16(b[:])%28000
+		if _, taken := p.waiters[k]; !taken {
+			break
+		}
+	}
+	p.waiters[k] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.waiters, k)
+		p.mu.Unlock()
+	}()
+
+	var seq [4]byte
+	rand.Read(seq[:])
+	segment := synSegment(src, dst, k.srcPort, k.port, binary.BigEndian.Uint32(seq[:]))
+	sa := &syscall.SockaddrInet4{Port: port}
+	copy(sa.Addr[:], dst)
+
+	start := time.Now()
+	resend := time.NewTimer(timeout / 2)
+	defer resend.Stop()
+	if err := syscall.Sendto(p.fd, segment, 0, sa); err != nil {
+		return ServiceCheckResult{Address: address, Status: "DOWN", Error: fmt.Errorf("failed to send SYN: %w", err)}
+	}
+	for {
+		select {
+		case flags := <-ch:
+			latency := time.Since(start)
+			switch {
+			case flags&0x12 == 0x12: // SYN+ACK
+				return ServiceCheckResult{Address: address, Status: "UP", Latency: latency}
+			case flags&0x04 != 0: // RST
+				return ServiceCheckResult{Address: address, Status: statusClosed, Error: fmt.Errorf("port closed (RST to SYN): %w", syscall.ECONNREFUSED)}
+			}
+		case <-resend.C:
+			syscall.Sendto(p.fd, segment, 0, sa)
+		case <-ctx.Done():
+			return ServiceCheckResult{Address: address, Status: statusFiltered, Error: fmt.Errorf("no reply to SYN within %s (filtered or host down): %w", timeout, os.ErrDeadlineExceeded)}
+		}
+	}
+}
+
+// localAddrFor returns the source address the kernel would route dst from.
+// Connecting a UDP socket selects the route without sending anything.
+func localAddrFor(dst net.IP, port int) (net.IP, error) {
+	c, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: dst, Port: port})
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.LocalAddr().(*net.UDPAddr).IP.To4(), nil
+}
+
+// synSegment builds a TCP SYN segment (header only, with an MSS option) and
+// its checksum over the IPv4 pseudo-header.
+func synSegment(src, dst net.IP, srcPort, dstPort uint16, seq uint32) []byte {
+	b := make([]byte, 24)
+	binary.BigEndian.PutUint16(b[0:], srcPort)
+	binary.BigEndian.PutUint16(b[2:], dstPort)
+	binary.BigEndian.PutUint32(b[4:], seq)
+	b[12] = 6 << 4 // Data offset: 6 words
+	b[13] = 0x02   // SYN
+	binary.BigEndian.PutUint16(b[14:], 64240)
+	copy(b[20:], []byte{2, 4, 0x05, 0xb4}) // MSS 1460
+
+	pseudo := make([]byte, 0, 12+len(b))
+	pseudo = append(pseudo, src...)
+	pseudo = append(pseudo, dst...)
+	pseudo = append(pseudo, 0, syscall.IPPROTO_TCP, 0, byte(len(b)))
+	pseudo = append(pseudo, b...)
+	var sum uint32
+	for i := 0; i+1 < len(pseudo); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(pseudo[i:]))
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	binary.BigEndian.PutUint16(b[16:], ^uint16(sum))
+	return b
+}
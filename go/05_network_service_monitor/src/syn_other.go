@@ -0,0 +1,22 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// synProber stands in for the raw-socket prober of syn_linux.go, which is
+// only built on Linux.
+type synProber struct{}
+
+// newSynProber refuses --syn on this system.
+func newSynProber() (*synProber, error) {
+	return nil, errors.New("SYN probes require Linux")
+}
+
+func (p *synProber) probe(ctx context.Context, address string, timeout time.Duration) ServiceCheckResult {
+	return ServiceCheckResult{Address: address, Status: "UNKNOWN", Error: errors.New("SYN probes require Linux")}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// reportView is what a --template is executed with, once per round.
+type reportView struct {
+	Tool     string
+	Version  string
+	Hostname string
+	Time     time.Time // Start of the round
+	Round    int       // 0 for a single pass
+	OnDemand bool      // Started by SIGUSR1 or the control API
+	Partial  bool      // Interrupted before every probe finished
+	Results  []stateRecord
+	Summary  portCounts
+	State    []stateRecord // Last result of every service seen so far, this round or earlier
+}
+
+// templateFuncs are the helpers available to report templates besides the
+// text/template built-ins.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"pad": func(width int, s string) string {
+		return fmt.Sprintf("%-*s", width, s)
+	},
+	"ms": func(ms float64) string {
+		return fmt.Sprintf("%.1fms", ms)
+	},
+	"time": func(t time.Time) string {
+		return stamp(t)
+	},
+}
+
+// loadReportTemplate parses the --template file and tries it on a sample
+// round, so a misspelt field stops the monitor at start instead of failing
+// every report.
+func loadReportTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report template %s: %w", path, err)
+	}
+	tmpl, err := template.New("report").Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid report template %s: %w", path, err)
+	}
+	sample := []ServiceCheckResult{{Address: "example.com:443", Status: "UP", Latency: time.Millisecond}}
+	if err := writeTemplateReport(tmpl, reportView{Time: time.Now(), State: []stateRecord{newStateRecord(time.Now(), sample[0])}}, sample, io.Discard); err != nil {
+		return nil, fmt.Errorf("invalid report template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// writeTemplateReport renders a round with the --template instead of the
+// built-in report layout.
+func writeTemplateReport(tmpl *template.Template, view reportView, results []ServiceCheckResult, output io.Writer) error {
+	view.Tool, view.Version = toolName, toolVersion
+	view.Hostname, _ = os.Hostname()
+	view.Time = view.Time.In(reportTZ)
+	view.Summary = countPorts(results)
+	for _, r := range results {
+		view.Results = append(view.Results, newStateRecord(view.Time, r))
+	}
+	return tmpl.Execute(output, view)
+}
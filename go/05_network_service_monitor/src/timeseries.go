@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// seriesWriter appends one record per probe result to a time-series file, as
+// CSV or InfluxDB line protocol, so availability and latency can be graphed
+// (e.g. Grafana's CSV data source, or `influx write`) without a metrics agent.
+type seriesWriter struct {
+	file   *os.File
+	format string
+}
+
+// openSeries opens path for appending and writes the CSV header if the file is new.
+func openSeries(path, format string) (*seriesWriter, error) {
+	if format != "csv" && format != "influx" {
+		return nil, fmt.Errorf("invalid series format %q (use csv or influx)", format)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open series file %s: %w", path, err)
+	}
+	w := &seriesWriter{file: file, format: format}
+	if info, err := file.Stat(); err == nil && info.Size() == 0 && format == "csv" {
+		fmt.Fprintln(file, "timestamp,service,vantage,status,up,latency_ms,error")
+	}
+	return w, nil
+}
+
+// write appends all results of one probe round, stamped with the round's time.
+func (w *seriesWriter) write(ts time.Time, results []ServiceCheckResult) error {
+	if w.format == "csv" {
+		cw := csv.NewWriter(w.file)
+		for _, r := range results {
+			cw.Write([]string{stamp(ts), r.Address, vantageOf(r), r.Status,
+				strconv.Itoa(upValue(r)), latencyMs(r), errorText(r)})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	var b strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&b, "service_probe,service=%s,vantage=%s status=\"%s\",up=%di",
+			influxTag(r.Address), influxTag(vantageOf(r)), influxString(r.Status), upValue(r))
+		if r.Latency > 0 {
+			fmt.Fprintf(&b, ",latency_ms=%s", latencyMs(r))
+		}
+		if e := errorText(r); e != "" {
+			fmt.Fprintf(&b, ",error=\"%s\"", influxString(e))
+		}
+		fmt.Fprintf(&b, " %d\n", ts.UnixNano())
+	}
+	_, err := w.file.WriteString(b.String())
+	return err
+}
+
+func (w *seriesWriter) close() error { return w.file.Close() }
+
+func vantageOf(r ServiceCheckResult) string {
+	if r.Vantage == "" {
+		return "local"
+	}
+	return r.Vantage
+}
+
+// upValue counts a service as available when it answered, even if slow.
+func upValue(r ServiceCheckResult) int {
+	if r.Status == "UP" || r.Status == "DEGRADED" {
+		return 1
+	}
+	return 0
+}
+
+func latencyMs(r ServiceCheckResult) string {
+	if r.Latency == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(r.Latency)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+func errorText(r ServiceCheckResult) string {
+	if r.Error == nil {
+		return ""
+	}
+	return r.Error.Error()
+}
+
+// influxTag escapes commas, spaces and equals signs in tag values.
+func influxTag(s string) string {
+	return strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`).Replace(s)
+}
+
+// influxString escapes a string field value.
+func influxString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s)
+}
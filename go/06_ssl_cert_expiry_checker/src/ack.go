@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ackRule silences alerts for matching targets until a date.
+type ackRule struct {
+	Pattern string    // Glob matched against the hostname, optionally with :port
+	Until   time.Time // Start of the day after the given date, local time
+	Reason  string
+}
+
+// loadAcks reads an acknowledgment file. Each non-comment line is
+// "<host pattern>[:port] <until YYYY-MM-DD> [reason]", e.g.
+//
+//	www.example.com      2026-11-15  renewal in progress, CHG-1234
+//	*.corp.example:8443  2026-11-01
+//
+// The acknowledgment covers the whole of its until date.
+func loadAcks(filePath string) ([]ackRule, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open acknowledgment file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var rules []ackRule
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("acknowledgment file %s line %d: expected '<host pattern> <until YYYY-MM-DD> [reason]'", filePath, lineNo)
+		}
+		pattern := strings.ToLower(fields[0])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("acknowledgment file %s line %d: invalid pattern %q: %w", filePath, lineNo, fields[0], err)
+		}
+		until, err := time.ParseInLocation("2006-01-02", fields[1], time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("acknowledgment file %s line %d: invalid date %q (expected YYYY-MM-DD)", filePath, lineNo, fields[1])
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+		reason := strings.TrimSpace(strings.TrimPrefix(rest, fields[1]))
+		rules = append(rules, ackRule{Pattern: pattern, Until: until.AddDate(0, 0, 1), Reason: reason})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading acknowledgment file %s: %w", filePath, err)
+	}
+	return rules, nil
+}
+
+// matches reports whether the rule's pattern covers target (host:port). A
+// pattern without a port covers every port of the host.
+func (a ackRule) matches(target string) bool {
+	hostname, port, err := net.SplitHostPort(target)
+	if err != nil {
+		hostname = target
+	}
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	if ok, _ := path.Match(a.Pattern, hostname); ok {
+		return true
+	}
+	ok, _ := path.Match(a.Pattern, strings.ToLower(net.JoinHostPort(hostname, port)))
+	return ok
+}
+
+// applyAcks marks results covered by an acknowledgment that is still in
+// effect at now. Acknowledged hosts are reported as usual but left out of
+// --notify alerts. Acknowledgments that have lapsed are warned about once,
+// so the file is kept tidy and nothing stays silenced by accident.
+func applyAcks(results []CertCheckResult, rules []ackRule, now time.Time) {
+	lapsed := map[int]bool{}
+	for i := range results {
+		for j, a := range rules {
+			if !a.matches(results[i].Host) {
+				continue
+			}
+			if !now.Before(a.Until) {
+				lapsed[j] = true
+				continue
+			}
+			note := "alerts silenced until " + a.Until.AddDate(0, 0, -1).Format("2006-01-02")
+			if a.Reason != "" {
+				note += " (" + a.Reason + ")"
+			}
+			results[i].Ack = note
+			break
+		}
+	}
+	for j := range rules {
+		if lapsed[j] {
+			warnf("Acknowledgment for %s lapsed on %s; alerts for it are no longer silenced.", rules[j].Pattern, rules[j].Until.AddDate(0, 0, -1).Format("2006-01-02"))
+		}
+	}
+}
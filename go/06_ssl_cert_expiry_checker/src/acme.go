@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// acmeIssuers maps issuer organization names of ACME certificate authorities
+// to renewal guidance shown for expiring certificates they issued.
+var acmeIssuers = []struct {
+	Name     string
+	Guidance string
+}{
+	{"Let's Encrypt", "Issued by Let's Encrypt (ACME, 90-day lifetime, normally renewed with 30 days left). Automatic renewal appears to have stalled: check the ACME client timer/cron (e.g. `certbot renew --dry-run`, `systemctl status certbot.timer`) and HTTP-01/DNS-01 challenge reachability."},
+	{"ZeroSSL", "Issued by ZeroSSL (ACME). Automatic renewal appears to have stalled: check the ACME client schedule (e.g. `acme.sh --cron`), that EAB credentials are still valid, and challenge reachability."},
+}
+
+// hookTimeout bounds how long a renewal hook may run per host.
+const hookTimeout = 2 * time.Minute
+
+// maxHookOutput caps the captured hook output included in the report.
+const maxHookOutput = 4096
+
+// acmeGuidance returns renewal guidance if the certificate was issued by a
+// known ACME CA, or "" otherwise.
+func acmeGuidance(result CertCheckResult) string {
+	if len(result.Chain) == 0 {
+		return ""
+	}
+	issuer := result.Chain[0].Issuer
+	candidates := append([]string{issuer.CommonName}, issuer.Organization...)
+	for _, ca := range acmeIssuers {
+		for _, c := range candidates {
+			if strings.Contains(strings.ToLower(c), strings.ToLower(ca.Name)) {
+				return ca.Guidance
+			}
+		}
+	}
+	return ""
+}
+
+// runRenewHook executes the user-supplied hook via the shell with details of
+// the expiring certificate in the environment, returning its combined output.
+func runRenewHook(command string, result CertCheckResult) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	hostname, port, _ := net.SplitHostPort(result.Host)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"CERT_HOST="+hostname,
+		"CERT_PORT="+port,
+		fmt.Sprintf("CERT_DAYS_LEFT=%d", result.DaysLeft),
+		"CERT_EXPIRY="+result.ExpiryDate.Format(time.RFC3339),
+		"CERT_ISSUER="+result.Chain[0].Issuer.String(),
+	)
+	out, err := cmd.CombinedOutput()
+	text := strings.TrimSpace(string(out))
+	if len(text) > maxHookOutput {
+		text = text[:maxHookOutput] + "\n[output truncated]"
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("renew hook timed out after %s", hookTimeout)
+	}
+	return text, err
+}
+
+// applyRenewalHints attaches ACME renewal guidance to expiring certificates
+// and, if a hook command is configured, runs it once per affected host.
+func applyRenewalHints(results []CertCheckResult, warnThreshold int, hook string) {
+	for i := range results {
+		r := &results[i]
+		if r.Error != nil || r.DaysLeft < 0 || r.DaysLeft > warnThreshold || len(r.Chain) == 0 {
+			continue
+		}
+		guidance := acmeGuidance(*r)
+		if guidance == "" {
+			continue
+		}
+		r.RenewalHint = guidance
+		if hook == "" {
+			continue
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Running renew hook for %s\n", r.Host)
+		}
+		out, err := runRenewHook(hook, *r)
+		r.HookOutput = out
+		if err != nil {
+			r.HookError = err
+		}
+	}
+}
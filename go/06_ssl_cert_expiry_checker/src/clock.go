@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// notBeforeWarnings flags certificates that are not yet valid, or became
+// valid so recently that clients with a slightly slow clock will reject them.
+func notBeforeWarnings(cert *x509.Certificate, now time.Time, recentWindow time.Duration) (notYetValid bool, warnings []string) {
+	if now.Before(cert.NotBefore) {
+		return true, []string{fmt.Sprintf("certificate is not valid until %s (%s from now)",
+			stamp(cert.NotBefore), cert.NotBefore.Sub(now).Round(time.Minute))}
+	}
+	if age := now.Sub(cert.NotBefore); recentWindow > 0 && age < recentWindow {
+		warnings = append(warnings, fmt.Sprintf("certificate became valid only %s ago (NotBefore %s); clients with slow clocks may reject it",
+			age.Round(time.Minute), stamp(cert.NotBefore)))
+	}
+	return false, warnings
+}
+
+// serverClockSkew sends a HEAD request over the established TLS connection and
+// compares the server's Date header with local time. A positive skew means the
+// server clock is ahead. Non-HTTP services return an error and are skipped.
+func serverClockSkew(conn *tls.Conn, hostname string, timeout time.Duration) (time.Duration, error) {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodHead, "https://"+hostname+"/", nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Close = true
+	sent := time.Now()
+	if err := req.Write(conn); err != nil {
+		return 0, fmt.Errorf("failed to send HEAD request: %w", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return 0, fmt.Errorf("no HTTP response: %w", err)
+	}
+	resp.Body.Close()
+	received := time.Now()
+
+	date := resp.Header.Get("Date")
+	if date == "" {
+		return 0, fmt.Errorf("response has no Date header")
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Date header %q: %w", date, err)
+	}
+	// Compare against the midpoint of the round trip; Date has 1s resolution.
+	local := sent.Add(received.Sub(sent) / 2)
+	return serverTime.Sub(local).Truncate(time.Second), nil
+}
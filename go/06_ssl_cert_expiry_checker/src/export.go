@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// certFingerprint returns the lower-case hex SHA-256 fingerprint of a certificate.
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// exportFileName builds "<host>_<port>_<fingerprint prefix>.pem", replacing
+// characters that are unsafe in file names (e.g. IPv6 colons and brackets).
+func exportFileName(hostPort string, leaf *x509.Certificate) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, hostPort)
+	return fmt.Sprintf("%s_%s.pem", safe, certFingerprint(leaf)[:16])
+}
+
+// exportChains writes each host's presented chain (leaf first) to its own PEM
+// file in dir, creating the directory if needed. Returns the number of files written.
+func exportChains(results []CertCheckResult, dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create export directory %s: %w", dir, err)
+	}
+	written := 0
+	for _, result := range results {
+		if len(result.Chain) == 0 {
+			continue
+		}
+		var buf []byte
+		for _, cert := range result.Chain {
+			buf = append(buf, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+		}
+		path := filepath.Join(dir, exportFileName(result.Host, result.Chain[0]))
+		if err := os.WriteFile(path, buf, 0644); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Exported %d certificate(s) for %s to %s\n", len(result.Chain), result.Host, path)
+		}
+		written++
+	}
+	return written, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Normalized findings shared by the scanners and audit tools. Each tool keeps
+// its own findings and report, and converts them to this model for
+// --findings: one JSON object per line, with the same severity scale and
+// fields in every tool, so the exports of several tools can be concatenated,
+// sorted and thresholded together (e.g. with jq).
+//
+// Severity is one of info, low, medium, high or critical. Score is an
+// optional 0.0-10.0 number from a CVSS-lite vector: the CVSS v3.1 base score
+// with attack complexity low, no user interaction and scope unchanged
+// assumed, so only AV, PR, C, I and A are given, e.g. "AV:N/PR:N/C:H/I:N/A:N".
+//
+// Every tool that exports findings carries an identical copy of this file
+// (scripts/check_shared_go.py).
+
+var (
+	findingsPath string
+	findingsMin  string
+)
+
+func registerFindingsFlags() {
+	flag.StringVar(&findingsPath, "findings", "", "Also export every finding in the normalized cross-tool model (NDJSON: tool, target, category, title, severity, score) to this destination.")
+	flag.StringVar(&findingsMin, "findings-min", "info", "Only export findings of at least this severity: info, low, medium, high or critical.")
+}
+
+var normSeverityNames = []string{"info", "low", "medium", "high", "critical"}
+
+// normSeverityRank orders normalized severities from info (0) to critical (4);
+// unknown names rank -1.
+func normSeverityRank(name string) int {
+	for i, n := range normSeverityNames {
+		if strings.EqualFold(name, n) {
+			return i
+		}
+	}
+	return -1
+}
+
+// normFinding is one finding in the normalized model.
+type normFinding struct {
+	Tool     string  `json:"tool"`
+	Target   string  `json:"target"`
+	Category string  `json:"category"`
+	Title    string  `json:"title"`
+	Severity string  `json:"severity"`
+	Score    float64 `json:"score,omitempty"`
+	Vector   string  `json:"vector,omitempty"` // CVSS-lite vector the score came from
+	Detail   string  `json:"detail,omitempty"`
+}
+
+// withVector scores f from a CVSS-lite vector. Vectors come from the tools'
+// rule tables, or are checked with cvssLiteScore when they are read (as in
+// benchmark files); an invalid one leaves f unscored.
+func (f normFinding) withVector(vector string) normFinding {
+	if score, err := cvssLiteScore(vector); err == nil {
+		f.Score, f.Vector = score, vector
+	}
+	return f
+}
+
+// cvssLiteWeights are the CVSS v3.1 metric weights for the metrics a
+// CVSS-lite vector carries.
+var cvssLiteWeights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvssLiteScore computes the base score of a CVSS-lite vector, rounded up to
+// one decimal as CVSS does.
+func cvssLiteScore(vector string) (float64, error) {
+	m := map[string]float64{}
+	for _, part := range strings.Split(vector, "/") {
+		key, value, _ := strings.Cut(part, ":")
+		w, ok := cvssLiteWeights[key][value]
+		if !ok {
+			return 0, fmt.Errorf("invalid CVSS-lite metric %q in %q", part, vector)
+		}
+		m[key] = w
+	}
+	if len(m) != len(cvssLiteWeights) {
+		return 0, fmt.Errorf("CVSS-lite vector %q must give AV, PR, C, I and A", vector)
+	}
+	iss := 1 - (1-m["C"])*(1-m["I"])*(1-m["A"])
+	impact := 6.42 * iss
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * m["AV"] * 0.77 * m["PR"] * 0.85 // AC:L, UI:N
+	return math.Ceil(math.Min(impact+exploitability, 10)*10) / 10, nil
+}
+
+// exportFindings writes findings at or above --findings-min, most severe
+// first, to the --findings destination.
+func exportFindings(findings []normFinding) error {
+	if findingsPath == "" {
+		return nil
+	}
+	min := normSeverityRank(findingsMin)
+	var kept []normFinding
+	for _, f := range findings {
+		if normSeverityRank(f.Severity) >= min {
+			kept = append(kept, f)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		a, b := kept[i], kept[j]
+		if ra, rb := normSeverityRank(a.Severity), normSeverityRank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Target < b.Target
+	})
+	out, err := openSink(findingsPath)
+	if err != nil {
+		return fmt.Errorf("failed to open findings export %s: %w", findingsPath, err)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	for _, f := range kept {
+		enc.Encode(f)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write findings export %s: %w", findingsPath, err)
+	}
+	return nil
+}
+
+// checkFindingsMin validates --findings-min.
+func checkFindingsMin() error {
+	if normSeverityRank(findingsMin) < 0 {
+		return fmt.Errorf("unknown severity %q (expected one of %s)", findingsMin, strings.Join(normSeverityNames, ", "))
+	}
+	return nil
+}
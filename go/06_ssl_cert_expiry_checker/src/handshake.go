@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sessionCache is shared by every connection of a --debug-handshake run, so
+// that a later connection to a name already seen can report resumption.
+var sessionCache = tls.NewLRUClientSessionCache(64)
+
+// chainEntry summarizes one presented certificate.
+type chainEntry struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	Key       string    `json:"key"`
+	Signature string    `json:"signature"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// handshakeDiag is the --debug-handshake transcript for one host: what was
+// negotiated, or how far the connection got and why it failed.
+type handshakeDiag struct {
+	RemoteAddr  string       `json:"remote_addr,omitempty"`
+	ConnectMs   int64        `json:"connect_ms"`
+	HandshakeMs int64        `json:"handshake_ms,omitempty"`
+	ServerName  string       `json:"server_name,omitempty"`
+	Version     string       `json:"version,omitempty"`
+	CipherSuite string       `json:"cipher_suite,omitempty"`
+	Curve       string       `json:"curve,omitempty"`
+	Resumed     bool         `json:"resumed"`
+	OCSPStapled bool         `json:"ocsp_stapled"`
+	Chain       []chainEntry `json:"chain,omitempty"`
+	FailedPhase string       `json:"failed_phase,omitempty"`
+	Failure     string       `json:"failure,omitempty"`
+}
+
+// dialTLS connects and handshakes with target in two steps so that a failure
+// can be attributed to the TCP connect or the TLS handshake. The returned
+// diagnostics are always filled in, whether or not the handshake succeeded.
+func dialTLS(ctx context.Context, target string, timeout time.Duration) (*tls.Conn, *handshakeDiag, error) {
+	diag := &handshakeDiag{}
+	hostname, _, _ := net.SplitHostPort(target)
+	cfg := &tls.Config{
+		InsecureSkipVerify: true, // Not secure, but simplifies demo and avoids cert chain issues
+	}
+	if addr, _, _ := strings.Cut(hostname, "%"); net.ParseIP(addr) == nil { // No SNI for IP literals
+		cfg.ServerName = hostname
+	}
+	if dumpHandshake {
+		cfg.ClientSessionCache = sessionCache
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	raw, err := (&net.Dialer{Resolver: dnsResolver}).DialContext(ctx, "tcp", target)
+	diag.ConnectMs = time.Since(start).Milliseconds()
+	if err != nil {
+		diag.FailedPhase = "TCP connect"
+		diag.Failure = explainDialError(err, timeout)
+		return nil, diag, err
+	}
+	diag.RemoteAddr = raw.RemoteAddr().String()
+	if _, port, _ := net.SplitHostPort(target); isSMTPPort(port) {
+		deadline, _ := ctx.Deadline()
+		if err := startTLS(raw, deadline); err != nil {
+			raw.Close()
+			diag.FailedPhase = "SMTP STARTTLS"
+			diag.Failure = explainDialError(err, timeout)
+			return nil, diag, fmt.Errorf("SMTP STARTTLS: %w", err)
+		}
+	}
+
+	conn := tls.Client(raw, cfg)
+	start = time.Now()
+	err = conn.HandshakeContext(ctx)
+	diag.HandshakeMs = time.Since(start).Milliseconds()
+	if err != nil {
+		raw.Close()
+		diag.FailedPhase = "TLS handshake"
+		diag.Failure = explainDialError(err, timeout)
+		return nil, diag, err
+	}
+
+	state := conn.ConnectionState()
+	diag.ServerName = state.ServerName
+	diag.Version = tls.VersionName(state.Version)
+	diag.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	if state.CurveID != 0 {
+		diag.Curve = state.CurveID.String()
+	} else {
+		diag.Curve = "none (RSA key exchange)"
+	}
+	diag.Resumed = state.DidResume
+	diag.OCSPStapled = len(state.OCSPResponse) > 0
+	for _, c := range state.PeerCertificates {
+		diag.Chain = append(diag.Chain, chainEntry{
+			Subject:   c.Subject.String(),
+			Issuer:    c.Issuer.String(),
+			Key:       keyDescription(c),
+			Signature: c.SignatureAlgorithm.String(),
+			NotAfter:  c.NotAfter.In(reportTZ),
+		})
+	}
+	return conn, diag, nil
+}
+
+// keyDescription names a certificate's public key type and size.
+func keyDescription(c *x509.Certificate) string {
+	switch k := c.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return c.PublicKeyAlgorithm.String()
+}
+
+// explainDialError turns a connect or handshake error into the most likely
+// cause, keeping the original message.
+func explainDialError(err error, timeout time.Duration) string {
+	var recErr tls.RecordHeaderError
+	var opErr *net.OpError
+	var ne net.Error
+	switch {
+	case errors.As(err, &recErr):
+		return fmt.Sprintf("server did not answer with TLS (first bytes %q); the port may serve plain text or another protocol", recErr.RecordHeader[:])
+	case errors.As(err, &opErr) && opErr.Op == "remote error":
+		return fmt.Sprintf("server aborted the handshake with alert %q; common causes are an unknown SNI name, no shared protocol version or cipher suite, or a required client certificate", strings.TrimPrefix(opErr.Err.Error(), "tls: "))
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()):
+		return fmt.Sprintf("no response within %s: %v", timeout, err)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused; nothing is listening on this port"
+	case errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET):
+		return fmt.Sprintf("connection closed during the handshake (%v); often a server or middlebox rejecting the SNI name or protocol version", err)
+	}
+	return err.Error()
+}
+
+// logHandshake writes a host's handshake transcript to stderr.
+func logHandshake(target string, d *handshakeDiag) {
+	if quietMode || d == nil {
+		return
+	}
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "[DEBUG] %s: "+format+"\n", append([]interface{}{target}, args...)...)
+	}
+	if d.FailedPhase == "TCP connect" {
+		p("TCP connect failed after %dms: %s", d.ConnectMs, d.Failure)
+		return
+	}
+	p("connected to %s in %dms", d.RemoteAddr, d.ConnectMs)
+	if d.FailedPhase != "" {
+		p("%s failed after %dms: %s", d.FailedPhase, d.HandshakeMs, d.Failure)
+		return
+	}
+	sni := d.ServerName
+	if sni == "" {
+		sni = "(none, IP address target)"
+	}
+	p("handshake %dms: %s, %s, key exchange %s, resumed=%t, SNI %s, OCSP stapled=%t",
+		d.HandshakeMs, d.Version, d.CipherSuite, d.Curve, d.Resumed, sni, d.OCSPStapled)
+	for i, c := range d.Chain {
+		p("  chain[%d] %s (%s, %s, expires %s) issued by %s", i, c.Subject, c.Key, c.Signature, stamp(c.NotAfter), c.Issuer)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// certObservation records one distinct certificate seen on a host.
+type certObservation struct {
+	Fingerprint string    `json:"fingerprint"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// certDB is the on-disk state store: every certificate observed per host:port,
+// in first-seen order. It is a plain JSON file so it needs no database engine.
+type certDB struct {
+	Version int                          `json:"version"`
+	Hosts   map[string][]certObservation `json:"hosts"`
+}
+
+// loadCertDB reads the state store, returning an empty one if it does not exist yet.
+func loadCertDB(path string) (*certDB, error) {
+	db := &certDB{Version: 1, Hosts: map[string][]certObservation{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate database %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, db); err != nil {
+		return nil, fmt.Errorf("failed to parse certificate database %s: %w", path, err)
+	}
+	if db.Hosts == nil {
+		db.Hosts = map[string][]certObservation{}
+	}
+	return db, nil
+}
+
+// save writes the store atomically (temp file + rename) so an interrupted run
+// never leaves a truncated database behind.
+func (db *certDB) save(path string) error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".certdb-*")
+	if err != nil {
+		return fmt.Errorf("failed to write certificate database: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write certificate database: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// record updates the store with the leaf certificate from each successful
+// check. When a host presents a new certificate sooner than minRotation after
+// the previous one was first seen, a rotation note is attached to the result.
+func (db *certDB) record(results []CertCheckResult, now time.Time, minRotation time.Duration) {
+	for i := range results {
+		r := &results[i]
+		if len(r.Chain) == 0 {
+			continue
+		}
+		leaf := r.Chain[0]
+		fp := certFingerprint(leaf)
+		history := db.Hosts[r.Host]
+
+		found := false
+		for j := range history {
+			if history[j].Fingerprint == fp {
+				history[j].LastSeen = now
+				found = true
+				break
+			}
+		}
+		if !found {
+			if n := len(history); n > 0 {
+				prev := history[n-1]
+				if age := now.Sub(prev.FirstSeen); age < minRotation {
+					r.RotationNote = fmt.Sprintf("certificate changed %s after the previous one was first seen (previous fingerprint %s...)",
+						age.Round(time.Hour), prev.Fingerprint[:16])
+				}
+			}
+			history = append(history, certObservation{
+				Fingerprint: fp,
+				Subject:     leaf.Subject.String(),
+				Issuer:      leaf.Issuer.String(),
+				NotBefore:   leaf.NotBefore,
+				NotAfter:    leaf.NotAfter,
+				FirstSeen:   now,
+				LastSeen:    now,
+			})
+		}
+		db.Hosts[r.Host] = history
+	}
+}
+
+// writeHistory prints every certificate observed for a host, oldest first,
+// flagging rotations that happened faster than minRotation.
+func writeHistory(db *certDB, hostPort string, minRotation time.Duration, output io.Writer) {
+	fmt.Fprintf(output, "--- Certificate History: %s ---\n\n", hostPort)
+	history := db.Hosts[hostPort]
+	if len(history) == 0 {
+		fmt.Fprintln(output, "No certificates recorded for this host.")
+		return
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].FirstSeen.Before(history[j].FirstSeen) })
+
+	frequent := 0
+	for i, obs := range history {
+		fmt.Fprintf(output, "Fingerprint: %s\n", obs.Fingerprint)
+		fmt.Fprintf(output, "Subject: %s\n", obs.Subject)
+		fmt.Fprintf(output, "Issuer: %s\n", obs.Issuer)
+		fmt.Fprintf(output, "Validity: %s to %s\n", stamp(obs.NotBefore), stamp(obs.NotAfter))
+		fmt.Fprintf(output, "First Seen: %s\n", stamp(obs.FirstSeen))
+		fmt.Fprintf(output, "Last Seen: %s\n", stamp(obs.LastSeen))
+		if i > 0 {
+			gap := obs.FirstSeen.Sub(history[i-1].FirstSeen)
+			fmt.Fprintf(output, "Replaced Previous After: %s\n", gap.Round(time.Hour))
+			if gap < minRotation {
+				fmt.Fprintln(output, "Warning: FREQUENT ROTATION")
+				frequent++
+			}
+		}
+		fmt.Fprintln(output, "------------------------------")
+	}
+	fmt.Fprintf(output, "\nDistinct certificates: %d, frequent rotations: %d\n", len(history), frequent)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// issuerRule maps a hostname pattern to the issuers allowed to sign its certificate.
+type issuerRule struct {
+	Pattern string   // Glob matched against the hostname, e.g. *.corp.example
+	Issuers []string // Accepted issuers; any one matching is sufficient
+}
+
+// loadIssuerPolicy reads an expected-issuer policy file. Each non-comment line
+// is "<host pattern> <expected issuer>[|<alternative issuer>...]", e.g.
+//
+//	*.corp.example   Corp Internal CA
+//	www.example.com  Let's Encrypt|DigiCert
+func loadIssuerPolicy(filePath string) ([]issuerRule, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open issuer policy %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var rules []issuerRule
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("issuer policy %s line %d: expected '<host pattern> <issuer>'", filePath, lineNo)
+		}
+		pattern := strings.ToLower(fields[0])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("issuer policy %s line %d: invalid pattern %q: %w", filePath, lineNo, fields[0], err)
+		}
+		rule := issuerRule{Pattern: pattern}
+		for _, issuer := range strings.Split(strings.TrimSpace(strings.TrimPrefix(line, fields[0])), "|") {
+			if issuer = strings.TrimSpace(issuer); issuer != "" {
+				rule.Issuers = append(rule.Issuers, issuer)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading issuer policy %s: %w", filePath, err)
+	}
+	return rules, nil
+}
+
+// checkIssuerPolicy returns a description of the violation if the first rule
+// matching hostname does not accept the certificate's issuer, or "" if the
+// certificate complies (or no rule applies). Issuers match case-insensitively
+// against any part of the issuer distinguished name.
+func checkIssuerPolicy(rules []issuerRule, hostname string, cert *x509.Certificate) string {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	issuerDN := strings.ToLower(cert.Issuer.String())
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.Pattern, hostname); !ok {
+			continue
+		}
+		for _, expected := range rule.Issuers {
+			if strings.Contains(issuerDN, strings.ToLower(expected)) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("expected issuer %q for %s, got %q", strings.Join(rule.Issuers, " | "), rule.Pattern, cert.Issuer.String())
+	}
+	return ""
+}
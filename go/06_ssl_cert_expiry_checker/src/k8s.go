@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadK8sTargets extracts TLS endpoints from a kubectl dump (JSON or YAML,
+// e.g. `kubectl get ingress,svc -A -o yaml`). Ingress resources contribute
+// every host listed under spec.tls on port 443; LoadBalancer Services
+// contribute their external addresses on ports that look like TLS (443 or a
+// port named https/tls).
+func loadK8sTargets(filePath string) ([]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("[ERROR] Failed to read Kubernetes dump %s: %w", filePath, err)
+	}
+
+	var doc interface{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		err = json.Unmarshal(trimmed, &doc)
+	} else {
+		doc, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[ERROR] Failed to parse Kubernetes dump %s: %w", filePath, err)
+	}
+
+	seen := map[string]bool{}
+	var targets []string
+	add := func(hostPort, source string) {
+		if !seen[hostPort] {
+			seen[hostPort] = true
+			targets = append(targets, hostPort)
+			if verboseMode {
+				fmt.Fprintf(os.Stderr, "[INFO] Kubernetes target %s (from %s)\n", hostPort, source)
+			}
+		}
+	}
+
+	for _, obj := range k8sObjects(doc) {
+		kind := k8sString(obj["kind"])
+		meta, _ := obj["metadata"].(map[string]interface{})
+		source := kind + " " + k8sString(meta["namespace"]) + "/" + k8sString(meta["name"])
+		spec, _ := obj["spec"].(map[string]interface{})
+
+		switch kind {
+		case "Ingress":
+			for _, t := range k8sList(spec["tls"]) {
+				tls, _ := t.(map[string]interface{})
+				for _, h := range k8sList(tls["hosts"]) {
+					if host := k8sString(h); host != "" && !strings.Contains(host, "*") {
+						add(net.JoinHostPort(host, "443"), source)
+					}
+				}
+			}
+		case "Service":
+			if k8sString(spec["type"]) != "LoadBalancer" {
+				continue
+			}
+			var ports []string
+			for _, p := range k8sList(spec["ports"]) {
+				pm, _ := p.(map[string]interface{})
+				port, name := k8sString(pm["port"]), strings.ToLower(k8sString(pm["name"]))
+				if port == "443" || strings.Contains(name, "https") || strings.Contains(name, "tls") {
+					ports = append(ports, port)
+				}
+			}
+			status, _ := obj["status"].(map[string]interface{})
+			lb, _ := status["loadBalancer"].(map[string]interface{})
+			for _, ing := range k8sList(lb["ingress"]) {
+				im, _ := ing.(map[string]interface{})
+				addr := k8sString(im["hostname"])
+				if addr == "" {
+					addr = k8sString(im["ip"])
+				}
+				for _, port := range ports {
+					if addr != "" {
+						add(net.JoinHostPort(addr, port), source)
+					}
+				}
+			}
+		}
+	}
+	return targets, nil
+}
+
+// k8sObjects flattens List resources, multi-document streams and plain
+// arrays into the individual resource objects.
+func k8sObjects(doc interface{}) []map[string]interface{} {
+	var objs []map[string]interface{}
+	switch v := doc.(type) {
+	case []interface{}:
+		for _, item := range v {
+			objs = append(objs, k8sObjects(item)...)
+		}
+	case map[string]interface{}:
+		if items, ok := v["items"].([]interface{}); ok {
+			return k8sObjects(items)
+		}
+		objs = append(objs, v)
+	}
+	return objs
+}
+
+func k8sList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+// k8sString renders scalars from either decoder (YAML strings, JSON numbers).
+func k8sString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// labelSet holds the key=value labels given to a target in the input file,
+// e.g. "shop.example.com,team=web,env=prod".
+type labelSet map[string]string
+
+// hostLabels maps each host:port target from the input file to its labels.
+var hostLabels = map[string]labelSet{}
+
+// unlabeled is the --by-label row for hosts without the label.
+const unlabeled = "(unlabeled)"
+
+// splitLabels separates the labels from the target in an input line. Port
+// lists ("host:443,8443") stay with the target; only fields with "=" are
+// labels.
+func splitLabels(line string) (string, labelSet, error) {
+	var target []string
+	var labels labelSet
+	for _, f := range strings.Split(line, ",") {
+		f = strings.TrimSpace(f)
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			target = append(target, f)
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if key == "" || value == "" || strings.ContainsAny(key, " \t") {
+			return "", nil, fmt.Errorf("invalid label %q (expected key=value)", f)
+		}
+		if labels == nil {
+			labels = labelSet{}
+		}
+		labels[key] = value
+	}
+	return strings.Join(target, ","), labels, nil
+}
+
+// String lists the labels sorted by key.
+func (l labelSet) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + l[k]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// labelSummary is one row of the --by-label table: the hosts sharing a
+// label value and the soonest of their expiries.
+type labelSummary struct {
+	Value     string     `json:"value"`
+	Hosts     int        `json:"hosts"`
+	NotValid  int        `json:"not_valid"`
+	Earliest  *time.Time `json:"earliest_expiry,omitempty"`
+	DaysLeft  *int       `json:"days_left,omitempty"`
+	SLABreach *time.Time `json:"sla_breach,omitempty"`
+	SLAMissed bool       `json:"sla_missed"`
+	Soonest   string     `json:"soonest_host,omitempty"`
+}
+
+// summarizeByLabel groups results by the value of the label key. The
+// renewal SLA is breached slaDays before the earliest expiry in a group,
+// when the certificate should already have been replaced. Rows are ordered
+// by earliest expiry; groups without a known expiry come last.
+func summarizeByLabel(results []CertCheckResult, key string, slaDays int, now time.Time) []labelSummary {
+	rows := map[string]*labelSummary{}
+	var values []string
+	for _, r := range results {
+		value, ok := r.Labels[key]
+		if !ok {
+			value = unlabeled
+		}
+		row := rows[value]
+		if row == nil {
+			row = &labelSummary{Value: value}
+			rows[value] = row
+			values = append(values, value)
+		}
+		row.Hosts++
+		if r.Status != "VALID" {
+			row.NotValid++
+		}
+		if r.ExpiryDate.IsZero() {
+			continue
+		}
+		if row.Earliest == nil || r.ExpiryDate.Before(*row.Earliest) {
+			expiry, days := r.ExpiryDate.In(reportTZ), r.DaysLeft
+			breach := expiry.AddDate(0, 0, -slaDays)
+			row.Earliest, row.DaysLeft, row.SLABreach, row.Soonest = &expiry, &days, &breach, r.Host
+			row.SLAMissed = !breach.After(now)
+		}
+	}
+	out := make([]labelSummary, 0, len(values))
+	for _, v := range values {
+		out = append(out, *rows[v])
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if (a.Earliest == nil) != (b.Earliest == nil) {
+			return b.Earliest == nil
+		}
+		if a.Earliest != nil && !a.Earliest.Equal(*b.Earliest) {
+			return a.Earliest.Before(*b.Earliest)
+		}
+		return a.Value < b.Value
+	})
+	return out
+}
+
+// writeLabelSummary prints the --by-label table.
+func writeLabelSummary(rows []labelSummary, key string, slaDays int, output io.Writer) {
+	fmt.Fprintf(output, "\n--- Expiry by %s (renewal SLA: %d days before expiry) ---\n\n", key, slaDays)
+	fmt.Fprintf(output, "%-20s %5s %9s %-25s %9s %-30s  %s\n", key, "Hosts", "Not Valid", "Earliest Expiry", "Days Left", "SLA Breach", "Soonest Host")
+	for _, r := range rows {
+		if r.Earliest == nil {
+			fmt.Fprintf(output, "%-20s %5d %9d %-25s %9s %-30s  %s\n", r.Value, r.Hosts, r.NotValid, "N/A", "N/A", "N/A", "-")
+			continue
+		}
+		breach := stamp(*r.SLABreach)
+		if r.SLAMissed {
+			breach += " (missed)"
+		}
+		fmt.Fprintf(output, "%-20s %5d %9d %-25s %9d %-30s  %s\n", r.Value, r.Hosts, r.NotValid, stamp(*r.Earliest), *r.DaysLeft, breach, r.Soonest)
+	}
+	fmt.Fprintln(output, "------------------------------")
+}
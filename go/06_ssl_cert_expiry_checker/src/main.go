@@ -5,41 +5,99 @@ SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
 
 CONTEXT: This code is a frozen demonstration of an SSL/TLS Certificate Expiry Checker.
 PURPOSE: Show skill in network programming (TLS), certificate handling, and CLI utility development in Go.
-CONSTRAINTS: Uses standard library only, designed for CLI, <=300 lines (intentional).
+CONSTRAINTS: Uses standard library only, designed for CLI.
 STATUS: Complete demonstration - no updates planned.
 EVALUATION: Assess what this demonstrates, not production readiness.
 */
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 )
 
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "ssl_cert_expiry_checker"
+	toolVersion = "1.0.2"
+)
+
 // Global variables for CLI flags
 var (
-	host        string
-	port        string
-	inputFile   string
-	outputFile  string
-	timeoutSec  int
-	warnDays    int
-	verboseMode bool
+	host          string
+	port          string
+	portList      string
+	inputFile     string
+	outputFile    string
+	timeoutSec    int
+	warnDays      int
+	verboseMode   bool
+	exportDir     string
+	policyFile    string
+	issuerRules   []issuerRule
+	caBundle      string
+	caRoots       *x509.CertPool
+	renewHook     string
+	dbPath        string
+	historyHost   string
+	minRotDays    int
+	k8sFile       string
+	mxDomain      string
+	resolverAddr  string
+	recentHours   int
+	checkClock    bool
+	maxSkewSec    int
+	probeQUIC     bool
+	summaryView   bool
+	notifyTargets notifyList
+	notifyTmpl    string
+	ackFile       string
+	format        string
+	dumpHandshake bool
+	sortKey       string
+	groupBy       string
+	byLabel       string
+	failFast      bool
 )
 
 // CertCheckResult stores the result of a single certificate check
 type CertCheckResult struct {
-	Host        string
-	ExpiryDate  time.Time
-	DaysLeft    int
-	Status      string
-	Error       error
+	Host       string
+	ExpiryDate time.Time
+	DaysLeft   int
+	Status     string
+	Error      error
+	Chain      []*x509.Certificate // Certificates as presented by the server, leaf first
+	// PolicyViolation describes an issuer that does not match the expected-issuer policy
+	PolicyViolation string
+	// UsageViolation is set when the key usage or EKU does not allow TLS server authentication
+	UsageViolation string
+	// TrustError is why the chain failed verification against --ca-bundle
+	TrustError string
+	// RenewalHint is ACME renewal guidance for expiring Let's Encrypt/ZeroSSL certificates
+	RenewalHint string
+	HookOutput  string // Captured output of --renew-hook
+	HookError   error
+	// RotationNote is set when --db shows the certificate was replaced unusually soon
+	RotationNote string
+	Warnings     []string // NotBefore and clock-skew observations
+	QUICNote     string   // Outcome of the --quic certificate comparison
+	Ack          string   // Set when --ack silences alerts for this host
+	// Diagnostics is the handshake transcript, recorded with --debug-handshake
+	Diagnostics *handshakeDiag
+	Labels      labelSet // From the input file (host,team=web,env=prod)
 }
 
 func init() {
@@ -50,10 +108,16 @@ func init() {
 	flag.StringVar(&port, "port", "443", "Port number for SSL/TLS connection.")
 	flag.StringVar(&port, "p", "443", "Port number for SSL/TLS connection (shorthand).")
 
-	flag.StringVar(&inputFile, "input", "", "Path to a file containing hosts to check (one host:port or host per line). Overrides -host if provided.")
+	flag.StringVar(&portList, "ports", "", "Comma-separated ports to check on every host that does not name its own (e.g. 443,8443,993); replaces -p. Targets may also list ports as host:443,8443.")
+
+	flag.StringVar(&inputFile, "input", "", "Path to a file containing hosts to check (one host:port or host per line, optionally followed by labels: host,team=web,env=prod), or - to read them from stdin. Overrides -host if provided.")
 	flag.StringVar(&inputFile, "i", "", "Path to a file containing hosts to check (shorthand).")
 
-	flag.StringVar(&outputFile, "output", "", "Path to save the report. If not provided, prints to stdout.")
+	flag.StringVar(&k8sFile, "k8s", "", "Path to a kubectl JSON/YAML dump of Ingress/Service resources; TLS endpoints are extracted as targets.")
+	flag.StringVar(&mxDomain, "mx", "", "Domain whose MX records are resolved; each mail exchanger's certificate is checked on port 25 via SMTP STARTTLS.")
+	flag.StringVar(&resolverAddr, "resolver", "", "DNS server (host[:port]) to use for --mx and for resolving targets, instead of the system resolver.")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
 	flag.StringVar(&outputFile, "o", "", "Path to save the report (shorthand).")
 
 	flag.IntVar(&timeoutSec, "timeout", 5, "Connection timeout in seconds.")
@@ -62,36 +126,86 @@ func init() {
 	flag.IntVar(&warnDays, "warn-days", 30, "Number of days before expiry to issue a warning.")
 	flag.IntVar(&warnDays, "w", 30, "Number of days before expiry to issue a warning (shorthand).")
 
+	flag.StringVar(&policyFile, "issuer-policy", "", "Path to an expected-issuer policy file ('<host pattern> <issuer>' per line); mismatches are reported as ISSUER_POLICY_VIOLATION.")
+
+	flag.StringVar(&caBundle, "ca-bundle", "", "PEM file of trusted root CAs; each chain is fully verified against them (chain, validity, hostname) and failures are reported as UNTRUSTED.")
+
+	flag.StringVar(&renewHook, "renew-hook", "", "Shell command run for each expiring ACME (Let's Encrypt/ZeroSSL) certificate; CERT_HOST, CERT_PORT, CERT_DAYS_LEFT, CERT_EXPIRY and CERT_ISSUER are set in its environment.")
+
+	flag.StringVar(&dbPath, "db", "", "Path to a certificate history database (JSON) recording every observed certificate per host.")
+	flag.StringVar(&historyHost, "history", "", "Print the recorded certificate history for host[:port] from --db and exit.")
+	flag.IntVar(&minRotDays, "min-rotation-days", 7, "Flag certificate rotations that happen sooner than this many days after the previous one (with --db).")
+
+	flag.IntVar(&recentHours, "recent-hours", 24, "Warn when a certificate's NotBefore is less than this many hours ago (0 disables).")
+	flag.BoolVar(&checkClock, "check-clock", false, "Send a HEAD request over each TLS connection and compare the server Date header with local time.")
+	flag.IntVar(&maxSkewSec, "max-skew", 120, "Clock skew in seconds above which --check-clock reports a warning.")
+
+	flag.BoolVar(&probeQUIC, "quic", false, "Also retrieve the certificate over QUIC (UDP, same port, ALPN h3) and flag hosts whose QUIC and TCP certificates differ.")
+
+	flag.StringVar(&sortKey, "sort", "target", "Report order: target (as given), host, days-left (soonest expiry first) or status (most urgent first).")
+	flag.StringVar(&groupBy, "group-by", "none", "Text report layout: none, or status (one section per status, most urgent first).")
+	flag.StringVar(&byLabel, "by-label", "", "Add a table per value of this input-file label (e.g. team): host count, earliest expiry and the date the renewal SLA (--warn-days before expiry) is breached.")
+
+	flag.BoolVar(&summaryView, "summary", false, "Print a fleet summary (host counts per expiry bucket and the soonest-expiring hosts) instead of the per-host report.")
+
+	flag.Var(&notifyTargets, "notify", "Send an alert listing certificates that are not VALID: a webhook URL, slack:<url>, teams:<url> or smtp://[user@]host:port?from=..&to=.. (repeatable).")
+	flag.StringVar(&ackFile, "ack", "", "Acknowledgment file ('<host pattern>[:port] <until YYYY-MM-DD> [reason]' per line); matching hosts stay in the report but are left out of --notify alerts until that date.")
+	flag.StringVar(&notifyTmpl, "notify-template", "", "Path to a Go text/template for alert messages (fields: .Tool .Hostname .Time .Summary .Items[].Target/.Status/.Detail).")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json (host results, with handshake diagnostics under \"diagnostics\" when --debug-handshake is set).")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.BoolVar(&dumpHandshake, "debug-handshake", false, "Log each host's handshake to stderr (TLS version, cipher suite, key exchange curve, resumption, chain summary, or where and why it failed).")
+
+	flag.BoolVar(&failFast, "fail-fast", false, "Stop at the first EXPIRED or ERROR result, report what was checked so far and exit with status 2 (for CI).")
+
+	flag.StringVar(&exportDir, "export-certs", "", "Directory to save each host's presented certificate chain as PEM (named by host and fingerprint).")
+
 	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
 	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
 
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerOtelFlag()
+	registerFindingsFlags()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+	registerTZFlag()
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  Checks the SSL/TLS certificate expiry date for specified hosts.\n")
 		fmt.Fprintf(os.Stderr, "  Example: %s -h google.com\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  Example: %s -i hosts.txt -o report.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s --mx example.com\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
 	}
 }
 
 // checkCertExpiry connects to a host, retrieves its SSL cert, and checks its expiry.
-func checkCertExpiry(targetHostPort string, timeout time.Duration, warnThreshold int) CertCheckResult {
+func checkCertExpiry(ctx context.Context, targetHostPort string, timeout time.Duration, warnThreshold int) CertCheckResult {
 	if verboseMode {
 		fmt.Fprintf(os.Stderr, "[INFO] Checking certificate for: %s\n", targetHostPort)
 	}
 
-	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", targetHostPort, &tls.Config{
-		InsecureSkipVerify: true, // Not secure, but simplifies demo and avoids cert chain issues
-	})
+	conn, diag, err := dialTLS(ctx, targetHostPort, timeout)
+	if dumpHandshake {
+		logHandshake(targetHostPort, diag)
+	} else {
+		diag = nil
+	}
 	if err != nil {
-		return CertCheckResult{Host: targetHostPort, Status: "ERROR", Error: fmt.Errorf("TLS connection failed: %w", err)}
+		return CertCheckResult{Host: targetHostPort, Status: "ERROR", Error: fmt.Errorf("TLS connection failed: %w", err), Diagnostics: diag}
 	}
 	defer conn.Close()
 
-	peerCerts := conn.ConnectionState().PeerCertificates
+	state := conn.ConnectionState()
+	debugf("%s: %s, %s, %d certificate(s) presented", targetHostPort, tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite), len(state.PeerCertificates))
+	peerCerts := state.PeerCertificates
 	if len(peerCerts) == 0 {
-		return CertCheckResult{Host: targetHostPort, Status: "ERROR", Error: fmt.Errorf("no certificates found")}
+		return CertCheckResult{Host: targetHostPort, Status: "ERROR", Error: fmt.Errorf("no certificates found"), Diagnostics: diag}
 	}
 
 	// Use the first certificate in the chain (usually the leaf certificate)
@@ -99,46 +213,105 @@ func checkCertExpiry(targetHostPort string, timeout time.Duration, warnThreshold
 
 	daysLeft := int(time.Until(cert.NotAfter).Hours() / 24)
 
+	notYetValid, warnings := notBeforeWarnings(cert, time.Now(), time.Duration(recentHours)*time.Hour)
+
 	status := "VALID"
 	if daysLeft < 0 {
 		status = "EXPIRED"
+	} else if notYetValid {
+		status = "NOT YET VALID"
 	} else if daysLeft <= warnThreshold {
 		status = fmt.Sprintf("EXPIRING SOON (%d days)", daysLeft)
 	}
 
-	return CertCheckResult{Host: targetHostPort, ExpiryDate: cert.NotAfter, DaysLeft: daysLeft, Status: status, Error: nil}
+	if hostname, p, _ := net.SplitHostPort(targetHostPort); checkClock && !isSMTPPort(p) { // SMTP has no Date header to ask for
+		skew, err := serverClockSkew(conn, hostname, timeout)
+		if err != nil {
+			if verboseMode {
+				fmt.Fprintf(os.Stderr, "[INFO] Clock skew check skipped for %s: %v\n", targetHostPort, err)
+			}
+		} else if skew > time.Duration(maxSkewSec)*time.Second || -skew > time.Duration(maxSkewSec)*time.Second {
+			warnings = append(warnings, fmt.Sprintf("server clock differs from local time by %s (server Date header); TLS validity checks may fail", skew))
+		}
+	}
+
+	result := CertCheckResult{Host: targetHostPort, ExpiryDate: cert.NotAfter, DaysLeft: daysLeft, Status: status, Error: nil, Chain: peerCerts, Warnings: warnings, Diagnostics: diag}
+	if len(issuerRules) > 0 {
+		hostname, _, _ := net.SplitHostPort(targetHostPort)
+		if violation := checkIssuerPolicy(issuerRules, hostname, cert); violation != "" {
+			result.PolicyViolation = violation
+			if status == "VALID" || strings.HasPrefix(status, "EXPIRING SOON") {
+				result.Status = "ISSUER_POLICY_VIOLATION"
+			}
+		}
+	}
+	if violation, usageWarnings := checkKeyUsage(cert); violation != "" || len(usageWarnings) > 0 {
+		result.Warnings = append(result.Warnings, usageWarnings...)
+		if violation != "" {
+			result.UsageViolation = violation
+			if status == "VALID" || strings.HasPrefix(status, "EXPIRING SOON") {
+				result.Status = "KEY_USAGE_VIOLATION"
+			}
+		}
+	}
+	if caRoots != nil {
+		hostname, _, _ := net.SplitHostPort(targetHostPort)
+		if err := verifyChain(peerCerts, hostname, caRoots, time.Now()); err != nil {
+			result.TrustError = err.Error()
+			if status != "EXPIRED" && status != "NOT YET VALID" {
+				result.Status = "UNTRUSTED"
+			}
+		}
+	}
+	if probeQUIC {
+		result.QUICNote = compareQUICCertificate(ctx, targetHostPort, timeout, cert)
+	}
+	return result
 }
 
-// loadHostsFromFile reads host:port or host entries from a specified file.
-func loadHostsFromFile(filePath string, defaultPort string) ([]string, error) {
-	file, err := os.Open(filePath)
+// loadHostsFromFile reads host:port or host entries from a specified file,
+// or from stdin if the path is "-". Entries without a port are checked on defaultPorts.
+func loadHostsFromFile(filePath string, defaultPorts []string) ([]string, error) {
+	file, err := openInput(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("[ERROR] Failed to open input file %s: %w", filePath, err)
 	}
+	name := inputName(filePath)
 	defer file.Close()
 
 	var hosts []string
 	scanner := bufio.NewScanner(file)
+	lineNo := 0
 	for scanner.Scan() {
+		lineNo++
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
 			continue
 		}
-		// If line doesn't contain a port, append defaultPort
-		if !strings.Contains(line, ":") {
-			line = net.JoinHostPort(line, defaultPort)
+		line, labels, err := splitLabels(line)
+		if err != nil {
+			return nil, fmt.Errorf("[ERROR] Input file %s line %d: %w", name, lineNo, err)
+		}
+		targets, err := expandPorts([]string{line}, defaultPorts)
+		if err != nil {
+			return nil, fmt.Errorf("[ERROR] Input file %s line %d: %w", name, lineNo, err)
+		}
+		hosts = append(hosts, targets...)
+		if labels != nil {
+			for _, t := range targets {
+				hostLabels[t] = labels
+			}
 		}
-		hosts = append(hosts, line)
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("[ERROR] Error reading input file %s: %w", filePath, err)
+		return nil, fmt.Errorf("[ERROR] Error reading input file %s: %w", name, err)
 	}
 	return hosts, nil
 }
 
 // writeReport generates the certificate expiry report.
-func writeReport(results []CertCheckResult, output *os.File) {
+func writeReport(results []CertCheckResult, output io.Writer) {
 	fmt.Fprintf(output, "--- SSL Certificate Expiry Report ---\n\n")
 	if len(results) == 0 {
 		fmt.Fprintln(output, "No hosts were checked or no results to report.")
@@ -146,82 +319,509 @@ func writeReport(results []CertCheckResult, output *os.File) {
 	}
 
 	for _, result := range results {
-		fmt.Fprintf(output, "Host: %s\n", result.Host)
-		fmt.Fprintf(output, "Status: %s\n", result.Status)
-		if result.ExpiryDate.IsZero() {
-			fmt.Fprintf(output, "Expiry Date: N/A\n")
-			fmt.Fprintf(output, "Days Left: N/A\n")
-		} else {
-			fmt.Fprintf(output, "Expiry Date: %s\n", result.ExpiryDate.Format("2006-01-02"))
-			fmt.Fprintf(output, "Days Left: %d\n", result.DaysLeft)
+		writeResult(result, output)
+	}
+}
+
+// writeResult writes one host's section of the text report.
+func writeResult(result CertCheckResult, output io.Writer) {
+	fmt.Fprintf(output, "Host: %s\n", result.Host)
+	fmt.Fprintf(output, "Status: %s\n", colorStatus(result.Status))
+	if len(result.Labels) > 0 {
+		fmt.Fprintf(output, "Labels: %s\n", result.Labels)
+	}
+	if result.ExpiryDate.IsZero() {
+		fmt.Fprintf(output, "Expiry Date: N/A\n")
+		fmt.Fprintf(output, "Days Left: N/A\n")
+	} else {
+		fmt.Fprintf(output, "Expiry Date: %s\n", stamp(result.ExpiryDate))
+		fmt.Fprintf(output, "Days Left: %d\n", result.DaysLeft)
+	}
+	if result.PolicyViolation != "" {
+		fmt.Fprintf(output, "Policy Violation: %s\n", result.PolicyViolation)
+	}
+	if result.UsageViolation != "" {
+		fmt.Fprintf(output, "Key Usage Violation: %s\n", result.UsageViolation)
+	}
+	if result.TrustError != "" {
+		fmt.Fprintf(output, "Verification Failed: %s\n", result.TrustError)
+	}
+	for _, w := range result.Warnings {
+		fmt.Fprintf(output, "Warning: %s\n", w)
+	}
+	if result.QUICNote != "" {
+		fmt.Fprintf(output, "QUIC: %s\n", result.QUICNote)
+	}
+	if result.Ack != "" && result.Status != "VALID" {
+		fmt.Fprintf(output, "Acknowledged: %s\n", result.Ack)
+	}
+	if result.RotationNote != "" {
+		fmt.Fprintf(output, "Frequent Rotation: %s\n", result.RotationNote)
+	}
+	if result.RenewalHint != "" {
+		fmt.Fprintf(output, "Renewal Hint: %s\n", result.RenewalHint)
+	}
+	if result.HookOutput != "" || result.HookError != nil {
+		fmt.Fprintln(output, "Renew Hook Output:")
+		for _, line := range strings.Split(result.HookOutput, "\n") {
+			fmt.Fprintf(output, "  %s\n", line)
 		}
-		if result.Error != nil {
-			fmt.Fprintf(output, "Error: %v\n", result.Error)
+		if result.HookError != nil {
+			fmt.Fprintf(output, "Renew Hook Error: %v\n", result.HookError)
 		}
-		fmt.Fprintln(output, "------------------------------")
 	}
+	if result.Error != nil {
+		fmt.Fprintf(output, "Error: %v\n", result.Error)
+	}
+	fmt.Fprintln(output, "------------------------------")
+}
+
+// notifyCertAlerts sends one alert covering every certificate that is not VALID.
+func notifyCertAlerts(results []CertCheckResult) {
+	notifier, err := newAlertNotifier(notifyTargets, notifyTmpl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	var items []alertItem
+	silenced := 0
+	for _, r := range results {
+		if r.Status == "VALID" {
+			continue
+		}
+		if r.Ack != "" {
+			silenced++
+			continue
+		}
+		detail := ""
+		switch {
+		case r.Error != nil:
+			detail = r.Error.Error()
+		case r.TrustError != "":
+			detail = r.TrustError
+		case r.PolicyViolation != "":
+			detail = r.PolicyViolation
+		case r.UsageViolation != "":
+			detail = r.UsageViolation
+		case r.DaysLeft < 0:
+			detail = fmt.Sprintf("expired %s", stamp(r.ExpiryDate))
+		default:
+			detail = fmt.Sprintf("expires %s, %d days left", stamp(r.ExpiryDate), r.DaysLeft)
+		}
+		items = append(items, alertItem{Target: r.Host, Status: r.Status, Detail: detail, ErrorClass: classifyError(r.Error)})
+	}
+	if silenced > 0 && verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] %d acknowledged host(s) left out of the alert (--ack).\n", silenced)
+	}
+	if len(items) == 0 {
+		return
+	}
+	event := alertEvent{Tool: toolName, Summary: fmt.Sprintf("%d certificate(s) need attention", len(items)), Items: items}
+	for _, err := range notifier.send(event) {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+	}
+}
+
+// normalizedFindings converts the certificate results to the cross-tool
+// findings model, one finding per problem: expiry, trust, key usage and
+// issuer policy are reported separately, where the status shows only the
+// most urgent. Hosts that could not be checked are not findings.
+func normalizedFindings(results []CertCheckResult) []normFinding {
+	var out []normFinding
+	for _, r := range results {
+		if r.Error != nil {
+			continue
+		}
+		add := func(category, title, severity, detail, vector string) {
+			f := normFinding{Tool: toolName, Target: r.Host, Category: category, Title: title, Severity: severity, Detail: detail}
+			if vector != "" {
+				f = f.withVector(vector)
+			}
+			out = append(out, f)
+		}
+		expires := "expires " + stamp(r.ExpiryDate)
+		switch {
+		case r.DaysLeft < 0: // Clients refuse to connect
+			add("expiry", "Certificate has expired", "critical", expires, "AV:N/PR:N/C:N/I:N/A:H")
+		case len(r.Chain) > 0 && time.Now().Before(r.Chain[0].NotBefore):
+			add("expiry", "Certificate is not yet valid", "high", "valid from "+stamp(r.Chain[0].NotBefore), "")
+		case r.DaysLeft <= warnDays:
+			add("expiry", fmt.Sprintf("Certificate expires in %d days", r.DaysLeft), "low", expires, "")
+		}
+		if r.TrustError != "" { // Open to an on-path impostor
+			add("trust", "Chain does not verify", "high", r.TrustError, "AV:A/PR:N/C:H/I:H/A:N")
+		}
+		if r.UsageViolation != "" {
+			add("key-usage", "Certificate is not valid for TLS servers", "medium", r.UsageViolation, "")
+		}
+		if r.PolicyViolation != "" {
+			add("issuer-policy", "Issuer violates policy", "medium", r.PolicyViolation, "")
+		}
+	}
+	return out
+}
+
+// manifestInputs lists the target and policy files this run was driven by.
+func manifestInputs() []string {
+	return []string{inputFile, k8sFile, policyFile, caBundle, ackFile, notifyTmpl}
 }
 
 // main is the entry point of the SSL Certificate Expiry Checker tool.
 func main() {
+	runSubcommand()
 	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+	if err := applyTZ(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	minRotation := time.Duration(minRotDays) * 24 * time.Hour
+
+	if historyHost != "" {
+		if dbPath == "" {
+			fmt.Fprintln(os.Stderr, "[ERROR] --history requires --db.")
+			os.Exit(1)
+		}
+		db, err := loadCertDB(dbPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		if h, p, err := splitTarget(historyHost); err == nil {
+			if p == "" {
+				p = port
+			}
+			historyHost = net.JoinHostPort(h, p)
+		}
+		output, err := openSink(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+			os.Exit(1)
+		}
+		writeHistory(db, historyHost, minRotation, output)
+		if !closeSink(output) {
+			os.Exit(1)
+		}
+		writeManifest(0, []string{dbPath}, []string{outputFile})
+		return
+	}
 
 	// Validate arguments
-	if inputFile == "" && host == "" {
+	if err := checkFindingsMin(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --findings-min: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	startOtel()
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Unsupported report format: %s (expected text or json)\n", format)
+		os.Exit(1)
+	}
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if summaryView && format == "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --summary is a text view and cannot be combined with --format json.")
+		os.Exit(1)
+	}
+	if !slices.Contains(sortKeys, sortKey) {
+		fmt.Fprintf(os.Stderr, "[ERROR] Unsupported --sort: %s (expected %s)\n", sortKey, strings.Join(sortKeys, ", "))
+		os.Exit(1)
+	}
+	if groupBy != "none" && groupBy != "status" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Unsupported --group-by: %s (expected none or status)\n", groupBy)
+		os.Exit(1)
+	}
+	if groupBy == "status" && (format == "json" || summaryView) {
+		fmt.Fprintln(os.Stderr, "[ERROR] --group-by status applies to the text report and cannot be combined with --format json or --summary.")
+		os.Exit(1)
+	}
+	if inputFile == "" && host == "" && k8sFile == "" && mxDomain == "" {
 		flag.Usage()
-		fmt.Fprintln(os.Stderr, "\n[ERROR] Either an input file (-i) or a hostname (-h) must be provided.")
+		fmt.Fprintln(os.Stderr, "\n[ERROR] Either an input file (-i), a Kubernetes dump (--k8s), a mail domain (--mx) or a hostname (-h) must be provided.")
+		os.Exit(1)
+	}
+	if (inputFile != "" || k8sFile != "" || mxDomain != "") && host != "" {
+		warnf("Input file (-i/--k8s/--mx) provided. -host flag will be ignored.")
+	}
+	if resolverAddr != "" {
+		dnsResolver = newResolver(resolverAddr, time.Duration(timeoutSec)*time.Second)
+	}
+
+	if policyFile != "" {
+		rules, err := loadIssuerPolicy(policyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		issuerRules = rules
+	}
+
+	defaultPorts, err := splitPorts(port)
+	if portList != "" {
+		defaultPorts, err = splitPorts(portList)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
 		os.Exit(1)
 	}
-	if inputFile != "" && host != "" {
-		fmt.Fprintln(os.Stderr, "[WARNING] Input file (-i) provided. -host flag will be ignored.")
+
+	var acks []ackRule
+	if ackFile != "" {
+		if acks, err = loadAcks(ackFile); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if caBundle != "" {
+		roots, n, err := loadCABundle(caBundle)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		caRoots = roots
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Verifying chains against %d CA certificate(s) from %s\n", n, caBundle)
+		}
 	}
 
 	var hostsToMonitor []string
 	if inputFile != "" {
-		loadedHosts, err := loadHostsFromFile(inputFile, port)
+		loadedHosts, err := loadHostsFromFile(inputFile, defaultPorts)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 		hostsToMonitor = loadedHosts
-	} else {
-		hostsToMonitor = []string{net.JoinHostPort(host, port)}
+	}
+	if k8sFile != "" {
+		k8sHosts, err := loadK8sTargets(k8sFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		hostsToMonitor = append(hostsToMonitor, k8sHosts...)
+	}
+	if mxDomain != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+		mxHosts, err := loadMXTargets(ctx, mxDomain)
+		cancel()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		hostsToMonitor = append(hostsToMonitor, mxHosts...)
+	}
+	if inputFile == "" && k8sFile == "" && mxDomain == "" {
+		hostsToMonitor = []string{host}
+	}
+	hostsToMonitor, err = expandPorts(hostsToMonitor, defaultPorts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
 	}
 
 	if verboseMode {
 		fmt.Fprintf(os.Stderr, "[INFO] Checking %d host(s) for SSL certificate expiry...\n", len(hostsToMonitor))
 	}
 
+	// SIGINT/SIGTERM abort pending handshakes; hosts checked so far are
+	// still reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// --fail-fast cancels checkCtx at the first EXPIRED or ERROR result, and
+	// a signal cancels it with ctx; the checks it aborts are left out of the
+	// report.
+	checkCtx, cancelChecks := context.WithCancel(ctx)
+	defer cancelChecks()
 	resultsChan := make(chan CertCheckResult, len(hostsToMonitor))
 	timeoutDuration := time.Duration(timeoutSec) * time.Second
 
-	for _, target := range hostsToMonitor {
+	var certCheckResults []CertCheckResult
+	received := 0
+	failedOn := "" // Host that stopped a --fail-fast run
+	collect := func(r CertCheckResult) {
+		received++
+		if checkCtx.Err() != nil && errors.Is(r.Error, context.Canceled) {
+			return
+		}
+		certCheckResults = append(certCheckResults, r)
+		if failFast && failedOn == "" && (r.Status == "EXPIRED" || r.Status == "ERROR") {
+			failedOn = r.Host
+			cancelChecks()
+		}
+	}
+
+	started := 0
+	for i, target := range hostsToMonitor {
+		if checkCtx.Err() != nil {
+			break
+		}
 		go func(t string) {
-			resultsChan <- checkCertExpiry(t, timeoutDuration, warnDays)
+			start := time.Now()
+			r := checkCertExpiry(checkCtx, t, timeoutDuration, warnDays)
+			telemetry.span("check", start, map[string]string{"target": t, "status": statusGroup(r.Status)}, r.Error)
+			resultsChan <- r
 		}(target)
-		time.Sleep(200 * time.Millisecond) // Introduce a small delay
+		started++
+		if i+1 < len(hostsToMonitor) && sameHost(target, hostsToMonitor[i+1]) {
+			continue // Ports of one host are checked in parallel
+		}
+		for delay := time.After(200 * time.Millisecond); checkCtx.Err() == nil; { // A small delay between hosts
+			select {
+			case r := <-resultsChan:
+				collect(r)
+				continue
+			case <-delay:
+			case <-checkCtx.Done():
+			}
+			break
+		}
 	}
 
-	var certCheckResults []CertCheckResult
-	for i := 0; i < len(hostsToMonitor); i++ {
-		certCheckResults = append(certCheckResults, <-resultsChan)
+	interrupted := false
+	for received < started && !interrupted {
+		select {
+		case r := <-resultsChan:
+			collect(r)
+		case <-ctx.Done():
+			interrupted = true
+		}
+	}
+	for drained := false; interrupted && !drained; { // Keep checks that finished before the signal
+		select {
+		case r := <-resultsChan:
+			collect(r)
+		default:
+			drained = true
+		}
+	}
+	if ctx.Err() != nil {
+		interrupted = true
+		stop() // A second signal terminates immediately
+		warnf("Interrupted by signal; reporting %d of %d host(s).", len(certCheckResults), len(hostsToMonitor))
+		renewHook = "" // Do not start renewals on an aborted run
+	} else if failedOn != "" {
+		warnf("--fail-fast: stopped at %s; reporting %d of %d host(s).", failedOn, len(certCheckResults), len(hostsToMonitor))
+	}
+
+	// Report in target order unless --sort says otherwise, so that the
+	// ports of a host stay together.
+	order := map[string]int{}
+	for i, t := range hostsToMonitor {
+		order[t] = i
+	}
+	sortResults(certCheckResults, sortKey, order)
+	for i := range certCheckResults {
+		certCheckResults[i].Labels = hostLabels[certCheckResults[i].Host]
 	}
 
-	output := os.Stdout
-	if outputFile != "" {
-		var err error
-		output, err = os.Create(outputFile)
+	applyRenewalHints(certCheckResults, warnDays, renewHook)
+	applyAcks(certCheckResults, acks, time.Now())
+
+	if dbPath != "" {
+		db, err := loadCertDB(dbPath)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "[ERROR] Failed to create output file %s: %v\n", outputFile, err)
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		db.record(certCheckResults, time.Now().UTC(), minRotation)
+		if err := db.save(dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to save certificate database: %v\n", err)
 			os.Exit(1)
 		}
-		defer output.Close()
 	}
 
-	writeReport(certCheckResults, output)
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
 
+	if format == "json" {
+		if err := writeJSONReport(certCheckResults, interrupted, failedOn, output); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else if summaryView {
+		writeSummary(certCheckResults, output)
+	} else if groupBy == "status" {
+		writeGroupedReport(certCheckResults, output)
+	} else {
+		writeReport(certCheckResults, output)
+	}
+	if byLabel != "" && format == "text" {
+		writeLabelSummary(summarizeByLabel(certCheckResults, byLabel, warnDays, time.Now()), byLabel, warnDays, output)
+	}
+	if interrupted && format == "text" {
+		fmt.Fprintf(output, "Partial report: interrupted after %d of %d hosts.\n", len(certCheckResults), len(hostsToMonitor))
+	} else if failedOn != "" && format == "text" {
+		fmt.Fprintf(output, "Partial report: --fail-fast stopped at %s after %d of %d hosts.\n", failedOn, len(certCheckResults), len(hostsToMonitor))
+	}
+	if selfStatsOn && format == "text" {
+		writeSelfStats(output, collectSelfStats(len(certCheckResults), "hosts"))
+	}
+
+	if len(notifyTargets) > 0 {
+		notifyCertAlerts(certCheckResults)
+	}
+
+	findings := normalizedFindings(certCheckResults)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+
+	if exportDir != "" {
+		n, err := exportChains(certCheckResults, exportDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to export certificates: %v\n", err)
+			os.Exit(1)
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Exported %d certificate chain(s) to %s\n", n, exportDir)
+		}
+	}
+
+	if interrupted {
+		closeSink(output)
+		telemetry.finish(130)
+		writeManifest(130, manifestInputs(), []string{outputFile, signatureTarget(outputFile), dbPath, findingsPath})
+		os.Exit(130)
+	}
 	if verboseMode {
 		fmt.Fprintln(os.Stderr, "[INFO] SSL certificate expiry check complete.")
 	}
-	os.Exit(0)
+	if !closeSink(output) {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	code := 0
+	if failedOn != "" {
+		code = 2
+	}
+	telemetry.finish(code)
+	writeManifest(code, manifestInputs(), []string{outputFile, signatureTarget(outputFile), dbPath, findingsPath})
+	os.Exit(code)
 }
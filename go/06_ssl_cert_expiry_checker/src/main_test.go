@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The tests run the checker itself: the test binary re-executes itself with
+// runMainEnv set, and TestMain then calls main with the arguments it holds,
+// so that exit statuses and signal handling are those of a real run.
+const runMainEnv = "CERT_CHECKER_TEST_ARGS"
+
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(runMainEnv); ok {
+		os.Args = append([]string{os.Args[0]}, strings.Split(args, "\n")...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// startChecker starts the checker with args and returns the command and
+// where its JSON report will be written.
+func startChecker(t *testing.T, args ...string) (*exec.Cmd, string) {
+	t.Helper()
+	report := filepath.Join(t.TempDir(), "report.json")
+	args = append(args, "-f", "json", "-o", report)
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), runMainEnv+"="+strings.Join(args, "\n"))
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd, report
+}
+
+// exitStatus waits for cmd and returns its exit status.
+func exitStatus(t *testing.T, cmd *exec.Cmd) int {
+	t.Helper()
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0
+}
+
+func readReport(t *testing.T, path string) jsonReport {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rep jsonReport
+	if err := json.Unmarshal(data, &rep); err != nil {
+		t.Fatalf("report: %v\n%s", err, data)
+	}
+	return rep
+}
+
+func reportedHosts(rep jsonReport) []string {
+	var hosts []string
+	for _, r := range rep.Results {
+		hosts = append(hosts, r.Host)
+	}
+	return hosts
+}
+
+// refusedTarget returns a local address that refuses connections.
+func refusedTarget(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// stalledTarget returns a local address that accepts connections but never
+// answers a TLS handshake.
+func stalledTarget(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				c.Close()
+			}
+		}()
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, c)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func writeHosts(t *testing.T, hosts ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hosts.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(hosts, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFailFastCancelsRemainingChecks(t *testing.T) {
+	stalled, refused := stalledTarget(t), refusedTarget(t)
+	_, stalledPort, _ := net.SplitHostPort(stalled)
+	// The stalled and refused ports of 127.0.0.1 are checked in parallel;
+	// the second host would only be started after them.
+	later := "127.0.0.2:" + stalledPort
+	hosts := writeHosts(t, stalled, refused, later)
+
+	start := time.Now()
+	cmd, report := startChecker(t, "-i", hosts, "-t", "30", "--fail-fast")
+	if code := exitStatus(t, cmd); code != 2 {
+		t.Fatalf("exit status %d, want 2", code)
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Errorf("run took %s; the stalled check was not cancelled", elapsed)
+	}
+	rep := readReport(t, report)
+	if got := reportedHosts(rep); len(got) != 1 || got[0] != refused {
+		t.Errorf("reported hosts %v, want only %s", got, refused)
+	}
+	if rep.StoppedAt != refused {
+		t.Errorf("fail_fast_stopped_at = %q, want %q", rep.StoppedAt, refused)
+	}
+	if rep.Interrupted {
+		t.Error("report marked interrupted")
+	}
+}
+
+func TestExitStatusWithoutFailFast(t *testing.T) {
+	refused := refusedTarget(t)
+	cmd, report := startChecker(t, "-h", refused)
+	if code := exitStatus(t, cmd); code != 0 {
+		t.Fatalf("exit status %d, want 0", code)
+	}
+	rep := readReport(t, report)
+	if len(rep.Results) != 1 || rep.Results[0].Status != "ERROR" {
+		t.Errorf("results %+v, want one ERROR", rep.Results)
+	}
+	if rep.StoppedAt != "" {
+		t.Errorf("fail_fast_stopped_at = %q without --fail-fast", rep.StoppedAt)
+	}
+}
+
+func TestExitStatusWhenInterrupted(t *testing.T) {
+	cmd, report := startChecker(t, "-h", stalledTarget(t), "-t", "30", "--fail-fast")
+	time.Sleep(time.Second)
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Skipf("cannot interrupt the checker: %v", err)
+	}
+	if code := exitStatus(t, cmd); code != 130 {
+		t.Fatalf("exit status %d, want 130", code)
+	}
+	rep := readReport(t, report)
+	if !rep.Interrupted || len(rep.Results) != 0 || rep.StoppedAt != "" {
+		t.Errorf("report %+v, want an empty interrupted report", rep)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// smtpPort is where mail exchangers accept mail from other servers.
+const smtpPort = "25"
+
+// dnsResolver answers the --mx lookup and resolves every target; it is the
+// system resolver unless --resolver names a DNS server.
+var dnsResolver = net.DefaultResolver
+
+// newResolver returns a resolver that sends every query to server
+// (host[:port], port 53 by default).
+func newResolver(server string, timeout time.Duration) *net.Resolver {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+		d := net.Dialer{Timeout: timeout}
+		return d.DialContext(ctx, network, server)
+	}}
+}
+
+// loadMXTargets resolves the mail exchangers of domain, in preference order,
+// as host:25 targets. A domain without MX records receives mail on its own
+// address (RFC 5321 section 5.1); a null MX (RFC 7505) accepts none.
+func loadMXTargets(ctx context.Context, domain string) ([]string, error) {
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	mxs, err := dnsResolver.LookupMX(ctx, domain)
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return nil, fmt.Errorf("[ERROR] MX lookup for %s failed: %w", domain, err)
+	}
+	if len(mxs) == 0 {
+		warnf("%s has no MX records; checking the domain itself as its mail exchanger.", domain)
+		return []string{net.JoinHostPort(domain, smtpPort)}, nil
+	}
+	var targets []string
+	for _, mx := range mxs {
+		host := strings.TrimSuffix(mx.Host, ".")
+		if host == "" {
+			continue
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] MX %s (preference %d)\n", host, mx.Pref)
+		}
+		targets = append(targets, net.JoinHostPort(host, smtpPort))
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("[ERROR] %s publishes a null MX and accepts no mail", domain)
+	}
+	return targets, nil
+}
+
+// isSMTPPort reports whether the TLS handshake on port follows an SMTP
+// STARTTLS exchange (25 for relays, 587 for submission) instead of
+// starting at once.
+func isSMTPPort(port string) bool {
+	return port == smtpPort || port == "587"
+}
+
+// startTLS upgrades an SMTP session: it reads the greeting, sends EHLO and,
+// if the server offers STARTTLS, sends it and waits for the go-ahead. The
+// connection is then ready for the TLS handshake.
+func startTLS(conn net.Conn, deadline time.Time) error {
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	defer conn.SetDeadline(time.Time{})
+	tp := textproto.NewConn(conn) // Never closed; the TLS client takes over conn
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	if err := tp.PrintfLine("EHLO %s", ehloName()); err != nil {
+		return err
+	}
+	_, ext, err := tp.ReadResponse(250)
+	if err != nil {
+		return fmt.Errorf("EHLO: %w", err)
+	}
+	offered := false
+	for _, line := range strings.Split(ext, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), "STARTTLS") {
+			offered = true
+		}
+	}
+	if !offered {
+		return errors.New("server does not offer STARTTLS; mail to it is sent unencrypted")
+	}
+	if err := tp.PrintfLine("STARTTLS"); err != nil {
+		return err
+	}
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return fmt.Errorf("STARTTLS: %w", err)
+	}
+	return nil
+}
+
+// ehloName is the name sent in EHLO: this host's name, or "localhost".
+func ehloName() string {
+	if h, err := os.Hostname(); err == nil && strings.Contains(h, ".") {
+		return h
+	}
+	return "localhost"
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sortKeys are the accepted --sort values. "target" keeps the order the
+// targets were given in, with the ports of a host together.
+var sortKeys = []string{"target", "host", "days-left", "status"}
+
+// statusRank orders statuses from most to least urgent, for --sort status
+// and --group-by status.
+var statusRank = []string{
+	"ERROR",
+	"EXPIRED",
+	"NOT YET VALID",
+	"UNTRUSTED",
+	"KEY_USAGE_VIOLATION",
+	"ISSUER_POLICY_VIOLATION",
+	"EXPIRING SOON",
+	"VALID",
+}
+
+// statusGroup drops the day count from "EXPIRING SOON (n days)".
+func statusGroup(status string) string {
+	if strings.HasPrefix(status, "EXPIRING SOON") {
+		return "EXPIRING SOON"
+	}
+	return status
+}
+
+// rankOf returns the position of a status in statusRank; unknown statuses
+// sort last.
+func rankOf(status string) int {
+	for i, s := range statusRank {
+		if s == statusGroup(status) {
+			return i
+		}
+	}
+	return len(statusRank)
+}
+
+// hostLess orders host:port targets by host name, then numerically by port.
+func hostLess(a, b string) bool {
+	ha, pa, _ := net.SplitHostPort(a)
+	hb, pb, _ := net.SplitHostPort(b)
+	if ha != hb {
+		return ha < hb
+	}
+	na, _ := strconv.Atoi(pa)
+	nb, _ := strconv.Atoi(pb)
+	return na < nb
+}
+
+// sortResults orders results by key. order maps each target to its position
+// on the command line or in the input file. Ties are broken by host, so the
+// report is the same on every run whatever order the checks finished in.
+// Hosts whose expiry is unknown sort after the others by days left.
+func sortResults(results []CertCheckResult, key string, order map[string]int) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch key {
+		case "host":
+			return hostLess(a.Host, b.Host)
+		case "days-left":
+			if a.ExpiryDate.IsZero() != b.ExpiryDate.IsZero() {
+				return b.ExpiryDate.IsZero()
+			}
+			if a.DaysLeft != b.DaysLeft {
+				return a.DaysLeft < b.DaysLeft
+			}
+			return hostLess(a.Host, b.Host)
+		case "status":
+			if ra, rb := rankOf(a.Status), rankOf(b.Status); ra != rb {
+				return ra < rb
+			}
+			return hostLess(a.Host, b.Host)
+		}
+		return order[a.Host] < order[b.Host]
+	})
+}
+
+// writeGroupedReport writes the text report with one section per status,
+// most urgent first. Results keep their --sort order within a section.
+func writeGroupedReport(results []CertCheckResult, output io.Writer) {
+	fmt.Fprintf(output, "--- SSL Certificate Expiry Report (by status) ---\n\n")
+	if len(results) == 0 {
+		fmt.Fprintln(output, "No hosts were checked or no results to report.")
+		return
+	}
+	groups := map[string][]CertCheckResult{}
+	var names []string
+	for _, r := range results {
+		g := statusGroup(r.Status)
+		if _, ok := groups[g]; !ok {
+			names = append(names, g)
+		}
+		groups[g] = append(groups[g], r)
+	}
+	sort.SliceStable(names, func(i, j int) bool { return rankOf(names[i]) < rankOf(names[j]) })
+	for _, g := range names {
+		fmt.Fprintf(output, "=== %s (%d) ===\n", colorStatus(g), len(groups[g]))
+		for _, r := range groups[g] {
+			writeResult(r, output)
+		}
+		fmt.Fprintln(output)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenTelemetry export. With --otel-endpoint the run is sent to an OTLP/HTTP
+// collector (JSON encoding) when it ends: a trace with one root span for the
+// run and a child span per target, file or check, and counters for the
+// targets by status and the findings by severity and category. Headers for
+// the collector, such as an API key, come from OTEL_EXPORTER_OTLP_HEADERS
+// ("key=value,key2=value2"). Export problems are warnings; they never change
+// the tool's exit status.
+//
+// Every tool with --otel-endpoint carries an identical copy of this file
+// (scripts/check_shared_go.py).
+
+var otelEndpoint string
+
+// telemetry is the current run's export, set by startOtel.
+var telemetry *otelRun
+
+func registerOtelFlag() {
+	flag.StringVar(&otelEndpoint, "otel-endpoint", "", "OTLP/HTTP collector URL (e.g. http://localhost:4318) to export a trace of the run and finding counters to; /v1/traces and /v1/metrics are appended.")
+}
+
+// checkOtelEndpoint validates --otel-endpoint.
+func checkOtelEndpoint() error {
+	if otelEndpoint == "" {
+		return nil
+	}
+	u, err := url.ParseRequestURI(otelEndpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid --otel-endpoint %q: expected an http(s):// collector URL", otelEndpoint)
+	}
+	return nil
+}
+
+// otelBatch caps the spans sent in one request.
+const otelBatch = 1000
+
+type otelSpan struct {
+	id         string
+	name       string
+	start, end time.Time
+	attrs      map[string]string
+	err        string
+}
+
+// otelRun collects the spans and counters of one run. A nil *otelRun (no
+// --otel-endpoint) ignores everything, so callers need no checks.
+type otelRun struct {
+	mu       sync.Mutex
+	traceID  string
+	rootID   string
+	start    time.Time
+	spans    []otelSpan
+	counters map[string]map[string]int64 // Metric name -> encoded attributes -> value
+}
+
+// startOtel begins the run's trace when --otel-endpoint is set.
+func startOtel() {
+	if otelEndpoint != "" {
+		telemetry = &otelRun{traceID: otelID(16), rootID: otelID(8), start: time.Now(), counters: map[string]map[string]int64{}}
+	}
+}
+
+func otelID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// span records one unit of work (a target, file or check) that ran from start
+// until now. A non-nil err marks the span as failed.
+func (o *otelRun) span(name string, start time.Time, attrs map[string]string, err error) {
+	if o == nil {
+		return
+	}
+	s := otelSpan{id: otelID(8), name: name, start: start, end: time.Now(), attrs: attrs}
+	if err != nil {
+		s.err = err.Error()
+	}
+	o.mu.Lock()
+	o.spans = append(o.spans, s)
+	o.mu.Unlock()
+}
+
+// count adds n to the counter name with the given attributes.
+func (o *otelRun) count(name string, attrs map[string]string, n int64) {
+	if o == nil {
+		return
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var enc []string
+	for _, k := range keys {
+		enc = append(enc, k+"="+attrs[k])
+	}
+	o.mu.Lock()
+	if o.counters[name] == nil {
+		o.counters[name] = map[string]int64{}
+	}
+	o.counters[name][strings.Join(enc, "\x00")] += n
+	o.mu.Unlock()
+}
+
+// finish ends the root span with the exit status and exports the run.
+func (o *otelRun) finish(exitStatus int) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	end := time.Now()
+	root := otelSpan{id: o.rootID, name: toolName, start: o.start, end: end, attrs: map[string]string{"process.exit_code": strconv.Itoa(exitStatus)}}
+	if exitStatus != 0 {
+		root.err = fmt.Sprintf("exit status %d", exitStatus)
+	}
+	spans := append([]otelSpan{root}, o.spans...)
+	for len(spans) > 0 {
+		n := min(len(spans), otelBatch)
+		if err := otelPost("/v1/traces", o.traces(spans[:n])); err != nil {
+			warnf("OpenTelemetry trace export to %s failed: %v", otelEndpoint, err)
+			break
+		}
+		spans = spans[n:]
+	}
+	if len(o.counters) > 0 {
+		if err := otelPost("/v1/metrics", o.metrics(end)); err != nil {
+			warnf("OpenTelemetry metric export to %s failed: %v", otelEndpoint, err)
+		}
+	}
+	debugf("OpenTelemetry trace %s: %d span(s) sent to %s", o.traceID, len(o.spans)+1, otelEndpoint)
+}
+
+// The OTLP/JSON encoding: ids are hex, 64-bit integers decimal strings.
+
+type otelKeyValue struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+func otelAttrs(attrs map[string]string) []otelKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := []otelKeyValue{}
+	for _, k := range keys {
+		kvs = append(kvs, otelKeyValue{k, map[string]string{"stringValue": attrs[k]}})
+	}
+	return kvs
+}
+
+func otelResource() map[string]interface{} {
+	host, _ := os.Hostname()
+	return map[string]interface{}{"attributes": otelAttrs(map[string]string{"service.name": toolName, "service.version": toolVersion, "host.name": host})}
+}
+
+func otelNanos(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+
+func (o *otelRun) traces(spans []otelSpan) interface{} {
+	var out []map[string]interface{}
+	for _, s := range spans {
+		span := map[string]interface{}{
+			"traceId": o.traceID, "spanId": s.id, "name": s.name, "kind": 1, // Internal
+			"startTimeUnixNano": otelNanos(s.start), "endTimeUnixNano": otelNanos(s.end),
+			"attributes": otelAttrs(s.attrs),
+		}
+		if s.id != o.rootID {
+			span["parentSpanId"] = o.rootID
+		}
+		if s.err != "" {
+			span["status"] = map[string]interface{}{"code": 2, "message": s.err}
+		}
+		out = append(out, span)
+	}
+	return map[string]interface{}{"resourceSpans": []interface{}{map[string]interface{}{
+		"resource":   otelResource(),
+		"scopeSpans": []interface{}{map[string]interface{}{"scope": map[string]string{"name": toolName, "version": toolVersion}, "spans": out}},
+	}}}
+}
+
+func (o *otelRun) metrics(end time.Time) interface{} {
+	names := make([]string, 0, len(o.counters))
+	for name := range o.counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var metrics []interface{}
+	for _, name := range names {
+		var points []interface{}
+		for enc, v := range o.counters[name] {
+			attrs := map[string]string{}
+			for _, kv := range strings.Split(enc, "\x00") {
+				if k, val, ok := strings.Cut(kv, "="); ok {
+					attrs[k] = val
+				}
+			}
+			points = append(points, map[string]interface{}{
+				"attributes": otelAttrs(attrs), "startTimeUnixNano": otelNanos(o.start), "timeUnixNano": otelNanos(end), "asInt": strconv.FormatInt(v, 10),
+			})
+		}
+		metrics = append(metrics, map[string]interface{}{
+			"name": toolName + "." + name,
+			"sum":  map[string]interface{}{"aggregationTemporality": 2, "isMonotonic": true, "dataPoints": points}, // Cumulative
+		})
+	}
+	return map[string]interface{}{"resourceMetrics": []interface{}{map[string]interface{}{
+		"resource":     otelResource(),
+		"scopeMetrics": []interface{}{map[string]interface{}{"scope": map[string]string{"name": toolName, "version": toolVersion}, "metrics": metrics}},
+	}}}
+}
+
+func otelPost(path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(otelEndpoint, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, h := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if k, v, ok := strings.Cut(h, "="); ok {
+			req.Header.Set(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return nil
+}
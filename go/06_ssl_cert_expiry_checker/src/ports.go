@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// maxPortRange bounds the ports one range may expand to, so a typo such as
+// 443-44300 does not start tens of thousands of handshakes.
+const maxPortRange = 1024
+
+// splitPorts parses a comma-separated port list such as "443,8443,993".
+// Ranges are accepted too: "8440-8449".
+func splitPorts(list string) ([]string, error) {
+	var ports []string
+	for _, p := range strings.Split(list, ",") {
+		p = strings.TrimSpace(p)
+		if from, to, ok := strings.Cut(p, "-"); ok {
+			lo, err1 := parsePort(from)
+			hi, err2 := parsePort(to)
+			if err1 != nil || err2 != nil || lo > hi {
+				return nil, fmt.Errorf("invalid port range %q in %q", p, list)
+			}
+			if hi-lo+1 > maxPortRange {
+				return nil, fmt.Errorf("port range %q in %q covers more than %d ports", p, list, maxPortRange)
+			}
+			for n := lo; n <= hi; n++ {
+				ports = append(ports, strconv.Itoa(n))
+			}
+			continue
+		}
+		if _, err := parsePort(p); err != nil {
+			return nil, fmt.Errorf("invalid port %q in %q", p, list)
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// parsePort parses one port number.
+func parsePort(p string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(p))
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("invalid port %q", p)
+	}
+	return n, nil
+}
+
+// splitTarget separates a target into its host and port list. Accepted
+// forms are a host name or IPv4 address with or without ":<ports>", a bare
+// IPv6 address ("2001:db8::1", "fe80::1%eth0"), and a bracketed IPv6
+// address with or without ports ("[2001:db8::1]:443,8443"). ports is empty
+// when the target names none.
+func splitTarget(t string) (host, ports string, err error) {
+	t = strings.TrimSpace(t)
+	if addr, _, _ := strings.Cut(t, "%"); strings.Contains(t, ":") && net.ParseIP(addr) != nil {
+		return t, "", nil // Bare IPv6
+	}
+	if strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]") {
+		host = t[1 : len(t)-1]
+	} else if strings.Contains(t, ":") {
+		if host, ports, err = net.SplitHostPort(t); err != nil {
+			return "", "", fmt.Errorf("invalid target %q: %w (bracket IPv6 addresses that have ports: [2001:db8::1]:443)", t, err)
+		}
+		if ports == "" {
+			return "", "", fmt.Errorf("missing port after ':' in target %q", t)
+		}
+	} else {
+		host = t
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("missing host in target %q", t)
+	}
+	return host, ports, nil
+}
+
+// expandPorts turns each target into one host:port entry per port. Targets
+// may name several ports ("example.com:443,8443,993", "[2001:db8::1]:443,993",
+// "10.0.0.5:8440-8449"); a bare host or IP address gets defaultPorts.
+// Duplicates are dropped, order is kept.
+func expandPorts(targets []string, defaultPorts []string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range targets {
+		host, list, err := splitTarget(t)
+		if err != nil {
+			return nil, err
+		}
+		ports := defaultPorts
+		if list != "" {
+			var err error
+			if ports, err = splitPorts(list); err != nil {
+				return nil, fmt.Errorf("target %q: %w", t, err)
+			}
+		}
+		for _, p := range ports {
+			hp := net.JoinHostPort(host, p)
+			if !seen[hp] {
+				seen[hp] = true
+				out = append(out, hp)
+			}
+		}
+	}
+	return out, nil
+}
+
+// sameHost reports whether two host:port targets are on the same host.
+func sameHost(a, b string) bool {
+	ha, _, _ := net.SplitHostPort(a)
+	hb, _, _ := net.SplitHostPort(b)
+	return ha == hb
+}
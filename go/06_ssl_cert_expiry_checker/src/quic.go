@@ -0,0 +1,673 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"os"
+	"sort"
+	"time"
+)
+
+// Minimal QUIC v1 (RFC 9000/9001) client that performs only the Initial and
+// Handshake exchanges needed to receive the server's certificate chain over
+// UDP. The TLS 1.3 handshake itself is driven by crypto/tls's QUICConn; this
+// file supplies packet protection, framing, ACKs and retransmission. No
+// streams are opened and the connection is closed as soon as the handshake
+// completes. Requires Go 1.24+ (crypto/hkdf).
+
+const quicVersion1 = 0x00000001
+
+// quicInitialSalt is the RFC 9001 section 5.2 salt for QUIC version 1.
+var quicInitialSalt = []byte{0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a}
+
+const (
+	quicPacketInitial   = 0
+	quicPacketHandshake = 2
+	quicPacketRetry     = 3
+	quicMinDatagram     = 1200
+)
+
+// quicKeys hold one direction's packet protection keys for an encryption level.
+type quicKeys struct {
+	aead cipher.AEAD
+	iv   []byte
+	hp   cipher.Block
+}
+
+func quicExpandLabel(h func() hash.Hash, secret []byte, label string, length int) ([]byte, error) {
+	full := "tls13 " + label
+	info := []byte{byte(length >> 8), byte(length), byte(len(full))}
+	info = append(info, full...)
+	info = append(info, 0) // empty context
+	return hkdf.Expand(h, secret, string(info), length)
+}
+
+func newQUICKeys(suite uint16, secret []byte) (*quicKeys, error) {
+	var h func() hash.Hash
+	var keyLen int
+	switch suite {
+	case tls.TLS_AES_128_GCM_SHA256:
+		h, keyLen = sha256.New, 16
+	case tls.TLS_AES_256_GCM_SHA384:
+		h, keyLen = sha512.New384, 32
+	default:
+		return nil, fmt.Errorf("unsupported QUIC cipher suite %s", tls.CipherSuiteName(suite))
+	}
+	key, err := quicExpandLabel(h, secret, "quic key", keyLen)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := quicExpandLabel(h, secret, "quic iv", 12)
+	if err != nil {
+		return nil, err
+	}
+	hpKey, err := quicExpandLabel(h, secret, "quic hp", keyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	hp, err := aes.NewCipher(hpKey)
+	if err != nil {
+		return nil, err
+	}
+	return &quicKeys{aead: aead, iv: iv, hp: hp}, nil
+}
+
+// quicInitialKeys derives the client and server Initial keys from the
+// client-chosen destination connection ID.
+func quicInitialKeys(dcid []byte) (client, server *quicKeys, err error) {
+	initial, err := hkdf.Extract(sha256.New, dcid, quicInitialSalt)
+	if err != nil {
+		return nil, nil, err
+	}
+	cs, err := quicExpandLabel(sha256.New, initial, "client in", 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	ss, err := quicExpandLabel(sha256.New, initial, "server in", 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client, err = newQUICKeys(tls.TLS_AES_128_GCM_SHA256, cs); err != nil {
+		return nil, nil, err
+	}
+	server, err = newQUICKeys(tls.TLS_AES_128_GCM_SHA256, ss)
+	return client, server, err
+}
+
+func (k *quicKeys) nonce(pn uint64) []byte {
+	n := append([]byte(nil), k.iv...)
+	for i := 0; i < 8; i++ {
+		n[len(n)-1-i] ^= byte(pn >> (8 * i))
+	}
+	return n
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return append(b, byte(v>>8)|0x40, byte(v))
+	case v < 1<<30:
+		return append(b, byte(v>>24)|0x80, byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return append(b, byte(v>>56)|0xc0, byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+}
+
+func readVarint(b []byte) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, errors.New("truncated varint")
+	}
+	n := 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, 0, errors.New("truncated varint")
+	}
+	v := uint64(b[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n, nil
+}
+
+// quicSpace tracks per-encryption-level state: keys, packet numbers,
+// received packets to acknowledge and CRYPTO stream reassembly.
+type quicSpace struct {
+	level      tls.QUICEncryptionLevel
+	packetType byte
+	read       *quicKeys
+	write      *quicKeys
+	nextPN     uint64
+	received   map[uint64]bool
+	needsAck   bool
+	sendOffset uint64
+	recvOffset uint64
+	pending    map[uint64][]byte
+}
+
+type quicProbe struct {
+	conn     *net.UDPConn
+	tls      *tls.QUICConn
+	dcid     []byte
+	scid     []byte
+	token    []byte
+	spaces   [2]*quicSpace // Initial, Handshake
+	hello    []byte        // ClientHello, kept for retransmission and Retry
+	done     bool
+	hadRetry bool
+}
+
+// quicTransportParameters encodes the client's RFC 9000 transport parameters.
+func quicTransportParameters(scid []byte) []byte {
+	var p []byte
+	param := func(id uint64, value []byte) {
+		p = appendVarint(p, id)
+		p = appendVarint(p, uint64(len(value)))
+		p = append(p, value...)
+	}
+	param(0x01, appendVarint(nil, 10000)) // max_idle_timeout (ms)
+	param(0x04, appendVarint(nil, 1<<16)) // initial_max_data
+	param(0x05, appendVarint(nil, 1<<15)) // initial_max_stream_data_bidi_local
+	param(0x06, appendVarint(nil, 1<<15)) // initial_max_stream_data_bidi_remote
+	param(0x07, appendVarint(nil, 1<<15)) // initial_max_stream_data_uni
+	param(0x0f, scid)                     // initial_source_connection_id
+	return p
+}
+
+// compareQUICCertificate fetches the QUIC leaf certificate for hostPort and
+// describes how it relates to the certificate served over TCP.
+func compareQUICCertificate(ctx context.Context, hostPort string, timeout time.Duration, tcpLeaf *x509.Certificate) string {
+	chain, err := fetchQUICCertificates(ctx, hostPort, timeout)
+	if err != nil {
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] QUIC probe failed for %s: %v\n", hostPort, err)
+		}
+		return fmt.Sprintf("no certificate retrieved over UDP (%v)", err)
+	}
+	quicFP, tcpFP := certFingerprint(chain[0]), certFingerprint(tcpLeaf)
+	if quicFP == tcpFP {
+		return "certificate matches TCP"
+	}
+	return fmt.Sprintf("CERTIFICATE MISMATCH - QUIC serves %s (expires %s, fingerprint %s...) but TCP serves fingerprint %s...",
+		chain[0].Subject.CommonName, stamp(chain[0].NotAfter), quicFP[:16], tcpFP[:16])
+}
+
+// fetchQUICCertificates retrieves the certificate chain a server presents
+// over QUIC (ALPN h3) on the given UDP host:port. Cancelling ctx abandons
+// the handshake.
+func fetchQUICCertificates(ctx context.Context, hostPort string, timeout time.Duration) ([]*x509.Certificate, error) {
+	hostname, _, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := (&net.Dialer{Resolver: dnsResolver}).DialContext(ctx, "udp", hostPort)
+	if err != nil {
+		return nil, err
+	}
+	udp := conn.(*net.UDPConn)
+	defer udp.Close()
+	// Wake a pending read as soon as ctx ends.
+	defer context.AfterFunc(ctx, func() { udp.SetReadDeadline(time.Now()) })()
+
+	p := &quicProbe{conn: udp, dcid: make([]byte, 8), scid: make([]byte, 8)}
+	rand.Read(p.dcid)
+	rand.Read(p.scid)
+	p.spaces[0] = &quicSpace{level: tls.QUICEncryptionLevelInitial, packetType: quicPacketInitial, received: map[uint64]bool{}, pending: map[uint64][]byte{}}
+	p.spaces[1] = &quicSpace{level: tls.QUICEncryptionLevelHandshake, packetType: quicPacketHandshake, received: map[uint64]bool{}, pending: map[uint64][]byte{}}
+	if p.spaces[0].write, p.spaces[0].read, err = quicInitialKeys(p.dcid); err != nil {
+		return nil, err
+	}
+
+	serverName := hostname
+	if net.ParseIP(hostname) != nil {
+		serverName = ""
+	}
+	p.tls = tls.QUICClient(&tls.QUICConfig{TLSConfig: &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         []string{"h3"},
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: true, // Certificates are collected for comparison, not trusted
+	}})
+	defer p.tls.Close()
+	p.tls.SetTransportParameters(quicTransportParameters(p.scid))
+
+	if err := p.tls.Start(ctx); err != nil {
+		return nil, err
+	}
+	if err := p.processEvents(); err != nil {
+		return nil, err
+	}
+
+	deadline, _ := ctx.Deadline()
+	buf := make([]byte, 65536)
+	for !p.done {
+		if err := ctx.Err(); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("QUIC handshake timed out")
+			}
+			return nil, err
+		}
+		wait := time.Until(deadline)
+		if wait > time.Second {
+			wait = time.Second
+		}
+		udp.SetReadDeadline(time.Now().Add(wait))
+		n, err := udp.Read(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				// Probe timeout: retransmit the ClientHello (new packet number).
+				if err := p.resendHello(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, err
+		}
+		if err := p.handleDatagram(append([]byte(nil), buf[:n]...)); err != nil {
+			return nil, err
+		}
+		if err := p.processEvents(); err != nil {
+			return nil, err
+		}
+		if !p.done {
+			if err := p.sendAcks(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	p.sendClose()
+	state := p.tls.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, errors.New("no certificates received over QUIC")
+	}
+	return state.PeerCertificates, nil
+}
+
+// processEvents drains crypto/tls events: key installation, outgoing
+// handshake data and handshake completion.
+func (p *quicProbe) processEvents() error {
+	for {
+		e := p.tls.NextEvent()
+		switch e.Kind {
+		case tls.QUICNoEvent:
+			return nil
+		case tls.QUICSetReadSecret, tls.QUICSetWriteSecret:
+			if e.Level != tls.QUICEncryptionLevelHandshake {
+				continue // 1-RTT keys are not needed to read the certificate
+			}
+			keys, err := newQUICKeys(e.Suite, e.Data)
+			if err != nil {
+				return err
+			}
+			if e.Kind == tls.QUICSetReadSecret {
+				p.spaces[1].read = keys
+			} else {
+				p.spaces[1].write = keys
+			}
+		case tls.QUICWriteData:
+			if e.Level == tls.QUICEncryptionLevelInitial {
+				p.hello = append(p.hello, e.Data...)
+				if err := p.sendCrypto(p.spaces[0], e.Data); err != nil {
+					return err
+				}
+			}
+			// Handshake-level data (client Finished) is not needed: the
+			// connection is closed once the server's flight is verified.
+		case tls.QUICHandshakeDone:
+			p.done = true
+		}
+	}
+}
+
+func (p *quicProbe) sendCrypto(s *quicSpace, data []byte) error {
+	frame := []byte{0x06}
+	frame = appendVarint(frame, s.sendOffset)
+	frame = appendVarint(frame, uint64(len(data)))
+	frame = append(frame, data...)
+	s.sendOffset += uint64(len(data))
+	return p.sendPacket(s, frame)
+}
+
+func (p *quicProbe) resendHello() error {
+	if len(p.hello) == 0 {
+		return nil
+	}
+	s := p.spaces[0]
+	frame := []byte{0x06, 0x00}
+	frame = appendVarint(frame, uint64(len(p.hello)))
+	frame = append(frame, p.hello...)
+	return p.sendPacket(s, frame)
+}
+
+// sendPacket protects and sends one long-header packet; Initial packets are
+// padded so the datagram reaches the 1200-byte minimum.
+func (p *quicProbe) sendPacket(s *quicSpace, payload []byte) error {
+	if s.write == nil {
+		return nil
+	}
+	hdr := []byte{0xc0 | s.packetType<<4 | 0x01} // 2-byte packet number
+	hdr = binary.BigEndian.AppendUint32(hdr, quicVersion1)
+	hdr = append(hdr, byte(len(p.dcid)))
+	hdr = append(hdr, p.dcid...)
+	hdr = append(hdr, byte(len(p.scid)))
+	hdr = append(hdr, p.scid...)
+	if s.packetType == quicPacketInitial {
+		hdr = appendVarint(hdr, uint64(len(p.token)))
+		hdr = append(hdr, p.token...)
+	}
+	overhead := len(hdr) + 2 + 2 + 16 // length field, packet number, AEAD tag
+	if s.packetType == quicPacketInitial && overhead+len(payload) < quicMinDatagram {
+		payload = append(payload, make([]byte, quicMinDatagram-overhead-len(payload))...)
+	}
+	for len(payload) < 4 {
+		payload = append(payload, 0) // header protection needs a 16-byte sample
+	}
+	length := 2 + len(payload) + 16
+	hdr = append(hdr, byte(length>>8)|0x40, byte(length))
+	pnOffset := len(hdr)
+	pn := s.nextPN
+	s.nextPN++
+	hdr = append(hdr, byte(pn>>8), byte(pn))
+
+	pkt := s.write.aead.Seal(hdr, s.write.nonce(pn), payload, hdr)
+	mask := make([]byte, 16)
+	s.write.hp.Encrypt(mask, pkt[pnOffset+4:pnOffset+20])
+	pkt[0] ^= mask[0] & 0x0f
+	pkt[pnOffset] ^= mask[1]
+	pkt[pnOffset+1] ^= mask[2]
+	_, err := p.conn.Write(pkt)
+	return err
+}
+
+// handleDatagram decrypts each coalesced long-header packet in a datagram.
+func (p *quicProbe) handleDatagram(d []byte) error {
+	for len(d) > 0 {
+		if d[0]&0x80 == 0 {
+			return nil // short header (1-RTT): not needed
+		}
+		if len(d) < 7 {
+			return errors.New("truncated QUIC packet")
+		}
+		version := binary.BigEndian.Uint32(d[1:5])
+		if version == 0 {
+			return errors.New("server does not support QUIC version 1 (version negotiation)")
+		}
+		off := 5
+		dcidLen := int(d[off])
+		off += 1 + dcidLen
+		if off >= len(d) {
+			return errors.New("truncated QUIC packet")
+		}
+		scidLen := int(d[off])
+		if off+1+scidLen > len(d) {
+			return errors.New("truncated QUIC packet")
+		}
+		scid := d[off+1 : off+1+scidLen]
+		off += 1 + scidLen
+		ptype := (d[0] >> 4) & 0x03
+
+		if ptype == quicPacketRetry {
+			return p.handleRetry(d, scid)
+		}
+		if ptype == quicPacketInitial {
+			tokenLen, n, err := readVarint(d[off:])
+			if err != nil {
+				return err
+			}
+			off += n + int(tokenLen)
+		}
+		if off > len(d) {
+			return errors.New("truncated QUIC packet")
+		}
+		length, n, err := readVarint(d[off:])
+		if err != nil {
+			return err
+		}
+		off += n
+		end := off + int(length)
+		if end > len(d) || length < 20 {
+			return errors.New("truncated QUIC packet")
+		}
+
+		var s *quicSpace
+		switch ptype {
+		case quicPacketInitial:
+			s = p.spaces[0]
+			p.dcid = append([]byte(nil), scid...) // Switch to the server-chosen connection ID
+		case quicPacketHandshake:
+			s = p.spaces[1]
+		}
+		if s != nil && s.read != nil {
+			if err := p.openPacket(s, d[:end], off); err != nil {
+				return err
+			}
+		}
+		d = d[end:]
+	}
+	return nil
+}
+
+func (p *quicProbe) openPacket(s *quicSpace, raw []byte, pnOffset int) error {
+	pkt := append([]byte(nil), raw...)
+	mask := make([]byte, 16)
+	s.read.hp.Encrypt(mask, pkt[pnOffset+4:pnOffset+20])
+	pkt[0] ^= mask[0] & 0x0f
+	pnLen := int(pkt[0]&0x03) + 1
+	var pn uint64
+	for i := 0; i < pnLen; i++ {
+		pkt[pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(pkt[pnOffset+i])
+	}
+	header := pkt[:pnOffset+pnLen]
+	payload, err := s.read.aead.Open(nil, s.read.nonce(pn), pkt[pnOffset+pnLen:], header)
+	if err != nil {
+		return nil // Undecryptable packets are dropped, as QUIC requires
+	}
+	s.received[pn] = true
+	return p.handleFrames(s, payload)
+}
+
+func (p *quicProbe) handleFrames(s *quicSpace, b []byte) error {
+	for len(b) > 0 {
+		ft := b[0]
+		switch {
+		case ft == 0x00: // PADDING
+			b = b[1:]
+		case ft == 0x01: // PING
+			s.needsAck = true
+			b = b[1:]
+		case ft == 0x02 || ft == 0x03: // ACK
+			rest := b[1:]
+			var vals [4]uint64
+			for i := range vals {
+				v, n, err := readVarint(rest)
+				if err != nil {
+					return err
+				}
+				vals[i], rest = v, rest[n:]
+			}
+			skip := int(vals[2]) * 2 // gap + range length per additional range
+			if ft == 0x03 {
+				skip += 3 // ECN counts
+			}
+			for i := 0; i < skip; i++ {
+				_, n, err := readVarint(rest)
+				if err != nil {
+					return err
+				}
+				rest = rest[n:]
+			}
+			b = rest
+		case ft == 0x06: // CRYPTO
+			s.needsAck = true
+			off, n1, err := readVarint(b[1:])
+			if err != nil {
+				return err
+			}
+			length, n2, err := readVarint(b[1+n1:])
+			if err != nil {
+				return err
+			}
+			start := 1 + n1 + n2
+			if start+int(length) > len(b) {
+				return errors.New("truncated CRYPTO frame")
+			}
+			if err := p.receiveCrypto(s, off, b[start:start+int(length)]); err != nil {
+				return err
+			}
+			b = b[start+int(length):]
+		case ft == 0x1c || ft == 0x1d: // CONNECTION_CLOSE
+			rest := b[1:]
+			code, n, _ := readVarint(rest)
+			rest = rest[n:]
+			if ft == 0x1c {
+				_, n, _ = readVarint(rest)
+				rest = rest[n:]
+			}
+			reasonLen, n, _ := readVarint(rest)
+			reason := ""
+			if n+int(reasonLen) <= len(rest) {
+				reason = string(rest[n : n+int(reasonLen)])
+			}
+			if code >= 0x100 && code < 0x200 {
+				return fmt.Errorf("server closed QUIC connection: TLS alert %d %s", code-0x100, reason)
+			}
+			return fmt.Errorf("server closed QUIC connection: error 0x%x %s", code, reason)
+		default:
+			return fmt.Errorf("unexpected QUIC frame type 0x%x during handshake", ft)
+		}
+	}
+	return nil
+}
+
+// receiveCrypto reassembles CRYPTO frames in offset order before handing
+// them to crypto/tls.
+func (p *quicProbe) receiveCrypto(s *quicSpace, off uint64, data []byte) error {
+	if off+uint64(len(data)) <= s.recvOffset {
+		return nil // duplicate
+	}
+	if off > s.recvOffset {
+		s.pending[off] = append([]byte(nil), data...)
+		return nil
+	}
+	data = data[s.recvOffset-off:]
+	for {
+		if err := p.tls.HandleData(s.level, data); err != nil {
+			return err
+		}
+		s.recvOffset += uint64(len(data))
+		next, ok := s.pending[s.recvOffset]
+		if !ok {
+			// Drop buffered frames that are now fully covered.
+			for o, d := range s.pending {
+				if o+uint64(len(d)) <= s.recvOffset {
+					delete(s.pending, o)
+				}
+			}
+			return nil
+		}
+		delete(s.pending, s.recvOffset)
+		data = next
+	}
+}
+
+// handleRetry restarts the handshake with the server-supplied token and
+// connection ID (RFC 9000 section 17.2.5). Only one Retry is accepted.
+func (p *quicProbe) handleRetry(d []byte, scid []byte) error {
+	if p.hadRetry || len(d) < 16 {
+		return nil
+	}
+	p.hadRetry = true
+	off := 7 + int(d[5]) + len(scid)
+	if off > len(d)-16 {
+		return errors.New("malformed QUIC Retry packet")
+	}
+	p.token = append([]byte(nil), d[off:len(d)-16]...)
+	p.dcid = append([]byte(nil), scid...)
+	var err error
+	if p.spaces[0].write, p.spaces[0].read, err = quicInitialKeys(p.dcid); err != nil {
+		return err
+	}
+	return p.resendHello()
+}
+
+// ackFrame builds an ACK frame covering all received packet numbers.
+func ackFrame(received map[uint64]bool) []byte {
+	pns := make([]uint64, 0, len(received))
+	for pn := range received {
+		pns = append(pns, pn)
+	}
+	sort.Slice(pns, func(i, j int) bool { return pns[i] > pns[j] })
+
+	type span struct{ hi, lo uint64 }
+	var spans []span
+	for _, pn := range pns {
+		if n := len(spans); n > 0 && spans[n-1].lo == pn+1 {
+			spans[n-1].lo = pn
+			continue
+		}
+		spans = append(spans, span{pn, pn})
+	}
+	frame := []byte{0x02}
+	frame = appendVarint(frame, spans[0].hi)
+	frame = appendVarint(frame, 0) // ACK delay
+	frame = appendVarint(frame, uint64(len(spans)-1))
+	frame = appendVarint(frame, spans[0].hi-spans[0].lo)
+	for i := 1; i < len(spans); i++ {
+		frame = appendVarint(frame, spans[i-1].lo-spans[i].hi-2)
+		frame = appendVarint(frame, spans[i].hi-spans[i].lo)
+	}
+	return frame
+}
+
+// sendAcks acknowledges received packets so the server can continue past its
+// anti-amplification limit when the certificate chain is large.
+func (p *quicProbe) sendAcks() error {
+	for _, s := range p.spaces {
+		if !s.needsAck || len(s.received) == 0 || s.write == nil {
+			continue
+		}
+		s.needsAck = false
+		if err := p.sendPacket(s, ackFrame(s.received)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendClose politely closes the connection at the Handshake level.
+func (p *quicProbe) sendClose() {
+	s := p.spaces[1]
+	if s.write == nil {
+		return
+	}
+	frame := []byte{0x1c}
+	frame = appendVarint(frame, 0) // NO_ERROR
+	frame = appendVarint(frame, 0) // frame type
+	frame = appendVarint(frame, 0) // reason length
+	p.sendPacket(s, frame)
+}
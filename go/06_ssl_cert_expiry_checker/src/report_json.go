@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// jsonResult is one host in the --format json report.
+type jsonResult struct {
+	Host            string         `json:"host"`
+	Status          string         `json:"status"`
+	ExpiryDate      *time.Time     `json:"expiry_date,omitempty"`
+	DaysLeft        *int           `json:"days_left,omitempty"`
+	Fingerprint     string         `json:"sha256,omitempty"`
+	Issuer          string         `json:"issuer,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	ErrorClass      string         `json:"error_class,omitempty"` // See errclass.go
+	PolicyViolation string         `json:"policy_violation,omitempty"`
+	UsageViolation  string         `json:"key_usage_violation,omitempty"`
+	TrustError      string         `json:"verification_error,omitempty"`
+	Warnings        []string       `json:"warnings,omitempty"`
+	QUIC            string         `json:"quic,omitempty"`
+	RotationNote    string         `json:"rotation_note,omitempty"`
+	RenewalHint     string         `json:"renewal_hint,omitempty"`
+	Acknowledged    string         `json:"acknowledged,omitempty"`
+	HookOutput      string         `json:"renew_hook_output,omitempty"`
+	HookError       string         `json:"renew_hook_error,omitempty"`
+	Diagnostics     *handshakeDiag `json:"diagnostics,omitempty"`
+	Labels          labelSet       `json:"labels,omitempty"`
+}
+
+// jsonReport is the --format json report.
+type jsonReport struct {
+	Tool        string       `json:"tool"`
+	Version     string       `json:"version"`
+	GitCommit   string       `json:"git_commit,omitempty"`
+	BuildDate   string       `json:"build_date,omitempty"`
+	Hostname    string       `json:"hostname"`
+	CheckedAt   time.Time    `json:"checked_at"`
+	Interrupted bool         `json:"interrupted"`
+	StoppedAt   string       `json:"fail_fast_stopped_at,omitempty"` // Host that ended a --fail-fast run
+	Results     []jsonResult `json:"results"`
+	// ByLabel is the --by-label table, keyed by the label name
+	ByLabel   map[string][]labelSummary `json:"by_label,omitempty"`
+	SelfStats *selfStats                `json:"self_stats,omitempty"`
+}
+
+// writeJSONReport writes the per-host results as one JSON document.
+// stoppedAt is the host that ended a --fail-fast run, or "".
+func writeJSONReport(results []CertCheckResult, interrupted bool, stoppedAt string, w io.Writer) error {
+	hostname, _ := os.Hostname()
+	build := currentBuild()
+	rep := jsonReport{
+		Tool:        toolName,
+		Version:     build.Version,
+		GitCommit:   build.GitCommit,
+		BuildDate:   build.BuildDate,
+		Hostname:    hostname,
+		CheckedAt:   time.Now().In(reportTZ),
+		Interrupted: interrupted,
+		StoppedAt:   stoppedAt,
+		Results:     []jsonResult{},
+	}
+	if byLabel != "" {
+		rep.ByLabel = map[string][]labelSummary{byLabel: summarizeByLabel(results, byLabel, warnDays, time.Now())}
+	}
+	if selfStatsOn {
+		s := collectSelfStats(len(results), "hosts")
+		rep.SelfStats = &s
+	}
+	for _, r := range results {
+		jr := jsonResult{
+			Host:            r.Host,
+			Status:          r.Status,
+			PolicyViolation: r.PolicyViolation,
+			UsageViolation:  r.UsageViolation,
+			TrustError:      r.TrustError,
+			Warnings:        r.Warnings,
+			QUIC:            r.QUICNote,
+			RotationNote:    r.RotationNote,
+			RenewalHint:     r.RenewalHint,
+			Acknowledged:    r.Ack,
+			HookOutput:      r.HookOutput,
+			Diagnostics:     r.Diagnostics,
+			Labels:          r.Labels,
+		}
+		if !r.ExpiryDate.IsZero() {
+			expiry, days := r.ExpiryDate.In(reportTZ), r.DaysLeft
+			jr.ExpiryDate, jr.DaysLeft = &expiry, &days
+		}
+		if len(r.Chain) > 0 {
+			jr.Fingerprint = certFingerprint(r.Chain[0])
+			jr.Issuer = r.Chain[0].Issuer.String()
+		}
+		if r.Error != nil {
+			jr.Error, jr.ErrorClass = r.Error.Error(), classifyError(r.Error)
+		}
+		if r.HookError != nil {
+			jr.HookError = r.HookError.Error()
+		}
+		rep.Results = append(rep.Results, jr)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// Input sources. An -i value of "-" reads the list from stdin, so the tools
+// can be chained, each taking the host:port or URL list another one printed:
+//
+//	network_service_monitor -i services.txt --emit-open | ssl_cert_expiry_checker -i -
+//
+// Stdin is not hashed into run manifests, as it cannot be read twice.
+//
+// Every tool that reads its targets from stdin carries an identical copy of
+// this file (scripts/check_shared_go.py).
+
+// openInput opens an -i value for reading; "-" is stdin.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// inputName describes an -i value in messages.
+func inputName(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	return path
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+)
+
+// loadCABundle reads the PEM trust anchors for --ca-bundle. Blocks other than
+// certificates (e.g. a key left in the file) are an error rather than being
+// skipped, so a wrong file is not silently treated as an empty trust store.
+func loadCABundle(filePath string) (*x509.CertPool, int, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read CA bundle %s: %w", filePath, err)
+	}
+	pool := x509.NewCertPool()
+	n := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, 0, fmt.Errorf("CA bundle %s: unexpected PEM block %q", filePath, block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, 0, fmt.Errorf("CA bundle %s: certificate %d: %w", filePath, n+1, err)
+		}
+		pool.AddCert(cert)
+		n++
+	}
+	if n == 0 {
+		return nil, 0, fmt.Errorf("CA bundle %s contains no PEM certificates", filePath)
+	}
+	return pool, n, nil
+}
+
+// verifyChain performs full verification of a presented chain: it must lead
+// from the leaf, through the intermediates the server sent, to one of roots,
+// be valid at now for TLS server authentication, and cover hostname (a DNS
+// name or IP address).
+func verifyChain(chain []*x509.Certificate, hostname string, roots *x509.CertPool, now time.Time) error {
+	opts := x509.VerifyOptions{
+		DNSName:       hostname,
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   now,
+	}
+	for _, c := range chain[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := chain[0].Verify(opts)
+	return err
+}
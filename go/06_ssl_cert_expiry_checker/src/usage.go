@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// ekuNames maps extended key usages to their RFC 5280 names.
+var ekuNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "serverAuth",
+	x509.ExtKeyUsageClientAuth:      "clientAuth",
+	x509.ExtKeyUsageCodeSigning:     "codeSigning",
+	x509.ExtKeyUsageEmailProtection: "emailProtection",
+	x509.ExtKeyUsageTimeStamping:    "timeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+	x509.ExtKeyUsageIPSECEndSystem:  "ipsecEndSystem",
+	x509.ExtKeyUsageIPSECTunnel:     "ipsecTunnel",
+	x509.ExtKeyUsageIPSECUser:       "ipsecUser",
+}
+
+// checkKeyUsage checks that a leaf certificate was issued for TLS server
+// authentication. It returns a violation when the certificate restricts its
+// use to other purposes (an EKU without serverAuth, e.g. a client or
+// code-signing certificate, or key usage bits that do not allow the TLS
+// handshake), plus warnings for looser problems that clients tolerate.
+func checkKeyUsage(cert *x509.Certificate) (string, []string) {
+	var violations, warnings []string
+
+	if len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+		warnings = append(warnings, "certificate has no extended key usage; publicly trusted TLS certificates must list serverAuth")
+	} else {
+		server := false
+		var names []string
+		for _, u := range cert.ExtKeyUsage {
+			server = server || u == x509.ExtKeyUsageServerAuth || u == x509.ExtKeyUsageAny
+			if n, ok := ekuNames[u]; ok {
+				names = append(names, n)
+			} else {
+				names = append(names, fmt.Sprintf("eku(%d)", u))
+			}
+		}
+		for _, oid := range cert.UnknownExtKeyUsage {
+			names = append(names, oid.String())
+		}
+		if !server {
+			violations = append(violations, "extended key usage is "+strings.Join(names, ", ")+" without serverAuth; the certificate was issued for another purpose")
+		}
+	}
+
+	// A missing KeyUsage extension places no restriction.
+	if ku := cert.KeyUsage; ku != 0 {
+		_, isRSA := cert.PublicKey.(*rsa.PublicKey)
+		switch {
+		case isRSA && ku&(x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment) == 0:
+			violations = append(violations, "key usage allows neither digitalSignature nor keyEncipherment, so the key cannot be used in a TLS handshake")
+		case !isRSA && ku&x509.KeyUsageDigitalSignature == 0:
+			violations = append(violations, "key usage lacks digitalSignature, which an ECDSA or Ed25519 server key needs to sign the TLS handshake")
+		case isRSA && ku&x509.KeyUsageDigitalSignature == 0:
+			warnings = append(warnings, "RSA key usage lacks digitalSignature; only RSA key exchange works, which TLS 1.3 and ECDHE suites do not use")
+		}
+		if ku&(x509.KeyUsageCertSign|x509.KeyUsageCRLSign) != 0 && !cert.IsCA {
+			warnings = append(warnings, "leaf certificate has keyCertSign or cRLSign key usage")
+		}
+	}
+	// Self-signed certificates are CA:TRUE by default with openssl req -x509.
+	if cert.BasicConstraintsValid && cert.IsCA && cert.Issuer.String() != cert.Subject.String() {
+		warnings = append(warnings, "server presents a CA certificate (basicConstraints CA:TRUE) as its leaf")
+	}
+	return strings.Join(violations, "; "), warnings
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Fleet aggregation: --aggregate merges the --format json verification
+// reports of many hosts into one summary, grouped by finding type (MODIFIED,
+// ADDED, DELETED, LABEL_CHANGED, SPARSE_CHANGED) and, within each type, by
+// host.
+
+// findingTypes are the report statuses that count as findings, in report order.
+var findingTypes = []string{"MODIFIED", "DELETED", "ADDED", "LABEL_CHANGED", "SPARSE_CHANGED"}
+
+// fleetHost is one host's latest report.
+type fleetHost struct {
+	Hostname    string         `json:"hostname"`
+	Report      string         `json:"report"`
+	RunID       string         `json:"run_id"`
+	BaselineSHA string         `json:"baseline_sha256,omitempty"`
+	ScanStart   time.Time      `json:"scan_start"`
+	Interrupted bool           `json:"interrupted"`
+	Files       int            `json:"files"`
+	Counts      map[string]int `json:"counts"`
+	Changes     int            `json:"changes"`
+	entries     []jsonEntry
+}
+
+// fleetGroup lists the paths one host reported under one finding type.
+type fleetGroup struct {
+	Hostname string   `json:"hostname"`
+	Paths    []string `json:"paths"`
+}
+
+// fleetSummary is the aggregated view written by --aggregate.
+type fleetSummary struct {
+	Tool      string                  `json:"tool"`
+	Version   string                  `json:"version"`
+	Generated time.Time               `json:"generated"`
+	Reports   int                     `json:"reports"`
+	Skipped   []string                `json:"skipped,omitempty"`
+	Hosts     []*fleetHost            `json:"hosts"`
+	Counts    map[string]int          `json:"counts"`
+	Findings  map[string][]fleetGroup `json:"findings"`
+}
+
+// aggregateFiles expands the --aggregate pattern. When the shell has already
+// expanded an unquoted pattern, the remaining names arrive as extra arguments.
+func aggregateFiles(pattern string, extra []string) ([]string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --aggregate pattern %q: %v", pattern, err)
+	}
+	files = append(files, extra...)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no reports match %s", pattern)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// aggregateReports reads the reports and builds the fleet summary. A file
+// that is not a JSON report of this tool is skipped with a warning, so one
+// broken upload does not hide the rest of the fleet. When a host appears in
+// several reports, only its most recent scan counts.
+func aggregateReports(files []string) (*fleetSummary, error) {
+	build := currentBuild()
+	s := &fleetSummary{Tool: toolName, Version: build.Version, Generated: time.Now().In(reportTZ), Counts: map[string]int{}, Findings: map[string][]fleetGroup{}}
+	latest := map[string]*fleetHost{}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		var rep jsonReport
+		if err == nil {
+			err = json.Unmarshal(data, &rep)
+		}
+		if err == nil && (rep.Tool != toolName || rep.Hostname == "") {
+			err = fmt.Errorf("not a %s JSON report", toolName)
+		}
+		if err != nil {
+			warnf("Skipping %s: %v", f, err)
+			s.Skipped = append(s.Skipped, f)
+			continue
+		}
+		s.Reports++
+		h := &fleetHost{Hostname: rep.Hostname, Report: f, RunID: rep.RunID, BaselineSHA: rep.BaselineSHA, ScanStart: rep.ScanStart.In(reportTZ),
+			Interrupted: rep.Interrupted, Files: rep.Files, Counts: rep.Counts, entries: rep.Entries}
+		if h.Counts == nil {
+			h.Counts = map[string]int{}
+			for _, e := range rep.Entries {
+				h.Counts[e.Status]++
+			}
+		}
+		if prev, ok := latest[h.Hostname]; ok {
+			if !h.ScanStart.After(prev.ScanStart) {
+				debugf("%s: older report %s ignored", h.Hostname, f)
+				continue
+			}
+			debugf("%s: older report %s replaced by %s", h.Hostname, prev.Report, f)
+		}
+		latest[h.Hostname] = h
+	}
+	if s.Reports == 0 {
+		return nil, fmt.Errorf("none of the %d file(s) is a verification report", len(files))
+	}
+
+	for _, h := range latest {
+		s.Hosts = append(s.Hosts, h)
+	}
+	sort.Slice(s.Hosts, func(i, j int) bool { return s.Hosts[i].Hostname < s.Hosts[j].Hostname })
+	for _, typ := range findingTypes {
+		s.Findings[typ] = []fleetGroup{}
+		for _, h := range s.Hosts {
+			g := fleetGroup{Hostname: h.Hostname}
+			for _, e := range h.entries {
+				if e.Status == typ {
+					g.Paths = append(g.Paths, e.Path)
+				}
+			}
+			if len(g.Paths) > 0 {
+				sort.Strings(g.Paths)
+				s.Findings[typ] = append(s.Findings[typ], g)
+				s.Counts[typ] += len(g.Paths)
+				h.Changes += len(g.Paths)
+			}
+		}
+	}
+	return s, nil
+}
+
+// changedHosts counts the hosts with at least one finding.
+func (s *fleetSummary) changedHosts() int {
+	n := 0
+	for _, h := range s.Hosts {
+		if h.Changes > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// writeFleetReport writes the fleet summary as text: totals, then each
+// finding type with the hosts and paths it was seen on, then one line per
+// host.
+func writeFleetReport(s *fleetSummary, w io.Writer) {
+	fmt.Fprintln(w, "--- Fleet Integrity Summary ---")
+	fmt.Fprintf(w, "\nReports: %d (%d host(s), %d with changes)\n", s.Reports, len(s.Hosts), s.changedHosts())
+	if len(s.Skipped) > 0 {
+		fmt.Fprintf(w, "Skipped: %d unreadable or foreign file(s)\n", len(s.Skipped))
+	}
+	for _, typ := range findingTypes {
+		fmt.Fprintf(w, "%s: %d file(s) on %d host(s)\n", colorStatus(typ), s.Counts[typ], len(s.Findings[typ]))
+	}
+	for _, typ := range findingTypes {
+		if len(s.Findings[typ]) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n== %s ==\n", colorStatus(typ))
+		for _, g := range s.Findings[typ] {
+			fmt.Fprintf(w, "%s (%d)\n", g.Hostname, len(g.Paths))
+			for _, p := range g.Paths {
+				fmt.Fprintf(w, "  %s\n", p)
+			}
+		}
+	}
+	fmt.Fprintln(w, "\n== Hosts ==")
+	for _, h := range s.Hosts {
+		status := colorStatus("OK")
+		if h.Changes > 0 {
+			status = fmt.Sprintf("%d change(s)", h.Changes)
+		}
+		note := ""
+		if h.Interrupted {
+			note = ", interrupted"
+		}
+		fmt.Fprintf(w, "%s: %s (%d files, scanned %s%s)\n", h.Hostname, status, h.Files, stamp(h.ScanStart), note)
+	}
+}
+
+// writeFleetJSON writes the fleet summary as one JSON document.
+func writeFleetJSON(s *fleetSummary, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// runAggregate is the --aggregate mode. Nothing is hashed, so hooks, alerts
+// and the scan options do not apply. The exit status is 1 when any host
+// reported a change, as for a single verification.
+func runAggregate(extra []string, signKey ed25519.PrivateKey) int {
+	files, err := aggregateFiles(aggregateArg, extra)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Aggregating %d report(s)...\n", len(files))
+	}
+	s, err := aggregateReports(files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	out, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		return 1
+	}
+	enableColor(sinkFile(out))
+	out = signSink(out, signKey, outputFile)
+	if format == "json" {
+		if err := writeFleetJSON(s, out); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+		}
+	} else {
+		writeFleetReport(s, out)
+	}
+	if !closeSink(out) {
+		return 1
+	}
+	code := 0
+	if s.changedHosts() > 0 {
+		code = 1
+	}
+	writeManifest(code, files, []string{outputFile, signatureTarget(outputFile)})
+	return code
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// goldenMessages replace the baseline wording in reports against a release
+// artifact.
+var goldenMessages = map[string]string{
+	"MODIFIED": "Differs from release artifact",
+	"ADDED":    "Not in release artifact",
+	"DELETED":  "In release artifact but missing",
+}
+
+// hashStream hashes r in chunks, checking ctx between them. Reads count
+// against --io-limit like those of live files.
+func hashStream(ctx context.Context, r io.Reader) (string, error) {
+	h := sha256.New()
+	buf := make([]byte, limiter.chunkSize())
+	for {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		n, err := r.Read(buf)
+		h.Write(buf[:n])
+		limiter.wait(ctx, n)
+		if err == io.EOF {
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// goldenPath maps an archive entry name to its installed path under root,
+// dropping the first strip components (like tar --strip-components). It
+// returns "" for entries that cannot be placed under root.
+func goldenPath(root, name string, strip int) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))[1:]
+	parts := strings.Split(name, "/")
+	if name == "" || len(parts) <= strip {
+		return ""
+	}
+	return filepath.Join(root, filepath.FromSlash(strings.Join(parts[strip:], "/")))
+}
+
+// loadGolden builds a baseline from a .tar, .tar.gz/.tgz or .zip release
+// artifact (detected from its contents), keyed by where each regular file
+// would be installed under root. Directories and other special entries are
+// not compared; the installed paths of symbolic links are returned so that
+// the files they point to are not reported as added.
+func loadGolden(ctx context.Context, artifact, root string, strip int) (Baseline, map[string]bool, error) {
+	f, err := os.Open(artifact)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+
+	base := Baseline{}
+	links := map[string]bool{}
+	add := func(name string, r io.Reader) error {
+		dest := goldenPath(root, name, strip)
+		if dest == "" {
+			debugf("golden: skipping entry %s", name)
+			return nil
+		}
+		h, err := hashStream(ctx, r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		base[dest] = h
+		return nil
+	}
+
+	if bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06")) {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, nil, err
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid zip archive: %w", err)
+		}
+		for _, e := range zr.File {
+			if e.Mode()&os.ModeSymlink != 0 {
+				if dest := goldenPath(root, e.Name, strip); dest != "" {
+					links[dest] = true
+				}
+			}
+			if !e.Mode().IsRegular() {
+				continue
+			}
+			rc, err := e.Open()
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", e.Name, err)
+			}
+			err = add(e.Name, rc)
+			rc.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return base, links, nil
+	}
+
+	var r io.Reader = br
+	if bytes.HasPrefix(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			if dest := goldenPath(root, hdr.Name, strip); dest != "" {
+				links[dest] = true
+			}
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := add(hdr.Name, tr); err != nil {
+			return nil, nil, err
+		}
+	}
+	return base, links, nil
+}
+
+// verifyGolden compares the tree under root against a release artifact.
+func verifyGolden(ctx context.Context, artifact, root string, strip int, files []string) ([]Report, error) {
+	base, links, err := loadGolden(ctx, artifact, root, strip)
+	if err != nil {
+		return nil, err
+	}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Release artifact lists %d file(s)\n", len(base))
+	}
+	var r []Report
+	for _, e := range compareBaseline(ctx, base, nil, files) {
+		if links[e.Path] && e.Status == "ADDED" {
+			continue
+		}
+		if msg, ok := goldenMessages[e.Status]; ok {
+			e.Message = msg
+		}
+		r = append(r, e)
+	}
+	return r, nil
+}
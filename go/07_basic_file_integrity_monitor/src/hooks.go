@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// hookTimeout bounds how long a --pre-hook or --post-hook command may run.
+const hookTimeout = 5 * time.Minute
+
+// hookSummary describes the run to --pre-hook and --post-hook commands
+// through FIM_* environment variables.
+type hookSummary struct {
+	Mode     string // create, verify or golden
+	Baseline string // Baseline being created or verified against
+	Report   string // Report destination ("-" for stdout)
+	Status   string // Post-hook only: ok, changes, interrupted or error
+	Files    int
+	Counts   map[string]int // Report entries per status (OK, MODIFIED, ADDED, DELETED)
+}
+
+// count tallies verification results by status.
+func (s *hookSummary) count(r []Report) {
+	s.Counts = map[string]int{}
+	for _, e := range r {
+		s.Counts[e.Status]++
+	}
+}
+
+func (s hookSummary) env(phase string) []string {
+	report := s.Report
+	if report == "" {
+		report = "-"
+	}
+	env := []string{"FIM_HOOK=" + phase, "FIM_RUN_ID=" + runID, "FIM_MODE=" + s.Mode, "FIM_BASELINE=" + s.Baseline, "FIM_REPORT=" + report}
+	if phase == "post" {
+		changes := s.Counts["MODIFIED"] + s.Counts["ADDED"] + s.Counts["DELETED"] + s.Counts["LABEL_CHANGED"] + s.Counts["SPARSE_CHANGED"]
+		env = append(env,
+			"FIM_STATUS="+s.Status,
+			"FIM_FILES="+strconv.Itoa(s.Files),
+			"FIM_OK="+strconv.Itoa(s.Counts["OK"]),
+			"FIM_MODIFIED="+strconv.Itoa(s.Counts["MODIFIED"]),
+			"FIM_ADDED="+strconv.Itoa(s.Counts["ADDED"]),
+			"FIM_DELETED="+strconv.Itoa(s.Counts["DELETED"]),
+			"FIM_CHANGES="+strconv.Itoa(changes),
+		)
+	}
+	return env
+}
+
+// runHook executes command via the shell. Its output goes to stderr so that
+// it never mixes with a report written to stdout.
+func runHook(phase, command string, s hookSummary) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Running %s-hook: %s\n", phase, command)
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), s.env(phase)...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s-hook timed out after %s", phase, hookTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s-hook failed: %w", phase, err)
+	}
+	return nil
+}
+
+// afterRun runs --post-hook, if set, with the run's outcome and returns the
+// exit code to use: a failing post-hook turns a clean run into exit status 1.
+// The run's trace is exported last, with that exit code.
+func afterRun(code int, status string, s hookSummary) int {
+	if postHook != "" {
+		s.Status = status
+		if err := runHook("post", postHook, s); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			if code == 0 {
+				code = 1
+			}
+		}
+	}
+	telemetry.finish(code)
+	return code
+}
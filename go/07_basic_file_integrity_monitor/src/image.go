@@ -0,0 +1,410 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// digestRE matches the content digests that name blobs in an OCI layout.
+var digestRE = regexp.MustCompile(`^(sha256|sha512):[0-9a-f]{64,128}$`)
+
+// imageFS gives access to the files of a container image, either an OCI
+// layout / extracted `docker save` directory or a `docker save` tarball. In
+// a tarball each member is located once and then read in place, so layers can
+// be visited in manifest order without extracting them.
+type imageFS struct {
+	dir     string
+	f       *os.File
+	entries map[string][2]int64 // Member offset and size
+}
+
+// countingReader tracks the offset in the tarball while archive/tar reads it;
+// Seek lets tar skip over member data instead of reading it.
+type countingReader struct {
+	f *os.File
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.f.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Seek(offset int64, whence int) (int64, error) {
+	n, err := c.f.Seek(offset, whence)
+	if err == nil {
+		c.n = n
+	}
+	return n, err
+}
+
+// openImageFS opens an image directory or tarball.
+func openImageFS(p string) (*imageFS, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &imageFS{dir: p}, nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	fs := &imageFS{f: f, entries: map[string][2]int64{}}
+	cr := &countingReader{f: f}
+	tr := tar.NewReader(cr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("not a docker save archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			fs.entries[path.Clean("/" + hdr.Name)[1:]] = [2]int64{cr.n, hdr.Size}
+		}
+	}
+	return fs, nil
+}
+
+func (fs *imageFS) Close() error {
+	if fs.f != nil {
+		return fs.f.Close()
+	}
+	return nil
+}
+
+func (fs *imageFS) has(name string) bool {
+	if fs.dir != "" {
+		_, err := os.Stat(filepath.Join(fs.dir, name))
+		return err == nil
+	}
+	_, ok := fs.entries[name]
+	return ok
+}
+
+// open returns a reader for name, which is confined to the image.
+func (fs *imageFS) open(name string) (io.ReadCloser, error) {
+	name = path.Clean("/" + name)[1:]
+	if fs.dir != "" {
+		return os.Open(filepath.Join(fs.dir, filepath.FromSlash(name)))
+	}
+	e, ok := fs.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: not in archive", name)
+	}
+	return io.NopCloser(io.NewSectionReader(fs.f, e[0], e[1])), nil
+}
+
+func (fs *imageFS) readJSON(name string, v any) error {
+	r, err := fs.open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// ociDescriptor references a blob in an OCI layout.
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Platform  *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+// ociManifest covers both image manifests and image indexes.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Config    ociDescriptor   `json:"config"`
+	Layers    []ociDescriptor `json:"layers"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+func blobName(digest string) (string, error) {
+	if !digestRE.MatchString(digest) {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	alg, hex, _ := strings.Cut(digest, ":")
+	return "blobs/" + alg + "/" + hex, nil
+}
+
+// pickManifest chooses the image from an index: the only entry, or else the
+// linux entry for this machine's architecture.
+func pickManifest(list []ociDescriptor) (ociDescriptor, error) {
+	if len(list) == 1 {
+		return list[0], nil
+	}
+	var match []ociDescriptor
+	for _, d := range list {
+		if d.Platform != nil && d.Platform.OS == "linux" && d.Platform.Architecture == runtime.GOARCH {
+			match = append(match, d)
+		}
+	}
+	if len(match) != 1 {
+		return ociDescriptor{}, fmt.Errorf("index lists %d manifests and %d for linux/%s; export a single image", len(list), len(match), runtime.GOARCH)
+	}
+	return match[0], nil
+}
+
+// imageLayers returns the config and layer member names of the image, from
+// the manifest.json of `docker save` or else the index.json of an OCI layout.
+func imageLayers(fs *imageFS) (string, []string, error) {
+	if fs.has("manifest.json") {
+		var m []struct {
+			Config   string
+			RepoTags []string
+			Layers   []string
+		}
+		if err := fs.readJSON("manifest.json", &m); err != nil {
+			return "", nil, err
+		}
+		if len(m) != 1 {
+			return "", nil, fmt.Errorf("archive holds %d images; save one image per archive", len(m))
+		}
+		if verboseMode && len(m[0].RepoTags) > 0 {
+			fmt.Fprintf(os.Stderr, "[INFO] Image %s\n", strings.Join(m[0].RepoTags, ", "))
+		}
+		return m[0].Config, m[0].Layers, nil
+	}
+	if !fs.has("index.json") {
+		return "", nil, fmt.Errorf("neither manifest.json (docker save) nor index.json (OCI layout) found")
+	}
+	var m ociManifest
+	if err := fs.readJSON("index.json", &m); err != nil {
+		return "", nil, err
+	}
+	for depth := 0; len(m.Manifests) > 0; depth++ {
+		d, err := pickManifest(m.Manifests)
+		if err != nil {
+			return "", nil, err
+		}
+		name, err := blobName(d.Digest)
+		if err != nil || depth > 3 {
+			return "", nil, fmt.Errorf("cannot resolve manifest %s", d.Digest)
+		}
+		m = ociManifest{}
+		if err := fs.readJSON(name, &m); err != nil {
+			return "", nil, err
+		}
+	}
+	config, err := blobName(m.Config.Digest)
+	if err != nil {
+		return "", nil, fmt.Errorf("manifest config: %w", err)
+	}
+	var layers []string
+	for _, l := range m.Layers {
+		name, err := blobName(l.Digest)
+		if err != nil {
+			return "", nil, fmt.Errorf("manifest layer: %w", err)
+		}
+		layers = append(layers, name)
+	}
+	return config, layers, nil
+}
+
+// layer is what one image layer contributes to the merged filesystem.
+type layer struct {
+	files   map[string]string // Regular files and their hashes
+	links   map[string]string // Hard links and their targets
+	removed []string          // Whiteouts, and symlinks or special files hiding lower entries
+	opaque  []string          // Directories whose lower contents are hidden
+}
+
+// readLayer reads a (possibly gzip-compressed) layer tar and returns its
+// entries with the digest of the uncompressed stream, its diff_id.
+func readLayer(ctx context.Context, r io.Reader) (*layer, string, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	var src io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		defer gz.Close()
+		src = gz
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return nil, "", fmt.Errorf("zstd-compressed layers are not supported")
+	}
+	sum := sha256.New()
+	tr := tar.NewReader(io.TeeReader(src, sum))
+
+	l := &layer{files: map[string]string{}, links: map[string]string{}}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid layer tar: %w", err)
+		}
+		name := path.Clean("/" + hdr.Name)
+		dir, base := path.Split(name)
+		switch {
+		case name == "/":
+		case base == ".wh..wh..opq":
+			l.opaque = append(l.opaque, path.Clean(dir))
+		case strings.HasPrefix(base, ".wh."):
+			l.removed = append(l.removed, path.Join(dir, base[4:]))
+		case hdr.Typeflag == tar.TypeReg:
+			h, err := hashStream(ctx, tr)
+			if err != nil {
+				return nil, "", fmt.Errorf("%s: %w", name, err)
+			}
+			l.files[name] = h
+		case hdr.Typeflag == tar.TypeLink:
+			l.links[name] = path.Clean("/" + hdr.Linkname)
+		case hdr.Typeflag != tar.TypeDir:
+			l.removed = append(l.removed, name)
+		}
+	}
+	// The diff_id covers the whole stream, including the end-of-archive blocks.
+	if _, err := io.Copy(sum, src); err != nil {
+		return nil, "", err
+	}
+	return l, "sha256:" + hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// removeTree deletes p and everything below it.
+func (b Baseline) removeTree(p string, self bool) {
+	if self {
+		delete(b, p)
+	}
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	for k := range b {
+		if strings.HasPrefix(k, prefix) {
+			delete(b, k)
+		}
+	}
+}
+
+// apply stacks l on top of the merged view b, following the OCI whiteout
+// rules: opaque directories and whiteouts only hide lower layers.
+func (b Baseline) apply(l *layer) {
+	for _, d := range l.opaque {
+		b.removeTree(d, false)
+	}
+	for _, p := range l.removed {
+		b.removeTree(p, true)
+	}
+	for p, h := range l.files {
+		b.removeTree(p, false)
+		b[p] = h
+	}
+	for p, target := range l.links {
+		if h, ok := b[target]; ok {
+			b[p] = h
+		}
+	}
+}
+
+// loadImage builds a baseline of the merged filesystem of a `docker save`
+// tarball or OCI layout directory, keyed by absolute path inside the image.
+// Each layer is checked against the diff_id recorded in the image config, so
+// a tampered layer is rejected rather than baselined.
+func loadImage(ctx context.Context, p string) (Baseline, error) {
+	fs, err := openImageFS(p)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+	configName, layers, err := imageLayers(fs)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		RootFS struct {
+			DiffIDs []string `json:"diff_ids"`
+		} `json:"rootfs"`
+	}
+	if err := fs.readJSON(configName, &config); err != nil {
+		return nil, fmt.Errorf("image config: %w", err)
+	}
+	if len(config.RootFS.DiffIDs) != len(layers) {
+		return nil, fmt.Errorf("image config lists %d diff_ids for %d layers", len(config.RootFS.DiffIDs), len(layers))
+	}
+
+	merged := Baseline{}
+	for i, name := range layers {
+		r, err := fs.open(name)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i+1, err)
+		}
+		l, diffID, err := readLayer(ctx, r)
+		r.Close()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("layer %d (%s): %w", i+1, name, err)
+		}
+		if diffID != config.RootFS.DiffIDs[i] {
+			return nil, fmt.Errorf("layer %d (%s) does not match its diff_id: got %s, config has %s", i+1, name, diffID, config.RootFS.DiffIDs[i])
+		}
+		merged.apply(l)
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Layer %d/%d: %d file(s), %d whiteout(s)\n", i+1, len(layers), len(l.files)+len(l.links), len(l.removed)+len(l.opaque))
+		}
+	}
+	return merged, nil
+}
+
+// compareImage compares the merged image view cur with a baseline, in path
+// order.
+func compareImage(base, cur Baseline) []Report {
+	now := time.Now()
+	paths := make([]string, 0, len(cur))
+	for p := range cur {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var r []Report
+	for _, p := range paths {
+		h := cur[p]
+		if old, ok := base[p]; !ok {
+			r = append(r, Report{p, "ADDED", "", h, "New file", now})
+		} else if old != h {
+			r = append(r, Report{p, "MODIFIED", old, h, "Hash mismatch", now})
+		} else {
+			r = append(r, Report{p, "OK", old, "", "", now})
+		}
+	}
+	var gone []string
+	for p := range base {
+		if _, ok := cur[p]; !ok {
+			gone = append(gone, p)
+		}
+	}
+	sort.Strings(gone)
+	for _, p := range gone {
+		r = append(r, Report{p, "DELETED", base[p], "", "File deleted", now})
+	}
+	return r
+}
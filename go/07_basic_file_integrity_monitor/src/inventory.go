@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pre-baseline survey: --inventory walks the --path or -i targets like a
+// baseline run would, but writes a description of what is there instead of
+// a baseline: counts and sizes per file extension, the largest and the most
+// recently modified files, and files with identical content. It helps decide
+// what to monitor (and what to leave out) before the first baseline.
+// Only files that share their size with another file are hashed, so a
+// survey of a large tree reads little of it.
+
+// inventoryMode is --inventory; inventoryTop is --inventory-top.
+var (
+	inventoryMode bool
+	inventoryTop  int
+)
+
+// extStat totals the files with one extension.
+type extStat struct {
+	Extension string `json:"extension"` // Lowercase, with the dot; "" for none
+	Files     int    `json:"files"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// inventoryFile is a file listed among the largest or newest.
+type inventoryFile struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// duplicateGroup is a set of files with identical content.
+type duplicateGroup struct {
+	SHA256 string   `json:"sha256"`
+	Size   int64    `json:"size"`
+	Wasted int64    `json:"wasted"` // Bytes held by all copies but one
+	Paths  []string `json:"paths"`
+}
+
+// inventory is the --inventory report.
+type inventory struct {
+	Tool            string           `json:"tool"`
+	Version         string           `json:"version"`
+	RunID           string           `json:"run_id"`
+	Hostname        string           `json:"hostname"`
+	RelativeTo      string           `json:"relative_to,omitempty"`
+	ScanStart       time.Time        `json:"scan_start"`
+	ScanEnd         time.Time        `json:"scan_end"`
+	Interrupted     bool             `json:"interrupted"` // Duplicates were not fully checked
+	Files           int              `json:"files"`
+	Bytes           int64            `json:"bytes"`
+	Unreadable      int              `json:"unreadable"`
+	Extensions      []extStat        `json:"extensions"`
+	Largest         []inventoryFile  `json:"largest"`
+	Newest          []inventoryFile  `json:"newest"`
+	DuplicateGroups int              `json:"duplicate_groups"`
+	DuplicateBytes  int64            `json:"duplicate_bytes"` // Reclaimable by keeping one copy of each
+	Duplicates      []duplicateGroup `json:"duplicates"`      // Most wasteful first, up to --inventory-top
+	SelfStats       *selfStats       `json:"self_stats,omitempty"`
+}
+
+// takeInventory surveys files. Devices named explicitly are counted but
+// have no size, and are never compared. If ctx is cancelled while
+// duplicates are being hashed, the inventory is returned with Interrupted
+// set and the duplicates found so far.
+func takeInventory(ctx context.Context, files []string) *inventory {
+	host, _ := os.Hostname()
+	build := currentBuild()
+	inv := &inventory{Tool: toolName, Version: build.Version, RunID: runID, Hostname: host, RelativeTo: relativeTo,
+		ScanStart: scanStarted.In(reportTZ), Extensions: []extStat{}, Duplicates: []duplicateGroup{}}
+
+	exts := map[string]*extStat{}
+	var all []inventoryFile
+	bySize := map[int64][]string{}
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			inv.Unreadable++
+			continue
+		}
+		inv.Files++
+		var size int64
+		if info.Mode().IsRegular() {
+			size = info.Size()
+			if size > 0 {
+				bySize[size] = append(bySize[size], f)
+			}
+		}
+		inv.Bytes += size
+		ext := strings.ToLower(filepath.Ext(f))
+		if exts[ext] == nil {
+			exts[ext] = &extStat{Extension: ext}
+		}
+		exts[ext].Files++
+		exts[ext].Bytes += size
+		all = append(all, inventoryFile{Path: relPath(f), Size: size, Modified: info.ModTime().In(reportTZ)})
+	}
+
+	for _, e := range exts {
+		inv.Extensions = append(inv.Extensions, *e)
+	}
+	sort.Slice(inv.Extensions, func(i, j int) bool {
+		a, b := inv.Extensions[i], inv.Extensions[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Extension < b.Extension
+	})
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Size != all[j].Size {
+			return all[i].Size > all[j].Size
+		}
+		return all[i].Path < all[j].Path
+	})
+	inv.Largest = append([]inventoryFile{}, all[:min(inventoryTop, len(all))]...)
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].Modified.Equal(all[j].Modified) {
+			return all[i].Modified.After(all[j].Modified)
+		}
+		return all[i].Path < all[j].Path
+	})
+	inv.Newest = append([]inventoryFile{}, all[:min(inventoryTop, len(all))]...)
+
+	// Only files of the same size can be identical.
+	sizes := make([]int64, 0, len(bySize))
+	for size, paths := range bySize {
+		if len(paths) > 1 {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] > sizes[j] })
+	var groups []duplicateGroup
+	for _, size := range sizes {
+		byHash := map[string][]string{}
+		for _, f := range bySize[size] {
+			h, err := hashFile(ctx, f)
+			if ctx.Err() != nil {
+				inv.Interrupted = true
+				break
+			}
+			if err != nil {
+				inv.Unreadable++
+				continue
+			}
+			byHash[h] = append(byHash[h], relPath(f))
+		}
+		for h, paths := range byHash {
+			if len(paths) > 1 {
+				sort.Strings(paths)
+				groups = append(groups, duplicateGroup{SHA256: h, Size: size, Wasted: int64(len(paths)-1) * size, Paths: paths})
+			}
+		}
+		if inv.Interrupted {
+			break
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Wasted != groups[j].Wasted {
+			return groups[i].Wasted > groups[j].Wasted
+		}
+		return groups[i].Paths[0] < groups[j].Paths[0]
+	})
+	inv.DuplicateGroups = len(groups)
+	for _, g := range groups {
+		inv.DuplicateBytes += g.Wasted
+	}
+	inv.Duplicates = append(inv.Duplicates, groups[:min(inventoryTop, len(groups))]...)
+	inv.ScanEnd = time.Now().In(reportTZ)
+	if selfStatsOn {
+		stats := collectSelfStats(inv.Files, "files")
+		inv.SelfStats = &stats
+	}
+	return inv
+}
+
+// writeInventory writes the inventory as text.
+func writeInventory(inv *inventory, w io.Writer) {
+	fmt.Fprintln(w, "--- File Inventory ---")
+	if inv.RelativeTo != "" {
+		fmt.Fprintf(w, "\nPaths relative to: %s", inv.RelativeTo)
+	}
+	fmt.Fprintf(w, "\nFiles: %d (%s)\n", inv.Files, formatSize(inv.Bytes))
+	if inv.Unreadable > 0 {
+		fmt.Fprintf(w, "Unreadable: %d\n", inv.Unreadable)
+	}
+
+	fmt.Fprintln(w, "\n== By Extension ==")
+	for _, e := range inv.Extensions {
+		ext := e.Extension
+		if ext == "" {
+			ext = "(none)"
+		}
+		fmt.Fprintf(w, "%-12s %7d file(s) %12s\n", ext, e.Files, formatSize(e.Bytes))
+	}
+
+	fmt.Fprintln(w, "\n== Largest Files ==")
+	for _, f := range inv.Largest {
+		fmt.Fprintf(w, "%12s  %s\n", formatSize(f.Size), f.Path)
+	}
+
+	fmt.Fprintln(w, "\n== Newest Files ==")
+	for _, f := range inv.Newest {
+		fmt.Fprintf(w, "%s  %s\n", stamp(f.Modified), f.Path)
+	}
+
+	fmt.Fprintln(w, "\n== Duplicates ==")
+	if inv.DuplicateGroups == 0 {
+		fmt.Fprintln(w, "No files with identical content.")
+	} else {
+		fmt.Fprintf(w, "%d group(s) of identical files; %s reclaimable by keeping one copy of each.\n", inv.DuplicateGroups, formatSize(inv.DuplicateBytes))
+	}
+	for _, g := range inv.Duplicates {
+		fmt.Fprintf(w, "\n%d x %s (sha256 %s)\n", len(g.Paths), formatSize(g.Size), g.SHA256)
+		for _, p := range g.Paths {
+			fmt.Fprintf(w, "  %s\n", p)
+		}
+	}
+	if n := inv.DuplicateGroups - len(inv.Duplicates); n > 0 {
+		fmt.Fprintf(w, "\n... and %d more group(s); raise --inventory-top to list them.\n", n)
+	}
+	if inv.Interrupted {
+		fmt.Fprintln(w, "\nPartial inventory: interrupted while comparing files; some duplicates were not checked.")
+	}
+	if inv.SelfStats != nil {
+		fmt.Fprintln(w)
+		writeSelfStats(w, *inv.SelfStats)
+	}
+}
+
+// writeInventoryJSON writes the inventory as one JSON document.
+func writeInventoryJSON(inv *inventory, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(inv)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Security labels. With --labels a baseline records each file's SELinux
+// context and, for executables, the AppArmor profile that confines them when
+// run. A file whose content is unchanged but whose label drifted (a chcon
+// that lets a confined service read it, an executable moved out from under
+// its profile) is reported as LABEL_CHANGED. Labels are read on Linux only.
+type labels struct {
+	SELinux  string `json:"selinux,omitempty"`
+	AppArmor string `json:"apparmor,omitempty"` // Profile and mode, e.g. "/usr/sbin/cupsd (enforce)", or "unconfined"
+}
+
+// labelMode is --labels.
+var labelMode bool
+
+// readSELinux returns the SELinux context of a file, or "" when it has
+// none. It is set by labels_linux.go; other builds read no labels.
+var readSELinux func(p string) (string, error)
+
+// apparmorDir is where the kernel publishes the loaded AppArmor policy.
+const apparmorDir = "/sys/kernel/security/apparmor"
+
+// errNoLabels is returned where security labels are not read.
+var errNoLabels = errors.New("security labels are only read on Linux")
+
+// fileLabels reads the security labels of p.
+func fileLabels(p string) (*labels, error) {
+	if readSELinux == nil {
+		return nil, errNoLabels
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	l := &labels{}
+	if l.SELinux, err = readSELinux(p); err != nil {
+		return nil, err
+	}
+	if info.Mode().IsRegular() && info.Mode()&0111 != 0 {
+		l.AppArmor = apparmorProfile(p)
+	}
+	return l, nil
+}
+
+// compareLabels returns the LABEL_CHANGED message for a file whose recorded
+// and current labels differ, or "".
+func compareLabels(old, cur *labels) string {
+	if old == nil || cur == nil {
+		return ""
+	}
+	var changes []string
+	if old.SELinux != cur.SELinux {
+		changes = append(changes, "SELinux context "+labelValue(old.SELinux)+" -> "+labelValue(cur.SELinux))
+	}
+	if old.AppArmor != cur.AppArmor {
+		changes = append(changes, "AppArmor profile "+labelValue(old.AppArmor)+" -> "+labelValue(cur.AppArmor))
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	return "Security label changed: " + strings.Join(changes, "; ")
+}
+
+func labelValue(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// apparmorAttachment is a loaded profile that attaches to executables by
+// path.
+type apparmorAttachment struct {
+	profile string // "name (mode)"
+	pattern string
+	literal int // Length of the pattern before its first wildcard
+	re      *regexp.Regexp
+}
+
+var (
+	apparmorOnce     sync.Once
+	apparmorEnabled  bool
+	apparmorAttaches []apparmorAttachment
+)
+
+// apparmorProfile returns the profile AppArmor would confine executable p
+// with, "unconfined" when none attaches to it, or "" when AppArmor is not
+// enabled. Of several matching profiles the most specific wins: an exact
+// path, then the longest literal prefix, then the longest pattern.
+func apparmorProfile(p string) string {
+	apparmorOnce.Do(loadApparmor)
+	if !apparmorEnabled {
+		return ""
+	}
+	best := -1
+	for i, a := range apparmorAttaches {
+		if !a.re.MatchString(p) {
+			continue
+		}
+		if best < 0 || a.literal > apparmorAttaches[best].literal ||
+			a.literal == apparmorAttaches[best].literal && len(a.pattern) > len(apparmorAttaches[best].pattern) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "unconfined"
+	}
+	return apparmorAttaches[best].profile
+}
+
+// loadApparmor reads the loaded profiles once. Each profile directory under
+// policy/profiles holds its name, mode and attachment pattern; older kernels
+// only list "name (mode)" lines in profiles, where a profile attaches to the
+// path it is named after.
+func loadApparmor() {
+	if _, err := os.Stat(apparmorDir); err != nil {
+		return
+	}
+	apparmorEnabled = true
+	add := func(name, mode, attach string) {
+		if !strings.HasPrefix(attach, "/") {
+			return // Not attached by path (a child or named-only profile)
+		}
+		re, err := regexp.Compile("^" + apparmorGlob(attach) + "$")
+		if err != nil {
+			debugf("skipping AppArmor profile %s: %v", name, err)
+			return
+		}
+		literal := strings.IndexAny(attach, "*?[{")
+		if literal < 0 {
+			literal = len(attach) + 1 // An exact path beats any pattern
+		}
+		apparmorAttaches = append(apparmorAttaches, apparmorAttachment{profile: name + " (" + mode + ")", pattern: attach, literal: literal, re: re})
+	}
+	dirs, _ := filepath.Glob(filepath.Join(apparmorDir, "policy", "profiles", "*"))
+	for _, d := range dirs {
+		read := func(f string) string {
+			data, _ := os.ReadFile(filepath.Join(d, f))
+			return strings.TrimSpace(string(data))
+		}
+		name, attach := read("name"), read("attach")
+		if attach == "" || strings.HasPrefix(attach, "<") {
+			attach = name
+		}
+		add(name, read("mode"), attach)
+	}
+	if len(dirs) > 0 {
+		return
+	}
+	data, _ := os.ReadFile(filepath.Join(apparmorDir, "profiles"))
+	for _, line := range strings.Split(string(data), "\n") {
+		open := strings.LastIndex(line, " (")
+		if open < 0 || !strings.HasSuffix(line, ")") {
+			continue
+		}
+		add(line[:open], line[open+2:len(line)-1], line[:open])
+	}
+}
+
+// apparmorGlob translates an AppArmor path pattern to a regular expression:
+// ** matches across directories, * and ? within one, {a,b} alternatives
+// and [...] character classes.
+func apparmorGlob(pattern string) string {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			b.WriteString(".*")
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '{':
+			depth++
+			b.WriteString("(?:")
+		case c == '}' && depth > 0:
+			depth--
+			b.WriteString(")")
+		case c == ',' && depth > 0:
+			b.WriteString("|")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(pattern[i : i+end+2]) // Same syntax as RE2
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
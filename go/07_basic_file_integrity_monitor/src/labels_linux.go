@@ -0,0 +1,35 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+)
+
+// SELinux contexts are read from the security.selinux extended attribute.
+// Linux only: other builds leave this file out and refuse --labels.
+
+func init() {
+	readSELinux = selinuxContext
+}
+
+// selinuxContext returns the context of p (following symlinks, as hashing
+// does), or "" when the file system or policy gives it none.
+func selinuxContext(p string) (string, error) {
+	buf := make([]byte, 256)
+	for {
+		n, err := syscall.Getxattr(p, "security.selinux", buf)
+		switch {
+		case errors.Is(err, syscall.ERANGE):
+			buf = make([]byte, 2*len(buf))
+			continue
+		case errors.Is(err, syscall.ENODATA), errors.Is(err, syscall.ENOTSUP):
+			return "", nil
+		case err != nil:
+			return "", err
+		}
+		return strings.TrimRight(string(buf[:n]), "\x00"), nil
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// applyNice lowers the CPU and I/O scheduling priority of the process (and
+// of any hooks it starts), like running under `nice -n 10 ionice -c2 -n7`.
+// The system's renice and ionice commands are used so the tool builds from a
+// plain file list on every platform. The best-effort I/O class is used
+// rather than idle, which could starve the scan indefinitely on a busy host.
+func applyNice() error {
+	ids := []string{strconv.Itoa(os.Getpid())}
+	// On Linux priorities are per thread: renice every thread of the Go
+	// runtime, from which later threads and child processes inherit.
+	if tasks, err := os.ReadDir("/proc/self/task"); err == nil {
+		ids = ids[:0]
+		for _, t := range tasks {
+			ids = append(ids, t.Name())
+		}
+	}
+	var failed []string
+	for _, args := range [][]string{
+		append([]string{"renice", "-n", "10", "-p"}, ids...),
+		append([]string{"ionice", "-c", "2", "-n", "7", "-p"}, ids...), // Linux only
+	} {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			failed = append(failed, args[0]+" not available")
+			continue
+		}
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v %s", args[0], err, strings.TrimSpace(string(out))))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return nil
+}
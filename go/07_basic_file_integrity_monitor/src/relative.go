@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Portable baselines. With --relative-to DIR, files are still collected and
+// hashed by their absolute paths, but the baseline and the report name them
+// relative to DIR, with forward slashes ("etc/nginx/nginx.conf"). A baseline
+// taken of /srv/app on one host, or from a mounted image, can then verify a
+// copy of the tree anywhere else. Paths outside DIR (such as the monitor's
+// own executable with --self-check) stay absolute.
+
+// relativeTo is the absolute --relative-to directory, or "".
+var relativeTo string
+
+// setRelativeTo checks and resolves the --relative-to directory.
+func setRelativeTo(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	relativeTo = abs
+	return nil
+}
+
+// relPath returns how p is named in baselines and reports.
+func relPath(p string) string {
+	if relativeTo == "" || !filepath.IsAbs(p) {
+		return p
+	}
+	rel, err := filepath.Rel(relativeTo, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return p
+	}
+	return filepath.ToSlash(rel)
+}
+
+// absPath is the inverse of relPath, for a path read from a baseline.
+func absPath(p string) string {
+	if relativeTo == "" || filepath.IsAbs(filepath.FromSlash(p)) {
+		return p
+	}
+	return filepath.Join(relativeTo, filepath.FromSlash(p))
+}
+
+// relativeBaseline renames the entries of a new baseline (and their
+// metadata) with relPath, and warns about files that stay absolute.
+func relativeBaseline(b Baseline, meta map[string]fileMeta) (Baseline, map[string]fileMeta) {
+	if relativeTo == "" {
+		return b, meta
+	}
+	rb, rm := Baseline{}, map[string]fileMeta{}
+	outside := 0
+	for p, h := range b {
+		rel := relPath(p)
+		if rel == p && p != selfPath {
+			outside++
+		}
+		rb[rel] = h
+		if m, ok := meta[p]; ok {
+			rm[rel] = m
+		}
+	}
+	if outside > 0 {
+		warnf("%d file(s) outside --relative-to %s keep their absolute paths.", outside, relativeTo)
+	}
+	return rb, rm
+}
+
+// absoluteBaseline resolves the entries of a loaded baseline against
+// --relative-to. Without it, a baseline of relative paths is an error, as
+// every file would otherwise be reported as both added and deleted.
+func absoluteBaseline(b Baseline, meta map[string]fileMeta) (Baseline, map[string]fileMeta, error) {
+	if relativeTo == "" {
+		for p := range b {
+			if !filepath.IsAbs(filepath.FromSlash(p)) {
+				return nil, nil, fmt.Errorf("the baseline names files relative to a root (e.g. %s); give that root with --relative-to", p)
+			}
+		}
+		return b, meta, nil
+	}
+	ab, am := Baseline{}, map[string]fileMeta{}
+	for p, h := range b {
+		ab[absPath(p)] = h
+		if m, ok := meta[p]; ok {
+			am[absPath(p)] = m
+		}
+	}
+	return ab, am, nil
+}
+
+// relativeReport renames report entries with relPath.
+func relativeReport(r []Report) {
+	for i := range r {
+		r[i].Path = relPath(r[i].Path)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// runID identifies this run in JSON reports and hook environments, so that
+// entries collected from many hosts and runs can be correlated and
+// deduplicated downstream.
+var runID = newRunID()
+
+// scanStarted is when this run began, before files were collected.
+var scanStarted = time.Now().UTC()
+
+// newRunID returns a random (version 4) UUID.
+func newRunID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// jsonEntry is one report entry. Each carries the run ID and scan start so
+// that it stays attributable once split out of the report (e.g. by a log
+// shipper).
+type jsonEntry struct {
+	RunID     string    `json:"run_id"`
+	ScanStart time.Time `json:"scan_start"`
+	CheckedAt time.Time `json:"checked_at"`
+	Path      string    `json:"path"`
+	Status    string    `json:"status"`
+	OldHash   string    `json:"old_hash,omitempty"`
+	NewHash   string    `json:"new_hash,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// jsonReport is the --format json report.
+type jsonReport struct {
+	Tool        string         `json:"tool"`
+	Version     string         `json:"version"`
+	GitCommit   string         `json:"git_commit,omitempty"`
+	BuildDate   string         `json:"build_date,omitempty"`
+	RunID       string         `json:"run_id"`
+	Hostname    string         `json:"hostname"`
+	Mode        string         `json:"mode"`
+	Baseline    string         `json:"baseline"`
+	RelativeTo  string         `json:"relative_to,omitempty"`     // Root of the entry paths, with --relative-to
+	BaselineSHA string         `json:"baseline_sha256,omitempty"` // With --self-check
+	ScanStart   time.Time      `json:"scan_start"`
+	ScanEnd     time.Time      `json:"scan_end"`
+	Interrupted bool           `json:"interrupted"`
+	ChangesOnly bool           `json:"changes_only"` // OK entries left out; counts still include them
+	Files       int            `json:"files"`
+	Counts      map[string]int `json:"counts"`
+	Entries     []jsonEntry    `json:"entries"`
+	SelfStats   *selfStats     `json:"self_stats,omitempty"`
+}
+
+// writeJSONReport writes the integrity report as a JSON document. For an
+// interrupted run, interrupted is set and deleted files were not checked.
+func writeJSONReport(r []Report, s hookSummary, interrupted bool, w io.Writer) error {
+	host, _ := os.Hostname()
+	build := currentBuild()
+	rep := jsonReport{
+		Tool:        toolName,
+		Version:     build.Version,
+		GitCommit:   build.GitCommit,
+		BuildDate:   build.BuildDate,
+		RunID:       runID,
+		Hostname:    host,
+		Mode:        s.Mode,
+		Baseline:    s.Baseline,
+		RelativeTo:  relativeTo,
+		ScanStart:   scanStarted.In(reportTZ),
+		ScanEnd:     time.Now().In(reportTZ),
+		Interrupted: interrupted,
+		ChangesOnly: changesOnly,
+		Files:       s.Files,
+		Counts:      s.Counts,
+		Entries:     []jsonEntry{},
+	}
+	if selfCheck {
+		rep.BaselineSHA = baselineSHA256
+	}
+	if selfStatsOn {
+		stats := collectSelfStats(s.Files, "files")
+		rep.SelfStats = &stats
+	}
+	for _, e := range r {
+		rep.Entries = append(rep.Entries, jsonEntry{
+			RunID:     runID,
+			ScanStart: scanStarted.In(reportTZ),
+			CheckedAt: e.CheckedAt.In(reportTZ),
+			Path:      e.Path,
+			Status:    e.Status,
+			OldHash:   e.OldHash,
+			NewHash:   e.NewHash,
+			Message:   e.Message,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// --self-check: the monitor's own executable is hashed with the monitored
+// files, so a replaced binary shows up as MODIFIED. The baseline cannot
+// contain its own hash; its SHA-256 is printed when it is created and
+// recorded in each verification report instead, to be compared with a copy
+// kept off the host.
+
+// baselineSHA256 is the digest of the baseline as last written or read.
+var baselineSHA256 string
+
+// selfPath is the resolved path of the running executable, once added.
+var selfPath string
+
+// digestBaseline records the digest of the baseline contents.
+func digestBaseline(data []byte) {
+	sum := sha256.Sum256(data)
+	baselineSHA256 = hex.EncodeToString(sum[:])
+}
+
+// addSelf appends the running executable to files unless it is already
+// being monitored.
+func addSelf(files []string) []string {
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		warnf("--self-check: cannot locate the running executable: %v", err)
+		return files
+	}
+	selfPath = exe
+	for _, f := range files {
+		if f == exe {
+			return files
+		}
+	}
+	debugf("self-check: monitoring %s", exe)
+	return append(files, exe)
+}
+
+// markSelf rewords the report entry of the running executable.
+func markSelf(r []Report) {
+	for i := range r {
+		if r[i].Path != selfPath {
+			continue
+		}
+		switch r[i].Status {
+		case "MODIFIED":
+			r[i].Message = "Monitor executable differs from the baseline"
+		case "ADDED":
+			r[i].Message = "Monitor executable not in the baseline (create it with --self-check)"
+		}
+	}
+}
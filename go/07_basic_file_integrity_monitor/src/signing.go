@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Report signing. --sign-report key.pem signs the JSON report with an Ed25519
+// key and writes the detached signature next to it, to <output>.sig (a file,
+// or an object beside an uploaded report). The signature is the raw 64 bytes
+// over the report exactly as delivered, so OpenSSL can check it as well:
+//
+//	openssl genpkey -algorithm ed25519 -out report-key.pem
+//	openssl pkey -in report-key.pem -pubout -out report-key.pub
+//	openssl pkeyutl -verify -pubin -inkey report-key.pub -rawin -in report.json -sigfile report.json.sig
+//
+// "<tool> verify-report --key report-key.pub report.json" does the same check.
+//
+// Every tool that signs reports carries an identical copy of this file
+// (scripts/check_shared_go.py).
+
+var signKeyPath string
+
+func init() {
+	subcommands = append(subcommands, subcommand{Name: "verify-report", Usage: "Check a signed report against its signature", Run: runVerifyReport})
+}
+
+func registerSignFlag() {
+	flag.StringVar(&signKeyPath, "sign-report", "", "Ed25519 private key (PKCS#8 PEM) to sign the JSON report with; the detached signature is written to <output>.sig.")
+}
+
+// signatureTarget is where the signature of a report sent to output goes, or
+// "" when the report is not signed. For an http(s) endpoint, .sig is added
+// to the URL path, so a query string stays intact.
+func signatureTarget(output string) string {
+	if signKeyPath == "" {
+		return ""
+	}
+	if strings.HasPrefix(output, "http://") || strings.HasPrefix(output, "https://") {
+		if u, err := url.Parse(output); err == nil {
+			u.Path += ".sig"
+			if u.RawPath != "" {
+				u.RawPath += ".sig"
+			}
+			return u.String()
+		}
+	}
+	return output + ".sig"
+}
+
+// loadSigningKey reads the --sign-report key; it returns nil when no key was
+// given. A signed report needs a destination that the signature can sit
+// next to, so stdout is refused.
+func loadSigningKey(output string) (ed25519.PrivateKey, error) {
+	if signKeyPath == "" {
+		return nil, nil
+	}
+	if output == "" || output == "-" {
+		return nil, fmt.Errorf("--sign-report needs a report destination (-o); the signature is written next to it")
+	}
+	key, err := readReportKey(signKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", signKeyPath)
+	}
+	return priv, nil
+}
+
+// readReportKey parses the first PEM key in path: a PKCS#8 private key or a
+// PKIX public key.
+func readReportKey(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", path, err)
+	}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid private key in %s: %w", path, err)
+			}
+			return key, nil
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid public key in %s: %w", path, err)
+			}
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("no PEM PRIVATE KEY or PUBLIC KEY block in %s", path)
+}
+
+// signingSink passes the report through to its destination and, once that
+// has been delivered, signs what was written and delivers the signature.
+type signingSink struct {
+	OutputSink
+	key    ed25519.PrivateKey
+	target string
+	buf    bytes.Buffer
+}
+
+// signSink wraps out so that the report is signed with key when closed; a
+// nil key leaves out unchanged.
+func signSink(out OutputSink, key ed25519.PrivateKey, output string) OutputSink {
+	if key == nil {
+		return out
+	}
+	return &signingSink{OutputSink: out, key: key, target: signatureTarget(output)}
+}
+
+func (s *signingSink) Write(p []byte) (int, error) {
+	s.buf.Write(p)
+	return s.OutputSink.Write(p)
+}
+
+func (s *signingSink) Close() error {
+	if err := s.OutputSink.Close(); err != nil {
+		return err
+	}
+	sig, err := openSink(s.target)
+	if err != nil {
+		return fmt.Errorf("failed to open signature %s: %w", s.target, err)
+	}
+	sig.Write(ed25519.Sign(s.key, s.buf.Bytes()))
+	if err := sig.Close(); err != nil {
+		return fmt.Errorf("failed to write signature %s: %w", s.target, err)
+	}
+	return nil
+}
+
+// runVerifyReport handles "<tool> verify-report --key key.pem report
+// [signature]" and returns the exit status: 0 when the signature matches, 1
+// when it does not, 2 for usage errors.
+func runVerifyReport(args []string, w io.Writer) int {
+	fs := flag.NewFlagSet("verify-report", flag.ContinueOnError)
+	keyPath := fs.String("key", "", "Ed25519 public key (PKIX PEM), or the private key the report was signed with.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify-report --key key.pem report.json [report.json.sig]\n", toolName)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *keyPath == "" || fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+	report := fs.Arg(0)
+	sigPath := report + ".sig"
+	if fs.NArg() == 2 {
+		sigPath = fs.Arg(1)
+	}
+	key, err := readReportKey(*keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+	var pub ed25519.PublicKey
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		pub = k
+	case ed25519.PrivateKey:
+		pub = k.Public().(ed25519.PublicKey)
+	default:
+		fmt.Fprintf(os.Stderr, "[ERROR] %s is not an Ed25519 key\n", *keyPath)
+		return 2
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to read report %s: %v\n", report, err)
+		return 2
+	}
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to read signature %s: %v\n", sigPath, err)
+		return 2
+	}
+	if len(sig) != ed25519.SignatureSize || !ed25519.Verify(pub, data, sig) {
+		fmt.Fprintf(w, "%s: signature does NOT match (%s)\n", report, sigPath)
+		return 1
+	}
+	fmt.Fprintf(w, "%s: signature OK (%s)\n", report, sigPath)
+	return 0
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// Sparse files. With --sparse a baseline records the layout of every
+// regular file next to its hash: its size, the bytes held in data extents
+// and whether it has holes. Verification flags a file whose content is
+// unchanged but which was converted from sparse to fully allocated (cp
+// without --sparse, a restore from backup) or the other way round
+// (fallocate --dig-holes) as SPARSE_CHANGED.
+//
+// Two hash modes are offered, and verification always uses the one the
+// baseline was created with:
+//
+//	logical  holes are read as zeros, so the hash is the plain SHA-256 of
+//	         the content and does not depend on how the file is stored
+//	data     only the data extents are hashed, each preceded by its offset
+//	         and length, so holes are never read (fast for sparse VM and
+//	         database images) and moving data in or out of a hole changes
+//	         the hash
+//
+// Holes are found with lseek(SEEK_DATA/SEEK_HOLE). File systems without
+// hole support report a file as one data extent, and so as not sparse.
+
+// Hash modes of --sparse.
+const (
+	sparseLogical = "logical"
+	sparseData    = "data"
+)
+
+// sparseMode is the --sparse hash mode for new baselines; empty records no
+// layout.
+var sparseMode string
+
+// layout is the storage layout of a file, as recorded with --sparse.
+type layout struct {
+	Hash      string `json:"hash"` // logical or data
+	Size      int64  `json:"size"`
+	DataBytes int64  `json:"data_bytes"` // Bytes in data extents; less than size when sparse
+	Sparse    bool   `json:"sparse"`
+}
+
+// describe summarizes the layout for report messages.
+func (l *layout) describe() string {
+	if !l.Sparse {
+		return fmt.Sprintf("fully allocated, %s", formatSize(l.Size))
+	}
+	return fmt.Sprintf("sparse, %s of %s in data extents", formatSize(l.DataBytes), formatSize(l.Size))
+}
+
+// extent is a run of data in a file: offset and length.
+type extent struct{ off, n int64 }
+
+// seekWhence returns the lseek whence values of SEEK_DATA and SEEK_HOLE on
+// this system, or ok false where holes cannot be queried.
+func seekWhence() (data, hole int, ok bool) {
+	switch runtime.GOOS {
+	case "linux", "android", "freebsd", "dragonfly", "solaris", "illumos":
+		return 3, 4, true
+	case "darwin", "ios":
+		return 4, 3, true
+	}
+	return 0, 0, false
+}
+
+// dataExtents lists the data extents of a regular file of the given size,
+// leaving the file offset at 0.
+func dataExtents(f *os.File, size int64) []extent {
+	whole := []extent{{0, size}}
+	seekData, seekHole, ok := seekWhence()
+	if !ok || size == 0 {
+		return whole
+	}
+	defer f.Seek(0, io.SeekStart)
+	var extents []extent
+	for off := int64(0); off < size; {
+		start, err := f.Seek(off, seekData)
+		if errors.Is(err, syscall.ENXIO) {
+			break // Only a hole remains
+		}
+		if err != nil {
+			return whole // Not supported by this file system
+		}
+		end, err := f.Seek(start, seekHole)
+		if err != nil {
+			return whole
+		}
+		if end > size {
+			end = size
+		}
+		if end > start {
+			extents = append(extents, extent{start, end - start})
+		}
+		off = end
+	}
+	return extents
+}
+
+// fileLayout builds the layout record of a file from its data extents.
+func fileLayout(mode string, size int64, extents []extent) *layout {
+	l := &layout{Hash: mode, Size: size}
+	for _, e := range extents {
+		l.DataBytes += e.n
+	}
+	l.Sparse = l.DataBytes < size
+	return l
+}
+
+// extentReader streams what the data mode hashes: for each extent, its
+// offset and length as two big-endian uint64s followed by its bytes.
+func extentReader(f *os.File, extents []extent) io.Reader {
+	var parts []io.Reader
+	for _, e := range extents {
+		var head [16]byte
+		binary.BigEndian.PutUint64(head[:8], uint64(e.off))
+		binary.BigEndian.PutUint64(head[8:], uint64(e.n))
+		parts = append(parts, bytes.NewReader(head[:]), io.NewSectionReader(f, e.off, e.n))
+	}
+	return io.MultiReader(parts...)
+}
+
+// compareLayout returns the SPARSE_CHANGED message for a file whose
+// recorded and current layouts differ in sparseness, or "".
+func compareLayout(old, cur *layout) string {
+	if old == nil || cur == nil || old.Sparse == cur.Sparse {
+		return ""
+	}
+	if old.Sparse {
+		return "Converted from sparse to fully allocated (was " + old.describe() + ", now " + cur.describe() + ")"
+	}
+	return "Converted from fully allocated to sparse (was " + old.describe() + ", now " + cur.describe() + ")"
+}
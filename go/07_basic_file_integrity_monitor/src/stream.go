@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// hashChunk is the read size used when hashing. Large sequential reads keep
+// block devices and disk images close to their streaming throughput.
+const hashChunk = 1 << 20
+
+// progressMin is the size from which -v reports hashing progress; devices
+// always report it.
+const progressMin = 64 << 20
+
+// progressStep is how often progress is reported for inputs of unknown size.
+const progressStep = 1 << 30
+
+// isDevice reports whether mode is a block or character device.
+func isDevice(mode os.FileMode) bool {
+	return mode&os.ModeDevice != 0
+}
+
+// inputSize returns the number of bytes that will be hashed, or -1 if it is
+// unknown. stat reports 0 for block devices, so their size is found by
+// seeking to the end; character devices (e.g. MTD flash) are read until EOF.
+func inputSize(f *os.File, info os.FileInfo) int64 {
+	if !isDevice(info.Mode()) {
+		return info.Size()
+	}
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil || end <= 0 {
+		return -1
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return -1
+	}
+	return end
+}
+
+// formatSize renders a byte count in binary units for progress messages.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// hashFile computes the SHA256 hash of a file, block device or disk image,
+// reading it in hashChunk pieces. Large inputs and devices report progress
+// with -v, reads are throttled by --io-limit, and ctx is checked between
+// chunks so that hashing a whole disk can be interrupted.
+func hashFile(ctx context.Context, p string) (string, error) {
+	sum, _, err := hashFileLayout(ctx, p, "")
+	return sum, err
+}
+
+// hashFileLayout is hashFile in a --sparse hash mode (logical or data); for
+// a regular file it also returns the file's layout. An empty mode hashes as
+// hashFile does and returns no layout.
+func hashFileLayout(ctx context.Context, p, mode string) (sum string, lay *layout, err error) {
+	defer func(start time.Time) {
+		telemetry.span("hash", start, map[string]string{"file": p}, err)
+	}(time.Now())
+	f, err := os.Open(p)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, err
+	}
+	size := inputSize(f, info)
+	var in io.Reader = f
+	if mode != "" && info.Mode().IsRegular() {
+		extents := dataExtents(f, size)
+		lay = fileLayout(mode, size, extents)
+		if mode == sparseData {
+			in, size = extentReader(f, extents), lay.DataBytes
+		}
+	}
+	report := verboseMode && (isDevice(info.Mode()) || size >= progressMin)
+	if report {
+		if size >= 0 {
+			fmt.Fprintf(os.Stderr, "[INFO] Hashing %s (%s)...\n", p, formatSize(size))
+		} else {
+			fmt.Fprintf(os.Stderr, "[INFO] Hashing %s (size unknown)...\n", p)
+		}
+	}
+
+	h := sha256.New()
+	buf := make([]byte, limiter.chunkSize())
+	var done int64
+	next := int64(progressStep)
+	if size > 0 {
+		next = size / 10
+	}
+	for {
+		if ctx.Err() != nil {
+			return "", nil, ctx.Err()
+		}
+		n, err := in.Read(buf)
+		h.Write(buf[:n])
+		limiter.wait(ctx, n)
+		done += int64(n)
+		if report && n > 0 && done >= next && done != size {
+			if size > 0 {
+				fmt.Fprintf(os.Stderr, "[INFO] Hashing %s: %d%% (%s of %s)\n", p, done*100/size, formatSize(done), formatSize(size))
+				next = done + size/10
+			} else {
+				fmt.Fprintf(os.Stderr, "[INFO] Hashing %s: %s read\n", p, formatSize(done))
+				next = done + progressStep
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if report {
+		fmt.Fprintf(os.Stderr, "[INFO] Hashed %s: %s\n", p, formatSize(done))
+	}
+	return hex.EncodeToString(h.Sum(nil)), lay, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateRE matches --io-limit values such as 20MB/s, 512KiB/s or 1.5G.
+var rateRE = regexp.MustCompile(`^(?i)([0-9]+(?:\.[0-9]+)?)\s*([kmgt]?)(i?)(b?)(?:/s)?$`)
+
+// parseRate converts an --io-limit value to bytes per second. KB, MB and GB
+// are decimal units; KiB, MiB and GiB are binary.
+func parseRate(s string) (float64, error) {
+	m := rateRE.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid rate %q (expected e.g. 20MB/s or 512KiB/s)", s)
+	}
+	n, _ := strconv.ParseFloat(m[1], 64)
+	base := 1000.0
+	if m[3] != "" {
+		base = 1024
+	}
+	for i := strings.Index("kmgt", strings.ToLower(m[2])); m[2] != "" && i >= 0; i-- {
+		n *= base
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("rate %q is too low", s)
+	}
+	return n, nil
+}
+
+// ioLimiter caps the average read rate across every file hashed in a run.
+// A zero rate means unlimited.
+type ioLimiter struct {
+	rate  float64 // Bytes per second
+	start time.Time
+	total int64
+}
+
+// limiter throttles hashing reads when --io-limit is set.
+var limiter ioLimiter
+
+// chunkSize returns the read size to use: small enough at low rates that a
+// single read does not turn into a long burst followed by a long pause.
+func (l *ioLimiter) chunkSize() int {
+	if l.rate == 0 || l.rate/10 >= hashChunk {
+		return hashChunk
+	}
+	return max(4096, int(l.rate/10))
+}
+
+// wait accounts for n bytes read and sleeps until the average rate is back
+// under the limit. Unused allowance is kept for at most a second, so a pause
+// (e.g. in a hook or between runs) does not permit a long burst afterwards.
+func (l *ioLimiter) wait(ctx context.Context, n int) {
+	if l.rate == 0 {
+		return
+	}
+	if l.start.IsZero() {
+		l.start = time.Now()
+	}
+	l.total += int64(n)
+	due := time.Duration(float64(l.total) / l.rate * float64(time.Second))
+	ahead := due - time.Since(l.start)
+	if ahead < -time.Second {
+		l.start, l.total = time.Now(), 0
+		return
+	}
+	if ahead <= 0 {
+		return
+	}
+	t := time.NewTimer(ahead)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
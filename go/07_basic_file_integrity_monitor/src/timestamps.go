@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+	_ "time/tzdata" // --tz names also work where the OS has no zone database (Windows, scratch images)
+)
+
+// Report timestamps are RFC 3339 in one zone, chosen with --tz (UTC by
+// default), so reports from hosts in different zones line up and no
+// timestamp leaves its zone unstated.
+//
+// Every tool with --tz carries an identical copy of this file
+// (scripts/check_shared_go.py).
+var (
+	tzName   string
+	reportTZ = time.UTC
+)
+
+func registerTZFlag() {
+	flag.StringVar(&tzName, "tz", "UTC", "Time zone for report timestamps (RFC 3339): UTC, Local, or an IANA name such as Europe/Berlin.")
+}
+
+// applyTZ resolves --tz.
+func applyTZ() error {
+	loc, err := time.LoadLocation(tzName)
+	if err != nil {
+		return fmt.Errorf("invalid --tz %q: %w", tzName, err)
+	}
+	reportTZ = loc
+	return nil
+}
+
+// stamp formats t for a report.
+func stamp(t time.Time) string {
+	return t.In(reportTZ).Format(time.RFC3339)
+}
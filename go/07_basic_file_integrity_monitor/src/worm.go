@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Write-once (WORM) evidence. With --worm, baselines and reports (and their
+// signatures) are never overwritten:
+//
+//   - Local files are created exclusively, synced to disk with their
+//     directory, made read-only and, where the system chattr command and
+//     the privileges allow it, marked immutable (chattr +i), so that not
+//     even root can change them without first clearing the flag.
+//   - s3:// destinations are uploaded with S3 Object Lock headers, so the
+//     bucket itself refuses to delete or replace the object before the
+//     retention date. The bucket must have Object Lock enabled.
+var (
+	wormMode      bool
+	wormRetainArg string
+	wormLockMode  string
+	wormRetain    time.Duration
+)
+
+func registerWormFlags() {
+	flag.BoolVar(&wormMode, "worm", false, "Write baselines and reports write-once: local files are created exclusively, synced, made read-only and immutable (chattr +i); s3:// uploads carry Object Lock retention.")
+	flag.StringVar(&wormRetainArg, "worm-retain", "365d", "With --worm, how long S3 Object Lock retains uploads, in days (e.g. 2555d) or as a Go duration.")
+	flag.StringVar(&wormLockMode, "worm-mode", "governance", "With --worm, the S3 Object Lock mode: governance or compliance (cannot be shortened or lifted, even by the account root).")
+}
+
+// checkWorm validates the --worm options against where this run writes.
+func checkWorm() error {
+	if !wormMode {
+		return nil
+	}
+	if wormLockMode != "governance" && wormLockMode != "compliance" {
+		return fmt.Errorf("invalid --worm-mode %q (expected governance or compliance)", wormLockMode)
+	}
+	retain, err := parseRetention(wormRetainArg)
+	if err != nil {
+		return fmt.Errorf("--worm-retain: %v", err)
+	}
+	wormRetain = retain
+	createLocalSink, prepareS3Upload = createWORM, lockS3Upload
+	if createB != "" {
+		if !strings.HasPrefix(createB, "s3://") {
+			if _, err := os.Lstat(createB); err == nil {
+				return fmt.Errorf("--worm: baseline %s already exists and will not be replaced", createB)
+			}
+		}
+		return nil
+	}
+	if outputFile == "" || outputFile == "-" || strings.HasPrefix(outputFile, "http://") || strings.HasPrefix(outputFile, "https://") {
+		return fmt.Errorf("--worm needs -o to be a local file or an s3:// URL")
+	}
+	return nil
+}
+
+// parseRetention reads "<n>d" as days, or any Go duration.
+func parseRetention(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid retention %q (use e.g. 365d or 720h)", s)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("retention must be positive: %q", s)
+	}
+	return d, nil
+}
+
+// wormFileSink is a local report file created with --worm; closing it seals
+// the file.
+type wormFileSink struct{ *os.File }
+
+func (f wormFileSink) Name() string   { return f.File.Name() }
+func (f wormFileSink) file() *os.File { return f.File }
+
+func (f wormFileSink) Close() error {
+	if err := f.File.Sync(); err != nil {
+		f.File.Close()
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	return sealFile(f.File.Name())
+}
+
+// createWORM opens a new file for a write-once report. An existing file is
+// an error rather than being truncated.
+func createWORM(target string) (OutputSink, error) {
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0644)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("%s already exists and --worm does not replace it", target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return wormFileSink{f}, nil
+}
+
+// lockS3Upload adds the Object Lock headers to an S3 upload. Locked uploads
+// must also carry a Content-MD5 (or checksum) header.
+func lockS3Upload(req *http.Request, body []byte) {
+	sum := md5.Sum(body)
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	req.Header.Set("X-Amz-Object-Lock-Mode", strings.ToUpper(wormLockMode))
+	req.Header.Set("X-Amz-Object-Lock-Retain-Until-Date", time.Now().Add(wormRetain).UTC().Format(time.RFC3339))
+}
+
+// linkWORM moves a finished temporary file to out, failing if out exists
+// (a hard link, unlike a rename, never replaces its target), then seals it.
+func linkWORM(tmp, out string) error {
+	err := os.Link(tmp, out)
+	os.Remove(tmp)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists and --worm does not replace it", out)
+	}
+	if err != nil {
+		return err
+	}
+	return sealFile(out)
+}
+
+// sealFile syncs the directory entry of a written file, makes the file
+// read-only and tries to mark it immutable. Immutability needs root (or
+// CAP_LINUX_IMMUTABLE) and a file system that supports it; without them
+// the file stays read-only and a warning says so.
+func sealFile(p string) error {
+	if dir, err := os.Open(filepath.Dir(p)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	if err := os.Chmod(p, 0444); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("chattr"); err != nil {
+		warnf("%s is read-only but not immutable: chattr not available", p)
+		return nil
+	}
+	if out, err := exec.Command("chattr", "+i", p).CombinedOutput(); err != nil {
+		warnf("%s is read-only but not immutable: chattr +i: %v %s", p, err, strings.TrimSpace(string(out)))
+		return nil
+	}
+	debugf("marked %s immutable", p)
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const cacheGuide = "https://developer.mozilla.org/en-US/docs/Web/HTTP/Caching"
+
+// sensitivePathRE matches URL paths that usually serve per-user content.
+var sensitivePathRE = regexp.MustCompile(`(?i)/(login|logout|signin|sign-in|auth|oauth|sso|account|accounts|profile|settings|admin|dashboard|checkout|billing|password|reset|session|token|me)(/|$|\.)`)
+
+// inodeETagRE matches Apache's default ETag format (inode-size-mtime), which
+// discloses the file's inode and differs between servers behind a balancer.
+var inodeETagRE = regexp.MustCompile(`^"[0-9a-f]+-[0-9a-f]+-[0-9a-f]+"$`)
+
+// sensitiveReason explains why a response is treated as carrying per-user
+// data by the cache audit, or returns "" if it is not.
+func sensitiveReason(h http.Header, u *url.URL) string {
+	switch {
+	case allSensitive:
+		return "--sensitive is set"
+	case len(h.Values("Set-Cookie")) > 0:
+		return "the response sets a cookie"
+	case sensitivePathRE.MatchString(u.Path):
+		return "the path looks like an account or login page"
+	}
+	return ""
+}
+
+// varies reports whether the Vary header lists field (or is "*").
+func varies(h http.Header, field string) bool {
+	return hasDirective(h.Values("Vary"), field) || hasDirective(h.Values("Vary"), "*")
+}
+
+// cacheFindings audits caching and compression headers. Responses judged
+// sensitive must not be stored (no-store, not just Pragma or no-cache);
+// Expires is checked for the ways it is commonly misread; and responses whose content depends on
+// a request header must say so in Vary, or a shared cache can serve one
+// client's variant to everyone (cache poisoning), which a shared ETag then
+// confirms on revalidation.
+func cacheFindings(h http.Header, u *url.URL) []Finding {
+	var findings []Finding
+	add := func(header string, sev Severity, desc string) {
+		findings = append(findings, Finding{Header: header, Severity: sev, Description: desc, Remediation: cacheGuide})
+	}
+	cc := h.Values("Cache-Control")
+	noStore := hasDirective(cc, "no-store")
+	private := hasDirective(cc, "private")
+	shared := !noStore && !private && (hasDirective(cc, "public") || hasDirective(cc, "s-maxage"))
+
+	// The API profile already requires no-store on JSON responses.
+	if reason := sensitiveReason(h, u); reason != "" && !noStore && profile != "api" {
+		switch {
+		case shared:
+			add("Cache-Control", SeverityHigh, "Sensitive response ("+reason+") is explicitly cacheable by shared caches; set Cache-Control: no-store.")
+		case len(cc) == 0:
+			add("Cache-Control", SeverityMedium, "Sensitive response ("+reason+") has no Cache-Control; browsers and proxies may store it heuristically. Set Cache-Control: no-store.")
+		case h.Get("Pragma") != "":
+			// net/http reports a lone Pragma: no-cache as Cache-Control: no-cache.
+			add("Cache-Control", SeverityMedium, "Sensitive response ("+reason+") relies on Pragma/Cache-Control: no-cache, which only asks caches to revalidate and does not prevent storage. Set Cache-Control: no-store.")
+		default:
+			add("Cache-Control", SeverityMedium, "Sensitive response ("+reason+") does not set no-store (Cache-Control: "+strings.Join(cc, ", ")+"); it may be kept on disk and shown from history.")
+		}
+	}
+	if shared && len(h.Values("Set-Cookie")) > 0 {
+		add("Set-Cookie", SeverityHigh, "Response sets a cookie but may be stored by shared caches, which would hand the same cookie to other users.")
+	}
+
+	if exp := strings.TrimSpace(h.Get("Expires")); exp != "" {
+		if _, err := http.ParseTime(exp); err != nil && exp != "0" && exp != "-1" {
+			add("Expires", SeverityLow, "Expires ("+exp+") is not a valid HTTP date; caches treat it as already expired, other clients may not.")
+		} else if !hasDirective(cc, "max-age") && !noStore {
+			add("Expires", SeverityInfo, "Expires is set without Cache-Control max-age; HTTP/1.1 caches prefer max-age and clock skew makes Expires unreliable.")
+		}
+	}
+
+	etag := strings.TrimSpace(h.Get("ETag"))
+	enc := strings.TrimSpace(h.Get("Content-Encoding"))
+	if enc != "" && enc != "identity" && !varies(h, "Accept-Encoding") && !noStore {
+		desc := "Response is " + enc + "-encoded without Vary: Accept-Encoding; a shared cache can serve it to clients that cannot decode it."
+		sev := SeverityLow
+		if strings.HasPrefix(etag, "W/") {
+			desc += " Its weak ETag (" + etag + ") declares every encoding equivalent, so revalidation confirms a poisoned entry."
+			sev = SeverityMedium
+		} else if etag != "" {
+			desc += " If its ETag (" + etag + ") is the same for every encoding, revalidation also confirms a poisoned entry."
+			sev = SeverityMedium
+		}
+		add("Vary: Accept-Encoding", sev, desc)
+	}
+	if acao := strings.TrimSpace(h.Get("Access-Control-Allow-Origin")); acao != "" && acao != "*" && !varies(h, "Origin") && !noStore {
+		add("Vary: Origin", SeverityMedium, "Access-Control-Allow-Origin names a specific origin ("+acao+") without Vary: Origin; a cached copy will carry that origin's CORS grant to every other origin.")
+	}
+	if inodeETagRE.MatchString(etag) {
+		add("ETag", SeverityInfo, "ETag "+etag+" has Apache's inode-size-mtime format, which discloses the file's inode and differs between servers behind a load balancer (FileETag MTime Size).")
+	}
+
+	if enc != "" && enc != "identity" && u.Scheme == "https" && sensitiveReason(h, u) != "" {
+		add("Content-Encoding", SeverityInfo, "Sensitive page is served compressed over TLS; if it reflects user input next to secrets such as CSRF tokens, BREACH can recover them.")
+	}
+	return findings
+}
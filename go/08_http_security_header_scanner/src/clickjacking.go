@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ClickjackingPolicy is the effective framing policy a browser would enforce
+// after combining X-Frame-Options and CSP frame-ancestors.
+type ClickjackingPolicy struct {
+	Source    string // Header the effective policy comes from ("" if none)
+	Policy    string // Effective policy value as enforced
+	Protected bool   // Whether cross-origin framing is blocked
+	Notes     []string
+}
+
+// frameAncestors returns the frame-ancestors source list of every enforced
+// CSP policy on the response, and whether any policy has the directive. A
+// response may carry several policies, in separate headers or comma-separated
+// in one; every directive of each is examined, and within a policy only the
+// first frame-ancestors counts, as in browsers. Report-only policies are not
+// enforced by browsers and are ignored.
+func frameAncestors(h http.Header) ([]string, bool) {
+	var lists []string
+	for _, header := range h.Values("Content-Security-Policy") {
+		for _, policy := range strings.Split(header, ",") {
+			for _, directive := range strings.Split(policy, ";") {
+				fields := strings.Fields(directive)
+				if len(fields) > 0 && strings.EqualFold(fields[0], "frame-ancestors") {
+					lists = append(lists, strings.Join(fields[1:], " "))
+					break
+				}
+			}
+		}
+	}
+	return lists, len(lists) > 0
+}
+
+// resolveClickjacking determines the effective clickjacking protection.
+// Browsers that support CSP Level 2 ignore X-Frame-Options entirely when
+// frame-ancestors is present, so CSP always wins.
+func resolveClickjacking(h http.Header) ClickjackingPolicy {
+	xfo := strings.TrimSpace(h.Get("X-Frame-Options"))
+	ancestors, hasCSP := frameAncestors(h)
+
+	if hasCSP {
+		// A frame must be allowed by every enforced policy, so one
+		// restrictive frame-ancestors protects the page.
+		p := ClickjackingPolicy{Source: "CSP frame-ancestors", Policy: strings.Join(ancestors, " | ")}
+		for _, sources := range ancestors {
+			p.Protected = p.Protected || frameAncestorsProtects(sources)
+		}
+		if !p.Protected {
+			p.Notes = append(p.Notes, "frame-ancestors allows framing from any origin.")
+		}
+		if xfo != "" {
+			p.Notes = append(p.Notes, "X-Frame-Options: "+xfo+" is ignored because frame-ancestors takes precedence.")
+		}
+		return p
+	}
+
+	if xfo == "" {
+		return ClickjackingPolicy{Notes: []string{"Neither X-Frame-Options nor CSP frame-ancestors is set."}}
+	}
+
+	p := ClickjackingPolicy{Source: "X-Frame-Options", Policy: xfo}
+	switch value := strings.ToUpper(xfo); {
+	case value == "DENY", value == "SAMEORIGIN":
+		p.Protected = true
+	case strings.HasPrefix(value, "ALLOW-FROM"):
+		p.Notes = append(p.Notes, "ALLOW-FROM is not supported by modern browsers; use CSP frame-ancestors instead.")
+	default:
+		p.Notes = append(p.Notes, "Unrecognized X-Frame-Options value is ignored by browsers.")
+	}
+	return p
+}
+
+// frameAncestorsProtects reports whether a frame-ancestors source list blocks
+// arbitrary cross-origin framing.
+func frameAncestorsProtects(sources string) bool {
+	fields := strings.Fields(sources)
+	if len(fields) == 0 {
+		// An empty source list is treated as 'none'.
+		return true
+	}
+	for _, src := range fields {
+		switch strings.ToLower(src) {
+		case "*", "https:", "http:", "https://*", "http://*":
+			return false
+		}
+	}
+	return true
+}
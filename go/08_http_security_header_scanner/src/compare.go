@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// --compare urlA urlB: scan two URLs, typically the same page on staging and
+// production, and diff their security headers and findings so drift between
+// environments is caught before a release. --compare-origin (see resolve.go)
+// diffs one URL as served by the CDN and by the origin.
+
+// compareSide is one side of a comparison.
+type compareSide struct {
+	URL       string
+	Label     string // How the side is named in the report
+	PublicDNS bool   // Connect through public DNS even if the host is pinned
+}
+
+// compareHeaderNames are the response headers compared in addition to
+// recommendedSecurityHeaders.
+var compareHeaderNames = []string{
+	"X-Frame-Options",
+	"Cross-Origin-Opener-Policy",
+	"Cross-Origin-Embedder-Policy",
+	"Cross-Origin-Resource-Policy",
+	"Cache-Control",
+	"Access-Control-Allow-Origin",
+	"Access-Control-Allow-Credentials",
+}
+
+// headerDiff is one compared header. A value of "" means the header is absent.
+type headerDiff struct {
+	Name string
+	A, B string
+}
+
+// findingDiff is a finding raised for only one of the two URLs.
+type findingDiff struct {
+	Side    string // A or B
+	Finding Finding
+}
+
+// diffHeaders compares the headers of two responses.
+func diffHeaders(a, b http.Header) []headerDiff {
+	names := map[string]bool{}
+	for name := range recommendedSecurityHeaders {
+		names[name] = true
+	}
+	for _, name := range compareHeaderNames {
+		names[name] = true
+	}
+	var diffs []headerDiff
+	for name := range names {
+		d := headerDiff{Name: name, A: strings.Join(a.Values(name), ", "), B: strings.Join(b.Values(name), ", ")}
+		if d.A != "" || d.B != "" {
+			diffs = append(diffs, d)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	return diffs
+}
+
+// diffFindings returns the findings (at or above --min-severity) that only
+// one side has, matched on header and severity.
+func diffFindings(a, b []Finding) []findingDiff {
+	key := func(f Finding) string { return f.Header + "\x00" + f.Severity.String() }
+	inA, inB := map[string]bool{}, map[string]bool{}
+	a, b = filterFindings(a, minSeverity), filterFindings(b, minSeverity)
+	for _, f := range a {
+		inA[key(f)] = true
+	}
+	for _, f := range b {
+		inB[key(f)] = true
+	}
+	var diffs []findingDiff
+	for _, f := range a {
+		if !inB[key(f)] {
+			diffs = append(diffs, findingDiff{"A", f})
+		}
+	}
+	for _, f := range b {
+		if !inA[key(f)] {
+			diffs = append(diffs, findingDiff{"B", f})
+		}
+	}
+	return diffs
+}
+
+// writeComparison writes the diff as text and returns the number of
+// differences.
+func writeComparison(a, b HeaderCheckResult, labelA, labelB string, output io.Writer) int {
+	fmt.Fprintln(output, "--- HTTP Security Header Comparison ---")
+	fmt.Fprintf(output, "\nA: %s\nB: %s\n\n", labelA, labelB)
+	differences := 0
+
+	fmt.Fprintln(output, "--- Headers ---")
+	for _, d := range diffHeaders(a.header, b.header) {
+		switch {
+		case d.A == d.B:
+			fmt.Fprintf(output, "  [SAME]    %s: %s\n", d.Name, d.A)
+			continue
+		case d.B == "":
+			fmt.Fprintf(output, "  [ONLY A]  %s: %s\n", d.Name, d.A)
+		case d.A == "":
+			fmt.Fprintf(output, "  [ONLY B]  %s: %s\n", d.Name, d.B)
+		default:
+			fmt.Fprintf(output, "  [DIFFERS] %s\n      A: %s\n      B: %s\n", d.Name, d.A, d.B)
+		}
+		differences++
+	}
+
+	fmt.Fprintf(output, "--- Findings Only on One Side (min severity: %s) ---\n", minSeverity)
+	findings := diffFindings(a.Findings, b.Findings)
+	if len(findings) == 0 {
+		fmt.Fprintln(output, "  None.")
+	}
+	for _, d := range findings {
+		fmt.Fprintf(output, "  %s: [%s] %s: %s\n", d.Side, colorStatus(strings.ToUpper(d.Finding.Severity.String())), d.Finding.Header, d.Finding.Description)
+	}
+	differences += len(findings)
+
+	fmt.Fprintln(output, "------------------------------")
+	if differences == 0 {
+		fmt.Fprintf(output, "Result: %s, no differences\n", colorStatus("OK"))
+	} else {
+		fmt.Fprintf(output, "Result: %d difference(s)\n", differences)
+	}
+	return differences
+}
+
+// runCompare scans both sides and writes the comparison. It returns the exit
+// status: 0 when the headers match, 1 when they differ or a scan failed.
+func runCompare(ctx context.Context, sideA, sideB compareSide, client *http.Client, recorder *harRecorder) int {
+	scan := func(s compareSide) HeaderCheckResult {
+		scanCtx := ctx
+		if s.PublicDNS {
+			scanCtx = viaPublicDNS(ctx)
+		}
+		return checkSecurityHeaders(scanCtx, scanTarget{URL: s.URL}, client)
+	}
+	a, b := scan(sideA), scan(sideB)
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "[ERROR] Interrupted; nothing was compared.")
+		return 130
+	}
+	for i, r := range []HeaderCheckResult{a, b} {
+		if r.Errors != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %s: %v\n", []string{sideA.Label, sideB.Label}[i], r.Errors)
+			return 1
+		}
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fatalError(fmt.Sprintf("Failed to open output %s", outputFile), err)
+	}
+	enableColor(sinkFile(output))
+	differences := writeComparison(a, b, sideA.Label, sideB.Label, output)
+	if recorder != nil {
+		if err := recorder.writeFile(harPath); err != nil {
+			fatalError(fmt.Sprintf("Failed to write HAR file %s", harPath), err)
+		}
+	}
+	if !closeSink(output) {
+		return 1
+	}
+	code := 0
+	if differences > 0 {
+		code = 1
+	}
+	writeManifest(code, []string{redirectPolicy}, []string{outputFile, harPath})
+	return code
+}
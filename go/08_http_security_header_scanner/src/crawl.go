@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// --crawl: a shallow, breadth-first crawl from each target that adds the
+// same-origin pages it links to, so headers set per route (an admin area
+// behind a different backend, a static file bucket) are checked too.
+// robots.txt is honoured for discovered pages; the targets given on the
+// command line are always scanned.
+
+// crawlBodyLimit bounds how much of a page is read to find links.
+const crawlBodyLimit = 1 << 20
+
+// anchorHref matches the href attribute of an <a> or <area> tag.
+var anchorHref = regexp.MustCompile(`(?is)<(?:a|area)\s[^>]*?\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+
+// robotsRule is one Allow or Disallow line.
+type robotsRule struct {
+	allow   bool
+	pattern *regexp.Regexp
+	length  int // Pattern length; the longest match wins
+}
+
+// crawler discovers pages breadth-first across all seeds.
+type crawler struct {
+	client   *http.Client
+	maxPages int
+	maxDepth int
+	robots   map[string][]robotsRule // By origin
+}
+
+// crawl returns the seeds followed by the pages discovered from them, at
+// most maxPages in total.
+func crawl(ctx context.Context, seeds []string, client *http.Client, maxPages, maxDepth int) []string {
+	c := &crawler{client: client, maxPages: maxPages, maxDepth: maxDepth, robots: map[string][]robotsRule{}}
+	type page struct {
+		url   string
+		depth int
+	}
+	seen := map[string]bool{}
+	var queue []page
+	var pages []string
+	for _, s := range seeds {
+		if !seen[s] && len(pages) < maxPages {
+			seen[s] = true
+			queue = append(queue, page{s, 0})
+			pages = append(pages, s)
+		}
+	}
+	for len(queue) > 0 && ctx.Err() == nil {
+		p := queue[0]
+		queue = queue[1:]
+		if p.depth >= maxDepth || len(pages) >= maxPages {
+			continue
+		}
+		links, err := c.links(ctx, p.url)
+		if err != nil {
+			debugf("crawl %s: %v", p.url, err)
+			continue
+		}
+		for _, link := range links {
+			if seen[link] || len(pages) >= maxPages {
+				continue
+			}
+			seen[link] = true
+			if !c.allowed(ctx, link) {
+				if verboseMode {
+					fmt.Fprintf(os.Stderr, "[INFO] Disallowed by robots.txt, skipping: %s\n", link)
+				}
+				continue
+			}
+			queue = append(queue, page{link, p.depth + 1})
+			pages = append(pages, link)
+		}
+	}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Crawl found %d page(s) from %d target(s).\n", len(pages), len(seeds))
+	}
+	return pages
+}
+
+// links fetches an HTML page and returns its same-origin links, normalized
+// like the scan targets.
+func (c *crawler) links(ctx context.Context, pageURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, fmt.Errorf("%s %s, not followed", resp.Status, mediaType)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, crawlBodyLimit))
+	if err != nil {
+		return nil, err
+	}
+	base := resp.Request.URL // After redirects
+	var links []string
+	for _, m := range anchorHref.FindAllStringSubmatch(string(body), -1) {
+		ref, err := url.Parse(html.UnescapeString(strings.TrimSpace(m[1] + m[2] + m[3])))
+		if err != nil {
+			continue
+		}
+		u := base.ResolveReference(ref)
+		if u.Scheme != base.Scheme || u.Host != base.Host {
+			continue // Other origins, mailto:, javascript:
+		}
+		if normalized, err := normalizeURL(u.String(), stripQuery); err == nil {
+			links = append(links, normalized)
+		}
+	}
+	debugf("crawl %s: %d link(s)", pageURL, len(links))
+	return links, nil
+}
+
+// allowed checks a URL against its origin's robots.txt.
+func (c *crawler) allowed(ctx context.Context, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	base := u.Scheme + "://" + u.Host
+	rules, ok := c.robots[base]
+	if !ok {
+		rules = c.fetchRobots(ctx, base)
+		c.robots[base] = rules
+	}
+	target := u.EscapedPath()
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	best := robotsRule{allow: true, length: -1}
+	for _, r := range rules {
+		if r.pattern.MatchString(target) && (r.length > best.length || (r.length == best.length && r.allow)) {
+			best = r
+		}
+	}
+	return best.allow
+}
+
+// fetchRobots reads the rules of the group for this tool, or of the "*"
+// group when there is none. A missing or unreadable robots.txt allows
+// everything.
+func (c *crawler) fetchRobots(ctx context.Context, base string) []robotsRule {
+	req, err := http.NewRequestWithContext(ctx, "GET", base+"/robots.txt", nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		debugf("robots.txt for %s: %v", base, err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		debugf("robots.txt for %s: %s", base, resp.Status)
+		return nil
+	}
+	rules := parseRobots(io.LimitReader(resp.Body, 512<<10))
+	debugf("robots.txt for %s: %d rule(s)", base, len(rules))
+	return rules
+}
+
+// parseRobots parses robots.txt (RFC 9309) and returns the rules that apply.
+func parseRobots(r io.Reader) []robotsRule {
+	groups := map[string][]robotsRule{}
+	var agents []string
+	inRules := false
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false // A new group starts
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue // "Disallow:" with no path allows everything
+			}
+			rule := robotsRule{allow: key == "allow", pattern: robotsPattern(value), length: len(value)}
+			for _, a := range agents {
+				groups[a] = append(groups[a], rule)
+			}
+		}
+	}
+	if rules, ok := groups[toolName]; ok {
+		return rules
+	}
+	return groups["*"]
+}
+
+// robotsPattern compiles a path pattern, where * matches any characters and
+// a trailing $ anchors the end.
+func robotsPattern(p string) *regexp.Regexp {
+	anchored := strings.HasSuffix(p, "$")
+	p = regexp.QuoteMeta(strings.TrimSuffix(p, "$"))
+	p = "^" + strings.ReplaceAll(p, `\*`, ".*")
+	if anchored {
+		p += "$"
+	}
+	return regexp.MustCompile(p)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// exposureGuide is the remediation reference for exposed artifacts.
+const exposureGuide = "https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/02-Configuration_and_Deployment_Management_Testing/04-Review_Old_Backup_and_Unreferenced_Files_for_Sensitive_Information"
+
+// exposurePath is one entry of the curated --check-exposures list.
+type exposurePath struct {
+	Path        string
+	Severity    Severity
+	Description string
+	HTML        bool // The artifact is itself an HTML page, so text/html does not indicate a soft 404
+}
+
+// exposurePaths is deliberately short: one HEAD request each per origin.
+var exposurePaths = []exposurePath{
+	{"/.git/HEAD", SeverityCritical, "Git metadata is served; the repository and its history can usually be reconstructed.", false},
+	{"/.env", SeverityCritical, "Environment file is served; these typically hold database credentials and API keys.", false},
+	{"/.aws/credentials", SeverityCritical, "AWS credentials file is served.", false},
+	{"/.htpasswd", SeverityHigh, "Password file is served; its hashes can be cracked offline.", false},
+	{"/.svn/wc.db", SeverityHigh, "Subversion working copy database is served, listing and often containing source files.", false},
+	{"/backup.zip", SeverityHigh, "Backup archive is served.", false},
+	{"/backup.tar.gz", SeverityHigh, "Backup archive is served.", false},
+	{"/backup.sql", SeverityHigh, "Database dump is served.", false},
+	{"/wp-config.php.bak", SeverityHigh, "Backup of the WordPress configuration is served as plain text, including database credentials.", false},
+	{"/server-status", SeverityMedium, "Apache mod_status page is public, revealing client addresses and the URLs being requested.", true},
+	{"/server-info", SeverityMedium, "Apache mod_info page is public, revealing the server configuration.", true},
+	{"/phpinfo.php", SeverityMedium, "phpinfo() page is public, revealing configuration, paths and environment variables.", true},
+	{"/.DS_Store", SeverityLow, "macOS folder metadata is served and lists file names in the directory.", false},
+}
+
+// origin returns the scheme and host of rawURL, or "" if it has none.
+func origin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// headProbe sends a HEAD request without following redirects and returns the
+// status code and media type.
+func headProbe(ctx context.Context, client *http.Client, target string) (int, string, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", target, nil)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	resp.Body.Close()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return resp.StatusCode, mediaType, resp.ContentLength, nil
+}
+
+// checkExposures probes the curated paths on one origin with HEAD requests
+// and returns a finding for each artifact that is served. Only 200 responses
+// count; redirects (often to a login page) are not followed. Servers that
+// answer 200 for any path are detected with a random path first: a hit must
+// then differ from that response's media type, and non-HTML artifacts
+// answered with an HTML page are ignored as soft 404s.
+func checkExposures(ctx context.Context, base string, client *http.Client) []Finding {
+	probe := *client
+	probe.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	var nonce [8]byte
+	rand.Read(nonce[:])
+	catchAll := ""
+	if status, mediaType, _, err := headProbe(ctx, &probe, base+"/"+hex.EncodeToString(nonce[:])); err == nil && status == http.StatusOK {
+		catchAll = mediaType
+		debugf("%s answers 200 for unknown paths (%s)", base, mediaType)
+	}
+
+	var findings []Finding
+	for _, e := range exposurePaths {
+		if ctx.Err() != nil {
+			break
+		}
+		status, mediaType, size, err := headProbe(ctx, &probe, base+e.Path)
+		if err != nil {
+			debugf("exposure probe %s%s: %v", base, e.Path, err)
+			continue
+		}
+		debugf("exposure probe %s%s: %d %s", base, e.Path, status, mediaType)
+		if status != http.StatusOK || (catchAll != "" && mediaType == catchAll) || (!e.HTML && mediaType == "text/html") {
+			continue
+		}
+		detail := "HEAD " + base + e.Path + " returned 200"
+		if mediaType != "" {
+			detail += " (" + mediaType
+			if size >= 0 {
+				detail += fmt.Sprintf(", %d bytes", size)
+			}
+			detail += ")"
+		}
+		findings = append(findings, Finding{
+			Header:      "Exposed Path " + e.Path,
+			Severity:    e.Severity,
+			Description: e.Description + " " + detail + ".",
+			Remediation: exposureGuide,
+		})
+	}
+	return findings
+}
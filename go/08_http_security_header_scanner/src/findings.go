@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity ranks how urgently a finding should be remediated.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"info", "low", "medium", "high", "critical"}
+
+// String returns the lower-case severity name.
+func (s Severity) String() string {
+	if s < SeverityInfo || s > SeverityCritical {
+		return "unknown"
+	}
+	return severityNames[s]
+}
+
+// parseSeverity converts a severity name (case-insensitive) into a Severity.
+func parseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(name, n) {
+			return Severity(i), nil
+		}
+	}
+	return SeverityInfo, fmt.Errorf("unknown severity %q (expected one of %s)", name, strings.Join(severityNames, ", "))
+}
+
+// Finding is a single security observation about a scanned URL.
+type Finding struct {
+	Header      string
+	Severity    Severity
+	Description string
+	Remediation string // Link to remediation guidance
+	// CompatNotes describe legacy browser behavior (populated in --legacy-browsers mode)
+	CompatNotes []string
+}
+
+// headerRule describes a recommended header and the finding raised when it is absent.
+type headerRule struct {
+	Severity    Severity
+	Description string
+	Remediation string
+}
+
+// missingFinding builds the finding for a recommended header that was not returned.
+func missingFinding(header string) Finding {
+	rule := recommendedSecurityHeaders[header]
+	return Finding{Header: header, Severity: rule.Severity, Description: rule.Description, Remediation: rule.Remediation}
+}
+
+// sortFindings orders findings by descending severity, then header name,
+// so reports are stable between runs.
+func sortFindings(findings []Finding) {
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		return findings[i].Header < findings[j].Header
+	})
+}
+
+// filterFindings returns the findings at or above the minimum severity.
+func filterFindings(findings []Finding, min Severity) []Finding {
+	var kept []Finding
+	for _, f := range findings {
+		if f.Severity >= min {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// missingHeaderVectors score the findings for absent recommended headers in
+// the --findings export. Other findings carry a severity only.
+var missingHeaderVectors = map[string]string{
+	"Strict-Transport-Security": "AV:A/PR:N/C:H/I:H/A:N", // Downgrade needs an on-path attacker
+	"Content-Security-Policy":   "AV:N/PR:N/C:L/I:L/A:N",
+	"X-Content-Type-Options":    "AV:N/PR:N/C:L/I:L/A:N",
+	"Referrer-Policy":           "AV:N/PR:N/C:L/I:N/A:N",
+}
+
+// normalizedFindings converts the scan results to the cross-tool findings
+// model. Exposed paths are scored as a network-readable confidentiality loss.
+func normalizedFindings(results []HeaderCheckResult) []normFinding {
+	var out []normFinding
+	for _, r := range results {
+		for _, f := range r.Findings {
+			n := normFinding{Tool: toolName, Target: r.URL, Category: "header", Title: f.Header, Severity: f.Severity.String(), Detail: f.Description}
+			switch {
+			case strings.HasPrefix(f.Header, "Exposed Path "):
+				n.Category = "exposure"
+				n = n.withVector("AV:N/PR:N/C:H/I:N/A:N")
+			case missingHeaderVectors[f.Header] != "" && f.Description == recommendedSecurityHeaders[f.Header].Description:
+				n = n.withVector(missingHeaderVectors[f.Header])
+			}
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// headerGroup collects one finding across every URL it was raised for.
+type headerGroup struct {
+	Header      string
+	Severity    Severity // Highest severity seen for the header
+	Remediation string
+	URLs        []string
+	Details     map[string]string // Description per URL
+}
+
+// groupFindings inverts per-URL results into one group per finding header,
+// ordered by severity, then by the number of affected URLs.
+func groupFindings(results []HeaderCheckResult, min Severity) []*headerGroup {
+	byHeader := map[string]*headerGroup{}
+	var groups []*headerGroup
+	for _, r := range results {
+		if r.Errors != nil {
+			continue
+		}
+		for _, f := range filterFindings(r.Findings, min) {
+			g := byHeader[f.Header]
+			if g == nil {
+				g = &headerGroup{Header: f.Header, Severity: f.Severity, Remediation: f.Remediation, Details: map[string]string{}}
+				byHeader[f.Header] = g
+				groups = append(groups, g)
+			}
+			if f.Severity > g.Severity {
+				g.Severity = f.Severity
+			}
+			if _, seen := g.Details[r.target()]; !seen {
+				g.URLs = append(g.URLs, r.target())
+			}
+			g.Details[r.target()] = f.Description
+		}
+	}
+	for _, g := range groups {
+		sort.Strings(g.URLs)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Severity != groups[j].Severity {
+			return groups[i].Severity > groups[j].Severity
+		}
+		if len(groups[i].URLs) != len(groups[j].URLs) {
+			return len(groups[i].URLs) > len(groups[j].URLs)
+		}
+		return groups[i].Header < groups[j].Header
+	})
+	return groups
+}
+
+// writeGroupedReport writes the --group-by header report: one section per
+// missing or weak header listing every affected URL, which maps directly onto
+// one remediation ticket each. URLs that could not be scanned are listed last.
+func writeGroupedReport(results []HeaderCheckResult, output io.Writer) {
+	fmt.Fprintf(output, "---\n")
+	fmt.Fprintf(output, "--- HTTP Security Header Scan Report (by header) ---\n")
+	fmt.Fprintf(output, "\n")
+	if len(results) == 0 {
+		fmt.Fprintln(output, "No URLs were scanned or no results to report.")
+		return
+	}
+
+	scanned := 0
+	var failed []HeaderCheckResult
+	for _, r := range results {
+		if r.Errors != nil {
+			failed = append(failed, r)
+		} else {
+			scanned++
+		}
+	}
+	groups := groupFindings(results, minSeverity)
+	fmt.Fprintf(output, "URLs scanned: %d, findings (min severity: %s): %d\n", scanned, minSeverity, len(groups))
+	fmt.Fprintln(output, "------------------------------")
+
+	for _, g := range groups {
+		fmt.Fprintf(output, "[%s] %s (%d of %d URLs)\n", colorStatus(strings.ToUpper(g.Severity.String())), g.Header, len(g.URLs), scanned)
+		same := true
+		for _, u := range g.URLs {
+			same = same && g.Details[u] == g.Details[g.URLs[0]]
+		}
+		if same {
+			fmt.Fprintf(output, "  %s\n", g.Details[g.URLs[0]])
+		}
+		fmt.Fprintf(output, "  Remediation: %s\n", g.Remediation)
+		fmt.Fprintln(output, "  Affected URLs:")
+		for _, u := range g.URLs {
+			if same {
+				fmt.Fprintf(output, "    %s\n", u)
+			} else {
+				fmt.Fprintf(output, "    %s: %s\n", u, g.Details[u])
+			}
+		}
+		fmt.Fprintln(output, "------------------------------")
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintf(output, "--- %s (%d URLs) ---\n", colorStatus("ERROR"), len(failed))
+		for _, r := range failed {
+			fmt.Fprintf(output, "  %s: %v\n", r.target(), r.Errors)
+		}
+		fmt.Fprintln(output, "------------------------------")
+	}
+}
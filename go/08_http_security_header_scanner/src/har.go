@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HTTP Archive (HAR 1.2) structures. Only the fields needed to describe a
+// header scan are populated; response bodies are never recorded. The body of
+// a JSON target's request is, so the request can be replayed.
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+
+	started time.Time // For ordering; StartedDateTime is its serialized form
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Params   []harNameValue `json:"params"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// harTimings are in milliseconds; -1 means the phase did not apply
+// (e.g. DNS on a reused connection, SSL on plain HTTP).
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harRecorder is an http.RoundTripper that records every request/response
+// pair (including each redirect hop) as a HAR entry.
+type harRecorder struct {
+	next    http.RoundTripper
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// newHARRecorder wraps next (or http.DefaultTransport when nil).
+func newHARRecorder(next http.RoundTripper) *harRecorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &harRecorder{next: next}
+}
+
+// RoundTrip performs the request while tracing connection phases.
+func (r *harRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var dnsStart, dnsDone, connStart, connDone, tlsStart, tlsDone, wroteReq, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { connStart = time.Now() },
+		ConnectDone:          func(string, string, error) { connDone = time.Now() },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tlsDone = time.Now() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wroteReq = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	start := time.Now()
+	resp, err := r.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	end := time.Now()
+
+	entry := harEntry{
+		started:         start,
+		StartedDateTime: start.In(reportTZ).Format(time.RFC3339Nano),
+		Time:            millis(start, end),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: harQuery(req),
+			HeadersSize: -1,
+			PostData:    harBody(req),
+			BodySize:    max(int(req.ContentLength), 0),
+		},
+		Timings: harTimings{
+			Blocked: -1,
+			DNS:     phase(dnsStart, dnsDone),
+			Connect: phase(connStart, connDone),
+			SSL:     phase(tlsStart, tlsDone),
+			Send:    0,
+			Wait:    phase(wroteReq, firstByte),
+			Receive: phase(firstByte, end),
+		},
+	}
+	if !tlsDone.IsZero() && entry.Timings.Connect >= 0 {
+		// HAR counts the TLS handshake as part of connect as well.
+		entry.Timings.Connect += entry.Timings.SSL
+	}
+	if entry.Timings.Wait < 0 {
+		entry.Timings.Wait = 0
+	}
+	if entry.Timings.Receive < 0 {
+		entry.Timings.Receive = 0
+	}
+
+	if err != nil {
+		entry.Response = harResponse{Cookies: []harNameValue{}, Headers: []harNameValue{}, HeadersSize: -1, BodySize: -1}
+		entry.Comment = err.Error()
+	} else {
+		entry.Response = harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(resp.Header),
+			Content:     harContent{Size: resp.ContentLength, MimeType: resp.Header.Get("Content-Type")},
+			RedirectURL: resp.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+	}
+	if entry.Request.HTTPVersion == "" {
+		entry.Request.HTTPVersion = entry.Response.HTTPVersion
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+	return resp, err
+}
+
+// writeFile saves all recorded entries, in start order, as a HAR document.
+func (r *harRecorder) writeFile(path string) error {
+	r.mu.Lock()
+	entries := append([]harEntry(nil), r.entries...)
+	r.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].started.Before(entries[j].started) })
+	if entries == nil {
+		entries = []harEntry{}
+	}
+
+	doc := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: toolName, Version: toolVersion},
+		Entries: entries,
+	}}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	out := []harNameValue{}
+	for name, values := range h {
+		for _, v := range values {
+			out = append(out, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func harQuery(req *http.Request) []harNameValue {
+	out := []harNameValue{}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			out = append(out, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// harBody returns a copy of the request body, or nil when there is none.
+func harBody(req *http.Request) *harPostData {
+	if req.GetBody == nil || req.ContentLength == 0 {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	text, err := io.ReadAll(body)
+	if err != nil {
+		return nil
+	}
+	return &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(text), Params: []harNameValue{}}
+}
+
+// phase returns the duration between two trace events in milliseconds, or -1
+// if either event did not occur.
+func phase(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() {
+		return -1
+	}
+	return millis(start, end)
+}
+
+func millis(start, end time.Time) float64 {
+	return float64(end.Sub(start).Microseconds()) / 1000
+}
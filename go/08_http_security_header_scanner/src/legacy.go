@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// browserCompat is one row of the embedded browser-compatibility table used
+// by the legacy analysis mode.
+type browserCompat struct {
+	Header   string
+	Browsers string // Affected user agents
+	Note     string
+}
+
+// browserCompatTable summarizes how legacy user agents interpret each header.
+var browserCompatTable = []browserCompat{
+	{"Strict-Transport-Security", "IE 10 and earlier, IE 11 on Windows 7/8.1 without KB3058515", "HSTS is ignored; first-visit downgrade protection is unavailable."},
+	{"Content-Security-Policy", "IE 10-11", "Only the prefixed X-Content-Security-Policy header with the sandbox directive is honored; all other directives are ignored."},
+	{"Content-Security-Policy", "Safari 5-6, Chrome 14-24", "Only the prefixed X-WebKit-CSP header is honored."},
+	{"X-Content-Type-Options", "Firefox before 50, Safari before 11", "nosniff is ignored for most resource types."},
+	{"Referrer-Policy", "IE 11, Edge before 79, Safari before 11.1", "Header is ignored; the full referrer is sent unless a <meta name=\"referrer\"> tag is used."},
+	{"Permissions-Policy", "Chrome before 88, all non-Chromium browsers", "Header is ignored; Chrome 60-87 only understands the older Feature-Policy header."},
+	{"X-Frame-Options / CSP frame-ancestors", "IE 8-11, Edge before 15", "CSP frame-ancestors is not supported; X-Frame-Options is the only effective framing control."},
+	{"X-Frame-Options", "Firefox 70+, Chrome, Safari", "ALLOW-FROM is not supported and the header is ignored entirely."},
+	{"X-XSS-Protection", "IE 8-11, Edge before 79, Chrome before 78, Safari", "The XSS auditor is active only in these browsers and can be abused for cross-site information leaks."},
+	{"Feature-Policy", "Chrome 60-87", "Superseded by Permissions-Policy; modern browsers ignore Feature-Policy."},
+}
+
+// compatNotes returns the compatibility notes recorded for a header.
+func compatNotes(header string) []string {
+	var notes []string
+	for _, c := range browserCompatTable {
+		if c.Header == header {
+			notes = append(notes, c.Browsers+": "+c.Note)
+		}
+	}
+	return notes
+}
+
+// legacyFindings evaluates headers that only matter to legacy user agents and
+// annotates every finding with browser-compat notes from the embedded table.
+func legacyFindings(h http.Header, findings []Finding) []Finding {
+	if xss := strings.TrimSpace(h.Get("X-XSS-Protection")); xss != "" && !strings.HasPrefix(xss, "0") {
+		findings = append(findings, Finding{
+			Header:      "X-XSS-Protection",
+			Severity:    SeverityLow,
+			Description: "XSS auditor is enabled (" + xss + "); it is removed from modern browsers and can introduce information leaks in legacy ones. Set it to 0 and rely on CSP.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/X-XSS-Protection",
+		})
+	}
+
+	xfo := strings.ToUpper(strings.TrimSpace(h.Get("X-Frame-Options")))
+	if strings.HasPrefix(xfo, "ALLOW-FROM") {
+		findings = append(findings, Finding{
+			Header:      "X-Frame-Options",
+			Severity:    SeverityMedium,
+			Description: "ALLOW-FROM only protects IE and old Firefox; other browsers ignore the header and allow framing.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy/frame-ancestors",
+		})
+	}
+	if _, hasAncestors := frameAncestors(h); hasAncestors && xfo == "" {
+		findings = append(findings, Finding{
+			Header:      "X-Frame-Options / CSP frame-ancestors",
+			Severity:    SeverityLow,
+			Description: "frame-ancestors is set without X-Frame-Options, leaving IE and legacy Edge users unprotected against clickjacking.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/X-Frame-Options",
+		})
+	}
+
+	if h.Get("Feature-Policy") != "" && h.Get("Permissions-Policy") == "" {
+		findings = append(findings, Finding{
+			Header:      "Feature-Policy",
+			Severity:    SeverityInfo,
+			Description: "Only the deprecated Feature-Policy header is set; add Permissions-Policy for current browsers.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Permissions-Policy",
+		})
+	}
+
+	for i := range findings {
+		findings[i].CompatNotes = compatNotes(findings[i].Header)
+	}
+	return findings
+}
@@ -5,47 +5,105 @@ SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
 
 CONTEXT: This code is a frozen demonstration of an HTTP Security Header Scanner.
 PURPOSE: Show skill in HTTP client operations, header parsing, and CLI utility development in Go.
-CONSTRAINTS: Uses standard library only, designed for CLI, <=300 lines (intentional).
+CONSTRAINTS: Uses standard library only, designed for CLI.
 STATUS: Complete demonstration - no updates planned.
 EVALUATION: Assess what this demonstrates, not production readiness.
 */
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url" // For URL parsing
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
+// Tool identity, recorded in run manifests and HAR files.
+const (
+	toolName    = "http_security_header_scanner"
+	toolVersion = "1.0.1"
+)
+
 // Global variables for CLI flags
 var (
-	targetURL   string
-	inputFile   string
-	outputFile  string
-	timeoutSec  int
-	verboseMode bool
+	targetURL     string
+	inputFile     string
+	outputFile    string
+	timeoutSec    int
+	verboseMode   bool
+	minSevFlag    string
+	minSeverity   Severity
+	harPath       string
+	format        string
+	scopeList     string
+	stripQuery    bool
+	legacyMode    bool
+	profile       string
+	exposures     bool
+	groupBy       string
+	cacheAudit    bool
+	crawlMode     bool
+	maxPages      int
+	crawlDepth    int
+	noTLSInfo     bool
+	compareMode   bool
+	compareOrigin string
+	sriAudit      bool
+	// redirectPolicy is the --redirect-policy file; its rules are loaded into redirectRules
+	redirectPolicy string
+	redirectRules  []redirectRule
+	// allSensitive treats every URL as per-user content in the cache audit
+	allSensitive bool
+	// Per-phase timeouts in seconds; 0 leaves a phase bounded only by timeoutSec
+	connectTimeoutSec, tlsTimeoutSec, headerTimeoutSec int
+	maxConnsPerHost                                    int
+	dnsCacheSec                                        int
+	noKeepAlive                                        bool
+	resolveOverrides                                   = resolveList{}
 )
 
 // HeaderCheckResult stores the result of a single URL header check
 type HeaderCheckResult struct {
-	URL     string
-	Headers map[string]string // Found security headers and their values
-	Missing []string          // Missing recommended security headers
-	Errors  error
+	URL string
+	// Method and Request describe a JSON target from the input file; both are empty for a plain GET
+	Method  string
+	Request string
+	// Response is the final status line, reported for JSON targets
+	Response string
+	Headers  map[string]string // Found security headers and their values
+	// Findings are missing or misconfigured headers, ordered by severity
+	Findings []Finding
+	// Clickjacking is the effective framing policy (X-Frame-Options and CSP frame-ancestors combined)
+	Clickjacking ClickjackingPolicy
+	// TLS is the certificate of an HTTPS response (nil for HTTP or with --no-tls-info)
+	TLS    *TLSSummary
+	Errors error
+	header http.Header // The full response header, for --compare
 }
 
-// Recommended security headers to check for
-var recommendedSecurityHeaders = map[string]string{
-	"Strict-Transport-Security": "Strict-Transport-Security (HSTS) enforces secure connections.",
-	"X-Frame-Options":           "X-Frame-Options prevents clickjacking attacks.",
-	"X-Content-Type-Options":    "X-Content-Type-Options prevents MIME sniffing.",
-	"Content-Security-Policy":   "Content-Security-Policy (CSP) prevents XSS and data injection attacks.",
-	"Referrer-Policy":           "Referrer-Policy controls how much referrer information is sent.",
-	"Permissions-Policy":        "Permissions-Policy allows/disallows use of browser features.",
+// Recommended security headers to check for.
+// X-Frame-Options is evaluated together with CSP frame-ancestors (see resolveClickjacking).
+var recommendedSecurityHeaders = map[string]headerRule{
+	"Strict-Transport-Security": {SeverityHigh, "Strict-Transport-Security (HSTS) enforces secure connections.",
+		"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Strict-Transport-Security"},
+	"X-Content-Type-Options": {SeverityMedium, "X-Content-Type-Options prevents MIME sniffing.",
+		"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/X-Content-Type-Options"},
+	"Content-Security-Policy": {SeverityHigh, "Content-Security-Policy (CSP) prevents XSS and data injection attacks.",
+		"https://developer.mozilla.org/en-US/docs/Web/HTTP/CSP"},
+	"Referrer-Policy": {SeverityLow, "Referrer-Policy controls how much referrer information is sent.",
+		"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Referrer-Policy"},
+	"Permissions-Policy": {SeverityLow, "Permissions-Policy allows/disallows use of browser features.",
+		"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Permissions-Policy"},
 	// Add other headers as needed
 }
 
@@ -54,18 +112,65 @@ func init() {
 	flag.StringVar(&targetURL, "url", "", "Target URL to scan (e.g., https://example.com).")
 	flag.StringVar(&targetURL, "u", "", "Target URL to scan (shorthand).")
 
-	flag.StringVar(&inputFile, "input", "", "Path to a file containing a list of URLs to scan (one URL per line). Overrides -url if provided.")
+	flag.StringVar(&inputFile, "input", "", "Path to a file containing a list of URLs to scan (one URL, host:port, or JSON {\"method\", \"url\", \"body\"} target per line), or - to read them from stdin. Overrides -url if provided.")
 	flag.StringVar(&inputFile, "i", "", "Path to a file containing a list of URLs to scan (shorthand).")
 
-	flag.StringVar(&outputFile, "output", "", "Path to save the report. If not provided, prints to stdout.")
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
 	flag.StringVar(&outputFile, "o", "", "Path to save the report (shorthand).")
 
-	flag.IntVar(&timeoutSec, "timeout", 10, "HTTP request timeout in seconds.")
+	flag.IntVar(&timeoutSec, "timeout", 10, "Overall HTTP request timeout in seconds, including redirects and all phases.")
 	flag.IntVar(&timeoutSec, "t", 10, "HTTP request timeout in seconds (shorthand).")
+	flag.IntVar(&connectTimeoutSec, "connect-timeout", 0, "Seconds allowed for DNS resolution and the TCP connect (0: only --timeout applies).")
+	flag.IntVar(&tlsTimeoutSec, "tls-timeout", 0, "Seconds allowed for the TLS handshake (0: only --timeout applies).")
+	flag.IntVar(&headerTimeoutSec, "header-timeout", 0, "Seconds allowed between sending the request and receiving the response headers (0: only --timeout applies).")
+
+	flag.IntVar(&maxConnsPerHost, "max-conns-per-host", 6, "Maximum connections per host; further requests wait to reuse one (0: unlimited).")
+	flag.IntVar(&dnsCacheSec, "dns-cache", 300, "Seconds to cache DNS answers between connections (0: resolve for every connection).")
+	flag.Var(resolveOverrides, "resolve", "Connect to host:port at the given address instead of resolving it, curl style: host:port:addr[,addr] (repeatable).")
+	flag.BoolVar(&noKeepAlive, "no-keepalive", false, "Open a new connection for every request instead of reusing idle ones.")
+
+	flag.StringVar(&minSevFlag, "min-severity", "info", "Only report findings at or above this severity (info, low, medium, high, critical).")
+
+	flag.StringVar(&scopeList, "scope", "", "Comma-separated domains to restrict scanning to (subdomains included); out-of-scope URLs are dropped.")
+	flag.BoolVar(&stripQuery, "strip-query", false, "Strip query strings when normalizing URLs, so ?a=1 and ?a=2 are scanned once.")
+
+	flag.StringVar(&profile, "profile", "web", "Policy profile: web (HTML pages) or api (JSON endpoints: caching, Content-Type, CORS checks).")
+
+	flag.BoolVar(&legacyMode, "legacy-browsers", false, "Evaluate how legacy user agents interpret the headers and annotate findings with browser-compat notes.")
+
+	flag.BoolVar(&exposures, "check-exposures", false, "Also send HEAD requests for a short list of sensitive paths (/.git/HEAD, /.env, /server-status, backups) on each scanned origin.")
+
+	flag.BoolVar(&crawlMode, "crawl", false, "Also scan same-origin pages linked from each target, breadth-first, honouring robots.txt.")
+	flag.IntVar(&maxPages, "max-pages", 50, "With --crawl, the most pages to scan in total, targets included.")
+	flag.IntVar(&crawlDepth, "depth", 2, "With --crawl, how many links away from a target to follow.")
+
+	flag.StringVar(&redirectPolicy, "redirect-policy", "", "File of expected redirects per URL pattern (status=, to=, max-hops=); violations are reported as findings.")
+	flag.BoolVar(&compareMode, "compare", false, "Scan the two URLs given as arguments (--compare urlA urlB) and report how their security headers differ.")
+	flag.StringVar(&compareOrigin, "compare-origin", "", "Scan this URL through public DNS (the CDN) and through its --resolve address (the origin), and report how their security headers differ.")
+	flag.BoolVar(&sriAudit, "sri-audit", false, "Read HTML pages and report third-party scripts/stylesheets without an integrity attribute, or not allowed by the CSP.")
+	flag.BoolVar(&noTLSInfo, "no-tls-info", false, "Leave the TLS version, certificate issuer and expiry out of the results for HTTPS URLs.")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or html.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.BoolVar(&cacheAudit, "cache-audit", false, "Audit Cache-Control/Pragma/Expires on sensitive pages, and Vary/ETag/Content-Encoding combinations that allow cache poisoning.")
+	flag.BoolVar(&allSensitive, "sensitive", false, "With --cache-audit, treat every URL as an authenticated page that must not be stored.")
+
+	flag.StringVar(&groupBy, "group-by", "url", "Text report layout: url (one section per URL) or header (one section per finding, listing affected URLs).")
+
+	flag.StringVar(&harPath, "har", "", "Path to write an HTTP Archive (HAR) file recording every request/response.")
 
 	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
 	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
 
+	registerOutputFlags()
+	registerManifestFlag()
+	registerFindingsFlags()
+	registerOtelFlag()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+	registerTZFlag()
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  Analyzes HTTP response headers of specified URLs for common security headers.\n")
@@ -86,52 +191,160 @@ func fatalError(msg string, err error) {
 	os.Exit(1)
 }
 
+// target is the URL as shown in reports, prefixed with the method for JSON
+// targets so that several requests to one endpoint can be told apart.
+func (r HeaderCheckResult) target() string {
+	if r.Method == "" {
+		return r.URL
+	}
+	return r.Method + " " + r.URL
+}
+
 // checkSecurityHeaders makes an HTTP request and analyzes security headers.
-func checkSecurityHeaders(targetURL string, client *http.Client) HeaderCheckResult {
-	result := HeaderCheckResult{URL: targetURL, Headers: make(map[string]string)}
+func checkSecurityHeaders(ctx context.Context, t scanTarget, client *http.Client) HeaderCheckResult {
+	targetURL := t.URL
+	result := HeaderCheckResult{URL: targetURL, Request: t.label(), Headers: make(map[string]string)}
+	if result.Request != "" {
+		result.Method = t.Method
+	}
 
 	if verboseMode {
-		fmt.Fprintf(os.Stderr, "[INFO] Scanning URL: %s\n", targetURL)
+		fmt.Fprintf(os.Stderr, "[INFO] Scanning URL: %s\n", result.target())
 	}
 
-	req, err := http.NewRequest("GET", targetURL, nil)
+	req, err := t.newRequest(ctx)
 	if err != nil {
 		result.Errors = fmt.Errorf("failed to create request: %w", err)
 		return result
 	}
+	if profile == "api" {
+		req.Header.Set("Origin", corsProbeOrigin)
+	}
+	if cacheAudit {
+		// Set explicitly so the transport keeps Content-Encoding instead of
+		// decompressing transparently; the body is never read.
+		req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
 		result.Errors = fmt.Errorf("HTTP request failed: %w", err)
 		return result
 	}
-	defer resp.Body.Close()
+	defer func() {
+		// Read what is left of a small body so the connection can be reused.
+		io.Copy(io.Discard, io.LimitReader(resp.Body, drainLimit))
+		resp.Body.Close()
+	}()
+	debugf("%s: %s %s, %d response header(s)", targetURL, resp.Proto, resp.Status, len(resp.Header))
+	result.header = resp.Header
+	if result.Request != "" {
+		result.Response = resp.Status
+	}
+	if !noTLSInfo {
+		result.TLS = summarizeTLS(resp.TLS)
+	}
 
-	for headerName := range recommendedSecurityHeaders {
-		if value := resp.Header.Get(headerName); value != "" {
-			result.Headers[headerName] = value
-		} else {
-			result.Missing = append(result.Missing, headerName)
+	if profile == "api" {
+		for _, headerName := range apiHeaders {
+			if value := resp.Header.Get(headerName); value != "" {
+				result.Headers[headerName] = value
+			}
+		}
+		result.Findings = apiFindings(resp.Header)
+	} else {
+		for headerName := range recommendedSecurityHeaders {
+			if value := resp.Header.Get(headerName); value != "" {
+				result.Headers[headerName] = value
+			} else {
+				result.Findings = append(result.Findings, missingFinding(headerName))
+			}
 		}
+		result.Clickjacking = resolveClickjacking(resp.Header)
+		if !result.Clickjacking.Protected {
+			result.Findings = append(result.Findings, Finding{
+				Header:      "X-Frame-Options / CSP frame-ancestors",
+				Severity:    SeverityMedium,
+				Description: "Page can be framed by other origins, enabling clickjacking attacks.",
+				Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy/frame-ancestors",
+			})
+		}
+		if sriAudit {
+			result.Findings = append(result.Findings, auditSubresources(targetURL, resp)...)
+		}
+	}
+	if f := checkRedirectPolicy(redirectRules, targetURL, resp); f != nil {
+		result.Findings = append(result.Findings, *f)
+	}
+	if cacheAudit {
+		result.Findings = append(result.Findings, cacheFindings(resp.Header, resp.Request.URL)...)
 	}
+	if legacyMode {
+		result.Findings = legacyFindings(resp.Header, result.Findings)
+	}
+	sortFindings(result.Findings)
 	return result
 }
 
-// loadURLsFromFile reads URLs from a specified file.
-func loadURLsFromFile(filePath string) ([]string, error) {
-	file, err := os.Open(filePath)
+// probeExposures runs --check-exposures once per origin, in report order,
+// attaching the findings to the first successfully scanned URL of each.
+func probeExposures(ctx context.Context, results []HeaderCheckResult, client *http.Client) {
+	probed := map[string]bool{}
+	for i := range results {
+		base := origin(results[i].URL)
+		if results[i].Errors != nil || base == "" || probed[base] || ctx.Err() != nil {
+			continue
+		}
+		probed[base] = true
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Checking %s for exposed files...\n", base)
+		}
+		results[i].Findings = append(results[i].Findings, checkExposures(ctx, base, client)...)
+		sortFindings(results[i].Findings)
+	}
+}
+
+// loadURLsFromFile reads URLs from a specified file (one per line, or a sitemap .xml),
+// or from stdin if the path is "-". Lines holding a JSON object are returned
+// separately as request targets, and host:port lines become URLs.
+func loadURLsFromFile(filePath string) ([]string, []scanTarget, error) {
+	if strings.HasSuffix(strings.ToLower(filePath), ".xml") {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read sitemap %s: %w", filePath, err)
+		}
+		urls, err := parseSitemap(data)
+		return urls, nil, err
+	}
+
+	file, err := openInput(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open input file %s: %w", filePath, err)
+		return nil, nil, fmt.Errorf("failed to open input file %s: %w", filePath, err)
 	}
 	defer file.Close()
+	name := inputName(filePath)
 
 	var urls []string
+	var targets []scanTarget
 	scanner := bufio.NewScanner(file)
+	lineNo := 0
 	for scanner.Scan() {
+		lineNo++
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
 			continue
 		}
+		if strings.HasPrefix(line, "{") {
+			t, err := parseTarget(line)
+			if err != nil {
+				return nil, nil, fmt.Errorf("input file %s line %d: %w", name, lineNo, err)
+			}
+			targets = append(targets, t)
+			continue
+		}
+		if u, ok := hostPortURL(line); ok {
+			line = u
+		}
 		// Basic validation: ensure it's a URL
 		if _, err := url.ParseRequestURI(line); err != nil {
 			if verboseMode {
@@ -143,13 +356,35 @@ func loadURLsFromFile(filePath string) ([]string, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading input file %s: %w", filePath, err)
+		return nil, nil, fmt.Errorf("error reading input file %s: %w", name, err)
 	}
-	return urls, nil
+	return urls, targets, nil
+}
+
+// hostPortURL turns a host:port line, as printed by the service monitor,
+// into the URL to scan: plain HTTP on port 80, HTTPS on any other.
+func hostPortURL(line string) (string, bool) {
+	if strings.Contains(line, "://") {
+		return "", false
+	}
+	host, port, err := net.SplitHostPort(line)
+	if err != nil || host == "" {
+		return "", false
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	switch port {
+	case "80":
+		return "http://" + host + "/", true
+	case "443":
+		return "https://" + host + "/", true
+	}
+	return "https://" + host + ":" + port + "/", true
 }
 
 // writeReport generates the security header scan report.
-func writeReport(results []HeaderCheckResult, output *os.File) {
+func writeReport(results []HeaderCheckResult, output io.Writer) {
 	fmt.Fprintf(output, "---\n")
 	fmt.Fprintf(output, "--- HTTP Security Header Scan Report ---\n")
 	fmt.Fprintf(output, "\n")
@@ -160,11 +395,25 @@ func writeReport(results []HeaderCheckResult, output *os.File) {
 
 	for _, result := range results {
 		fmt.Fprintf(output, "URL: %s\n", result.URL)
+		if result.Request != "" {
+			fmt.Fprintf(output, "Request: %s\n", result.Request)
+		}
+		if pins := resolveOverrides.pinned(result.URL); len(pins) > 0 {
+			fmt.Fprintf(output, "Resolved: %s (--resolve)\n", strings.Join(pins, ", "))
+		}
 		if result.Errors != nil {
-			fmt.Fprintf(output, "Status: ERROR\n")
+			fmt.Fprintf(output, "Status: %s\n", colorStatus("ERROR"))
 			fmt.Fprintf(output, "Error: %v\n", result.Errors)
 		} else {
-			fmt.Fprintf(output, "Status: OK\n")
+			fmt.Fprintf(output, "Status: %s\n", colorStatus("OK"))
+			if result.Response != "" {
+				fmt.Fprintf(output, "Response: %s\n", result.Response)
+			}
+			if t := result.TLS; t != nil {
+				fmt.Fprintf(output, "TLS: %s\n", t.Version)
+				fmt.Fprintf(output, "Certificate Issuer: %s\n", t.Issuer)
+				fmt.Fprintf(output, "Certificate Expiry: %s\n", t.Expiry())
+			}
 			fmt.Fprintln(output, "--- Found Security Headers ---")
 			if len(result.Headers) == 0 {
 				fmt.Fprintln(output, "  None found.")
@@ -172,38 +421,143 @@ func writeReport(results []HeaderCheckResult, output *os.File) {
 			for name, value := range result.Headers {
 				fmt.Fprintf(output, "  %s: %s\n", name, value)
 			}
-			fmt.Fprintln(output, "--- Missing Recommended Headers ---")
-			if len(result.Missing) == 0 {
-				fmt.Fprintln(output, "  None missing.")
+			fmt.Fprintf(output, "--- Findings (min severity: %s) ---\n", minSeverity)
+			findings := filterFindings(result.Findings, minSeverity)
+			if len(findings) == 0 {
+				fmt.Fprintln(output, "  None.")
+			}
+			for _, f := range findings {
+				fmt.Fprintf(output, "  [%s] %s: %s\n", colorStatus(strings.ToUpper(f.Severity.String())), f.Header, f.Description)
+				fmt.Fprintf(output, "    Remediation: %s\n", f.Remediation)
+				for _, note := range f.CompatNotes {
+					fmt.Fprintf(output, "    Compat: %s\n", note)
+				}
 			}
-			for _, name := range result.Missing {
-				fmt.Fprintf(output, "  %s: %s\n", name, recommendedSecurityHeaders[name])
+			if profile != "api" {
+				writeClickjacking(result.Clickjacking, output)
 			}
 		}
 		fmt.Fprintln(output, "------------------------------")
 	}
 }
 
+// writeClickjacking prints the effective clickjacking protection section.
+func writeClickjacking(p ClickjackingPolicy, output io.Writer) {
+	fmt.Fprintln(output, "--- Clickjacking Protection ---")
+	status := "NOT PROTECTED"
+	if p.Protected {
+		status = "PROTECTED"
+	}
+	fmt.Fprintf(output, "  Status: %s\n", colorStatus(status))
+	if p.Source != "" {
+		fmt.Fprintf(output, "  Effective Policy: %s (from %s)\n", p.Policy, p.Source)
+	}
+	for _, note := range p.Notes {
+		fmt.Fprintf(output, "  Note: %s\n", note)
+	}
+}
+
 // main is the entry point of the HTTP Security Header Scanner tool.
 func main() {
+	runSubcommand()
 	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+	if err := applyTZ(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
 
 	// Validate arguments
-	if inputFile == "" && targetURL == "" {
+	if compareMode {
+		if flag.NArg() != 2 || inputFile != "" || targetURL != "" {
+			fatalError("--compare takes exactly two URLs and no -u or -i: --compare urlA urlB", nil)
+		}
+		if format != "text" || crawlMode {
+			fatalError("--compare writes a text report and cannot be combined with --crawl.", nil)
+		}
+	} else if compareOrigin != "" {
+		if flag.NArg() != 0 || inputFile != "" || targetURL != "" {
+			fatalError("--compare-origin takes one URL and no -u or -i: --compare-origin URL --resolve host:port:addr", nil)
+		}
+		if format != "text" || crawlMode {
+			fatalError("--compare-origin writes a text report and cannot be combined with --crawl.", nil)
+		}
+	} else if inputFile == "" && targetURL == "" {
 		flag.Usage()
 		fatalError("Either an input file (-i) or a target URL (-u) must be provided.", nil)
 	}
+	sev, err := parseSeverity(minSevFlag)
+	if err != nil {
+		fatalError("Invalid -min-severity", err)
+	}
+	minSeverity = sev
+	if err := checkFindingsMin(); err != nil {
+		fatalError("Invalid --findings-min", err)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fatalError(err.Error(), nil)
+	}
+	startOtel()
+	if profile != "web" && profile != "api" {
+		fatalError(fmt.Sprintf("Unsupported profile: %s (expected web or api)", profile), nil)
+	}
+	if connectTimeoutSec < 0 || tlsTimeoutSec < 0 || headerTimeoutSec < 0 {
+		fatalError("Phase timeouts (--connect-timeout, --tls-timeout, --header-timeout) cannot be negative.", nil)
+	}
+	if maxConnsPerHost < 0 || dnsCacheSec < 0 {
+		fatalError("--max-conns-per-host and --dns-cache cannot be negative.", nil)
+	}
+	if format != "text" && format != "html" {
+		fatalError(fmt.Sprintf("Unsupported report format: %s (expected text or html)", format), nil)
+	}
+	if groupBy != "url" && groupBy != "header" {
+		fatalError(fmt.Sprintf("Unsupported --group-by: %s (expected url or header)", groupBy), nil)
+	}
+	if groupBy == "header" && format != "text" {
+		fatalError("--group-by header is only available for text reports.", nil)
+	}
+	if redirectPolicy != "" {
+		if redirectRules, err = loadRedirectPolicy(redirectPolicy); err != nil {
+			fatalError("Invalid --redirect-policy", err)
+		}
+	}
+	if maxPages < 1 || crawlDepth < 0 {
+		fatalError("--max-pages must be at least 1 and --depth cannot be negative.", nil)
+	}
 	if inputFile != "" && targetURL != "" {
-		fmt.Fprintln(os.Stderr, "[WARNING] Input file (-i) provided. -url flag will be ignored.")
+		warnf("Input file (-i) provided. -url flag will be ignored.")
 	}
 
 	var urlsToScan []string
-	if inputFile != "" {
-		loadedURLs, err := loadURLsFromFile(inputFile)
+	var requestTargets []scanTarget // JSON lines of the input file
+	if compareMode {
+		for _, raw := range flag.Args() {
+			normalized, err := normalizeURL(raw, stripQuery)
+			if err != nil {
+				fatalError(fmt.Sprintf("Invalid URL provided: %s", raw), err)
+			}
+			urlsToScan = append(urlsToScan, normalized)
+		}
+	} else if compareOrigin != "" {
+		normalized, err := normalizeURL(compareOrigin, stripQuery)
+		if err != nil {
+			fatalError(fmt.Sprintf("Invalid URL provided: %s", compareOrigin), err)
+		}
+		if len(resolveOverrides.pinned(normalized)) == 0 {
+			fatalError(fmt.Sprintf("--compare-origin needs the origin address of %s: add --resolve host:port:addr", normalized), nil)
+		}
+		urlsToScan = []string{normalized}
+	} else if inputFile != "" {
+		loadedURLs, loadedTargets, err := loadURLsFromFile(inputFile)
 		if err != nil {
 			fatalError("Failed to load URLs from file", err)
 		}
-		urlsToScan = loadedURLs
+		urlsToScan, requestTargets = loadedURLs, loadedTargets
 	} else {
 		// Basic validation for single URL
 		if _, err := url.ParseRequestURI(targetURL); err != nil {
@@ -212,42 +566,163 @@ func main() {
 		urlsToScan = []string{targetURL}
 	}
 
-	if verboseMode {
-		fmt.Fprintf(os.Stderr, "[INFO] Scanning %d URL(s)...\n", len(urlsToScan))
+	var scopes []string
+	if scopeList != "" {
+		scopes = strings.Split(scopeList, ",")
+	}
+	if !compareMode && compareOrigin == "" {
+		urlsToScan = prepareTargets(urlsToScan, scopes, stripQuery)
+		requestTargets = prepareRequestTargets(requestTargets, scopes, stripQuery)
 	}
 
+	transport := newTransport(resolveOverrides)
 	client := &http.Client{
-		Timeout: time.Duration(timeoutSec) * time.Second,
+		Timeout:   time.Duration(timeoutSec) * time.Second,
+		Transport: transport,
+	}
+	if compareOrigin != "" {
+		client.Transport = originRouter{origin: transport, public: newTransport(nil)}
+	}
+	var recorder *harRecorder
+	if harPath != "" {
+		recorder = newHARRecorder(client.Transport)
+		client.Transport = recorder
 	}
 
-	resultsChan := make(chan HeaderCheckResult, len(urlsToScan))
+	// SIGINT/SIGTERM cancel outstanding requests; finished scans (and the
+	// HAR entries recorded for them) are still written out.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
+	if compareMode {
+		os.Exit(runCompare(ctx, compareSide{URL: urlsToScan[0], Label: urlsToScan[0]}, compareSide{URL: urlsToScan[1], Label: urlsToScan[1]}, client, recorder))
+	}
+	if compareOrigin != "" {
+		u := urlsToScan[0]
+		cdn := compareSide{URL: u, Label: u + " via public DNS (CDN)", PublicDNS: true}
+		origin := compareSide{URL: u, Label: u + " via " + strings.Join(resolveOverrides.pinned(u), ", ") + " (origin, --resolve)"}
+		os.Exit(runCompare(ctx, cdn, origin, client, recorder))
+	}
+	if crawlMode {
+		urlsToScan = crawl(ctx, urlsToScan, client, maxPages, crawlDepth)
+	}
+	targets := make([]scanTarget, 0, len(urlsToScan)+len(requestTargets))
 	for _, u := range urlsToScan {
-		go func(scanURL string) {
-			resultsChan <- checkSecurityHeaders(scanURL, client)
-		}(u)
-		time.Sleep(100 * time.Millisecond) // Introduce a small delay to avoid overwhelming targets/network
+		targets = append(targets, scanTarget{URL: u})
+	}
+	targets = append(targets, requestTargets...)
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Scanning %d URL(s)...\n", len(targets))
+	}
+
+	resultsChan := make(chan HeaderCheckResult, len(targets))
+
+	started := 0
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		go func(t scanTarget) {
+			start := time.Now()
+			r := checkSecurityHeaders(ctx, t, client)
+			telemetry.span("scan", start, map[string]string{"target": r.target(), "findings": strconv.Itoa(len(r.Findings))}, r.Errors)
+			resultsChan <- r
+		}(t)
+		started++
+		select { // Introduce a small delay to avoid overwhelming targets/network
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+		}
 	}
 
 	var allResults []HeaderCheckResult
-	for i := 0; i < len(urlsToScan); i++ {
-		allResults = append(allResults, <-resultsChan)
+	for i := 0; i < started && ctx.Err() == nil; i++ {
+		select {
+		case r := <-resultsChan:
+			allResults = append(allResults, r)
+		case <-ctx.Done():
+		}
+	}
+	interrupted := ctx.Err() != nil
+	for drained := false; interrupted && !drained; { // Keep scans that finished before the signal
+		select {
+		case r := <-resultsChan:
+			if !errors.Is(r.Errors, context.Canceled) {
+				allResults = append(allResults, r)
+			}
+		default:
+			drained = true
+		}
+	}
+	if exposures && !interrupted {
+		probeExposures(ctx, allResults, client)
+		interrupted = ctx.Err() != nil
+	}
+	if interrupted {
+		stop() // A second signal terminates immediately
+		warnf("Interrupted by signal; reporting %d of %d URL(s).", len(allResults), len(targets))
 	}
 
-	output := os.Stdout
-	if outputFile != "" {
-		var err error
-		output, err = os.Create(outputFile)
-		if err != nil {
-			fatalError(fmt.Sprintf("Failed to create output file %s", outputFile), err)
+	output, err := openSink(outputFile)
+	if err != nil {
+		fatalError(fmt.Sprintf("Failed to open output %s", outputFile), err)
+	}
+	enableColor(sinkFile(output))
+
+	var stats *selfStats
+	if selfStatsOn {
+		s := collectSelfStats(int(transport.requests.Load()), "requests")
+		stats = &s
+	}
+	if format == "html" {
+		if err := writeHTMLReport(allResults, stats, output); err != nil {
+			fatalError("Failed to write HTML report", err)
+		}
+	} else {
+		if groupBy == "header" {
+			writeGroupedReport(allResults, output)
+		} else {
+			writeReport(allResults, output)
+		}
+		if interrupted {
+			fmt.Fprintf(output, "Partial report: interrupted after %d of %d URLs.\n", len(allResults), len(targets))
+		}
+		if stats != nil {
+			writeSelfStats(output, *stats)
 		}
-		defer output.Close()
 	}
 
-	writeReport(allResults, output)
+	if recorder != nil {
+		if err := recorder.writeFile(harPath); err != nil {
+			fatalError(fmt.Sprintf("Failed to write HAR file %s", harPath), err)
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] HAR file written to %s\n", harPath)
+		}
+	}
 
+	findings := normalizedFindings(allResults)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fatalError("Failed to export findings", err)
+	}
+
+	if !closeSink(output) && !interrupted {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if interrupted {
+		telemetry.finish(130)
+		writeManifest(130, []string{inputFile, redirectPolicy}, []string{outputFile, harPath, findingsPath})
+		os.Exit(130)
+	}
 	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] %d request(s) sent over %d new connection(s).\n", transport.requests.Load(), transport.newConns.Load())
 		fmt.Fprintln(os.Stderr, "[INFO] HTTP Security Header scan complete.")
 	}
+	telemetry.finish(0)
+	writeManifest(0, []string{inputFile, redirectPolicy}, []string{outputFile, harPath, findingsPath})
 	os.Exit(0)
 }
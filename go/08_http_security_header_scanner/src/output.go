@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Output control: verbosity levels (quiet, normal, verbose, debug) and ANSI
+// colors for report statuses. Colors are used automatically only when the
+// report goes to a terminal and NO_COLOR is not set.
+var (
+	quietMode  bool
+	debugMode  bool
+	forceColor bool
+	noColor    bool
+	useColor   bool
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+func registerOutputFlags() {
+	flag.BoolVar(&quietMode, "quiet", false, "Only print errors to stderr (suppresses warnings and verbose output).")
+	flag.BoolVar(&quietMode, "q", false, "Only print errors to stderr (shorthand).")
+	flag.BoolVar(&debugMode, "debug", false, "Enable debug output (implies --verbose).")
+	flag.BoolVar(&forceColor, "color", false, "Always color statuses in the report, even when not writing to a terminal.")
+	flag.BoolVar(&noColor, "no-color", false, "Never color statuses in the report.")
+}
+
+// applyVerbosity resolves the verbosity flags; --quiet wins over -v/--debug.
+func applyVerbosity() {
+	if debugMode {
+		verboseMode = true
+	}
+	if quietMode {
+		verboseMode, debugMode = false, false
+	}
+}
+
+// enableColor decides whether statuses written to report are colored.
+func enableColor(report *os.File) {
+	switch {
+	case noColor:
+		useColor = false
+	case forceColor:
+		useColor = true
+	default:
+		info, err := report.Stat()
+		useColor = err == nil && info.Mode()&os.ModeCharDevice != 0 &&
+			os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	}
+}
+
+// colorStatus wraps a report status or severity label in the color matching its meaning.
+func colorStatus(status string) string {
+	if !useColor {
+		return status
+	}
+	switch status {
+	case "OK", "PROTECTED":
+		return ansiGreen + status + ansiReset
+	case "MEDIUM":
+		return ansiYellow + status + ansiReset
+	case "ERROR", "NOT PROTECTED", "HIGH", "CRITICAL":
+		return ansiRed + status + ansiReset
+	}
+	return status
+}
+
+func warnf(format string, args ...interface{}) {
+	if !quietMode {
+		fmt.Fprintf(os.Stderr, "[WARNING] "+format+"\n", args...)
+	}
+}
+
+func debugf(format string, args ...interface{}) {
+	if debugMode {
+		fmt.Fprintf(os.Stderr, "[DEBUG] "+format+"\n", args...)
+	}
+}
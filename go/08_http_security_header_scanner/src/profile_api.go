@@ -0,0 +1,123 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// corsProbeOrigin is sent as the Origin header in API mode to detect servers
+// that reflect arbitrary origins into Access-Control-Allow-Origin.
+const corsProbeOrigin = "https://cors-probe.invalid"
+
+// apiHeaders are the headers recorded as "found" for the API profile.
+var apiHeaders = []string{
+	"Strict-Transport-Security",
+	"X-Content-Type-Options",
+	"Cache-Control",
+	"Content-Type",
+	"Access-Control-Allow-Origin",
+	"Access-Control-Allow-Credentials",
+}
+
+// isJSONMediaType reports whether a media type is JSON (application/json or
+// a +json structured syntax suffix such as application/problem+json).
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// apiFindings applies the API policy: JSON responses must not be cached, must
+// declare a JSON Content-Type with charset, must disable MIME sniffing, and
+// must not combine permissive CORS with credentials.
+func apiFindings(h http.Header) []Finding {
+	var findings []Finding
+
+	if h.Get("Strict-Transport-Security") == "" {
+		findings = append(findings, missingFinding("Strict-Transport-Security"))
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(h.Get("X-Content-Type-Options")), "nosniff") {
+		findings = append(findings, Finding{
+			Header:      "X-Content-Type-Options",
+			Severity:    SeverityMedium,
+			Description: "API responses should set X-Content-Type-Options: nosniff so JSON is never sniffed as HTML or script.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/X-Content-Type-Options",
+		})
+	}
+
+	contentType := h.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	switch {
+	case contentType == "" || err != nil:
+		findings = append(findings, Finding{
+			Header:      "Content-Type",
+			Severity:    SeverityMedium,
+			Description: "Content-Type is missing or malformed; clients and browsers must guess how to interpret the response.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Type",
+		})
+	case !isJSONMediaType(mediaType):
+		findings = append(findings, Finding{
+			Header:      "Content-Type",
+			Severity:    SeverityMedium,
+			Description: "API endpoint returned " + mediaType + " instead of a JSON media type.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Type",
+		})
+	case params["charset"] == "":
+		findings = append(findings, Finding{
+			Header:      "Content-Type",
+			Severity:    SeverityLow,
+			Description: "JSON Content-Type does not declare a charset (expected application/json; charset=utf-8).",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Type",
+		})
+	}
+
+	if err == nil && isJSONMediaType(mediaType) && !hasDirective(h.Values("Cache-Control"), "no-store") {
+		findings = append(findings, Finding{
+			Header:      "Cache-Control",
+			Severity:    SeverityMedium,
+			Description: "JSON response is cacheable; set Cache-Control: no-store so API data is not kept by browsers or proxies.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Cache-Control",
+		})
+	}
+
+	allowOrigin := strings.TrimSpace(h.Get("Access-Control-Allow-Origin"))
+	credentials := strings.EqualFold(strings.TrimSpace(h.Get("Access-Control-Allow-Credentials")), "true")
+	switch {
+	case allowOrigin == corsProbeOrigin && credentials:
+		findings = append(findings, Finding{
+			Header:      "Access-Control-Allow-Origin",
+			Severity:    SeverityCritical,
+			Description: "Arbitrary Origin is reflected with Access-Control-Allow-Credentials: true; any site can read authenticated responses.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS",
+		})
+	case allowOrigin == "*" && credentials:
+		findings = append(findings, Finding{
+			Header:      "Access-Control-Allow-Origin",
+			Severity:    SeverityHigh,
+			Description: "Wildcard Access-Control-Allow-Origin is combined with Access-Control-Allow-Credentials: true.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS",
+		})
+	case allowOrigin == corsProbeOrigin:
+		findings = append(findings, Finding{
+			Header:      "Access-Control-Allow-Origin",
+			Severity:    SeverityLow,
+			Description: "Arbitrary Origin is reflected into Access-Control-Allow-Origin; restrict it to an allowlist.",
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS",
+		})
+	}
+	return findings
+}
+
+// hasDirective reports whether any of the comma-separated header values
+// contains the given directive (case-insensitive, ignoring arguments).
+func hasDirective(values []string, directive string) bool {
+	for _, v := range values {
+		for _, d := range strings.Split(v, ",") {
+			name := strings.TrimSpace(strings.SplitN(d, "=", 2)[0])
+			if strings.EqualFold(name, directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// redirectRule states how requests for matching URLs must be redirected.
+type redirectRule struct {
+	Pattern string         // As written in the policy file
+	match   *regexp.Regexp // Pattern compiled; * matches any characters
+	Status  int            // Status of the first response; 0 means any
+	To      string         // Prefix of the final URL; "" means any
+	MaxHops int            // Redirects allowed; -1 means any
+}
+
+// redirectGuide is the remediation reference for redirect violations.
+const redirectGuide = "https://developer.mozilla.org/en-US/docs/Web/HTTP/Redirections"
+
+// loadRedirectPolicy reads a redirect policy file. Each non-comment line is
+// "<URL pattern> [status=<code>] [to=<prefix>] [max-hops=<n>]", e.g.
+//
+//	http://example.com/*   status=301 to=https://example.com/ max-hops=1
+//	https://example.com/   status=200
+func loadRedirectPolicy(filePath string) ([]redirectRule, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open redirect policy %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var rules []redirectRule
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("redirect policy %s line %d: expected '<URL pattern> status=<code> to=<prefix> max-hops=<n>'", filePath, lineNo)
+		}
+		pattern := regexp.QuoteMeta(fields[0])
+		rule := redirectRule{Pattern: fields[0], MaxHops: -1,
+			match: regexp.MustCompile("^" + strings.ReplaceAll(pattern, `\*`, ".*") + "$")}
+		for _, f := range fields[1:] {
+			key, value, _ := strings.Cut(f, "=")
+			var err error
+			switch key {
+			case "status":
+				rule.Status, err = strconv.Atoi(value)
+			case "to":
+				rule.To = value
+			case "max-hops":
+				rule.MaxHops, err = strconv.Atoi(value)
+			default:
+				err = fmt.Errorf("unknown key")
+			}
+			if err != nil || value == "" {
+				return nil, fmt.Errorf("redirect policy %s line %d: invalid %q (use status=, to= or max-hops=)", filePath, lineNo, f)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading redirect policy %s: %w", filePath, err)
+	}
+	return rules, nil
+}
+
+// checkRedirectPolicy compares the redirect chain that ended in resp with
+// the first rule matching targetURL, and returns a finding listing every
+// expectation that was not met, or nil.
+func checkRedirectPolicy(rules []redirectRule, targetURL string, resp *http.Response) *Finding {
+	for _, rule := range rules {
+		if !rule.match.MatchString(targetURL) {
+			continue
+		}
+		// Each request made for a redirect links to the response that caused it.
+		hops, first := 0, resp
+		for r := resp; r.Request != nil && r.Request.Response != nil; r = r.Request.Response {
+			hops++
+			first = r.Request.Response
+		}
+		final := resp.Request.URL.String()
+		var problems []string
+		if rule.Status != 0 && first.StatusCode != rule.Status {
+			problems = append(problems, fmt.Sprintf("expected status %d, got %d", rule.Status, first.StatusCode))
+		}
+		if rule.To != "" && !strings.HasPrefix(final, rule.To) {
+			problems = append(problems, fmt.Sprintf("expected to end at %s..., ended at %s", rule.To, final))
+		}
+		if rule.MaxHops >= 0 && hops > rule.MaxHops {
+			problems = append(problems, fmt.Sprintf("expected at most %d redirect(s), followed %d", rule.MaxHops, hops))
+		}
+		if len(problems) == 0 {
+			return nil
+		}
+		return &Finding{
+			Header:      "Redirect Policy",
+			Severity:    SeverityMedium,
+			Description: fmt.Sprintf("Redirects violate the rule for %s: %s.", rule.Pattern, strings.Join(problems, "; ")),
+			Remediation: redirectGuide,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// htmlRow is the per-URL view model for the HTML report.
+type htmlRow struct {
+	URL          string
+	Status       string
+	Error        string
+	Grade        string
+	Score        int
+	Findings     []Finding
+	Headers      map[string]string
+	Clickjacking ClickjackingPolicy
+	TLS          *TLSSummary
+	// ShowClickjacking is false for the api profile, where framing does not apply
+	ShowClickjacking bool
+}
+
+// gradeWeights is the score deducted per finding of each severity.
+var gradeWeights = map[Severity]int{
+	SeverityCritical: 40,
+	SeverityHigh:     20,
+	SeverityMedium:   10,
+	SeverityLow:      5,
+	SeverityInfo:     0,
+}
+
+// grade scores a set of findings out of 100 and maps it to a letter grade.
+func grade(findings []Finding) (string, int) {
+	score := 100
+	for _, f := range findings {
+		score -= gradeWeights[f.Severity]
+	}
+	if score < 0 {
+		score = 0
+	}
+	switch {
+	case score >= 90:
+		return "A", score
+	case score >= 80:
+		return "B", score
+	case score >= 70:
+		return "C", score
+	case score >= 60:
+		return "D", score
+	default:
+		return "F", score
+	}
+}
+
+// writeHTMLReport renders a standalone HTML report (no external assets).
+// stats, if not nil, is shown below the results table.
+func writeHTMLReport(results []HeaderCheckResult, stats *selfStats, w io.Writer) error {
+	rows := make([]htmlRow, 0, len(results))
+	for _, r := range results {
+		row := htmlRow{URL: r.target(), Status: "OK", Headers: r.Headers, Clickjacking: r.Clickjacking, TLS: r.TLS, ShowClickjacking: profile != "api"}
+		if r.Errors != nil {
+			row.Status, row.Error, row.Grade = "ERROR", r.Errors.Error(), "-"
+		} else {
+			row.Grade, row.Score = grade(r.Findings)
+			row.Findings = filterFindings(r.Findings, minSeverity)
+		}
+		rows = append(rows, row)
+	}
+	return htmlReportTemplate.Execute(w, map[string]interface{}{
+		"Generated":   stamp(time.Now()),
+		"MinSeverity": minSeverity.String(),
+		"Rows":        rows,
+		"SelfStats":   stats,
+	})
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"upper": strings.ToUpper,
+	"mib":   func(b int64) string { return fmt.Sprintf("%.1f MiB", float64(b)/(1<<20)) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>HTTP Security Header Scan Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; }
+th.sorted-asc::after { content: " \25B2"; }
+th.sorted-desc::after { content: " \25BC"; }
+.grade { font-weight: bold; text-align: center; }
+.grade-A { color: #1a7f37; } .grade-B { color: #4d8a1a; } .grade-C { color: #9a6700; }
+.grade-D { color: #bc4c00; } .grade-F, .status-ERROR { color: #cf222e; }
+.sev-critical, .sev-high { color: #cf222e; } .sev-medium { color: #bc4c00; } .sev-low { color: #9a6700; } .sev-info { color: #57606a; }
+.filters { margin-bottom: 1em; }
+.self-stats { color: #57606a; font-size: 0.9em; }
+.filters input, .filters select { margin-right: 1em; padding: 4px; }
+</style>
+</head>
+<body>
+<h1>HTTP Security Header Scan Report</h1>
+<p>Generated {{.Generated}} &middot; minimum severity: {{.MinSeverity}}</p>
+<div class="filters">
+  <input id="filter-url" type="search" placeholder="Filter URL...">
+  <select id="filter-status"><option value="">All statuses</option><option>OK</option><option>ERROR</option></select>
+  <select id="filter-grade"><option value="">All grades</option><option>A</option><option>B</option><option>C</option><option>D</option><option>F</option></select>
+</div>
+<table id="results">
+<thead>
+<tr><th data-type="text">URL</th><th data-type="text">Status</th><th data-type="text">Grade</th><th data-type="num">Score</th><th data-type="num">Findings</th><th>Details</th></tr>
+</thead>
+<tbody>
+{{- range .Rows}}
+<tr data-status="{{.Status}}" data-grade="{{.Grade}}">
+  <td>{{.URL}}</td>
+  <td class="status-{{.Status}}">{{.Status}}</td>
+  <td class="grade grade-{{.Grade}}">{{.Grade}}</td>
+  <td>{{.Score}}</td>
+  <td>{{len .Findings}}</td>
+  <td>
+  {{- if .Error}}{{.Error}}{{else}}
+  <details>
+    <summary>Show details</summary>
+    <h4>Findings</h4>
+    {{- if .Findings}}
+    <ul>
+    {{- range .Findings}}
+      <li><span class="sev-{{.Severity}}">[{{upper .Severity.String}}]</span> <strong>{{.Header}}</strong>: {{.Description}} (<a href="{{.Remediation}}">remediation</a>)
+      {{- if .CompatNotes}}<ul>{{range .CompatNotes}}<li><em>Compat:</em> {{.}}</li>{{end}}</ul>{{end}}</li>
+    {{- end}}
+    </ul>
+    {{- else}}<p>None.</p>{{end}}
+    <h4>Found Security Headers</h4>
+    {{- if .Headers}}
+    <ul>{{range $name, $value := .Headers}}<li><code>{{$name}}: {{$value}}</code></li>{{end}}</ul>
+    {{- else}}<p>None found.</p>{{end}}
+    {{- with .TLS}}
+    <h4>TLS Certificate</h4>
+    <p>{{.Version}} &middot; issued by {{.Issuer}} &middot; expires {{.Expiry}}</p>
+    {{- end}}
+    {{- if .ShowClickjacking}}
+    <h4>Clickjacking Protection</h4>
+    <p>{{if .Clickjacking.Protected}}PROTECTED{{else}}NOT PROTECTED{{end}}{{if .Clickjacking.Source}} &middot; {{.Clickjacking.Policy}} (from {{.Clickjacking.Source}}){{end}}</p>
+    {{- range .Clickjacking.Notes}}<p>Note: {{.}}</p>{{end}}
+    {{- end}}
+  </details>
+  {{- end}}
+  </td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+{{- with .SelfStats}}
+<p class="self-stats">Scanner: {{printf "%.1f" .RuntimeSeconds}}s runtime &middot; peak RSS {{if .PeakRSSBytes}}{{mib .PeakRSSBytes}}{{else}}n/a{{end}} &middot; {{.Goroutines}} goroutine(s) (peak {{.PeakGoroutines}}) &middot; {{.Items}} {{.Unit}} ({{printf "%.1f" .PerSecond}}/s)</p>
+{{- end}}
+<script>
+(function () {
+  var table = document.getElementById("results");
+  var rows = Array.prototype.slice.call(table.tBodies[0].rows);
+  function applyFilters() {
+    var text = document.getElementById("filter-url").value.toLowerCase();
+    var status = document.getElementById("filter-status").value;
+    var grade = document.getElementById("filter-grade").value;
+    rows.forEach(function (row) {
+      var show = row.cells[0].textContent.toLowerCase().indexOf(text) !== -1 &&
+        (!status || row.dataset.status === status) &&
+        (!grade || row.dataset.grade === grade);
+      row.style.display = show ? "" : "none";
+    });
+  }
+  ["filter-url", "filter-status", "filter-grade"].forEach(function (id) {
+    document.getElementById(id).addEventListener("input", applyFilters);
+  });
+  Array.prototype.forEach.call(table.tHead.rows[0].cells, function (th, col) {
+    if (!th.dataset.type) { return; }
+    th.addEventListener("click", function () {
+      var asc = !th.classList.contains("sorted-asc");
+      Array.prototype.forEach.call(table.tHead.rows[0].cells, function (c) { c.classList.remove("sorted-asc", "sorted-desc"); });
+      th.classList.add(asc ? "sorted-asc" : "sorted-desc");
+      rows.sort(function (a, b) {
+        var x = a.cells[col].textContent.trim(), y = b.cells[col].textContent.trim();
+        var cmp = th.dataset.type === "num" ? (parseFloat(x) || 0) - (parseFloat(y) || 0) : x.localeCompare(y);
+        return asc ? cmp : -cmp;
+      });
+      rows.forEach(function (row) { table.tBodies[0].appendChild(row); });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// resolveList collects repeated --resolve flags (curl syntax host:port:addr),
+// each pinning connections for host:port to one or more addresses. The URL's
+// host name is still used for the Host header, SNI and certificate checks.
+type resolveList map[string][]string
+
+func (r resolveList) String() string {
+	var parts []string
+	for k, v := range r {
+		parts = append(parts, k+":"+strings.Join(v, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (r resolveList) Set(value string) error {
+	host, rest, ok := strings.Cut(value, ":")
+	port, addrs, ok2 := strings.Cut(rest, ":")
+	if !ok || !ok2 || host == "" {
+		return fmt.Errorf("expected host:port:addr[,addr...], got %q", value)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q in %q", port, value)
+	}
+	key := net.JoinHostPort(strings.ToLower(host), port)
+	for _, a := range strings.Split(addrs, ",") {
+		a = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(a), "["), "]")
+		if net.ParseIP(a) == nil {
+			return fmt.Errorf("invalid address %q in %q (an IP address is required)", a, value)
+		}
+		r[key] = append(r[key], a)
+	}
+	return nil
+}
+
+// --compare-origin URL scans the URL twice in one run, through public DNS
+// (normally the CDN) and through its --resolve pin (the origin), and diffs the
+// two like --compare. Each side has its own transport, so a pooled connection
+// to one is never reused for the other.
+
+// publicDNSKey marks a request context whose connections ignore --resolve.
+type publicDNSKey struct{}
+
+// originRouter sends each request through the pinned or the unpinned
+// transport, as its context asks.
+type originRouter struct {
+	origin, public http.RoundTripper
+}
+
+func (r originRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(publicDNSKey{}) != nil {
+		return r.public.RoundTrip(req)
+	}
+	return r.origin.RoundTrip(req)
+}
+
+// viaPublicDNS returns a context whose requests bypass --resolve.
+func viaPublicDNS(ctx context.Context) context.Context {
+	return context.WithValue(ctx, publicDNSKey{}, true)
+}
+
+// pinned returns the --resolve addresses used for rawURL, if any.
+func (r resolveList) pinned(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return r[net.JoinHostPort(strings.ToLower(u.Hostname()), port)]
+}
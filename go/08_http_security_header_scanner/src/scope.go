@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// normalizeURL canonicalizes a URL so trivially different spellings of the
+// same resource are scanned once: scheme and host are lower-cased, default
+// ports and fragments are removed, an empty path becomes "/", a trailing
+// slash on a non-root path is dropped, and the query is optionally stripped.
+func normalizeURL(raw string, stripQuery bool) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("URL must be absolute: %s", raw)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := u.Hostname(), u.Port()
+	host = strings.ToLower(host)
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	} else if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+	u.RawPath = ""
+	u.Fragment = ""
+	if stripQuery {
+		u.RawQuery = ""
+		u.ForceQuery = false
+	}
+	return u.String(), nil
+}
+
+// inScope reports whether host equals, or is a subdomain of, one of the
+// scope domains. An empty scope list allows every host.
+func inScope(host string, scopes []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, s := range scopes {
+		s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+		if s == "" {
+			continue
+		}
+		if host == s || strings.HasSuffix(host, "."+s) {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareTargets applies scope restriction and normalization to the loaded
+// URL list, dropping out-of-scope entries and duplicates while preserving
+// the original order.
+func prepareTargets(urls []string, scopes []string, stripQuery bool) []string {
+	seen := make(map[string]bool, len(urls))
+	var targets []string
+	for _, raw := range urls {
+		normalized, err := normalizeURL(raw, stripQuery)
+		if err != nil {
+			if verboseMode {
+				fmt.Fprintf(os.Stderr, "[WARNING] Skipping invalid URL: %s (%v)\n", raw, err)
+			}
+			continue
+		}
+		u, _ := url.Parse(normalized)
+		if !inScope(u.Hostname(), scopes) {
+			if verboseMode {
+				fmt.Fprintf(os.Stderr, "[INFO] Out of scope, skipping: %s\n", raw)
+			}
+			continue
+		}
+		if seen[normalized] {
+			if verboseMode {
+				fmt.Fprintf(os.Stderr, "[INFO] Duplicate after normalization, skipping: %s\n", raw)
+			}
+			continue
+		}
+		seen[normalized] = true
+		targets = append(targets, normalized)
+	}
+	return targets
+}
+
+// sitemapURLSet and sitemapIndex cover the two top-level sitemap forms.
+type sitemapURLSet struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+}
+
+type sitemapIndex struct {
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// parseSitemap extracts <loc> entries from a sitemap.xml (urlset) document.
+// Sitemap index files are reported as an error because nested sitemaps are
+// not fetched.
+func parseSitemap(data []byte) ([]string, error) {
+	var set sitemapURLSet
+	if err := xml.Unmarshal(data, &set); err == nil && len(set.URLs) > 0 {
+		var urls []string
+		for _, u := range set.URLs {
+			if loc := strings.TrimSpace(u.Loc); loc != "" {
+				urls = append(urls, loc)
+			}
+		}
+		return urls, nil
+	}
+	var index sitemapIndex
+	if err := xml.Unmarshal(data, &index); err == nil && len(index.Sitemaps) > 0 {
+		return nil, fmt.Errorf("sitemap index files are not supported; pass the individual sitemaps instead")
+	}
+	return nil, fmt.Errorf("no <url><loc> entries found in sitemap")
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// --sri-audit: external scripts and stylesheets referenced by an HTML page
+// are checked for a Subresource Integrity attribute when they come from
+// another origin, and against the page's CSP, which must allow them.
+
+// sriGuide is the remediation reference for SRI findings.
+const sriGuide = "https://developer.mozilla.org/en-US/docs/Web/Security/Subresource_Integrity"
+
+// maxNamed bounds the resources named in one finding's description.
+const maxNamed = 5
+
+var (
+	// subresourceTag matches <script ...> and <link ...> start tags.
+	subresourceTag = regexp.MustCompile(`(?is)<(script|link)\b([^>]*)>`)
+	// tagAttr matches one attribute, quoted, unquoted or bare.
+	tagAttr = regexp.MustCompile(`(?s)([^\s"'=<>/]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+)
+
+// subresource is one external script or stylesheet.
+type subresource struct {
+	Kind      string // script or style
+	URL       *url.URL
+	Integrity string
+	Nonce     string
+}
+
+// findSubresources extracts <script src> and <link rel=stylesheet href>
+// references from page, resolved against base.
+func findSubresources(page string, base *url.URL) []subresource {
+	var found []subresource
+	for _, tag := range subresourceTag.FindAllStringSubmatch(page, -1) {
+		attrs := map[string]string{}
+		for _, a := range tagAttr.FindAllStringSubmatch(tag[2], -1) {
+			attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2] + a[3] + a[4])
+		}
+		r := subresource{Kind: "script", Integrity: attrs["integrity"], Nonce: attrs["nonce"]}
+		ref := attrs["src"]
+		if strings.EqualFold(tag[1], "link") {
+			rel := " " + strings.ToLower(attrs["rel"]) + " "
+			if !strings.Contains(rel, " stylesheet ") && !strings.Contains(rel, " modulepreload ") {
+				continue
+			}
+			r.Kind, ref = "style", attrs["href"]
+			if strings.Contains(rel, " modulepreload ") {
+				r.Kind = "script"
+			}
+		}
+		if strings.TrimSpace(ref) == "" {
+			continue // Inline script
+		}
+		u, err := base.Parse(strings.TrimSpace(ref))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		r.URL = u
+		found = append(found, r)
+	}
+	return found
+}
+
+// auditSubresources reads an HTML response body, up to crawlBodyLimit, and
+// audits the scripts and stylesheets it references. Other responses, and
+// bodies left compressed by --cache-audit, are skipped.
+func auditSubresources(targetURL string, resp *http.Response) []Finding {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		debugf("%s: %s is not HTML, no SRI audit", targetURL, mediaType)
+		return nil
+	}
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" && !strings.EqualFold(enc, "identity") {
+		debugf("%s: body is %s-encoded, no SRI audit", targetURL, enc)
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, crawlBodyLimit))
+	if err != nil {
+		warnf("%s: reading the page for the SRI audit: %v", targetURL, err)
+		return nil
+	}
+	return sriFindings(string(body), resp.Request.URL, resp.Header)
+}
+
+// sriFindings audits the subresources of one page.
+func sriFindings(page string, base *url.URL, h http.Header) []Finding {
+	var missing, blocked []string
+	for _, r := range findSubresources(page, base) {
+		thirdParty := r.URL.Scheme != base.Scheme || r.URL.Host != base.Host
+		if !thirdParty {
+			continue
+		}
+		if r.Integrity == "" {
+			missing = append(missing, r.URL.String())
+		}
+		for _, csp := range h.Values("Content-Security-Policy") {
+			if !cspAllows(csp, r, base) {
+				blocked = append(blocked, r.Kind+" "+r.URL.String())
+				break
+			}
+		}
+	}
+	var findings []Finding
+	if len(missing) > 0 {
+		findings = append(findings, Finding{
+			Header:   "Subresource Integrity",
+			Severity: SeverityMedium,
+			Description: fmt.Sprintf("%d third-party script(s)/stylesheet(s) load without an integrity attribute, so a compromised CDN can change them: %s.",
+				len(missing), nameSome(missing)),
+			Remediation: sriGuide,
+		})
+	}
+	if len(blocked) > 0 {
+		findings = append(findings, Finding{
+			Header:   "CSP Subresource Coverage",
+			Severity: SeverityLow,
+			Description: fmt.Sprintf("%d third-party script(s)/stylesheet(s) referenced by the page are not allowed by its Content-Security-Policy and will be blocked, or the policy is out of date: %s.",
+				len(blocked), nameSome(blocked)),
+			Remediation: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy/script-src",
+		})
+	}
+	return findings
+}
+
+// nameSome joins up to maxNamed items.
+func nameSome(items []string) string {
+	if len(items) > maxNamed {
+		return strings.Join(items[:maxNamed], ", ") + fmt.Sprintf(" and %d more", len(items)-maxNamed)
+	}
+	return strings.Join(items, ", ")
+}
+
+// cspDirective returns the source list that governs kind (script or style)
+// in one policy, falling back as browsers do: script-src-elem, script-src,
+// default-src. ok is false when no directive restricts the kind.
+func cspDirective(policy, kind string) (sources []string, ok bool) {
+	directives := map[string][]string{}
+	for _, d := range strings.Split(policy, ";") {
+		fields := strings.Fields(strings.ToLower(d))
+		if len(fields) > 0 {
+			if _, dup := directives[fields[0]]; !dup { // The first occurrence wins
+				directives[fields[0]] = fields[1:]
+			}
+		}
+	}
+	for _, name := range []string{kind + "-src-elem", kind + "-src", "default-src"} {
+		if sources, ok := directives[name]; ok {
+			return sources, true
+		}
+	}
+	return nil, false
+}
+
+// cspAllows reports whether one policy allows loading r on the page at base.
+func cspAllows(policy string, r subresource, base *url.URL) bool {
+	sources, ok := cspDirective(policy, r.Kind)
+	if !ok {
+		return true
+	}
+	strictDynamic := false
+	for _, src := range sources {
+		switch {
+		case src == "'strict-dynamic'" && r.Kind == "script":
+			strictDynamic = true
+		case strings.HasPrefix(src, "'nonce-"):
+			if r.Nonce != "" && src == "'nonce-"+strings.ToLower(r.Nonce)+"'" {
+				return true
+			}
+		case strings.HasPrefix(src, "'sha"):
+			// CSP3: a hash source allows an external script with the same integrity hash.
+			for _, h := range strings.Fields(strings.ToLower(r.Integrity)) {
+				if src == "'"+h+"'" {
+					return true
+				}
+			}
+		}
+	}
+	if strictDynamic {
+		return false // Host sources are ignored; only a nonce or hash allows it
+	}
+	for _, src := range sources {
+		if cspSourceMatches(src, r.URL, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// cspSourceMatches matches a URL against one scheme, host or keyword source.
+func cspSourceMatches(src string, u, base *url.URL) bool {
+	switch src {
+	case "'self'":
+		return u.Host == base.Host && (u.Scheme == base.Scheme || (base.Scheme == "http" && u.Scheme == "https"))
+	case "*":
+		return true
+	case "http:":
+		return true // Also allows https
+	case "https:":
+		return u.Scheme == "https"
+	}
+	if strings.HasPrefix(src, "'") || strings.HasSuffix(src, ":") {
+		return false // Other keywords and schemes (data:, blob:)
+	}
+	scheme, rest, hasScheme := strings.Cut(src, "://")
+	if !hasScheme {
+		scheme, rest = base.Scheme, src
+	}
+	if u.Scheme != scheme && !(scheme == "http" && u.Scheme == "https") {
+		return false
+	}
+	hostPort, path, _ := strings.Cut(rest, "/")
+	host, port, hasPort := strings.Cut(hostPort, ":")
+	name := strings.ToLower(u.Hostname())
+	if suffix, ok := strings.CutPrefix(host, "*."); ok {
+		if !strings.HasSuffix(name, "."+suffix) {
+			return false
+		}
+	} else if host != name {
+		return false
+	}
+	if hasPort && port != "*" && port != u.Port() {
+		return false
+	} else if !hasPort && u.Port() != "" {
+		return false
+	}
+	if path == "" {
+		return true
+	}
+	if strings.HasSuffix(path, "/") {
+		return strings.HasPrefix(u.Path, "/"+path)
+	}
+	return u.Path == "/"+path
+}
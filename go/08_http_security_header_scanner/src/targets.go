@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// scanTarget is one request to scan. Plain URLs are fetched with GET; lines
+// of an input file that hold a JSON object instead describe a request with
+// its own method and body, e.g.
+//
+//	{"method": "POST", "url": "https://api.example.com/orders", "body": {"item": 1}}
+//
+// so the headers of API responses, which often differ between success and
+// error responses, are checked as well. A JSON body is sent as written; a
+// string body is sent as its contents, for form data or deliberately
+// malformed input.
+type scanTarget struct {
+	URL         string          `json:"url"`
+	Method      string          `json:"method"`
+	Body        json.RawMessage `json:"body"`
+	ContentType string          `json:"content_type"`
+	payload     []byte          // Body as sent
+}
+
+// probeContentType is sent with a body when the target does not set one.
+const probeContentType = "application/json"
+
+// parseTarget parses the JSON form of an input file line.
+func parseTarget(line string) (scanTarget, error) {
+	var t scanTarget
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return t, fmt.Errorf("invalid JSON target: %w", err)
+	}
+	if u, err := url.ParseRequestURI(t.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return t, fmt.Errorf("\"url\" must be an absolute http(s) URL, got %q", t.URL)
+	}
+	t.Method = strings.ToUpper(t.Method)
+	if t.Method == "" {
+		t.Method = http.MethodGet
+	}
+	if strings.ContainsAny(t.Method, " \t/:") {
+		return t, fmt.Errorf("invalid method %q", t.Method)
+	}
+	t.payload = t.Body
+	var text string
+	if json.Unmarshal(t.Body, &text) == nil {
+		t.payload = []byte(text)
+	}
+	if len(t.payload) > 0 && t.ContentType == "" {
+		t.ContentType = probeContentType
+	}
+	return t, nil
+}
+
+// label describes a target's request in reports: empty for a plain GET,
+// otherwise the method, content type and body size.
+func (t scanTarget) label() string {
+	if t.Method == "" || (t.Method == http.MethodGet && len(t.payload) == 0) {
+		return ""
+	}
+	if len(t.payload) == 0 {
+		return t.Method
+	}
+	return fmt.Sprintf("%s %s, %d byte body", t.Method, t.ContentType, len(t.payload))
+}
+
+// newRequest builds the request for a target. A body is replayed on 307
+// and 308 redirects.
+func (t scanTarget) newRequest(ctx context.Context) (*http.Request, error) {
+	method := t.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	if len(t.payload) == 0 {
+		return http.NewRequestWithContext(ctx, method, t.URL, nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.URL, bytes.NewReader(t.payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", t.ContentType)
+	return req, nil
+}
+
+// prepareRequestTargets applies --scope and normalization to the JSON
+// targets. Unlike plain URLs they are not deduplicated: the same endpoint
+// is usually probed with several bodies.
+func prepareRequestTargets(targets []scanTarget, scopes []string, stripQuery bool) []scanTarget {
+	var kept []scanTarget
+	for _, t := range targets {
+		normalized, err := normalizeURL(t.URL, stripQuery)
+		if err != nil {
+			if verboseMode {
+				fmt.Fprintf(os.Stderr, "[WARNING] Skipping invalid URL: %s (%v)\n", t.URL, err)
+			}
+			continue
+		}
+		u, _ := url.Parse(normalized)
+		if !inScope(u.Hostname(), scopes) {
+			if verboseMode {
+				fmt.Fprintf(os.Stderr, "[INFO] Out of scope, skipping: %s %s\n", t.Method, t.URL)
+			}
+			continue
+		}
+		t.URL = normalized
+		kept = append(kept, t)
+	}
+	return kept
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+)
+
+// TLSSummary describes the certificate of an HTTPS response, taken from the
+// connection the scan already made. Expiry is computed as in the SSL
+// certificate expiry checker: whole days until NotAfter of the leaf.
+type TLSSummary struct {
+	Version  string
+	Issuer   string
+	NotAfter time.Time
+	DaysLeft int
+}
+
+// summarizeTLS returns nil for plain HTTP responses.
+func summarizeTLS(state *tls.ConnectionState) *TLSSummary {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := state.PeerCertificates[0]
+	return &TLSSummary{
+		Version:  tls.VersionName(state.Version),
+		Issuer:   cert.Issuer.String(),
+		NotAfter: cert.NotAfter,
+		DaysLeft: int(time.Until(cert.NotAfter).Hours() / 24),
+	}
+}
+
+// Expiry formats the expiry date with the days left.
+func (t *TLSSummary) Expiry() string {
+	return fmt.Sprintf("%s (%d days left)", stamp(t.NotAfter), t.DaysLeft)
+}
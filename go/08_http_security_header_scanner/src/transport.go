@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// drainLimit is how much of an unread response body is read before closing
+// it; smaller bodies leave the connection reusable, larger ones close it.
+const drainLimit = 256 << 10
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+// newTransport builds the scanner's transport. Per-phase timeouts apply to
+// each request; connections are pooled per host (at most maxConnsPerHost, so
+// that further requests wait for one to be reused instead of handshaking
+// again) and DNS answers are cached between connections. Errors are labelled
+// with the phase the request was in when it failed. Hosts in pins are
+// connected to at their --resolve addresses.
+func newTransport(pins resolveList) *phaseTransport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	d := &dialer{dialer: net.Dialer{KeepAlive: 30 * time.Second}, timeout: seconds(connectTimeoutSec), pins: pins}
+	if dnsCacheSec > 0 {
+		d.dns = &dnsCache{ttl: seconds(dnsCacheSec), entries: map[string]*dnsEntry{}}
+	}
+	t.DialContext = d.dial
+	t.TLSHandshakeTimeout = seconds(tlsTimeoutSec)
+	t.ResponseHeaderTimeout = seconds(headerTimeoutSec)
+	t.MaxConnsPerHost = maxConnsPerHost
+	t.MaxIdleConnsPerHost = max(maxConnsPerHost, http.DefaultMaxIdleConnsPerHost)
+	t.MaxIdleConns = 0
+	t.DisableKeepAlives = noKeepAlive
+	return &phaseTransport{next: t}
+}
+
+// dnsEntry is a cached (or in-flight) lookup; concurrent connections to the
+// same host wait for the first lookup instead of repeating it.
+type dnsEntry struct {
+	ready   chan struct{}
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// dnsCache caches successful lookups for ttl.
+type dnsCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	c.mu.Lock()
+	e := c.entries[host]
+	if e != nil {
+		select {
+		case <-e.ready:
+			if e.err != nil || time.Now().After(e.expires) {
+				e = nil
+			}
+		default:
+		}
+	}
+	if e == nil {
+		e = &dnsEntry{ready: make(chan struct{})}
+		c.entries[host] = e
+		c.mu.Unlock()
+		e.addrs, e.err = net.DefaultResolver.LookupIPAddr(ctx, host)
+		e.expires = time.Now().Add(c.ttl)
+		close(e.ready)
+		return e.addrs, e.err
+	}
+	c.mu.Unlock()
+	select {
+	case <-e.ready:
+		return e.addrs, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dialer opens connections for the transport. timeout bounds DNS resolution
+// and the TCP connect together; the addresses of a host, or those pinned with
+// --resolve, are tried in turn.
+type dialer struct {
+	dialer  net.Dialer
+	timeout time.Duration
+	dns     *dnsCache   // nil: resolve on every connection
+	pins    resolveList // --resolve addresses; nil for none
+}
+
+func (d *dialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	host, port, err := net.SplitHostPort(addr)
+	var addrs []string
+	if pins := d.pins[strings.ToLower(addr)]; len(pins) > 0 {
+		debugf("connecting to %s via --resolve %s", addr, strings.Join(pins, ","))
+		addrs = pins
+	} else if err != nil || d.dns == nil || net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, addr)
+	} else {
+		ips, err := d.dns.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			addrs = append(addrs, ip.String())
+		}
+	}
+	var firstErr error
+	for _, a := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(a, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	return nil, firstErr
+}
+
+// phaseTransport tracks how far each request got, so that a timeout can be
+// reported as slow DNS, an unreachable host, a stalled TLS handshake or a
+// slow application rather than as a bare deadline. It also counts how many
+// requests needed a new connection.
+type phaseTransport struct {
+	next               http.RoundTripper
+	requests, newConns atomic.Int64
+}
+
+func (p *phaseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var mu sync.Mutex
+	phase := "connect"
+	set := func(s string) {
+		mu.Lock()
+		phase = s
+		mu.Unlock()
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { set("DNS lookup") },
+		ConnectStart:      func(string, string) { set("TCP connect") },
+		TLSHandshakeStart: func() { set("TLS handshake") },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				set("request write")
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				p.newConns.Add(1)
+			}
+			set("request write")
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set("response header wait") },
+		GotFirstResponseByte: func() { set("response header read") },
+	}
+	p.requests.Add(1)
+	start := time.Now()
+	resp, err := p.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err != nil && isTimeout(err) {
+		mu.Lock()
+		defer mu.Unlock()
+		return nil, fmt.Errorf("timed out in %s phase after %s: %w", phase, time.Since(start).Round(time.Millisecond), err)
+	}
+	return resp, err
+}
+
+// isTimeout reports whether err comes from a deadline rather than a refusal
+// or protocol error.
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// A minimal DNS stub resolver. net.Resolver follows CNAME chains internally
+// and reports a CNAME whose target does not exist only as "no such host",
+// hiding exactly the record a takeover check needs, so queries are built and
+// parsed here instead.
+
+const (
+	dnsTypeA      = 1
+	dnsTypeCNAME  = 5
+	dnsTypeAAAA   = 28
+	rcodeNoError  = 0
+	rcodeNXDomain = 3
+)
+
+// rcodeName returns the mnemonic for a DNS response code.
+func rcodeName(rcode int) string {
+	names := map[int]string{1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED"}
+	if n, ok := names[rcode]; ok {
+		return n
+	}
+	return fmt.Sprintf("rcode %d", rcode)
+}
+
+type dnsRecord struct {
+	Name string
+	Type uint16
+	Data string // Address for A/AAAA, target name for CNAME
+}
+
+type dnsResponse struct {
+	RCode   int
+	Answers []dnsRecord
+}
+
+type dnsClient struct {
+	server  string
+	timeout time.Duration
+}
+
+// systemNameserver returns the first nameserver in /etc/resolv.conf.
+func systemNameserver() string {
+	f, err := os.Open("/etc/resolv.conf")
+	if err == nil {
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				return net.JoinHostPort(fields[1], "53")
+			}
+		}
+	}
+	return "127.0.0.1:53"
+}
+
+// query sends one recursive query for name over UDP, retrying once on
+// timeout and switching to TCP when the answer is truncated.
+func (c *dnsClient) query(ctx context.Context, name string, qtype uint16) (*dnsResponse, error) {
+	id := uint16(rand.Intn(1 << 16))
+	msg, err := buildDNSQuery(id, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		resp, truncated, err := c.exchangeUDP(ctx, msg, id)
+		if err == nil && truncated {
+			resp, err = c.exchangeTCP(ctx, msg, id)
+		}
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+		var ne net.Error
+		if !errors.As(err, &ne) || !ne.Timeout() || ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *dnsClient) exchangeUDP(ctx context.Context, msg []byte, id uint16) (*dnsResponse, bool, error) {
+	conn, err := (&net.Dialer{Timeout: c.timeout}).DialContext(ctx, "udp", c.server)
+	if err != nil {
+		return nil, false, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := conn.Write(msg); err != nil {
+		return nil, false, err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, false, err
+		}
+		if n < 12 || binary.BigEndian.Uint16(buf) != id {
+			continue // Stray or spoofed packet
+		}
+		truncated := buf[2]&0x02 != 0
+		resp, err := parseDNSResponse(buf[:n])
+		return resp, truncated, err
+	}
+}
+
+func (c *dnsClient) exchangeTCP(ctx context.Context, msg []byte, id uint16) (*dnsResponse, error) {
+	conn, err := (&net.Dialer{Timeout: c.timeout}).DialContext(ctx, "tcp", c.server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+	if _, err := conn.Write(append(framed, msg...)); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	if len(buf) < 12 || binary.BigEndian.Uint16(buf) != id {
+		return nil, fmt.Errorf("mismatched DNS response over TCP")
+	}
+	return parseDNSResponse(buf)
+}
+
+func buildDNSQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = append(msg, 0x01, 0x00) // Recursion desired
+	msg = append(msg, 0, 1, 0, 0, 0, 0, 0, 0)
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid DNS name %q", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, 1), nil // Class IN
+}
+
+func parseDNSResponse(msg []byte) (*dnsResponse, error) {
+	resp := &dnsResponse{RCode: int(msg[3] & 0x0f)}
+	qdCount := int(binary.BigEndian.Uint16(msg[4:]))
+	anCount := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+	for i := 0; i < qdCount; i++ {
+		_, next, err := readDNSName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4
+	}
+	for i := 0; i < anCount; i++ {
+		name, next, err := readDNSName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(msg) {
+			return nil, fmt.Errorf("truncated DNS record")
+		}
+		rtype := binary.BigEndian.Uint16(msg[next:])
+		rdLen := int(binary.BigEndian.Uint16(msg[next+8:]))
+		rdata := next + 10
+		if rdata+rdLen > len(msg) {
+			return nil, fmt.Errorf("truncated DNS record data")
+		}
+		rec := dnsRecord{Name: name, Type: rtype}
+		switch rtype {
+		case dnsTypeA, dnsTypeAAAA:
+			rec.Data = net.IP(msg[rdata : rdata+rdLen]).String()
+		case dnsTypeCNAME:
+			if rec.Data, _, err = readDNSName(msg, rdata); err != nil {
+				return nil, err
+			}
+		}
+		resp.Answers = append(resp.Answers, rec)
+		off = rdata + rdLen
+	}
+	return resp, nil
+}
+
+// readDNSName decodes a possibly compressed name at off and returns it in
+// lower case with the offset just past it.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; jumps < 64; {
+		if off >= len(msg) {
+			return "", 0, fmt.Errorf("truncated DNS name")
+		}
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")), end, nil
+		case l&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, fmt.Errorf("truncated DNS name pointer")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			jumps++
+		default:
+			if off+1+l > len(msg) {
+				return "", 0, fmt.Errorf("truncated DNS label")
+			}
+			labels = append(labels, string(msg[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+	return "", 0, fmt.Errorf("DNS name compression loop")
+}
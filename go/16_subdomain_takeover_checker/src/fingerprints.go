@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// takeoverService describes a hosting provider whose custom-domain CNAMEs can
+// be claimed by anyone once the original resource is deleted.
+type takeoverService struct {
+	Name string
+	// CNAME matches any hop of the subdomain's CNAME chain.
+	CNAME *regexp.Regexp
+	// Bodies are texts the provider serves for an unclaimed resource.
+	Bodies []string
+	// NXDomain marks providers whose target name disappears with the
+	// resource, so a dangling CNAME alone shows it is claimable.
+	NXDomain bool
+}
+
+// takeoverServices is a small built-in fingerprint table, based on the
+// community-maintained "can-i-take-over-xyz" list.
+var takeoverServices = []takeoverService{
+	{"AWS S3", regexp.MustCompile(`(^|\.)s3([.-][a-z0-9-]+)*\.amazonaws\.com$`), []string{"NoSuchBucket", "The specified bucket does not exist"}, false},
+	{"GitHub Pages", regexp.MustCompile(`\.github\.io$`), []string{"There isn't a GitHub Pages site here."}, false},
+	{"Heroku", regexp.MustCompile(`\.(herokuapp|herokudns|herokussl)\.com$`), []string{"No such app", "herokucdn.com/error-pages/no-such-app.html"}, true},
+	{"Microsoft Azure", regexp.MustCompile(`\.(azurewebsites|cloudapp|trafficmanager|azureedge)\.net$|\.cloudapp\.azure\.com$|\.blob\.core\.windows\.net$`), nil, true},
+	{"Shopify", regexp.MustCompile(`\.myshopify\.com$`), []string{"Sorry, this shop is currently unavailable."}, false},
+	{"Fastly", regexp.MustCompile(`\.fastly\.net$`), []string{"Fastly error: unknown domain"}, false},
+	{"Zendesk", regexp.MustCompile(`\.zendesk\.com$`), []string{"Help Center Closed"}, false},
+	{"Tumblr", regexp.MustCompile(`^domains\.tumblr\.com$`), []string{"Whatever you were looking for doesn't currently exist at this address"}, false},
+	{"Surge.sh", regexp.MustCompile(`\.surge\.sh$`), []string{"project not found"}, false},
+	{"Bitbucket", regexp.MustCompile(`\.bitbucket\.io$`), []string{"Repository not found"}, false},
+	{"Pantheon", regexp.MustCompile(`\.pantheonsite\.io$`), []string{"The gods are wise, but do not know of the site which you seek."}, false},
+}
+
+// matchService returns the provider behind a CNAME chain, if it is a known one.
+func matchService(chain []string) *takeoverService {
+	for i := range takeoverServices {
+		for _, hop := range chain {
+			if takeoverServices[i].CNAME.MatchString(hop) {
+				return &takeoverServices[i]
+			}
+		}
+	}
+	return nil
+}
+
+// fetchBody requests / from host (over HTTP, then HTTPS) at the address our
+// own resolver found, and returns the start of the response body.
+func fetchBody(ctx context.Context, host, addr string, timeout time.Duration) (string, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+				_, port, _ := net.SplitHostPort(hostPort)
+				return dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+			},
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // Unclaimed endpoints rarely hold a matching certificate
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	var lastErr error
+	for _, scheme := range []string{"http", "https"} {
+		req, err := http.NewRequestWithContext(ctx, "GET", scheme+"://"+host+"/", nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		debugf("%s: %s %s, %d body bytes", host, scheme, resp.Status, len(body))
+		if err == nil && len(body) > 0 {
+			return string(body), nil
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// matchBody returns the first fingerprint of svc found in body.
+func matchBody(svc *takeoverService, body string) string {
+	for _, fp := range svc.Bodies {
+		if strings.Contains(body, fp) {
+			return fp
+		}
+	}
+	return ""
+}
@@ -0,0 +1,423 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of a Subdomain Takeover Checker.
+PURPOSE: Show skill in DNS protocol handling, concurrent enumeration, HTTP fingerprinting, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "subdomain_takeover_checker"
+	toolVersion = "1.0.0"
+)
+
+// Global variables for CLI flags
+var (
+	domain       string
+	wordlistFile string
+	passiveFile  string
+	outputFile   string
+	resolverAddr string
+	timeoutSec   int
+	concurrency  int
+	showAll      bool
+	verboseMode  bool
+)
+
+// maxCNAMEHops bounds how far a CNAME chain is followed.
+const maxCNAMEHops = 8
+
+// SubdomainResult describes one existing subdomain and its takeover verdict.
+type SubdomainResult struct {
+	Name     string
+	Chain    []string // CNAME targets in resolution order
+	Addrs    []string
+	Dangling bool // The last CNAME target does not exist (NXDOMAIN)
+	Service  string
+	Status   string // VULNERABLE, DANGLING, OK or ERROR
+	Evidence string
+	Error    error
+}
+
+func init() {
+	flag.StringVar(&domain, "domain", "", "Parent domain to enumerate (e.g. example.com).")
+	flag.StringVar(&domain, "d", "", "Parent domain to enumerate (shorthand).")
+
+	flag.StringVar(&wordlistFile, "wordlist", "", "Path to a file of subdomain labels (one per line) to try under the domain.")
+	flag.StringVar(&wordlistFile, "w", "", "Path to a wordlist file (shorthand).")
+
+	flag.StringVar(&passiveFile, "passive", "", "Path to a file of subdomains from passive sources (certificate transparency, subfinder/amass output); names outside the domain are ignored.")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.StringVar(&resolverAddr, "resolver", "", "DNS server (ip[:port]) to query; defaults to the first nameserver in /etc/resolv.conf.")
+
+	flag.IntVar(&timeoutSec, "timeout", 5, "DNS and HTTP timeout in seconds.")
+	flag.IntVar(&timeoutSec, "t", 5, "DNS and HTTP timeout in seconds (shorthand).")
+
+	flag.IntVar(&concurrency, "concurrency", 20, "Number of subdomains checked in parallel.")
+	flag.IntVar(&concurrency, "c", 20, "Number of subdomains checked in parallel (shorthand).")
+
+	flag.BoolVar(&showAll, "all", false, "Also list subdomains with no takeover indication (status OK).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Enumerates subdomains and flags dangling CNAMEs to unclaimed SaaS endpoints.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s -d example.com -w wordlist.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -d example.com --passive crt_names.txt -o report.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// readLines returns the non-empty, non-comment lines of a file.
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// normalizeName turns a passive-source entry (URL, wildcard, host:port) into
+// a lower-case host name.
+func normalizeName(entry string) string {
+	name := strings.ToLower(entry)
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+	if i := strings.IndexAny(name, "/?#"); i >= 0 {
+		name = name[:i]
+	}
+	if h, _, err := net.SplitHostPort(name); err == nil {
+		name = h
+	}
+	name = strings.TrimPrefix(name, "*.")
+	return strings.TrimSuffix(name, ".")
+}
+
+// buildCandidates combines wordlist labels and passive names under domain,
+// without duplicates.
+func buildCandidates(domain string, labels, passive []string) []string {
+	seen := map[string]bool{}
+	var names []string
+	add := func(name string) {
+		if name != domain && strings.HasSuffix(name, "."+domain) && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, label := range labels {
+		add(strings.ToLower(strings.Trim(label, ".")) + "." + domain)
+	}
+	for _, line := range passive {
+		// crt.sh and similar exports may hold several names per line
+		for _, entry := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			add(normalizeName(entry))
+		}
+	}
+	return names
+}
+
+// resolveSubdomain queries name's A records and reconstructs its CNAME chain.
+// It reports exists=false for names without CNAME or A records.
+func resolveSubdomain(ctx context.Context, c *dnsClient, name string) (result SubdomainResult, exists bool) {
+	result = SubdomainResult{Name: name}
+	current := name
+	for hop := 0; hop <= maxCNAMEHops; hop++ {
+		known := len(result.Chain)
+		resp, err := c.query(ctx, current, dnsTypeA)
+		if err != nil {
+			result.Error = fmt.Errorf("DNS query for %s failed: %w", current, err)
+			return result, true
+		}
+		// A recursive resolver answers with the whole chain; walk it from current.
+		cnames := map[string]string{}
+		for _, rec := range resp.Answers {
+			switch rec.Type {
+			case dnsTypeCNAME:
+				cnames[rec.Name] = rec.Data
+			case dnsTypeA:
+				result.Addrs = append(result.Addrs, rec.Data)
+			}
+		}
+		for target, ok := cnames[current]; ok && len(result.Chain) < maxCNAMEHops; target, ok = cnames[current] {
+			result.Chain = append(result.Chain, target)
+			current = target
+		}
+
+		switch {
+		case resp.RCode == rcodeNXDomain:
+			if len(result.Chain) == 0 {
+				return result, false
+			}
+			result.Dangling = true
+			return result, true
+		case resp.RCode != rcodeNoError:
+			result.Error = fmt.Errorf("DNS server returned %s for %s", rcodeName(resp.RCode), current)
+			return result, true
+		case len(result.Addrs) > 0 || len(result.Chain) == 0 || hop == maxCNAMEHops || (hop > 0 && len(result.Chain) == known):
+			return result, len(result.Addrs) > 0 || len(result.Chain) > 0
+		}
+		// Chain stops at a name the resolver did not expand; query it directly.
+	}
+	return result, true
+}
+
+// checkSubdomain resolves name and decides whether it can be taken over.
+func checkSubdomain(ctx context.Context, c *dnsClient, name string, timeout time.Duration) (SubdomainResult, bool) {
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Checking: %s\n", name)
+	}
+	result, exists := resolveSubdomain(ctx, c, name)
+	if !exists {
+		return result, false
+	}
+	debugf("%s: chain %v, addresses %v, dangling %v", name, result.Chain, result.Addrs, result.Dangling)
+	if result.Error != nil {
+		result.Status = "ERROR"
+		return result, true
+	}
+
+	result.Status = "OK"
+	svc := matchService(result.Chain)
+	if svc != nil {
+		result.Service = svc.Name
+	}
+	last := ""
+	if len(result.Chain) > 0 {
+		last = result.Chain[len(result.Chain)-1]
+	}
+	switch {
+	case result.Dangling && svc != nil && svc.NXDomain:
+		result.Status = "VULNERABLE"
+		result.Evidence = fmt.Sprintf("CNAME target %s does not exist (NXDOMAIN); the %s resource can be re-registered", last, svc.Name)
+	case result.Dangling:
+		result.Status = "DANGLING"
+		result.Evidence = fmt.Sprintf("CNAME target %s does not exist (NXDOMAIN); check whether it can be registered", last)
+	case svc != nil && len(svc.Bodies) > 0 && len(result.Addrs) > 0:
+		body, err := fetchBody(ctx, name, result.Addrs[0], timeout)
+		if err != nil {
+			result.Evidence = fmt.Sprintf("HTTP fingerprint check failed: %v", err)
+		} else if fp := matchBody(svc, body); fp != "" {
+			result.Status = "VULNERABLE"
+			result.Evidence = fmt.Sprintf("HTTP response contains %q (unclaimed %s resource)", fp, svc.Name)
+		}
+	}
+	return result, true
+}
+
+// runChecks checks candidates with a bounded worker pool. If ctx is cancelled
+// the results gathered so far are returned with interrupted set.
+func runChecks(ctx context.Context, c *dnsClient, candidates []string, timeout time.Duration) (results []SubdomainResult, checked int, interrupted bool) {
+	jobs := make(chan string)
+	type outcome struct {
+		result SubdomainResult
+		exists bool
+	}
+	outcomes := make(chan outcome, len(candidates))
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				r, exists := checkSubdomain(ctx, c, name, timeout)
+				outcomes <- outcome{r, exists}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, name := range candidates {
+			select {
+			case jobs <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	for o := range outcomes {
+		if ctx.Err() != nil && o.result.Error != nil {
+			continue // Cut short by the interrupt, not a real DNS failure
+		}
+		checked++
+		if o.exists {
+			results = append(results, o.result)
+		}
+	}
+	return results, checked, ctx.Err() != nil
+}
+
+// statusRank orders the report: takeover candidates first.
+var statusRank = map[string]int{"VULNERABLE": 0, "DANGLING": 1, "ERROR": 2, "OK": 3}
+
+// writeReport writes the takeover findings to output.
+func writeReport(results []SubdomainResult, candidates int, output io.Writer) {
+	fmt.Fprintf(output, "--- Subdomain Takeover Report ---\n\n")
+	fmt.Fprintf(output, "Domain: %s\nCandidates Checked: %d\nExisting Subdomains: %d\n", domain, candidates, len(results))
+	fmt.Fprintln(output, "------------------------------")
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if statusRank[results[i].Status] != statusRank[results[j].Status] {
+			return statusRank[results[i].Status] < statusRank[results[j].Status]
+		}
+		return results[i].Name < results[j].Name
+	})
+	potential := 0
+	for _, r := range results {
+		if r.Status == "VULNERABLE" || r.Status == "DANGLING" {
+			potential++
+		}
+		if r.Status == "OK" && !showAll {
+			continue
+		}
+		fmt.Fprintf(output, "Subdomain: %s\n", r.Name)
+		fmt.Fprintf(output, "Status: %s\n", colorStatus(r.Status))
+		if len(r.Chain) > 0 {
+			fmt.Fprintf(output, "CNAME: %s -> %s\n", r.Name, strings.Join(r.Chain, " -> "))
+		}
+		if len(r.Addrs) > 0 {
+			fmt.Fprintf(output, "Addresses: %s\n", strings.Join(r.Addrs, ", "))
+		}
+		if r.Service != "" {
+			fmt.Fprintf(output, "Service: %s\n", r.Service)
+		}
+		if r.Evidence != "" {
+			fmt.Fprintf(output, "Evidence: %s\n", r.Evidence)
+		}
+		if r.Error != nil {
+			fmt.Fprintf(output, "Error: %v\n", r.Error)
+		}
+		fmt.Fprintln(output, "------------------------------")
+	}
+	fmt.Fprintf(output, "Potential Takeovers: %d\n", potential)
+}
+
+// main is the entry point of the Subdomain Takeover Checker tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	domain = strings.ToLower(strings.Trim(domain, "."))
+	if domain == "" || (wordlistFile == "" && passiveFile == "") {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] A domain (-d) and a wordlist (-w) and/or passive source file (--passive) must be provided.")
+		os.Exit(1)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var labels, passive []string
+	var err error
+	if wordlistFile != "" {
+		if labels, err = readLines(wordlistFile); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if passiveFile != "" {
+		if passive, err = readLines(passiveFile); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+	}
+	candidates := buildCandidates(domain, labels, passive)
+
+	server := resolverAddr
+	if server == "" {
+		server = systemNameserver()
+	} else if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	timeout := time.Duration(timeoutSec) * time.Second
+	client := &dnsClient{server: server, timeout: timeout}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Checking %d candidate subdomain(s) of %s using DNS server %s...\n", len(candidates), domain, server)
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+
+	// SIGINT/SIGTERM stop the enumeration; subdomains checked so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results, checked, interrupted := runChecks(ctx, client, candidates, timeout)
+	writeReport(results, checked, output)
+	if selfStatsOn {
+		writeSelfStats(output, collectSelfStats(checked, "candidates"))
+	}
+	inputs := []string{wordlistFile, passiveFile}
+	if interrupted {
+		stop()
+		fmt.Fprintf(output, "Partial report: interrupted after %d of %d candidates.\n", checked, len(candidates))
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		writeManifest(130, inputs, []string{outputFile})
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Subdomain takeover check complete.")
+	}
+	writeManifest(0, inputs, []string{outputFile})
+	os.Exit(0)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities, in increasing order of concern.
+const (
+	sevPass = iota
+	sevWarn
+	sevFail
+)
+
+var severityNames = map[int]string{sevPass: "PASS", sevWarn: "WARN", sevFail: "FAIL"}
+
+// Finding is one weakness found on a server or in an authorized_keys file.
+type Finding struct {
+	Severity int
+	Category string
+	Subject  string
+	Reason   string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s %s: %s", severityNames[f.Severity], f.Category, f.Subject, f.Reason)
+}
+
+// algorithmRule flags an algorithm by exact name, by prefix ("hmac-md5*") or
+// by suffix ("*-cbc").
+type algorithmRule struct {
+	Pattern  string
+	Severity int
+	Reason   string
+}
+
+func (r algorithmRule) matches(name string) bool {
+	if p, ok := strings.CutSuffix(r.Pattern, "*"); ok {
+		return strings.HasPrefix(name, p)
+	}
+	if s, ok := strings.CutPrefix(r.Pattern, "*"); ok {
+		return strings.HasSuffix(name, s)
+	}
+	return name == r.Pattern
+}
+
+// weakAlgorithms follows the OpenSSH deprecation history and the
+// recommendations of RFC 9142 (key exchange) and RFC 8332 (RSA signatures).
+var weakAlgorithms = map[string][]algorithmRule{
+	"kex": {
+		{"diffie-hellman-group1-sha1", sevFail, "1024-bit group with SHA-1 (Logjam-range)"},
+		{"gss-group1-sha1-*", sevFail, "1024-bit group with SHA-1"},
+		{"diffie-hellman-group14-sha1", sevWarn, "SHA-1 key exchange hash"},
+		{"diffie-hellman-group-exchange-sha1", sevWarn, "SHA-1 key exchange hash"},
+		{"gss-group14-sha1-*", sevWarn, "SHA-1 key exchange hash"},
+		{"gss-gex-sha1-*", sevWarn, "SHA-1 key exchange hash"},
+	},
+	"hostkey": {
+		{"ssh-dss", sevFail, "DSA keys are limited to 1024 bits and removed from OpenSSH"},
+		{"ssh-dss-cert-v01@openssh.com", sevFail, "DSA certificate"},
+		{"ssh-rsa", sevWarn, "RSA signatures with SHA-1; offer rsa-sha2-256/512 instead"},
+		{"ssh-rsa-cert-v01@openssh.com", sevWarn, "RSA certificate signatures with SHA-1"},
+	},
+	"cipher": {
+		{"none", sevFail, "no encryption"},
+		{"3des-cbc", sevFail, "64-bit block cipher (Sweet32)"},
+		{"blowfish-cbc", sevFail, "64-bit block cipher (Sweet32)"},
+		{"cast128-cbc", sevFail, "64-bit block cipher (Sweet32)"},
+		{"idea-cbc", sevFail, "64-bit block cipher (Sweet32)"},
+		{"des-cbc*", sevFail, "56-bit key"},
+		{"arcfour*", sevFail, "RC4 stream cipher is broken"},
+		{"rijndael-cbc@lysator.liu.se", sevWarn, "CBC mode (plaintext recovery attacks)"},
+		{"*-cbc", sevWarn, "CBC mode (plaintext recovery attacks)"},
+	},
+	"mac": {
+		{"none", sevFail, "no integrity protection"},
+		{"hmac-md5*", sevFail, "MD5-based MAC"},
+		{"hmac-sha1-96*", sevWarn, "SHA-1-based MAC truncated to 96 bits"},
+		{"hmac-sha1*", sevWarn, "SHA-1-based MAC"},
+		{"umac-64*", sevWarn, "64-bit tag"},
+		{"hmac-ripemd160*", sevWarn, "RIPEMD-160-based MAC"},
+	},
+	"compression": {
+		{"zlib", sevWarn, "compression before authentication exposes the decompressor to unauthenticated clients"},
+	},
+}
+
+// classifyAlgorithm returns the severity of the first rule matching name.
+func classifyAlgorithm(category, name string) (int, string) {
+	for _, rule := range weakAlgorithms[category] {
+		if rule.matches(name) {
+			return rule.Severity, rule.Reason
+		}
+	}
+	return sevPass, ""
+}
+
+// auditOffer checks everything a server advertised against weakAlgorithms.
+func auditOffer(offer *serverOffer) []Finding {
+	var findings []Finding
+	if version, _, _ := strings.Cut(strings.TrimPrefix(offer.Banner, "SSH-"), "-"); version != "2.0" {
+		findings = append(findings, Finding{sevFail, "protocol", version, "SSH protocol 1 is supported"})
+	}
+	lists := []struct {
+		category string
+		names    []string
+	}{
+		{"kex", offer.Kex},
+		{"hostkey", offer.HostKeys},
+		{"cipher", offer.Ciphers},
+		{"mac", offer.MACs},
+		{"compression", offer.Compression},
+	}
+	for _, l := range lists {
+		for _, name := range l.names {
+			if sev, reason := classifyAlgorithm(l.category, name); sev > sevPass {
+				findings = append(findings, Finding{sev, l.category, name, reason})
+			}
+		}
+	}
+	return findings
+}
+
+// worstSeverity returns the highest severity among findings.
+func worstSeverity(findings []Finding) int {
+	worst := sevPass
+	for _, f := range findings {
+		worst = max(worst, f.Severity)
+	}
+	return worst
+}
+
+// normalized maps a finding to the cross-tool findings model: FAIL is high
+// and WARN medium, except that no encryption or no integrity at all is
+// critical. Weak server algorithms are scored as an attack by someone on the
+// network path; authorized_keys findings carry a severity only.
+func (f Finding) normalized(target string) normFinding {
+	n := normFinding{Tool: toolName, Target: target, Category: f.Category, Title: f.Subject, Severity: "medium", Detail: f.Reason}
+	if f.Severity == sevFail {
+		n.Severity = "high"
+		if f.Subject == "none" {
+			n.Severity = "critical"
+		}
+	}
+	switch {
+	case f.Category == "key":
+	case f.Severity == sevFail:
+		n = n.withVector("AV:A/PR:N/C:H/I:H/A:N")
+	default:
+		n = n.withVector("AV:A/PR:N/C:L/I:L/A:N")
+	}
+	return n
+}
+
+// normalizedFindings collects the findings of every server and key file.
+func normalizedFindings(hosts []HostResult, keys []KeyFileResult) []normFinding {
+	var out []normFinding
+	for _, h := range hosts {
+		for _, f := range h.Findings {
+			out = append(out, f.normalized(h.Address))
+		}
+	}
+	for _, k := range keys {
+		for _, f := range k.Findings {
+			out = append(out, f.normalized(k.Path))
+		}
+	}
+	return out
+}
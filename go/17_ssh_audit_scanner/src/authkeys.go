@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// authorizedKey is one parsed line of an authorized_keys file.
+type authorizedKey struct {
+	File    string
+	Line    int
+	Type    string
+	Comment string
+	Bits    int
+}
+
+func (k authorizedKey) location() string {
+	loc := fmt.Sprintf("%s:%d", k.File, k.Line)
+	if k.Comment != "" {
+		loc += " (" + k.Comment + ")"
+	}
+	return loc
+}
+
+// KeyFileResult holds the keys and findings for one authorized_keys file.
+type KeyFileResult struct {
+	Path     string
+	Keys     []authorizedKey
+	Findings []Finding
+	Error    error
+}
+
+// stringList collects a repeatable flag.
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+// expandKeyFiles resolves glob patterns such as /home/*/.ssh/authorized_keys.
+// A pattern without matches is kept so that the missing file is reported.
+func expandKeyFiles(patterns []string) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, p := range patterns {
+		matches, err := filepath.Glob(p)
+		if err != nil || len(matches) == 0 {
+			matches = []string{p}
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	return files
+}
+
+// splitKeyLine separates the optional leading options field from the key
+// type, base64 data and comment. Options may contain quoted spaces and
+// backslash-escaped quotes.
+func splitKeyLine(line string) (keyType, data, comment string, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) >= 2 && !isKeyType(fields[0]) {
+		inQuote, escaped := false, false
+	scan:
+		for i, r := range line {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && inQuote:
+				escaped = true
+			case r == '"':
+				inQuote = !inQuote
+			case (r == ' ' || r == '\t') && !inQuote:
+				fields = strings.Fields(line[i:])
+				break scan
+			}
+		}
+	}
+	if len(fields) < 2 || !isKeyType(fields[0]) {
+		return "", "", "", false
+	}
+	if len(fields) > 2 {
+		comment = strings.Join(fields[2:], " ")
+	}
+	return fields[0], fields[1], comment, true
+}
+
+func isKeyType(s string) bool {
+	return strings.HasPrefix(s, "ssh-") || strings.HasPrefix(s, "ecdsa-") || strings.HasPrefix(s, "sk-")
+}
+
+// readSSHString reads one length-prefixed field of the SSH wire format.
+func readSSHString(b []byte) ([]byte, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, fmt.Errorf("truncated key data")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return nil, nil, fmt.Errorf("truncated key data")
+	}
+	return b[4 : 4+n], b[4+n:], nil
+}
+
+// keyBits decodes a public key blob and returns its embedded type and size.
+func keyBits(blob []byte) (string, int, error) {
+	t, rest, err := readSSHString(blob)
+	if err != nil {
+		return "", 0, err
+	}
+	keyType := string(t)
+	base := strings.TrimSuffix(keyType, "-cert-v01@openssh.com")
+	if base != keyType {
+		if _, rest, err = readSSHString(rest); err != nil { // Certificate nonce
+			return keyType, 0, err
+		}
+	}
+	switch {
+	case base == "ssh-rsa":
+		if _, rest, err = readSSHString(rest); err != nil { // Public exponent
+			return keyType, 0, err
+		}
+		n, _, err := readSSHString(rest)
+		if err != nil {
+			return keyType, 0, err
+		}
+		return keyType, new(big.Int).SetBytes(n).BitLen(), nil
+	case base == "ssh-dss":
+		p, _, err := readSSHString(rest)
+		if err != nil {
+			return keyType, 0, err
+		}
+		return keyType, new(big.Int).SetBytes(p).BitLen(), nil
+	case strings.Contains(base, "ecdsa-sha2-nistp"):
+		curve, _, err := readSSHString(rest)
+		if err != nil {
+			return keyType, 0, err
+		}
+		sizes := map[string]int{"nistp256": 256, "nistp384": 384, "nistp521": 521}
+		return keyType, sizes[string(curve)], nil
+	case strings.Contains(base, "ed25519"):
+		return keyType, 256, nil
+	}
+	return keyType, 0, fmt.Errorf("unsupported key type %s", keyType)
+}
+
+// auditKeyFile parses an authorized_keys file and flags weak or malformed
+// keys. seen maps key data to the first place it was found so duplicates are
+// detected across files as well as within one.
+func auditKeyFile(path string, seen map[string]authorizedKey) KeyFileResult {
+	result := KeyFileResult{Path: path}
+	file, err := os.Open(path)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // Certificates and options can make long lines
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyType, data, comment, ok := splitKeyLine(line)
+		key := authorizedKey{File: path, Line: lineNo, Type: keyType, Comment: comment}
+		add := func(sev int, reason string) {
+			result.Findings = append(result.Findings, Finding{sev, "key", key.location(), reason})
+		}
+		if !ok {
+			add(sevFail, "unparseable line (no recognised key type)")
+			continue
+		}
+		blob, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			add(sevFail, "key data is not valid base64")
+			continue
+		}
+		embedded, bits, err := keyBits(blob)
+		if err != nil {
+			add(sevFail, fmt.Sprintf("malformed key: %v", err))
+			continue
+		}
+		key.Bits = bits
+		result.Keys = append(result.Keys, key)
+		debugf("%s: %s %d bits", key.location(), keyType, bits)
+
+		if embedded != keyType {
+			add(sevFail, fmt.Sprintf("declared type %s does not match key data (%s)", keyType, embedded))
+		}
+		switch {
+		case strings.HasPrefix(embedded, "ssh-dss"):
+			add(sevFail, fmt.Sprintf("DSA key (%d bits); DSA is no longer accepted by OpenSSH", bits))
+		case strings.HasPrefix(embedded, "ssh-rsa") && bits < 2048:
+			add(sevFail, fmt.Sprintf("RSA key is only %d bits (minimum 2048)", bits))
+		case strings.HasPrefix(embedded, "ssh-rsa") && bits < 3072:
+			add(sevWarn, fmt.Sprintf("RSA key is %d bits; 3072 or more is recommended", bits))
+		}
+		if first, dup := seen[data]; dup {
+			add(sevWarn, fmt.Sprintf("duplicate of %s", first.location()))
+		} else {
+			seen[data] = key
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		result.Error = err
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// The SSH banner and the server's first key-exchange message (SSH_MSG_KEXINIT)
+// are sent in cleartext before any cryptography is negotiated, so everything
+// the audit needs can be read without implementing the SSH handshake.
+
+const (
+	sshMsgKexinit = 20
+	maxPacketLen  = 35000 // RFC 4253 minimum every implementation must accept
+	clientBanner  = "SSH-2.0-ssh_audit_scanner_" + toolVersion
+)
+
+// serverOffer is what an SSH server advertises before key exchange.
+type serverOffer struct {
+	Banner      string
+	Kex         []string
+	HostKeys    []string
+	Ciphers     []string
+	MACs        []string
+	Compression []string
+}
+
+// fetchServerOffer connects to address, exchanges banners and parses the
+// server's KEXINIT. The connection is closed before key exchange starts.
+func fetchServerOffer(ctx context.Context, address string, timeout time.Duration) (*serverOffer, error) {
+	conn, err := (&net.Dialer{Timeout: timeout}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	// Unblock reads from a server that stalls after accepting, on interrupt.
+	unblock := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer unblock()
+	r := bufio.NewReader(conn)
+
+	offer := &serverOffer{}
+	// Servers may send other lines before the version string (RFC 4253 4.2).
+	for lines := 0; ; lines++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("no SSH banner received: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			offer.Banner = line
+			break
+		}
+		if lines > 20 || len(line) > 255 {
+			return nil, fmt.Errorf("no SSH banner received (service sent %q)", truncate(line, 40))
+		}
+	}
+	if !strings.HasPrefix(offer.Banner, "SSH-2.0-") && !strings.HasPrefix(offer.Banner, "SSH-1.99-") {
+		return offer, nil // SSH-1 only; there is no KEXINIT to read
+	}
+	if _, err := io.WriteString(conn, clientBanner+"\r\n"); err != nil {
+		return offer, err
+	}
+
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return offer, fmt.Errorf("failed to read KEXINIT: %w", err)
+	}
+	packetLen := binary.BigEndian.Uint32(header[:4])
+	padding := uint32(header[4])
+	if packetLen < 2 || packetLen > maxPacketLen || padding >= packetLen {
+		return offer, fmt.Errorf("invalid SSH packet length %d", packetLen)
+	}
+	body := make([]byte, packetLen-1)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return offer, fmt.Errorf("failed to read KEXINIT: %w", err)
+	}
+	payload := body[:packetLen-1-padding]
+	if len(payload) < 17 || payload[0] != sshMsgKexinit {
+		return offer, fmt.Errorf("expected KEXINIT, got SSH message %d", payload[0])
+	}
+
+	lists := make([][]string, 8)
+	rest := payload[17:] // Message type and 16-byte cookie
+	for i := range lists {
+		if len(rest) < 4 {
+			return offer, fmt.Errorf("truncated KEXINIT")
+		}
+		n := binary.BigEndian.Uint32(rest)
+		if uint32(len(rest)-4) < n {
+			return offer, fmt.Errorf("truncated KEXINIT name-list")
+		}
+		if n > 0 {
+			lists[i] = strings.Split(string(rest[4:4+n]), ",")
+		}
+		rest = rest[4+n:]
+	}
+	offer.Kex, offer.HostKeys = lists[0], lists[1]
+	offer.Ciphers = union(lists[2], lists[3]) // client-to-server and server-to-client
+	offer.MACs = union(lists[4], lists[5])
+	offer.Compression = union(lists[6], lists[7])
+	return offer, nil
+}
+
+// union merges name-lists, keeping the server's preference order.
+func union(a, b []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, name := range append(append([]string{}, a...), b...) {
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
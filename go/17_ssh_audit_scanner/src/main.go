@@ -0,0 +1,381 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of an SSH Configuration and Key Audit Scanner.
+PURPOSE: Show skill in SSH protocol handling, cryptographic policy checks, key parsing, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "ssh_audit_scanner"
+	toolVersion = "1.0.0"
+)
+
+// Global variables for CLI flags
+var (
+	targetHost  string
+	targetPort  int
+	inputFile   string
+	keyFiles    stringList
+	outputFile  string
+	timeoutSec  int
+	concurrency int
+	verboseMode bool
+)
+
+// HostResult holds what one SSH endpoint offered and the weaknesses found.
+type HostResult struct {
+	Address  string
+	Offer    *serverOffer
+	Findings []Finding
+	Status   string // PASS, WARN, FAIL or ERROR
+	Error    error
+}
+
+func init() {
+	flag.StringVar(&targetHost, "host", "", "Single SSH server to audit (host or host:port).")
+	flag.StringVar(&targetHost, "h", "", "Single SSH server to audit (shorthand).")
+
+	flag.IntVar(&targetPort, "port", 22, "SSH port used for targets that do not name one.")
+	flag.IntVar(&targetPort, "p", 22, "SSH port (shorthand).")
+
+	flag.StringVar(&inputFile, "input", "", "Path to a file of SSH servers (host or host:port, one per line), or - to read them from stdin.")
+	flag.StringVar(&inputFile, "i", "", "Path to a file of SSH servers (shorthand).")
+
+	flag.Var(&keyFiles, "authorized-keys", "authorized_keys file to audit for weak or duplicated keys; repeatable, glob patterns allowed.")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.IntVar(&timeoutSec, "timeout", 10, "Connection timeout in seconds.")
+	flag.IntVar(&timeoutSec, "t", 10, "Connection timeout in seconds (shorthand).")
+
+	flag.IntVar(&concurrency, "concurrency", 10, "Number of servers audited in parallel.")
+	flag.IntVar(&concurrency, "c", 10, "Number of servers audited in parallel (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerFindingsFlags()
+	registerOtelFlag()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Audits SSH servers for weak algorithms and authorized_keys files for weak keys.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s -h bastion.example.com\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -i servers.txt --authorized-keys '/home/*/.ssh/authorized_keys' -o ssh_audit.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// normalizeTarget adds the default port to entries without one.
+func normalizeTarget(entry string) string {
+	if _, _, err := net.SplitHostPort(entry); err == nil {
+		return entry
+	}
+	return net.JoinHostPort(strings.Trim(entry, "[]"), strconv.Itoa(targetPort))
+}
+
+// loadTargets reads SSH servers from a file, or stdin for "-", skipping
+// blanks and comments.
+func loadTargets(path string) ([]string, error) {
+	file, err := openInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	var targets []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			targets = append(targets, normalizeTarget(line))
+		}
+	}
+	return targets, scanner.Err()
+}
+
+// auditHost reads one server's offer and grades it.
+func auditHost(ctx context.Context, address string, timeout time.Duration) HostResult {
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Auditing: %s\n", address)
+	}
+	result := HostResult{Address: address}
+	offer, err := fetchServerOffer(ctx, address, timeout)
+	result.Offer = offer
+	if offer != nil {
+		debugf("%s: banner %q, %d kex, %d host key, %d cipher, %d MAC algorithms",
+			address, offer.Banner, len(offer.Kex), len(offer.HostKeys), len(offer.Ciphers), len(offer.MACs))
+		result.Findings = auditOffer(offer)
+	}
+	if err != nil {
+		result.Error = err
+		result.Status = "ERROR"
+		return result
+	}
+	result.Status = severityNames[worstSeverity(result.Findings)]
+	return result
+}
+
+// runAudits audits targets with a bounded worker pool, keeping input order.
+// If ctx is cancelled the hosts finished so far are returned with interrupted set.
+func runAudits(ctx context.Context, targets []string, timeout time.Duration) (results []HostResult, interrupted bool) {
+	slots := make([]*HostResult, len(targets))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				start := time.Now()
+				r := auditHost(ctx, targets[idx], timeout)
+				telemetry.span("audit", start, map[string]string{"target": r.Address, "status": r.Status}, r.Error)
+				if ctx.Err() != nil && r.Error != nil {
+					continue // Cut short by the interrupt
+				}
+				slots[idx] = &r
+			}
+		}()
+	}
+feed:
+	for idx := range targets {
+		select {
+		case jobs <- idx:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range slots {
+		if r != nil {
+			results = append(results, *r)
+		}
+	}
+	return results, ctx.Err() != nil
+}
+
+// writeList prints one advertised algorithm list.
+func writeList(output io.Writer, label string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintf(output, "%s: %s\n", label, strings.Join(names, ", "))
+}
+
+// writeReport writes the server and authorized_keys findings to output.
+func writeReport(hosts []HostResult, keyResults []KeyFileResult, output io.Writer) (fails, warns int) {
+	count := func(findings []Finding) {
+		for _, f := range findings {
+			switch f.Severity {
+			case sevFail:
+				fails++
+			case sevWarn:
+				warns++
+			}
+		}
+	}
+
+	if len(hosts) > 0 {
+		fmt.Fprintf(output, "--- SSH Configuration Audit Report ---\n\n")
+		fmt.Fprintln(output, "------------------------------")
+	}
+	for _, h := range hosts {
+		fmt.Fprintf(output, "Host: %s\n", h.Address)
+		fmt.Fprintf(output, "Status: %s\n", colorStatus(h.Status))
+		if h.Offer != nil {
+			fmt.Fprintf(output, "Banner: %s\n", h.Offer.Banner)
+			writeList(output, "Key Exchange", h.Offer.Kex)
+			writeList(output, "Host Key Algorithms", h.Offer.HostKeys)
+			writeList(output, "Ciphers", h.Offer.Ciphers)
+			writeList(output, "MACs", h.Offer.MACs)
+			writeList(output, "Compression", h.Offer.Compression)
+		}
+		if h.Error != nil {
+			fmt.Fprintf(output, "Error: %v\n", h.Error)
+		}
+		if len(h.Findings) > 0 {
+			fmt.Fprintln(output, "Findings:")
+			for _, f := range h.Findings {
+				fmt.Fprintf(output, "  %s\n", f)
+			}
+		}
+		count(h.Findings)
+		fmt.Fprintln(output, "------------------------------")
+	}
+
+	if len(keyResults) > 0 {
+		if len(hosts) > 0 {
+			fmt.Fprintln(output)
+		}
+		fmt.Fprintf(output, "--- authorized_keys Audit ---\n\n")
+		fmt.Fprintln(output, "------------------------------")
+	}
+	for _, k := range keyResults {
+		status := severityNames[worstSeverity(k.Findings)]
+		if k.Error != nil {
+			status = "ERROR"
+		}
+		fmt.Fprintf(output, "File: %s\n", k.Path)
+		fmt.Fprintf(output, "Status: %s\n", colorStatus(status))
+		if k.Error != nil {
+			fmt.Fprintf(output, "Error: %v\n", k.Error)
+		}
+		fmt.Fprintf(output, "Keys: %d\n", len(k.Keys))
+		for _, key := range k.Keys {
+			fmt.Fprintf(output, "  line %d: %s %d bits %s\n", key.Line, key.Type, key.Bits, key.Comment)
+		}
+		if len(k.Findings) > 0 {
+			fmt.Fprintln(output, "Findings:")
+			for _, f := range k.Findings {
+				fmt.Fprintf(output, "  %s\n", f)
+			}
+		}
+		count(k.Findings)
+		fmt.Fprintln(output, "------------------------------")
+	}
+	fmt.Fprintf(output, "Summary: %d server(s), %d key file(s), %d FAIL, %d WARN\n", len(hosts), len(keyResults), fails, warns)
+	return fails, warns
+}
+
+// main is the entry point of the SSH Audit Scanner tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	var targets []string
+	if targetHost != "" {
+		targets = append(targets, normalizeTarget(targetHost))
+	}
+	if inputFile != "" {
+		fileTargets, err := loadTargets(inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		targets = append(targets, fileTargets...)
+	}
+	if len(targets) == 0 && len(keyFiles) == 0 {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] A server (-h), a server list (-i) and/or an authorized_keys file (--authorized-keys) must be provided.")
+		os.Exit(1)
+	}
+	if err := checkFindingsMin(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --findings-min: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	startOtel()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	timeout := time.Duration(timeoutSec) * time.Second
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+
+	// SIGINT/SIGTERM stop the scan; servers audited so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if verboseMode && len(targets) > 0 {
+		fmt.Fprintf(os.Stderr, "[INFO] Auditing %d SSH server(s)...\n", len(targets))
+	}
+	hosts, interrupted := runAudits(ctx, targets, timeout)
+
+	keyPaths := expandKeyFiles(keyFiles)
+	var keyResults []KeyFileResult
+	seen := map[string]authorizedKey{}
+	for _, path := range keyPaths {
+		if interrupted {
+			break
+		}
+		start := time.Now()
+		r := auditKeyFile(path, seen)
+		telemetry.span("audit_keys", start, map[string]string{"target": path, "status": severityNames[worstSeverity(r.Findings)]}, r.Error)
+		if r.Error != nil {
+			warnf("Failed to read %s: %v", path, r.Error)
+		}
+		keyResults = append(keyResults, r)
+	}
+
+	fails, _ := writeReport(hosts, keyResults, output)
+	if selfStatsOn {
+		writeSelfStats(output, collectSelfStats(len(hosts), "servers"))
+	}
+	findings := normalizedFindings(hosts, keyResults)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	inputs := append([]string{inputFile}, keyPaths...)
+	outputs := []string{outputFile, findingsPath}
+	if interrupted {
+		stop()
+		fmt.Fprintf(output, "Partial report: interrupted after %d of %d servers.\n", len(hosts), len(targets))
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		telemetry.finish(130)
+		writeManifest(130, inputs, outputs)
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] SSH audit complete.")
+	}
+	if fails > 0 {
+		telemetry.finish(1)
+		writeManifest(1, inputs, outputs)
+		os.Exit(1)
+	}
+	telemetry.finish(0)
+	writeManifest(0, inputs, outputs)
+	os.Exit(0)
+}
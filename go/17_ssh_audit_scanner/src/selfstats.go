@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// --self-stats adds the tool's own resource usage to its report, so a
+// recurring job shows when a new version (or a grown input) makes the tool
+// slower or hungrier.
+//
+// Every tool carries an identical copy of this file (scripts/check_shared_go.py).
+var (
+	selfStatsOn    bool
+	peakGoroutines atomic.Int64
+)
+
+type selfStats struct {
+	RuntimeSeconds float64 `json:"runtime_seconds"`
+	PeakRSSBytes   int64   `json:"peak_rss_bytes,omitempty"` // Linux only (VmHWM)
+	Goroutines     int     `json:"goroutines"`
+	PeakGoroutines int     `json:"peak_goroutines"`
+	Items          int     `json:"items"`
+	Unit           string  `json:"unit"`
+	PerSecond      float64 `json:"per_second"`
+}
+
+func registerSelfStatsFlag() {
+	flag.BoolVar(&selfStatsOn, "self-stats", false, "Add the tool's own resource usage to the report: peak RSS, goroutines, runtime and throughput.")
+}
+
+// startSelfStats samples the goroutine count in the background while the
+// tool runs. The peak RSS needs no sampling; the kernel keeps it.
+func startSelfStats() {
+	if !selfStatsOn {
+		return
+	}
+	go func() {
+		for {
+			notePeakGoroutines()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+}
+
+func notePeakGoroutines() int {
+	n := runtime.NumGoroutine()
+	for {
+		peak := peakGoroutines.Load()
+		if int64(n) <= peak || peakGoroutines.CompareAndSwap(peak, int64(n)) {
+			return n
+		}
+	}
+}
+
+// collectSelfStats measures the run so far; items is the amount of work
+// done (files, hosts, requests...) in the given unit.
+func collectSelfStats(items int, unit string) selfStats {
+	s := selfStats{
+		RuntimeSeconds: time.Since(runStarted).Seconds(),
+		PeakRSSBytes:   peakRSS(),
+		Goroutines:     notePeakGoroutines(),
+		PeakGoroutines: int(peakGoroutines.Load()),
+		Items:          items,
+		Unit:           unit,
+	}
+	if s.RuntimeSeconds > 0 {
+		s.PerSecond = float64(items) / s.RuntimeSeconds
+	}
+	return s
+}
+
+// peakRSS returns the high-water mark of the resident set size from
+// /proc/self/status, or 0 where that is not available.
+func peakRSS() int64 {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "VmHWM:"); ok {
+			kb, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "kB")), 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// writeSelfStats appends the text-report block.
+func writeSelfStats(output io.Writer, s selfStats) {
+	rss := "n/a"
+	if s.PeakRSSBytes > 0 {
+		rss = fmt.Sprintf("%.1f MiB", float64(s.PeakRSSBytes)/(1<<20))
+	}
+	fmt.Fprintln(output, "--- Self Stats ---")
+	fmt.Fprintf(output, "Runtime: %s\n", time.Duration(s.RuntimeSeconds*float64(time.Second)).Round(time.Millisecond))
+	fmt.Fprintf(output, "Peak RSS: %s\n", rss)
+	fmt.Fprintf(output, "Goroutines: %d (peak %d)\n", s.Goroutines, s.PeakGoroutines)
+	fmt.Fprintf(output, "Throughput: %d %s (%.1f/s)\n", s.Items, s.Unit, s.PerSecond)
+}
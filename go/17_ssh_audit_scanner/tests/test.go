@@ -0,0 +1,11 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPlaceholder(t *testing.T) {
+	// This is a placeholder test to ensure the Go test runner can find and execute tests.
+	// Actual tests would replay canned SSH banners and KEXINIT packets and parse sample authorized_keys files.
+	t.Log("Placeholder Go test executed successfully.")
+}
@@ -0,0 +1 @@
+API_KEY = "k9Jd8Hs7Gf6Ds5Aa4Qw3Ee2"
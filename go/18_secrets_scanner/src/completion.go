@@ -0,0 +1,196 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// "<tool> completion bash|zsh|fish" prints a completion script generated
+// from the registered flags, so it never falls out of date. The script
+// completes the command name toolName, which is what the build example in
+// docs/GETTING_STARTED.md produces, written <tool> here:
+//
+//	source <(<tool> completion bash)
+//	<tool> completion zsh > "${fpath[1]}/_<tool>"
+//	<tool> completion fish > ~/.config/fish/completions/<tool>.fish
+//
+// Every tool carries an identical copy of this file (scripts/check_shared_go.py).
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// subcommand is a mode chosen by the first argument instead of flags, such
+// as "completion". The completion scripts offer every registered subcommand,
+// and complete file names after any but "completion".
+type subcommand struct {
+	Name  string
+	Usage string
+	Run   func(args []string, w io.Writer) int // Returns the exit status
+}
+
+// subcommands are the tool's subcommands; a file that adds one registers it
+// in its init function (see signing.go).
+var subcommands []subcommand
+
+func init() {
+	subcommands = append(subcommands, subcommand{Name: "completion", Usage: "Print a shell completion script", Run: runCompletion})
+}
+
+// runSubcommand runs the subcommand named by the first argument, if any, and
+// exits with its status.
+func runSubcommand() {
+	if len(os.Args) < 2 {
+		return
+	}
+	for _, c := range subcommands {
+		if c.Name == os.Args[1] {
+			os.Exit(c.Run(os.Args[2:], os.Stdout))
+		}
+	}
+}
+
+// subcommandNames returns the registered subcommands, and those of them that
+// take file names, as space-separated lists.
+func subcommandNames() (all, withFiles string) {
+	var names, files []string
+	for _, c := range subcommands {
+		names = append(names, c.Name)
+		if c.Name != "completion" {
+			files = append(files, c.Name)
+		}
+	}
+	return strings.Join(names, " "), strings.Join(files, " ")
+}
+
+// completionFlag is one registered flag as the completion scripts see it.
+type completionFlag struct {
+	Name     string
+	Usage    string // First sentence of the flag's usage text
+	TakesArg bool
+}
+
+// dashed returns the flag as users type it: -x for one letter, --name otherwise.
+func (f completionFlag) dashed() string {
+	if len(f.Name) == 1 {
+		return "-" + f.Name
+	}
+	return "--" + f.Name
+}
+
+func completionFlags() []completionFlag {
+	var flags []completionFlag
+	flag.VisitAll(func(f *flag.Flag) {
+		usage, _, _ := strings.Cut(f.Usage, ". ")
+		usage = strings.TrimSuffix(strings.ReplaceAll(usage, "\n", " "), ".")
+		b, isBool := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{Name: f.Name, Usage: usage, TakesArg: !(isBool && b.IsBoolFlag())})
+	})
+	return flags
+}
+
+// runCompletion handles the completion mode and returns the exit status.
+func runCompletion(args []string, w io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "[ERROR] Usage: %s completion %s\n", toolName, strings.Join(completionShells, "|"))
+		return 2
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(w, completionFlags())
+	case "zsh":
+		writeZshCompletion(w, completionFlags())
+	case "fish":
+		writeFishCompletion(w, completionFlags())
+	default:
+		fmt.Fprintf(os.Stderr, "[ERROR] Unsupported shell %q (use %s)\n", args[0], strings.Join(completionShells, ", "))
+		return 2
+	}
+	return 0
+}
+
+func writeBashCompletion(w io.Writer, flags []completionFlag) {
+	var all, withArg []string
+	for _, f := range flags {
+		all = append(all, f.dashed())
+		if f.TakesArg {
+			withArg = append(withArg, "-"+f.Name, "--"+f.Name) // Go flags accept both -name and --name
+		}
+	}
+	names, files := subcommandNames()
+	fn := "_" + toolName
+	fmt.Fprintf(w, "# bash completion for %s\n", toolName)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintln(w, `	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, `	if [[ $COMP_CWORD -eq 2 && $prev == completion ]]; then`)
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(completionShells, " "))
+	fmt.Fprintln(w, "\t\treturn")
+	fmt.Fprintln(w, "\tfi")
+	if files != "" {
+		fmt.Fprintf(w, "\tif [[ $COMP_CWORD -ge 2 && \" %s \" == *\" ${COMP_WORDS[1]} \"* ]]; then\n", files)
+		fmt.Fprintln(w, `		COMPREPLY=($(compgen -f -- "$cur"))`)
+		fmt.Fprintln(w, "\t\treturn")
+		fmt.Fprintln(w, "\tfi")
+	}
+	if len(withArg) > 0 {
+		fmt.Fprintln(w, `	case "$prev" in`)
+		fmt.Fprintf(w, "\t%s)\n", strings.Join(withArg, "|"))
+		fmt.Fprintln(w, `		COMPREPLY=($(compgen -f -- "$cur"))`)
+		fmt.Fprintln(w, "\t\treturn")
+		fmt.Fprintln(w, "\t\t;;")
+		fmt.Fprintln(w, "\tesac")
+	}
+	fmt.Fprintln(w, `	if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then`)
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", names)
+	fmt.Fprintln(w, "\t\treturn")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintf(w, "\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(all, " "))
+	fmt.Fprintln(w, "}")
+	fmt.Fprintf(w, "complete -o default -F %s %s\n", fn, toolName)
+}
+
+func writeZshCompletion(w io.Writer, flags []completionFlag) {
+	escape := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`)
+	fmt.Fprintf(w, "#compdef %s\n\n", toolName)
+	fmt.Fprintln(w, "_arguments \\")
+	for _, f := range flags {
+		if f.TakesArg {
+			fmt.Fprintf(w, "  '%s=[%s]:%s:_files' \\\n", f.dashed(), escape.Replace(f.Usage), f.Name)
+		} else {
+			fmt.Fprintf(w, "  '%s[%s]' \\\n", f.dashed(), escape.Replace(f.Usage))
+		}
+	}
+	names, _ := subcommandNames()
+	fmt.Fprintf(w, "  '1:mode:(%s)' \\\n", names)
+	fmt.Fprintln(w, "  '*:: :->args'")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "if [[ $state == args ]]; then")
+	fmt.Fprintln(w, "  case $words[1] in")
+	fmt.Fprintf(w, "    completion) _values shell %s ;;\n", strings.Join(completionShells, " "))
+	fmt.Fprintln(w, "    *) _files ;;")
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w, "fi")
+}
+
+func writeFishCompletion(w io.Writer, flags []completionFlag) {
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	fmt.Fprintf(w, "# fish completion for %s\n", toolName)
+	fmt.Fprintf(w, "complete -c %s -f\n", toolName)
+	for _, c := range subcommands {
+		fmt.Fprintf(w, "complete -c %s -n '__fish_use_subcommand' -a %s -d '%s'\n", toolName, c.Name, escape.Replace(c.Usage))
+	}
+	fmt.Fprintf(w, "complete -c %s -n '__fish_seen_subcommand_from completion' -a '%s'\n", toolName, strings.Join(completionShells, " "))
+	if _, files := subcommandNames(); files != "" {
+		fmt.Fprintf(w, "complete -c %s -n '__fish_seen_subcommand_from %s' -F\n", toolName, files)
+	}
+	for _, f := range flags {
+		opt := "-l " + f.Name
+		if len(f.Name) == 1 {
+			opt = "-s " + f.Name
+		}
+		if f.TakesArg {
+			opt += " -r -F"
+		}
+		fmt.Fprintf(w, "complete -c %s %s -d '%s'\n", toolName, opt, escape.Replace(f.Usage))
+	}
+}
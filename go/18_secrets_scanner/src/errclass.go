@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Error classes shared by the Go tools. JSON outputs carry one next to each
+// error message (as "error_class"), so automation can branch on the kind of
+// failure instead of parsing messages, whose wording varies by platform and
+// Go version.
+// Every tool carries an identical copy of this file (scripts/check_shared_go.py).
+const (
+	errClassDNS        = "DNS_FAILURE"       // The name did not resolve
+	errClassTimeout    = "TIMEOUT"           // A deadline or timeout was reached
+	errClassRefused    = "CONN_REFUSED"      // Nothing listening (TCP RST)
+	errClassTLS        = "TLS_ERROR"         // Handshake, alert or certificate verification failure
+	errClassPermission = "PERMISSION_DENIED" // File or socket permissions
+	errClassIO         = "IO_ERROR"          // Any other file or network I/O failure
+	errClassOther      = "OTHER"             // Everything else, e.g. protocol or parse errors
+)
+
+// classifyError returns the error class of err, or "" for a nil error.
+func classifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var recordErr tls.RecordHeaderError
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var pathErr *fs.PathError
+	var opErr *net.OpError
+	var errno syscall.Errno
+	msg := err.Error()
+	switch {
+	case errors.As(err, &dnsErr):
+		return errClassDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return errClassRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errClassTimeout
+	case errors.As(err, &recordErr), errors.As(err, &verifyErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr),
+		strings.Contains(msg, "tls: "), strings.Contains(msg, "x509: "): // Alerts are unexported types
+		return errClassTLS
+	case errors.Is(err, fs.ErrPermission):
+		return errClassPermission
+	case errors.As(err, &pathErr), errors.As(err, &opErr), errors.As(err, &errno),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return errClassIO
+	}
+	return errClassOther
+}
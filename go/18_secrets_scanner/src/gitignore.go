@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// --respect-gitignore: .gitignore files found while walking a directory
+// exclude the paths they match, following git's rules: patterns are relative
+// to the directory of their .gitignore, a pattern without a slash matches a
+// name at any depth, a trailing slash matches directories only, "**" spans
+// directories, "!" re-includes, and the last matching pattern wins. As in
+// git, a file inside an excluded directory cannot be re-included.
+
+// ignoreRule is one .gitignore pattern, split into path segments.
+type ignoreRule struct {
+	segs    []string
+	negate  bool
+	dirOnly bool
+}
+
+// gitignore holds the rules of every .gitignore loaded so far, by directory.
+// It is shared by the goroutines of a --walk-workers walk.
+type gitignore struct {
+	mu    sync.RWMutex
+	rules map[string][]ignoreRule
+}
+
+func newGitignore() *gitignore {
+	return &gitignore{rules: map[string][]ignoreRule{}}
+}
+
+// load reads dir/.gitignore, if there is one.
+func (g *gitignore) load(dir string) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	var rules []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if r, ok := parseIgnoreRule(sc.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	g.mu.Lock()
+	g.rules[dir] = rules
+	g.mu.Unlock()
+	debugf("loaded %d .gitignore rule(s) from %s", len(rules), dir)
+}
+
+// parseIgnoreRule parses one .gitignore line; ok is false for blank lines
+// and comments.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var r ignoreRule
+	// Trailing spaces are dropped unless escaped with a backslash.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return r, false
+	}
+	if line[0] == '!' {
+		r.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, "\\#") || strings.HasPrefix(line, "\\!") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return r, false
+	}
+	// A pattern with no slash other than a trailing one matches at any depth.
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+	r.segs = strings.Split(strings.TrimPrefix(line, "/"), "/")
+	return r, true
+}
+
+// ignored reports whether p, found while walking root, is excluded by the
+// .gitignore files of root and the directories between root and p.
+func (g *gitignore) ignored(root, p string, isDir bool) bool {
+	if isDir && filepath.Base(p) == ".git" {
+		return true // Repository metadata is never part of the tree
+	}
+	var dirs []string
+	for d := filepath.Dir(p); ; d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+		if d == root || d == filepath.Dir(d) {
+			break
+		}
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	ignored := false
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(dirs[i], p)
+		if err != nil {
+			continue
+		}
+		segs := strings.Split(filepath.ToSlash(rel), "/")
+		for _, r := range g.rules[dirs[i]] {
+			if (!r.dirOnly || isDir) && matchSegments(r.segs, segs) {
+				ignored = !r.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// stands for any number of directories. A trailing "**" needs at least one
+// segment: "build/**" matches what is inside build, not build itself.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return len(name) > 0
+		}
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
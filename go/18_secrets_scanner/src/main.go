@@ -0,0 +1,396 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of a Secrets Scanner for code and configuration trees.
+PURPOSE: Show skill in pattern matching, entropy analysis, file system traversal, SARIF reporting, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "secrets_scanner"
+	toolVersion = "1.0.0"
+)
+
+// Global variables for CLI flags
+var (
+	pathArg       string
+	inputFile     string
+	outputFile    string
+	format        string
+	allowlistFile string
+	baselinePath  string
+	writeBaseTo   string
+	maxFileSize   int64
+	verboseMode   bool
+)
+
+// Finding is one suspected secret.
+type Finding struct {
+	Rule        *secretRule
+	Path        string
+	Line        int
+	Column      int
+	Secret      string
+	Entropy     float64
+	Fingerprint string
+}
+
+// scanStats counts what was scanned and what was suppressed.
+type scanStats struct {
+	Files, Skipped, Allowlisted, Baselined int
+}
+
+func init() {
+	// Version control metadata and vendored dependency trees are large and
+	// rarely hold the project's own secrets. Symbolic links are left out, as
+	// their targets are scanned where they live, and an unreadable entry does
+	// not stop the scan.
+	skipDirs = map[string]bool{".git": true, ".hg": true, ".svn": true, "node_modules": true, "vendor": true}
+	skipUnreadable, regularOnly = true, true
+
+	flag.StringVar(&pathArg, "path", ".", "File or directory to scan. Used if -i is not specified.")
+	flag.StringVar(&pathArg, "p", ".", "File or directory to scan (shorthand).")
+
+	flag.StringVar(&inputFile, "input", "", "Path to a file listing files/directories to scan (one per line, same format as the file integrity monitor's -i).")
+	flag.StringVar(&inputFile, "i", "", "Path to a file listing files/directories to scan (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or sarif.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&allowlistFile, "allowlist", "", "Path to an allowlist: path:<glob>, rule:<id> or a regular expression matching secrets to ignore, one per line.")
+	flag.StringVar(&baselinePath, "baseline", "", "Path to a baseline file; findings recorded in it are suppressed.")
+	flag.StringVar(&writeBaseTo, "write-baseline", "", "Write the current findings to this baseline file (accepting them for future scans).")
+
+	flag.Int64Var(&maxFileSize, "max-size", 1<<20, "Skip files larger than this many bytes.")
+	flag.BoolVar(&respectGitignore, "respect-gitignore", false, "Skip paths excluded by .gitignore files found while walking a directory.")
+	flag.IntVar(&walkWorkers, "walk-workers", 1, "Directories listed concurrently while walking --path or -i directories; raise it for network file systems such as NFS.")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Scans source and configuration files for hard-coded secrets.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s -p ./repo\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -p ./repo --allowlist .secrets-allowlist --baseline .secrets-baseline.json -f sarif -o secrets.sarif\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// displayPath shows paths relative to the working directory where possible,
+// which keeps reports and baseline fingerprints portable between checkouts.
+func displayPath(abs string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return abs
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return abs
+	}
+	return rel
+}
+
+// redact shows enough of a secret to recognize it without disclosing it.
+// Private key headers carry no secret material and are shown as is.
+func redact(f Finding) string {
+	if f.Rule.Group == 0 {
+		return f.Secret
+	}
+	if len(f.Secret) <= 8 {
+		return "****"
+	}
+	return f.Secret[:4] + strings.Repeat("*", 8)
+}
+
+// scanFile matches every rule against each line of a file.
+func scanFile(path string, allow *allowlist, stats *scanStats) ([]Finding, error) {
+	shown := displayPath(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxFileSize {
+		debugf("Skipping %s: %d bytes exceeds --max-size", shown, info.Size())
+		stats.Skipped++
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		debugf("Skipping binary file %s", shown)
+		stats.Skipped++
+		return nil, nil
+	}
+	stats.Files++
+
+	var findings []Finding
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1) // Minified files can be a single long line
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if strings.Contains(line, inlineAllowMarker) {
+			continue
+		}
+		// Rules run from most to least specific; a span already claimed
+		// (e.g. an AWS key) is not reported again as a generic secret.
+		var claimed [][2]int
+		overlaps := func(start, end int) bool {
+			for _, c := range claimed {
+				if start < c[1] && c[0] < end {
+					return true
+				}
+			}
+			return false
+		}
+		for i := range secretRules {
+			rule := &secretRules[i]
+			for _, m := range rule.Pattern.FindAllStringSubmatchIndex(line, -1) {
+				start, end := m[2*rule.Group], m[2*rule.Group+1]
+				if start < 0 || overlaps(start, end) {
+					continue
+				}
+				claimed = append(claimed, [2]int{start, end})
+				f := Finding{Rule: rule, Path: shown, Line: lineNo, Column: start + 1, Secret: line[start:end]}
+				f.Entropy = shannonEntropy(f.Secret)
+				if rule.MinEntropy > 0 && f.Entropy < rule.MinEntropy {
+					debugf("%s:%d: %s match below entropy threshold (%.2f)", shown, lineNo, rule.ID, f.Entropy)
+					continue
+				}
+				if allow.allows(f) {
+					stats.Allowlisted++
+					continue
+				}
+				f.Fingerprint = fingerprint(rule.ID, shown, f.Secret)
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings, scanner.Err()
+}
+
+// severityRank orders the report: most severe first.
+var severityRank = map[string]int{"HIGH": 0, "MEDIUM": 1, "LOW": 2}
+
+// writeReport writes the findings as a text report.
+func writeReport(findings []Finding, stats scanStats, output io.Writer) {
+	fmt.Fprintf(output, "--- Secrets Scan Report ---\n\n")
+	fmt.Fprintf(output, "Files Scanned: %d\n", stats.Files)
+	if stats.Skipped > 0 {
+		fmt.Fprintf(output, "Files Skipped (binary or oversized): %d\n", stats.Skipped)
+	}
+	fmt.Fprintf(output, "Findings: %d\n", len(findings))
+	if stats.Allowlisted+stats.Baselined > 0 {
+		fmt.Fprintf(output, "Suppressed: %d by allowlist, %d by baseline\n", stats.Allowlisted, stats.Baselined)
+	}
+	fmt.Fprintln(output, "------------------------------")
+	for _, f := range findings {
+		fmt.Fprintf(output, "Severity: %s\n", colorStatus(f.Rule.Severity))
+		fmt.Fprintf(output, "Rule: %s (%s)\n", f.Rule.ID, f.Rule.Description)
+		fmt.Fprintf(output, "Location: %s:%d:%d\n", f.Path, f.Line, f.Column)
+		fmt.Fprintf(output, "Secret: %s\n", redact(f))
+		if f.Rule.MinEntropy > 0 {
+			fmt.Fprintf(output, "Entropy: %.2f bits/char\n", f.Entropy)
+		}
+		fmt.Fprintf(output, "Fingerprint: %s\n", f.Fingerprint)
+		fmt.Fprintln(output, "------------------------------")
+	}
+	if len(findings) == 0 {
+		fmt.Fprintf(output, "Status: %s\n", colorStatus("CLEAN"))
+	}
+}
+
+// main is the entry point of the Secrets Scanner tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	if format != "text" && format != "sarif" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Unsupported report format: %s (expected text or sarif)\n", format)
+		os.Exit(1)
+	}
+	if walkWorkers < 1 {
+		fmt.Fprintln(os.Stderr, "[ERROR] --walk-workers must be at least 1")
+		os.Exit(1)
+	}
+	if signKeyPath != "" && format != "sarif" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs SARIF reports; add -f sarif.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	allow, err := loadAllowlist(allowlistFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	known, err := loadBaseline(baselinePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+
+	var list []string
+	baseDir := ""
+	if inputFile != "" {
+		lines, err := os.ReadFile(inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to open input file %s: %v\n", inputFile, err)
+			os.Exit(1)
+		}
+		for _, l := range strings.Split(string(lines), "\n") {
+			if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "#") {
+				list = append(list, filepath.Clean(l))
+			}
+		}
+		baseDir = filepath.Dir(inputFile)
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+	inputs := []string{inputFile, allowlistFile, baselinePath}
+	outputs := []string{outputFile, signatureTarget(outputFile), writeBaseTo}
+
+	// SIGINT/SIGTERM stop the scan between files; findings so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	files, err := collectFiles(ctx, []string{pathArg}, list, baseDir)
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "[ERROR] Interrupted while collecting files; nothing was scanned.")
+		os.Exit(130)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to collect files: %v\n", err)
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Scanning %d file(s) for secrets...\n", len(files))
+	}
+
+	var findings []Finding
+	var stats scanStats
+	scanned := 0
+	for _, path := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		scanned++
+		if allow.skipsPath(displayPath(path)) {
+			debugf("Allowlisted path %s", displayPath(path))
+			continue
+		}
+		found, err := scanFile(path, allow, &stats)
+		if err != nil {
+			warnf("Failed to scan %s: %v", displayPath(path), err)
+			continue
+		}
+		for _, f := range found {
+			if known[f.Fingerprint] {
+				stats.Baselined++
+				continue
+			}
+			findings = append(findings, f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if severityRank[a.Rule.Severity] != severityRank[b.Rule.Severity] {
+			return severityRank[a.Rule.Severity] < severityRank[b.Rule.Severity]
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+
+	if format == "sarif" {
+		if err := writeSARIF(findings, output); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write SARIF report: %v\n", err)
+		}
+	} else {
+		writeReport(findings, stats, output)
+		if selfStatsOn {
+			writeSelfStats(output, collectSelfStats(scanned, "files"))
+		}
+	}
+
+	if ctx.Err() != nil {
+		stop()
+		if format == "text" {
+			fmt.Fprintf(output, "Partial report: interrupted after %d of %d files.\n", scanned, len(files))
+		}
+		warnf("Interrupted by signal; partial report written (baseline not updated).")
+		closeSink(output)
+		writeManifest(130, inputs, outputs)
+		os.Exit(130)
+	}
+	if writeBaseTo != "" {
+		if err := writeBaseline(writeBaseTo, findings); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write baseline %s: %v\n", writeBaseTo, err)
+			os.Exit(1)
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Baseline with %d finding(s) written to %s\n", len(findings), writeBaseTo)
+		}
+	}
+	if !closeSink(output) {
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Secrets scan complete.")
+	}
+	if len(findings) > 0 && writeBaseTo == "" {
+		writeManifest(1, inputs, outputs)
+		os.Exit(1)
+	}
+	writeManifest(0, inputs, outputs)
+	os.Exit(0)
+}
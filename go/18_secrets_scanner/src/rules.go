@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"regexp"
+)
+
+// secretRule detects one kind of hard-coded credential.
+type secretRule struct {
+	ID          string
+	Description string
+	Severity    string // HIGH, MEDIUM or LOW
+	Pattern     *regexp.Regexp
+	// Group is the capture group holding the secret itself (0 = whole match).
+	Group int
+	// MinEntropy rejects low-randomness matches such as "password" or
+	// "changeme"; 0 disables the check.
+	MinEntropy float64
+}
+
+// secretRules are matched line by line against every scanned file.
+var secretRules = []secretRule{
+	{"aws-access-key-id", "AWS access key ID", "HIGH",
+		regexp.MustCompile(`\b((?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16})\b`), 1, 0},
+	{"aws-secret-access-key", "AWS secret access key", "HIGH",
+		regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private)?.{0,20}?(?:key|token).{0,5}?['"]?\s*[:=]\s*['"]?([A-Za-z0-9/+]{40})\b`), 1, 4.0},
+	{"private-key", "Private key block", "HIGH",
+		regexp.MustCompile(`-----BEGIN (?:RSA |DSA |EC |OPENSSH |ENCRYPTED |PGP )?PRIVATE KEY(?: BLOCK)?-----`), 0, 0},
+	{"github-token", "GitHub token", "HIGH",
+		regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{82})\b`), 1, 0},
+	{"slack-token", "Slack token", "HIGH",
+		regexp.MustCompile(`\b(xox[abprs]-[0-9A-Za-z-]{10,})\b`), 1, 0},
+	{"jwt", "JSON Web Token", "MEDIUM",
+		regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`), 1, 0},
+	{"password-in-url", "Password embedded in a URL", "MEDIUM",
+		regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@'"]+:([^/\s:@'"]{3,})@[^\s'"/]+`), 1, 0},
+	{"generic-secret", "Password or secret assignment", "LOW",
+		regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)['"]?\s*[:=]\s*['"]([^'"\s]{8,})['"]`), 1, 3.5},
+}
+
+// shannonEntropy returns the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// ruleByID returns the rule with the given ID, or nil.
+func ruleByID(id string) *secretRule {
+	for i := range secretRules {
+		if secretRules[i].ID == id {
+			return &secretRules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// Minimal SARIF 2.1.0 output, enough for GitHub code scanning and other
+// SARIF viewers to show each finding at its file and line.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID                   string    `json:"id"`
+	ShortDescription     sarifText `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifText         `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+// sarifLevel maps report severities to SARIF result levels.
+var sarifLevel = map[string]string{"HIGH": "error", "MEDIUM": "warning", "LOW": "note"}
+
+// writeSARIF writes findings as a SARIF log. Secrets appear only redacted.
+func writeSARIF(findings []Finding, output io.Writer) error {
+	driver := sarifDriver{Name: toolName, Version: toolVersion}
+	for _, r := range secretRules {
+		rule := sarifRule{ID: r.ID, ShortDescription: sarifText{r.Description}}
+		rule.DefaultConfiguration.Level = sarifLevel[r.Severity]
+		driver.Rules = append(driver.Rules, rule)
+	}
+	run := sarifRun{Tool: sarifTool{driver}, Results: []sarifResult{}}
+	for _, f := range findings {
+		res := sarifResult{
+			RuleID:              f.Rule.ID,
+			Level:               sarifLevel[f.Rule.Severity],
+			Message:             sarifText{fmt.Sprintf("%s found: %s", f.Rule.Description, redact(f))},
+			PartialFingerprints: map[string]string{"secretHash/v1": f.Fingerprint},
+		}
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(f.Path)
+		if filepath.IsAbs(f.Path) {
+			loc.PhysicalLocation.ArtifactLocation.URI = "file://" + loc.PhysicalLocation.ArtifactLocation.URI
+		}
+		loc.PhysicalLocation.Region.StartLine = f.Line
+		loc.PhysicalLocation.Region.StartColumn = f.Column
+		res.Locations = []sarifLocation{loc}
+		run.Results = append(run.Results, res)
+	}
+	enc := json.NewEncoder(output)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
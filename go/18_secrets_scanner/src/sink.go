@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report destinations. The -o value selects the sink:
+//
+//	(empty) or -                  stdout
+//	report.txt                    local file
+//	https://collector/reports     HTTP POST of the finished report
+//	s3://bucket/path/report.txt   upload to S3 or an S3-compatible store
+//
+// Remote sinks buffer the report and deliver it when closed, so a report is
+// only uploaded once it is complete (or cut short by an interrupt).
+//
+// HTTP sinks send OUTPUT_AUTHORIZATION, if set, as the Authorization header.
+// S3 sinks read AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
+// and AWS_REGION (default us-east-1); AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL
+// point them at an S3-compatible service such as MinIO (path-style URLs).
+//
+// Every tool carries an identical copy of this file (scripts/check_shared_go.py).
+
+// OutputSink is where a report is written.
+type OutputSink interface {
+	io.Writer
+	// Close finishes the report: closes the file or delivers the upload.
+	Close() error
+	// Name describes the destination for messages and run manifests.
+	Name() string
+}
+
+// Hooks a tool may set to change how reports are stored (the file integrity
+// monitor's --worm sets both); nil keeps the defaults.
+var (
+	// createLocalSink replaces os.Create for local report files.
+	createLocalSink func(target string) (OutputSink, error)
+	// prepareS3Upload adds headers to an S3 upload before it is signed.
+	prepareS3Upload func(req *http.Request, body []byte)
+)
+
+// deliveredOutputs remembers what remote sinks uploaded, so run manifests can
+// hash reports that never existed as local files.
+var deliveredOutputs = map[string]manifestFile{}
+
+// openSink returns the sink for an -o value.
+func openSink(target string) (OutputSink, error) {
+	switch {
+	case target == "" || target == "-":
+		return stdoutSink{}, nil
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		if _, err := url.ParseRequestURI(target); err != nil {
+			return nil, fmt.Errorf("invalid output URL %s: %w", target, err)
+		}
+		return &httpSink{endpoint: target}, nil
+	case strings.HasPrefix(target, "s3://"):
+		u, err := url.Parse(target)
+		if err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+			return nil, fmt.Errorf("invalid S3 output %s: expected s3://bucket/key", target)
+		}
+		if os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
+			return nil, fmt.Errorf("S3 output needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+		return &s3Sink{target: target, bucket: u.Host, key: strings.TrimPrefix(u.Path, "/")}, nil
+	}
+	if createLocalSink != nil {
+		return createLocalSink(target)
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return nil, err
+	}
+	return fileSink{f}, nil
+}
+
+// sinkFile returns the file behind a sink, for terminal detection.
+func sinkFile(s OutputSink) *os.File {
+	switch s := s.(type) {
+	case stdoutSink:
+		return os.Stdout
+	case interface{ file() *os.File }:
+		return s.file()
+	}
+	return nil
+}
+
+// closeSink finishes the report and reports delivery failures.
+func closeSink(s OutputSink) bool {
+	if err := s.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to deliver report to %s: %v\n", s.Name(), err)
+		return false
+	}
+	return true
+}
+
+type stdoutSink struct{}
+
+func (stdoutSink) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdoutSink) Close() error                { return nil }
+func (stdoutSink) Name() string                { return "stdout" }
+
+type fileSink struct{ *os.File }
+
+func (f fileSink) Name() string   { return f.File.Name() }
+func (f fileSink) file() *os.File { return f.File }
+
+// httpSink POSTs the buffered report when closed.
+type httpSink struct {
+	endpoint string
+	buf      bytes.Buffer
+}
+
+func (h *httpSink) Write(p []byte) (int, error) { return h.buf.Write(p) }
+func (h *httpSink) Name() string                { return h.endpoint }
+
+func (h *httpSink) Close() error {
+	req, err := http.NewRequest(http.MethodPost, h.endpoint, bytes.NewReader(h.buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", reportContentType(h.endpoint))
+	if auth := os.Getenv("OUTPUT_AUTHORIZATION"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	if err := deliver(req); err != nil {
+		return err
+	}
+	recordDelivery(h.endpoint, h.buf.Bytes())
+	return nil
+}
+
+// s3Sink uploads the buffered report with a SigV4-signed PUT when closed.
+type s3Sink struct {
+	target, bucket, key string
+	buf                 bytes.Buffer
+}
+
+func (s *s3Sink) Write(p []byte) (int, error) { return s.buf.Write(p) }
+func (s *s3Sink) Name() string                { return s.target }
+
+func (s *s3Sink) Close() error {
+	region := s3Region()
+	body := s.buf.Bytes()
+	req, err := http.NewRequest(http.MethodPut, s3ObjectURL(s.bucket, s.key, region), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", reportContentType(s.key))
+	if prepareS3Upload != nil {
+		prepareS3Upload(req, body)
+	}
+	signS3Request(req, body, region, time.Now().UTC())
+	if err := deliver(req); err != nil {
+		return err
+	}
+	recordDelivery(s.target, body)
+	return nil
+}
+
+// fetchS3 downloads an s3://bucket/key object, such as a baseline kept in a
+// bucket, with a SigV4-signed GET.
+func fetchS3(target string) ([]byte, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("invalid S3 location %s: expected s3://bucket/key", target)
+	}
+	region := s3Region()
+	req, err := http.NewRequest(http.MethodGet, s3ObjectURL(u.Host, strings.TrimPrefix(u.Path, "/"), region), nil)
+	if err != nil {
+		return nil, err
+	}
+	signS3Request(req, nil, region, time.Now().UTC())
+	resp, err := (&http.Client{Timeout: 60 * time.Second}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("HTTP %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	recordDelivery(target, data) // Hashed into run manifests like an upload
+	return data, nil
+}
+
+func s3Region() string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return "us-east-1"
+}
+
+// s3ObjectURL addresses an object on AWS (virtual-hosted style) or, with
+// AWS_ENDPOINT_URL(_S3) set, on a compatible store (path style).
+func s3ObjectURL(bucket, key, region string) string {
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint != "" {
+		return strings.TrimRight(endpoint, "/") + "/" + bucket + "/" + s3EscapePath(key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, s3EscapePath(key))
+}
+
+func deliver(req *http.Request) error {
+	resp, err := (&http.Client{Timeout: 60 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func recordDelivery(name string, body []byte) {
+	sum := sha256.Sum256(body)
+	deliveredOutputs[name] = manifestFile{Path: name, Size: int64(len(body)), SHA256: hex.EncodeToString(sum[:])}
+}
+
+func reportContentType(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".json"):
+		return "application/json"
+	case strings.HasSuffix(name, ".html"):
+		return "text/html; charset=utf-8"
+	case strings.HasSuffix(name, ".csv"):
+		return "text/csv"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// s3EscapePath percent-encodes an object key as SigV4 expects, keeping "/".
+func s3EscapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// signS3Request adds AWS Signature Version 4 headers for an S3 request.
+func signS3Request(req *http.Request, body []byte, region string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadSum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payloadSum[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	// Sign the host, the content headers and every x-amz-* header set, such
+	// as those added by prepareS3Upload.
+	headers := []string{"host"}
+	values := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		h := strings.ToLower(name)
+		if h == "content-type" || h == "content-md5" || strings.HasPrefix(h, "x-amz-") {
+			headers = append(headers, h)
+			values[h] = req.Header.Get(name)
+		}
+	}
+	sort.Strings(headers)
+	var canonicalHeaders strings.Builder
+	for _, h := range headers {
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(values[h]) + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestSum := sha256.Sum256([]byte(canonicalRequest))
+	scope := day + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestSum[:])
+
+	key := []byte("AWS4" + os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		os.Getenv("AWS_ACCESS_KEY_ID"), scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
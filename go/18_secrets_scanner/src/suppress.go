@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// inlineAllowMarker on a source line suppresses findings on that line.
+const inlineAllowMarker = "secrets-scanner:allow"
+
+// allowlist holds the suppressions read from --allowlist. Each line is one of
+//
+//	path:<glob>   skip files whose path or base name matches the glob
+//	              (a trailing "/" skips everything under a directory)
+//	rule:<id>     disable a rule
+//	<regexp>      ignore secrets matching the expression (e.g. EXAMPLE$)
+type allowlist struct {
+	paths   []string
+	rules   map[string]bool
+	secrets []*regexp.Regexp
+}
+
+func loadAllowlist(path string) (*allowlist, error) {
+	a := &allowlist{rules: map[string]bool{}}
+	if path == "" {
+		return a, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read allowlist %s: %w", path, err)
+	}
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "path:"):
+			a.paths = append(a.paths, strings.TrimSpace(strings.TrimPrefix(line, "path:")))
+		case strings.HasPrefix(line, "rule:"):
+			id := strings.TrimSpace(strings.TrimPrefix(line, "rule:"))
+			if ruleByID(id) == nil {
+				return nil, fmt.Errorf("%s:%d: unknown rule %q", path, n+1, id)
+			}
+			a.rules[id] = true
+		default:
+			re, err := regexp.Compile(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n+1, err)
+			}
+			a.secrets = append(a.secrets, re)
+		}
+	}
+	return a, nil
+}
+
+// skipsPath reports whether a file is excluded by a path: entry.
+func (a *allowlist) skipsPath(path string) bool {
+	slashed := filepath.ToSlash(path)
+	for _, p := range a.paths {
+		if strings.HasSuffix(p, "/") {
+			if strings.HasPrefix(slashed, p) || strings.Contains(slashed, "/"+p) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := filepath.Match(p, slashed); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether a finding is suppressed by a rule: or secret entry.
+func (a *allowlist) allows(f Finding) bool {
+	if a.rules[f.Rule.ID] {
+		return true
+	}
+	for _, re := range a.secrets {
+		if re.MatchString(f.Secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint identifies a finding independently of its line number, so a
+// baselined secret stays suppressed when code around it moves. The secret
+// itself is only stored as part of a hash.
+func fingerprint(ruleID, path, secret string) string {
+	sum := sha256.Sum256([]byte(ruleID + "\x00" + filepath.ToSlash(path) + "\x00" + secret))
+	return hex.EncodeToString(sum[:16])
+}
+
+// baselineEntry records one accepted finding. Rule, path and line are kept
+// for reviewers; only the fingerprint is used for matching.
+type baselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	Rule        string `json:"rule"`
+	Path        string `json:"path"`
+	Line        int    `json:"line"`
+}
+
+type baselineFile struct {
+	Tool     string          `json:"tool"`
+	Created  time.Time       `json:"created"`
+	Findings []baselineEntry `json:"findings"`
+}
+
+// loadBaseline returns the set of fingerprints in a baseline file.
+func loadBaseline(path string) (map[string]bool, error) {
+	known := map[string]bool{}
+	if path == "" {
+		return known, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s: %w", path, err)
+	}
+	var b baselineFile
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	for _, e := range b.Findings {
+		known[e.Fingerprint] = true
+	}
+	return known, nil
+}
+
+// writeBaseline saves findings as accepted, replacing path atomically.
+func writeBaseline(path string, findings []Finding) error {
+	b := baselineFile{Tool: toolName, Created: time.Now().UTC(), Findings: []baselineEntry{}}
+	for _, f := range findings {
+		b.Findings = append(b.Findings, baselineEntry{f.Fingerprint, f.Rule.ID, filepath.ToSlash(f.Path), f.Line})
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".secrets-baseline-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
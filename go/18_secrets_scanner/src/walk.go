@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The directory walker shared by the file integrity monitor and the secrets
+// scanner. Every tool that walks trees carries an identical copy of this file
+// (scripts/check_shared_go.py), with gitignore.go.
+
+// Walk settings. respectGitignore and walkWorkers are the --respect-gitignore
+// and --walk-workers flags; the others are fixed by each tool.
+var (
+	respectGitignore bool
+	walkWorkers      = 1
+	// skipDirs are directory names never descended into below a root.
+	skipDirs map[string]bool
+	// skipUnreadable warns about and skips entries that cannot be read,
+	// instead of failing the walk.
+	skipUnreadable bool
+	// regularOnly keeps only regular files from a walk, leaving out
+	// symbolic links.
+	regularOnly bool
+)
+
+// collectFiles recursively gathers files from the given root paths or a
+// list, resolving relative list entries against a base directory. Files and
+// devices named directly are always collected; pipes and sockets are not. A
+// file reached through more than one root is collected once.
+func collectFiles(ctx context.Context, roots []string, list []string, base string) ([]string, error) {
+	var files []string
+	addFile := func(p string) error {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			if os.IsNotExist(err) {
+				if verboseMode {
+					fmt.Fprintln(os.Stderr, "[INFO] Missing:", abs)
+				}
+				return nil
+			}
+			return err
+		}
+		if info.Mode()&(os.ModeNamedPipe|os.ModeSocket) != 0 {
+			warnf("Skipping %s: not a file, directory or device.", abs)
+			return nil
+		}
+		if info.IsDir() {
+			var ign *gitignore
+			if respectGitignore {
+				ign = newGitignore()
+			}
+			if walkWorkers > 1 {
+				found, err := walkParallel(ctx, abs, ign, walkWorkers)
+				files = append(files, found...)
+				return err
+			}
+			return filepath.Walk(abs, func(p string, i os.FileInfo, e error) error {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if e != nil {
+					return walkError(p, e)
+				}
+				descend, keep := walkEntry(abs, p, i, ign)
+				if i.IsDir() && !descend {
+					return filepath.SkipDir
+				}
+				if keep {
+					files = append(files, p)
+				}
+				return nil
+			})
+		}
+		files = append(files, abs)
+		return nil
+	}
+
+	if len(list) > 0 {
+		for _, p := range list {
+			if base != "" && !filepath.IsAbs(p) {
+				p = filepath.Join(base, p)
+			}
+			if err := addFile(p); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		for _, root := range roots {
+			if err := addFile(root); err != nil {
+				return nil, err
+			}
+		}
+	}
+	seen := map[string]bool{}
+	unique := files[:0]
+	for _, f := range files {
+		if !seen[f] {
+			seen[f] = true
+			unique = append(unique, f)
+		}
+	}
+	return unique, nil
+}
+
+// walkEntry decides what a directory walk below root does with p: descend
+// into it, keep it as a file to hash, or neither.
+func walkEntry(root, p string, i os.FileInfo, ign *gitignore) (descend, keep bool) {
+	if ign != nil && p != root && ign.ignored(root, p, i.IsDir()) {
+		debugf("ignored by .gitignore: %s", p)
+		return false, false
+	}
+	if i.IsDir() && p != root && skipDirs[i.Name()] {
+		debugf("skipping directory %s", p)
+		return false, false
+	}
+	if i.IsDir() {
+		if ign != nil {
+			ign.load(p)
+		}
+		return true, false
+	}
+	// Device nodes, pipes and sockets inside a tree are skipped (a walk of
+	// /dev must not read /dev/zero); devices are only hashed when named
+	// explicitly.
+	if i.Mode()&(os.ModeDevice|os.ModeNamedPipe|os.ModeSocket) != 0 {
+		debugf("skipping special file %s", p)
+		return false, false
+	}
+	return false, !regularOnly || i.Mode().IsRegular()
+}
+
+// walkError handles an entry that could not be read during a walk.
+func walkError(p string, err error) error {
+	if skipUnreadable {
+		warnf("Skipping %s: %v", p, err)
+		return nil
+	}
+	return err
+}
+
+// walkParallel lists the tree below root with the given number of
+// goroutines, for --walk-workers. On NFS and other network file systems each
+// directory read is a round trip, so a single-threaded walk spends most of
+// its time waiting. Directories are handed to the workers over a channel and
+// the files they find come back over another. The result is sorted into the
+// order filepath.Walk would produce, so reports do not depend on timing.
+// The first error stops the walk, as it does filepath.Walk.
+func walkParallel(ctx context.Context, root string, ign *gitignore, workers int) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		pending  sync.WaitGroup // Directories queued but not yet listed
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() { firstErr = err })
+		cancel()
+	}
+	dirs := make(chan string)
+	found := make(chan string, 256)
+	queue := func(dir string) {
+		pending.Add(1)
+		go func() { dirs <- dir }() // Never blocks a worker on its own queue
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dir := range dirs {
+				if ctx.Err() == nil {
+					listDir(ctx, root, dir, ign, queue, found, fail)
+				}
+				pending.Done()
+			}
+		}()
+	}
+	if info, err := os.Lstat(root); err != nil {
+		return nil, err
+	} else if descend, _ := walkEntry(root, root, info, ign); descend {
+		queue(root)
+	}
+	go func() {
+		pending.Wait()
+		close(dirs)
+		wg.Wait()
+		close(found)
+	}()
+
+	var files []string
+	for f := range found {
+		files = append(files, f)
+	}
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err() // Interrupted by the caller
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sort.Slice(files, func(i, j int) bool { return walkOrderLess(files[i], files[j]) })
+	return files, nil
+}
+
+// listDir reads one directory, queueing its subdirectories and sending its
+// files to found.
+func listDir(ctx context.Context, root, dir string, ign *gitignore, queue func(string), found chan<- string, fail func(error)) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if err = walkError(dir, err); err != nil {
+			fail(err)
+		}
+		return
+	}
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		p := filepath.Join(dir, e.Name())
+		info, err := e.Info()
+		if err != nil {
+			if err = walkError(p, err); err != nil {
+				fail(err)
+				return
+			}
+			continue
+		}
+		descend, keep := walkEntry(root, p, info, ign)
+		switch {
+		case descend:
+			queue(p)
+		case keep:
+			found <- p
+		}
+	}
+}
+
+// walkOrderLess orders paths as filepath.Walk visits them: by name within
+// each directory, with a directory's contents directly after its name.
+func walkOrderLess(a, b string) bool {
+	as := strings.Split(a, string(filepath.Separator))
+	bs := strings.Split(b, string(filepath.Separator))
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPlaceholder(t *testing.T) {
+	// This is a placeholder test to ensure the Go test runner can find and execute tests.
+	// Actual tests would scan fixture trees with planted secrets, allowlists and baselines.
+	t.Log("Placeholder Go test executed successfully.")
+}
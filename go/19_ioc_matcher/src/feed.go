@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Indicator kinds after normalization.
+const (
+	kindHash   = "hash"
+	kindIP     = "ip"
+	kindDomain = "domain"
+)
+
+// Indicator is one IOC from a feed.
+type Indicator struct {
+	Kind        string // hash, ip or domain
+	Algorithm   string // md5, sha1 or sha256 for hashes
+	Value       string // Lower-case hash, IP, CIDR or domain
+	Description string
+	Origin      string // Feed file and line, or STIX object ID
+	network     *net.IPNet
+}
+
+func (ind *Indicator) label() string {
+	if ind.Kind == kindHash {
+		return ind.Algorithm + " " + ind.Value
+	}
+	return ind.Kind + " " + ind.Value
+}
+
+var (
+	hexRE    = regexp.MustCompile(`^[0-9a-f]+$`)
+	domainRE = regexp.MustCompile(`^(?:[a-z0-9_](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{1,62}$`)
+	// stixComparisonRE picks "object:property = 'value'" comparisons out of a
+	// STIX pattern such as [file:hashes.'SHA-256' = '...' OR ipv4-addr:value = '...'].
+	stixComparisonRE = regexp.MustCompile(`([a-z0-9-]+):([A-Za-z0-9_.'-]+)\s*=\s*'((?:[^'\\]|\\.)*)'`)
+)
+
+// hashAlgorithms maps digest lengths in hex characters to algorithm names.
+var hashAlgorithms = map[int]string{32: "md5", 40: "sha1", 64: "sha256"}
+
+// newIndicator normalizes a typed feed value. An empty or unrecognized type
+// is inferred from the value itself.
+func newIndicator(kind, value, description, origin string) (*Indicator, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	ind := &Indicator{Value: value, Description: strings.TrimSpace(description), Origin: origin}
+	switch kind {
+	case "md5", "sha1", "sha-1", "sha256", "sha-256", "hash", "filehash", "file-hash", "file":
+		ind.Kind = kindHash
+	case "ip", "ipv4", "ipv6", "ipv4-addr", "ipv6-addr", "ip-dst", "ip-src", "cidr", "address":
+		ind.Kind = kindIP
+	case "domain", "domain-name", "hostname", "host", "fqdn":
+		ind.Kind = kindDomain
+	case "url", "uri":
+		u, err := url.Parse(value)
+		if err != nil || u.Hostname() == "" {
+			return nil, fmt.Errorf("cannot extract a host from URL %q", value)
+		}
+		ind.Value = u.Hostname()
+		kind = ""
+	}
+	if ind.Kind == "" {
+		switch {
+		case hexRE.MatchString(ind.Value) && hashAlgorithms[len(ind.Value)] != "":
+			ind.Kind = kindHash
+		case net.ParseIP(ind.Value) != nil || strings.Contains(ind.Value, "/"):
+			ind.Kind = kindIP
+		case domainRE.MatchString(strings.TrimSuffix(ind.Value, ".")):
+			ind.Kind = kindDomain
+		default:
+			return nil, fmt.Errorf("cannot determine indicator type of %q (type %q)", value, kind)
+		}
+	}
+
+	switch ind.Kind {
+	case kindHash:
+		ind.Algorithm = hashAlgorithms[len(ind.Value)]
+		if ind.Algorithm == "" || !hexRE.MatchString(ind.Value) {
+			return nil, fmt.Errorf("invalid hash %q", value)
+		}
+	case kindIP:
+		if _, network, err := net.ParseCIDR(ind.Value); err == nil {
+			ind.network = network
+		} else if ip := net.ParseIP(ind.Value); ip != nil {
+			ind.Value = ip.String()
+		} else {
+			return nil, fmt.Errorf("invalid IP address %q", value)
+		}
+	case kindDomain:
+		ind.Value = strings.TrimSuffix(strings.TrimPrefix(ind.Value, "*."), ".")
+		if !domainRE.MatchString(ind.Value) {
+			return nil, fmt.Errorf("invalid domain %q", value)
+		}
+	}
+	return ind, nil
+}
+
+// loadFeed reads indicators from a CSV file or a STIX-lite JSON file (chosen
+// by extension). Invalid entries are skipped with a warning.
+func loadFeed(path string) ([]*Indicator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feed %s: %w", path, err)
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return loadSTIX(f, path)
+	}
+	return loadCSV(f, path)
+}
+
+// loadCSV accepts "type,value[,description]" rows, an optional header naming
+// the columns (type, value/indicator, description/comment), or a single
+// column of values whose type is inferred.
+func loadCSV(r io.Reader, path string) ([]*Indicator, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	typeCol, valueCol, descCol := 0, 1, 2
+	var indicators []*Indicator
+	for first := true; ; first = false {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse feed %s: %w", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if first && isCSVHeader(row) {
+			typeCol, valueCol, descCol = -1, -1, -1
+			for i, name := range row {
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "type":
+					typeCol = i
+				case "value", "indicator":
+					valueCol = i
+				case "description", "comment", "name":
+					if descCol < 0 {
+						descCol = i
+					}
+				}
+			}
+			continue
+		}
+		field := func(i int) string {
+			if i >= 0 && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		kind, value := field(typeCol), field(valueCol)
+		if len(row) == 1 {
+			kind, value = "", row[0]
+		}
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		origin := fmt.Sprintf("%s:%d", path, line)
+		ind, err := newIndicator(kind, value, field(descCol), origin)
+		if err != nil {
+			warnf("%s: %v", origin, err)
+			continue
+		}
+		indicators = append(indicators, ind)
+	}
+	return indicators, nil
+}
+
+// isCSVHeader reports whether a first row names its columns.
+func isCSVHeader(row []string) bool {
+	for _, name := range row {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "value", "indicator":
+			return true
+		}
+	}
+	return false
+}
+
+// stixObject covers both STIX 2.1 indicator objects and the flat
+// {"type","value","description"} entries of a STIX-lite list.
+type stixObject struct {
+	Type        string `json:"type"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Pattern     string `json:"pattern"`
+	Value       string `json:"value"`
+}
+
+// loadSTIX reads a STIX 2.x bundle (indicator objects with patterns) or a
+// JSON array of flat indicator objects.
+func loadSTIX(r io.Reader, path string) ([]*Indicator, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var objects []stixObject
+	var bundle struct {
+		Objects []stixObject `json:"objects"`
+	}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			return nil, fmt.Errorf("failed to parse feed %s: %w", path, err)
+		}
+		objects = bundle.Objects
+	}
+
+	var indicators []*Indicator
+	for i, obj := range objects {
+		origin := obj.ID
+		if origin == "" {
+			origin = fmt.Sprintf("%s[%d]", path, i)
+		}
+		description := obj.Description
+		if description == "" {
+			description = obj.Name
+		}
+		if obj.Pattern == "" {
+			if obj.Value == "" {
+				continue // Identity, relationship and other non-indicator objects
+			}
+			ind, err := newIndicator(obj.Type, obj.Value, description, origin)
+			if err != nil {
+				warnf("%s: %v", origin, err)
+				continue
+			}
+			indicators = append(indicators, ind)
+			continue
+		}
+		comparisons := stixComparisonRE.FindAllStringSubmatch(obj.Pattern, -1)
+		if len(comparisons) == 0 {
+			warnf("%s: unsupported STIX pattern %q", origin, obj.Pattern)
+		}
+		for _, c := range comparisons {
+			objectType, property, value := c[1], c[2], strings.ReplaceAll(c[3], `\'`, `'`)
+			kind := objectType
+			switch {
+			case objectType == "file" && strings.HasPrefix(property, "hashes."):
+				kind = "hash"
+			case objectType == "file":
+				continue // File names and sizes are not matched
+			case property != "value":
+				continue
+			}
+			ind, err := newIndicator(kind, value, description, origin)
+			if err != nil {
+				warnf("%s: %v", origin, err)
+				continue
+			}
+			indicators = append(indicators, ind)
+		}
+	}
+	return indicators, nil
+}
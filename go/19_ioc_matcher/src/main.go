@@ -0,0 +1,247 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of an Indicator of Compromise (IOC) Matcher.
+PURPOSE: Show skill in threat-intelligence parsing (CSV/STIX), log and artifact correlation, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "ioc_matcher"
+	toolVersion = "1.0.0"
+)
+
+// stringList collects a repeatable flag.
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+// Global variables for CLI flags
+var (
+	feedFiles     stringList
+	fimBaselines  stringList
+	hostsFiles    stringList
+	dnsCacheFiles stringList
+	netstatFiles  stringList
+	outputFile    string
+	verboseMode   bool
+)
+
+func init() {
+	flag.Var(&feedFiles, "feed", "IOC feed: CSV (type,value,description) or STIX-lite JSON (.json); repeatable.")
+	flag.Var(&feedFiles, "f", "IOC feed (shorthand).")
+
+	flag.Var(&fimBaselines, "fim-baseline", "File integrity monitor baseline (JSON of path to SHA-256) to match file hashes against; repeatable.")
+	flag.Var(&hostsFiles, "hosts", "Hosts file to match addresses and names against (e.g. /etc/hosts); repeatable.")
+	flag.Var(&dnsCacheFiles, "dns-cache", "DNS cache dump (ipconfig /displaydns, resolver or dnsmasq output) to match; repeatable.")
+	flag.Var(&netstatFiles, "netstat", "Saved netstat/ss output to match connection endpoints against; repeatable.")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Matches IOC feeds (hashes, IPs, domains) against file baselines, hosts files, DNS caches and netstat output.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s -f feed.csv --hosts /etc/hosts --netstat netstat.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -f bundle.json --fim-baseline baseline.json --dns-cache displaydns.txt -o ioc_report.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// writeReport writes matched indicators with their correlated sightings.
+func writeReport(indicators []*Indicator, m *matcher, output io.Writer) int {
+	kinds := map[string]int{}
+	for _, ind := range indicators {
+		kinds[ind.Kind]++
+	}
+	fmt.Fprintf(output, "--- IOC Match Report ---\n\n")
+	fmt.Fprintf(output, "Indicators Loaded: %d (hashes %d, IPs %d, domains %d)\n", len(indicators), kinds[kindHash], kinds[kindIP], kinds[kindDomain])
+	fmt.Fprintf(output, "Entries Examined: FIM baseline %d, hosts %d, DNS cache %d, netstat %d\n",
+		m.counts["fim"], m.counts["hosts"], m.counts["dns"], m.counts["netstat"])
+	fmt.Fprintln(output, "------------------------------")
+
+	var matched []*Indicator
+	for _, ind := range indicators {
+		if len(m.sightings[ind]) > 0 {
+			matched = append(matched, ind)
+		}
+	}
+	// Indicators seen in several kinds of source first: those are the
+	// strongest signs of an actual compromise.
+	sources := func(ind *Indicator) []string {
+		var kinds []string
+		for _, s := range m.sightings[ind] {
+			if !slices.Contains(kinds, s.Source) {
+				kinds = append(kinds, s.Source)
+			}
+		}
+		return kinds
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return len(sources(matched[i])) > len(sources(matched[j]))
+	})
+
+	total := 0
+	for _, ind := range matched {
+		fmt.Fprintf(output, "Indicator: %s\n", ind.label())
+		fmt.Fprintf(output, "Status: %s\n", colorStatus("MATCH"))
+		if ind.Description != "" {
+			fmt.Fprintf(output, "Description: %s\n", ind.Description)
+		}
+		fmt.Fprintf(output, "Feed Entry: %s\n", ind.Origin)
+		fmt.Fprintf(output, "Seen In: %s\n", strings.Join(sources(ind), ", "))
+		fmt.Fprintln(output, "Sightings:")
+		for _, s := range m.sightings[ind] {
+			fmt.Fprintf(output, "  [%s] %s: %s\n", s.Source, s.Location, s.Detail)
+			total++
+		}
+		fmt.Fprintln(output, "------------------------------")
+	}
+	if len(matched) == 0 {
+		fmt.Fprintf(output, "Status: %s\n", colorStatus("CLEAN"))
+	}
+	fmt.Fprintf(output, "Matched Indicators: %d\nTotal Sightings: %d\n", len(matched), total)
+	return len(matched)
+}
+
+// main is the entry point of the IOC Matcher tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	if len(feedFiles) == 0 || len(fimBaselines)+len(hostsFiles)+len(dnsCacheFiles)+len(netstatFiles) == 0 {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] At least one feed (-f) and one data source (--fim-baseline, --hosts, --dns-cache, --netstat) must be provided.")
+		os.Exit(1)
+	}
+
+	var indicators []*Indicator
+	for _, path := range feedFiles {
+		loaded, err := loadFeed(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Loaded %d indicator(s) from %s\n", len(loaded), path)
+		}
+		indicators = append(indicators, loaded...)
+	}
+	if len(indicators) == 0 {
+		fmt.Fprintln(os.Stderr, "[ERROR] No usable indicators in the feed(s).")
+		os.Exit(1)
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+
+	// SIGINT/SIGTERM stop between data sources; matches so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Name sources run before netstat so that connections can be correlated
+	// with the IOC domains their addresses resolved from.
+	m := newMatcher(indicators)
+	type job struct {
+		path, source string
+		scan         func(string) error
+	}
+	var jobs []job
+	for _, p := range fimBaselines {
+		jobs = append(jobs, job{p, "fim", m.scanFIMBaseline})
+	}
+	for _, p := range hostsFiles {
+		jobs = append(jobs, job{p, "hosts", func(p string) error { return m.scanNameFile(p, "hosts") }})
+	}
+	for _, p := range dnsCacheFiles {
+		jobs = append(jobs, job{p, "dns", func(p string) error { return m.scanNameFile(p, "dns") }})
+	}
+	for _, p := range netstatFiles {
+		jobs = append(jobs, job{p, "netstat", m.scanNetstat})
+	}
+
+	var inputs []string
+	done := 0
+	for _, j := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		inputs = append(inputs, j.path)
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Matching %s data: %s\n", j.source, j.path)
+		}
+		if err := j.scan(j.path); err != nil {
+			warnf("Failed to read %s: %v", j.path, err)
+		}
+		done++
+	}
+	debugf("Entries per source: %v", m.counts)
+
+	matched := writeReport(indicators, m, output)
+	if selfStatsOn {
+		entries := 0
+		for _, n := range m.counts {
+			entries += n
+		}
+		writeSelfStats(output, collectSelfStats(entries, "entries"))
+	}
+	inputs = append(append([]string{}, feedFiles...), inputs...)
+	if ctx.Err() != nil {
+		stop()
+		fmt.Fprintf(output, "Partial report: interrupted after %d of %d data sources.\n", done, len(jobs))
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		writeManifest(130, inputs, []string{outputFile})
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] IOC matching complete.")
+	}
+	if matched > 0 {
+		writeManifest(1, inputs, []string{outputFile})
+		os.Exit(1)
+	}
+	writeManifest(0, inputs, []string{outputFile})
+	os.Exit(0)
+}
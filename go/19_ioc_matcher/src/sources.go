@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Sighting is one place an indicator was observed.
+type Sighting struct {
+	Source   string // fim, hosts, dns or netstat
+	Location string // File and line (or baseline entry)
+	Detail   string
+}
+
+// resolution is a name matching an IOC domain, seen resolving to an address.
+type resolution struct {
+	ind  *Indicator
+	name string
+}
+
+// matcher indexes indicators for lookup and collects sightings.
+type matcher struct {
+	hashes    map[string]*Indicator
+	ips       map[string]*Indicator
+	networks  []*Indicator
+	domains   map[string]*Indicator
+	sightings map[*Indicator][]Sighting
+	// resolved maps IPs to the IOC domains they were seen resolving to in a
+	// hosts file or DNS cache, so connections to them can be correlated.
+	resolved map[string][]resolution
+	// counts records how many entries each source contributed.
+	counts map[string]int
+}
+
+func newMatcher(indicators []*Indicator) *matcher {
+	m := &matcher{
+		hashes:    map[string]*Indicator{},
+		ips:       map[string]*Indicator{},
+		domains:   map[string]*Indicator{},
+		sightings: map[*Indicator][]Sighting{},
+		resolved:  map[string][]resolution{},
+		counts:    map[string]int{},
+	}
+	for _, ind := range indicators {
+		switch {
+		case ind.Kind == kindHash:
+			m.hashes[ind.Value] = ind
+		case ind.network != nil:
+			m.networks = append(m.networks, ind)
+		case ind.Kind == kindIP:
+			m.ips[ind.Value] = ind
+		case ind.Kind == kindDomain:
+			m.domains[ind.Value] = ind
+		}
+	}
+	return m
+}
+
+func (m *matcher) record(ind *Indicator, s Sighting) {
+	for _, seen := range m.sightings[ind] {
+		if seen == s {
+			return
+		}
+	}
+	m.sightings[ind] = append(m.sightings[ind], s)
+}
+
+// matchIP returns the indicator for an address, by exact value or CIDR.
+func (m *matcher) matchIP(addr string) *Indicator {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil
+	}
+	if ind := m.ips[ip.String()]; ind != nil {
+		return ind
+	}
+	for _, ind := range m.networks {
+		if ind.network.Contains(ip) {
+			return ind
+		}
+	}
+	return nil
+}
+
+// matchDomain returns the indicator for name or any parent domain of it,
+// so an IOC for evil.example also matches cdn.evil.example.
+func (m *matcher) matchDomain(name string) *Indicator {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for {
+		if ind := m.domains[name]; ind != nil {
+			return ind
+		}
+		i := strings.IndexByte(name, '.')
+		if i < 0 {
+			return nil
+		}
+		name = name[i+1:]
+	}
+}
+
+// scanFIMBaseline matches file hashes recorded by the Basic File Integrity
+// Monitor (a JSON object of path to SHA-256, or path to an object with a
+// "sha256"/"hash" field).
+func (m *matcher) scanFIMBaseline(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("not a file integrity baseline: %w", err)
+	}
+	for file, raw := range entries {
+		var hash string
+		if json.Unmarshal(raw, &hash) != nil {
+			var rec struct {
+				SHA256 string `json:"sha256"`
+				Hash   string `json:"hash"`
+			}
+			if json.Unmarshal(raw, &rec) != nil {
+				continue
+			}
+			hash = rec.SHA256 + rec.Hash
+		}
+		m.counts["fim"]++
+		if ind := m.hashes[strings.ToLower(hash)]; ind != nil {
+			m.record(ind, Sighting{"fim", path, "file " + file + " has this hash"})
+		}
+	}
+	return nil
+}
+
+// scanNameFile matches a hosts file or a DNS cache dump. Each line is split
+// into addresses and names; for "ipconfig /displaydns" style output, where
+// the name and its address are on separate lines, the last name seen is
+// paired with the next address and reported as one "name -> address"
+// sighting.
+func (m *matcher) scanNameFile(path, source string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// A matching name waits for its address line; it is reported on its own
+	// only if the record has none (e.g. a cached NXDOMAIN).
+	var pendingInd *Indicator
+	var pending Sighting
+	flush := func() {
+		if pendingInd != nil {
+			m.record(pendingInd, pending)
+			pendingInd = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	lastName := ""
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		var ips, names []string
+		for _, tok := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' || r == '"' }) {
+			tok = strings.TrimSuffix(strings.ToLower(tok), ".")
+			switch {
+			case net.ParseIP(tok) != nil:
+				ips = append(ips, tok)
+			case domainRE.MatchString(tok):
+				names = append(names, tok)
+			}
+		}
+		if len(ips)+len(names) == 0 {
+			continue
+		}
+		m.counts[source]++
+		location := fmt.Sprintf("%s:%d", path, lineNo)
+		text := strings.Join(strings.Fields(line), " ")
+		if len(names) > 0 {
+			if names[len(names)-1] != lastName {
+				flush() // A new record starts
+			}
+			lastName = names[len(names)-1]
+		} else if lastName != "" {
+			names = []string{lastName}
+			text = lastName + " -> " + strings.Join(ips, ", ")
+		}
+
+		for _, name := range names {
+			ind := m.matchDomain(name)
+			if ind == nil {
+				continue
+			}
+			if len(ips) == 0 {
+				pendingInd, pending = ind, Sighting{source, location, text}
+				continue
+			}
+			if pendingInd == ind {
+				pendingInd = nil
+			}
+			m.record(ind, Sighting{source, location, text})
+			for _, ip := range ips {
+				if r := (resolution{ind, name}); !slices.Contains(m.resolved[ip], r) {
+					m.resolved[ip] = append(m.resolved[ip], r)
+				}
+			}
+		}
+		for _, ip := range ips {
+			if ind := m.matchIP(ip); ind != nil {
+				m.record(ind, Sighting{source, location, text})
+			}
+		}
+	}
+	flush()
+	return scanner.Err()
+}
+
+// scanNetstat matches connection endpoints in netstat or ss output (Linux,
+// macOS and Windows formats). Connections to an address that an IOC domain
+// resolved to are added to that domain's sightings as well.
+func (m *matcher) scanNetstat(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		var endpoints []string
+		for _, tok := range strings.Fields(line) {
+			if ip := endpointIP(tok); ip != "" {
+				endpoints = append(endpoints, ip)
+			}
+		}
+		if len(endpoints) == 0 {
+			continue
+		}
+		m.counts["netstat"]++
+		location := fmt.Sprintf("%s:%d", path, lineNo)
+		text := strings.Join(strings.Fields(line), " ")
+		for i, ip := range endpoints {
+			role := "remote"
+			if i == 0 && len(endpoints) > 1 {
+				role = "local"
+			}
+			if ind := m.matchIP(ip); ind != nil {
+				m.record(ind, Sighting{"netstat", location, fmt.Sprintf("%s (%s address)", text, role)})
+			}
+			for _, r := range m.resolved[ip] {
+				m.record(r.ind, Sighting{"netstat", location, fmt.Sprintf("%s (%s resolved from %s)", text, ip, r.name)})
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// endpointIP extracts the address from a netstat endpoint such as
+// 10.0.0.5:443, [2001:db8::1]:443, 2001:db8::1:443 or (macOS) 10.0.0.5.443.
+// Wildcard and unspecified addresses are ignored.
+func endpointIP(tok string) string {
+	host := ""
+	if h, _, err := net.SplitHostPort(tok); err == nil {
+		host = h
+	} else if i := strings.LastIndexAny(tok, ":."); i > 0 {
+		host = tok[:i]
+	}
+	host = strings.Trim(host, "[]")
+	if j := strings.IndexByte(host, '%'); j >= 0 {
+		host = host[:j] // Zone, e.g. fe80::1%eth0
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsUnspecified() {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,513 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of a Domain Registration Expiry Checker.
+PURPOSE: Show skill in registry protocols (RDAP, WHOIS), response parsing, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "domain_expiry_checker"
+	toolVersion = "1.0.0"
+)
+
+// Global variables for CLI flags
+var (
+	domainArg     string
+	inputFile     string
+	outputFile    string
+	timeoutSec    int
+	warnDays      int
+	sourceMode    string
+	rdapServer    string
+	whoisServer   string
+	requireLock   bool
+	summaryView   bool
+	verboseMode   bool
+	notifyTargets notifyList
+	notifyTmpl    string
+)
+
+// DomainCheckResult stores the result of a single domain check
+type DomainCheckResult struct {
+	Domain     string
+	ExpiryDate time.Time
+	DaysLeft   int
+	Status     string
+	Registrar  string
+	Lock       string   // LOCKED, UNLOCKED or UNKNOWN (registry published no statuses)
+	LockCodes  []string // EPP *Prohibited statuses in effect
+	Source     string   // RDAP or WHOIS, with the reason RDAP was not used
+	Warnings   []string // Statuses that need attention (pending delete, hold, ...)
+	Error      error
+}
+
+func init() {
+	// --- CLI Argument Parsing ---
+	flag.StringVar(&domainArg, "domain", "", "Registered domain to check (e.g., example.com).")
+	flag.StringVar(&domainArg, "d", "", "Registered domain to check (shorthand).")
+
+	flag.StringVar(&inputFile, "input", "", "Path to a file containing domains to check (one per line). Overrides -domain if provided.")
+	flag.StringVar(&inputFile, "i", "", "Path to a file containing domains to check (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Path to save the report (shorthand).")
+
+	flag.IntVar(&timeoutSec, "timeout", 10, "RDAP/WHOIS query timeout in seconds.")
+	flag.IntVar(&timeoutSec, "t", 10, "RDAP/WHOIS query timeout in seconds (shorthand).")
+
+	flag.IntVar(&warnDays, "warn-days", 30, "Number of days before expiry to issue a warning.")
+	flag.IntVar(&warnDays, "w", 30, "Number of days before expiry to issue a warning (shorthand).")
+
+	flag.StringVar(&sourceMode, "source", "auto", "Registry protocol: auto (RDAP, falling back to WHOIS), rdap or whois.")
+	flag.StringVar(&rdapServer, "rdap-server", "", "RDAP base URL to query for every domain instead of the IANA bootstrap registry.")
+	flag.StringVar(&whoisServer, "whois-server", "", "WHOIS server (host[:port]) to query for every domain instead of the IANA referral.")
+
+	flag.BoolVar(&requireLock, "require-lock", false, "Report domains without a transfer lock (clientTransferProhibited/serverTransferProhibited) as UNLOCKED.")
+
+	flag.BoolVar(&summaryView, "summary", false, "Print a portfolio summary (domain counts per expiry bucket and the soonest-expiring domains) instead of the per-domain report.")
+
+	flag.Var(&notifyTargets, "notify", "Send an alert listing domains that are not VALID: a webhook URL, slack:<url>, teams:<url> or smtp://[user@]host:port?from=..&to=.. (repeatable).")
+	flag.StringVar(&notifyTmpl, "notify-template", "", "Path to a Go text/template for alert messages (fields: .Tool .Hostname .Time .Summary .Items[].Target/.Status/.Detail).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+	registerTZFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Checks domain registration expiry and registrar lock status via RDAP/WHOIS.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s -d example.com\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -i domains.txt -w 60 -o report.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// statusWarnings explains EPP statuses that mean a domain is at risk.
+var statusWarnings = map[string]string{
+	"redemptionPeriod": "domain is in its redemption grace period and will be deleted unless restored",
+	"pendingDelete":    "domain is pending deletion",
+	"pendingTransfer":  "a transfer to another registrar is pending",
+	"clientHold":       "domain is on hold (clientHold) and not published in DNS",
+	"serverHold":       "domain is on hold (serverHold) and not published in DNS",
+	"inactive":         "domain has no name servers delegated",
+}
+
+// lockStatus classifies EPP statuses. A domain is locked against hijacking
+// when transfers are prohibited by the registrar (client) or registry (server).
+func lockStatus(statuses []string) (string, []string) {
+	if len(statuses) == 0 {
+		return "UNKNOWN", nil
+	}
+	lock := "UNLOCKED"
+	var codes []string
+	for _, s := range statuses {
+		if !strings.HasSuffix(s, "Prohibited") {
+			continue
+		}
+		codes = append(codes, s)
+		if s == "clientTransferProhibited" || s == "serverTransferProhibited" {
+			lock = "LOCKED"
+		}
+	}
+	return lock, codes
+}
+
+// checker holds the registry clients shared by all domain checks.
+type checker struct {
+	http         *http.Client
+	bootstrap    rdapBootstrap
+	bootstrapErr error // Why the bootstrap registry could not be loaded
+	whois        *whoisClient
+}
+
+// lookup queries RDAP and, in auto mode, falls back to WHOIS for TLDs without
+// an RDAP service or when RDAP fails. RDAP "not found" answers are final.
+func (c *checker) lookup(ctx context.Context, domain string) (*registration, string, error) {
+	var rdapErr error
+	if sourceMode != "whois" {
+		base := rdapServer
+		if base == "" {
+			base = c.bootstrap.serverFor(domain)
+		}
+		if base != "" {
+			debugf("%s: querying RDAP server %s", domain, base)
+			reg, err := queryRDAP(ctx, c.http, base, domain)
+			if err == nil || errors.Is(err, errNotFound) || sourceMode == "rdap" || ctx.Err() != nil {
+				return reg, "RDAP", err
+			}
+			rdapErr = err
+		} else {
+			rdapErr = c.bootstrapErr
+			if rdapErr == nil {
+				rdapErr = fmt.Errorf("no RDAP service for .%s", domain[strings.LastIndexByte(domain, '.')+1:])
+			}
+			if sourceMode == "rdap" {
+				return nil, "RDAP", rdapErr
+			}
+		}
+	}
+	reg, err := c.whois.lookup(ctx, domain)
+	if rdapErr != nil {
+		debugf("%s: RDAP unavailable, used WHOIS: %v", domain, rdapErr)
+		return reg, fmt.Sprintf("WHOIS (RDAP: %v)", rdapErr), err
+	}
+	return reg, "WHOIS", err
+}
+
+// checkDomainExpiry looks up a domain's registration and checks its expiry.
+func (c *checker) checkDomainExpiry(ctx context.Context, domain string, warnThreshold int) DomainCheckResult {
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Checking registration for: %s\n", domain)
+	}
+	reg, source, err := c.lookup(ctx, domain)
+	result := DomainCheckResult{Domain: domain, Source: source}
+	if errors.Is(err, errNotFound) {
+		result.Status, result.Error = "NOT FOUND", fmt.Errorf("%w (unregistered, deleted, or not a registered domain)", err)
+		return result
+	}
+	if err != nil {
+		result.Status, result.Error = "ERROR", err
+		return result
+	}
+
+	result.Registrar = reg.Registrar
+	result.Lock, result.LockCodes = lockStatus(reg.Statuses)
+	for _, s := range reg.Statuses {
+		if w := statusWarnings[s]; w != "" {
+			result.Warnings = append(result.Warnings, w)
+		}
+	}
+	if reg.Expiry.IsZero() {
+		result.Status, result.Error = "ERROR", fmt.Errorf("registry did not publish an expiry date")
+		return result
+	}
+	result.ExpiryDate = reg.Expiry
+	result.DaysLeft = int(time.Until(reg.Expiry).Hours() / 24)
+
+	switch {
+	case result.DaysLeft < 0:
+		result.Status = "EXPIRED"
+	case result.DaysLeft <= warnThreshold:
+		result.Status = fmt.Sprintf("EXPIRING SOON (%d days)", result.DaysLeft)
+	case requireLock && result.Lock == "UNLOCKED":
+		result.Status = "UNLOCKED"
+	default:
+		result.Status = "VALID"
+	}
+	return result
+}
+
+// normalizeDomain reduces an input line (a domain, URL or host:port) to a
+// lower-case domain name.
+func normalizeDomain(entry string) string {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if strings.Contains(entry, "://") {
+		if u, err := url.Parse(entry); err == nil {
+			entry = u.Hostname()
+		}
+	}
+	if i := strings.IndexAny(entry, ":/"); i >= 0 {
+		entry = entry[:i]
+	}
+	return strings.Trim(entry, ".")
+}
+
+// loadDomainsFromFile reads domains from a specified file, skipping blank
+// lines, comments and duplicates.
+func loadDomainsFromFile(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("[ERROR] Failed to open input file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var domains []string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		domain := normalizeDomain(line)
+		if domain == "" || seen[domain] {
+			continue
+		}
+		if !strings.Contains(domain, ".") {
+			warnf("Skipping %q: not a domain name.", domain)
+			continue
+		}
+		seen[domain] = true
+		domains = append(domains, domain)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("[ERROR] Error reading input file %s: %w", filePath, err)
+	}
+	return domains, nil
+}
+
+// writeReport generates the domain expiry report.
+func writeReport(results []DomainCheckResult, output io.Writer) {
+	fmt.Fprintf(output, "--- Domain Expiry Report ---\n\n")
+	if len(results) == 0 {
+		fmt.Fprintln(output, "No domains were checked or no results to report.")
+		return
+	}
+
+	for _, result := range results {
+		fmt.Fprintf(output, "Domain: %s\n", result.Domain)
+		fmt.Fprintf(output, "Status: %s\n", colorStatus(result.Status))
+		if result.ExpiryDate.IsZero() {
+			fmt.Fprintf(output, "Expiry Date: N/A\n")
+			fmt.Fprintf(output, "Days Left: N/A\n")
+		} else {
+			fmt.Fprintf(output, "Expiry Date: %s\n", stamp(result.ExpiryDate))
+			fmt.Fprintf(output, "Days Left: %d\n", result.DaysLeft)
+		}
+		if result.Registrar != "" {
+			fmt.Fprintf(output, "Registrar: %s\n", result.Registrar)
+		}
+		if result.Lock != "" {
+			if len(result.LockCodes) > 0 {
+				fmt.Fprintf(output, "Registrar Lock: %s (%s)\n", result.Lock, strings.Join(result.LockCodes, ", "))
+			} else {
+				fmt.Fprintf(output, "Registrar Lock: %s\n", result.Lock)
+			}
+		}
+		for _, w := range result.Warnings {
+			fmt.Fprintf(output, "Warning: %s\n", w)
+		}
+		fmt.Fprintf(output, "Source: %s\n", result.Source)
+		if result.Error != nil {
+			fmt.Fprintf(output, "Error: %v\n", result.Error)
+		}
+		fmt.Fprintln(output, "------------------------------")
+	}
+}
+
+// notifyDomainAlerts sends one alert covering every domain that is not VALID.
+func notifyDomainAlerts(results []DomainCheckResult) {
+	notifier, err := newAlertNotifier(notifyTargets, notifyTmpl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	var items []alertItem
+	for _, r := range results {
+		if r.Status == "VALID" {
+			continue
+		}
+		detail := ""
+		switch {
+		case r.Error != nil:
+			detail = r.Error.Error()
+		case r.DaysLeft < 0:
+			detail = fmt.Sprintf("expired %s", stamp(r.ExpiryDate))
+		case r.Status == "UNLOCKED":
+			detail = "no transfer lock"
+		default:
+			detail = fmt.Sprintf("expires %s, %d days left", stamp(r.ExpiryDate), r.DaysLeft)
+		}
+		items = append(items, alertItem{Target: r.Domain, Status: r.Status, Detail: detail, ErrorClass: classifyError(r.Error)})
+	}
+	if len(items) == 0 {
+		return
+	}
+	event := alertEvent{Tool: toolName, Summary: fmt.Sprintf("%d domain(s) need attention", len(items)), Items: items}
+	for _, err := range notifier.send(event) {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+	}
+}
+
+// main is the entry point of the Domain Expiry Checker tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+	if err := applyTZ(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+
+	// Validate arguments
+	if inputFile == "" && domainArg == "" {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] Either an input file (-i) or a domain (-d) must be provided.")
+		os.Exit(1)
+	}
+	if inputFile != "" && domainArg != "" {
+		warnf("Input file (-i) provided. -domain flag will be ignored.")
+	}
+	if sourceMode != "auto" && sourceMode != "rdap" && sourceMode != "whois" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --source %q: use auto, rdap or whois.\n", sourceMode)
+		os.Exit(1)
+	}
+
+	var domains []string
+	if inputFile != "" {
+		loaded, err := loadDomainsFromFile(inputFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		domains = loaded
+	} else {
+		domains = []string{normalizeDomain(domainArg)}
+	}
+
+	// SIGINT/SIGTERM abort pending queries; domains checked so far are
+	// still reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	timeout := time.Duration(timeoutSec) * time.Second
+	c := &checker{
+		http:  &http.Client{Timeout: timeout},
+		whois: &whoisClient{server: whoisServer, timeout: timeout, refer: map[string]string{}},
+	}
+	if c.whois.server != "" && !strings.Contains(c.whois.server, ":") {
+		c.whois.server += ":43"
+	}
+	if sourceMode != "whois" && rdapServer == "" {
+		bootstrap, err := loadRDAPBootstrap(ctx, c.http)
+		if err != nil && sourceMode == "rdap" {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			warnf("RDAP unavailable, %v; using WHOIS only.", err)
+			c.bootstrapErr = errors.New("bootstrap registry unavailable")
+		}
+		c.bootstrap = bootstrap
+	}
+
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Checking %d domain(s) for registration expiry...\n", len(domains))
+	}
+
+	type indexed struct {
+		i int
+		r DomainCheckResult
+	}
+	resultsChan := make(chan indexed, len(domains))
+	started := 0
+	for i, domain := range domains {
+		if ctx.Err() != nil {
+			break
+		}
+		go func(i int, d string) {
+			resultsChan <- indexed{i, c.checkDomainExpiry(ctx, d, warnDays)}
+		}(i, domain)
+		started++
+		select { // Registries rate-limit WHOIS; space the queries out
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+		}
+	}
+
+	// Results are reported in input order. Failures caused by the interrupt
+	// itself are dropped rather than reported as registry errors.
+	slots := make([]*DomainCheckResult, len(domains))
+	keep := func(x indexed) {
+		if ctx.Err() == nil || x.r.Error == nil {
+			slots[x.i] = &x.r
+		}
+	}
+	interrupted := false
+	for n := 0; n < started && !interrupted; n++ {
+		select {
+		case x := <-resultsChan:
+			keep(x)
+		case <-ctx.Done():
+			interrupted = true
+		}
+	}
+	for drained := false; interrupted && !drained; { // Keep checks that finished before the signal
+		select {
+		case x := <-resultsChan:
+			keep(x)
+		default:
+			drained = true
+		}
+	}
+	var results []DomainCheckResult
+	for _, r := range slots {
+		if r != nil {
+			results = append(results, *r)
+		}
+	}
+	if ctx.Err() != nil {
+		interrupted = true
+		stop() // A second signal terminates immediately
+		warnf("Interrupted by signal; reporting %d of %d domain(s).", len(results), len(domains))
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+
+	if summaryView {
+		writeSummary(results, output)
+	} else {
+		writeReport(results, output)
+	}
+	if interrupted {
+		fmt.Fprintf(output, "Partial report: interrupted after %d of %d domains.\n", len(results), len(domains))
+	}
+	if selfStatsOn {
+		writeSelfStats(output, collectSelfStats(len(results), "domains"))
+	}
+
+	if len(notifyTargets) > 0 {
+		notifyDomainAlerts(results)
+	}
+
+	inputs := []string{inputFile, notifyTmpl}
+	if interrupted {
+		closeSink(output)
+		writeManifest(130, inputs, []string{outputFile})
+		os.Exit(130)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Domain expiry check complete.")
+	}
+	if !closeSink(output) {
+		os.Exit(1)
+	}
+	writeManifest(0, inputs, []string{outputFile})
+	os.Exit(0)
+}
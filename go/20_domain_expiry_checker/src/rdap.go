@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// rdapBootstrapURL is IANA's registry of RDAP base URLs per TLD (RFC 9224).
+const rdapBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// errNotFound reports a domain the registry has no record of.
+var errNotFound = errors.New("domain not found in registry")
+
+// registration is the registry data needed for the report, from either RDAP
+// or WHOIS.
+type registration struct {
+	Expiry    time.Time
+	Registrar string
+	Statuses  []string // EPP status codes, e.g. clientTransferProhibited
+}
+
+// rdapBootstrap maps TLDs to RDAP base URLs.
+type rdapBootstrap map[string]string
+
+// loadRDAPBootstrap fetches the IANA bootstrap file.
+func loadRDAPBootstrap(ctx context.Context, client *http.Client) (rdapBootstrap, error) {
+	var doc struct {
+		Services [][][]string `json:"services"`
+	}
+	if err := getJSON(ctx, client, rdapBootstrapURL, &doc); err != nil {
+		return nil, fmt.Errorf("failed to load RDAP bootstrap: %w", err)
+	}
+	bootstrap := rdapBootstrap{}
+	for _, service := range doc.Services {
+		if len(service) < 2 || len(service[1]) == 0 {
+			continue
+		}
+		base := service[1][0]
+		for _, u := range service[1] {
+			if strings.HasPrefix(u, "https://") {
+				base = u // Prefer HTTPS where both are listed
+				break
+			}
+		}
+		for _, tld := range service[0] {
+			bootstrap[strings.ToLower(tld)] = base
+		}
+	}
+	return bootstrap, nil
+}
+
+// serverFor returns the base URL for the longest matching suffix of domain.
+func (b rdapBootstrap) serverFor(domain string) string {
+	for name := domain; ; {
+		if base, ok := b[name]; ok {
+			return base
+		}
+		i := strings.IndexByte(name, '.')
+		if i < 0 {
+			return ""
+		}
+		name = name[i+1:]
+	}
+}
+
+// getJSON fetches url and decodes the JSON response into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+	req.Header.Set("User-Agent", toolName+"/"+toolVersion)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("HTTP %s from %s", resp.Status, url)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON from %s: %w", url, err)
+	}
+	return nil
+}
+
+// rdapEntity is the part of an RDAP entity used to find the registrar.
+type rdapEntity struct {
+	Roles      []string          `json:"roles"`
+	VCardArray []json.RawMessage `json:"vcardArray"`
+}
+
+// name returns the entity's vCard "fn" (formatted name) property.
+func (e rdapEntity) name() string {
+	if len(e.VCardArray) < 2 {
+		return ""
+	}
+	var props [][]interface{}
+	if json.Unmarshal(e.VCardArray[1], &props) != nil {
+		return ""
+	}
+	for _, p := range props {
+		if len(p) >= 4 && p[0] == "fn" {
+			if fn, ok := p[3].(string); ok {
+				return fn
+			}
+		}
+	}
+	return ""
+}
+
+// queryRDAP looks up a domain at base (an RDAP base URL ending in /).
+func queryRDAP(ctx context.Context, client *http.Client, base, domain string) (*registration, error) {
+	var doc struct {
+		Status []string `json:"status"`
+		Events []struct {
+			Action string `json:"eventAction"`
+			Date   string `json:"eventDate"`
+		} `json:"events"`
+		Entities []rdapEntity `json:"entities"`
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	if err := getJSON(ctx, client, base+"domain/"+domain, &doc); err != nil {
+		return nil, err
+	}
+
+	reg := &registration{}
+	for _, ev := range doc.Events {
+		if ev.Action != "expiration" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, ev.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RDAP expiration date %q", ev.Date)
+		}
+		reg.Expiry = t
+	}
+	for _, e := range doc.Entities {
+		for _, role := range e.Roles {
+			if role == "registrar" && reg.Registrar == "" {
+				reg.Registrar = e.name()
+			}
+		}
+	}
+	for _, s := range doc.Status {
+		reg.Statuses = append(reg.Statuses, eppStatus(s))
+	}
+	return reg, nil
+}
+
+// eppStatus converts an RDAP status ("client transfer prohibited") or a WHOIS
+// status ("clientTransferProhibited https://icann.org/epp#...") to its EPP code.
+func eppStatus(s string) string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return ""
+	}
+	if len(words) == 1 || strings.Contains(words[1], "://") || strings.HasPrefix(words[1], "(") {
+		return words[0] // Already an EPP code
+	}
+	code := strings.ToLower(words[0])
+	for _, w := range words[1:] {
+		code += strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+	}
+	return code
+}
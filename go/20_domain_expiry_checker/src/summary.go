@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// expiryBucket is one row of the --summary matrix; a domain falls into the first
+// bucket whose upper bound (exclusive, in days) exceeds its days left.
+type expiryBucket struct {
+	Label string
+	Max   int
+}
+
+var expiryBuckets = []expiryBucket{
+	{"Expired", 0},
+	{"< 7 days", 7},
+	{"< 30 days", 30},
+	{"< 90 days", 90},
+	{">= 90 days", int(^uint(0) >> 1)},
+}
+
+// worstOffenders is the number of soonest-expiring domains listed in --summary.
+const worstOffenders = 10
+
+func bucketFor(daysLeft int) int {
+	for i, b := range expiryBuckets {
+		if daysLeft < b.Max {
+			return i
+		}
+	}
+	return len(expiryBuckets) - 1
+}
+
+// writeSummary prints a portfolio triage view: domain counts per expiry
+// bucket and the soonest-expiring domains. Domains that could not be checked
+// are counted separately since their expiry is unknown.
+func writeSummary(results []DomainCheckResult, output io.Writer) {
+	fmt.Fprintf(output, "--- Domain Expiry Summary ---\n\n")
+	if len(results) == 0 {
+		fmt.Fprintln(output, "No domains were checked or no results to report.")
+		return
+	}
+
+	counts := make([]int, len(expiryBuckets))
+	var checked []DomainCheckResult
+	errors := 0
+	for _, r := range results {
+		if r.ExpiryDate.IsZero() {
+			errors++
+			continue
+		}
+		counts[bucketFor(r.DaysLeft)]++
+		checked = append(checked, r)
+	}
+
+	fmt.Fprintf(output, "%-12s %6s\n", "Bucket", "Domains")
+	for i, b := range expiryBuckets {
+		fmt.Fprintf(output, "%-12s %6d\n", b.Label, counts[i])
+	}
+	fmt.Fprintf(output, "%-12s %6d\n", "Errors", errors)
+	fmt.Fprintf(output, "%-12s %6d\n", "Total", len(results))
+	fmt.Fprintln(output, "------------------------------")
+
+	if len(checked) == 0 {
+		return
+	}
+	sort.SliceStable(checked, func(i, j int) bool { return checked[i].DaysLeft < checked[j].DaysLeft })
+	if len(checked) > worstOffenders {
+		checked = checked[:worstOffenders]
+	}
+	fmt.Fprintf(output, "\nWorst Offenders (soonest expiry first):\n\n")
+	fmt.Fprintf(output, "%-40s %10s %-25s  %s\n", "Domain", "Days Left", "Expiry", "Status")
+	for _, r := range checked {
+		fmt.Fprintf(output, "%-40s %10d %-25s  %s\n", r.Domain, r.DaysLeft, stamp(r.ExpiryDate), colorStatus(r.Status))
+	}
+	fmt.Fprintln(output, "------------------------------")
+}
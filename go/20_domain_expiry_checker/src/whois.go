@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ianaWhois answers "which WHOIS server serves this TLD" referral queries.
+const ianaWhois = "whois.iana.org:43"
+
+// whoisExpiryKeys are the field names registries use for the expiry date.
+var whoisExpiryKeys = []string{
+	"registry expiry date", "registrar registration expiration date", "expiration date",
+	"expiry date", "expire date", "expires", "expires on", "paid-till", "renewal date",
+}
+
+// whoisDateLayouts are the date formats seen in registry WHOIS output.
+var whoisDateLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04:05 MST",
+	"2006-01-02", "02-Jan-2006", "2006.01.02", "2006/01/02", "02.01.2006", "January 2 2006",
+}
+
+// whoisClient queries port-43 WHOIS servers, caching per-TLD referrals.
+type whoisClient struct {
+	server  string // Fixed server (--whois-server); empty to ask IANA per TLD
+	timeout time.Duration
+	mu      sync.Mutex
+	refer   map[string]string
+}
+
+// query sends one WHOIS query to server (host:port) and returns the response.
+func (c *whoisClient) query(ctx context.Context, server, q string) (string, error) {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	defer context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := fmt.Fprintf(conn, "%s\r\n", q); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(io.LimitReader(conn, 1<<20))
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// serverFor returns the WHOIS server for a domain's TLD, asking IANA once per TLD.
+func (c *whoisClient) serverFor(ctx context.Context, domain string) (string, error) {
+	if c.server != "" {
+		return c.server, nil
+	}
+	tld := domain[strings.LastIndexByte(domain, '.')+1:]
+	c.mu.Lock()
+	server, ok := c.refer[tld]
+	c.mu.Unlock()
+	if ok {
+		return server, nil
+	}
+	resp, err := c.query(ctx, ianaWhois, tld)
+	if err != nil {
+		return "", fmt.Errorf("IANA WHOIS referral failed: %w", err)
+	}
+	fields := whoisFields(resp)
+	server = firstField(fields, "refer", "whois")
+	if server == "" {
+		return "", fmt.Errorf("no WHOIS server known for .%s", tld)
+	}
+	server = net.JoinHostPort(server, "43")
+	c.mu.Lock()
+	c.refer[tld] = server
+	c.mu.Unlock()
+	return server, nil
+}
+
+// lookup queries the registry WHOIS server for a domain and parses the reply.
+func (c *whoisClient) lookup(ctx context.Context, domain string) (*registration, error) {
+	server, err := c.serverFor(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	debugf("%s: querying WHOIS server %s", domain, server)
+	resp, err := c.query(ctx, server, domain)
+	if err != nil {
+		return nil, fmt.Errorf("WHOIS query to %s failed: %w", server, err)
+	}
+	return parseWhois(resp)
+}
+
+// whoisFields collects "key: value" lines, keyed by lower-case field name.
+// A key with no value on its line (Nominet style) takes the next line.
+func whoisFields(resp string) map[string][]string {
+	fields := map[string][]string{}
+	pending := ""
+	for _, line := range strings.Split(resp, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ">>>") {
+			continue
+		}
+		if pending != "" {
+			fields[pending] = append(fields[pending], line)
+			pending = ""
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if value = strings.TrimSpace(value); value == "" {
+			pending = key
+			continue
+		}
+		fields[key] = append(fields[key], value)
+	}
+	return fields
+}
+
+// firstField returns the first value of the first key present.
+func firstField(fields map[string][]string, keys ...string) string {
+	for _, k := range keys {
+		if v := fields[k]; len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// parseWhois extracts expiry, registrar and status codes from a registry
+// reply. A reply without an expiry date is checked for "not found" wording,
+// which differs between registries.
+func parseWhois(resp string) (*registration, error) {
+	fields := whoisFields(resp)
+	reg := &registration{Registrar: firstField(fields, "registrar", "sponsoring registrar", "registrar name")}
+	raw := firstField(fields, whoisExpiryKeys...)
+	if raw == "" {
+		lower := strings.ToLower(resp)
+		for _, marker := range []string{"no match for", "not found", "no data found", "no entries found", "status: free", "status: available"} {
+			if strings.Contains(lower, marker) {
+				return nil, errNotFound
+			}
+		}
+		return nil, fmt.Errorf("no expiry date in WHOIS response")
+	}
+	t, err := parseWhoisDate(raw)
+	if err != nil {
+		return nil, err
+	}
+	reg.Expiry = t
+	for _, s := range append(fields["domain status"], fields["status"]...) {
+		if code := eppStatus(s); code != "" {
+			reg.Statuses = append(reg.Statuses, code)
+		}
+	}
+	return reg, nil
+}
+
+// parseWhoisDate tries the known layouts on the whole value, then on its
+// first word (some registries append "(YYYY-MM-DD)" or a time zone name).
+func parseWhoisDate(raw string) (time.Time, error) {
+	candidates := []string{raw}
+	if f := strings.Fields(raw); len(f) > 1 {
+		candidates = append(candidates, f[0])
+	}
+	for _, c := range candidates {
+		for _, layout := range whoisDateLayouts {
+			if t, err := time.Parse(layout, c); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized WHOIS expiry date %q", raw)
+}
@@ -0,0 +1,428 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of an HTTP Directory Brute-Forcer.
+PURPOSE: Show skill in concurrent HTTP clients, response filtering, recursive content discovery, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "http_dir_bruteforcer"
+	toolVersion = "1.0.0"
+)
+
+// headerList collects repeatable -H "Name: value" flags.
+type headerList []string
+
+func (h *headerList) String() string { return strings.Join(*h, ", ") }
+func (h *headerList) Set(v string) error {
+	if name, _, ok := strings.Cut(v, ":"); !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected \"Name: value\", got %q", v)
+	}
+	*h = append(*h, v)
+	return nil
+}
+
+// Global variables for CLI flags
+var (
+	targetURL    string
+	wordlistFile string
+	extensions   string
+	outputFile   string
+	format       string
+	matchList    string
+	filterList   string
+	sizeList     string
+	headers      headerList
+	timeoutSec   int
+	concurrency  int
+	maxDepth     int
+	insecure     bool
+	noCalibrate  bool
+	verboseMode  bool
+)
+
+func init() {
+	flag.StringVar(&targetURL, "url", "", "Base URL to brute-force (e.g. https://app.example.com/).")
+	flag.StringVar(&targetURL, "u", "", "Base URL to brute-force (shorthand).")
+
+	flag.StringVar(&wordlistFile, "wordlist", "", "Path to a file of paths to try, one per line; entries ending in / are tried as directories only.")
+	flag.StringVar(&wordlistFile, "w", "", "Path to a wordlist file (shorthand).")
+
+	flag.StringVar(&extensions, "extensions", "", "Comma-separated extensions also tried for each word (e.g. php,bak,txt).")
+	flag.StringVar(&extensions, "x", "", "Comma-separated extensions (shorthand).")
+
+	flag.StringVar(&matchList, "match-codes", "200,204,301,302,307,308,401,403", "Comma-separated status codes reported as hits.")
+	flag.StringVar(&filterList, "filter-codes", "", "Comma-separated status codes never reported, even if matched.")
+	flag.StringVar(&sizeList, "filter-size", "", "Comma-separated response body lengths (bytes) never reported, e.g. the size of a custom error page.")
+	flag.BoolVar(&noCalibrate, "no-calibrate", false, "Do not probe each directory with a random path to filter out its soft-404 responses.")
+
+	flag.IntVar(&maxDepth, "depth", 0, "Recursion depth: 0 scans only the base URL, 1 also scans the directories found there, and so on.")
+
+	flag.IntVar(&concurrency, "concurrency", 10, "Number of requests in flight at once.")
+	flag.IntVar(&concurrency, "c", 10, "Number of requests in flight at once (shorthand).")
+
+	flag.IntVar(&timeoutSec, "timeout", 10, "Request timeout in seconds.")
+	flag.IntVar(&timeoutSec, "t", 10, "Request timeout in seconds (shorthand).")
+
+	flag.Var(&headers, "header", "Extra request header \"Name: value\", e.g. a session cookie for an authenticated scan (repeatable).")
+	flag.Var(&headers, "H", "Extra request header (shorthand, repeatable).")
+
+	flag.BoolVar(&insecure, "insecure", false, "Do not verify the server's TLS certificate (self-signed test environments).")
+	flag.BoolVar(&insecure, "k", false, "Do not verify the server's TLS certificate (shorthand).")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerVersionFlag()
+	registerTZFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Discovers unlinked directories and files on a web server from a wordlist (authorized testing only).\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s -u https://app.example.com/ -w wordlist.txt -x php,bak\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -u http://10.0.0.5/ -w wordlist.txt --depth 2 --filter-size 1245 -f json -o hits.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// readWords returns the non-empty, non-comment lines of a wordlist.
+func readWords(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wordlist %s: %w", path, err)
+	}
+	defer file.Close()
+	var words []string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word != "" && !strings.HasPrefix(word, "#") && !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words, scanner.Err()
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseCodes(flagName, list string) (map[int]bool, error) {
+	codes := map[int]bool{}
+	for _, item := range splitList(list) {
+		code, err := strconv.Atoi(item)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status code %q in --%s", item, flagName)
+		}
+		codes[code] = true
+	}
+	return codes, nil
+}
+
+func parseSizes(list string) (map[int64]bool, error) {
+	sizes := map[int64]bool{}
+	for _, item := range splitList(list) {
+		size, err := strconv.ParseInt(item, 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid length %q in --filter-size", item)
+		}
+		sizes[size] = true
+	}
+	return sizes, nil
+}
+
+// newScanner builds the scanner from the flags. Redirects are not followed:
+// a redirect is itself a hit, and tells directories apart from files.
+func newScanner() (*scanner, error) {
+	s := &scanner{headers: http.Header{}}
+	var err error
+	if s.matchCodes, err = parseCodes("match-codes", matchList); err != nil {
+		return nil, err
+	}
+	if s.filterCodes, err = parseCodes("filter-codes", filterList); err != nil {
+		return nil, err
+	}
+	if s.filterSizes, err = parseSizes(sizeList); err != nil {
+		return nil, err
+	}
+	for _, h := range headers {
+		name, value, _ := strings.Cut(h, ":")
+		s.headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = concurrency
+	transport.MaxIdleConnsPerHost = concurrency
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	s.client = &http.Client{
+		Transport:     transport,
+		Timeout:       time.Duration(timeoutSec) * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return s, nil
+}
+
+// scanSummary is the run-level part of the report.
+type scanSummary struct {
+	Target      string
+	Words       int
+	Extensions  []string
+	Dirs        int
+	Requests    int64
+	Errors      int64
+	FirstErr    error
+	Started     time.Time
+	Interrupted bool
+}
+
+// sortHits orders hits by URL, so a directory's contents follow it.
+func sortHits(hits []Hit) {
+	sort.Slice(hits, func(i, j int) bool { return hits[i].URL < hits[j].URL })
+}
+
+// writeReport writes the discovered paths as a text report.
+func writeReport(hits []Hit, s scanSummary, output io.Writer) {
+	fmt.Fprintf(output, "--- HTTP Directory Brute-Force Report ---\n\n")
+	fmt.Fprintf(output, "Target: %s\n", s.Target)
+	fmt.Fprintf(output, "Wordlist Entries: %d\n", s.Words)
+	if len(s.Extensions) > 0 {
+		fmt.Fprintf(output, "Extensions: %s\n", strings.Join(s.Extensions, ", "))
+	}
+	fmt.Fprintf(output, "Directories Scanned: %d\nRequests Sent: %d\n", s.Dirs, s.Requests)
+	if s.Errors > 0 {
+		fmt.Fprintf(output, "Request Errors: %d (first: %v)\n", s.Errors, s.FirstErr)
+	}
+	fmt.Fprintln(output, "------------------------------")
+	for _, h := range hits {
+		line := fmt.Sprintf("%-5s %10d  %s", colorStatus(h.Status), h.Length, h.URL)
+		if h.Redirect != "" {
+			line += " -> " + h.Redirect
+		}
+		if h.Directory {
+			line += "  [DIR]"
+		}
+		fmt.Fprintln(output, line)
+	}
+	fmt.Fprintln(output, "------------------------------")
+	fmt.Fprintf(output, "Paths Found: %d\n", len(hits))
+}
+
+// jsonReport is the --format json report.
+type jsonReport struct {
+	Tool        string     `json:"tool"`
+	Version     string     `json:"version"`
+	GitCommit   string     `json:"git_commit,omitempty"`
+	BuildDate   string     `json:"build_date,omitempty"`
+	Target      string     `json:"target"`
+	ScanStart   time.Time  `json:"scan_start"`
+	ScanEnd     time.Time  `json:"scan_end"`
+	Interrupted bool       `json:"interrupted"`
+	Words       int        `json:"wordlist_entries"`
+	Extensions  []string   `json:"extensions"`
+	Dirs        int        `json:"directories_scanned"`
+	Requests    int64      `json:"requests"`
+	Errors      int64      `json:"request_errors"`
+	FirstError  string     `json:"first_error,omitempty"`
+	ErrorClass  string     `json:"error_class,omitempty"`
+	Results     []Hit      `json:"results"`
+	SelfStats   *selfStats `json:"self_stats,omitempty"`
+}
+
+// writeJSONReport writes the hits and run summary as one JSON document.
+func writeJSONReport(hits []Hit, s scanSummary, w io.Writer) error {
+	build := currentBuild()
+	rep := jsonReport{
+		Tool:        toolName,
+		Version:     build.Version,
+		GitCommit:   build.GitCommit,
+		BuildDate:   build.BuildDate,
+		Target:      s.Target,
+		ScanStart:   s.Started.In(reportTZ),
+		ScanEnd:     time.Now().In(reportTZ),
+		Interrupted: s.Interrupted,
+		Words:       s.Words,
+		Extensions:  append([]string{}, s.Extensions...),
+		Dirs:        s.Dirs,
+		Requests:    s.Requests,
+		Errors:      s.Errors,
+		ErrorClass:  classifyError(s.FirstErr),
+		Results:     append([]Hit{}, hits...),
+	}
+	if s.FirstErr != nil {
+		rep.FirstError = s.FirstErr.Error()
+	}
+	if selfStatsOn {
+		stats := collectSelfStats(int(s.Requests), "requests")
+		rep.SelfStats = &stats
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// main is the entry point of the HTTP Directory Brute-Forcer tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+	if err := applyTZ(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+
+	if targetURL == "" || wordlistFile == "" {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] A base URL (-u) and a wordlist (-w) must be provided.")
+		os.Exit(1)
+	}
+	base, err := url.Parse(targetURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid URL %q: expected http(s)://host[/path]\n", targetURL)
+		os.Exit(1)
+	}
+	base.RawQuery, base.Fragment = "", ""
+	base.Path = strings.TrimSuffix(base.Path, "/") + "/"
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --format %q (use text or json)\n", format)
+		os.Exit(1)
+	}
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if maxDepth < 0 {
+		maxDepth = 0
+	}
+
+	words, err := readWords(wordlistFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	exts := splitList(extensions)
+	for i, ext := range exts {
+		exts[i] = strings.TrimPrefix(ext, ".")
+	}
+	s, err := newScanner()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Brute-forcing %s with %d word(s) and %d extension(s), %d request(s) in flight...\n", base, len(words), len(exts), concurrency)
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+
+	// SIGINT/SIGTERM stop the scan; paths found so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	started := time.Now()
+	hits, dirs := s.run(ctx, base.String(), words, exts)
+	interrupted := ctx.Err() != nil
+	if interrupted {
+		stop() // A second signal terminates immediately
+	}
+	sortHits(hits)
+	summary := scanSummary{
+		Target: base.String(), Words: len(words), Extensions: exts, Dirs: dirs,
+		Requests: s.requests.Load(), Errors: s.errors.Load(), FirstErr: s.firstErr,
+		Started: started, Interrupted: interrupted,
+	}
+	if format == "json" {
+		if err := writeJSONReport(hits, summary, output); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		writeReport(hits, summary, output)
+		if interrupted {
+			fmt.Fprintf(output, "Partial report: interrupted after %d request(s).\n", summary.Requests)
+		}
+		if selfStatsOn {
+			writeSelfStats(output, collectSelfStats(int(summary.Requests), "requests"))
+		}
+	}
+
+	inputs := []string{wordlistFile}
+	if interrupted {
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		writeManifest(130, inputs, []string{outputFile, signatureTarget(outputFile)})
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Directory brute-force complete.")
+	}
+	writeManifest(0, inputs, []string{outputFile, signatureTarget(outputFile)})
+	os.Exit(0)
+}
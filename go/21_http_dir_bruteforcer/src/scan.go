@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maxBodyRead bounds how much of a response body is read to measure its
+// length; larger bodies are reported with this length.
+const maxBodyRead = 10 << 20
+
+// Hit is one path that passed the status and length filters.
+type Hit struct {
+	URL       string `json:"url"`
+	Status    int    `json:"status"`
+	Length    int64  `json:"length"`
+	Redirect  string `json:"redirect,omitempty"`
+	Depth     int    `json:"depth"` // Directory level it was found at; 0 is the base URL
+	Directory bool   `json:"directory"`
+}
+
+// response is what one request observed.
+type response struct {
+	status   int
+	length   int64
+	location string
+}
+
+// soft404 is how a directory answers for a path that cannot exist. Servers
+// that send 200 (or a redirect to a login page) for everything would
+// otherwise turn every wordlist entry into a hit.
+type soft404 struct {
+	status int
+	length int64
+	ok     bool
+}
+
+// dirJob is a directory to brute-force, always ending in "/".
+type dirJob struct {
+	url   string
+	depth int
+}
+
+// scanner holds the request settings and filters of a run.
+type scanner struct {
+	client      *http.Client
+	headers     http.Header
+	matchCodes  map[int]bool
+	filterCodes map[int]bool
+	filterSizes map[int64]bool
+	requests    atomic.Int64
+	errors      atomic.Int64
+	mu          sync.Mutex
+	firstErr    error // First request error, for the report
+}
+
+func (s *scanner) fetch(ctx context.Context, target string) (response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return response{}, err
+	}
+	req.Header = s.headers.Clone()
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", toolName+"/"+toolVersion)
+	}
+	s.requests.Add(1)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return response{}, err
+	}
+	defer resp.Body.Close()
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyRead))
+	if err != nil {
+		return response{}, err
+	}
+	return response{status: resp.StatusCode, length: n, location: resp.Header.Get("Location")}, nil
+}
+
+// noteError counts a failed request, unless the interrupt cut it short.
+func (s *scanner) noteError(ctx context.Context, target string, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	s.errors.Add(1)
+	s.mu.Lock()
+	if s.firstErr == nil {
+		s.firstErr = err
+	}
+	s.mu.Unlock()
+	debugf("%s: %v", target, err)
+}
+
+// calibrate requests a random path in dir to learn its soft-404 response.
+func (s *scanner) calibrate(ctx context.Context, dir string) soft404 {
+	b := make([]byte, 12)
+	rand.Read(b)
+	target := dir + hex.EncodeToString(b)
+	r, err := s.fetch(ctx, target)
+	if err != nil {
+		s.noteError(ctx, target, err)
+		return soft404{}
+	}
+	if !s.matchCodes[r.status] {
+		return soft404{}
+	}
+	return soft404{status: r.status, length: r.length, ok: true}
+}
+
+// keep applies --match-codes, --filter-codes, --filter-size and the
+// directory's soft-404 response.
+func (s *scanner) keep(r response, soft soft404) bool {
+	if !s.matchCodes[r.status] || s.filterCodes[r.status] || s.filterSizes[r.length] {
+		return false
+	}
+	return !(soft.ok && r.status == soft.status && r.length == soft.length)
+}
+
+// isDirectory reports whether target is a directory: the server redirects
+// it to the same path with a trailing slash, or a word ending in "/" exists.
+func isDirectory(target string, r response) bool {
+	if strings.HasSuffix(target, "/") {
+		return r.status < 300 || r.status == http.StatusUnauthorized || r.status == http.StatusForbidden
+	}
+	if r.status < 300 || r.status >= 400 || r.location == "" {
+		return false
+	}
+	base, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	loc, err := base.Parse(r.location)
+	return err == nil && loc.Host == base.Host && loc.Path == base.Path+"/"
+}
+
+// candidates expands the wordlist for dir: each word as is, then with each
+// extension. Words ending in "/" are only tried as directories.
+func candidates(dir string, words, exts []string) []string {
+	var paths []string
+	for _, w := range words {
+		escaped := (&url.URL{Path: strings.TrimPrefix(w, "/")}).EscapedPath()
+		paths = append(paths, dir+escaped)
+		if strings.HasSuffix(w, "/") {
+			continue
+		}
+		for _, ext := range exts {
+			paths = append(paths, dir+escaped+"."+ext)
+		}
+	}
+	return paths
+}
+
+// scanDir requests every candidate in d with a bounded worker pool.
+func (s *scanner) scanDir(ctx context.Context, d dirJob, paths []string, soft soft404) []Hit {
+	jobs := make(chan string)
+	hits := make(chan Hit, len(paths))
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				r, err := s.fetch(ctx, target)
+				if err != nil {
+					s.noteError(ctx, target, err)
+					continue
+				}
+				debugf("%s: %d, %d byte(s)", target, r.status, r.length)
+				if s.keep(r, soft) {
+					hits <- Hit{URL: target, Status: r.status, Length: r.length, Redirect: r.location, Depth: d.depth, Directory: isDirectory(target, r)}
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, p := range paths {
+			select {
+			case jobs <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(hits)
+
+	var found []Hit
+	for h := range hits {
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Found: %s (%d)\n", h.URL, h.Status)
+		}
+		found = append(found, h)
+	}
+	return found
+}
+
+// run brute-forces base and, up to maxDepth levels below it, the
+// directories found. It returns the hits and the number of directories
+// scanned; if ctx is cancelled the hits so far are returned.
+func (s *scanner) run(ctx context.Context, base string, words, exts []string) (hits []Hit, dirs int) {
+	queue := []dirJob{{url: base}}
+	queued := map[string]bool{base: true}
+	for len(queue) > 0 && ctx.Err() == nil {
+		d := queue[0]
+		queue = queue[1:]
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Scanning %s (depth %d)...\n", d.url, d.depth)
+		}
+		var soft soft404
+		if !noCalibrate {
+			soft = s.calibrate(ctx, d.url)
+			if soft.ok {
+				warnf("%s answers unknown paths with %d (%d bytes); responses like that are filtered out.", d.url, soft.status, soft.length)
+			}
+		}
+		found := s.scanDir(ctx, d, candidates(d.url, words, exts), soft)
+		dirs++
+		for _, h := range found {
+			hits = append(hits, h)
+			next := strings.TrimSuffix(h.URL, "/") + "/"
+			if h.Directory && d.depth < maxDepth && !queued[next] {
+				queued[next] = true
+				queue = append(queue, dirJob{url: next, depth: d.depth + 1})
+			}
+		}
+	}
+	return hits, dirs
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBeaconEvents bounds the connection times kept per flow.
+const maxBeaconEvents = 10000
+
+type hostStats struct {
+	SentBytes int64 `json:"sent_bytes"`
+	RecvBytes int64 `json:"received_bytes"`
+	Packets   int   `json:"packets"`
+}
+
+type protoStats struct {
+	Packets int   `json:"packets"`
+	Bytes   int64 `json:"bytes"`
+}
+
+type servicePort struct {
+	proto string
+	port  uint16
+}
+
+type dnsKey struct {
+	name  string
+	qtype uint16
+}
+
+type hostPair struct{ src, dst netip.Addr }
+
+type sweepKey struct {
+	src  netip.Addr
+	port uint16
+}
+
+// flowKey identifies a client-to-service flow for beacon detection.
+type flowKey struct {
+	src, dst netip.Addr
+	proto    string
+	port     uint16
+}
+
+// Finding is one suspicious pattern.
+type Finding struct {
+	Severity string `json:"severity"` // HIGH or MEDIUM
+	Type     string `json:"type"`     // port_scan, host_sweep or beaconing
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Detail   string `json:"detail"`
+}
+
+// summary accumulates statistics over the packets of a capture.
+type summary struct {
+	packets   int
+	undecoded int // Non-IP frames and truncated headers
+	bytes     int64
+	first     time.Time
+	last      time.Time
+	hosts     map[netip.Addr]*hostStats
+	protos    map[string]*protoStats
+	services  map[servicePort]int
+	queries   map[dnsKey]int
+	nxdomains int
+	synPorts  map[hostPair]map[uint16]bool     // Ports each source sent SYNs to, per target
+	openPorts map[hostPair]map[uint16]bool     // Ports that answered SYN/ACK, keyed like synPorts
+	sweeps    map[sweepKey]map[netip.Addr]bool // Targets each source sent SYNs to, per port
+	starts    map[flowKey][]time.Time          // Connection (or datagram burst) start times
+}
+
+func newSummary() *summary {
+	return &summary{
+		hosts:     map[netip.Addr]*hostStats{},
+		protos:    map[string]*protoStats{},
+		services:  map[servicePort]int{},
+		queries:   map[dnsKey]int{},
+		synPorts:  map[hostPair]map[uint16]bool{},
+		openPorts: map[hostPair]map[uint16]bool{},
+		sweeps:    map[sweepKey]map[netip.Addr]bool{},
+		starts:    map[flowKey][]time.Time{},
+	}
+}
+
+func protoName(proto int) string {
+	switch proto {
+	case protoTCP:
+		return "TCP"
+	case protoUDP:
+		return "UDP"
+	case protoICMP:
+		return "ICMP"
+	case protoICMPv6:
+		return "ICMPv6"
+	}
+	return "IP/" + strconv.Itoa(proto)
+}
+
+// add records one packet.
+func (s *summary) add(p packet) {
+	s.packets++
+	s.bytes += int64(p.origLen)
+	if !p.ts.IsZero() {
+		if s.first.IsZero() || p.ts.Before(s.first) {
+			s.first = p.ts
+		}
+		if p.ts.After(s.last) {
+			s.last = p.ts
+		}
+	}
+	d, ok := decodePacket(p)
+	if !ok {
+		s.undecoded++
+		s.count(s.protos, "non-IP", p.origLen)
+		return
+	}
+	proto := protoName(d.proto)
+	s.count(s.protos, proto, p.origLen)
+	s.host(d.src).SentBytes += int64(p.origLen)
+	s.host(d.src).Packets++
+	s.host(d.dst).RecvBytes += int64(p.origLen)
+	s.host(d.dst).Packets++
+	if d.fragment || (d.proto != protoTCP && d.proto != protoUDP) {
+		return
+	}
+	// The lower port of a packet is usually the service's; count it.
+	s.services[servicePort{proto, min(d.sport, d.dport)}]++
+
+	if q := d.dns; q != nil {
+		if !q.response {
+			s.queries[dnsKey{q.name, q.qtype}]++
+		} else if q.rcode == 3 {
+			s.nxdomains++
+		}
+	}
+	if d.proto == protoTCP {
+		switch d.tcpFlags & (tcpSYN | tcpACK) {
+		case tcpSYN:
+			addSet(s.synPorts, hostPair{d.src, d.dst}, d.dport)
+			addSet(s.sweeps, sweepKey{d.src, d.dport}, d.dst)
+			s.start(flowKey{d.src, d.dst, proto, d.dport}, p.ts, false)
+		case tcpSYN | tcpACK:
+			addSet(s.openPorts, hostPair{d.dst, d.src}, d.sport)
+		}
+	} else if d.dport <= d.sport {
+		s.start(flowKey{d.src, d.dst, proto, d.dport}, p.ts, true)
+	}
+}
+
+func (s *summary) count(m map[string]*protoStats, key string, length int) {
+	if m[key] == nil {
+		m[key] = &protoStats{}
+	}
+	m[key].Packets++
+	m[key].Bytes += int64(length)
+}
+
+func (s *summary) host(a netip.Addr) *hostStats {
+	if s.hosts[a] == nil {
+		s.hosts[a] = &hostStats{}
+	}
+	return s.hosts[a]
+}
+
+func addSet[K comparable, V comparable](m map[K]map[V]bool, key K, value V) {
+	if m[key] == nil {
+		m[key] = map[V]bool{}
+	}
+	m[key][value] = true
+}
+
+// start records a connection start. For UDP, datagrams less than a second
+// after the previous one belong to the same burst.
+func (s *summary) start(key flowKey, ts time.Time, burst bool) {
+	events := s.starts[key]
+	if ts.IsZero() || len(events) >= maxBeaconEvents {
+		return
+	}
+	if burst && len(events) > 0 && ts.Sub(events[len(events)-1]) < time.Second {
+		return
+	}
+	s.starts[key] = append(events, ts)
+}
+
+// findings applies the port scan, host sweep and beaconing thresholds.
+func (s *summary) findings() []Finding {
+	var out []Finding
+	for pair, ports := range s.synPorts {
+		if len(ports) < scanPorts {
+			continue
+		}
+		detail := fmt.Sprintf("SYNs to %d ports (%s)", len(ports), portList(ports, 10))
+		if open := s.openPorts[pair]; len(open) > 0 {
+			detail += fmt.Sprintf("; %d answered SYN/ACK (%s)", len(open), portList(open, 10))
+		}
+		out = append(out, Finding{Severity: "HIGH", Type: "port_scan", Source: pair.src.String(), Target: pair.dst.String(), Detail: detail})
+	}
+	for key, targets := range s.sweeps {
+		if len(targets) < scanHosts {
+			continue
+		}
+		out = append(out, Finding{Severity: "HIGH", Type: "host_sweep", Source: key.src.String(), Target: fmt.Sprintf("tcp/%d", key.port),
+			Detail: fmt.Sprintf("SYNs to port %d on %d hosts", key.port, len(targets))})
+	}
+	for key, events := range s.starts {
+		if len(events) < beaconMin {
+			continue
+		}
+		sort.Slice(events, func(i, j int) bool { return events[i].Before(events[j]) })
+		mean, jitter := intervalStats(events)
+		if mean < time.Second || jitter > beaconJitter/100 {
+			continue
+		}
+		target := netip.AddrPortFrom(key.dst, key.port).String() + "/" + strings.ToLower(key.proto)
+		out = append(out, Finding{Severity: "MEDIUM", Type: "beaconing", Source: key.src.String(), Target: target,
+			Detail: fmt.Sprintf("%d connections every %s (jitter %.1f%%) from %s to %s", len(events), mean.Round(100*time.Millisecond), jitter*100,
+				stamp(events[0]), stamp(events[len(events)-1]))})
+	}
+	rank := map[string]int{"HIGH": 0, "MEDIUM": 1}
+	sort.Slice(out, func(i, j int) bool {
+		if rank[out[i].Severity] != rank[out[j].Severity] {
+			return rank[out[i].Severity] < rank[out[j].Severity]
+		}
+		if out[i].Type != out[j].Type {
+			return out[i].Type < out[j].Type
+		}
+		return out[i].Source+" "+out[i].Target < out[j].Source+" "+out[j].Target
+	})
+	return out
+}
+
+// intervalStats returns the mean gap between sorted event times and its
+// coefficient of variation (standard deviation / mean).
+func intervalStats(events []time.Time) (time.Duration, float64) {
+	n := float64(len(events) - 1)
+	var sum float64
+	for i := 1; i < len(events); i++ {
+		sum += events[i].Sub(events[i-1]).Seconds()
+	}
+	mean := sum / n
+	var variance float64
+	for i := 1; i < len(events); i++ {
+		d := events[i].Sub(events[i-1]).Seconds() - mean
+		variance += d * d
+	}
+	if mean == 0 {
+		return 0, 0
+	}
+	return time.Duration(mean * float64(time.Second)), math.Sqrt(variance/n) / mean
+}
+
+// portList formats up to limit ports in ascending order.
+func portList(ports map[uint16]bool, limit int) string {
+	sorted := make([]int, 0, len(ports))
+	for p := range ports {
+		sorted = append(sorted, int(p))
+	}
+	sort.Ints(sorted)
+	var parts []string
+	for i, p := range sorted {
+		if i == limit {
+			parts = append(parts, "...")
+			break
+		}
+		parts = append(parts, strconv.Itoa(p))
+	}
+	return strings.Join(parts, ", ")
+}
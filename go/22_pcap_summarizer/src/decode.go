@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// IP protocol numbers.
+const (
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+)
+
+// TCP flags.
+const (
+	tcpSYN = 0x02
+	tcpACK = 0x10
+)
+
+// decoded is the part of a packet the summary needs.
+type decoded struct {
+	src, dst     netip.Addr
+	proto        int
+	sport, dport uint16
+	tcpFlags     byte
+	fragment     bool // Non-first IPv4/IPv6 fragment: no transport header
+	dns          *dnsQuestion
+}
+
+// dnsQuestion is the question of a DNS query or response.
+type dnsQuestion struct {
+	name     string
+	qtype    uint16
+	response bool
+	rcode    int
+}
+
+// decodePacket strips the link layer and decodes IP, TCP/UDP and DNS.
+// ok is false for non-IP frames (ARP, LLDP...) and truncated headers.
+func decodePacket(p packet) (d decoded, ok bool) {
+	payload, ok := stripLink(p.linkType, p.data)
+	if !ok || len(payload) < 1 {
+		return d, false
+	}
+	var transport []byte
+	switch payload[0] >> 4 {
+	case 4:
+		if len(payload) < 20 {
+			return d, false
+		}
+		ihl := int(payload[0]&0x0f) * 4
+		if ihl < 20 || len(payload) < ihl {
+			return d, false
+		}
+		d.src = netip.AddrFrom4([4]byte(payload[12:16]))
+		d.dst = netip.AddrFrom4([4]byte(payload[16:20]))
+		d.proto = int(payload[9])
+		d.fragment = binary.BigEndian.Uint16(payload[6:])&0x1fff != 0
+		transport = payload[ihl:]
+	case 6:
+		if len(payload) < 40 {
+			return d, false
+		}
+		d.src = netip.AddrFrom16([16]byte(payload[8:24]))
+		d.dst = netip.AddrFrom16([16]byte(payload[24:40]))
+		next, rest := int(payload[6]), payload[40:]
+		for (next == 0 || next == 43 || next == 44 || next == 60) && len(rest) >= 8 { // Extension headers
+			if next == 44 && binary.BigEndian.Uint16(rest[2:])&0xfff8 != 0 {
+				d.fragment = true
+			}
+			size := 8
+			if next != 44 {
+				size = (int(rest[1]) + 1) * 8
+			}
+			if len(rest) < size {
+				return d, false
+			}
+			next, rest = int(rest[0]), rest[size:]
+		}
+		d.proto, transport = next, rest
+	default:
+		return d, false
+	}
+	if d.fragment {
+		return d, true
+	}
+
+	switch d.proto {
+	case protoTCP:
+		if len(transport) >= 14 {
+			d.sport, d.dport = binary.BigEndian.Uint16(transport), binary.BigEndian.Uint16(transport[2:])
+			d.tcpFlags = transport[13]
+		}
+	case protoUDP:
+		if len(transport) >= 8 {
+			d.sport, d.dport = binary.BigEndian.Uint16(transport), binary.BigEndian.Uint16(transport[2:])
+			if d.sport == 53 || d.dport == 53 || d.sport == 5353 || d.dport == 5353 {
+				d.dns = parseDNSQuestion(transport[8:])
+			}
+		}
+	}
+	return d, true
+}
+
+// stripLink returns the network-layer payload of a frame.
+func stripLink(linkType int, data []byte) ([]byte, bool) {
+	switch linkType {
+	case linkEthernet:
+		if len(data) < 14 {
+			return nil, false
+		}
+		etherType, rest := binary.BigEndian.Uint16(data[12:]), data[14:]
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(rest) >= 4 { // VLAN tags
+			etherType, rest = binary.BigEndian.Uint16(rest[2:]), rest[4:]
+		}
+		return rest, etherType == 0x0800 || etherType == 0x86dd
+	case linkSLL:
+		if len(data) < 16 {
+			return nil, false
+		}
+		etherType := binary.BigEndian.Uint16(data[14:])
+		return data[16:], etherType == 0x0800 || etherType == 0x86dd
+	case linkSLL2:
+		if len(data) < 20 {
+			return nil, false
+		}
+		etherType := binary.BigEndian.Uint16(data)
+		return data[20:], etherType == 0x0800 || etherType == 0x86dd
+	case linkNull, linkLoop:
+		if len(data) < 4 {
+			return nil, false
+		}
+		return data[4:], true // The version nibble tells IPv4 from IPv6
+	case linkRaw, linkRawAlt:
+		return data, true
+	}
+	return nil, false
+}
+
+// parseDNSQuestion reads the first question of a DNS message.
+func parseDNSQuestion(msg []byte) *dnsQuestion {
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg[4:]) == 0 {
+		return nil
+	}
+	q := &dnsQuestion{response: msg[2]&0x80 != 0, rcode: int(msg[3] & 0x0f)}
+	var labels []string
+	off := 12
+	for {
+		if off >= len(msg) {
+			return nil
+		}
+		n := int(msg[off])
+		if n == 0 {
+			off++
+			break
+		}
+		if n&0xc0 != 0 || off+1+n > len(msg) { // Questions are not compressed
+			return nil
+		}
+		labels = append(labels, strings.ToLower(string(msg[off+1:off+1+n])))
+		off += 1 + n
+	}
+	if off+2 > len(msg) {
+		return nil
+	}
+	q.name = strings.Join(labels, ".")
+	if q.name == "" {
+		q.name = "."
+	}
+	q.qtype = binary.BigEndian.Uint16(msg[off:])
+	return q
+}
+
+// qtypeName returns the mnemonic of common DNS record types.
+func qtypeName(t uint16) string {
+	names := map[uint16]string{1: "A", 2: "NS", 5: "CNAME", 6: "SOA", 12: "PTR", 15: "MX", 16: "TXT", 28: "AAAA", 33: "SRV", 65: "HTTPS", 255: "ANY"}
+	if n, ok := names[t]; ok {
+		return n
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
@@ -0,0 +1,408 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of a Packet Capture Summarizer.
+PURPOSE: Show skill in binary file format parsing, protocol decoding, traffic analytics, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "pcap_summarizer"
+	toolVersion = "1.0.0"
+)
+
+// Global variables for CLI flags
+var (
+	captureFile  string
+	liveIface    string
+	outputFile   string
+	format       string
+	durationSec  int
+	maxPackets   int
+	topN         int
+	scanPorts    int
+	scanHosts    int
+	beaconMin    int
+	beaconJitter float64
+	verboseMode  bool
+)
+
+// openLive opens a live capture on an interface. It is set by live.go,
+// which is only compiled with the "live" build tag (Linux).
+var openLive func(iface string, duration time.Duration) (packetSource, error)
+
+// errIdle is returned by a live source when no packet arrived in time, so
+// the read loop can check for interrupts.
+var errIdle = errors.New("no packet")
+
+func init() {
+	flag.StringVar(&captureFile, "read", "", "Capture file to summarize (pcap or pcapng, as written by tcpdump, Wireshark or dumpcap).")
+	flag.StringVar(&captureFile, "r", "", "Capture file to summarize (shorthand).")
+
+	flag.StringVar(&liveIface, "live", "", "Capture live on this interface instead (Linux, needs root or CAP_NET_RAW; only in builds with -tags live).")
+	flag.IntVar(&durationSec, "duration", 0, "Stop a live capture after this many seconds (0: until interrupted or --count).")
+	flag.IntVar(&maxPackets, "count", 0, "Stop after this many packets (0: no limit).")
+
+	flag.IntVar(&topN, "top", 10, "Number of entries in the top talker, service and DNS query lists.")
+	flag.IntVar(&scanPorts, "scan-ports", 25, "Report a port scan when one source sends SYNs to at least this many ports of one host.")
+	flag.IntVar(&scanHosts, "scan-hosts", 25, "Report a host sweep when one source sends SYNs to the same port on at least this many hosts.")
+	flag.IntVar(&beaconMin, "beacon-min", 6, "Minimum connections from a client to the same service before checking it for beaconing.")
+	flag.Float64Var(&beaconJitter, "beacon-jitter", 10, "Report beaconing when the connection intervals vary by at most this percentage (standard deviation / mean).")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerVersionFlag()
+	registerTZFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Summarizes a packet capture: top talkers, protocols, DNS queries, port scans and beaconing.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s -r capture.pcap\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -r capture.pcapng --beacon-jitter 5 -f json -o summary.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: sudo %s --live eth0 --duration 300\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+type talker struct {
+	Address string `json:"address"`
+	hostStats
+}
+
+type protoEntry struct {
+	Protocol string `json:"protocol"`
+	protoStats
+}
+
+type serviceEntry struct {
+	Service string `json:"service"` // e.g. tcp/443
+	Packets int    `json:"packets"`
+}
+
+type queryEntry struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Queries int    `json:"queries"`
+}
+
+// report is the rendered summary, shared by the text and JSON formats.
+type report struct {
+	Tool        string         `json:"tool"`
+	Version     string         `json:"version"`
+	GitCommit   string         `json:"git_commit,omitempty"`
+	BuildDate   string         `json:"build_date,omitempty"`
+	Source      string         `json:"source"`
+	Format      string         `json:"format"`
+	FirstPacket *time.Time     `json:"first_packet,omitempty"`
+	LastPacket  *time.Time     `json:"last_packet,omitempty"`
+	Packets     int            `json:"packets"`
+	Bytes       int64          `json:"bytes"`
+	Undecoded   int            `json:"undecoded"`
+	Interrupted bool           `json:"interrupted"`
+	ReadError   string         `json:"read_error,omitempty"`
+	ErrorClass  string         `json:"error_class,omitempty"`
+	Protocols   []protoEntry   `json:"protocols"`
+	TopTalkers  []talker       `json:"top_talkers"`
+	TopServices []serviceEntry `json:"top_services"`
+	DNSQueries  []queryEntry   `json:"dns_queries"`
+	NXDomains   int            `json:"nxdomain_responses"`
+	Findings    []Finding      `json:"findings"`
+	SelfStats   *selfStats     `json:"self_stats,omitempty"`
+}
+
+// buildReport sorts the summary into top-N lists and findings.
+func buildReport(s *summary, source, kind string) report {
+	build := currentBuild()
+	r := report{
+		Tool: toolName, Version: build.Version, GitCommit: build.GitCommit, BuildDate: build.BuildDate,
+		Source: source, Format: kind, Packets: s.packets, Bytes: s.bytes, Undecoded: s.undecoded,
+		NXDomains: s.nxdomains, Findings: s.findings(),
+		Protocols: []protoEntry{}, TopTalkers: []talker{}, TopServices: []serviceEntry{}, DNSQueries: []queryEntry{},
+	}
+	if r.Findings == nil {
+		r.Findings = []Finding{}
+	}
+	if !s.first.IsZero() {
+		first, last := s.first.In(reportTZ), s.last.In(reportTZ)
+		r.FirstPacket, r.LastPacket = &first, &last
+	}
+	for name, p := range s.protos {
+		r.Protocols = append(r.Protocols, protoEntry{name, *p})
+	}
+	sort.Slice(r.Protocols, func(i, j int) bool { return r.Protocols[i].Packets > r.Protocols[j].Packets })
+
+	for addr, h := range s.hosts {
+		r.TopTalkers = append(r.TopTalkers, talker{addr.String(), *h})
+	}
+	sort.Slice(r.TopTalkers, func(i, j int) bool {
+		a, b := r.TopTalkers[i], r.TopTalkers[j]
+		if a.SentBytes+a.RecvBytes != b.SentBytes+b.RecvBytes {
+			return a.SentBytes+a.RecvBytes > b.SentBytes+b.RecvBytes
+		}
+		return a.Address < b.Address
+	})
+	r.TopTalkers = r.TopTalkers[:min(topN, len(r.TopTalkers))]
+
+	for svc, n := range s.services {
+		r.TopServices = append(r.TopServices, serviceEntry{fmt.Sprintf("%s/%d", strings.ToLower(svc.proto), svc.port), n})
+	}
+	sort.Slice(r.TopServices, func(i, j int) bool {
+		if r.TopServices[i].Packets != r.TopServices[j].Packets {
+			return r.TopServices[i].Packets > r.TopServices[j].Packets
+		}
+		return r.TopServices[i].Service < r.TopServices[j].Service
+	})
+	r.TopServices = r.TopServices[:min(topN, len(r.TopServices))]
+
+	for q, n := range s.queries {
+		r.DNSQueries = append(r.DNSQueries, queryEntry{q.name, qtypeName(q.qtype), n})
+	}
+	sort.Slice(r.DNSQueries, func(i, j int) bool {
+		if r.DNSQueries[i].Queries != r.DNSQueries[j].Queries {
+			return r.DNSQueries[i].Queries > r.DNSQueries[j].Queries
+		}
+		return r.DNSQueries[i].Name < r.DNSQueries[j].Name
+	})
+	r.DNSQueries = r.DNSQueries[:min(topN, len(r.DNSQueries))]
+	return r
+}
+
+// writeReport writes the summary as a text report.
+func writeReport(r report, output io.Writer) {
+	fmt.Fprintf(output, "--- Packet Capture Summary ---\n\n")
+	fmt.Fprintf(output, "Source: %s (%s)\n", r.Source, r.Format)
+	fmt.Fprintf(output, "Packets: %d (%d bytes)", r.Packets, r.Bytes)
+	if r.Undecoded > 0 {
+		fmt.Fprintf(output, ", %d not decoded (non-IP or truncated)", r.Undecoded)
+	}
+	fmt.Fprintln(output)
+	if r.FirstPacket != nil {
+		fmt.Fprintf(output, "Time Span: %s to %s (%s)\n", stamp(*r.FirstPacket), stamp(*r.LastPacket), r.LastPacket.Sub(*r.FirstPacket).Round(time.Millisecond))
+	}
+	if r.ReadError != "" {
+		fmt.Fprintf(output, "Read Error: %s (summary covers the packets before it)\n", r.ReadError)
+	}
+	fmt.Fprintln(output, "------------------------------")
+
+	fmt.Fprintln(output, "Protocols:")
+	for _, p := range r.Protocols {
+		fmt.Fprintf(output, "  %-8s %10d packets %14d bytes\n", p.Protocol, p.Packets, p.Bytes)
+	}
+	fmt.Fprintln(output, "Top Talkers (bytes sent / received):")
+	for _, t := range r.TopTalkers {
+		fmt.Fprintf(output, "  %-39s %12d / %-12d %8d packets\n", t.Address, t.SentBytes, t.RecvBytes, t.Packets)
+	}
+	fmt.Fprintln(output, "Top Services (packets):")
+	for _, s := range r.TopServices {
+		fmt.Fprintf(output, "  %-12s %10d\n", s.Service, s.Packets)
+	}
+	fmt.Fprintln(output, "DNS Queries:")
+	if len(r.DNSQueries) == 0 {
+		fmt.Fprintln(output, "  none")
+	}
+	for _, q := range r.DNSQueries {
+		fmt.Fprintf(output, "  %-50s %-6s %6d\n", q.Name, q.Type, q.Queries)
+	}
+	if r.NXDomains > 0 {
+		fmt.Fprintf(output, "  (%d NXDOMAIN response(s))\n", r.NXDomains)
+	}
+	fmt.Fprintln(output, "------------------------------")
+
+	for _, f := range r.Findings {
+		fmt.Fprintf(output, "[%s] %s: %s -> %s: %s\n", colorSeverity(f.Severity), f.Type, f.Source, f.Target, f.Detail)
+	}
+	fmt.Fprintf(output, "Findings: %d\n", len(r.Findings))
+}
+
+// writeJSONReport writes the summary as one JSON document.
+func writeJSONReport(r report, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// openSource opens the capture file or live interface.
+func openSource() (src packetSource, name string, closer io.Closer, err error) {
+	if liveIface != "" {
+		if openLive == nil {
+			return nil, "", nil, fmt.Errorf("live capture is not built in; rebuild with src/live.go (go build -tags live) on Linux")
+		}
+		src, err = openLive(liveIface, time.Duration(durationSec)*time.Second)
+		return src, "live:" + liveIface, nil, err
+	}
+	f, err := os.Open(captureFile)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to open capture %s: %w", captureFile, err)
+	}
+	src, err = openCapture(f)
+	if err != nil {
+		f.Close()
+		return nil, "", nil, fmt.Errorf("%s: %w", captureFile, err)
+	}
+	return src, captureFile, f, nil
+}
+
+// main is the entry point of the Packet Capture Summarizer tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+	if err := applyTZ(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+
+	if (captureFile == "") == (liveIface == "") {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] Give either a capture file (-r) or an interface (--live).")
+		os.Exit(1)
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --format %q (use text or json)\n", format)
+		os.Exit(1)
+	}
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if topN < 1 {
+		topN = 1
+	}
+	if beaconMin < 3 {
+		beaconMin = 3 // Two intervals at least, or every repeated connection is "regular"
+	}
+
+	src, name, closer, err := openSource()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Reading packets from %s (%s)...\n", name, src.format())
+	}
+
+	// SIGINT/SIGTERM stop reading; the packets read so far are summarized.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := newSummary()
+	var readErr error
+	for ctx.Err() == nil && (maxPackets == 0 || s.packets < maxPackets) {
+		p, err := src.next()
+		if errors.Is(err, errIdle) {
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readErr = err
+			warnf("%s: %v; summarizing the %d packet(s) read before it.", name, err, s.packets)
+			break
+		}
+		s.add(p)
+		if verboseMode && s.packets%100000 == 0 {
+			fmt.Fprintf(os.Stderr, "[INFO] %d packets read...\n", s.packets)
+		}
+	}
+	interrupted := ctx.Err() != nil
+	if interrupted {
+		stop() // A second signal terminates immediately
+	}
+
+	r := buildReport(s, name, src.format())
+	r.Interrupted = interrupted
+	if readErr != nil {
+		r.ReadError, r.ErrorClass = readErr.Error(), classifyError(readErr)
+	}
+	if selfStatsOn {
+		stats := collectSelfStats(s.packets, "packets")
+		r.SelfStats = &stats
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+	if format == "json" {
+		if err := writeJSONReport(r, output); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		writeReport(r, output)
+		if interrupted {
+			fmt.Fprintf(output, "Partial report: interrupted after %d packets.\n", s.packets)
+		}
+		if r.SelfStats != nil {
+			writeSelfStats(output, *r.SelfStats)
+		}
+	}
+
+	var inputs []string
+	if captureFile != "" {
+		inputs = []string{captureFile}
+	}
+	if interrupted {
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		writeManifest(130, inputs, []string{outputFile, signatureTarget(outputFile)})
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Packet capture summary complete.")
+	}
+	writeManifest(0, inputs, []string{outputFile, signatureTarget(outputFile)})
+	os.Exit(0)
+}
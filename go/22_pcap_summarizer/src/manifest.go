@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Run manifests record who ran what, where and on which inputs, so a saved
+// report can be presented as audit evidence with its chain of custody.
+// Every tool carries an identical copy of this file (scripts/check_shared_go.py).
+
+var (
+	manifestPath string
+	runStarted   = time.Now()
+)
+
+// manifestFile identifies one input or output file by content.
+type manifestFile struct {
+	Path       string `json:"path"`
+	Size       int64  `json:"size,omitempty"`
+	SHA256     string `json:"sha256,omitempty"`
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+}
+
+type runManifest struct {
+	Tool       string         `json:"tool"`
+	Version    string         `json:"version"`
+	GitCommit  string         `json:"git_commit,omitempty"`
+	BuildDate  string         `json:"build_date,omitempty"`
+	GoVersion  string         `json:"go_version"`
+	Hostname   string         `json:"hostname"`
+	User       string         `json:"user,omitempty"`
+	WorkDir    string         `json:"working_directory"`
+	Args       []string       `json:"arguments"`
+	StartTime  time.Time      `json:"start_time"`
+	EndTime    time.Time      `json:"end_time"`
+	ExitStatus int            `json:"exit_status"`
+	Inputs     []manifestFile `json:"inputs"`
+	Outputs    []manifestFile `json:"outputs"`
+}
+
+func registerManifestFlag() {
+	flag.StringVar(&manifestPath, "manifest", "", "Write a JSON run manifest (tool version, git commit, host, arguments, start/end time, SHA-256 of inputs and outputs) to this path.")
+}
+
+// describeFile hashes path for the manifest; unreadable files are listed with the error.
+func describeFile(path string) manifestFile {
+	entry := manifestFile{Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		entry.Error, entry.ErrorClass = err.Error(), classifyError(err)
+		return entry
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		entry.Error, entry.ErrorClass = err.Error(), classifyError(err)
+		return entry
+	}
+	entry.Size, entry.SHA256 = n, hex.EncodeToString(h.Sum(nil))
+	return entry
+}
+
+func describeFiles(paths []string) []manifestFile {
+	entries := []manifestFile{}
+	for _, p := range paths {
+		if d, ok := deliveredOutputs[p]; ok { // Uploaded by a remote output sink
+			entries = append(entries, d)
+		} else if p != "" && p != "-" {
+			entries = append(entries, describeFile(p))
+		}
+	}
+	return entries
+}
+
+// secretFlags are the flags whose values carry credentials: --notify
+// targets (webhook URLs are bearer secrets, SMTP URLs may hold a password)
+// and request headers such as Authorization or Cookie.
+var secretFlags = map[string]bool{"notify": true, "header": true, "H": true}
+
+// redactArgs returns the command line as recorded in manifests, with the
+// values of secretFlags and the passwords of URLs in any argument replaced,
+// so the manifest can be filed with the report without leaking credentials.
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	secret := "" // Flag whose value is the next argument
+	for i, a := range args {
+		switch {
+		case secret != "":
+			out[i], secret = redactValue(secret, a), ""
+		case a == "--":
+			copy(out[i:], args[i:])
+			for j := i + 1; j < len(out); j++ {
+				out[j] = redactURL(out[j])
+			}
+			return out
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			name, value, inline := strings.Cut(strings.TrimLeft(a, "-"), "=")
+			switch {
+			case !secretFlags[name]:
+				out[i] = redactURL(a)
+			case inline:
+				out[i] = a[:len(a)-len(value)] + redactValue(name, value)
+			default:
+				out[i], secret = a, name
+			}
+		default:
+			out[i] = redactURL(a)
+		}
+	}
+	return out
+}
+
+// redactValue hides the secret part of a secretFlags value: the value of a
+// header, or everything after the host of a notify target.
+func redactValue(flagName, value string) string {
+	const hidden = "REDACTED"
+	if flagName != "notify" {
+		if name, _, ok := strings.Cut(value, ":"); ok {
+			return name + ": " + hidden
+		}
+		return hidden
+	}
+	prefix := ""
+	if kind, rest, ok := strings.Cut(value, ":"); ok && (kind == "slack" || kind == "teams") {
+		prefix, value = kind+":", rest
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return prefix + hidden
+	}
+	return prefix + u.Scheme + "://" + u.Host + "/" + hidden
+}
+
+// redactURL hides the password of an argument that is a URL with userinfo,
+// or of a flag=URL argument.
+func redactURL(a string) string {
+	if !strings.Contains(a, "@") || !strings.Contains(a, "://") {
+		return a
+	}
+	head, raw := "", a
+	if strings.HasPrefix(a, "-") {
+		if name, value, ok := strings.Cut(a, "="); ok {
+			head, raw = name+"=", value
+		}
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return a
+	}
+	if _, ok := u.User.Password(); !ok {
+		return a
+	}
+	return head + u.Redacted()
+}
+
+// writeManifest records this run in the --manifest file, if one was requested.
+// Empty paths in inputs and outputs are ignored. Failures are reported but do
+// not change the tool's exit status.
+func writeManifest(exitStatus int, inputs, outputs []string) {
+	if manifestPath == "" {
+		return
+	}
+	b := currentBuild()
+	m := runManifest{
+		Tool:       toolName,
+		Version:    b.Version,
+		GitCommit:  b.GitCommit,
+		BuildDate:  b.BuildDate,
+		GoVersion:  b.GoVersion,
+		Args:       redactArgs(os.Args[1:]),
+		StartTime:  runStarted.UTC(),
+		EndTime:    time.Now().UTC(),
+		ExitStatus: exitStatus,
+		Inputs:     describeFiles(inputs),
+		Outputs:    describeFiles(outputs),
+	}
+	m.Hostname, _ = os.Hostname()
+	m.User = os.Getenv("USER")
+	m.WorkDir, _ = os.Getwd()
+
+	data, _ := json.MarshalIndent(m, "", "  ")
+	if err := os.WriteFile(manifestPath, append(data, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to write run manifest %s: %v\n", manifestPath, err)
+		return
+	}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Run manifest written to %s\n", manifestPath)
+	}
+}
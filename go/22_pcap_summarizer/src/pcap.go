@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Capture file readers for the classic libpcap format and pcapng. Both are
+// read sequentially and never held in memory, so captures of any size can be
+// summarized.
+
+// Link-layer header types (https://www.tcpdump.org/linktypes.html).
+const (
+	linkNull     = 0   // BSD loopback: 4-byte address family
+	linkEthernet = 1   // Ethernet II, with optional 802.1Q/802.1ad tags
+	linkRaw      = 101 // Raw IPv4/IPv6
+	linkRawAlt   = 12  // Raw IP on some BSDs
+	linkLoop     = 108 // OpenBSD loopback: 4-byte address family, network order
+	linkSLL      = 113 // Linux cooked capture v1 (tcpdump -i any)
+	linkSLL2     = 276 // Linux cooked capture v2
+)
+
+// maxSnapLen bounds the size of one captured packet; larger lengths mean a
+// corrupt file.
+const maxSnapLen = 256 << 10
+
+// packet is one captured frame.
+type packet struct {
+	ts       time.Time
+	data     []byte
+	origLen  int // Length on the wire; data may be truncated to the snap length
+	linkType int
+}
+
+// packetSource yields packets until io.EOF.
+type packetSource interface {
+	next() (packet, error)
+	format() string
+}
+
+// openCapture detects the file format from its magic number.
+func openCapture(r io.Reader) (packetSource, error) {
+	br := bufio.NewReaderSize(r, 1<<16)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("not a capture file: %w", err)
+	}
+	switch binary.LittleEndian.Uint32(magic) {
+	case 0xa1b2c3d4, 0xd4c3b2a1, 0xa1b23c4d, 0x4d3cb2a1:
+		return newPcapReader(br)
+	case 0x0a0d0d0a:
+		return &pcapngReader{r: br}, nil
+	}
+	return nil, fmt.Errorf("not a pcap or pcapng file (magic %x)", magic)
+}
+
+// pcapReader reads the classic libpcap format.
+type pcapReader struct {
+	r        io.Reader
+	order    binary.ByteOrder
+	nano     bool
+	linkType int
+}
+
+func newPcapReader(r io.Reader) (*pcapReader, error) {
+	hdr := make([]byte, 24)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, fmt.Errorf("truncated pcap header: %w", err)
+	}
+	p := &pcapReader{r: r, order: binary.LittleEndian}
+	magic := binary.LittleEndian.Uint32(hdr)
+	if magic == 0xd4c3b2a1 || magic == 0x4d3cb2a1 {
+		p.order = binary.BigEndian
+	}
+	p.nano = magic == 0xa1b23c4d || magic == 0x4d3cb2a1
+	p.linkType = int(p.order.Uint32(hdr[20:]) & 0xffff) // Upper bits carry FCS flags
+	return p, nil
+}
+
+func (p *pcapReader) format() string { return "pcap" }
+
+func (p *pcapReader) next() (packet, error) {
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(p.r, hdr); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return packet{}, fmt.Errorf("truncated packet header: %w", err)
+		}
+		return packet{}, err
+	}
+	sec, frac := int64(p.order.Uint32(hdr)), int64(p.order.Uint32(hdr[4:]))
+	capLen, origLen := p.order.Uint32(hdr[8:]), p.order.Uint32(hdr[12:])
+	if capLen > maxSnapLen {
+		return packet{}, fmt.Errorf("corrupt packet header (captured length %d)", capLen)
+	}
+	data := make([]byte, capLen)
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		return packet{}, fmt.Errorf("truncated packet: %w", err)
+	}
+	if !p.nano {
+		frac *= 1000
+	}
+	return packet{ts: time.Unix(sec, frac), data: data, origLen: int(origLen), linkType: p.linkType}, nil
+}
+
+// pcapngReader reads pcapng section, interface and packet blocks; other
+// block types (statistics, name resolution, comments) are skipped.
+type pcapngReader struct {
+	r      io.Reader
+	order  binary.ByteOrder
+	ifaces []pcapngIface
+}
+
+type pcapngIface struct {
+	linkType int
+	tsUnit   time.Duration // Duration of one timestamp tick; nanoseconds below 1ns are not supported
+	tsPerSec uint64
+}
+
+const (
+	blockSHB = 0x0a0d0d0a
+	blockIDB = 1
+	blockSPB = 3
+	blockEPB = 6
+)
+
+func (p *pcapngReader) format() string { return "pcapng" }
+
+func (p *pcapngReader) next() (packet, error) {
+	for {
+		hdr := make([]byte, 8)
+		if _, err := io.ReadFull(p.r, hdr); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return packet{}, fmt.Errorf("truncated block header: %w", err)
+			}
+			return packet{}, err
+		}
+		if binary.LittleEndian.Uint32(hdr) == blockSHB {
+			// The byte-order magic follows the length, so peek at it first.
+			bom := make([]byte, 4)
+			if _, err := io.ReadFull(p.r, bom); err != nil {
+				return packet{}, fmt.Errorf("truncated section header: %w", err)
+			}
+			switch binary.LittleEndian.Uint32(bom) {
+			case 0x1a2b3c4d:
+				p.order = binary.LittleEndian
+			case 0x4d3c2b1a:
+				p.order = binary.BigEndian
+			default:
+				return packet{}, fmt.Errorf("corrupt section header")
+			}
+			p.ifaces = nil // Interface IDs are per section
+			length := p.order.Uint32(hdr[4:])
+			if length < 28 || length > maxSnapLen {
+				return packet{}, fmt.Errorf("corrupt section header length %d", length)
+			}
+			if _, err := io.CopyN(io.Discard, p.r, int64(length)-12); err != nil {
+				return packet{}, fmt.Errorf("truncated section header: %w", err)
+			}
+			continue
+		}
+		if p.order == nil {
+			return packet{}, fmt.Errorf("pcapng block before the section header")
+		}
+		kind, length := p.order.Uint32(hdr), p.order.Uint32(hdr[4:])
+		if length < 12 || length%4 != 0 || length > maxSnapLen+64 {
+			return packet{}, fmt.Errorf("corrupt block length %d", length)
+		}
+		body := make([]byte, length-12)
+		if _, err := io.ReadFull(p.r, body); err != nil {
+			return packet{}, fmt.Errorf("truncated block: %w", err)
+		}
+		if _, err := io.CopyN(io.Discard, p.r, 4); err != nil { // Trailing length
+			return packet{}, fmt.Errorf("truncated block: %w", err)
+		}
+
+		switch kind {
+		case blockIDB:
+			if len(body) < 8 {
+				return packet{}, fmt.Errorf("corrupt interface block")
+			}
+			p.ifaces = append(p.ifaces, p.parseIface(body))
+		case blockEPB:
+			if len(body) < 20 {
+				return packet{}, fmt.Errorf("corrupt packet block")
+			}
+			id := int(p.order.Uint32(body))
+			if id >= len(p.ifaces) {
+				return packet{}, fmt.Errorf("packet for undeclared interface %d", id)
+			}
+			iface := p.ifaces[id]
+			ticks := uint64(p.order.Uint32(body[4:]))<<32 | uint64(p.order.Uint32(body[8:]))
+			capLen, origLen := p.order.Uint32(body[12:]), p.order.Uint32(body[16:])
+			if int(capLen) > len(body)-20 {
+				return packet{}, fmt.Errorf("corrupt packet block (captured length %d)", capLen)
+			}
+			ts := time.Unix(int64(ticks/iface.tsPerSec), int64(ticks%iface.tsPerSec)*int64(iface.tsUnit))
+			return packet{ts: ts, data: body[20 : 20+capLen], origLen: int(origLen), linkType: iface.linkType}, nil
+		case blockSPB:
+			if len(body) < 4 || len(p.ifaces) == 0 {
+				return packet{}, fmt.Errorf("corrupt simple packet block")
+			}
+			origLen := int(p.order.Uint32(body))
+			data := body[4:]
+			if origLen < len(data) {
+				data = data[:origLen] // Padding
+			}
+			return packet{data: data, origLen: origLen, linkType: p.ifaces[0].linkType}, nil
+		}
+	}
+}
+
+// parseIface reads an interface description block and its if_tsresol option.
+func (p *pcapngReader) parseIface(body []byte) pcapngIface {
+	iface := pcapngIface{linkType: int(p.order.Uint16(body)), tsUnit: time.Microsecond, tsPerSec: 1e6}
+	opts := body[8:]
+	for len(opts) >= 4 {
+		code, n := p.order.Uint16(opts), int(p.order.Uint16(opts[2:]))
+		if code == 0 || 4+n > len(opts) {
+			break
+		}
+		if code == 9 && n >= 1 { // if_tsresol
+			res := opts[4]
+			if res&0x80 == 0 && res <= 9 {
+				iface.tsPerSec = 1
+				for i := byte(0); i < res; i++ {
+					iface.tsPerSec *= 10
+				}
+				iface.tsUnit = time.Second / time.Duration(iface.tsPerSec)
+			}
+		}
+		opts = opts[4+(n+3)&^3:]
+	}
+	return iface
+}
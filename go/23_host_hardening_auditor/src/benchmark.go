@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Benchmark definitions: a YAML file with a name, a version and a list of
+// checks. Each check has a type that selects how it is evaluated; the other
+// fields parameterize that type.
+
+// Severity levels, lowest first.
+var severityNames = []string{"low", "medium", "high", "critical"}
+
+// Check is one benchmark rule.
+type Check struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Severity    string   `json:"severity"`
+	Type        string   `json:"type"` // sshd_config, key_value, sysctl, file_mode, world_writable or sudoers
+	Path        string   `json:"path,omitempty"`
+	Paths       []string `json:"paths,omitempty"`
+	Key         string   `json:"key,omitempty"`
+	Expect      string   `json:"expect,omitempty"`  // Exact value (case-insensitive)
+	OneOf       []string `json:"one_of,omitempty"`  // Any of these values
+	Min         *float64 `json:"min,omitempty"`     // Numeric lower bound
+	Max         *float64 `json:"max,omitempty"`     // Numeric upper bound
+	Default     string   `json:"default,omitempty"` // Value assumed when the key is not set
+	Mode        string   `json:"mode,omitempty"`    // Most permissive allowed mode, octal
+	Owner       string   `json:"owner,omitempty"`
+	Forbid      []string `json:"forbid,omitempty"` // sudoers tags that fail the check
+	Remediation string   `json:"remediation,omitempty"`
+	CVSS        string   `json:"cvss,omitempty"` // CVSS-lite vector scoring a failure in --findings
+}
+
+// Benchmark is a parsed benchmark definition.
+type Benchmark struct {
+	Name    string
+	Version string
+	Checks  []Check
+}
+
+// checkTypes maps each check type to its default path, if any.
+var checkTypes = map[string]string{
+	"sshd_config":    "/etc/ssh/sshd_config",
+	"key_value":      "",
+	"sysctl":         "",
+	"file_mode":      "",
+	"world_writable": "",
+	"sudoers":        "/etc/sudoers",
+}
+
+// loadBenchmark reads and validates a benchmark definition.
+func loadBenchmark(path string) (*Benchmark, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read benchmark %s: %w", path, err)
+	}
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse benchmark %s: %w", path, err)
+	}
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("benchmark %s: expected a mapping with 'benchmark' and 'checks'", path)
+	}
+	b := &Benchmark{Name: yamlString(root["benchmark"]), Version: yamlString(root["version"])}
+	if b.Name == "" {
+		b.Name = path
+	}
+	items, _ := root["checks"].([]interface{})
+	if len(items) == 0 {
+		return nil, fmt.Errorf("benchmark %s: no checks defined", path)
+	}
+	seen := map[string]bool{}
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("benchmark %s: check %d is not a mapping", path, i+1)
+		}
+		c, err := parseCheck(m)
+		if err != nil {
+			return nil, fmt.Errorf("benchmark %s: check %d (%s): %w", path, i+1, c.ID, err)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("benchmark %s: duplicate check id %q", path, c.ID)
+		}
+		seen[c.ID] = true
+		b.Checks = append(b.Checks, c)
+	}
+	return b, nil
+}
+
+// parseCheck converts and validates one check mapping.
+func parseCheck(m map[string]interface{}) (Check, error) {
+	c := Check{
+		ID: yamlString(m["id"]), Title: yamlString(m["title"]), Type: yamlString(m["type"]),
+		Severity: strings.ToLower(yamlString(m["severity"])), Path: yamlString(m["path"]), Key: yamlString(m["key"]),
+		Expect: yamlString(m["expect"]), Default: yamlString(m["default"]), Mode: yamlString(m["mode"]),
+		Owner: yamlString(m["owner"]), Remediation: yamlString(m["remediation"]), CVSS: yamlString(m["cvss"]),
+		Paths: yamlStrings(m["paths"]), OneOf: yamlStrings(m["one_of"]), Forbid: yamlStrings(m["forbid"]),
+	}
+	if c.ID == "" {
+		return c, fmt.Errorf("missing id")
+	}
+	if c.Title == "" {
+		c.Title = c.ID
+	}
+	if severityRank(c.Severity) < 0 {
+		return c, fmt.Errorf("severity must be one of %s", strings.Join(severityNames, ", "))
+	}
+	if c.CVSS != "" {
+		if _, err := cvssLiteScore(c.CVSS); err != nil {
+			return c, err
+		}
+	}
+	defaultPath, ok := checkTypes[c.Type]
+	if !ok {
+		return c, fmt.Errorf("unknown type %q", c.Type)
+	}
+	if c.Path == "" {
+		c.Path = defaultPath
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **float64
+	}{{"min", &c.Min}, {"max", &c.Max}} {
+		if s := yamlString(m[bound.name]); s != "" {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return c, fmt.Errorf("%s must be a number, got %q", bound.name, s)
+			}
+			*bound.dst = &v
+		}
+	}
+
+	switch c.Type {
+	case "sshd_config", "key_value", "sysctl":
+		if c.Key == "" {
+			return c, fmt.Errorf("%s checks need a key", c.Type)
+		}
+		if c.Path == "" && c.Type == "key_value" {
+			return c, fmt.Errorf("key_value checks need a path")
+		}
+		if c.Expect == "" && len(c.OneOf) == 0 && c.Min == nil && c.Max == nil {
+			return c, fmt.Errorf("give expect, one_of, min or max")
+		}
+	case "file_mode":
+		if c.Path == "" || (c.Mode == "" && c.Owner == "") {
+			return c, fmt.Errorf("file_mode checks need a path and a mode and/or owner")
+		}
+		if _, err := strconv.ParseUint(c.Mode, 8, 32); c.Mode != "" && err != nil {
+			return c, fmt.Errorf("mode must be octal, e.g. \"0640\"")
+		}
+	case "world_writable":
+		if len(c.Paths) == 0 {
+			return c, fmt.Errorf("world_writable checks need paths")
+		}
+	case "sudoers":
+		if len(c.Forbid) == 0 {
+			c.Forbid = []string{"NOPASSWD", "!authenticate"}
+		}
+	}
+	return c, nil
+}
+
+// severityRank returns the position of a severity in severityNames, or -1.
+func severityRank(s string) int {
+	for i, name := range severityNames {
+		if name == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func yamlString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+// yamlStrings accepts a sequence or a single scalar.
+func yamlStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s := yamlString(item); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case nil:
+		return nil
+	}
+	return []string{yamlString(v)}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Check evaluation. Every check is read-only: files are opened for reading
+// and directories are walked, nothing is changed or executed.
+
+// maxListed bounds the offending entries named in a result's detail.
+const maxListed = 10
+
+// Result is the outcome of one check.
+type Result struct {
+	Check
+	Status string `json:"status"` // PASS, FAIL, ERROR or SKIP
+	Actual string `json:"actual,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// hostPath maps an absolute path of the audited system below --root.
+func hostPath(p string) string {
+	return filepath.Join(rootDir, p)
+}
+
+// displayPath reverses hostPath for the report.
+func displayPath(p string) string {
+	if rel, err := filepath.Rel(rootDir, p); err == nil && !strings.HasPrefix(rel, "..") {
+		return "/" + filepath.ToSlash(rel)
+	}
+	return p
+}
+
+// runCheck evaluates one check.
+func runCheck(c Check) Result {
+	r := Result{Check: c}
+	var err error
+	switch c.Type {
+	case "sshd_config":
+		opts := map[string]string{}
+		if err = readSSHDConfig(hostPath(c.Path), opts, 0); err == nil {
+			value, set := opts[strings.ToLower(c.Key)]
+			compareValue(&r, value, set)
+		}
+	case "key_value":
+		var opts map[string]string
+		if opts, err = readKeyValue(hostPath(c.Path)); err == nil {
+			value, set := opts[c.Key]
+			compareValue(&r, value, set)
+		}
+	case "sysctl":
+		var data []byte
+		if data, err = os.ReadFile(hostPath("/proc/sys/" + strings.ReplaceAll(c.Key, ".", "/"))); err == nil {
+			compareValue(&r, strings.Join(strings.Fields(string(data)), " "), true)
+		}
+	case "file_mode":
+		err = checkFileMode(&r)
+	case "world_writable":
+		err = checkWorldWritable(&r)
+	case "sudoers":
+		err = checkSudoers(&r)
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		r.Status, r.Detail = "SKIP", "not applicable: "+displayPath(pathOf(err))+" does not exist"
+	case err != nil:
+		r.Status, r.Detail = "ERROR", err.Error()
+		if errors.Is(err, fs.ErrPermission) {
+			r.Detail += " (run as root to audit this check)"
+		}
+	}
+	return r
+}
+
+// pathOf returns the path of a *fs.PathError.
+func pathOf(err error) string {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return pe.Path
+	}
+	return "the file"
+}
+
+// compareValue grades a configured value against expect, one_of, min and max.
+func compareValue(r *Result, value string, set bool) {
+	r.Actual = value
+	if !set {
+		if r.Default == "" {
+			r.Status, r.Detail = "FAIL", r.Key+" is not set"
+			return
+		}
+		value, r.Actual = r.Default, r.Default+" (default)"
+	}
+	var problems []string
+	if r.Expect != "" && !strings.EqualFold(value, r.Expect) {
+		problems = append(problems, "expected "+r.Expect)
+	}
+	if len(r.OneOf) > 0 {
+		match := false
+		for _, v := range r.OneOf {
+			match = match || strings.EqualFold(value, v)
+		}
+		if !match {
+			problems = append(problems, "expected one of "+strings.Join(r.OneOf, ", "))
+		}
+	}
+	if r.Min != nil || r.Max != nil {
+		fields := strings.Fields(value)
+		n, err := strconv.ParseFloat(strings.Join(fields[:min(1, len(fields))], ""), 64)
+		switch {
+		case err != nil:
+			problems = append(problems, "expected a number")
+		case r.Min != nil && n < *r.Min:
+			problems = append(problems, fmt.Sprintf("expected >= %g", *r.Min))
+		case r.Max != nil && n > *r.Max:
+			problems = append(problems, fmt.Sprintf("expected <= %g", *r.Max))
+		}
+	}
+	r.Status = "PASS"
+	if len(problems) > 0 {
+		r.Status, r.Detail = "FAIL", strings.Join(problems, "; ")
+	}
+}
+
+// readSSHDConfig collects the global options of an sshd_config, following
+// Include directives. As in sshd, the first value of an option wins and a
+// Match block ends the global section of the file it appears in.
+func readSSHDConfig(path string, opts map[string]string, depth int) error {
+	if depth > 8 {
+		return fmt.Errorf("%s: Include nested too deeply", displayPath(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.Replace(line, "=", " ", 1))
+		key := strings.ToLower(fields[0])
+		value := strings.Join(fields[1:], " ")
+		switch key {
+		case "match":
+			return nil
+		case "include":
+			for _, pattern := range fields[1:] {
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join("/etc/ssh", pattern)
+				}
+				matches, _ := filepath.Glob(hostPath(pattern))
+				for _, m := range matches {
+					if err := readSSHDConfig(m, opts, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			if _, ok := opts[key]; !ok {
+				opts[key] = value
+			}
+		}
+	}
+	return nil
+}
+
+// readKeyValue reads "KEY value" or "key = value" lines, as in login.defs or
+// pwquality.conf. Later lines override earlier ones.
+func readKeyValue(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	opts := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.ContainsAny(strings.TrimSpace(key), " \t") {
+			fields := strings.Fields(line)
+			key, value = fields[0], strings.Join(fields[1:], " ")
+		}
+		opts[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return opts, scanner.Err()
+}
+
+// checkFileMode compares a file's permissions and owner with the check.
+func checkFileMode(r *Result) error {
+	info, err := os.Stat(hostPath(r.Path))
+	if err != nil {
+		return err
+	}
+	perm := info.Mode().Perm()
+	owner := ""
+	if uid, ok := fileOwner(info); ok {
+		owner = userName(uid)
+	}
+	r.Actual = fmt.Sprintf("%04o %s", perm, owner)
+	var problems []string
+	if r.Mode != "" {
+		allowed, _ := strconv.ParseUint(r.Mode, 8, 32)
+		if uint64(perm)&^allowed != 0 {
+			problems = append(problems, fmt.Sprintf("mode %04o is more permissive than %04o", perm, allowed))
+		}
+	}
+	if r.Owner != "" && owner != r.Owner {
+		problems = append(problems, "owned by "+owner+", expected "+r.Owner)
+	}
+	r.Status = "PASS"
+	if len(problems) > 0 {
+		r.Status, r.Detail = "FAIL", strings.Join(problems, "; ")
+	}
+	return nil
+}
+
+// userName resolves a uid through the audited system's /etc/passwd.
+func userName(uid uint32) string {
+	data, _ := os.ReadFile(hostPath("/etc/passwd"))
+	for _, line := range strings.Split(string(data), "\n") {
+		if f := strings.Split(line, ":"); len(f) > 2 && f[2] == strconv.Itoa(int(uid)) {
+			return f[0]
+		}
+	}
+	return strconv.Itoa(int(uid))
+}
+
+// checkWorldWritable walks the check's paths, staying on each path's file
+// system, for world-writable files and world-writable directories without
+// the sticky bit.
+func checkWorldWritable(r *Result) error {
+	var found []string
+	walked, unreadable := 0, 0
+	for _, p := range r.Paths {
+		start, err := os.Stat(hostPath(p))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		walked++
+		dev, sameFS := fileDevice(start)
+		filepath.WalkDir(hostPath(p), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				unreadable++
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || d.Type()&fs.ModeSymlink != 0 {
+				return nil
+			}
+			if d, ok := fileDevice(info); sameFS && ok && d != dev {
+				return filepath.SkipDir
+			}
+			switch mode := info.Mode(); {
+			case mode.IsDir() && mode.Perm()&0o002 != 0 && mode&fs.ModeSticky == 0:
+				found = append(found, displayPath(path)+"/ (no sticky bit)")
+			case mode.IsRegular() && mode.Perm()&0o002 != 0:
+				found = append(found, displayPath(path))
+			}
+			return nil
+		})
+	}
+	if walked == 0 {
+		return &fs.PathError{Op: "stat", Path: hostPath(r.Paths[0]), Err: fs.ErrNotExist}
+	}
+	r.Actual = fmt.Sprintf("%d world-writable", len(found))
+	r.Status = "PASS"
+	if len(found) > 0 {
+		r.Status, r.Detail = "FAIL", listDetail(found)
+	}
+	if unreadable > 0 {
+		debugf("%s: %d entries could not be read", r.ID, unreadable)
+	}
+	return nil
+}
+
+// checkSudoers looks for forbidden tags and Defaults (NOPASSWD, !authenticate)
+// in the sudoers file and the files it includes.
+func checkSudoers(r *Result) error {
+	var found []string
+	var visit func(path string, depth int) error
+	visit = func(path string, depth int) error {
+		data, err := os.ReadFile(path)
+		if err != nil || depth > 8 {
+			return err
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 2 && (fields[0] == "#includedir" || fields[0] == "@includedir"):
+				entries, _ := os.ReadDir(hostPath(fields[1]))
+				for _, e := range entries {
+					// sudo skips names ending in ~ or containing a dot.
+					if !e.IsDir() && !strings.HasSuffix(e.Name(), "~") && !strings.Contains(e.Name(), ".") {
+						if err := visit(filepath.Join(hostPath(fields[1]), e.Name()), depth+1); err != nil {
+							return err
+						}
+					}
+				}
+			case len(fields) == 2 && (fields[0] == "#include" || fields[0] == "@include"):
+				if err := visit(hostPath(fields[1]), depth+1); err != nil {
+					return err
+				}
+			case line == "" || strings.HasPrefix(line, "#"):
+			default:
+				for _, tag := range r.Forbid {
+					if strings.Contains(line, tag) {
+						found = append(found, fmt.Sprintf("%s:%d: %s", displayPath(path), i+1, line))
+						break
+					}
+				}
+			}
+		}
+		return nil
+	}
+	if err := visit(hostPath(r.Path), 0); err != nil {
+		return err
+	}
+	r.Actual = fmt.Sprintf("%d rule(s) with %s", len(found), strings.Join(r.Forbid, " or "))
+	r.Status = "PASS"
+	if len(found) > 0 {
+		r.Status, r.Detail = "FAIL", listDetail(found)
+	}
+	return nil
+}
+
+// listDetail joins up to maxListed entries.
+func listDetail(items []string) string {
+	if len(items) > maxListed {
+		return strings.Join(items[:maxListed], "; ") + fmt.Sprintf("; ... %d more", len(items)-maxListed)
+	}
+	return strings.Join(items, "; ")
+}
+
+// normalizedFindings converts failed checks to the cross-tool findings
+// model, keeping the benchmark's severity and its optional cvss vector. The
+// target is the audited host, or the --root directory when one is given.
+func normalizedFindings(results []Result) []normFinding {
+	target := rootName
+	if target == "/" {
+		target, _ = os.Hostname()
+	}
+	var out []normFinding
+	for _, r := range results {
+		if r.Status != "FAIL" {
+			continue
+		}
+		n := normFinding{Tool: toolName, Target: target, Category: r.Type, Title: r.ID + " " + r.Title, Severity: r.Severity, Detail: r.Detail}
+		if r.CVSS != "" {
+			n = n.withVector(r.CVSS)
+		}
+		out = append(out, n)
+	}
+	return out
+}
@@ -0,0 +1,345 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of a CIS-Style Host Hardening Auditor.
+PURPOSE: Show skill in Linux configuration review, declarative rule engines, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "host_hardening_auditor"
+	toolVersion = "1.0.0"
+)
+
+// Global variables for CLI flags
+var (
+	benchmarkFile string
+	rootDir       string
+	rootName      string // --root as given, for the report
+	minSeverity   string
+	onlyChecks    string
+	outputFile    string
+	format        string
+	verboseMode   bool
+)
+
+func init() {
+	flag.StringVar(&benchmarkFile, "benchmark", "", "YAML benchmark definition with the checks to run.")
+	flag.StringVar(&benchmarkFile, "b", "", "YAML benchmark definition (shorthand).")
+
+	flag.StringVar(&rootDir, "root", "/", "Audit the system mounted at this directory (e.g. a container or VM image) instead of the running host.")
+	flag.StringVar(&minSeverity, "severity", "low", "Only run checks of at least this severity: low, medium, high or critical.")
+	flag.StringVar(&onlyChecks, "checks", "", "Comma-separated check IDs to run (default: all).")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerFindingsFlags()
+	registerSignFlag()
+	registerOtelFlag()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Runs read-only hardening checks from a YAML benchmark against a Linux host.\n")
+		fmt.Fprintf(os.Stderr, "  Example: sudo %s -b linux_baseline.yaml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -b linux_baseline.yaml --root /mnt/image --severity high -f json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// tally counts results by status, and failures by severity.
+type tally struct {
+	Pass     int            `json:"pass"`
+	Fail     int            `json:"fail"`
+	Error    int            `json:"error"`
+	Skip     int            `json:"skip"`
+	Score    float64        `json:"score_percent"` // Passed share of the checks that could be evaluated
+	Failures map[string]int `json:"failures_by_severity"`
+}
+
+func countResults(results []Result) tally {
+	t := tally{Failures: map[string]int{}}
+	for _, r := range results {
+		switch r.Status {
+		case "PASS":
+			t.Pass++
+		case "FAIL":
+			t.Fail++
+			t.Failures[r.Severity]++
+		case "ERROR":
+			t.Error++
+		case "SKIP":
+			t.Skip++
+		}
+	}
+	if t.Pass+t.Fail > 0 {
+		t.Score = float64(t.Pass) * 100 / float64(t.Pass+t.Fail)
+	}
+	return t
+}
+
+// writeReport writes the results as a text report.
+func writeReport(b *Benchmark, results []Result, output io.Writer) tally {
+	fmt.Fprintf(output, "--- Host Hardening Audit ---\n\n")
+	fmt.Fprintf(output, "Benchmark: %s", b.Name)
+	if b.Version != "" {
+		fmt.Fprintf(output, " (version %s)", b.Version)
+	}
+	fmt.Fprintf(output, "\nRoot: %s\n", rootName)
+	fmt.Fprintln(output, "------------------------------")
+	for _, r := range results {
+		fmt.Fprintf(output, "[%s] %s %s (%s)\n", colorStatus(r.Status), r.ID, r.Title, strings.ToUpper(r.Severity))
+		if r.Actual != "" {
+			fmt.Fprintf(output, "    Actual: %s\n", r.Actual)
+		}
+		if r.Detail != "" {
+			fmt.Fprintf(output, "    Detail: %s\n", r.Detail)
+		}
+		if r.Status == "FAIL" && r.Remediation != "" {
+			fmt.Fprintf(output, "    Remediation: %s\n", r.Remediation)
+		}
+	}
+	fmt.Fprintln(output, "------------------------------")
+	t := countResults(results)
+	fmt.Fprintf(output, "Summary: %d check(s): %d PASS, %d FAIL, %d ERROR, %d SKIP\n", len(results), t.Pass, t.Fail, t.Error, t.Skip)
+	if t.Fail > 0 {
+		var parts []string
+		for i := len(severityNames) - 1; i >= 0; i-- {
+			if n := t.Failures[severityNames[i]]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", n, strings.ToUpper(severityNames[i])))
+			}
+		}
+		fmt.Fprintf(output, "Failures: %s\n", strings.Join(parts, ", "))
+	}
+	fmt.Fprintf(output, "Score: %.0f%% of evaluated checks passed\n", t.Score)
+	return t
+}
+
+type jsonReport struct {
+	Tool      string     `json:"tool"`
+	Version   string     `json:"version"`
+	GitCommit string     `json:"git_commit,omitempty"`
+	BuildDate string     `json:"build_date,omitempty"`
+	Benchmark string     `json:"benchmark"`
+	BenchVer  string     `json:"benchmark_version,omitempty"`
+	Root      string     `json:"root"`
+	Summary   tally      `json:"summary"`
+	Results   []Result   `json:"results"`
+	SelfStats *selfStats `json:"self_stats,omitempty"`
+}
+
+// writeJSONReport writes the results as one JSON document.
+func writeJSONReport(b *Benchmark, results []Result, w io.Writer) (tally, error) {
+	build := currentBuild()
+	report := jsonReport{
+		Tool: toolName, Version: build.Version, GitCommit: build.GitCommit, BuildDate: build.BuildDate,
+		Benchmark: b.Name, BenchVer: b.Version, Root: rootName, Summary: countResults(results), Results: results,
+	}
+	if report.Results == nil {
+		report.Results = []Result{}
+	}
+	if selfStatsOn {
+		stats := collectSelfStats(len(results), "checks")
+		report.SelfStats = &stats
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return report.Summary, enc.Encode(report)
+}
+
+// selectChecks applies --severity and --checks.
+func selectChecks(checks []Check) ([]Check, error) {
+	floor := severityRank(strings.ToLower(minSeverity))
+	if floor < 0 {
+		return nil, fmt.Errorf("invalid --severity %q (use %s)", minSeverity, strings.Join(severityNames, ", "))
+	}
+	wanted := map[string]bool{}
+	for _, id := range strings.Split(onlyChecks, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			wanted[id] = true
+		}
+	}
+	var selected []Check
+	for _, c := range checks {
+		if severityRank(c.Severity) >= floor && (len(wanted) == 0 || wanted[c.ID]) {
+			selected = append(selected, c)
+			delete(wanted, c.ID)
+		}
+	}
+	for id := range wanted {
+		warnf("Check %s is not in the benchmark (or below --severity).", id)
+	}
+	return selected, nil
+}
+
+// main is the entry point of the Host Hardening Auditor tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	if benchmarkFile == "" {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] A benchmark definition (-b) must be provided.")
+		os.Exit(1)
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --format %q (use text or json)\n", format)
+		os.Exit(1)
+	}
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkFindingsMin(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --findings-min: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	startOtel()
+	abs, err := filepath.Abs(rootDir)
+	if info, statErr := os.Stat(abs); err != nil || statErr != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "[ERROR] --root %s is not a directory\n", rootDir)
+		os.Exit(1)
+	}
+	rootName, rootDir = filepath.Clean(rootDir), abs
+
+	bench, err := loadBenchmark(benchmarkFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	checks, err := selectChecks(bench.Checks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if os.Geteuid() != 0 && verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Not running as root; checks of protected files (sudoers, shadow) may report ERROR.")
+	}
+
+	// SIGINT/SIGTERM stop the audit; the checks run so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var results []Result
+	for _, c := range checks {
+		if ctx.Err() != nil {
+			break
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Checking %s %s...\n", c.ID, c.Title)
+		}
+		start := time.Now()
+		r := runCheck(c)
+		var checkErr error
+		if r.Status == "ERROR" {
+			checkErr = errors.New(r.Detail)
+		}
+		telemetry.span("check", start, map[string]string{"check.id": c.ID, "type": c.Type, "severity": c.Severity, "status": r.Status}, checkErr)
+		debugf("%s: %s (actual %q)", c.ID, r.Status, r.Actual)
+		results = append(results, r)
+	}
+	interrupted := ctx.Err() != nil
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+	var t tally
+	if format == "json" {
+		if t, err = writeJSONReport(bench, results, output); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		t = writeReport(bench, results, output)
+		if selfStatsOn {
+			writeSelfStats(output, collectSelfStats(len(results), "checks"))
+		}
+	}
+
+	findings := normalizedFindings(results)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	inputs := []string{benchmarkFile}
+	outputs := []string{outputFile, signatureTarget(outputFile), findingsPath}
+	if interrupted {
+		stop()
+		if format == "text" {
+			fmt.Fprintf(output, "Partial report: interrupted after %d of %d checks.\n", len(results), len(checks))
+		}
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		telemetry.finish(130)
+		writeManifest(130, inputs, outputs)
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Hardening audit complete.")
+	}
+	if t.Fail > 0 {
+		telemetry.finish(1)
+		writeManifest(1, inputs, outputs)
+		os.Exit(1)
+	}
+	telemetry.finish(0)
+	writeManifest(0, inputs, outputs)
+	os.Exit(0)
+}
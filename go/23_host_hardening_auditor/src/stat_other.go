@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import "io/fs"
+
+// Without Unix file ownership, file-mode checks compare permissions only and
+// world-writable walks are not kept to one file system.
+
+func fileOwner(info fs.FileInfo) (uint32, bool) { return 0, false }
+
+func fileDevice(info fs.FileInfo) (uint64, bool) { return 0, false }
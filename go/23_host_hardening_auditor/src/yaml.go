@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Minimal YAML reader for benchmark definitions, covering the block-style subset:
+// nested mappings, sequences (including "- key: value" items), plain and quoted
+// scalars, literal/folded block scalars, comments, simple flow collections and
+// multiple "---" documents. Scalars are returned as strings; mappings as
+// map[string]interface{} and sequences as []interface{}, mirroring encoding/json.
+
+type yamlLine struct {
+	indent  int
+	content string
+	lineNo  int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML parses a YAML stream. A single document is returned as-is; multiple
+// documents are returned as a []interface{}.
+func parseYAML(data []byte) (interface{}, error) {
+	var docs []interface{}
+	var current []yamlLine
+	flush := func() error {
+		if len(current) == 0 {
+			return nil
+		}
+		p := &yamlParser{lines: current}
+		node, err := p.parseNode(current[0].indent)
+		if err != nil {
+			return err
+		}
+		if p.pos < len(p.lines) {
+			l := p.lines[p.pos]
+			return fmt.Errorf("yaml line %d: unexpected indentation", l.lineNo)
+		}
+		docs = append(docs, node)
+		current = nil
+		return nil
+	}
+
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimRight(raw, " \t")
+		if trimmed == "---" || strings.HasPrefix(trimmed, "--- ") || trimmed == "..." {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		content := stripYAMLComment(strings.TrimLeft(trimmed, " "))
+		if content == "" {
+			// Preserve blank lines only inside block scalars (marked by empty content).
+			current = append(current, yamlLine{indent: -1, lineNo: i + 1})
+			continue
+		}
+		current = append(current, yamlLine{indent: len(trimmed) - len(strings.TrimLeft(trimmed, " ")), content: content, lineNo: i + 1})
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if len(docs) == 1 {
+		return docs[0], nil
+	}
+	return docs, nil
+}
+
+// stripYAMLComment removes a trailing "# comment" that is outside quotes.
+func stripYAMLComment(s string) string {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return strings.TrimRight(s[:i], " \t")
+		}
+	}
+	return s
+}
+
+// skipBlank advances past blank lines (which only matter inside block scalars).
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && p.lines[p.pos].indent < 0 {
+		p.pos++
+	}
+}
+
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	l := p.lines[p.pos]
+	if isSeqItem(l.content) {
+		return p.parseSequence(l.indent)
+	}
+	if _, _, ok := splitYAMLKey(l.content); ok {
+		return p.parseMapping(l.indent)
+	}
+	p.pos++
+	return parseYAMLScalar(l.content), nil
+}
+
+func isSeqItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	items := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return items, nil
+		}
+		l := p.lines[p.pos]
+		if l.indent != indent || !isSeqItem(l.content) {
+			if l.indent > indent {
+				return nil, fmt.Errorf("yaml line %d: unexpected indentation", l.lineNo)
+			}
+			return items, nil
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(l.content, "-"), " ")
+		if rest == "" {
+			p.pos++
+			p.skipBlank()
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				node, err := p.parseNode(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, node)
+			} else {
+				items = append(items, nil)
+			}
+			continue
+		}
+		// Re-read the item content as if it started on its own line at the
+		// column after "- ", so "- key: value" continues as a mapping.
+		p.lines[p.pos] = yamlLine{indent: indent + len(l.content) - len(rest), content: rest, lineNo: l.lineNo}
+		node, err := p.parseNode(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, node)
+	}
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return m, nil
+		}
+		l := p.lines[p.pos]
+		if l.indent != indent || isSeqItem(l.content) {
+			if l.indent > indent {
+				return nil, fmt.Errorf("yaml line %d: unexpected indentation", l.lineNo)
+			}
+			return m, nil
+		}
+		key, rest, ok := splitYAMLKey(l.content)
+		if !ok {
+			return nil, fmt.Errorf("yaml line %d: expected 'key: value'", l.lineNo)
+		}
+		p.pos++
+
+		switch {
+		case rest == "":
+			p.skipBlank()
+			if p.pos < len(p.lines) {
+				next := p.lines[p.pos]
+				// Sequences may sit at the same indentation as their parent key.
+				if next.indent > indent || (next.indent == indent && isSeqItem(next.content)) {
+					node, err := p.parseNode(next.indent)
+					if err != nil {
+						return nil, err
+					}
+					m[key] = node
+					continue
+				}
+			}
+			m[key] = nil
+		case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+			m[key] = p.parseBlockScalar(indent, rest[0] == '>')
+		default:
+			m[key] = parseYAMLScalar(rest)
+		}
+	}
+}
+
+// parseBlockScalar collects a literal (|) or folded (>) block more indented than parent.
+func (p *yamlParser) parseBlockScalar(parent int, folded bool) string {
+	var lines []string
+	blockIndent := -1
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent >= 0 && l.indent <= parent {
+			break
+		}
+		if l.indent < 0 {
+			lines = append(lines, "")
+		} else {
+			if blockIndent < 0 {
+				blockIndent = l.indent
+			}
+			lines = append(lines, strings.Repeat(" ", l.indent-blockIndent)+l.content)
+		}
+		p.pos++
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	sep := "\n"
+	if folded {
+		sep = " "
+	}
+	return strings.Join(lines, sep) + "\n"
+}
+
+// splitYAMLKey splits "key: value" (or "key:") at the first colon that is
+// followed by a space or the end of line and is outside quotes.
+func splitYAMLKey(s string) (string, string, bool) {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && i == 0:
+			quote = r
+		case r == '{' || r == '[':
+			if i == 0 {
+				return "", "", false
+			}
+		case r == ':' && (i == len(s)-1 || s[i+1] == ' '):
+			key := strings.TrimSpace(s[:i])
+			if unq, ok := unquoteYAML(key); ok {
+				key = unq
+			}
+			return key, strings.TrimSpace(s[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseYAMLScalar converts a scalar or simple flow collection.
+func parseYAMLScalar(s string) interface{} {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "~" || s == "null":
+		return nil
+	case strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"):
+		items := []interface{}{}
+		for _, part := range splitFlow(s[1 : len(s)-1]) {
+			items = append(items, parseYAMLScalar(part))
+		}
+		return items
+	case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+		m := map[string]interface{}{}
+		for _, part := range splitFlow(s[1 : len(s)-1]) {
+			if k, v, ok := splitYAMLKey(part); ok {
+				m[k] = parseYAMLScalar(v)
+			} else if k, v, ok := splitYAMLKey(part + " "); ok {
+				m[k] = parseYAMLScalar(v)
+			}
+		}
+		return m
+	}
+	if unq, ok := unquoteYAML(s); ok {
+		return unq
+	}
+	return s
+}
+
+// splitFlow splits a flow collection body on top-level commas.
+func splitFlow(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+func unquoteYAML(s string) (string, bool) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if unq, err := strconv.Unquote(s); err == nil {
+			return unq, true
+		}
+		return s[1 : len(s)-1], true
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), true
+	}
+	return "", false
+}
@@ -0,0 +1,536 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of an Email Security (SPF/DKIM/DMARC/MTA-STS) Analyzer.
+PURPOSE: Show skill in DNS-based mail authentication, policy evaluation, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "email_security_analyzer"
+	toolVersion = "1.0.0"
+)
+
+// Global variables for CLI flags
+var (
+	domainArg     string
+	inputFile     string
+	outputFile    string
+	format        string
+	resolverAddr  string
+	dkimSelectors string
+	failUnder     string
+	timeoutSec    int
+	concurrency   int
+	verboseMode   bool
+)
+
+// defaultSelectors are DKIM selectors used by common mail providers and MTAs.
+const defaultSelectors = "default,dkim,mail,selector1,selector2,google,k1,k2,s1,s2,mxvault,smtp"
+
+// severityPenalty is deducted from a domain's score of 100 per finding.
+var severityPenalty = map[string]int{"HIGH": 25, "MEDIUM": 10, "LOW": 3}
+
+// grades maps minimum scores to letter grades, best first.
+var grades = []struct {
+	min   int
+	grade string
+}{{90, "A"}, {75, "B"}, {60, "C"}, {40, "D"}, {0, "F"}}
+
+func init() {
+	flag.StringVar(&domainArg, "domain", "", "Mail domain to analyze (e.g., example.com).")
+	flag.StringVar(&domainArg, "d", "", "Mail domain to analyze (shorthand).")
+
+	flag.StringVar(&inputFile, "input", "", "Path to a file of domains to analyze (one per line). Overrides -domain if provided.")
+	flag.StringVar(&inputFile, "i", "", "Path to a file of domains to analyze (shorthand).")
+
+	flag.StringVar(&dkimSelectors, "dkim-selectors", defaultSelectors, "Comma-separated DKIM selectors to look for.")
+	flag.StringVar(&resolverAddr, "resolver", "", "DNS server (host[:port]) to query instead of the system resolver.")
+	flag.StringVar(&failUnder, "fail-under", "", "Exit with status 1 when any domain grades below this letter (A-F).")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.IntVar(&timeoutSec, "timeout", 10, "DNS and HTTPS timeout in seconds.")
+	flag.IntVar(&timeoutSec, "t", 10, "DNS and HTTPS timeout in seconds (shorthand).")
+
+	flag.IntVar(&concurrency, "concurrency", 5, "Number of domains analyzed in parallel.")
+	flag.IntVar(&concurrency, "c", 5, "Number of domains analyzed in parallel (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerOtelFlag()
+	registerFindingsFlags()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Grades the mail security of domains: SPF, DKIM, DMARC, MTA-STS and TLS-RPT.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s -d example.com\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -i domains.txt --fail-under C -f json -o mail_security.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// Finding is one weakness in a domain's mail security.
+type Finding struct {
+	Severity string `json:"severity"` // HIGH, MEDIUM or LOW
+	Area     string `json:"area"`     // SPF, DKIM, DMARC, MTA-STS or TLS-RPT
+	Detail   string `json:"detail"`
+}
+
+// DomainResult is the analysis of one domain.
+type DomainResult struct {
+	Domain   string        `json:"domain"`
+	Grade    string        `json:"grade"`
+	Score    int           `json:"score"`
+	MX       []string      `json:"mx"`
+	SPF      *SPFResult    `json:"spf"`
+	DMARC    *DMARCResult  `json:"dmarc"`
+	DKIM     []DKIMKey     `json:"dkim"`
+	MTASTS   *MTASTSResult `json:"mta_sts"`
+	TLSRPT   string        `json:"tls_rpt,omitempty"`
+	Findings []Finding     `json:"findings"`
+	Error    string        `json:"error,omitempty"`
+	ErrClass string        `json:"error_class,omitempty"`
+}
+
+// normalizedFindings converts the domains' findings to the cross-tool
+// findings model. Mail findings have no CVSS-lite score.
+func normalizedFindings(results []DomainResult) []normFinding {
+	var out []normFinding
+	for _, r := range results {
+		for _, f := range r.Findings {
+			out = append(out, normFinding{Tool: toolName, Target: r.Domain, Category: f.Area, Title: f.Detail, Severity: strings.ToLower(f.Severity)})
+		}
+	}
+	return out
+}
+
+// analyzer holds the DNS resolver and HTTP client shared by all domains.
+type analyzer struct {
+	resolver  *net.Resolver
+	http      *http.Client
+	selectors []string
+}
+
+func newAnalyzer(timeout time.Duration) *analyzer {
+	r := net.DefaultResolver
+	if resolverAddr != "" {
+		server := resolverAddr
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		r = &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, network, server)
+		}}
+	}
+	// The policy host is resolved through the same resolver as the records.
+	dialer := &net.Dialer{Timeout: timeout, Resolver: r}
+	a := &analyzer{
+		resolver: r,
+		http: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout, Proxy: http.ProxyFromEnvironment},
+			// RFC 8461: redirects must not be followed when fetching a policy.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+	for _, s := range strings.Split(dkimSelectors, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			a.selectors = append(a.selectors, s)
+		}
+	}
+	return a
+}
+
+// txtWithPrefix returns the TXT records of name that start with prefix
+// (case-insensitive). A name without records yields none and no error.
+func (a *analyzer) txtWithPrefix(ctx context.Context, name, prefix string) ([]string, error) {
+	txts, err := a.resolver.LookupTXT(ctx, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, t := range txts {
+		if len(t) >= len(prefix) && strings.EqualFold(t[:len(prefix)], prefix) &&
+			(len(t) == len(prefix) || prefix == "" || t[len(prefix)] == ' ' || t[len(prefix)] == ';') {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// analyze runs every check against one domain and grades it.
+func (a *analyzer) analyze(ctx context.Context, domain string) DomainResult {
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Analyzing %s...\n", domain)
+	}
+	r := DomainResult{Domain: domain, MX: []string{}, DKIM: []DKIMKey{}, Findings: []Finding{}}
+	fail := func(err error) DomainResult {
+		r.Grade, r.Error, r.ErrClass = "ERROR", err.Error(), classifyError(err)
+		return r
+	}
+	mxs, err := a.resolver.LookupMX(ctx, domain)
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return fail(fmt.Errorf("MX lookup: %w", err))
+	}
+	for _, mx := range mxs {
+		if host := strings.TrimSuffix(mx.Host, "."); host != "" { // "." is a null MX (RFC 7505)
+			r.MX = append(r.MX, host)
+		}
+	}
+	if r.SPF, err = a.checkSPF(ctx, domain, &r.Findings); err != nil {
+		return fail(fmt.Errorf("SPF lookup: %w", err))
+	}
+	if r.DMARC, err = a.checkDMARC(ctx, domain, &r.Findings); err != nil {
+		return fail(fmt.Errorf("DMARC lookup: %w", err))
+	}
+	if len(r.MX) > 0 || r.SPF == nil || !strings.HasPrefix(r.SPF.Record, "v=spf1 -all") {
+		// Domains that send no mail (null MX, "v=spf1 -all") need no DKIM key.
+		r.DKIM = append(r.DKIM, a.checkDKIM(ctx, domain, &r.Findings)...)
+	}
+	if r.MTASTS, err = a.checkMTASTS(ctx, domain, r.MX, &r.Findings); err != nil {
+		return fail(fmt.Errorf("MTA-STS lookup: %w", err))
+	}
+	if r.TLSRPT, err = a.checkTLSRPT(ctx, domain, r.MX, &r.Findings); err != nil {
+		return fail(fmt.Errorf("TLS-RPT lookup: %w", err))
+	}
+
+	rank := map[string]int{"HIGH": 0, "MEDIUM": 1, "LOW": 2}
+	sort.SliceStable(r.Findings, func(i, j int) bool { return rank[r.Findings[i].Severity] < rank[r.Findings[j].Severity] })
+	r.Score = 100
+	for _, f := range r.Findings {
+		r.Score -= severityPenalty[f.Severity]
+	}
+	r.Score = max(r.Score, 0)
+	for _, g := range grades {
+		if r.Score >= g.min {
+			r.Grade = g.grade
+			break
+		}
+	}
+	return r
+}
+
+// runAnalyses analyzes the domains with a bounded worker pool, keeping
+// input order.
+func runAnalyses(ctx context.Context, a *analyzer, domains []string) (results []DomainResult, interrupted bool) {
+	slots := make([]*DomainResult, len(domains))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				start := time.Now()
+				r := a.analyze(ctx, domains[idx])
+				var err error
+				if r.Error != "" {
+					err = errors.New(r.Error)
+				}
+				telemetry.span("analyze", start, map[string]string{"target": r.Domain, "grade": r.Grade}, err)
+				if ctx.Err() != nil && r.Error != "" {
+					continue // Cut short by the interrupt
+				}
+				slots[idx] = &r
+			}
+		}()
+	}
+feed:
+	for idx := range domains {
+		select {
+		case jobs <- idx:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range slots {
+		if r != nil {
+			results = append(results, *r)
+		}
+	}
+	return results, ctx.Err() != nil
+}
+
+// loadDomains reads domains from a file, skipping blank lines, comments and
+// duplicates.
+func loadDomains(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file %s: %w", path, err)
+	}
+	defer file.Close()
+	var domains []string
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		domain := strings.Trim(strings.ToLower(strings.TrimSpace(line)), ".")
+		if domain != "" && !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains, sc.Err()
+}
+
+// writeReport writes the per-domain grades and findings.
+func writeReport(results []DomainResult, output io.Writer) {
+	fmt.Fprintf(output, "--- Email Security Report ---\n\n")
+	for _, r := range results {
+		fmt.Fprintf(output, "Domain: %s\n", r.Domain)
+		if r.Error != "" {
+			fmt.Fprintf(output, "Grade: %s\nError: %s\n", colorGrade(r.Grade), r.Error)
+			fmt.Fprintln(output, "------------------------------")
+			continue
+		}
+		fmt.Fprintf(output, "Grade: %s (%d/100)\n", colorGrade(r.Grade), r.Score)
+		fmt.Fprintf(output, "MX: %s\n", orNone(strings.Join(r.MX, ", ")))
+		if r.SPF != nil {
+			fmt.Fprintf(output, "SPF: %s (%d/%d DNS lookups)\n", r.SPF.Record, r.SPF.Lookups, spfMaxLookups)
+		} else {
+			fmt.Fprintln(output, "SPF: none")
+		}
+		if r.DMARC != nil {
+			fmt.Fprintf(output, "DMARC: %s\n", r.DMARC.Record)
+		} else {
+			fmt.Fprintln(output, "DMARC: none")
+		}
+		var keys []string
+		for _, k := range r.DKIM {
+			if k.Bits > 0 {
+				keys = append(keys, fmt.Sprintf("%s (%s %d)", k.Selector, k.Type, k.Bits))
+			} else {
+				keys = append(keys, fmt.Sprintf("%s (%s)", k.Selector, k.Type))
+			}
+		}
+		fmt.Fprintf(output, "DKIM: %s\n", orNone(strings.Join(keys, ", ")))
+		if m := r.MTASTS; m != nil && m.Mode == "" {
+			fmt.Fprintf(output, "MTA-STS: id %s, policy not available\n", m.ID)
+		} else if m != nil {
+			fmt.Fprintf(output, "MTA-STS: id %s, mode %s, max_age %d, mx %s\n", m.ID, orNone(m.Mode), m.MaxAge, orNone(strings.Join(m.MX, ", ")))
+		} else {
+			fmt.Fprintln(output, "MTA-STS: none")
+		}
+		fmt.Fprintf(output, "TLS-RPT: %s\n", orNone(r.TLSRPT))
+		for _, f := range r.Findings {
+			fmt.Fprintf(output, "  [%s] %s: %s\n", f.Severity, f.Area, f.Detail)
+		}
+		fmt.Fprintln(output, "------------------------------")
+	}
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Grade]++
+	}
+	var parts []string
+	for _, g := range []string{"A", "B", "C", "D", "F", "ERROR"} {
+		if counts[g] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[g], g))
+		}
+	}
+	fmt.Fprintf(output, "Summary: %d domain(s): %s\n", len(results), orNone(strings.Join(parts, ", ")))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+type jsonReport struct {
+	Tool      string         `json:"tool"`
+	Version   string         `json:"version"`
+	GitCommit string         `json:"git_commit,omitempty"`
+	BuildDate string         `json:"build_date,omitempty"`
+	Domains   []DomainResult `json:"domains"`
+	SelfStats *selfStats     `json:"self_stats,omitempty"`
+}
+
+// belowGrade reports whether a domain's grade is worse than the --fail-under letter.
+func belowGrade(grade string) bool {
+	if failUnder == "" {
+		return false
+	}
+	return grade == "ERROR" || grade > strings.ToUpper(failUnder)
+}
+
+// main is the entry point of the Email Security Analyzer tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	if inputFile == "" && domainArg == "" {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] Either an input file (-i) or a domain (-d) must be provided.")
+		os.Exit(1)
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --format %q (use text or json)\n", format)
+		os.Exit(1)
+	}
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if g := strings.ToUpper(failUnder); g != "" && (len(g) != 1 || !strings.Contains("ABCDF", g)) {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --fail-under %q (use a grade from A to F)\n", failUnder)
+		os.Exit(1)
+	}
+	if err := checkFindingsMin(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --findings-min: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	startOtel()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	domains := []string{strings.Trim(strings.ToLower(domainArg), ".")}
+	if inputFile != "" {
+		if domainArg != "" {
+			warnf("Input file (-i) provided. -domain flag will be ignored.")
+		}
+		loaded, err := loadDomains(inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		domains = loaded
+	}
+
+	// SIGINT/SIGTERM stop the analysis; domains finished so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a := newAnalyzer(time.Duration(timeoutSec) * time.Second)
+	results, interrupted := runAnalyses(ctx, a, domains)
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+	if format == "json" {
+		build := currentBuild()
+		report := jsonReport{Tool: toolName, Version: build.Version, GitCommit: build.GitCommit, BuildDate: build.BuildDate, Domains: results}
+		if report.Domains == nil {
+			report.Domains = []DomainResult{}
+		}
+		if selfStatsOn {
+			stats := collectSelfStats(len(results), "domains")
+			report.SelfStats = &stats
+		}
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		writeReport(results, output)
+		if selfStatsOn {
+			writeSelfStats(output, collectSelfStats(len(results), "domains"))
+		}
+	}
+
+	findings := normalizedFindings(results)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	inputs := []string{inputFile}
+	outputs := []string{outputFile, signatureTarget(outputFile), findingsPath}
+	if interrupted {
+		stop()
+		if format == "text" {
+			fmt.Fprintf(output, "Partial report: interrupted after %d of %d domains.\n", len(results), len(domains))
+		}
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		telemetry.finish(130)
+		writeManifest(130, inputs, outputs)
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Email security analysis complete.")
+	}
+	for _, r := range results {
+		if belowGrade(r.Grade) {
+			telemetry.finish(1)
+			writeManifest(1, inputs, outputs)
+			os.Exit(1)
+		}
+	}
+	telemetry.finish(0)
+	writeManifest(0, inputs, outputs)
+	os.Exit(0)
+}
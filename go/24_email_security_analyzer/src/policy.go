@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+)
+
+// DMARC (RFC 7489), DKIM key (RFC 6376), MTA-STS (RFC 8461) and TLS-RPT
+// (RFC 8460) checks.
+
+// DMARCResult describes a domain's DMARC policy.
+type DMARCResult struct {
+	Record    string `json:"record"`
+	Policy    string `json:"policy"`
+	SubPolicy string `json:"subdomain_policy"`
+	Percent   int    `json:"percent"`
+	Reports   bool   `json:"aggregate_reports"`
+}
+
+// DKIMKey is a public key found at one selector.
+type DKIMKey struct {
+	Selector string `json:"selector"`
+	Type     string `json:"type"` // rsa, ed25519 or revoked
+	Bits     int    `json:"bits,omitempty"`
+}
+
+// MTASTSResult describes a domain's MTA-STS record and policy.
+type MTASTSResult struct {
+	ID     string   `json:"id"`
+	Mode   string   `json:"mode,omitempty"`
+	MaxAge int      `json:"max_age,omitempty"`
+	MX     []string `json:"mx,omitempty"`
+}
+
+// tagList parses "k=v; k=v" records (DMARC, DKIM, MTA-STS, TLS-RPT).
+func tagList(record string) map[string]string {
+	tags := map[string]string{}
+	for _, part := range strings.Split(record, ";") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			tags[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+		}
+	}
+	return tags
+}
+
+// checkDMARC grades the strength of the domain's DMARC policy.
+func (a *analyzer) checkDMARC(ctx context.Context, domain string, findings *[]Finding) (*DMARCResult, error) {
+	records, err := a.txtWithPrefix(ctx, "_dmarc."+domain, "v=DMARC1")
+	if err != nil || len(records) == 0 {
+		if err == nil {
+			*findings = append(*findings, Finding{"HIGH", "DMARC", "no DMARC record: spoofed mail is not rejected and no reports are sent"})
+		}
+		return nil, err
+	}
+	if len(records) > 1 {
+		*findings = append(*findings, Finding{"HIGH", "DMARC", fmt.Sprintf("%d DMARC records published; receivers ignore DMARC", len(records))})
+	}
+	tags := tagList(records[0])
+	r := &DMARCResult{Record: records[0], Policy: strings.ToLower(tags["p"]), SubPolicy: strings.ToLower(tags["sp"]), Percent: 100, Reports: tags["rua"] != ""}
+	if r.SubPolicy == "" {
+		r.SubPolicy = r.Policy
+	}
+	if pct, err := strconv.Atoi(tags["pct"]); err == nil {
+		r.Percent = pct
+	}
+	switch r.Policy {
+	case "reject":
+	case "quarantine":
+		*findings = append(*findings, Finding{"LOW", "DMARC", "p=quarantine: spoofed mail is delivered to spam instead of rejected"})
+	case "none":
+		*findings = append(*findings, Finding{"MEDIUM", "DMARC", "p=none: monitoring only, spoofed mail is delivered"})
+	default:
+		*findings = append(*findings, Finding{"HIGH", "DMARC", fmt.Sprintf("invalid or missing policy p=%q: the record is ignored", tags["p"])})
+	}
+	if r.SubPolicy == "none" && r.Policy != "none" {
+		*findings = append(*findings, Finding{"MEDIUM", "DMARC", "sp=none: subdomains can be spoofed"})
+	}
+	if r.Percent < 100 && r.Policy != "none" {
+		*findings = append(*findings, Finding{"LOW", "DMARC", fmt.Sprintf("pct=%d: the policy applies to only part of the failing mail", r.Percent)})
+	}
+	if !r.Reports {
+		*findings = append(*findings, Finding{"LOW", "DMARC", "no rua= address: aggregate reports are not collected"})
+	}
+	return r, nil
+}
+
+// checkDKIM looks for DKIM keys at the configured selectors. Selectors
+// cannot be listed through DNS, so a miss only means none of the guesses hit.
+func (a *analyzer) checkDKIM(ctx context.Context, domain string, findings *[]Finding) []DKIMKey {
+	var keys []DKIMKey
+	for _, sel := range a.selectors {
+		records, err := a.txtWithPrefix(ctx, sel+"._domainkey."+domain, "")
+		if err != nil || len(records) == 0 {
+			continue
+		}
+		tags := tagList(records[0])
+		if _, ok := tags["p"]; !ok {
+			continue // A CNAME to something else, or not a key record
+		}
+		key := DKIMKey{Selector: sel, Type: strings.ToLower(tags["k"])}
+		if key.Type == "" {
+			key.Type = "rsa"
+		}
+		der, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(tags["p"], " ", ""))
+		switch {
+		case tags["p"] == "":
+			key.Type = "revoked"
+		case err != nil:
+			*findings = append(*findings, Finding{"MEDIUM", "DKIM", "selector " + sel + ": key is not valid base64"})
+		case key.Type == "ed25519":
+			key.Bits = len(der) * 8
+			if len(der) != ed25519.PublicKeySize {
+				*findings = append(*findings, Finding{"MEDIUM", "DKIM", "selector " + sel + ": malformed Ed25519 key"})
+			}
+		default:
+			pub, err := x509.ParsePKIXPublicKey(der)
+			if err != nil {
+				pub, err = x509.ParsePKCS1PublicKey(der) // Some signers publish the bare RSA key
+			}
+			rsaKey, ok := pub.(*rsa.PublicKey)
+			if err != nil || !ok {
+				*findings = append(*findings, Finding{"MEDIUM", "DKIM", "selector " + sel + ": key cannot be parsed"})
+				break
+			}
+			key.Bits = rsaKey.N.BitLen()
+			if key.Bits < 1024 {
+				*findings = append(*findings, Finding{"HIGH", "DKIM", fmt.Sprintf("selector %s: %d-bit RSA key can be factored; signatures can be forged", sel, key.Bits)})
+			} else if key.Bits < 2048 {
+				*findings = append(*findings, Finding{"LOW", "DKIM", fmt.Sprintf("selector %s: %d-bit RSA key; 2048 bits is recommended", sel, key.Bits)})
+			}
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		*findings = append(*findings, Finding{"MEDIUM", "DKIM", fmt.Sprintf("no DKIM key at %d common selectors (use --dkim-selectors to name yours)", len(a.selectors))})
+	}
+	return keys
+}
+
+// checkMTASTS reads the _mta-sts record and fetches the policy it announces.
+func (a *analyzer) checkMTASTS(ctx context.Context, domain string, mx []string, findings *[]Finding) (*MTASTSResult, error) {
+	records, err := a.txtWithPrefix(ctx, "_mta-sts."+domain, "v=STSv1")
+	if err != nil || len(records) == 0 {
+		if err == nil && len(mx) > 0 {
+			*findings = append(*findings, Finding{"LOW", "MTA-STS", "no MTA-STS policy: senders fall back to opportunistic, downgradable TLS"})
+		}
+		return nil, err
+	}
+	r := &MTASTSResult{ID: tagList(records[0])["id"]}
+	policyURL := "https://mta-sts." + domain + "/.well-known/mta-sts.txt"
+	policy, err := a.fetchPolicy(ctx, policyURL)
+	if err != nil {
+		*findings = append(*findings, Finding{"MEDIUM", "MTA-STS", fmt.Sprintf("record published but the policy at %s cannot be fetched (%v); senders ignore MTA-STS", policyURL, err)})
+		return r, nil
+	}
+	for k, values := range policy {
+		switch k {
+		case "mode":
+			r.Mode = values[0]
+		case "max_age":
+			r.MaxAge, _ = strconv.Atoi(values[0])
+		case "mx":
+			r.MX = values
+		}
+	}
+	switch r.Mode {
+	case "enforce":
+	case "testing":
+		*findings = append(*findings, Finding{"LOW", "MTA-STS", "mode: testing: TLS failures are reported but mail is still delivered"})
+	case "none":
+		*findings = append(*findings, Finding{"MEDIUM", "MTA-STS", "mode: none: the policy is withdrawn"})
+	default:
+		*findings = append(*findings, Finding{"MEDIUM", "MTA-STS", fmt.Sprintf("invalid mode %q", r.Mode)})
+	}
+	if r.MaxAge < 86400 && r.Mode != "none" {
+		*findings = append(*findings, Finding{"LOW", "MTA-STS", fmt.Sprintf("max_age %d is under a day; RFC 8461 recommends weeks", r.MaxAge)})
+	}
+	for _, host := range mx {
+		if !mxCovered(host, r.MX) {
+			*findings = append(*findings, Finding{"MEDIUM", "MTA-STS", "MX " + host + " is not listed in the policy; senders in enforce mode will not deliver to it"})
+		}
+	}
+	return r, nil
+}
+
+// fetchPolicy downloads an MTA-STS policy and returns its key/value lines.
+func (a *analyzer) fetchPolicy(ctx context.Context, url string) (map[string][]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.http.Do(req)
+	var urlErr *neturl.Error
+	if errors.As(err, &urlErr) {
+		return nil, urlErr.Err // The URL is already part of the finding
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	policy := map[string][]string{}
+	sc := bufio.NewScanner(io.LimitReader(resp.Body, 64<<10))
+	for sc.Scan() {
+		if k, v, ok := strings.Cut(sc.Text(), ":"); ok {
+			k = strings.TrimSpace(k)
+			policy[k] = append(policy[k], strings.ToLower(strings.TrimSpace(v)))
+		}
+	}
+	if v := policy["version"]; len(v) == 0 || v[0] != "stsv1" {
+		return nil, fmt.Errorf("not an STSv1 policy")
+	}
+	return policy, sc.Err()
+}
+
+// mxCovered matches an MX host against policy patterns; "*." matches
+// exactly one leftmost label.
+func mxCovered(host string, patterns []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, p := range patterns {
+		if p == host {
+			return true
+		}
+		if rest, ok := strings.CutPrefix(p, "*."); ok {
+			if label, parent, found := strings.Cut(host, "."); found && label != "" && parent == rest {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkTLSRPT looks for an SMTP TLS reporting address.
+func (a *analyzer) checkTLSRPT(ctx context.Context, domain string, mx []string, findings *[]Finding) (string, error) {
+	records, err := a.txtWithPrefix(ctx, "_smtp._tls."+domain, "v=TLSRPTv1")
+	if err != nil || len(records) == 0 {
+		if err == nil && len(mx) > 0 {
+			*findings = append(*findings, Finding{"LOW", "TLS-RPT", "no TLS-RPT record: delivery failures caused by TLS problems go unreported"})
+		}
+		return "", err
+	}
+	rua := tagList(records[0])["rua"]
+	for _, dest := range strings.Split(rua, ",") {
+		dest = strings.TrimSpace(dest)
+		if !strings.HasPrefix(dest, "mailto:") && !strings.HasPrefix(dest, "https://") {
+			*findings = append(*findings, Finding{"LOW", "TLS-RPT", fmt.Sprintf("invalid rua destination %q (mailto: or https:// expected)", dest)})
+		}
+	}
+	return records[0], nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SPF evaluation (RFC 7208). Only the record structure is checked, no
+// sender IP is evaluated: the point is whether receivers can evaluate the
+// policy at all (one record, at most 10 DNS-querying terms, at most 2 void
+// lookups) and how strict its final "all" is.
+
+const (
+	spfMaxLookups = 10
+	spfMaxVoid    = 2
+)
+
+// SPFResult describes a domain's SPF policy.
+type SPFResult struct {
+	Record      string `json:"record,omitempty"`
+	Lookups     int    `json:"dns_lookups"`
+	VoidLookups int    `json:"void_lookups"`
+	All         string `json:"all,omitempty"` // Qualifier and mechanism, e.g. "~all"
+}
+
+// spfWalk follows include: and redirect= through the records they name.
+type spfWalk struct {
+	a        *analyzer
+	result   *SPFResult
+	findings *[]Finding
+	visited  map[string]bool
+}
+
+// checkSPF fetches and evaluates the SPF record of domain.
+func (a *analyzer) checkSPF(ctx context.Context, domain string, findings *[]Finding) (*SPFResult, error) {
+	records, err := a.txtWithPrefix(ctx, domain, "v=spf1")
+	if err != nil {
+		return nil, err
+	}
+	switch len(records) {
+	case 0:
+		*findings = append(*findings, Finding{"HIGH", "SPF", "no SPF record: anyone can send mail as this domain unless DMARC rejects it"})
+		return nil, nil
+	case 1:
+	default:
+		*findings = append(*findings, Finding{"HIGH", "SPF", fmt.Sprintf("%d SPF records published; receivers treat this as a permanent error", len(records))})
+	}
+	r := &SPFResult{Record: records[0]}
+	w := &spfWalk{a: a, result: r, findings: findings, visited: map[string]bool{domain: true}}
+	w.walk(ctx, records[0], true)
+
+	if r.Lookups > spfMaxLookups {
+		*findings = append(*findings, Finding{"HIGH", "SPF", fmt.Sprintf("%d DNS lookups, over the limit of %d; receivers return permerror and SPF fails for all mail", r.Lookups, spfMaxLookups)})
+	} else if r.Lookups >= spfMaxLookups-2 {
+		*findings = append(*findings, Finding{"LOW", "SPF", fmt.Sprintf("%d of %d DNS lookups used; one more include breaks SPF", r.Lookups, spfMaxLookups)})
+	}
+	if r.VoidLookups > spfMaxVoid {
+		*findings = append(*findings, Finding{"MEDIUM", "SPF", fmt.Sprintf("%d void lookups (names without records), over the limit of %d", r.VoidLookups, spfMaxVoid)})
+	}
+	switch r.All {
+	case "+all", "all":
+		*findings = append(*findings, Finding{"HIGH", "SPF", "ends in +all: every server on the internet is authorized"})
+	case "?all":
+		*findings = append(*findings, Finding{"MEDIUM", "SPF", "ends in ?all (neutral): unauthorized senders are not marked"})
+	case "":
+		*findings = append(*findings, Finding{"MEDIUM", "SPF", "no all mechanism or redirect: unmatched senders default to neutral"})
+	}
+	return r, nil
+}
+
+// walk counts the DNS-querying terms of one record and recurses into
+// include: and redirect= targets. top is true for the domain's own record,
+// whose all mechanism is the one that applies.
+func (w *spfWalk) walk(ctx context.Context, record string, top bool) {
+	var redirect string
+	for _, term := range strings.Fields(record)[1:] {
+		term = strings.ToLower(term)
+		mech := strings.TrimLeft(term, "+-~?")
+		name, arg, _ := strings.Cut(mech, ":")
+		if strings.HasPrefix(mech, "redirect=") {
+			name, arg = "redirect", strings.TrimPrefix(mech, "redirect=")
+		}
+		name, _, _ = strings.Cut(name, "/") // a/24, mx/24
+		switch name {
+		case "include", "redirect":
+			w.result.Lookups++
+			if name == "redirect" {
+				redirect = arg
+			} else {
+				w.follow(ctx, arg)
+			}
+		case "a", "mx", "exists":
+			w.result.Lookups++
+		case "ptr":
+			w.result.Lookups++
+			*w.findings = append(*w.findings, Finding{"LOW", "SPF", "uses the deprecated ptr mechanism (slow and unreliable)"})
+		case "all":
+			if top {
+				w.result.All = term
+			}
+		}
+	}
+	if redirect != "" && (!top || w.result.All == "") {
+		// A redirect only applies when the record has no all mechanism.
+		w.follow(ctx, redirect)
+		if top && w.result.All == "" {
+			w.result.All = "redirect=" + redirect
+		}
+	}
+}
+
+// follow evaluates an included or redirected record.
+func (w *spfWalk) follow(ctx context.Context, target string) {
+	if strings.Contains(target, "%") {
+		return // Macros depend on the sender; the lookup is counted but not followed
+	}
+	if w.visited[target] {
+		*w.findings = append(*w.findings, Finding{"HIGH", "SPF", "include loop through " + target})
+		return
+	}
+	w.visited[target] = true
+	records, err := w.a.txtWithPrefix(ctx, target, "v=spf1")
+	if err != nil {
+		*w.findings = append(*w.findings, Finding{"MEDIUM", "SPF", fmt.Sprintf("include %s could not be resolved: %v", target, err)})
+		return
+	}
+	if len(records) == 0 {
+		w.result.VoidLookups++
+		*w.findings = append(*w.findings, Finding{"MEDIUM", "SPF", "include " + target + " has no SPF record (void lookup; receivers return permerror)"})
+		return
+	}
+	debugf("SPF include %s: %s", target, records[0])
+	w.walk(ctx, records[0], false)
+}
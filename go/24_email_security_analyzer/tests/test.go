@@ -0,0 +1,11 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPlaceholder(t *testing.T) {
+	// This is a placeholder test to ensure the Go test runner can find and execute tests.
+	// Actual tests would grade fixture domains served by a local DNS stub and an MTA-STS policy server.
+	t.Log("Placeholder Go test executed successfully.")
+}
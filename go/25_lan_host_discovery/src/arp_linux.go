@@ -0,0 +1,187 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ARP sweeps through an AF_PACKET socket, and ICMP echo through an
+// unprivileged ping socket. Linux only: builds for other systems leave this
+// file out and discover hosts with TCP probes alone.
+
+func init() {
+	arpSweep = sweepARP
+	icmpEcho = pingSocket
+}
+
+// arpPacketLen is the size of an ARP packet for IPv4 over Ethernet.
+const arpPacketLen = 28
+
+// sweepARP broadcasts a who-has request for every target on ifi, paced at
+// --rate packets per second, and collects the replies. Targets that have not
+// answered are asked again, --retries times; each round waits timeout for
+// late replies. Needs root or CAP_NET_RAW.
+func sweepARP(ctx context.Context, ifi *net.Interface, src net.IP, targets []net.IP, timeout time.Duration) (map[string]net.HardwareAddr, error) {
+	if len(ifi.HardwareAddr) != 6 || ifi.Flags&net.FlagBroadcast == 0 {
+		return nil, fmt.Errorf("interface %s is not an Ethernet broadcast interface", ifi.Name)
+	}
+	proto := htons(syscall.ETH_P_ARP)
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(proto))
+	if err != nil {
+		return nil, fmt.Errorf("packet socket (needs root or CAP_NET_RAW): %w", err)
+	}
+	defer syscall.Close(fd)
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: proto, Ifindex: ifi.Index}); err != nil {
+		return nil, fmt.Errorf("bind to %s: %w", ifi.Name, err)
+	}
+	// Wake up regularly so the receiver notices the end of the sweep.
+	tv := syscall.Timeval{Usec: 100000}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return nil, fmt.Errorf("socket timeout: %w", err)
+	}
+
+	wanted := map[string]bool{}
+	for _, ip := range targets {
+		wanted[ip.String()] = true
+	}
+	var mu sync.Mutex
+	found := map[string]net.HardwareAddr{}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 1500)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err != nil {
+				continue // Timeout or EINTR
+			}
+			ip, mac, ok := parseARP(buf[:n])
+			// Requests from other hosts name their sender too; our own are skipped.
+			if !ok || !wanted[ip.String()] || mac.String() == ifi.HardwareAddr.String() {
+				continue
+			}
+			mu.Lock()
+			if found[ip.String()] == nil {
+				debugf("ARP: %s is at %s", ip, mac)
+				found[ip.String()] = mac
+			}
+			mu.Unlock()
+		}
+	}()
+
+	broadcast := &syscall.SockaddrLinklayer{Protocol: proto, Ifindex: ifi.Index, Halen: 6, Addr: [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}}
+	pace := time.NewTicker(time.Second / time.Duration(rate))
+	defer pace.Stop()
+	var sendErr error
+sweep:
+	for round := 0; round <= retries; round++ {
+		for _, ip := range targets {
+			mu.Lock()
+			answered := found[ip.String()] != nil
+			mu.Unlock()
+			if answered {
+				continue
+			}
+			select {
+			case <-pace.C:
+			case <-ctx.Done():
+				break sweep
+			}
+			if err := syscall.Sendto(fd, arpRequest(ifi.HardwareAddr, src, ip), 0, broadcast); err != nil && !errors.Is(err, syscall.ENOBUFS) {
+				sendErr = fmt.Errorf("send ARP request for %s: %w", ip, err)
+				break sweep
+			}
+		}
+		select {
+		case <-time.After(timeout):
+		case <-ctx.Done():
+			break sweep
+		}
+	}
+	close(stop)
+	<-done
+	return found, sendErr
+}
+
+// arpRequest builds a who-has request for dst from srcMAC/src.
+func arpRequest(srcMAC net.HardwareAddr, src, dst net.IP) []byte {
+	p := make([]byte, arpPacketLen)
+	binary.BigEndian.PutUint16(p[0:], 1)      // Hardware type: Ethernet
+	binary.BigEndian.PutUint16(p[2:], 0x0800) // Protocol type: IPv4
+	p[4], p[5] = 6, 4
+	binary.BigEndian.PutUint16(p[6:], 1) // Operation: request
+	copy(p[8:14], srcMAC)
+	copy(p[14:18], src.To4())
+	copy(p[24:28], dst.To4()) // Target hardware address left zero
+	return p
+}
+
+// parseARP returns the sender of an IPv4-over-Ethernet ARP request or reply.
+func parseARP(p []byte) (net.IP, net.HardwareAddr, bool) {
+	if len(p) < arpPacketLen || binary.BigEndian.Uint16(p[0:]) != 1 || binary.BigEndian.Uint16(p[2:]) != 0x0800 || p[4] != 6 || p[5] != 4 {
+		return nil, nil, false
+	}
+	if op := binary.BigEndian.Uint16(p[6:]); op != 1 && op != 2 {
+		return nil, nil, false
+	}
+	ip := net.IP(append([]byte(nil), p[14:18]...))
+	if ip.IsUnspecified() { // ARP probes (RFC 5227) carry no sender address
+		return nil, nil, false
+	}
+	return ip, net.HardwareAddr(append([]byte(nil), p[8:14]...)), true
+}
+
+// pingSocket sends one ICMP echo request to ip through a datagram ICMP
+// socket, which needs no privileges where net.ipv4.ping_group_range includes
+// the user's group; otherwise socket creation fails with EACCES. The kernel
+// fills in the identifier and checksum and delivers only our replies.
+func pingSocket(ctx context.Context, ip net.IP, timeout time.Duration) (bool, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, syscall.IPPROTO_ICMP)
+	if err != nil {
+		return false, err
+	}
+	defer syscall.Close(fd)
+	tv := syscall.NsecToTimeval(int64(min(timeout, 200*time.Millisecond)))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return false, err
+	}
+	dst := &syscall.SockaddrInet4{}
+	copy(dst.Addr[:], ip.To4())
+	echo := []byte{8, 0, 0, 0, 0, 0, 0, 1, 'l', 'h', 'd'} // Type 8 (echo request), sequence 1
+	if err := syscall.Sendto(fd, echo, 0, dst); err != nil {
+		return false, nil // Unreachable networks and the like: no answer
+	}
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 512)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		n, from, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			continue
+		}
+		if sa, ok := from.(*syscall.SockaddrInet4); ok && n > 0 && buf[0] == 0 && net.IP(sa.Addr[:]).Equal(ip) {
+			return true, nil // Type 0: echo reply
+		}
+	}
+	return false, nil
+}
+
+// htons converts a protocol number to network byte order for sockaddr_ll.
+func htons(v uint16) uint16 {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return binary.NativeEndian.Uint16(b)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// Known-hosts inventory. One device per line: its MAC address, its IP
+// address and an optional name, with "-" for an unknown MAC or IP:
+//
+//	# mac              ip            name
+//	02:fc:00:00:00:05  192.0.2.1     gateway
+//	-                  192.0.2.10    printer (MAC not recorded)
+//
+// --save-inventory writes the same format, so a first sweep can seed the
+// inventory and later sweeps flag what changed.
+
+// inventoryEntry is one known device.
+type inventoryEntry struct {
+	MAC  string // Canonical lower-case form, or ""
+	IP   string // Or ""
+	Name string
+	seen bool
+}
+
+// loadInventory reads a known-hosts file.
+func loadInventory(path string) ([]*inventoryEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open inventory %s: %w", path, err)
+	}
+	defer file.Close()
+	var entries []*inventoryEntry
+	sc := bufio.NewScanner(file)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<mac|-> <ip|-> [name]\"", path, lineNo)
+		}
+		e := &inventoryEntry{Name: strings.Join(fields[2:], " ")}
+		if fields[0] != "-" {
+			mac, err := net.ParseMAC(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid MAC address %q", path, lineNo, fields[0])
+			}
+			e.MAC = mac.String()
+		}
+		if fields[1] != "-" {
+			ip := net.ParseIP(fields[1])
+			if ip == nil || ip.To4() == nil {
+				return nil, fmt.Errorf("%s:%d: invalid IPv4 address %q", path, lineNo, fields[1])
+			}
+			e.IP = ip.String()
+		}
+		if e.MAC == "" && e.IP == "" {
+			return nil, fmt.Errorf("%s:%d: an entry needs a MAC or an IP address", path, lineNo)
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// diffInventory sets the status of every discovered host against the
+// inventory and returns the inventory devices that did not answer:
+//
+//   - KNOWN: the MAC (or, without one, the IP) is in the inventory.
+//   - IP_CHANGED: a known MAC answered from another address (DHCP, or a moved device).
+//   - MAC_CHANGED: a known address answered from an unknown MAC: a replaced
+//     device, or another host claiming the address (ARP spoofing).
+//   - NEW: neither is in the inventory.
+//
+// Inventory entries inside network that nobody matched are returned as
+// MISSING; entries with only a MAC are checked only when MACs were seen.
+func diffInventory(hosts []*Host, inventory []*inventoryEntry, network *net.IPNet, macsSeen bool) []*Host {
+	byMAC := map[string]*inventoryEntry{}
+	byIP := map[string]*inventoryEntry{}
+	for _, e := range inventory {
+		if e.MAC != "" {
+			byMAC[e.MAC] = e
+		}
+		if e.IP != "" {
+			byIP[e.IP] = e
+		}
+	}
+	for _, h := range hosts {
+		if e := byMAC[h.MAC]; h.MAC != "" && e != nil {
+			e.seen = true
+			h.Name, h.Status = e.Name, "KNOWN"
+			if e.IP != "" && e.IP != h.IP {
+				h.Status, h.Previous = "IP_CHANGED", e.IP
+			}
+			continue
+		}
+		e := byIP[h.IP]
+		switch {
+		case e == nil:
+			h.Status = "NEW"
+		case e.MAC != "" && h.MAC != "":
+			e.seen = true
+			h.Name, h.Status, h.Previous = e.Name, "MAC_CHANGED", e.MAC
+		default:
+			e.seen = true
+			h.Name, h.Status = e.Name, "KNOWN"
+		}
+	}
+	var missing []*Host
+	for _, e := range inventory {
+		if e.seen {
+			continue
+		}
+		if e.IP == "" && !macsSeen || e.IP != "" && !network.Contains(net.ParseIP(e.IP)) {
+			continue
+		}
+		h := &Host{IP: e.IP, MAC: e.MAC, Name: e.Name, Status: "MISSING"}
+		if mac, err := net.ParseMAC(e.MAC); err == nil {
+			h.Vendor = macVendor(mac)
+		}
+		missing = append(missing, h)
+	}
+	return missing
+}
+
+// writeInventory writes hosts in the inventory format.
+func writeInventory(w io.Writer, hosts []*Host) {
+	fmt.Fprintf(w, "# Known hosts written by %s %s\n", toolName, toolVersion)
+	fmt.Fprintf(w, "# %-17s  %-15s  %s\n", "mac", "ip", "name")
+	for _, h := range hosts {
+		mac, ip := h.MAC, h.IP
+		if mac == "" {
+			mac = "-"
+		}
+		if ip == "" {
+			ip = "-"
+		}
+		line := fmt.Sprintf("%-19s  %-15s  %s", mac, ip, h.Name)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
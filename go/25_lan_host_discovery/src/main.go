@@ -0,0 +1,562 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of an ARP/LAN Host Discovery Scanner.
+PURPOSE: Show skill in link-layer networking, asset inventory, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "lan_host_discovery"
+	toolVersion = "1.0.0"
+)
+
+// Global variables for CLI flags
+var (
+	networkArg    string
+	ifaceName     string
+	method        string
+	portList      string
+	inventoryFile string
+	saveInventory string
+	ouiFile       string
+	outputFile    string
+	format        string
+	timeoutMs     int
+	retries       int
+	rate          int
+	concurrency   int
+	failOnNew     bool
+	verboseMode   bool
+)
+
+func init() {
+	flag.StringVar(&networkArg, "network", "", "IPv4 network to sweep in CIDR notation (e.g., 192.168.1.0/24); defaults to the network of -I.")
+	flag.StringVar(&networkArg, "n", "", "IPv4 network to sweep (shorthand).")
+
+	flag.StringVar(&ifaceName, "interface", "", "Interface to sweep from (e.g., eth0); defaults to the one attached to the network.")
+	flag.StringVar(&ifaceName, "I", "", "Interface to sweep from (shorthand).")
+
+	flag.StringVar(&method, "method", "auto", "Discovery method: arp (needs root or CAP_NET_RAW), tcp (TCP and ICMP probes, no privileges) or auto (arp when possible, else tcp).")
+	flag.StringVar(&portList, "ports", "22,80,443,445,3389,8080", "Comma-separated TCP ports probed by the tcp method.")
+
+	flag.StringVar(&inventoryFile, "known", "", "Known-hosts inventory to diff against: lines of \"<mac|-> <ip|-> [name]\".")
+	flag.StringVar(&inventoryFile, "k", "", "Known-hosts inventory to diff against (shorthand).")
+	flag.StringVar(&saveInventory, "save-inventory", "", "Write the hosts found (and the missing known ones) to this file in the inventory format.")
+	flag.StringVar(&ouiFile, "oui-file", "", "Extra MAC vendor list, e.g. the IEEE oui.txt registry, added to the built-in table.")
+	flag.BoolVar(&failOnNew, "fail-on-new", false, "Exit with status 1 when a NEW device or a MAC_CHANGED address is found.")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.IntVar(&timeoutMs, "timeout-ms", 1000, "Milliseconds to wait for a probe answer, and for late ARP replies after each round.")
+	flag.IntVar(&retries, "retries", 1, "ARP requests repeated for addresses that have not answered.")
+	flag.IntVar(&rate, "rate", 200, "ARP requests sent per second.")
+
+	flag.IntVar(&concurrency, "concurrency", 64, "Number of addresses probed in parallel by the tcp method.")
+	flag.IntVar(&concurrency, "c", 64, "Number of addresses probed in parallel (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerOtelFlag()
+	registerFindingsFlags()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Discovers the hosts on a local network and flags devices missing from a known-hosts inventory.\n")
+		fmt.Fprintf(os.Stderr, "  Only sweep networks you own or are authorized to scan.\n")
+		fmt.Fprintf(os.Stderr, "  Example: sudo %s -I eth0\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -n 192.168.1.0/24 --method tcp -k known_hosts.txt --fail-on-new\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// Host is one device found on the network, or a known one that was not.
+type Host struct {
+	IP       string `json:"ip,omitempty"`
+	MAC      string `json:"mac,omitempty"`
+	Vendor   string `json:"vendor,omitempty"`
+	Name     string `json:"name,omitempty"`     // From the inventory
+	Status   string `json:"status"`             // UP without an inventory; else KNOWN, NEW, IP_CHANGED, MAC_CHANGED or MISSING
+	Previous string `json:"previous,omitempty"` // Inventory IP (IP_CHANGED) or MAC (MAC_CHANGED)
+	Evidence string `json:"evidence,omitempty"` // What answered: arp reply, tcp/22 open, neighbor table, ...
+}
+
+// Discovery is the result of one sweep.
+type Discovery struct {
+	Network   string  `json:"network"`
+	Interface string  `json:"interface,omitempty"`
+	Method    string  `json:"method"` // arp or tcp
+	Addresses int     `json:"addresses"`
+	Inventory string  `json:"inventory,omitempty"`
+	Hosts     []*Host `json:"hosts"`
+}
+
+// normalizedFindings converts inventory changes to the cross-tool findings
+// model. Without an inventory there is nothing to flag.
+func normalizedFindings(d Discovery) []normFinding {
+	var out []normFinding
+	for _, h := range d.Hosts {
+		target := h.IP
+		if target == "" {
+			target = h.MAC
+		}
+		f := normFinding{Tool: toolName, Target: target, Category: "inventory", Detail: describeDevice(h)}
+		switch h.Status {
+		case "NEW":
+			f.Title, f.Severity = "Unknown device on the network", "medium"
+		case "MAC_CHANGED":
+			f.Title, f.Severity = "Known address answered from a different MAC (was "+h.Previous+")", "high"
+			f = f.withVector("AV:A/PR:N/C:H/I:H/A:N") // A spoofed gateway intercepts the segment's traffic
+		case "IP_CHANGED":
+			f.Title, f.Severity = "Known device moved from "+h.Previous, "info"
+		case "MISSING":
+			f.Title, f.Severity = "Known device did not answer", "info"
+		default:
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// describeDevice summarizes a host's identity for findings.
+func describeDevice(h *Host) string {
+	var parts []string
+	for _, p := range []string{h.Name, h.MAC, h.Vendor} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// discover sweeps network and returns the hosts found, sorted by address.
+func discover(ctx context.Context, network *net.IPNet, targets []net.IP, ports []int) (Discovery, error) {
+	d := Discovery{Network: network.String(), Addresses: len(targets), Hosts: []*Host{}}
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+	ifi, src, err := localInterface(ifaceName, network)
+	if err != nil {
+		return d, err
+	}
+	if ifi != nil {
+		d.Interface = ifi.Name
+	}
+	onLink := ifi != nil && src != nil && ifi.Flags&net.FlagLoopback == 0
+
+	found := map[string]*Host{}
+	d.Method = "tcp"
+	if method != "tcp" {
+		var arpErr error
+		switch {
+		case arpSweep == nil:
+			arpErr = errors.New("ARP sweeps are not built in (Linux only)")
+		case !onLink:
+			arpErr = fmt.Errorf("%s is not on a local Ethernet segment", network)
+		default:
+			start := time.Now()
+			var macs map[string]net.HardwareAddr
+			macs, arpErr = arpSweep(ctx, ifi, src, targets, timeout)
+			telemetry.span("sweep", start, map[string]string{"method": "arp", "network": d.Network, "hosts": strconv.Itoa(len(macs))}, arpErr)
+			if arpErr == nil {
+				d.Method = "arp"
+				for ip, mac := range macs {
+					found[ip] = &Host{IP: ip, MAC: mac.String(), Evidence: "arp reply"}
+				}
+			}
+		}
+		if arpErr != nil && method == "arp" {
+			return d, arpErr
+		}
+		if arpErr != nil && verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] ARP sweep unavailable (%v); using TCP/ICMP probes.\n", arpErr)
+		}
+	}
+	if d.Method == "tcp" {
+		start := time.Now()
+		for ip, evidence := range probeAll(ctx, targets, ports, timeout) {
+			found[ip] = &Host{IP: ip, Evidence: evidence}
+		}
+		if onLink {
+			// Every probe made the kernel ARP for its address: resolved
+			// entries name the MAC of each host, even one that filters
+			// every probed port.
+			for ip, n := range readNeighbors() {
+				if n.iface != ifi.Name || !network.Contains(net.ParseIP(ip)) {
+					continue
+				}
+				if found[ip] == nil {
+					found[ip] = &Host{IP: ip, Evidence: "neighbor table"}
+				}
+				found[ip].MAC = n.mac.String()
+			}
+		}
+		telemetry.span("sweep", start, map[string]string{"method": "tcp", "network": d.Network, "hosts": strconv.Itoa(len(found))}, nil)
+	}
+	if onLink && network.Contains(src) {
+		// Hosts do not answer their own ARP requests or show up in their own
+		// neighbor table.
+		if found[src.String()] == nil {
+			found[src.String()] = &Host{IP: src.String(), Evidence: "this host"}
+		}
+		found[src.String()].MAC = ifi.HardwareAddr.String()
+	}
+	for _, h := range found {
+		if mac, err := net.ParseMAC(h.MAC); err == nil {
+			h.Vendor = macVendor(mac)
+		}
+		d.Hosts = append(d.Hosts, h)
+	}
+	sortHosts(d.Hosts)
+	return d, nil
+}
+
+// probeAll probes targets with a bounded worker pool and returns the
+// evidence for each address that answered.
+func probeAll(ctx context.Context, targets []net.IP, ports []int, timeout time.Duration) map[string]string {
+	useICMP := icmpEcho != nil
+	if useICMP {
+		// One trial tells whether ping sockets are allowed for this user.
+		if _, err := icmpEcho(ctx, net.IPv4(127, 0, 0, 1), 10*time.Millisecond); err != nil {
+			debugf("ICMP echo unavailable (%v); see net.ipv4.ping_group_range", err)
+			useICMP = false
+		}
+	}
+	var mu sync.Mutex
+	up := map[string]string{}
+	jobs := make(chan net.IP)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range jobs {
+				if evidence, ok := probeHost(ctx, ip, ports, timeout, useICMP); ok {
+					debugf("%s is up: %s", ip, evidence)
+					mu.Lock()
+					up[ip.String()] = evidence
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+feed:
+	for _, ip := range targets {
+		select {
+		case jobs <- ip:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return up
+}
+
+// sortHosts orders hosts by IP address; hosts without one (MISSING entries
+// known only by MAC) go last.
+func sortHosts(hosts []*Host) {
+	key := func(h *Host) []byte {
+		if ip := net.ParseIP(h.IP).To4(); ip != nil {
+			return ip
+		}
+		return []byte{0xff, 0xff, 0xff, 0xff, 0xff}
+	}
+	sort.SliceStable(hosts, func(i, j int) bool { return bytes.Compare(key(hosts[i]), key(hosts[j])) < 0 })
+}
+
+// writeReport writes the hosts table and a status summary.
+func writeReport(d Discovery, output io.Writer) {
+	fmt.Fprintf(output, "--- LAN Host Discovery Report ---\n\n")
+	via := ""
+	if d.Interface != "" {
+		via = " via " + d.Interface
+	}
+	fmt.Fprintf(output, "Network: %s%s (%s sweep, %d addresses)\n", d.Network, via, d.Method, d.Addresses)
+	if d.Inventory != "" {
+		fmt.Fprintf(output, "Inventory: %s\n", d.Inventory)
+	}
+	fmt.Fprintln(output)
+	fmt.Fprintf(output, "%-11s  %-15s  %-17s  %-24s  %s\n", "STATUS", "IP", "MAC", "VENDOR", "NAME / EVIDENCE")
+	counts := map[string]int{}
+	for _, h := range d.Hosts {
+		counts[h.Status]++
+		note := h.Name
+		if h.Previous != "" {
+			note = strings.TrimSpace(note + " (was " + h.Previous + ")")
+		}
+		if h.Evidence != "" {
+			note = strings.TrimSpace(note + " [" + h.Evidence + "]")
+		}
+		status := colorStatus(h.Status) + strings.Repeat(" ", max(11-len(h.Status), 0))
+		fmt.Fprintf(output, "%s  %-15s  %-17s  %-24s  %s\n", status, orDash(h.IP), orDash(h.MAC), orDash(h.Vendor), note)
+	}
+	fmt.Fprintln(output)
+	var parts []string
+	for _, s := range []string{"UP", "KNOWN", "NEW", "IP_CHANGED", "MAC_CHANGED", "MISSING"} {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	summary := "none"
+	if len(parts) > 0 {
+		summary = strings.Join(parts, ", ")
+	}
+	fmt.Fprintf(output, "Summary: %d host(s) answered; %s\n", len(d.Hosts)-counts["MISSING"], summary)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+type jsonReport struct {
+	Tool      string `json:"tool"`
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	Discovery
+	SelfStats *selfStats `json:"self_stats,omitempty"`
+}
+
+// main is the entry point of the LAN Host Discovery tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	if networkArg == "" && ifaceName == "" {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] Either a network (-n) or an interface (-I) must be provided.")
+		os.Exit(1)
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --format %q (use text or json)\n", format)
+		os.Exit(1)
+	}
+	if method != "auto" && method != "arp" && method != "tcp" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --method %q (use auto, arp or tcp)\n", method)
+		os.Exit(1)
+	}
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	ports, err := parsePorts(portList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --ports: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkFindingsMin(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --findings-min: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	startOtel()
+	concurrency, rate, retries, timeoutMs = max(concurrency, 1), max(rate, 1), max(retries, 0), max(timeoutMs, 1)
+
+	var network *net.IPNet
+	if networkArg != "" {
+		_, network, err = net.ParseCIDR(networkArg)
+		if err == nil && network.IP.To4() == nil {
+			err = errors.New("not an IPv4 network")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Invalid --network %q: %v\n", networkArg, err)
+			os.Exit(1)
+		}
+		network.IP = network.IP.To4()
+	} else if network, err = interfaceNetwork(ifaceName); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	targets, err := hostAddresses(network)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	var inventory []*inventoryEntry
+	if inventoryFile != "" {
+		if inventory, err = loadInventory(inventoryFile); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if ouiFile != "" {
+		n, err := loadOUIFile(ouiFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		debugf("Loaded %d vendor prefixes from %s", n, ouiFile)
+	}
+
+	// SIGINT/SIGTERM stop the sweep; hosts found so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Sweeping %d address(es) in %s...\n", len(targets), network)
+	}
+	d, err := discover(ctx, network, targets, ports)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Sweep failed: %v\n", err)
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	interrupted := ctx.Err() != nil
+	for _, h := range d.Hosts {
+		h.Status = "UP"
+	}
+	if inventoryFile != "" {
+		d.Inventory = inventoryFile
+		macsSeen := d.Method == "arp"
+		for _, h := range d.Hosts {
+			macsSeen = macsSeen || h.Evidence == "neighbor table"
+		}
+		if !interrupted { // A cut-short sweep would report most devices missing
+			d.Hosts = append(d.Hosts, diffInventory(d.Hosts, inventory, network, macsSeen)...)
+		} else {
+			diffInventory(d.Hosts, inventory, network, macsSeen)
+		}
+		sortHosts(d.Hosts)
+	}
+	for _, h := range d.Hosts {
+		telemetry.count("hosts", map[string]string{"status": h.Status}, 1)
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+	if format == "json" {
+		build := currentBuild()
+		report := jsonReport{Tool: toolName, Version: build.Version, GitCommit: build.GitCommit, BuildDate: build.BuildDate, Discovery: d}
+		if selfStatsOn {
+			stats := collectSelfStats(d.Addresses, "addresses")
+			report.SelfStats = &stats
+		}
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		writeReport(d, output)
+		if selfStatsOn {
+			writeSelfStats(output, collectSelfStats(d.Addresses, "addresses"))
+		}
+	}
+
+	findings := normalizedFindings(d)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	inputs := []string{inventoryFile, ouiFile}
+	outputs := []string{outputFile, signatureTarget(outputFile), findingsPath, saveInventory}
+	if interrupted {
+		stop()
+		if format == "text" {
+			fmt.Fprintln(output, "Partial report: sweep interrupted; missing devices were not evaluated.")
+		}
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		telemetry.finish(130)
+		writeManifest(130, inputs, outputs)
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if saveInventory != "" {
+		inv, err := openSink(saveInventory)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to open inventory %s: %v\n", saveInventory, err)
+			os.Exit(1)
+		}
+		writeInventory(inv, d.Hosts)
+		if !closeSink(inv) {
+			telemetry.finish(1)
+			os.Exit(1)
+		}
+	}
+	if verboseMode {
+		answered := 0
+		for _, h := range d.Hosts {
+			if h.Status != "MISSING" {
+				answered++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "[INFO] Discovery complete: %d host(s) answered.\n", answered)
+	}
+	if failOnNew {
+		for _, h := range d.Hosts {
+			if h.Status == "NEW" || h.Status == "MAC_CHANGED" {
+				telemetry.finish(1)
+				writeManifest(1, inputs, outputs)
+				os.Exit(1)
+			}
+		}
+	}
+	telemetry.finish(0)
+	writeManifest(0, inputs, outputs)
+	os.Exit(0)
+}
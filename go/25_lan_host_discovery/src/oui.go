@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// MAC vendor lookup. The first three bytes of a globally administered MAC
+// address are the IEEE Organizationally Unique Identifier (OUI) of its
+// maker. The table below covers vendors common on office and home networks
+// and in virtualization; --oui-file adds the full IEEE registry (oui.txt
+// from https://standards-oui.ieee.org/oui/oui.txt) or a local list.
+
+// ouiVendors maps OUIs (six upper-case hex digits) to vendor names.
+var ouiVendors = map[string]string{
+	// Virtualization
+	"000569": "VMware", "000C29": "VMware", "001C14": "VMware", "005056": "VMware",
+	"080027": "Oracle VirtualBox", "001C42": "Parallels", "00155D": "Microsoft Hyper-V",
+	"0003FF": "Microsoft Virtual PC", "00163E": "Xen", "525400": "QEMU/KVM",
+	// Network equipment
+	"00000C": "Cisco", "001B54": "Cisco", "004096": "Cisco Aironet", "00180A": "Cisco Meraki",
+	"000F66": "Cisco-Linksys", "001217": "Cisco-Linksys", "000586": "Juniper Networks",
+	"001DB5": "Juniper Networks", "001C73": "Arista Networks", "00090F": "Fortinet",
+	"001B17": "Palo Alto Networks", "000B86": "Aruba Networks", "00156D": "Ubiquiti",
+	"0418D6": "Ubiquiti", "24A43C": "Ubiquiti", "788A20": "Ubiquiti", "B4FBE4": "Ubiquiti",
+	"F09FC2": "Ubiquiti", "FCECDA": "Ubiquiti", "14CC20": "TP-Link", "50C7BF": "TP-Link",
+	"F4F26D": "TP-Link", "00184D": "Netgear", "001F33": "Netgear", "A040A0": "Netgear",
+	"001DAA": "DrayTek", "000DB9": "PC Engines", "0024D4": "Freebox",
+	// Computers, servers and adapters
+	"00144F": "Sun Microsystems", "001422": "Dell", "001EC9": "Dell", "0026B9": "Dell", "F8BC12": "Dell",
+	"00215A": "Hewlett Packard", "3CD92B": "Hewlett Packard", "001B21": "Intel",
+	"001E67": "Intel", "3CFDFE": "Intel", "A0369F": "Intel", "00E04C": "Realtek",
+	"000EC6": "ASIX Electronics", "002590": "Supermicro", "AC1F6B": "Supermicro",
+	"D05099": "ASRock", "00044B": "NVIDIA",
+	// Apple
+	"000393": "Apple", "0017F2": "Apple", "001CB3": "Apple", "002500": "Apple", "0026BB": "Apple",
+	"28CFE9": "Apple", "3C0754": "Apple", "A45E60": "Apple", "ACBC32": "Apple", "F01898": "Apple",
+	// Storage and printers
+	"001132": "Synology", "245EBE": "QNAP", "0090A9": "Western Digital",
+	"000048": "Seiko Epson", "001BA9": "Brother",
+	// Embedded, IoT and consumer devices
+	"B827EB": "Raspberry Pi", "28CDC1": "Raspberry Pi", "D83ADD": "Raspberry Pi",
+	"DCA632": "Raspberry Pi", "E45F01": "Raspberry Pi", "18FE34": "Espressif",
+	"240AC4": "Espressif", "30AEA4": "Espressif", "84F3EB": "Espressif", "A4CF12": "Espressif",
+	"ECFABC": "Espressif", "0004A3": "Microchip", "001EC0": "Microchip",
+	"001A11": "Google", "3C5AB4": "Google", "F4F5D8": "Google", "18B430": "Nest Labs",
+	"44650D": "Amazon", "6837E9": "Amazon", "74C246": "Amazon", "F0272D": "Amazon",
+	"FC65DE": "Amazon", "001788": "Philips Lighting", "000E58": "Sonos", "5CAAFD": "Sonos",
+	"0024E4": "Withings",
+}
+
+// macVendor returns the vendor of mac from the OUI table. Locally
+// administered addresses (randomized by phones and laptops for privacy, or
+// assigned by a hypervisor) have no registered vendor.
+func macVendor(mac net.HardwareAddr) string {
+	if len(mac) < 3 {
+		return ""
+	}
+	if v, ok := ouiVendors[fmt.Sprintf("%02X%02X%02X", mac[0], mac[1], mac[2])]; ok {
+		return v
+	}
+	if mac[0]&0x02 != 0 {
+		return "(locally administered)"
+	}
+	return ""
+}
+
+// loadOUIFile adds the vendors in path to the OUI table. It reads the IEEE
+// registry format ("00-1A-11   (hex)\t\tGoogle, Inc.") and simple lists of
+// "00:1A:11 Google" or "001A11 Google", one per line.
+func loadOUIFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open OUI file %s: %w", path, err)
+	}
+	defer file.Close()
+	added := 0
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefix := strings.Fields(line)[0]
+		vendor := strings.TrimSpace(line[len(prefix):])
+		if rest, isIEEE := strings.CutPrefix(vendor, "(hex)"); isIEEE {
+			vendor = strings.TrimSpace(rest)
+		} else if strings.HasPrefix(vendor, "(base 16)") {
+			continue // Each IEEE entry is listed twice; the (hex) line is enough
+		}
+		key := strings.ToUpper(strings.NewReplacer("-", "", ":", "", ".", "").Replace(prefix))
+		if len(key) != 6 || strings.Trim(key, "0123456789ABCDEF") != "" || vendor == "" {
+			continue
+		}
+		ouiVendors[key] = vendor
+		added++
+	}
+	return added, sc.Err()
+}
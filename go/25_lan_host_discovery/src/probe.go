@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Discovery without privileges: TCP connects to a few common ports, where a
+// refused connection proves a host is up as well as an accepted one, plus
+// ICMP echo where ping sockets are allowed. On the local segment each probe
+// also makes the kernel resolve the address with ARP, so its neighbor table
+// afterwards holds the MAC address of every host that answered, even those
+// that filter all probed ports.
+
+// arpSweep and icmpEcho are set by arp_linux.go; on other systems they stay
+// nil and only TCP probes are used.
+var (
+	arpSweep func(ctx context.Context, ifi *net.Interface, src net.IP, targets []net.IP, timeout time.Duration) (map[string]net.HardwareAddr, error)
+	icmpEcho func(ctx context.Context, ip net.IP, timeout time.Duration) (bool, error)
+)
+
+// maxAddresses caps the size of a sweep (a /16).
+const maxAddresses = 1 << 16
+
+// hostAddresses lists the addresses of network to probe: all of them for a
+// /31 or /32, otherwise all but the network and broadcast addresses.
+func hostAddresses(network *net.IPNet) ([]net.IP, error) {
+	ones, bits := network.Mask.Size()
+	if bits != 32 {
+		return nil, fmt.Errorf("%s is not an IPv4 network", network)
+	}
+	if bits-ones > 16 {
+		return nil, fmt.Errorf("%s has %d addresses; sweeps are limited to a /16 (%d)", network, uint64(1)<<(bits-ones), maxAddresses)
+	}
+	base := binary.BigEndian.Uint32(network.IP.To4())
+	size := uint32(1) << (bits - ones)
+	first, last := base, base+size-1
+	if size > 2 {
+		first, last = first+1, last-1
+	}
+	var ips []net.IP
+	for v := first; v <= last && v >= first; v++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, v)
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// localInterface returns the interface to sweep network from and its
+// address on that network. With name empty, the interface is the one
+// attached to network; src is nil when network is not on the local segment
+// (reached through a router), where ARP cannot see hosts.
+func localInterface(name string, network *net.IPNet) (ifi *net.Interface, src net.IP, err error) {
+	var candidates []net.Interface
+	if name != "" {
+		i, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("interface %s: %w", name, err)
+		}
+		candidates = []net.Interface{*i}
+	} else if candidates, err = net.Interfaces(); err != nil {
+		return nil, nil, fmt.Errorf("listing interfaces: %w", err)
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		if c.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, _ := c.Addrs()
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil && ipnet.Contains(network.IP) {
+				return c, ipnet.IP.To4(), nil
+			}
+		}
+	}
+	if name != "" {
+		return &candidates[0], nil, nil
+	}
+	return nil, nil, nil
+}
+
+// interfaceNetwork returns the IPv4 network of the named interface, for
+// sweeps given -I without -n.
+func interfaceNetwork(name string) (*net.IPNet, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", name, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", name, err)
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return &net.IPNet{IP: ipnet.IP.To4().Mask(ipnet.Mask), Mask: ipnet.Mask}, nil
+		}
+	}
+	return nil, fmt.Errorf("interface %s has no IPv4 address", name)
+}
+
+// parsePorts parses the comma-separated --ports list.
+func parsePorts(list string) ([]int, error) {
+	var ports []int
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+		ports = append(ports, n)
+	}
+	if len(ports) == 0 {
+		return nil, errors.New("no ports given")
+	}
+	return ports, nil
+}
+
+// probeHost reports whether ip answers an ICMP echo or a TCP connect to one
+// of ports, and how. All probes run at once; the first answer wins.
+func probeHost(ctx context.Context, ip net.IP, ports []int, timeout time.Duration, useICMP bool) (evidence string, up bool) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	answers := make(chan string, len(ports)+1)
+	var wg sync.WaitGroup
+	if useICMP {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := icmpEcho(ctx, ip, timeout); ok {
+				answers <- "icmp echo reply"
+			}
+		}()
+	}
+	for _, port := range ports {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp4", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+			switch {
+			case err == nil:
+				conn.Close()
+				answers <- fmt.Sprintf("tcp/%d open", port)
+			case errors.Is(err, syscall.ECONNREFUSED):
+				answers <- fmt.Sprintf("tcp/%d refused", port)
+			}
+		}(port)
+	}
+	go func() {
+		wg.Wait()
+		close(answers)
+	}()
+	evidence, up = <-answers
+	return evidence, up
+}
+
+// neighbor is one resolved entry of the kernel's ARP table.
+type neighbor struct {
+	mac   net.HardwareAddr
+	iface string
+}
+
+// readNeighbors reads the resolved entries of /proc/net/arp (Linux). Other
+// systems, without the file, have no entries.
+func readNeighbors() map[string]neighbor {
+	entries := map[string]neighbor{}
+	file, err := os.Open("/proc/net/arp")
+	if err != nil {
+		debugf("No neighbor table: %v", err)
+		return entries
+	}
+	defer file.Close()
+	sc := bufio.NewScanner(file)
+	sc.Scan() // Header
+	for sc.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		f := strings.Fields(sc.Text())
+		if len(f) < 6 {
+			continue
+		}
+		flags, _ := strconv.ParseUint(strings.TrimPrefix(f[2], "0x"), 16, 32)
+		mac, err := net.ParseMAC(f[3])
+		if err != nil || flags&0x2 == 0 { // ATF_COM: resolved
+			continue
+		}
+		entries[f[0]] = neighbor{mac: mac, iface: f[5]}
+	}
+	return entries
+}
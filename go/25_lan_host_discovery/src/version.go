@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, shared by --version, run manifests and JSON reports.
+// Every tool carries an identical copy of this file (scripts/check_shared_go.py).
+// The version is toolVersion (semantic versioning). The commit and build
+// date can be stamped at build time:
+//
+//	go build -ldflags "-X main.gitCommit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./src
+//
+// Otherwise the VCS revision and commit time embedded by the Go toolchain
+// (module builds) are used.
+var (
+	gitCommit   string
+	buildDate   string
+	showVersion bool
+)
+
+type buildInfo struct {
+	Version   string
+	GitCommit string
+	BuildDate string
+	GoVersion string
+}
+
+// currentBuild returns the build information of this binary.
+func currentBuild() buildInfo {
+	b := buildInfo{Version: toolVersion, GitCommit: gitCommit, BuildDate: buildDate, GoVersion: runtime.Version()}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && b.GitCommit == "":
+				b.GitCommit = s.Value
+			case s.Key == "vcs.time" && b.BuildDate == "":
+				b.BuildDate = s.Value
+			}
+		}
+	}
+	return b
+}
+
+func registerVersionFlag() {
+	flag.BoolVar(&showVersion, "version", false, "Print the version, git commit and build date, then exit.")
+}
+
+// printVersion writes the --version line, e.g.
+// "network_service_monitor 1.0.0 (commit 1a2b3c4d5e6f, built 2026-10-15T05:00:00Z, go1.22.5 linux/amd64)".
+func printVersion() {
+	b := currentBuild()
+	commit, built := b.GitCommit, b.BuildDate
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if built == "" {
+		built = "unknown"
+	}
+	fmt.Printf("%s %s (commit %s, built %s, %s %s/%s)\n", toolName, b.Version, commit, built, b.GoVersion, runtime.GOOS, runtime.GOARCH)
+}
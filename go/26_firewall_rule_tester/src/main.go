@@ -0,0 +1,495 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of a Firewall Rule Tester.
+PURPOSE: Show skill in network policy verification, compliance reporting, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "firewall_rule_tester"
+	toolVersion = "1.0.0"
+)
+
+// Global variables for CLI flags
+var (
+	policyFile  string
+	outputFile  string
+	format      string
+	timeoutSec  int
+	attempts    int
+	concurrency int
+	strictMode  bool
+	verboseMode bool
+)
+
+func init() {
+	flag.StringVar(&policyFile, "policy", "", "Expected firewall policy: lines of \"<src> <dst> <port> <allow|deny> [name]\".")
+	flag.StringVar(&policyFile, "p", "", "Expected firewall policy (shorthand).")
+
+	flag.BoolVar(&strictMode, "strict", false, "Also exit with status 1 for INCONCLUSIVE rules (allowed ports where nothing listens).")
+	flag.IntVar(&attempts, "attempts", 2, "Connection attempts per port before it is considered FILTERED (timeouts only).")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.IntVar(&timeoutSec, "timeout", 3, "Connection timeout in seconds.")
+	flag.IntVar(&timeoutSec, "t", 3, "Connection timeout in seconds (shorthand).")
+
+	flag.IntVar(&concurrency, "concurrency", 32, "Number of connection attempts in parallel.")
+	flag.IntVar(&concurrency, "c", 32, "Number of connection attempts in parallel (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerOtelFlag()
+	registerFindingsFlags()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Verifies an expected firewall policy from this host with TCP connection attempts.\n")
+		fmt.Fprintf(os.Stderr, "  Only test networks you own or are authorized to assess.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s -p policy.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -p policy.txt --strict -f json -o firewall_compliance.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// Observed states of a port, as in the network service monitor's firewall
+// terms: a RST means the host was reached but nothing listens (CLOSED);
+// silence or an ICMP unreachable means the traffic is dropped (FILTERED).
+// DOWN covers failures that say nothing about the port.
+const (
+	observedOpen     = "OPEN"
+	observedClosed   = "CLOSED"
+	observedFiltered = "FILTERED"
+	observedDown     = "DOWN"
+)
+
+// PortResult is the test of one port of a rule.
+type PortResult struct {
+	Port      int     `json:"port"`
+	Observed  string  `json:"observed"` // OPEN, CLOSED, FILTERED or DOWN
+	Verdict   string  `json:"verdict"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	ErrClass  string  `json:"error_class,omitempty"`
+}
+
+// RuleResult is the test of one rule.
+type RuleResult struct {
+	Rule
+	Source  string       `json:"source,omitempty"`  // Local address the connections came from
+	Address string       `json:"address,omitempty"` // dst as resolved
+	Verdict string       `json:"verdict"`           // PASS, FAIL, INCONCLUSIVE, ERROR or SKIP
+	Reason  string       `json:"reason,omitempty"`
+	Results []PortResult `json:"results,omitempty"`
+}
+
+// verdictRank orders verdicts from best to worst, for a rule's overall verdict.
+var verdictRank = map[string]int{"PASS": 0, "INCONCLUSIVE": 1, "ERROR": 2, "FAIL": 3}
+
+// portVerdict compares what was observed on a port with the rule's action.
+// An allowed port where nothing listens (CLOSED) cannot show whether the
+// firewall passes traffic, so it is INCONCLUSIVE. For a denied port,
+// CLOSED and FILTERED both mean no service is reachable.
+func portVerdict(action, observed string) string {
+	switch {
+	case observed == observedDown:
+		return "ERROR"
+	case action == "allow" && observed == observedOpen, action == "deny" && observed != observedOpen:
+		return "PASS"
+	case action == "allow" && observed == observedClosed:
+		return "INCONCLUSIVE"
+	}
+	return "FAIL"
+}
+
+// observe classifies a failed connection attempt like portstate.go in the
+// network service monitor.
+func observe(err error) string {
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return observedClosed
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH), classifyError(err) == errClassTimeout:
+		return observedFiltered
+	}
+	return observedDown
+}
+
+// testPort connects to addr:port from bind (nil for the default source),
+// retrying timeouts up to --attempts times.
+func testPort(ctx context.Context, addr string, port int, bind net.IP, timeout time.Duration) PortResult {
+	d := net.Dialer{Timeout: timeout}
+	if bind != nil {
+		d.LocalAddr = &net.TCPAddr{IP: bind}
+	}
+	target := net.JoinHostPort(addr, strconv.Itoa(port))
+	var pr PortResult
+	for try := 0; try < attempts; try++ {
+		start := time.Now()
+		conn, err := d.DialContext(ctx, "tcp", target)
+		pr = PortResult{Port: port, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+		if err == nil {
+			conn.Close()
+			pr.Observed = observedOpen
+			break
+		}
+		pr.Observed, pr.Error, pr.ErrClass = observe(err), err.Error(), classifyError(err)
+		if pr.Observed != observedFiltered || ctx.Err() != nil || try+1 == attempts {
+			break
+		}
+		debugf("%s: %s, retrying", target, pr.Observed)
+	}
+	if pr.Observed == observedFiltered || pr.Observed == observedDown {
+		pr.LatencyMs = 0 // Time to a timeout says nothing about the path
+	}
+	return pr
+}
+
+// job is one port of one rule.
+type job struct {
+	rule, slot int
+}
+
+// runRules resolves and tests every rule that applies to this host with a
+// bounded worker pool, keeping policy order.
+func runRules(ctx context.Context, rules []Rule, host localHost) (results []RuleResult, interrupted bool) {
+	timeout := time.Duration(timeoutSec) * time.Second
+	results = make([]RuleResult, len(rules))
+	var jobs []job
+	for i, r := range rules {
+		res := &results[i]
+		res.Rule = r
+		applies, bind := host.source(r.Src)
+		if !applies {
+			res.Verdict, res.Reason = "SKIP", fmt.Sprintf("src %s does not cover this host", r.Src)
+			continue
+		}
+		if bind != nil {
+			res.Source = bind.String()
+		}
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, r.Dst)
+		if err != nil || len(ips) == 0 {
+			res.Verdict, res.Reason = "ERROR", fmt.Sprintf("cannot resolve %s: %v", r.Dst, err)
+			continue
+		}
+		res.Address = ips[0].IP.String()
+		for _, ip := range ips {
+			if bind == nil && ip.IP.To4() != nil || bind != nil && (ip.IP.To4() != nil) == (bind.To4() != nil) {
+				res.Address = ip.IP.String() // Prefer IPv4, or the bound address's family
+				break
+			}
+		}
+		res.Results = make([]PortResult, len(r.ports))
+		for slot := range r.ports {
+			jobs = append(jobs, job{i, slot})
+		}
+	}
+
+	queue := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				res := &results[j.rule]
+				start := time.Now()
+				pr := testPort(ctx, res.Address, res.ports[j.slot], net.ParseIP(res.Source), timeout)
+				if ctx.Err() != nil {
+					continue // Cut short by the interrupt
+				}
+				pr.Verdict = portVerdict(res.Action, pr.Observed)
+				telemetry.span("connect", start, map[string]string{"rule": res.label(), "target": net.JoinHostPort(res.Dst, strconv.Itoa(pr.Port)), "observed": pr.Observed, "verdict": pr.Verdict}, nil)
+				if verboseMode {
+					fmt.Fprintf(os.Stderr, "[INFO] %s:%d %s (%s expected) -> %s\n", res.Dst, pr.Port, pr.Observed, res.Action, pr.Verdict)
+				}
+				res.Results[j.slot] = pr
+			}
+		}()
+	}
+feed:
+	for _, j := range jobs {
+		select {
+		case queue <- j:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	for i := range results {
+		res := &results[i]
+		if res.Verdict != "" {
+			continue
+		}
+		var done []PortResult
+		for _, pr := range res.Results {
+			if pr.Verdict != "" {
+				done = append(done, pr)
+			}
+		}
+		res.Results = done
+		if len(done) == 0 {
+			res.Verdict, res.Reason = "SKIP", "interrupted before testing"
+			continue
+		}
+		res.Verdict = "PASS"
+		for _, pr := range done {
+			if verdictRank[pr.Verdict] > verdictRank[res.Verdict] {
+				res.Verdict = pr.Verdict
+			}
+		}
+		if len(done) < len(res.ports) {
+			res.Reason = fmt.Sprintf("interrupted after %d of %d ports", len(done), len(res.ports))
+		}
+	}
+	return results, ctx.Err() != nil
+}
+
+// explain describes a port that did not pass.
+func explain(action string, pr PortResult) string {
+	switch {
+	case pr.Observed == observedDown:
+		return "connection failed: " + pr.Error
+	case action == "allow" && pr.Observed == observedClosed:
+		return "reached the host but nothing listens; cannot confirm the allow"
+	case action == "allow":
+		return "expected reachable, traffic is dropped or rejected"
+	}
+	return "expected blocked, connection accepted"
+}
+
+// writeReport writes each rule's verdict and the ports behind it.
+func writeReport(results []RuleResult, output io.Writer) {
+	fmt.Fprintf(output, "--- Firewall Rule Test Report ---\n\n")
+	host, _ := os.Hostname()
+	fmt.Fprintf(output, "Policy: %s (%d rules), tested from %s\n\n", policyFile, len(results), host)
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Verdict]++
+		from := ""
+		if r.Source != "" {
+			from = " from " + r.Source
+		}
+		fmt.Fprintf(output, "[%s] %s\n", colorStatus(r.Verdict), r.label())
+		if r.Name != "" {
+			fmt.Fprintf(output, "    %s -> %s %s %s%s\n", r.Src, r.Dst, r.Ports, r.Action, from)
+		}
+		if r.Reason != "" {
+			fmt.Fprintf(output, "    %s\n", r.Reason)
+		}
+		for _, pr := range r.Results {
+			if pr.Verdict == "PASS" {
+				continue
+			}
+			latency := ""
+			if pr.LatencyMs > 0 {
+				latency = fmt.Sprintf(" in %.1f ms", pr.LatencyMs)
+			}
+			fmt.Fprintf(output, "    port %d: %s%s: %s\n", pr.Port, pr.Observed, latency, explain(r.Action, pr))
+		}
+	}
+	var parts []string
+	for _, v := range []string{"PASS", "FAIL", "INCONCLUSIVE", "ERROR", "SKIP"} {
+		if counts[v] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[v], v))
+		}
+	}
+	fmt.Fprintf(output, "\nSummary: %d rule(s): %s\n", len(results), strings.Join(parts, ", "))
+}
+
+// normalizedFindings converts ports that behave differently from the policy
+// to the cross-tool findings model.
+func normalizedFindings(results []RuleResult) []normFinding {
+	var out []normFinding
+	for _, r := range results {
+		for _, pr := range r.Results {
+			f := normFinding{Tool: toolName, Target: net.JoinHostPort(r.Dst, strconv.Itoa(pr.Port)), Category: "firewall", Detail: r.label() + ": " + explain(r.Action, pr)}
+			switch {
+			case pr.Verdict == "FAIL" && r.Action == "deny":
+				f.Title, f.Severity = "Port the policy denies is reachable", "high"
+				f = f.withVector("AV:N/PR:N/C:L/I:L/A:N")
+			case pr.Verdict == "FAIL":
+				f.Title, f.Severity = "Port the policy allows is blocked", "medium"
+				f = f.withVector("AV:N/PR:N/C:N/I:N/A:L")
+			case pr.Verdict == "INCONCLUSIVE":
+				f.Title, f.Severity = "Allowed port has no listener to confirm the rule", "info"
+			default:
+				continue
+			}
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+type jsonReport struct {
+	Tool      string       `json:"tool"`
+	Version   string       `json:"version"`
+	GitCommit string       `json:"git_commit,omitempty"`
+	BuildDate string       `json:"build_date,omitempty"`
+	Policy    string       `json:"policy"`
+	Host      string       `json:"host"`
+	Rules     []RuleResult `json:"rules"`
+	SelfStats *selfStats   `json:"self_stats,omitempty"`
+}
+
+// main is the entry point of the Firewall Rule Tester tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	if policyFile == "" {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] A policy file (-p) must be provided.")
+		os.Exit(1)
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --format %q (use text or json)\n", format)
+		os.Exit(1)
+	}
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkFindingsMin(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --findings-min: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	startOtel()
+	concurrency, attempts = max(concurrency, 1), max(attempts, 1)
+
+	rules, err := loadPolicy(policyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+
+	// SIGINT/SIGTERM stop the tests; rules finished so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results, interrupted := runRules(ctx, rules, currentHost())
+	for _, r := range results {
+		telemetry.count("rules", map[string]string{"verdict": r.Verdict, "action": r.Action}, 1)
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+	if format == "json" {
+		build := currentBuild()
+		host, _ := os.Hostname()
+		report := jsonReport{Tool: toolName, Version: build.Version, GitCommit: build.GitCommit, BuildDate: build.BuildDate, Policy: policyFile, Host: host, Rules: results}
+		if report.Rules == nil {
+			report.Rules = []RuleResult{}
+		}
+		if selfStatsOn {
+			stats := collectSelfStats(len(results), "rules")
+			report.SelfStats = &stats
+		}
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		writeReport(results, output)
+		if selfStatsOn {
+			writeSelfStats(output, collectSelfStats(len(results), "rules"))
+		}
+	}
+
+	findings := normalizedFindings(results)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	inputs := []string{policyFile}
+	outputs := []string{outputFile, signatureTarget(outputFile), findingsPath}
+	if interrupted {
+		stop()
+		if format == "text" {
+			fmt.Fprintln(output, "Partial report: interrupted; untested rules are listed as SKIP.")
+		}
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		telemetry.finish(130)
+		writeManifest(130, inputs, outputs)
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Firewall rule test complete.")
+	}
+	for _, r := range results {
+		if r.Verdict == "FAIL" || r.Verdict == "ERROR" || strictMode && r.Verdict == "INCONCLUSIVE" {
+			telemetry.finish(1)
+			writeManifest(1, inputs, outputs)
+			os.Exit(1)
+		}
+	}
+	telemetry.finish(0)
+	writeManifest(0, inputs, outputs)
+	os.Exit(0)
+}
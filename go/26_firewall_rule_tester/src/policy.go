@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Expected firewall policy. One rule per line, in columns:
+//
+//	# src           dst           port          action  name
+//	any             10.0.1.10     tcp/22        allow   ssh to bastion
+//	10.0.0.0/24     db.internal   5432          deny    no direct database access
+//	any             192.0.2.80    tcp/80,443    allow
+//	any             192.0.2.80    8000-8010     deny    admin ports
+//
+// src is where the traffic comes from: "any", an IP address, a CIDR network
+// or a host name. The tester can only speak for the host it runs on, so a
+// rule is tested when src covers one of its addresses (or names it) and
+// skipped otherwise; run the tester from each source zone to cover all
+// rules. dst is a host name or IP address. Ports are TCP, optionally written
+// tcp/<ports>, as a comma list and ranges. action is allow or deny.
+
+// maxRulePorts caps the ports one rule may expand to.
+const maxRulePorts = 256
+
+// Rule is one expected policy entry.
+type Rule struct {
+	Line   int    `json:"line"`
+	Name   string `json:"name,omitempty"`
+	Src    string `json:"src"`
+	Dst    string `json:"dst"`
+	Ports  string `json:"ports"`
+	Action string `json:"action"` // allow or deny
+
+	ports []int
+}
+
+// label names the rule in reports: its name, or its line and columns.
+func (r Rule) label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("line %d: %s -> %s %s %s", r.Line, r.Src, r.Dst, r.Ports, r.Action)
+}
+
+// loadPolicy reads and validates a policy file.
+func loadPolicy(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open policy %s: %w", path, err)
+	}
+	defer file.Close()
+	var rules []Rule
+	sc := bufio.NewScanner(file)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("%s:%d: expected \"<src> <dst> <port> <allow|deny> [name]\"", path, lineNo)
+		}
+		r := Rule{Line: lineNo, Src: fields[0], Dst: fields[1], Ports: fields[2], Action: strings.ToLower(fields[3]), Name: strings.Join(fields[4:], " ")}
+		if r.Action != "allow" && r.Action != "deny" {
+			return nil, fmt.Errorf("%s:%d: action %q must be allow or deny", path, lineNo, fields[3])
+		}
+		if r.ports, err = parseRulePorts(r.Ports); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		if _, _, err := net.ParseCIDR(r.Src); err != nil && !strings.EqualFold(r.Src, "any") && strings.Contains(r.Src, "/") {
+			return nil, fmt.Errorf("%s:%d: invalid src network %q", path, lineNo, r.Src)
+		}
+		rules = append(rules, r)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read policy %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// parseRulePorts expands "tcp/80,443,8000-8010" (the tcp/ prefix optional).
+func parseRulePorts(spec string) ([]int, error) {
+	list := spec
+	if proto, rest, ok := strings.Cut(spec, "/"); ok {
+		if !strings.EqualFold(proto, "tcp") {
+			return nil, fmt.Errorf("protocol %q is not supported (only tcp connects can be verified)", proto)
+		}
+		list = rest
+	}
+	var ports []int
+	for _, part := range strings.Split(list, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(hi)
+		}
+		if err != nil || first < 1 || last > 65535 || last < first {
+			return nil, fmt.Errorf("invalid port %q in %q", part, spec)
+		}
+		for p := first; p <= last; p++ {
+			ports = append(ports, p)
+		}
+		if len(ports) > maxRulePorts {
+			return nil, fmt.Errorf("%q expands to more than %d ports", spec, maxRulePorts)
+		}
+	}
+	return ports, nil
+}
+
+// localHost is what the tester knows about the host it runs on.
+type localHost struct {
+	addrs []net.IP
+	names []string // Host name and its first label, lower-case
+}
+
+func currentHost() localHost {
+	var h localHost
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok {
+				h.addrs = append(h.addrs, ipnet.IP)
+			}
+		}
+	}
+	if name, err := os.Hostname(); err == nil {
+		name = strings.ToLower(name)
+		short, _, _ := strings.Cut(name, ".")
+		h.names = []string{name, short}
+	}
+	return h
+}
+
+// source reports whether the rule's src covers this host and, for an IP or
+// CIDR src, which local address the test connections should come from.
+func (h localHost) source(src string) (applies bool, bind net.IP) {
+	if strings.EqualFold(src, "any") || src == "*" {
+		return true, nil
+	}
+	var match func(net.IP) bool
+	if _, network, err := net.ParseCIDR(src); err == nil {
+		match = network.Contains
+	} else if ip := net.ParseIP(src); ip != nil {
+		match = ip.Equal
+	} else {
+		for _, n := range h.names {
+			if strings.EqualFold(src, n) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	for _, a := range h.addrs {
+		if match(a) {
+			return true, a
+		}
+	}
+	return false, nil
+}
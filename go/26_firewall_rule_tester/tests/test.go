@@ -0,0 +1,11 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPlaceholder(t *testing.T) {
+	// This is a placeholder test to ensure the Go test runner can find and execute tests.
+	// Actual tests would run a fixture policy against local listeners, closed ports and a blackholed address.
+	t.Log("Placeholder Go test executed successfully.")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CVSS v3.0/v3.1 base scores. OSV records carry a vector
+// ("CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H") rather than a score, so
+// the base score is computed as in the CVSS v3.1 specification, section 7.
+// Unlike the CVSS-lite vectors of the shared findings model, all eight base
+// metrics are used.
+
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27}, // Scope unchanged; see cvss3Score
+	"UI": {"N": 0.85, "R": 0.62},
+	"S":  {"U": 0, "C": 0},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvss3Score returns the base score of a CVSS v3 vector.
+func cvss3Score(vector string) (float64, error) {
+	parts := strings.Split(vector, "/")
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "CVSS:3.") {
+		return 0, fmt.Errorf("not a CVSS v3 vector: %q", vector)
+	}
+	m := map[string]float64{}
+	scopeChanged, privileges := false, ""
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(part, ":")
+		weights, base := cvss3Weights[key]
+		if !base {
+			continue // Temporal and environmental metrics do not change the base score
+		}
+		w, ok := weights[value]
+		if !ok {
+			return 0, fmt.Errorf("invalid metric %q in %q", part, vector)
+		}
+		m[key] = w
+		switch key {
+		case "S":
+			scopeChanged = value == "C"
+		case "PR":
+			privileges = value
+		}
+	}
+	if len(m) != len(cvss3Weights) {
+		return 0, fmt.Errorf("vector %q lacks base metrics", vector)
+	}
+	if scopeChanged && privileges != "N" {
+		m["PR"] = map[string]float64{"L": 0.68, "H": 0.5}[privileges]
+	}
+	iss := 1 - (1-m["C"])*(1-m["I"])*(1-m["A"])
+	impact := 6.42 * iss
+	if scopeChanged {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * m["AV"] * m["AC"] * m["PR"] * m["UI"]
+	if scopeChanged {
+		return cvssRoundUp(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return cvssRoundUp(math.Min(impact+exploitability, 10)), nil
+}
+
+// cvssRoundUp rounds up to one decimal, avoiding floating point artifacts
+// as the specification's Appendix A describes.
+func cvssRoundUp(x float64) float64 {
+	i := int64(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
+
+// scoreSeverity maps a CVSS score to its qualitative rating.
+func scoreSeverity(score float64) string {
+	switch {
+	case score >= 9:
+		return "critical"
+	case score >= 7:
+		return "high"
+	case score >= 4:
+		return "medium"
+	case score > 0:
+		return "low"
+	}
+	return "info"
+}
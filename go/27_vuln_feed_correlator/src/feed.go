@@ -0,0 +1,606 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Local vulnerability feed snapshots:
+//
+//   - OSV records (https://ossf.github.io/osv-schema/): a directory of
+//     <id>.json files, an ecosystem's all.zip as published in the OSV bucket,
+//     or a JSON file holding one record or an array of them.
+//   - NVD CVE API 2.0 JSON ({"vulnerabilities": [{"cve": ...}]}), plain or
+//     gzipped, as written by the NVD API or its yearly feed mirrors.
+//
+// OSV records name the affected package and version ranges in the package's
+// own ecosystem, so they match precisely. NVD records name products through
+// CPEs and upstream version bounds, which is only a heuristic for packaged
+// software: the CPE product is matched against the package (or source) name
+// and the bounds against the upstream part of the installed version. A
+// distribution that backports a fix without changing the upstream version
+// still matches, so an NVD match is dropped whenever an OSV record already
+// covers the same CVE for the package.
+
+// Advisory is one vulnerability record from a feed.
+type Advisory struct {
+	ID       string
+	Aliases  []string
+	Summary  string
+	Score    float64 // CVSS base score, 0 when the record has none
+	Vector   string
+	Severity string // Rating of the score, or the record's own rating
+	Feed     string // osv or nvd
+	affected []osvAffected
+	cpes     []nvdCPEMatch
+}
+
+// osvRecord is the part of an OSV record the correlator uses.
+type osvRecord struct {
+	ID               string          `json:"id"`
+	Aliases          []string        `json:"aliases"`
+	Upstream         []string        `json:"upstream"`
+	Summary          string          `json:"summary"`
+	Details          string          `json:"details"`
+	Withdrawn        string          `json:"withdrawn"`
+	Severity         []osvSeverity   `json:"severity"`
+	Affected         []osvAffected   `json:"affected"`
+	DatabaseSpecific json.RawMessage `json:"database_specific"`
+}
+
+type osvSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Severity         []osvSeverity   `json:"severity"`
+	Ranges           []osvRange      `json:"ranges"`
+	Versions         []string        `json:"versions"`
+	DatabaseSpecific json.RawMessage `json:"database_specific"`
+}
+
+type osvRange struct {
+	Type   string     `json:"type"`
+	Events []osvEvent `json:"events"`
+}
+
+type osvEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// nvdFeed is the part of an NVD CVE API 2.0 response the correlator uses.
+type nvdFeed struct {
+	Vulnerabilities []struct {
+		CVE struct {
+			ID           string `json:"id"`
+			Descriptions []struct {
+				Lang  string `json:"lang"`
+				Value string `json:"value"`
+			} `json:"descriptions"`
+			Metrics        map[string][]nvdMetric `json:"metrics"`
+			Configurations []struct {
+				Nodes []struct {
+					Negate   bool          `json:"negate"`
+					CPEMatch []nvdCPEMatch `json:"cpeMatch"`
+				} `json:"nodes"`
+			} `json:"configurations"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+type nvdMetric struct {
+	Type     string `json:"type"` // Primary (NVD) or Secondary (CNA)
+	CVSSData struct {
+		BaseScore    float64 `json:"baseScore"`
+		VectorString string  `json:"vectorString"`
+	} `json:"cvssData"`
+}
+
+type nvdCPEMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	Criteria              string `json:"criteria"`
+	VersionStartIncluding string `json:"versionStartIncluding"`
+	VersionStartExcluding string `json:"versionStartExcluding"`
+	VersionEndIncluding   string `json:"versionEndIncluding"`
+	VersionEndExcluding   string `json:"versionEndExcluding"`
+}
+
+// cvePattern finds the CVE a record is about in its ID, aliases or upstream
+// references (Debian's OSV records are "DEBIAN-CVE-...", Red Hat's "RHSA-...").
+var cvePattern = regexp.MustCompile(`CVE-\d{4}-\d{4,}`)
+
+// cves returns the CVE IDs an advisory is about.
+func (a *Advisory) cves() []string {
+	var ids []string
+	for _, s := range append([]string{a.ID}, a.Aliases...) {
+		if id := cvePattern.FindString(s); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// feedIndex holds every advisory, indexed by the lower-cased package or CPE
+// product name it affects, and NVD records by CVE ID for their scores.
+type feedIndex struct {
+	byName     map[string][]*Advisory
+	nvd        map[string]*Advisory
+	advisories int
+}
+
+func newFeedIndex() *feedIndex {
+	return &feedIndex{byName: map[string][]*Advisory{}, nvd: map[string]*Advisory{}}
+}
+
+func (idx *feedIndex) add(a *Advisory, names []string) {
+	seen := map[string]bool{}
+	for _, n := range names {
+		n = strings.ToLower(n)
+		if n != "" && !seen[n] {
+			seen[n] = true
+			idx.byName[n] = append(idx.byName[n], a)
+		}
+	}
+	idx.advisories++
+}
+
+// loadFeed adds the advisories of one feed path (directory, .zip, .json or
+// .json.gz) to idx.
+func loadFeed(idx *feedIndex, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to open feed %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(p, ".json") && !strings.HasSuffix(p, ".json.gz") {
+				return nil
+			}
+			return loadFeedFile(idx, p)
+		})
+	}
+	if strings.HasSuffix(path, ".zip") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return fmt.Errorf("failed to open feed %s: %w", path, err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if !strings.HasSuffix(f.Name, ".json") {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", path, f.Name, err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", path, f.Name, err)
+			}
+			if err := parseFeed(idx, data); err != nil {
+				return fmt.Errorf("%s: %s: %w", path, f.Name, err)
+			}
+		}
+		return nil
+	}
+	return loadFeedFile(idx, path)
+}
+
+func loadFeedFile(idx *feedIndex, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open feed %s: %w", path, err)
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read feed %s: %w", path, err)
+	}
+	if err := parseFeed(idx, data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseFeed tells NVD responses, OSV arrays and single OSV records apart.
+func parseFeed(idx *feedIndex, data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	if data[0] == '[' {
+		var records []osvRecord
+		if err := json.Unmarshal(data, &records); err != nil {
+			return fmt.Errorf("invalid OSV records: %w", err)
+		}
+		for i := range records {
+			addOSV(idx, &records[i])
+		}
+		return nil
+	}
+	var probe struct {
+		ID              string          `json:"id"`
+		Vulnerabilities json.RawMessage `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	switch {
+	case probe.Vulnerabilities != nil:
+		var feed nvdFeed
+		if err := json.Unmarshal(data, &feed); err != nil {
+			return fmt.Errorf("invalid NVD feed: %w", err)
+		}
+		addNVD(idx, &feed)
+	case probe.ID != "":
+		var rec osvRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("invalid OSV record: %w", err)
+		}
+		addOSV(idx, &rec)
+	default:
+		return fmt.Errorf("neither an OSV record nor an NVD feed")
+	}
+	return nil
+}
+
+// ratingSeverity maps the ratings databases put in database_specific
+// (GitHub's MODERATE, Red Hat's Important, Ubuntu's negligible) to the
+// findings scale.
+func ratingSeverity(raw json.RawMessage) string {
+	var ds struct {
+		Severity string `json:"severity"`
+	}
+	if json.Unmarshal(raw, &ds) != nil {
+		return ""
+	}
+	switch strings.ToLower(ds.Severity) {
+	case "critical":
+		return "critical"
+	case "high", "important":
+		return "high"
+	case "medium", "moderate":
+		return "medium"
+	case "low":
+		return "low"
+	case "negligible", "unimportant":
+		return "info"
+	}
+	return ""
+}
+
+func addOSV(idx *feedIndex, rec *osvRecord) {
+	if rec.ID == "" || rec.Withdrawn != "" {
+		return
+	}
+	a := &Advisory{ID: rec.ID, Aliases: append(rec.Aliases, rec.Upstream...), Summary: rec.Summary, Feed: "osv", affected: rec.Affected}
+	if a.Summary == "" {
+		a.Summary = firstSentence(rec.Details)
+	}
+	severities := rec.Severity
+	for _, af := range rec.Affected {
+		severities = append(severities, af.Severity...)
+	}
+	for _, s := range severities {
+		if s.Type != "CVSS_V3" {
+			continue // CVSS v2 and v4 vectors are not scored here
+		}
+		if score, err := cvss3Score(s.Score); err == nil && score > a.Score {
+			a.Score, a.Vector = score, s.Score
+		}
+	}
+	if a.Vector != "" {
+		a.Severity = scoreSeverity(a.Score)
+	} else if a.Severity = ratingSeverity(rec.DatabaseSpecific); a.Severity == "" {
+		for _, af := range rec.Affected {
+			if a.Severity = ratingSeverity(af.DatabaseSpecific); a.Severity != "" {
+				break
+			}
+		}
+	}
+	var names []string
+	for _, af := range rec.Affected {
+		names = append(names, af.Package.Name)
+	}
+	idx.add(a, names)
+}
+
+func addNVD(idx *feedIndex, feed *nvdFeed) {
+	for _, v := range feed.Vulnerabilities {
+		cve := v.CVE
+		a := &Advisory{ID: cve.ID, Feed: "nvd"}
+		for _, d := range cve.Descriptions {
+			if d.Lang == "en" {
+				a.Summary = firstSentence(d.Value)
+				break
+			}
+		}
+		// NVD's own (Primary) CVSS v3.1, else v3.0, else v2 assessment.
+		for _, key := range []string{"cvssMetricV31", "cvssMetricV30", "cvssMetricV2"} {
+			for _, m := range cve.Metrics[key] {
+				if a.Vector == "" || m.Type == "Primary" {
+					a.Score, a.Vector = m.CVSSData.BaseScore, m.CVSSData.VectorString
+				}
+			}
+			if a.Vector != "" {
+				a.Severity = scoreSeverity(a.Score)
+				break
+			}
+		}
+		idx.nvd[a.ID] = a
+		var names []string
+		for _, c := range cve.Configurations {
+			for _, n := range c.Nodes {
+				if n.Negate {
+					continue
+				}
+				for _, m := range n.CPEMatch {
+					if part, product, _ := cpeProduct(m.Criteria); m.Vulnerable && part == "a" {
+						a.cpes = append(a.cpes, m)
+						names = append(names, product)
+					}
+				}
+			}
+		}
+		if len(a.cpes) > 0 {
+			idx.add(a, names)
+		}
+	}
+}
+
+// firstSentence shortens a record's details to a one-line summary.
+func firstSentence(text string) string {
+	text, _, _ = strings.Cut(strings.TrimSpace(text), "\n")
+	if i := strings.Index(text, ". "); i > 0 {
+		return text[:i+1]
+	}
+	return text
+}
+
+// cpeProduct returns the part (a, o or h), product and version of a CPE 2.3
+// name: cpe:2.3:part:vendor:product:version:...
+func cpeProduct(cpe string) (part, product, version string) {
+	f := strings.Split(cpe, ":")
+	if len(f) < 6 || f[0] != "cpe" {
+		return "", "", ""
+	}
+	return f[2], f[4], f[5]
+}
+
+// sameEcosystem compares OSV ecosystems by name ("Debian" against
+// "Debian:12") and by release only when both sides name one.
+func sameEcosystem(feed, installed string) bool {
+	fb, fr, fok := strings.Cut(feed, ":")
+	ib, ir, iok := strings.Cut(installed, ":")
+	return strings.EqualFold(fb, ib) && (!fok || !iok || fr == ir)
+}
+
+// osvAffects reports whether af covers p, with the version that fixes it.
+func osvAffects(af osvAffected, p Package) (bool, string) {
+	if !sameEcosystem(af.Package.Ecosystem, p.Ecosystem) || af.Package.Name != p.Name && af.Package.Name != p.SrcName {
+		return false, ""
+	}
+	for _, v := range af.Versions {
+		if v == p.Version || p.Ecosystem == "Go" && strings.TrimPrefix(v, "v") == strings.TrimPrefix(p.Version, "v") {
+			return true, ""
+		}
+	}
+	for _, r := range af.Ranges {
+		if r.Type != "ECOSYSTEM" && r.Type != "SEMVER" {
+			continue // GIT ranges name commits, not releases
+		}
+		cmp := comparerFor(af.Package.Ecosystem, r.Type)
+		if affected, fixed := inRange(r.Events, p.Version, cmp); affected {
+			return true, fixed
+		}
+	}
+	return false, ""
+}
+
+// inRange evaluates OSV range events in version order, as the OSV schema
+// describes: introduced opens an affected interval, fixed and last_affected
+// close it.
+func inRange(events []osvEvent, version string, cmp compareFunc) (bool, string) {
+	key := func(e osvEvent) string {
+		return e.Introduced + e.Fixed + e.LastAffected + e.Limit
+	}
+	sorted := append([]osvEvent(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := key(sorted[i]), key(sorted[j])
+		if a == "0" || b == "0" {
+			return a == "0" && b != "0"
+		}
+		return cmp(a, b) < 0
+	})
+	affected, fixed := false, ""
+	for _, e := range sorted {
+		switch {
+		case e.Introduced != "":
+			if e.Introduced == "0" || cmp(version, e.Introduced) >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if cmp(version, e.Fixed) >= 0 {
+				affected = false
+			} else if affected && fixed == "" {
+				fixed = e.Fixed
+			}
+		case e.LastAffected != "":
+			if cmp(version, e.LastAffected) > 0 {
+				affected = false
+			}
+		case e.Limit != "":
+			if cmp(version, e.Limit) >= 0 {
+				affected = false
+			}
+		}
+	}
+	if !affected {
+		fixed = ""
+	}
+	return affected, fixed
+}
+
+// upstreamVersion strips packaging from an installed version for comparison
+// with NVD's upstream bounds: the epoch, Debian revision or RPM release,
+// "+dfsg"-style repack suffixes, "~" pre-release markers and Go's "v".
+func upstreamVersion(p Package) string {
+	v := p.Version
+	if p.Ecosystem == "Go" {
+		v = strings.TrimPrefix(v, "v")
+		v, _, _ = strings.Cut(v, "-")
+		v, _, _ = strings.Cut(v, "+")
+		return v
+	}
+	if _, rest, ok := strings.Cut(v, ":"); ok {
+		v = rest
+	}
+	if i := strings.LastIndex(v, "-"); i > 0 {
+		v = v[:i]
+	}
+	v, _, _ = strings.Cut(v, "+")
+	v, _, _ = strings.Cut(v, "~")
+	return v
+}
+
+// cpeNames are the CPE products p may be listed under: its package and
+// source names, with library sonames dropped ("libcurl4" as "libcurl") and
+// dashes read as underscores. Go modules go by their last path element and
+// the standard library by "go".
+func cpeNames(p Package) []string {
+	if p.Ecosystem == "Go" {
+		if p.Name == "stdlib" {
+			return []string{"go"}
+		}
+		return []string{strings.ToLower(p.Name[strings.LastIndex(p.Name, "/")+1:])}
+	}
+	var names []string
+	for _, n := range []string{p.Name, p.SrcName} {
+		n = strings.ToLower(n)
+		if n == "" {
+			continue
+		}
+		names = append(names, n, strings.ReplaceAll(n, "-", "_"))
+		if strings.HasPrefix(n, "lib") {
+			names = append(names, strings.TrimRight(strings.TrimSuffix(n, "t64"), "0123456789.-"))
+		}
+	}
+	return names
+}
+
+// nvdAffects reports whether a CPE match covers p.
+func nvdAffects(m nvdCPEMatch, p Package) bool {
+	_, product, exact := cpeProduct(m.Criteria)
+	matched := false
+	for _, n := range cpeNames(p) {
+		matched = matched || product == n
+	}
+	if !matched {
+		return false
+	}
+	v := upstreamVersion(p)
+	if exact != "*" && exact != "-" {
+		return rpmvercmp(v, exact) == 0
+	}
+	if m.VersionStartIncluding == "" && m.VersionStartExcluding == "" && m.VersionEndIncluding == "" && m.VersionEndExcluding == "" {
+		return false // Every version: too broad to attribute to an installed package
+	}
+	return (m.VersionStartIncluding == "" || rpmvercmp(v, m.VersionStartIncluding) >= 0) &&
+		(m.VersionStartExcluding == "" || rpmvercmp(v, m.VersionStartExcluding) > 0) &&
+		(m.VersionEndIncluding == "" || rpmvercmp(v, m.VersionEndIncluding) <= 0) &&
+		(m.VersionEndExcluding == "" || rpmvercmp(v, m.VersionEndExcluding) < 0)
+}
+
+// Match is one advisory that affects an installed package.
+type Match struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Severity string   `json:"severity"`
+	Score    float64  `json:"score,omitempty"`
+	Vector   string   `json:"vector,omitempty"`
+	Unscored bool     `json:"unscored,omitempty"` // Severity is the --unscored default
+	Fixed    string   `json:"fixed,omitempty"`
+	Summary  string   `json:"summary,omitempty"`
+	Feed     string   `json:"feed"`
+}
+
+// correlate returns the advisories that affect p, OSV matches first. An
+// unscored OSV record borrows the NVD score of its CVE when the NVD feed
+// has one.
+func correlate(idx *feedIndex, p Package) []Match {
+	var osv, nvd []Match
+	covered := map[string]bool{}
+	seen := map[*Advisory]bool{}
+	for _, name := range append([]string{p.Name, p.SrcName}, cpeNames(p)...) {
+		for _, a := range idx.byName[strings.ToLower(name)] {
+			if seen[a] {
+				continue
+			}
+			seen[a] = true
+			m := Match{ID: a.ID, Aliases: a.Aliases, Severity: a.Severity, Score: a.Score, Vector: a.Vector, Summary: a.Summary, Feed: a.Feed}
+			if a.Feed == "nvd" {
+				for _, c := range a.cpes {
+					if nvdAffects(c, p) {
+						nvd = append(nvd, m)
+						break
+					}
+				}
+				continue
+			}
+			hit := false
+			for _, af := range a.affected {
+				if ok, fixed := osvAffects(af, p); ok {
+					hit, m.Fixed = true, fixed
+					break
+				}
+			}
+			if !hit {
+				continue
+			}
+			for _, id := range a.cves() {
+				covered[id] = true
+				if n := idx.nvd[id]; m.Severity == "" && n != nil && n.Severity != "" {
+					m.Severity, m.Score, m.Vector = n.Severity, n.Score, n.Vector
+				}
+			}
+			osv = append(osv, m)
+		}
+	}
+	for _, m := range nvd {
+		if !covered[m.ID] {
+			osv = append(osv, m)
+		}
+	}
+	return osv
+}
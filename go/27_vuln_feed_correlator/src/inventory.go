@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Installed-software inventories, as the package tools print them:
+//
+//	dpkg -l                                              (Debian, Ubuntu)
+//	dpkg-query -W -f '${Package} ${Version} ${source:Package}\n'
+//	rpm -qa                                              (RHEL, Fedora, SUSE)
+//	rpm -qa --qf '%{NAME} %{EPOCHNUM}:%{VERSION}-%{RELEASE}\n'
+//	go version -m /usr/local/bin/*                       (Go binaries)
+//
+// The dpkg-query form is preferred on Debian: distribution advisories name
+// source packages (openssl), while dpkg -l lists binary ones (libssl3).
+
+// Package is one installed package or Go module.
+type Package struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Ecosystem string `json:"ecosystem"`        // OSV ecosystem: Debian, Red Hat, Go, ...
+	SrcName   string `json:"source,omitempty"` // Debian source package
+	Binary    string `json:"binary,omitempty"` // Go binary the module was built into
+}
+
+// goBinaryLine is the first line of each binary in go version -m output.
+var goBinaryLine = regexp.MustCompile(`^(\S.*): (go[0-9][^\s]*)$`)
+
+// rpmArchs are the architecture suffixes of rpm -qa names.
+var rpmArchs = []string{"x86_64", "aarch64", "i686", "i386", "noarch", "ppc64le", "s390x", "armv7hl", "src"}
+
+// detectInventoryFormat guesses the tool that printed lines: go, dpkg or rpm.
+func detectInventoryFormat(lines []string) string {
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(line, "#"):
+			continue
+		case goBinaryLine.MatchString(line):
+			return "go"
+		case strings.HasPrefix(line, "Desired=") || strings.HasPrefix(line, "||/"):
+			return "dpkg"
+		case len(fields) == 1 && hasRPMArch(fields[0]):
+			return "rpm"
+		}
+	}
+	return "dpkg"
+}
+
+func hasRPMArch(nevra string) bool {
+	for _, arch := range rpmArchs {
+		if strings.HasSuffix(nevra, "."+arch) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadInventory reads an inventory file in format (auto, dpkg, rpm or go).
+// ecosystem overrides the OSV ecosystem of dpkg and rpm packages, e.g.
+// "Ubuntu" or "Rocky Linux:9".
+func loadInventory(path, format, ecosystem string) ([]Package, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open inventory %s: %w", path, err)
+	}
+	defer file.Close()
+	var lines []string
+	sc := bufio.NewScanner(file)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		lines = append(lines, strings.TrimRight(sc.Text(), "\r"))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read inventory %s: %w", path, err)
+	}
+	if format == "auto" {
+		format = detectInventoryFormat(lines)
+	}
+	var pkgs []Package
+	switch format {
+	case "go":
+		pkgs = parseGoVersionM(lines)
+	case "dpkg":
+		pkgs = parseDpkg(lines, orDefault(ecosystem, "Debian"))
+	case "rpm":
+		pkgs = parseRPM(lines, orDefault(ecosystem, "Red Hat"))
+	default:
+		return nil, "", fmt.Errorf("unknown inventory format %q", format)
+	}
+	return pkgs, format, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// parseDpkg reads dpkg -l (only installed, "?i" rows) or dpkg-query lines
+// of name, version and optional source package.
+func parseDpkg(lines []string, ecosystem string) []Package {
+	var pkgs []Package
+	listing := false
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "+++-"):
+			listing = true // dpkg -l table starts
+			continue
+		case strings.HasPrefix(line, "Desired=") || strings.HasPrefix(line, "|") || strings.HasPrefix(line, "||/"):
+			continue
+		}
+		if listing {
+			if len(fields) < 3 || len(fields[0]) != 2 || fields[0][1] != 'i' {
+				continue // Removed, config-files only, half-installed
+			}
+			fields = fields[1:3]
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		p := Package{Name: strings.SplitN(fields[0], ":", 2)[0], Version: fields[1], Ecosystem: ecosystem}
+		if len(fields) > 2 {
+			// ${source:Package} may carry a version: "openssl (3.0.11-1)"
+			p.SrcName = fields[2]
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs
+}
+
+// parseRPM reads rpm -qa NEVRA names (name-version-release.arch) or
+// "name [epoch:]version-release" lines.
+func parseRPM(lines []string, ecosystem string) []Package {
+	var pkgs []Package
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(fields) >= 2 {
+			pkgs = append(pkgs, Package{Name: fields[0], Version: fields[1], Ecosystem: ecosystem})
+			continue
+		}
+		nevra := fields[0]
+		for _, arch := range rpmArchs {
+			nevra = strings.TrimSuffix(nevra, "."+arch)
+		}
+		// The last two dashes separate version and release from the name.
+		r := strings.LastIndex(nevra, "-")
+		if r <= 0 {
+			continue
+		}
+		v := strings.LastIndex(nevra[:r], "-")
+		if v <= 0 {
+			continue
+		}
+		pkgs = append(pkgs, Package{Name: nevra[:v], Version: nevra[v+1:], Ecosystem: ecosystem})
+	}
+	return pkgs
+}
+
+// parseGoVersionM reads go version -m output: the toolchain of each binary
+// (as the OSV "stdlib" package) and its main module and dependencies, with
+// replacements ("=>") taking the place of the module they replace.
+func parseGoVersionM(lines []string) []Package {
+	var pkgs []Package
+	binary := ""
+	for _, line := range lines {
+		if m := goBinaryLine.FindStringSubmatch(line); m != nil {
+			binary = m[1]
+			pkgs = append(pkgs, Package{Name: "stdlib", Version: strings.TrimPrefix(m[2], "go"), Ecosystem: "Go", Binary: binary})
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || binary == "" {
+			continue
+		}
+		switch fields[0] {
+		case "mod", "dep":
+			if fields[2] != "(devel)" {
+				pkgs = append(pkgs, Package{Name: fields[1], Version: fields[2], Ecosystem: "Go", Binary: binary})
+			}
+		case "=>":
+			if len(pkgs) > 0 && !strings.HasPrefix(fields[1], ".") && !strings.HasPrefix(fields[1], "/") {
+				last := &pkgs[len(pkgs)-1]
+				last.Name, last.Version = fields[1], fields[2]
+			}
+		}
+	}
+	return pkgs
+}
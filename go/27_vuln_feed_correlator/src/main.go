@@ -0,0 +1,403 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of a Vulnerability Feed Correlator.
+PURPOSE: Show skill in vulnerability management, package version semantics, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "vuln_feed_correlator"
+	toolVersion = "1.0.0"
+)
+
+// stringList collects a repeatable flag.
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+// Global variables for CLI flags
+var (
+	inventoryFiles  stringList
+	feedPaths       stringList
+	inventoryFormat string
+	ecosystem       string
+	minSeverity     string
+	unscored        string
+	failOn          string
+	outputFile      string
+	format          string
+	verboseMode     bool
+)
+
+func init() {
+	flag.Var(&inventoryFiles, "inventory", "Installed-software inventory: dpkg -l, dpkg-query, rpm -qa or go version -m output; repeatable.")
+	flag.Var(&inventoryFiles, "i", "Installed-software inventory (shorthand).")
+	flag.StringVar(&inventoryFormat, "inventory-format", "auto", "Inventory format: auto, dpkg, rpm or go.")
+	flag.StringVar(&ecosystem, "ecosystem", "", "OSV ecosystem of dpkg/rpm packages, optionally with a release (e.g. Debian:12, Ubuntu, Rocky Linux:9). Default: Debian for dpkg, Red Hat for rpm.")
+
+	flag.Var(&feedPaths, "feed", "Feed snapshot: an OSV directory, all.zip or .json file, or an NVD 2.0 .json(.gz) file; repeatable.")
+
+	flag.StringVar(&minSeverity, "min-severity", "low", "Only report vulnerabilities of at least this severity: info, low, medium, high or critical.")
+	flag.StringVar(&unscored, "unscored", "medium", "Severity assumed for vulnerabilities no feed rates.")
+	flag.StringVar(&failOn, "fail-on", "", "Exit with status 1 when a reported vulnerability is at least this severity.")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerOtelFlag()
+	registerFindingsFlags()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Matches installed packages against a local OSV/NVD feed snapshot and reports known vulnerabilities.\n")
+		fmt.Fprintf(os.Stderr, "  Example: dpkg-query -W -f '${Package} ${Version} ${source:Package}\\n' > inventory.txt\n")
+		fmt.Fprintf(os.Stderr, "           %s -i inventory.txt --ecosystem Debian:12 --feed osv-debian/all.zip\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: go version -m /usr/local/bin/* > go_binaries.txt\n")
+		fmt.Fprintf(os.Stderr, "           %s -i go_binaries.txt --feed osv-go/ --min-severity high --fail-on high\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// PackageResult is an installed package and the vulnerabilities that affect it.
+type PackageResult struct {
+	Package
+	Vulns []Match `json:"vulnerabilities"`
+}
+
+// severityOf is the severity a match is reported with.
+func severityOf(m *Match) string {
+	if m.Severity == "" {
+		m.Severity, m.Unscored = unscored, true
+	}
+	return m.Severity
+}
+
+// correlateAll matches every package, keeping those with a vulnerability at
+// or above --min-severity, worst first.
+func correlateAll(idx *feedIndex, pkgs []Package) []PackageResult {
+	var results []PackageResult
+	floor := normSeverityRank(minSeverity)
+	for _, p := range pkgs {
+		var vulns []Match
+		for _, m := range correlate(idx, p) {
+			if normSeverityRank(severityOf(&m)) >= floor {
+				vulns = append(vulns, m)
+			}
+		}
+		if len(vulns) == 0 {
+			continue
+		}
+		sort.SliceStable(vulns, func(i, j int) bool {
+			ri, rj := normSeverityRank(vulns[i].Severity), normSeverityRank(vulns[j].Severity)
+			if ri != rj {
+				return ri > rj
+			}
+			if vulns[i].Score != vulns[j].Score {
+				return vulns[i].Score > vulns[j].Score
+			}
+			return vulns[i].ID < vulns[j].ID
+		})
+		for _, m := range vulns {
+			telemetry.count("vulnerabilities", map[string]string{"severity": m.Severity, "ecosystem": p.Ecosystem, "feed": m.Feed}, 1)
+		}
+		results = append(results, PackageResult{Package: p, Vulns: vulns})
+	}
+	return results
+}
+
+// label names a package as the report lists it.
+func (r PackageResult) label() string {
+	l := r.Name + " " + r.Version
+	switch {
+	case r.Binary != "":
+		l += " (in " + r.Binary + ")"
+	case r.SrcName != "" && r.SrcName != r.Name:
+		l += " (source " + r.SrcName + ")"
+	}
+	return l
+}
+
+// writeReport writes each vulnerable package with its advisories.
+func writeReport(results []PackageResult, scanned, advisories int, output io.Writer) {
+	fmt.Fprintf(output, "--- Vulnerability Feed Correlation Report ---\n\n")
+	fmt.Fprintf(output, "Feeds: %s (%d advisory records)\n", strings.Join(feedPaths, ", "), advisories)
+	fmt.Fprintf(output, "Inventory: %s (%d packages)\n", strings.Join(inventoryFiles, ", "), scanned)
+	fmt.Fprintf(output, "Minimum severity: %s\n\n", minSeverity)
+	counts := map[string]int{}
+	for _, r := range results {
+		fmt.Fprintf(output, "%s\n", r.label())
+		for _, m := range r.Vulns {
+			counts[m.Severity]++
+			rating := colorStatus(strings.ToUpper(m.Severity))
+			switch {
+			case m.Unscored:
+				rating += " unscored"
+			case m.Score > 0:
+				rating += fmt.Sprintf(" %.1f", m.Score)
+			}
+			id := m.ID
+			if cves := (&Advisory{ID: m.ID, Aliases: m.Aliases}).cves(); len(cves) > 0 && cves[0] != m.ID {
+				id += " (" + cves[0] + ")"
+			}
+			fixed := ", no fix listed"
+			if m.Fixed != "" {
+				fixed = ", fixed in " + m.Fixed
+			}
+			if m.Feed == "nvd" {
+				fixed = ", NVD upstream range"
+			}
+			fmt.Fprintf(output, "    [%s] %s%s\n", rating, id, fixed)
+			if m.Summary != "" {
+				fmt.Fprintf(output, "        %s\n", m.Summary)
+			}
+		}
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(output, "No known vulnerabilities at or above %s.\n", minSeverity)
+	}
+	var parts []string
+	total := 0
+	for i := len(normSeverityNames) - 1; i >= 0; i-- {
+		if n := counts[normSeverityNames[i]]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, strings.ToUpper(normSeverityNames[i])))
+			total += n
+		}
+	}
+	fmt.Fprintf(output, "\nSummary: %d of %d package(s) vulnerable, %d match(es)", len(results), scanned, total)
+	if len(parts) > 0 {
+		fmt.Fprintf(output, ": %s", strings.Join(parts, ", "))
+	}
+	fmt.Fprintln(output)
+}
+
+// normalizedFindings converts each reported vulnerability to the cross-tool
+// findings model, keeping the feed's CVSS score.
+func normalizedFindings(results []PackageResult) []normFinding {
+	var out []normFinding
+	for _, r := range results {
+		for _, m := range r.Vulns {
+			detail := m.Summary
+			if m.Fixed != "" {
+				detail = strings.TrimSpace(detail + " (fixed in " + m.Fixed + ")")
+			}
+			out = append(out, normFinding{Tool: toolName, Target: r.Name + "@" + r.Version, Category: "vulnerability", Title: m.ID + " affects " + r.Name, Severity: m.Severity, Score: m.Score, Detail: detail})
+		}
+	}
+	return out
+}
+
+type jsonReport struct {
+	Tool       string          `json:"tool"`
+	Version    string          `json:"version"`
+	GitCommit  string          `json:"git_commit,omitempty"`
+	BuildDate  string          `json:"build_date,omitempty"`
+	Feeds      []string        `json:"feeds"`
+	Advisories int             `json:"advisories"`
+	Inventory  []string        `json:"inventory"`
+	Scanned    int             `json:"packages_scanned"`
+	Packages   []PackageResult `json:"vulnerable_packages"`
+	SelfStats  *selfStats      `json:"self_stats,omitempty"`
+}
+
+// main is the entry point of the Vulnerability Feed Correlator tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	if len(inventoryFiles) == 0 || len(feedPaths) == 0 {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] At least one inventory (-i) and one feed (--feed) must be provided.")
+		os.Exit(1)
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --format %q (use text or json)\n", format)
+		os.Exit(1)
+	}
+	switch inventoryFormat {
+	case "auto", "dpkg", "rpm", "go":
+	default:
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --inventory-format %q (use auto, dpkg, rpm or go)\n", inventoryFormat)
+		os.Exit(1)
+	}
+	for _, opt := range [][2]string{{"--min-severity", minSeverity}, {"--unscored", unscored}, {"--fail-on", failOn}} {
+		if normSeverityRank(opt[1]) < 0 && (opt[1] != "" || opt[0] != "--fail-on") {
+			fmt.Fprintf(os.Stderr, "[ERROR] Invalid %s %q (use info, low, medium, high or critical)\n", opt[0], opt[1])
+			os.Exit(1)
+		}
+	}
+	minSeverity, unscored, failOn = strings.ToLower(minSeverity), strings.ToLower(unscored), strings.ToLower(failOn)
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkFindingsMin(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --findings-min: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	startOtel()
+
+	// SIGINT/SIGTERM stop loading; nothing is reported from a partial feed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	inputs := append(append([]string{}, inventoryFiles...), feedPaths...)
+	interrupted := func() {
+		warnf("Interrupted by signal; no report written.")
+		telemetry.finish(130)
+		writeManifest(130, inputs, nil)
+		os.Exit(130)
+	}
+
+	idx := newFeedIndex()
+	for _, path := range feedPaths {
+		start, before := time.Now(), idx.advisories
+		err := loadFeed(idx, path)
+		telemetry.span("feed", start, map[string]string{"feed": path, "advisories": fmt.Sprint(idx.advisories - before)}, err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			telemetry.finish(1)
+			os.Exit(1)
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Loaded %d advisories from %s\n", idx.advisories-before, path)
+		}
+		if ctx.Err() != nil {
+			interrupted()
+		}
+	}
+	if idx.advisories == 0 {
+		warnf("The feeds hold no advisories; every package will look clean.")
+	}
+
+	var pkgs []Package
+	for _, path := range inventoryFiles {
+		start := time.Now()
+		loaded, detected, err := loadInventory(path, inventoryFormat, ecosystem)
+		telemetry.span("inventory", start, map[string]string{"inventory": path, "format": detected, "packages": fmt.Sprint(len(loaded))}, err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			telemetry.finish(1)
+			os.Exit(1)
+		}
+		if len(loaded) == 0 {
+			warnf("No packages found in %s (read as %s).", path, detected)
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Read %d packages from %s (%s)\n", len(loaded), path, detected)
+		}
+		pkgs = append(pkgs, loaded...)
+	}
+	if ctx.Err() != nil {
+		interrupted()
+	}
+
+	results := correlateAll(idx, pkgs)
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+	if format == "json" {
+		build := currentBuild()
+		report := jsonReport{Tool: toolName, Version: build.Version, GitCommit: build.GitCommit, BuildDate: build.BuildDate, Feeds: feedPaths, Advisories: idx.advisories, Inventory: inventoryFiles, Scanned: len(pkgs), Packages: results}
+		if report.Packages == nil {
+			report.Packages = []PackageResult{}
+		}
+		if selfStatsOn {
+			stats := collectSelfStats(len(pkgs), "packages")
+			report.SelfStats = &stats
+		}
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		writeReport(results, len(pkgs), idx.advisories, output)
+		if selfStatsOn {
+			writeSelfStats(output, collectSelfStats(len(pkgs), "packages"))
+		}
+	}
+
+	findings := normalizedFindings(results)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	outputs := []string{outputFile, signatureTarget(outputFile), findingsPath}
+	if !closeSink(output) {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Vulnerability correlation complete.")
+	}
+	if failOn != "" {
+		for _, r := range results {
+			for _, m := range r.Vulns {
+				if normSeverityRank(m.Severity) >= normSeverityRank(failOn) {
+					telemetry.finish(1)
+					writeManifest(1, inputs, outputs)
+					os.Exit(1)
+				}
+			}
+		}
+	}
+	telemetry.finish(0)
+	writeManifest(0, inputs, outputs)
+	os.Exit(0)
+}
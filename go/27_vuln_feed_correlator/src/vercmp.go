@@ -0,0 +1,264 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Version ordering per ecosystem. Debian and RPM versions are not semantic
+// versions ("1:2.9-1ubuntu0.3~esm1", "3.0.7-25.el9_3"), so each packaging
+// system's own comparison is reimplemented: dpkg's verrevcmp and RPM's
+// rpmvercmp. Go modules use semantic versioning.
+
+// compareFunc returns <0, 0 or >0 as a sorts before, equal to or after b.
+type compareFunc func(a, b string) int
+
+// rpmEcosystems are the OSV ecosystems versioned with RPM rules.
+var rpmEcosystems = []string{"Red Hat", "AlmaLinux", "Rocky Linux", "openSUSE", "SUSE", "Mageia", "openEuler"}
+
+// comparerFor picks the version ordering for an OSV ecosystem and range type.
+func comparerFor(ecosystem, rangeType string) compareFunc {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	switch {
+	case rangeType == "SEMVER" || base == "Go":
+		return compareSemver
+	case base == "Debian" || base == "Ubuntu":
+		return compareDpkg
+	}
+	for _, e := range rpmEcosystems {
+		if strings.EqualFold(base, e) {
+			return compareRPM
+		}
+	}
+	return rpmvercmp // Good enough for dotted numeric versions
+}
+
+// compareDpkg orders Debian versions: [epoch:]upstream[-revision].
+func compareDpkg(a, b string) int {
+	ea, ua, ra := splitDpkg(a)
+	eb, ub, rb := splitDpkg(b)
+	if ea != eb {
+		return ea - eb
+	}
+	if c := verrevcmp(ua, ub); c != 0 {
+		return c
+	}
+	return verrevcmp(ra, rb)
+}
+
+func splitDpkg(v string) (epoch int, upstream, revision string) {
+	if e, rest, ok := strings.Cut(v, ":"); ok {
+		if n, err := strconv.Atoi(e); err == nil {
+			epoch, v = n, rest
+		}
+	}
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		return epoch, v[:i], v[i+1:]
+	}
+	return epoch, v, ""
+}
+
+// dpkgOrder is the weight of one non-digit character: "~" sorts before
+// everything, even the end of the string, and letters before other symbols.
+func dpkgOrder(s string, i int) int {
+	switch {
+	case i >= len(s) || isDigit(s[i]):
+		return 0
+	case s[i] == '~':
+		return -1
+	case isLetter(s[i]):
+		return int(s[i])
+	}
+	return int(s[i]) + 256
+}
+
+// verrevcmp is dpkg's comparison of an upstream version or revision:
+// alternating runs of non-digits (by dpkgOrder) and numbers (by value).
+func verrevcmp(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isDigit(a[i]) || j < len(b) && !isDigit(b[j]) {
+			if ac, bc := dpkgOrder(a, i), dpkgOrder(b, j); ac != bc {
+				return ac - bc
+			}
+			i, j = i+1, j+1
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i, j = i+1, j+1
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
+
+// compareRPM orders RPM versions: [epoch:]version[-release]. Epochs are
+// compared only when both sides give one: rpm -qa names leave the epoch out,
+// and reading that as 0 would make every fix with an epoch look newer.
+func compareRPM(a, b string) int {
+	ea, va, ra := splitRPM(a)
+	eb, vb, rb := splitRPM(b)
+	if ea != eb && strings.Contains(a, ":") && strings.Contains(b, ":") {
+		return ea - eb
+	}
+	if c := rpmvercmp(va, vb); c != 0 || ra == "" || rb == "" {
+		return c
+	}
+	return rpmvercmp(ra, rb)
+}
+
+func splitRPM(v string) (epoch int, version, release string) {
+	if e, rest, ok := strings.Cut(v, ":"); ok {
+		if n, err := strconv.Atoi(e); err == nil {
+			epoch, v = n, rest
+		}
+	}
+	version, release, _ = strings.Cut(v, "-")
+	return epoch, version, release
+}
+
+// rpmvercmp is RPM's segment comparison: runs of digits compare as numbers
+// and are newer than runs of letters, other characters only separate, "~"
+// sorts before anything (pre-releases) and "^" after the end but before
+// anything else (snapshots).
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	for {
+		a = strings.TrimLeftFunc(a, isRPMSeparator)
+		b = strings.TrimLeftFunc(b, isRPMSeparator)
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case !strings.HasPrefix(a, "^"):
+				return 1
+			case !strings.HasPrefix(b, "^"):
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+		numeric := isDigit(a[0])
+		segA, segB := leadingRun(a, numeric), leadingRun(b, numeric)
+		a, b = a[len(segA):], b[len(segB):]
+		if segB == "" {
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		if numeric {
+			segA, segB = strings.TrimLeft(segA, "0"), strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				return len(segA) - len(segB)
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+func isRPMSeparator(r rune) bool {
+	return r < 128 && !isDigit(byte(r)) && !isLetter(byte(r)) && r != '~' && r != '^'
+}
+
+// leadingRun returns the leading digits (numeric) or letters of s.
+func leadingRun(s string, numeric bool) string {
+	i := 0
+	for i < len(s) && (numeric && isDigit(s[i]) || !numeric && isLetter(s[i])) {
+		i++
+	}
+	return s[:i]
+}
+
+// compareSemver orders semantic versions, with or without a "v" prefix;
+// build metadata ("+incompatible") is ignored and a pre-release sorts before
+// its release.
+func compareSemver(a, b string) int {
+	ca, pa := splitSemver(a)
+	cb, pb := splitSemver(b)
+	for i := 0; i < 3; i++ {
+		if ca[i] != cb[i] {
+			return ca[i] - cb[i]
+		}
+	}
+	switch {
+	case pa == pb:
+		return 0
+	case pa == "":
+		return 1
+	case pb == "":
+		return -1
+	}
+	ia, ib := strings.Split(pa, "."), strings.Split(pb, ".")
+	for k := 0; k < len(ia) && k < len(ib); k++ {
+		na, errA := strconv.Atoi(ia[k])
+		nb, errB := strconv.Atoi(ib[k])
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			return na - nb
+		case errA == nil && errB != nil:
+			return -1 // Numeric identifiers sort first
+		case errA != nil && errB == nil:
+			return 1
+		case errA != nil && ia[k] != ib[k]:
+			return strings.Compare(ia[k], ib[k])
+		}
+	}
+	return len(ia) - len(ib)
+}
+
+func splitSemver(v string) (core [3]int, pre string) {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "+")
+	v, pre, _ = strings.Cut(v, "-")
+	for i, part := range strings.SplitN(v, ".", 3) {
+		core[i], _ = strconv.Atoi(part)
+	}
+	return core, pre
+}
+
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
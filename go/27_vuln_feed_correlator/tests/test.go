@@ -0,0 +1,11 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPlaceholder(t *testing.T) {
+	// This is a placeholder test to ensure the Go test runner can find and execute tests.
+	// Actual tests would check dpkg/RPM/semver ordering, OSV range evaluation and CVSS v3 scoring against known vectors.
+	t.Log("Placeholder Go test executed successfully.")
+}
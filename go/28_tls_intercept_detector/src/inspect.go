@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Site statuses, from clean to the strongest sign of interception.
+const (
+	statusOK        = "OK"              // Trusted chain that matches a pin
+	statusUnpinned  = "UNPINNED"        // Trusted chain, no pins to compare, nothing suspicious
+	statusError     = "ERROR"           // No handshake, so nothing could be checked
+	statusSuspect   = "SUSPECT"         // Trusted chain with a heuristic sign of interception
+	statusInspector = "KNOWN_INSPECTOR" // Issued by a TLS inspection product's CA
+	statusMismatch  = "PIN_MISMATCH"    // Trusted chain that matches none of the pins
+	statusUntrusted = "UNTRUSTED"       // Chain that does not verify for the site
+)
+
+// statusRank orders statuses by how strongly they indicate interception.
+var statusRank = map[string]int{statusOK: 0, statusUnpinned: 0, statusError: 1, statusSuspect: 2, statusInspector: 3, statusMismatch: 4, statusUntrusted: 5}
+
+// inspectorIssuers are names found in the CAs of TLS inspection products:
+// secure web gateways, next-generation firewalls, antivirus HTTPS scanning
+// and debugging proxies. They re-sign every site with their own CA, which
+// the administrator (or the antivirus installer) added to the trust store.
+var inspectorIssuers = []string{
+	"Zscaler", "Fortinet", "FortiGate", "Palo Alto Networks", "Blue Coat", "Cisco Umbrella",
+	"Sophos", "Netskope", "Forcepoint", "Websense", "McAfee Web Gateway", "Check Point",
+	"Barracuda", "Untangle", "Smoothwall", "Lightspeed", "Securly", "iboss", "Menlo Security",
+	"Kaspersky Anti-Virus", "Avast Web/Mail Shield", "AVG Technologies", "ESET SSL Filter",
+	"Bitdefender Personal CA", "mitmproxy", "PortSwigger", "Charles Proxy", "DO_NOT_TRUST_Fiddler",
+}
+
+// SiteResult is what one site presented and how it compares.
+type SiteResult struct {
+	Site
+	Address     string   `json:"address,omitempty"` // Server or proxy connected to
+	Proxy       string   `json:"proxy,omitempty"`
+	Status      string   `json:"status"`
+	Subject     string   `json:"subject,omitempty"`
+	Issuer      string   `json:"issuer,omitempty"`
+	IssuerPin   string   `json:"issuer_pin,omitempty"` // SPKI pin of the leaf's issuer, when presented
+	Fingerprint string   `json:"fingerprint,omitempty"`
+	ChainLength int      `json:"chain_length,omitempty"`
+	AgeHours    float64  `json:"age_hours,omitempty"` // Time since the leaf's NotBefore
+	Verify      string   `json:"verify_error,omitempty"`
+	MatchedPin  string   `json:"matched_pin,omitempty"`
+	Signals     []string `json:"signals,omitempty"`
+	Error       string   `json:"error,omitempty"`
+	ErrClass    string   `json:"error_class,omitempty"`
+	LatencyMs   float64  `json:"latency_ms,omitempty"`
+
+	verified []*x509.Certificate // Verified chain, leaf to root
+}
+
+// raise sets the status if it is stronger than the current one.
+func (r *SiteResult) raise(status, signal string) {
+	if statusRank[status] > statusRank[r.Status] {
+		r.Status = status
+	}
+	r.Signals = append(r.Signals, signal)
+}
+
+// dialer connects to sites directly, through --resolve overrides, or
+// through an HTTP CONNECT proxy, the way an application on this host would.
+type dialer struct {
+	proxy   *url.URL
+	resolve map[string]string // host:port -> address to connect to instead
+	timeout time.Duration
+	roots   *x509.CertPool // nil for the system trust store
+}
+
+// connect opens the TCP path to site, returning the address connected to.
+func (d *dialer) connect(ctx context.Context, site Site) (net.Conn, string, error) {
+	nd := net.Dialer{}
+	if d.proxy == nil {
+		addr := site.target()
+		if override, ok := d.resolve[addr]; ok {
+			addr = override
+		}
+		conn, err := nd.DialContext(ctx, "tcp", addr)
+		return conn, addr, err
+	}
+	addr := d.proxy.Host
+	if d.proxy.Port() == "" {
+		addr = net.JoinHostPort(d.proxy.Hostname(), "8080")
+	}
+	conn, err := nd.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, addr, fmt.Errorf("proxy %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", site.target(), site.target())
+	if user := d.proxy.User; user != nil {
+		req += "Proxy-Authorization: Basic " + basicAuth(user) + "\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		conn.Close()
+		return nil, addr, fmt.Errorf("proxy %s: %w", addr, err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		conn.Close()
+		return nil, addr, fmt.Errorf("proxy %s: %w", addr, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, addr, fmt.Errorf("proxy %s refused CONNECT %s: %s", addr, site.target(), resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, addr, nil
+}
+
+func basicAuth(user *url.Userinfo) string {
+	password, _ := user.Password()
+	return base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+}
+
+// inspect handshakes with site without verification, then verifies the
+// presented chain itself, so that an untrusted chain can still be examined.
+func (d *dialer) inspect(ctx context.Context, site Site) SiteResult {
+	r := SiteResult{Site: site}
+	if d.proxy != nil {
+		r.Proxy = d.proxy.Redacted()
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	start := time.Now()
+	raw, addr, err := d.connect(ctx, site)
+	r.Address = addr
+	if err != nil {
+		r.Status, r.Error, r.ErrClass = statusError, err.Error(), classifyError(err)
+		return r
+	}
+	defer raw.Close()
+	cfg := &tls.Config{InsecureSkipVerify: true} // Verified below, against the chosen roots
+	if net.ParseIP(site.Host) == nil {
+		cfg.ServerName = site.Host
+	}
+	conn := tls.Client(raw, cfg)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		r.Status, r.Error, r.ErrClass = statusError, "TLS handshake: "+err.Error(), classifyError(err)
+		return r
+	}
+	r.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	chain := conn.ConnectionState().PeerCertificates
+	leaf := chain[0]
+	r.Subject, r.Issuer, r.Fingerprint, r.ChainLength = leaf.Subject.String(), leaf.Issuer.String(), certFingerprint(leaf), len(chain)
+	r.AgeHours = float64(int(time.Since(leaf.NotBefore).Hours()*10)) / 10
+	if len(chain) > 1 {
+		r.IssuerPin = spkiPin(chain[1])
+	}
+	r.Status = statusUnpinned
+
+	opts := x509.VerifyOptions{DNSName: site.Host, Roots: d.roots, Intermediates: x509.NewCertPool()}
+	for _, c := range chain[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	if chains, err := leaf.Verify(opts); err != nil {
+		r.Verify = err.Error()
+		r.raise(statusUntrusted, explainVerify(err, leaf))
+	} else {
+		r.verified = chains[0]
+	}
+
+	// Pins and inspector names also match the root from the trust store,
+	// which servers usually omit.
+	all := append(append([]*x509.Certificate{}, chain...), r.verified...)
+	for _, c := range all {
+		if name := inspectorName(c); name != "" {
+			r.raise(statusInspector, fmt.Sprintf("chain issued by %s, a TLS inspection product", name))
+			break
+		}
+	}
+	if len(site.Pins) > 0 {
+		if r.MatchedPin = matchPin(site.Pins, all); r.MatchedPin != "" {
+			if r.Status == statusUnpinned {
+				r.Status = statusOK
+			}
+		} else {
+			r.raise(statusMismatch, "presented chain matches none of the pinned keys or issuers")
+		}
+	}
+	if r.AgeHours < 24 && statusRank[r.Status] >= statusRank[statusSuspect] {
+		r.Signals = append(r.Signals, fmt.Sprintf("leaf certificate issued %.1f hours ago (interception proxies mint them on demand)", r.AgeHours))
+	}
+	return r
+}
+
+// explainVerify says why a chain did not verify, in interception terms.
+func explainVerify(err error, leaf *x509.Certificate) string {
+	var unknown x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknown):
+		return fmt.Sprintf("issued by %q, which this host does not trust", leaf.Issuer.String())
+	case errors.As(err, &hostname):
+		return "certificate is not valid for this name: " + hostname.Error()
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return "certificate is expired or not yet valid"
+	}
+	return "chain does not verify: " + err.Error()
+}
+
+// inspectorName returns the inspection product named in c's subject or
+// issuer, or "".
+func inspectorName(c *x509.Certificate) string {
+	names := strings.ToLower(c.Subject.String() + " " + c.Issuer.String())
+	for _, p := range inspectorIssuers {
+		if strings.Contains(names, strings.ToLower(p)) {
+			return p
+		}
+	}
+	return ""
+}
+
+// flagCommonIssuer marks the sites when every one that answered has the
+// same issuer: the default sites use different CAs, so one issuer for all of
+// them means a local CA is re-signing the traffic. It needs at least three
+// sites, and returns the common issuer.
+func flagCommonIssuer(results []SiteResult) string {
+	issuers := map[string]bool{}
+	var issuer string
+	checked := 0
+	for _, r := range results {
+		if r.Status != statusError {
+			checked++
+			issuers[r.Issuer] = true
+			issuer = r.Issuer
+		}
+	}
+	if checked < 3 || len(issuers) != 1 {
+		return ""
+	}
+	for i := range results {
+		if results[i].Status != statusError {
+			results[i].raise(statusSuspect, fmt.Sprintf("same issuer as all %d other sites checked", checked-1))
+		}
+	}
+	return issuer
+}
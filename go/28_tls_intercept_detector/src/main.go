@@ -0,0 +1,468 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of a TLS Interception Detector.
+PURPOSE: Show skill in TLS and PKI analysis, man-in-the-middle detection, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "tls_intercept_detector"
+	toolVersion = "1.0.0"
+)
+
+// stringList collects a repeatable flag.
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+// Global variables for CLI flags
+var (
+	sitesFile   string
+	caBundle    string
+	proxyFlag   string
+	resolveList stringList
+	savePins    string
+	outputFile  string
+	format      string
+	timeoutSec  int
+	concurrency int
+	verboseMode bool
+)
+
+func init() {
+	flag.StringVar(&sitesFile, "sites", "", "Sites to check: lines of \"<host>[:<port>] [<pin>|<pin>...]\" (default: a built-in list of well-known sites, unpinned).")
+	flag.StringVar(&sitesFile, "s", "", "Sites to check (shorthand).")
+
+	flag.StringVar(&caBundle, "ca-bundle", "", "Verify chains against this PEM bundle instead of the system trust store (e.g. a clean Mozilla bundle, which a locally installed inspection CA is not part of).")
+	flag.StringVar(&proxyFlag, "proxy", proxyFromEnv(), "HTTP proxy to connect through with CONNECT, as applications here would; empty to connect directly. Defaults to HTTPS_PROXY.")
+	flag.Var(&resolveList, "resolve", "Connect to <host>:<port> at another address, as <host>:<port>:<addr>; repeatable.")
+	flag.StringVar(&savePins, "save-pins", "", "Write a sites file pinning every cleanly verified site to its CA keys (run on a network you trust).")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.IntVar(&timeoutSec, "timeout", 5, "Connection and handshake timeout in seconds.")
+	flag.IntVar(&timeoutSec, "t", 5, "Connection and handshake timeout in seconds (shorthand).")
+
+	flag.IntVar(&concurrency, "concurrency", 8, "Number of sites checked in parallel.")
+	flag.IntVar(&concurrency, "c", 8, "Number of sites checked in parallel (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerOtelFlag()
+	registerFindingsFlags()
+	registerVersionFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Connects to well-known sites and compares their certificates with pinned expectations to detect TLS interception.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s --save-pins pins.txt            (on a trusted network)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -s pins.txt -f json -o tls_interception.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// proxyFromEnv returns the HTTPS proxy applications on this host would use.
+func proxyFromEnv() string {
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseProxy accepts "http://[user:pass@]host[:port]" or a bare host:port.
+func parseProxy(s string) (*url.URL, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid --proxy %q", s)
+	}
+	if u.Scheme != "http" {
+		return nil, fmt.Errorf("--proxy %q: only http:// proxies (CONNECT) are supported", s)
+	}
+	return u, nil
+}
+
+// parseResolve turns --resolve entries into a host:port -> address map.
+func parseResolve(entries []string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, e := range entries {
+		i := strings.Index(e, ":")
+		j := -1
+		if i >= 0 {
+			j = strings.Index(e[i+1:], ":")
+		}
+		if i <= 0 || j <= 0 {
+			return nil, fmt.Errorf("invalid --resolve %q (use <host>:<port>:<addr>)", e)
+		}
+		host, port, addr := strings.ToLower(e[:i]), e[i+1:i+1+j], strings.Trim(e[i+2+j:], "[]")
+		if net.ParseIP(addr) == nil {
+			return nil, fmt.Errorf("invalid --resolve %q: %q is not an IP address", e, addr)
+		}
+		m[net.JoinHostPort(host, port)] = net.JoinHostPort(addr, port)
+	}
+	return m, nil
+}
+
+// checkSites inspects every site with a bounded worker pool, keeping order.
+// Sites not reached before an interrupt are reported as ERROR.
+func checkSites(ctx context.Context, sites []Site, d *dialer) (results []SiteResult, interrupted bool) {
+	results = make([]SiteResult, len(sites))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range queue {
+				start := time.Now()
+				r := d.inspect(ctx, sites[n])
+				if ctx.Err() != nil {
+					continue // Cut short by the interrupt
+				}
+				var err error
+				if r.Error != "" {
+					err = fmt.Errorf("%s", r.Error)
+				}
+				telemetry.span("handshake", start, map[string]string{"site": r.target(), "status": r.Status, "issuer": r.Issuer}, err)
+				if verboseMode {
+					fmt.Fprintf(os.Stderr, "[INFO] %s: %s (issuer %q)\n", r.target(), r.Status, r.Issuer)
+				}
+				results[n] = r
+			}
+		}()
+	}
+feed:
+	for n := range sites {
+		select {
+		case queue <- n:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	for i := range results {
+		if results[i].Status == "" {
+			results[i] = SiteResult{Site: sites[i], Status: statusError, Error: "interrupted before checking"}
+		}
+	}
+	return results, ctx.Err() != nil
+}
+
+// assess sums the site statuses up into one conclusion.
+func assess(results []SiteResult, commonIssuer string) string {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	inspectors := map[string]bool{}
+	var issuers []string
+	for _, r := range results {
+		if (r.Status == statusMismatch || r.Status == statusInspector) && !inspectors[r.Issuer] {
+			inspectors[r.Issuer] = true
+			issuers = append(issuers, r.Issuer)
+		}
+	}
+	switch {
+	case counts[statusUntrusted] > 0:
+		return fmt.Sprintf("Hostile interception likely: %d site(s) presented certificates this host does not trust.", counts[statusUntrusted])
+	case len(issuers) > 0:
+		return fmt.Sprintf("TLS inspection by a locally trusted CA likely: %s.", strings.Join(issuers, "; "))
+	case commonIssuer != "":
+		return fmt.Sprintf("TLS inspection likely: every site was issued by %s.", commonIssuer)
+	case counts[statusError] == len(results):
+		return "Inconclusive: no site could be checked."
+	}
+	return fmt.Sprintf("No interception detected (%d site(s) checked, %d matched a pin).", len(results)-counts[statusError], counts[statusOK])
+}
+
+// writeReport writes each site's status, issuer and signals.
+func writeReport(results []SiteResult, assessment string, output io.Writer) {
+	fmt.Fprintf(output, "--- TLS Interception Report ---\n\n")
+	path := "direct"
+	if proxyFlag != "" {
+		path = "via proxy " + results[0].Proxy
+	}
+	store := "system"
+	if caBundle != "" {
+		store = caBundle
+	}
+	fmt.Fprintf(output, "Path: %s; trust store: %s\n\n", path, store)
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		fmt.Fprintf(output, "[%s] %s\n", colorStatus(r.Status), r.target())
+		if r.Error != "" {
+			fmt.Fprintf(output, "    %s\n", r.Error)
+			continue
+		}
+		fmt.Fprintf(output, "    issuer: %s (chain of %d)\n", r.Issuer, r.ChainLength)
+		if r.MatchedPin != "" {
+			fmt.Fprintf(output, "    pin matched: %s\n", r.MatchedPin)
+		}
+		for _, s := range r.Signals {
+			fmt.Fprintf(output, "    - %s\n", s)
+		}
+	}
+	var parts []string
+	for _, s := range []string{statusOK, statusUnpinned, statusSuspect, statusInspector, statusMismatch, statusUntrusted, statusError} {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	fmt.Fprintf(output, "\nAssessment: %s\n", assessment)
+	fmt.Fprintf(output, "Summary: %d site(s): %s\n", len(results), strings.Join(parts, ", "))
+}
+
+// normalizedFindings converts sites with signs of interception to the
+// cross-tool findings model.
+func normalizedFindings(results []SiteResult) []normFinding {
+	var out []normFinding
+	for _, r := range results {
+		f := normFinding{Tool: toolName, Target: r.target(), Category: "tls-interception", Detail: strings.Join(r.Signals, "; ")}
+		switch r.Status {
+		case statusUntrusted:
+			f.Title, f.Severity = "Untrusted certificate presented for a well-known site", "high"
+			f = f.withVector("AV:A/PR:N/C:H/I:H/A:N")
+		case statusMismatch, statusInspector:
+			f.Title, f.Severity = "TLS traffic is re-signed by a locally trusted CA", "medium"
+			f = f.withVector("AV:A/PR:H/C:H/I:H/A:N")
+		case statusSuspect:
+			f.Title, f.Severity = "Certificate pattern suggests TLS interception", "low"
+		default:
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+type jsonReport struct {
+	Tool       string       `json:"tool"`
+	Version    string       `json:"version"`
+	GitCommit  string       `json:"git_commit,omitempty"`
+	BuildDate  string       `json:"build_date,omitempty"`
+	Host       string       `json:"host"`
+	Proxy      string       `json:"proxy,omitempty"`
+	TrustStore string       `json:"trust_store"`
+	Assessment string       `json:"assessment"`
+	Sites      []SiteResult `json:"sites"`
+	SelfStats  *selfStats   `json:"self_stats,omitempty"`
+}
+
+// main is the entry point of the TLS Interception Detector tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --format %q (use text or json)\n", format)
+		os.Exit(1)
+	}
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkFindingsMin(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --findings-min: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	d := &dialer{timeout: time.Duration(max(timeoutSec, 1)) * time.Second}
+	if d.proxy, err = parseProxy(proxyFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if d.resolve, err = parseResolve(resolveList); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if d.proxy != nil && len(d.resolve) > 0 {
+		fmt.Fprintln(os.Stderr, "[ERROR] --resolve cannot be combined with --proxy; the proxy resolves the sites.")
+		os.Exit(1)
+	}
+	if caBundle != "" {
+		var n int
+		if d.roots, n, err = loadCABundle(caBundle); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Loaded %d trust anchor(s) from %s\n", n, caBundle)
+		}
+	} else if _, err := x509.SystemCertPool(); err != nil {
+		warnf("System trust store unavailable (%v); every chain will look untrusted. Use --ca-bundle.", err)
+	}
+	startOtel()
+	concurrency = max(concurrency, 1)
+
+	var sites []Site
+	if sitesFile != "" {
+		if sites, err = loadSites(sitesFile); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		for _, host := range defaultSites {
+			site, _ := parseSite(host)
+			sites = append(sites, site)
+		}
+	}
+
+	// SIGINT/SIGTERM stop the checks; sites finished so far are reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results, interrupted := checkSites(ctx, sites, d)
+	commonIssuer := ""
+	if sitesFile == "" {
+		commonIssuer = flagCommonIssuer(results)
+	}
+	assessment := assess(results, commonIssuer)
+	for _, r := range results {
+		telemetry.count("sites", map[string]string{"status": r.Status}, 1)
+	}
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+	if format == "json" {
+		build := currentBuild()
+		host, _ := os.Hostname()
+		report := jsonReport{Tool: toolName, Version: build.Version, GitCommit: build.GitCommit, BuildDate: build.BuildDate, Host: host, TrustStore: "system", Assessment: assessment, Sites: results}
+		if d.proxy != nil {
+			report.Proxy = d.proxy.Redacted()
+		}
+		if caBundle != "" {
+			report.TrustStore = caBundle
+		}
+		if selfStatsOn {
+			stats := collectSelfStats(len(results), "sites")
+			report.SelfStats = &stats
+		}
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		writeReport(results, assessment, output)
+		if selfStatsOn {
+			writeSelfStats(output, collectSelfStats(len(results), "sites"))
+		}
+	}
+
+	findings := normalizedFindings(results)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if savePins != "" && !interrupted {
+		n, err := writePins(savePins, results)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		if n < len(results) {
+			warnf("Pinned %d of %d site(s); sites with errors or signs of interception were left out.", n, len(results))
+		} else if verboseMode {
+			fmt.Fprintf(os.Stderr, "[INFO] Pinned %d site(s) to %s\n", n, savePins)
+		}
+	}
+	inputs := []string{sitesFile, caBundle}
+	outputs := []string{outputFile, signatureTarget(outputFile), findingsPath, savePins}
+	if interrupted {
+		stop()
+		if format == "text" {
+			fmt.Fprintln(output, "Partial report: interrupted; unchecked sites are listed as ERROR.")
+		}
+		warnf("Interrupted by signal; partial report written.")
+		closeSink(output)
+		telemetry.finish(130)
+		writeManifest(130, inputs, outputs)
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] TLS interception check complete.")
+	}
+	code := 0
+	if strings.HasPrefix(assessment, "Inconclusive") {
+		code = 1
+	}
+	for _, r := range results {
+		if statusRank[r.Status] >= statusRank[statusSuspect] {
+			code = 1
+		}
+	}
+	telemetry.finish(code)
+	writeManifest(code, inputs, outputs)
+	os.Exit(code)
+}
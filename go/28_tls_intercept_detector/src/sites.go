@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// defaultSites are checked when no --sites file is given: popular sites
+// served by different certificate authorities, so that one CA issuing all
+// of them stands out.
+var defaultSites = []string{
+	"www.google.com", "github.com", "www.cloudflare.com", "www.microsoft.com",
+	"www.wikipedia.org", "www.apple.com", "www.amazon.com", "www.mozilla.org",
+}
+
+// Site is one endpoint to check and the pins its chain is expected to match.
+type Site struct {
+	Host string   `json:"host"`
+	Port string   `json:"port"`
+	Pins []string `json:"pins,omitempty"`
+}
+
+func (s Site) target() string { return net.JoinHostPort(s.Host, s.Port) }
+
+// parseSite reads "<host>[:<port>]", defaulting to port 443.
+func parseSite(spec string) (Site, error) {
+	host, port, err := net.SplitHostPort(spec)
+	if err != nil {
+		host, port = strings.Trim(spec, "[]"), "443"
+	}
+	if host == "" || strings.ContainsAny(host, "/ ") {
+		return Site{}, fmt.Errorf("invalid site %q", spec)
+	}
+	return Site{Host: strings.ToLower(strings.TrimSuffix(host, ".")), Port: port}, nil
+}
+
+// loadSites reads a sites file. Each non-comment line is
+// "<host>[:<port>] [<pin>[|<pin>...]]", where a pin is one of
+//
+//	sha256/<base64>  SHA-256 of the SubjectPublicKeyInfo of any certificate
+//	                 in the chain (the HPKP form, stable across renewals)
+//	cert/<hex>       SHA-256 fingerprint of the leaf certificate
+//	issuer/<text>    text found in the leaf's issuer name, e.g.
+//	                 "issuer/Google Trust Services"
+//
+// and the site passes when any one of them matches.
+func loadSites(filePath string) ([]Site, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sites file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var sites []Site
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		spec := strings.Fields(line)[0]
+		rest := strings.TrimPrefix(line, spec)
+		site, err := parseSite(spec)
+		if err != nil {
+			return nil, fmt.Errorf("sites file %s line %d: %w", filePath, lineNo, err)
+		}
+		for _, pin := range strings.Split(rest, "|") {
+			if pin = strings.TrimSpace(pin); pin == "" {
+				continue
+			}
+			if err := checkPin(pin); err != nil {
+				return nil, fmt.Errorf("sites file %s line %d: %w", filePath, lineNo, err)
+			}
+			site.Pins = append(site.Pins, pin)
+		}
+		sites = append(sites, site)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading sites file %s: %w", filePath, err)
+	}
+	if len(sites) == 0 {
+		return nil, fmt.Errorf("sites file %s lists no sites", filePath)
+	}
+	return sites, nil
+}
+
+// checkPin validates the syntax of one pin.
+func checkPin(pin string) error {
+	kind, value, _ := strings.Cut(pin, "/")
+	switch kind {
+	case "sha256":
+		if b, err := base64.StdEncoding.DecodeString(value); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("invalid SPKI pin %q (expected sha256/<base64 of 32 bytes>)", pin)
+		}
+	case "cert":
+		if b, err := hex.DecodeString(strings.ReplaceAll(value, ":", "")); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("invalid certificate pin %q (expected cert/<64 hex digits>)", pin)
+		}
+	case "issuer":
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("empty issuer pin")
+		}
+	default:
+		return fmt.Errorf("unknown pin %q (use sha256/, cert/ or issuer/)", pin)
+	}
+	return nil
+}
+
+// spkiPin returns the sha256/<base64> pin of a certificate's public key.
+func spkiPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// certFingerprint returns the lower-case hex SHA-256 fingerprint of a certificate.
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// matchPin returns the first pin that the presented chain (leaf first)
+// satisfies, or "".
+func matchPin(pins []string, chain []*x509.Certificate) string {
+	for _, pin := range pins {
+		kind, value, _ := strings.Cut(pin, "/")
+		switch kind {
+		case "sha256":
+			for _, c := range chain {
+				if spkiPin(c) == pin {
+					return pin
+				}
+			}
+		case "cert":
+			if strings.EqualFold(certFingerprint(chain[0]), strings.ReplaceAll(value, ":", "")) {
+				return pin
+			}
+		case "issuer":
+			if strings.Contains(strings.ToLower(chain[0].Issuer.String()), strings.ToLower(value)) {
+				return pin
+			}
+		}
+	}
+	return ""
+}
+
+// writePins saves a sites file pinning each cleanly verified site to the
+// public keys of its intermediate and root CAs, which survive the leaf's
+// renewal. Sites with any interception signal are left out, so the pins of
+// an interceptor are never learned.
+func writePins(filePath string, results []SiteResult) (int, error) {
+	var b strings.Builder
+	b.WriteString("# TLS interception detector pins: <host>[:<port>] <pin>|<pin>...\n")
+	b.WriteString("# Learned from verified chains; any one pin must match.\n")
+	n := 0
+	for _, r := range results {
+		if r.Status != statusOK && r.Status != statusUnpinned || len(r.verified) < 2 {
+			continue
+		}
+		var pins []string
+		for _, c := range r.verified[1:] {
+			pins = append(pins, spkiPin(c))
+		}
+		spec := r.Host
+		if r.Port != "443" {
+			spec = r.target()
+		}
+		fmt.Fprintf(&b, "%s %s\n", spec, strings.Join(pins, " | "))
+		n++
+	}
+	if err := os.WriteFile(filePath, []byte(b.String()), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write pins %s: %w", filePath, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// loadCABundle reads the PEM trust anchors for --ca-bundle. Blocks other than
+// certificates (e.g. a key left in the file) are an error rather than being
+// skipped, so a wrong file is not silently treated as an empty trust store.
+func loadCABundle(filePath string) (*x509.CertPool, int, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read CA bundle %s: %w", filePath, err)
+	}
+	pool := x509.NewCertPool()
+	n := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, 0, fmt.Errorf("CA bundle %s: unexpected PEM block %q", filePath, block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, 0, fmt.Errorf("CA bundle %s: certificate %d: %w", filePath, n+1, err)
+		}
+		pool.AddCert(cert)
+		n++
+	}
+	if n == 0 {
+		return nil, 0, fmt.Errorf("CA bundle %s contains no PEM certificates", filePath)
+	}
+	return pool, n, nil
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPlaceholder(t *testing.T) {
+	// This is a placeholder test to ensure the Go test runner can find and execute tests.
+	// Actual tests would check pin parsing and matching, chain verification outcomes and the interception assessment against generated certificates.
+	t.Log("Placeholder Go test executed successfully.")
+}
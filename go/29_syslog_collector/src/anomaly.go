@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Kinds of anomaly.
+const (
+	kindNewProgram = "new_program" // A program name never seen before
+	kindErrorSpike = "error_spike" // A host's error messages jump above its usual rate
+	kindRule       = "rule"        // A message matched a --rules pattern
+)
+
+// Anomaly is one flagged condition. Repeats of the same condition (the same
+// rule on the same host, say) are counted on the first occurrence instead of
+// being listed again.
+type Anomaly struct {
+	Kind      string    `json:"kind"`
+	Severity  string    `json:"severity"` // info, low, medium, high or critical
+	Host      string    `json:"host"`
+	Program   string    `json:"program,omitempty"`
+	Rule      string    `json:"rule,omitempty"`
+	Detail    string    `json:"detail"`
+	Sample    string    `json:"sample,omitempty"` // Message that raised it
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Count     int       `json:"count"`
+}
+
+// Rule flags messages whose text matches a pattern.
+type Rule struct {
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Pattern  string `json:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// loadRules reads "<name> <severity> <regexp>" lines; the pattern is the
+// rest of the line and is matched against the message text.
+func loadRules(filePath string) ([]Rule, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rules file %s: %w", filePath, err)
+	}
+	defer file.Close()
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("rules file %s line %d: expected \"<name> <severity> <regexp>\"", filePath, lineNo)
+		}
+		if normSeverityRank(fields[1]) < 0 {
+			return nil, fmt.Errorf("rules file %s line %d: invalid severity %q (use info, low, medium, high or critical)", filePath, lineNo, fields[1])
+		}
+		pattern := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(line, fields[0])), fields[1]))
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rules file %s line %d: %w", filePath, lineNo, err)
+		}
+		rules = append(rules, Rule{Name: fields[0], Severity: strings.ToLower(fields[1]), Pattern: pattern, re: re})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rules file %s: %w", filePath, err)
+	}
+	return rules, nil
+}
+
+// hostRate tracks one host's error messages per window against an
+// exponentially weighted average of its previous windows.
+type hostRate struct {
+	start   time.Time // Current window
+	errors  int
+	average float64
+	windows int      // Windows folded into average
+	spike   *Anomaly // Raised in the current window
+}
+
+// rateAlpha weighs the latest window in the average.
+const rateAlpha = 0.3
+
+// detector flags anomalies in the message stream. It is used from the
+// collector's single writer goroutine and needs no locking.
+type detector struct {
+	rules       []Rule
+	window      time.Duration
+	spikeFactor float64
+	spikeMin    int
+	warmup      int // Windows of history before a host's spikes are flagged
+	learn       time.Duration
+
+	programs   map[string]time.Time // Known program names, when first seen
+	learnUntil time.Time            // Program names seen before this are learned silently
+	rates      map[string]*hostRate
+	anomalies  []*Anomaly
+	index      map[string]*Anomaly
+	changed    bool // Programs learned since the state was loaded
+}
+
+// detectorState is the --state file: the program names learned so far.
+type detectorState struct {
+	Programs map[string]time.Time `json:"programs"`
+}
+
+func newDetector() *detector {
+	return &detector{programs: map[string]time.Time{}, rates: map[string]*hostRate{}, index: map[string]*Anomaly{}}
+}
+
+// loadState reads the known program names. A missing file starts empty.
+func (d *detector) loadState(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state %s: %w", filePath, err)
+	}
+	var st detectorState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("failed to parse state %s: %w", filePath, err)
+	}
+	for name, t := range st.Programs {
+		d.programs[name] = t
+	}
+	return nil
+}
+
+// saveState writes the known program names, replacing the file atomically.
+func (d *detector) saveState(filePath string) error {
+	data, err := json.MarshalIndent(detectorState{Programs: d.programs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filePath + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0640); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", filePath, err)
+	}
+	if err := os.Rename(tmp, filePath); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// observe checks one message at time now (its receipt, or its own
+// timestamp when replaying) and returns the anomalies it raised for the
+// first time.
+func (d *detector) observe(m *Message, now time.Time) []*Anomaly {
+	var raised []*Anomaly
+	add := func(kind, key, severity, detail string, rule string) {
+		a, seen := d.index[kind+"\x00"+key]
+		if seen {
+			a.Count++
+			a.LastSeen = now
+		} else {
+			a = &Anomaly{Kind: kind, Severity: severity, Host: m.Host, Program: m.Program, Rule: rule, Detail: detail,
+				Sample: clip(m.Text, 200), FirstSeen: now, LastSeen: now, Count: 1}
+			d.index[kind+"\x00"+key] = a
+			d.anomalies = append(d.anomalies, a)
+			raised = append(raised, a)
+		}
+		m.Anomalies = append(m.Anomalies, kind)
+	}
+
+	if d.learnUntil.IsZero() {
+		d.learnUntil = now
+		if len(d.programs) == 0 {
+			d.learnUntil = now.Add(d.learn)
+		}
+	}
+	if m.Program != "" {
+		if _, known := d.programs[m.Program]; !known {
+			d.programs[m.Program], d.changed = now, true
+			if !now.Before(d.learnUntil) {
+				add(kindNewProgram, m.Program, "low", fmt.Sprintf("program %q was never seen before", m.Program), "")
+			}
+		}
+	}
+
+	if r := d.rate(m.Host, now); m.sev <= sevErr {
+		r.errors++
+		limit := d.spikeLimit(r)
+		switch {
+		case r.spike != nil:
+			r.spike.Detail, r.spike.LastSeen = d.spikeDetail(r), now
+		case r.windows >= d.warmup && float64(r.errors) >= limit:
+			add(kindErrorSpike, m.Host+"\x00"+r.start.String(), "medium", d.spikeDetail(r), "")
+			r.spike = d.index[kindErrorSpike+"\x00"+m.Host+"\x00"+r.start.String()]
+		}
+	}
+
+	for _, rule := range d.rules {
+		if rule.re.MatchString(m.Text) {
+			add(kindRule, rule.Name+"\x00"+m.Host, rule.Severity, "message matches /"+rule.Pattern+"/", rule.Name)
+		}
+	}
+	return raised
+}
+
+// rate returns host's counter for the window containing now, folding the
+// windows that ended into its average (windows without errors count as 0).
+func (d *detector) rate(host string, now time.Time) *hostRate {
+	start := now.Truncate(d.window)
+	r := d.rates[host]
+	if r == nil {
+		r = &hostRate{start: start}
+		d.rates[host] = r
+	}
+	for n := 0; r.start.Before(start); n++ {
+		if n == 100 { // Long silence: the average has decayed to nothing
+			r.start, r.average = start, 0
+			break
+		}
+		if r.windows == 0 {
+			r.average = float64(r.errors)
+		} else {
+			r.average = rateAlpha*float64(r.errors) + (1-rateAlpha)*r.average
+		}
+		r.windows++
+		r.start, r.errors, r.spike = r.start.Add(d.window), 0, nil
+	}
+	return r
+}
+
+// spikeLimit is the error count within a window that counts as a spike.
+func (d *detector) spikeLimit(r *hostRate) float64 {
+	return max(float64(d.spikeMin), d.spikeFactor*r.average)
+}
+
+func (d *detector) spikeDetail(r *hostRate) string {
+	return fmt.Sprintf("%d error messages in the %s window from %s (usual: %.1f)", r.errors, d.window, r.start.In(reportTZ).Format("15:04:05"), r.average)
+}
+
+// sortedAnomalies returns the anomalies most severe first, then by time.
+func (d *detector) sortedAnomalies() []Anomaly {
+	out := make([]Anomaly, 0, len(d.anomalies))
+	for _, a := range d.anomalies {
+		out = append(out, *a)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if ri, rj := normSeverityRank(out[i].Severity), normSeverityRank(out[j].Severity); ri != rj {
+			return ri > rj
+		}
+		return out[i].FirstSeen.Before(out[j].FirstSeen)
+	})
+	return out
+}
+
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tcpIdleTimeout closes TCP connections that send nothing for this long.
+const tcpIdleTimeout = 10 * time.Minute
+
+// maxTCPConns bounds the TCP connections served at once.
+const maxTCPConns = 512
+
+// collector receives syslog frames on its listeners and hands the parsed
+// messages to a single consumer.
+type collector struct {
+	allow   []netip.Prefix // Senders accepted; empty accepts all
+	maxSize int
+	out     chan Message
+
+	dropped atomic.Int64 // UDP messages lost because the consumer fell behind
+	denied  atomic.Int64 // Messages from senders outside --allow
+	conns   atomic.Int64 // TCP connections accepted
+	wg      sync.WaitGroup
+}
+
+// parseAllow reads a sender address or CIDR.
+func parseAllow(spec string) (netip.Prefix, error) {
+	if p, err := netip.ParsePrefix(spec); err == nil {
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(spec)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid --allow %q (use an address or CIDR)", spec)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func (c *collector) allowed(addr net.Addr) bool {
+	if len(c.allow) == 0 {
+		return true
+	}
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := ap.Addr().Unmap()
+	for _, p := range c.allow {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// listenUDP binds addr and receives one message per datagram until ctx ends.
+func (c *collector) listenUDP(ctx context.Context, addr string) error {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %w", addr, err)
+	}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Listening on udp %s\n", pc.LocalAddr())
+	}
+	c.wg.Add(2)
+	go func() {
+		defer c.wg.Done()
+		<-ctx.Done()
+		pc.Close()
+	}()
+	go func() {
+		defer c.wg.Done()
+		buf := make([]byte, 65536)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				if ctx.Err() == nil {
+					warnf("udp %s: %v", addr, err)
+				}
+				return
+			}
+			if !c.allowed(from) {
+				c.denied.Add(1)
+				continue
+			}
+			m := parseMessage(buf[:min(n, c.maxSize)], "udp", from.String(), time.Now())
+			select {
+			case c.out <- m:
+			default:
+				c.dropped.Add(1) // Like the kernel, drop rather than block the socket
+			}
+		}
+	}()
+	return nil
+}
+
+// listenTCP binds addr and serves each connection until ctx ends.
+func (c *collector) listenTCP(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on tcp %s: %w", addr, err)
+	}
+	if verboseMode {
+		fmt.Fprintf(os.Stderr, "[INFO] Listening on tcp %s\n", ln.Addr())
+	}
+	var mu sync.Mutex
+	open := map[net.Conn]bool{}
+	c.wg.Add(2)
+	go func() {
+		defer c.wg.Done()
+		<-ctx.Done()
+		ln.Close()
+		mu.Lock()
+		for conn := range open {
+			conn.Close()
+		}
+		mu.Unlock()
+	}()
+	go func() {
+		defer c.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if ctx.Err() == nil {
+					warnf("tcp %s: %v", addr, err)
+				}
+				return
+			}
+			mu.Lock()
+			if ctx.Err() != nil || !c.allowed(conn.RemoteAddr()) || len(open) >= maxTCPConns {
+				mu.Unlock()
+				if ctx.Err() == nil && !c.allowed(conn.RemoteAddr()) {
+					c.denied.Add(1)
+				} else if ctx.Err() == nil {
+					warnf("tcp %s: refusing %s, %d connections open", addr, conn.RemoteAddr(), maxTCPConns)
+				}
+				conn.Close()
+				continue
+			}
+			open[conn] = true
+			mu.Unlock()
+			c.conns.Add(1)
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				c.serveConn(ctx, conn)
+				mu.Lock()
+				delete(open, conn)
+				mu.Unlock()
+				conn.Close()
+			}()
+		}
+	}()
+	return nil
+}
+
+// serveConn reads frames from one TCP sender, blocking it when the
+// consumer falls behind rather than losing messages.
+func (c *collector) serveConn(ctx context.Context, conn net.Conn) {
+	peer := conn.RemoteAddr().String()
+	debugf("tcp connection from %s", peer)
+	r := bufio.NewReaderSize(conn, 64*1024)
+	for {
+		conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		frame, err := readFrame(r, c.maxSize)
+		if err != nil {
+			switch {
+			case errors.Is(err, errFrameTooLarge):
+				warnf("tcp %s: %v; closing the connection", peer, err)
+			case err != io.EOF && ctx.Err() == nil:
+				debugf("tcp %s: %v", peer, err)
+			}
+			return
+		}
+		if len(strings.TrimSpace(string(frame))) == 0 {
+			continue
+		}
+		select {
+		case c.out <- parseMessage(frame, "tcp", peer, time.Now()):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// replay reads messages, one per line, from a file (or - for stdin) as if
+// they had been received, then closes the stream.
+func (c *collector) replay(ctx context.Context, filePath string) error {
+	in := os.Stdin
+	if filePath != "-" {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open replay file %s: %w", filePath, err)
+		}
+		in = file
+	}
+	peer := filepath.Base(filePath)
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer in.Close()
+		r := bufio.NewReaderSize(in, 64*1024)
+		for ctx.Err() == nil {
+			frame, err := readFrame(r, c.maxSize)
+			if err != nil {
+				if err != io.EOF {
+					warnf("replay %s: %v", filePath, err)
+				}
+				return
+			}
+			if len(strings.TrimSpace(string(frame))) == 0 {
+				continue
+			}
+			select {
+			case c.out <- parseMessage(frame, "replay", peer, time.Now()):
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return nil
+}
+
+// wait closes the message stream once every listener has stopped.
+func (c *collector) wait() {
+	c.wg.Wait()
+	close(c.out)
+}
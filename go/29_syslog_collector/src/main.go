@@ -0,0 +1,582 @@
+package main
+
+/*
+SECURITY PORTFOLIO ARTIFACT - DEMONSTRATION ONLY
+
+CONTEXT: This code is a frozen demonstration of a Syslog Collector and Anomaly Highlighter.
+PURPOSE: Show skill in log collection, network services, anomaly detection, and CLI utility development in Go.
+CONSTRAINTS: Uses standard library only, designed for CLI.
+STATUS: Complete demonstration - no updates planned.
+EVALUATION: Assess what this demonstrates, not production readiness.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Tool identity, recorded in run manifests.
+const (
+	toolName    = "syslog_collector"
+	toolVersion = "1.0.0"
+)
+
+// spikeWarmup is how many windows of a host's history are needed before
+// its error spikes are flagged.
+const spikeWarmup = 3
+
+// stringList collects a repeatable flag.
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+// Global variables for CLI flags
+var (
+	udpAddr        string
+	tcpAddr        string
+	allowSenders   stringList
+	archiveDir     string
+	rotateSizeMB   int
+	rotateInterval time.Duration
+	keepFiles      int
+	rulesFile      string
+	stateFile      string
+	learnPeriod    time.Duration
+	window         time.Duration
+	spikeFactor    float64
+	spikeMin       int
+	maxSize        int
+	replayFile     string
+	runDuration    time.Duration
+	failOn         string
+	outputFile     string
+	format         string
+	verboseMode    bool
+)
+
+func init() {
+	flag.StringVar(&udpAddr, "udp", ":5514", "Address to receive syslog over UDP on (\"\" disables).")
+	flag.StringVar(&tcpAddr, "tcp", ":5514", "Address to receive syslog over TCP on, octet-counted or newline-framed (\"\" disables).")
+	flag.Var(&allowSenders, "allow", "Only accept messages from this address or CIDR; repeatable (default: any sender).")
+
+	flag.StringVar(&archiveDir, "dir", "syslog", "Directory for the JSONL archive (syslog.jsonl and its rotations), anomalies.jsonl and the state file.")
+	flag.StringVar(&archiveDir, "d", "syslog", "Archive directory (shorthand).")
+	flag.IntVar(&rotateSizeMB, "rotate-size", 64, "Rotate the archive when it reaches this many MiB (0 disables).")
+	flag.DurationVar(&rotateInterval, "rotate-interval", 24*time.Hour, "Also rotate the archive at every multiple of this interval, e.g. 1h or 24h (0 disables).")
+	flag.IntVar(&keepFiles, "keep", 14, "Rotated archive files to keep; older ones are deleted (0 keeps all).")
+
+	flag.StringVar(&rulesFile, "rules", "", "Regex rules flagging messages: lines of \"<name> <severity> <regexp>\".")
+	flag.StringVar(&stateFile, "state", "", "File remembering known program names across runs (default: <dir>/syslog_state.json).")
+	flag.DurationVar(&learnPeriod, "learn", 10*time.Minute, "With no known programs yet, learn program names silently for this long before flagging new ones.")
+	flag.DurationVar(&window, "window", time.Minute, "Window error messages are counted in for spike detection.")
+	flag.Float64Var(&spikeFactor, "spike-factor", 5, "Flag a host whose errors in a window exceed this multiple of its usual rate.")
+	flag.IntVar(&spikeMin, "spike-min", 20, "Errors a window needs before it can count as a spike.")
+	flag.IntVar(&maxSize, "max-size", 65536, "Largest message accepted, in bytes; longer UDP messages are truncated and TCP senders disconnected.")
+
+	flag.StringVar(&replayFile, "replay", "", "Read messages, one per line, from this file (- for stdin) instead of listening.")
+	flag.StringVar(&replayFile, "r", "", "Replay messages from a file (shorthand).")
+	flag.DurationVar(&runDuration, "duration", 0, "Stop collecting after this long and write the report (0 runs until interrupted).")
+	flag.StringVar(&failOn, "fail-on", "", "Exit with status 1 when an anomaly is at least this severity.")
+
+	flag.StringVar(&format, "format", "text", "Report format: text or json.")
+	flag.StringVar(&format, "f", "text", "Report format (shorthand).")
+
+	flag.StringVar(&outputFile, "output", "", "Where to save the report written when collection stops: a file path, - for stdout (default), an http(s):// URL to POST it to, or s3://bucket/key.")
+	flag.StringVar(&outputFile, "o", "", "Where to save the report (shorthand).")
+
+	flag.BoolVar(&verboseMode, "verbose", false, "Enable verbose output.")
+	flag.BoolVar(&verboseMode, "v", false, "Enable verbose output (shorthand).")
+
+	registerOutputFlags()
+	registerManifestFlag()
+	registerSignFlag()
+	registerOtelFlag()
+	registerFindingsFlags()
+	registerVersionFlag()
+	registerTZFlag()
+	registerSelfStatsFlag()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Receives syslog over UDP and TCP, archives it as rotated JSONL and flags anomalies.\n")
+		fmt.Fprintf(os.Stderr, "  Example: %s --udp :5514 --tcp :5514 -d /var/log/collector --rules syslog_rules.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  Example: %s -r messages.log --rules syslog_rules.txt -f json -o syslog_report.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// hostStats counts what one sending host logged.
+type hostStats struct {
+	Host     string         `json:"host"`
+	Messages int            `json:"messages"`
+	Errors   int            `json:"errors"` // Severity err or worse
+	Programs map[string]int `json:"programs"`
+	LastSeen time.Time      `json:"last_seen"`
+}
+
+// summary is the collection's totals.
+type summary struct {
+	Started     time.Time      `json:"started"`
+	Ended       time.Time      `json:"ended"`
+	FirstDated  time.Time      `json:"first_dated,omitempty"` // Earliest and latest message time, as sent
+	LastDated   time.Time      `json:"last_dated,omitempty"`
+	Received    int            `json:"received"`
+	ByTransport map[string]int `json:"by_transport"`
+	Unparsed    int            `json:"unparsed"`    // Kept as raw messages
+	Dropped     int64          `json:"dropped"`     // UDP messages lost while the archive fell behind
+	Denied      int64          `json:"denied"`      // Messages and connections from senders outside --allow
+	Connections int64          `json:"connections"` // TCP connections accepted
+	Rotations   int            `json:"rotations"`
+	Hosts       []hostStats    `json:"hosts"`
+}
+
+// collect archives and checks messages until the stream ends, returning the
+// totals. Anomalies are printed as they are raised and appended to
+// anomalies.jsonl.
+func collect(msgs <-chan Message, det *detector, archive *rotator, alerts io.Writer) (summary, error) {
+	sum := summary{Started: time.Now(), ByTransport: map[string]int{}}
+	hosts := map[string]*hostStats{}
+	flushTick := time.NewTicker(time.Second)
+	defer flushTick.Stop()
+	var eventTime time.Time
+	for {
+		var m Message
+		select {
+		case <-flushTick.C:
+			if err := archive.flush(); err != nil {
+				return sum, err
+			}
+			continue
+		case msg, ok := <-msgs:
+			if !ok {
+				sum.Ended, sum.Rotations = time.Now(), archive.rotated
+				for _, h := range hosts {
+					sum.Hosts = append(sum.Hosts, *h)
+				}
+				sort.Slice(sum.Hosts, func(i, j int) bool { return sum.Hosts[i].Messages > sum.Hosts[j].Messages })
+				return sum, nil
+			}
+			m = msg
+		}
+		// Replayed messages are checked on their own clock; those without a
+		// timestamp take the previous one's.
+		switch {
+		case m.Transport != "replay" || m.Timestamp == nil && eventTime.IsZero():
+			eventTime = m.Received
+		case m.Timestamp != nil:
+			eventTime = *m.Timestamp
+		}
+		raised := det.observe(&m, eventTime)
+		if m.Timestamp != nil {
+			if sum.FirstDated.IsZero() || m.Timestamp.Before(sum.FirstDated) {
+				sum.FirstDated = *m.Timestamp
+			}
+			if m.Timestamp.After(sum.LastDated) {
+				sum.LastDated = *m.Timestamp
+			}
+		}
+
+		sum.Received++
+		sum.ByTransport[m.Transport]++
+		if m.Format == "raw" {
+			sum.Unparsed++
+		}
+		h := hosts[m.Host]
+		if h == nil {
+			h = &hostStats{Host: m.Host, Programs: map[string]int{}}
+			hosts[m.Host] = h
+		}
+		h.Messages++
+		if m.sev <= sevErr {
+			h.Errors++
+		}
+		if m.Program != "" {
+			h.Programs[m.Program]++
+		}
+		h.LastSeen = m.Received
+		telemetry.count("messages", map[string]string{"transport": m.Transport, "severity": m.Severity}, 1)
+
+		line, err := json.Marshal(m)
+		if err != nil {
+			return sum, err
+		}
+		if err := archive.write(append(line, '\n'), m.Received); err != nil {
+			return sum, err
+		}
+		for _, a := range raised {
+			telemetry.count("anomalies", map[string]string{"kind": a.Kind, "severity": a.Severity}, 1)
+			warnf("Anomaly (%s) %s on %s: %s", a.Severity, a.Kind, a.Host, a.Detail)
+			line, _ := json.Marshal(a)
+			if _, err := alerts.Write(append(line, '\n')); err != nil {
+				return sum, fmt.Errorf("failed to write anomaly log: %w", err)
+			}
+		}
+	}
+}
+
+// writeReport writes the collection totals, hosts and anomalies.
+func writeReport(sum summary, anomalies []Anomaly, listening []string, output io.Writer) {
+	fmt.Fprintf(output, "--- Syslog Collection Report ---\n\n")
+	if replayFile != "" {
+		fmt.Fprintf(output, "Replayed: %s\n", replayFile)
+	} else {
+		fmt.Fprintf(output, "Listening: %s\n", strings.Join(listening, ", "))
+	}
+	fmt.Fprintf(output, "Archive: %s (%d rotation(s) this run)\n", filepath.Join(archiveDir, "syslog.jsonl"), sum.Rotations)
+	fmt.Fprintf(output, "Collected: %s to %s (%s)\n", stamp(sum.Started), stamp(sum.Ended), sum.Ended.Sub(sum.Started).Round(time.Second))
+	if !sum.FirstDated.IsZero() {
+		fmt.Fprintf(output, "Messages dated: %s to %s\n", stamp(sum.FirstDated), stamp(sum.LastDated))
+	}
+	fmt.Fprintln(output)
+
+	if len(sum.Hosts) == 0 {
+		fmt.Fprintln(output, "No messages received.")
+	} else {
+		fmt.Fprintf(output, "%-24s %9s %7s  %s\n", "HOST", "MESSAGES", "ERRORS", "TOP PROGRAMS")
+	}
+	for _, h := range sum.Hosts {
+		fmt.Fprintf(output, "%-24s %9d %7d  %s\n", h.Host, h.Messages, h.Errors, topPrograms(h.Programs, 3))
+	}
+
+	counts := map[string]int{}
+	if len(anomalies) > 0 {
+		fmt.Fprintf(output, "\nAnomalies:\n")
+	}
+	for _, a := range anomalies {
+		counts[a.Severity]++
+		what := a.Kind
+		if a.Rule != "" {
+			what += " " + a.Rule
+		}
+		times := ""
+		if a.Count > 1 {
+			times = fmt.Sprintf(" (x%d, last %s)", a.Count, stamp(a.LastSeen))
+		}
+		fmt.Fprintf(output, "  [%s] %s %s on %s: %s%s\n", colorStatus(strings.ToUpper(a.Severity)), stamp(a.FirstSeen), what, a.Host, a.Detail, times)
+		if a.Sample != "" {
+			prefix := ""
+			if a.Program != "" {
+				prefix = a.Program + ": "
+			}
+			fmt.Fprintf(output, "        %s%s\n", prefix, a.Sample)
+		}
+	}
+
+	var parts []string
+	for i := len(normSeverityNames) - 1; i >= 0; i-- {
+		if n := counts[normSeverityNames[i]]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, strings.ToUpper(normSeverityNames[i])))
+		}
+	}
+	var transports []string
+	for _, t := range []string{"udp", "tcp", "replay"} {
+		if n := sum.ByTransport[t]; n > 0 {
+			transports = append(transports, fmt.Sprintf("%s %d", t, n))
+		}
+	}
+	fmt.Fprintf(output, "\nSummary: %d message(s) from %d host(s)", sum.Received, len(sum.Hosts))
+	if len(transports) > 0 {
+		fmt.Fprintf(output, " (%s)", strings.Join(transports, ", "))
+	}
+	fmt.Fprintf(output, ", %d unparsed, %d dropped, %d denied; %d anomal(ies)", sum.Unparsed, sum.Dropped, sum.Denied, len(anomalies))
+	if len(parts) > 0 {
+		fmt.Fprintf(output, ": %s", strings.Join(parts, ", "))
+	}
+	fmt.Fprintln(output, ".")
+}
+
+// topPrograms lists the n programs with the most messages.
+func topPrograms(programs map[string]int, n int) string {
+	names := make([]string, 0, len(programs))
+	for name := range programs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if programs[names[i]] != programs[names[j]] {
+			return programs[names[i]] > programs[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	var parts []string
+	for i, name := range names {
+		if i == n {
+			parts = append(parts, fmt.Sprintf("+%d more", len(names)-n))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s (%d)", name, programs[name]))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// normalizedFindings maps each anomaly to the shared findings model.
+func normalizedFindings(anomalies []Anomaly) []normFinding {
+	titles := map[string]string{
+		kindNewProgram: "Previously unseen program is logging",
+		kindErrorSpike: "Spike in error messages",
+		kindRule:       "Log message matched a detection rule",
+	}
+	var out []normFinding
+	for _, a := range anomalies {
+		f := normFinding{Tool: toolName, Target: a.Host, Category: "syslog-anomaly", Title: titles[a.Kind], Severity: a.Severity, Detail: a.Detail}
+		if a.Rule != "" {
+			f.Title += ": " + a.Rule
+		}
+		if a.Sample != "" {
+			f.Detail += " (" + strings.TrimSpace(a.Program+": "+a.Sample) + ")"
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+type jsonReport struct {
+	Tool      string     `json:"tool"`
+	Version   string     `json:"version"`
+	GitCommit string     `json:"git_commit,omitempty"`
+	BuildDate string     `json:"build_date,omitempty"`
+	Collector string     `json:"collector"` // Host the collector ran on
+	Listen    []string   `json:"listen,omitempty"`
+	Replay    string     `json:"replay,omitempty"`
+	Archive   string     `json:"archive"`
+	Rules     []Rule     `json:"rules,omitempty"`
+	Summary   summary    `json:"summary"`
+	Anomalies []Anomaly  `json:"anomalies"`
+	SelfStats *selfStats `json:"self_stats,omitempty"`
+}
+
+// main is the entry point of the Syslog Collector tool.
+func main() {
+	runSubcommand()
+	flag.Parse()
+	if showVersion {
+		printVersion()
+		return
+	}
+	applyVerbosity()
+	startSelfStats()
+	if err := applyTZ(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+
+	if replayFile == "" && udpAddr == "" && tcpAddr == "" {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "\n[ERROR] Nothing to collect: give --udp, --tcp or -r.")
+		os.Exit(1)
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --format %q (use text or json)\n", format)
+		os.Exit(1)
+	}
+	if normSeverityRank(failOn) < 0 && failOn != "" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --fail-on %q (use info, low, medium, high or critical)\n", failOn)
+		os.Exit(1)
+	}
+	failOn = strings.ToLower(failOn)
+	if window <= 0 || spikeFactor <= 0 || maxSize < 480 || rotateSizeMB < 0 || rotateInterval < 0 {
+		fmt.Fprintln(os.Stderr, "[ERROR] --window and --spike-factor must be positive, --max-size at least 480 and the rotation limits not negative.")
+		os.Exit(1)
+	}
+	var allow []netip.Prefix
+	for _, spec := range allowSenders {
+		p, err := parseAllow(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		allow = append(allow, p)
+	}
+	if signKeyPath != "" && format != "json" {
+		fmt.Fprintln(os.Stderr, "[ERROR] --sign-report signs JSON reports; add -f json.")
+		os.Exit(1)
+	}
+	signKey, err := loadSigningKey(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkFindingsMin(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid --findings-min: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOtelEndpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+
+	det := newDetector()
+	det.window, det.spikeFactor, det.spikeMin, det.warmup, det.learn = window, spikeFactor, spikeMin, spikeWarmup, learnPeriod
+	if rulesFile != "" {
+		if det.rules, err = loadRules(rulesFile); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if stateFile == "" {
+		stateFile = filepath.Join(archiveDir, "syslog_state.json")
+	}
+	if err := det.loadState(stateFile); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	archive := &rotator{dir: archiveDir, base: "syslog", maxBytes: int64(rotateSizeMB) << 20, interval: rotateInterval, keep: keepFiles}
+	if err := archive.open(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	alertsPath := filepath.Join(archiveDir, "anomalies.jsonl")
+	alerts, err := os.OpenFile(alertsPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open anomaly log: %v\n", err)
+		os.Exit(1)
+	}
+	startOtel()
+
+	// SIGINT/SIGTERM stop collecting; the report covers what was received.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if runDuration > 0 {
+		time.AfterFunc(runDuration, cancel)
+	}
+
+	col := &collector{allow: allow, maxSize: maxSize, out: make(chan Message, 4096)}
+	var listening []string
+	if replayFile != "" {
+		err = col.replay(runCtx, replayFile)
+	} else {
+		for _, l := range [][2]string{{"udp", udpAddr}, {"tcp", tcpAddr}} {
+			if l[1] == "" || err != nil {
+				continue
+			}
+			if l[0] == "udp" {
+				err = col.listenUDP(runCtx, l[1])
+			} else {
+				err = col.listenTCP(runCtx, l[1])
+			}
+			listening = append(listening, l[0]+" "+l[1])
+		}
+	}
+	if err != nil {
+		cancel()
+		col.wg.Wait()
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	go col.wait()
+
+	sum, err := collect(col.out, det, archive, alerts)
+	cancel()
+	if err == nil {
+		err = archive.close()
+	}
+	if cerr := alerts.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("failed to write anomaly log: %w", cerr)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if det.changed {
+		if err := det.saveState(stateFile); err != nil {
+			warnf("%v", err)
+		}
+	}
+	sum.Dropped, sum.Denied, sum.Connections = col.dropped.Load(), col.denied.Load(), col.conns.Load()
+	if sum.Dropped > 0 {
+		warnf("%d UDP message(s) dropped while the archive fell behind.", sum.Dropped)
+	}
+	anomalies := det.sortedAnomalies()
+
+	output, err := openSink(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open output %s: %v\n", outputFile, err)
+		os.Exit(1)
+	}
+	enableColor(sinkFile(output))
+	output = signSink(output, signKey, outputFile)
+	if format == "json" {
+		build := currentBuild()
+		host, _ := os.Hostname()
+		report := jsonReport{Tool: toolName, Version: build.Version, GitCommit: build.GitCommit, BuildDate: build.BuildDate, Collector: host,
+			Listen: listening, Replay: replayFile, Archive: archive.path(), Rules: det.rules, Summary: sum, Anomalies: anomalies}
+		report.Summary.Started, report.Summary.Ended = sum.Started.In(reportTZ), sum.Ended.In(reportTZ)
+		if report.Summary.Hosts == nil {
+			report.Summary.Hosts = []hostStats{}
+		}
+		if report.Anomalies == nil {
+			report.Anomalies = []Anomaly{}
+		}
+		if selfStatsOn {
+			stats := collectSelfStats(sum.Received, "messages")
+			report.SelfStats = &stats
+		}
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		writeReport(sum, anomalies, listening, output)
+		if selfStatsOn {
+			writeSelfStats(output, collectSelfStats(sum.Received, "messages"))
+		}
+	}
+
+	findings := normalizedFindings(anomalies)
+	for _, f := range findings {
+		telemetry.count("findings", map[string]string{"severity": f.Severity, "category": f.Category}, 1)
+	}
+	if err := exportFindings(findings); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	inputs := []string{replayFile, rulesFile}
+	outputs := []string{outputFile, signatureTarget(outputFile), findingsPath, archive.path(), alertsPath, stateFile}
+	if ctx.Err() != nil {
+		stop()
+		warnf("Interrupted by signal; report covers the messages received so far.")
+		closeSink(output)
+		telemetry.finish(130)
+		writeManifest(130, inputs, outputs)
+		os.Exit(130)
+	}
+	if !closeSink(output) {
+		telemetry.finish(1)
+		os.Exit(1)
+	}
+	if verboseMode {
+		fmt.Fprintln(os.Stderr, "[INFO] Syslog collection complete.")
+	}
+	if failOn != "" {
+		for _, a := range anomalies {
+			if normSeverityRank(a.Severity) >= normSeverityRank(failOn) {
+				telemetry.finish(1)
+				writeManifest(1, inputs, outputs)
+				os.Exit(1)
+			}
+		}
+	}
+	telemetry.finish(0)
+	writeManifest(0, inputs, outputs)
+	os.Exit(0)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rotator appends JSON lines to <dir>/<base>.jsonl and rotates it to
+// <base>-<YYYYMMDD-HHMMSS>.jsonl when it reaches maxBytes or when the time
+// crosses a multiple of interval (midnight UTC for 24h), deleting all but
+// the newest keep rotated files.
+type rotator struct {
+	dir, base string
+	maxBytes  int64
+	interval  time.Duration
+	keep      int
+
+	file    *os.File
+	w       *bufio.Writer
+	size    int64
+	opened  time.Time
+	rotated int
+}
+
+func (r *rotator) path() string { return filepath.Join(r.dir, r.base+".jsonl") }
+
+// open opens the active file for appending, continuing an existing one.
+func (r *rotator) open() error {
+	if err := os.MkdirAll(r.dir, 0750); err != nil {
+		return fmt.Errorf("failed to create archive directory %s: %w", r.dir, err)
+	}
+	file, err := os.OpenFile(r.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open archive %s: %w", r.path(), err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open archive %s: %w", r.path(), err)
+	}
+	r.file, r.w, r.size, r.opened = file, bufio.NewWriterSize(file, 64*1024), info.Size(), time.Now()
+	if info.Size() > 0 {
+		r.opened = info.ModTime()
+	}
+	return nil
+}
+
+// write appends one line, rotating first when the file is due.
+func (r *rotator) write(line []byte, now time.Time) error {
+	due := r.maxBytes > 0 && r.size > 0 && r.size+int64(len(line)) > r.maxBytes
+	if r.interval > 0 && !now.Truncate(r.interval).Equal(r.opened.Truncate(r.interval)) {
+		due = r.size > 0
+	}
+	if due {
+		if err := r.rotate(now); err != nil {
+			return err
+		}
+	}
+	n, err := r.w.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+// rotate closes the active file, renames it with its rotation time and
+// starts a new one.
+func (r *rotator) rotate(now time.Time) error {
+	if err := r.close(); err != nil {
+		return err
+	}
+	stamp := now.UTC().Format("20060102-150405")
+	target := filepath.Join(r.dir, r.base+"-"+stamp+".jsonl")
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(target); os.IsNotExist(err) {
+			break
+		}
+		target = filepath.Join(r.dir, fmt.Sprintf("%s-%s.%d.jsonl", r.base, stamp, i))
+	}
+	if err := os.Rename(r.path(), target); err != nil {
+		return fmt.Errorf("failed to rotate archive: %w", err)
+	}
+	r.rotated++
+	debugf("Rotated %s to %s", r.path(), target)
+	r.prune()
+	return r.open()
+}
+
+// prune deletes the oldest rotated files beyond keep (0 keeps them all).
+func (r *rotator) prune() {
+	old := r.archives()
+	if r.keep <= 0 || len(old) <= r.keep {
+		return
+	}
+	for _, p := range old[:len(old)-r.keep] {
+		if err := os.Remove(p); err != nil {
+			warnf("Failed to delete old archive: %v", err)
+		} else {
+			debugf("Deleted old archive %s", p)
+		}
+	}
+}
+
+// archives lists the rotated files, oldest first.
+func (r *rotator) archives() []string {
+	matches, _ := filepath.Glob(filepath.Join(r.dir, r.base+"-*.jsonl"))
+	var out []string
+	modified := map[string]time.Time{}
+	for _, p := range matches {
+		info, err := os.Lstat(p)
+		if err != nil || !strings.HasPrefix(filepath.Base(p), r.base+"-2") {
+			continue // Gone, or another base that shares the prefix
+		}
+		out = append(out, p)
+		modified[p] = info.ModTime()
+	}
+	// By last write, as files rotated within one second carry a .N suffix.
+	sort.Slice(out, func(i, j int) bool { return modified[out[i]].Before(modified[out[j]]) })
+	return out
+}
+
+func (r *rotator) flush() error {
+	if r.w == nil {
+		return nil
+	}
+	return r.w.Flush()
+}
+
+func (r *rotator) close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.w.Flush()
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.file, r.w = nil, nil
+	if err != nil {
+		return fmt.Errorf("failed to write archive %s: %w", r.path(), err)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Syslog messages as senders emit them:
+//
+//	<34>1 2026-10-15T22:14:15.003Z web01 sshd 4121 - - Accepted publickey ...   (RFC 5424)
+//	<34>Oct 15 22:14:15 web01 sshd[4121]: Accepted publickey ...                 (RFC 3164, BSD)
+//
+// Anything else is kept as a raw message from the sending address.
+
+var facilityNames = []string{"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "audit", "alert", "clock",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7"}
+
+var severityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// sevErr is the least severe level counted as an error (emerg to err).
+const sevErr = 3
+
+// Message is one received syslog message, as archived (one JSON object per line).
+type Message struct {
+	Received  time.Time  `json:"received"`
+	Transport string     `json:"transport"` // udp or tcp
+	Peer      string     `json:"peer"`      // Sending address
+	Format    string     `json:"format"`    // rfc5424, rfc3164 or raw
+	Facility  string     `json:"facility"`
+	Severity  string     `json:"severity"`
+	Timestamp *time.Time `json:"timestamp,omitempty"` // As sent; BSD timestamps get the current year
+	Host      string     `json:"host"`                // Sender's hostname, or the peer address
+	Program   string     `json:"program,omitempty"`
+	PID       string     `json:"pid,omitempty"`
+	MsgID     string     `json:"msgid,omitempty"`
+	SD        string     `json:"structured_data,omitempty"`
+	Text      string     `json:"message"`
+	Anomalies []string   `json:"anomalies,omitempty"` // Kinds of anomaly this message raised
+
+	sev int
+}
+
+// parseMessage parses one syslog frame received from peer (host:port).
+func parseMessage(raw []byte, transport, peer string, received time.Time) Message {
+	line := strings.TrimRight(strings.ToValidUTF8(string(raw), "�"), "\r\n\x00")
+	peerHost := peer
+	if i := strings.LastIndex(peer, ":"); i > 0 {
+		peerHost = strings.Trim(peer[:i], "[]")
+	}
+	m := Message{Received: received, Transport: transport, Peer: peer, Format: "raw", Host: peerHost}
+	pri, rest, ok := parsePRI(line)
+	if !ok {
+		pri, rest = 13, line // RFC 3164: no PRI means user.notice
+	}
+	m.sev = pri % 8
+	m.Facility, m.Severity = facilityNames[pri/8], severityNames[pri%8]
+	if ok && strings.HasPrefix(rest, "1 ") && parse5424(&m, rest[2:]) {
+		m.Format = "rfc5424"
+	} else if parse3164(&m, rest, received) {
+		m.Format = "rfc3164"
+	} else {
+		m.Text = rest
+	}
+	if m.Host == "" || m.Host == "-" {
+		m.Host = peerHost
+	}
+	return m
+}
+
+// parsePRI reads the "<N>" priority, 0 to 191.
+func parsePRI(line string) (int, string, bool) {
+	end := strings.IndexByte(line, '>')
+	if !strings.HasPrefix(line, "<") || end < 2 || end > 4 {
+		return 0, line, false
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, line, false
+	}
+	return pri, line[end+1:], true
+}
+
+// parse5424 reads TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG.
+func parse5424(m *Message, s string) bool {
+	fields := strings.SplitN(s, " ", 6)
+	if len(fields) < 6 {
+		return false
+	}
+	if fields[0] != "-" {
+		t, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return false
+		}
+		m.Timestamp = &t
+	}
+	m.Host, m.Program, m.PID, m.MsgID = nilValue(fields[1]), nilValue(fields[2]), nilValue(fields[3]), nilValue(fields[4])
+	sd, msg, ok := splitSD(fields[5])
+	if !ok {
+		return false
+	}
+	m.SD, m.Text = nilValue(sd), strings.TrimPrefix(msg, "\ufeff") // UTF-8 BOM
+	return true
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// splitSD separates the STRUCTURED-DATA ("-" or "[id k="v"]...") from the
+// message, honoring quoted values and the \] escape.
+func splitSD(s string) (string, string, bool) {
+	if strings.HasPrefix(s, "-") {
+		return "-", strings.TrimPrefix(s[1:], " "), true
+	}
+	i, inQuote := 0, false
+	for i < len(s) {
+		if s[i] != '[' {
+			return "", "", false
+		}
+		for i++; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && inQuote {
+				i++
+			} else if c == '"' {
+				inQuote = !inQuote
+			} else if c == ']' && !inQuote {
+				break
+			}
+		}
+		if i >= len(s) {
+			return "", "", false
+		}
+		i++
+		if i == len(s) || s[i] == ' ' {
+			return s[:i], strings.TrimPrefix(s[i:], " "), true
+		}
+	}
+	return "", "", false
+}
+
+// parse3164 reads "Mmm dd hh:mm:ss HOST TAG[pid]: MSG". Local senders often
+// leave out the hostname, and some the timestamp.
+func parse3164(m *Message, s string, received time.Time) bool {
+	if len(s) >= 16 && s[15] == ' ' {
+		if t, err := time.ParseInLocation(time.Stamp, s[:15], received.Location()); err == nil {
+			t = t.AddDate(received.Year(), 0, 0)
+			if t.Sub(received) > 24*time.Hour {
+				t = t.AddDate(-1, 0, 0) // December messages read in January
+			}
+			m.Timestamp = &t
+			s = s[16:]
+			if host, rest, ok := strings.Cut(s, " "); ok && !isTag(host) {
+				m.Host, s = host, rest
+			}
+		}
+	}
+	tag, msg, ok := strings.Cut(s, ": ")
+	if !ok || !isTag(tag+":") {
+		if m.Timestamp == nil {
+			return false
+		}
+		m.Text = s
+		return true
+	}
+	if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+		m.Program, m.PID = tag[:open], tag[open+1:len(tag)-1]
+	} else {
+		m.Program = tag
+	}
+	m.Text = msg
+	return true
+}
+
+// isTag reports whether word looks like "prog:" or "prog[pid]:", the BSD
+// TAG that follows the hostname.
+func isTag(word string) bool {
+	if !strings.HasSuffix(word, ":") || utf8.RuneCountInString(word) > 48 || len(word) < 2 {
+		return false
+	}
+	for _, r := range strings.TrimSuffix(word, ":") {
+		if r == ' ' || r == '<' || r == '>' {
+			return false
+		}
+	}
+	return true
+}
+
+// errFrameTooLarge ends a TCP connection whose sender announces a frame
+// larger than --max-size.
+var errFrameTooLarge = errors.New("frame larger than --max-size")
+
+// readFrame reads one message from a TCP stream, framed by octet counting
+// ("<len> <msg>", RFC 6587) or terminated by a newline or NUL. Each frame is
+// framed on its own, as senders may mix both.
+func readFrame(r *bufio.Reader, maxSize int) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		for i := 1; i <= 10; i++ {
+			head, err := r.Peek(i + 1)
+			if err != nil || head[i] < '0' || head[i] > '9' {
+				if err != nil || head[i] != ' ' {
+					break // Not a length: a newline-terminated frame
+				}
+				n, _ := strconv.Atoi(string(head[:i]))
+				if n > maxSize {
+					return nil, fmt.Errorf("%w (%d bytes)", errFrameTooLarge, n)
+				}
+				r.Discard(i + 1)
+				frame := make([]byte, n)
+				_, err := io.ReadFull(r, frame)
+				return frame, err
+			}
+		}
+	}
+	var frame []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			if len(frame) > 0 && err == io.EOF {
+				return frame, nil
+			}
+			return nil, err
+		}
+		if c == '\n' || c == 0 {
+			return frame, nil
+		}
+		if len(frame) >= maxSize {
+			return nil, fmt.Errorf("%w (no newline within %d bytes)", errFrameTooLarge, maxSize)
+		}
+		frame = append(frame, c)
+	}
+}
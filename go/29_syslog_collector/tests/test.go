@@ -0,0 +1,11 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPlaceholder(t *testing.T) {
+	// This is a placeholder test to ensure the Go test runner can find and execute tests.
+	// Actual tests would check RFC 3164/5424 parsing, TCP framing, archive rotation and the anomaly detectors against replayed messages.
+	t.Log("Placeholder Go test executed successfully.")
+}
@@ -0,0 +1,61 @@
+# scripts/check_shared_go.py
+#
+# Some Go sources are shared by several tools. Each tool is built on its own
+# from its src/ directory, standard library only and without a go.mod, so a
+# shared file cannot live in a common package and is copied into every tool
+# that uses it instead. This check fails when the copies of a shared file
+# are no longer byte-identical: change one copy, then copy it to the others.
+import hashlib
+import sys
+from pathlib import Path
+
+RED = '\033[0;31m'
+GREEN = '\033[0;32m'
+NC = '\033[0m' # No Color
+
+# File names under go/<tool>/src/ whose copies must all be identical.
+SHARED_FILES = [
+    'completion.go',
+    'errclass.go',
+    'findings_model.go',
+    'gitignore.go',
+    'manifest.go',
+    'notify.go',
+    'otel.go',
+    'selfstats.go',
+    'signing.go',
+    'sink.go',
+    'source.go',
+    'timestamps.go',
+    'version.go',
+    'walk.go',
+]
+
+def check_shared(go_root):
+    failures = 0
+    for name in SHARED_FILES:
+        copies = sorted(go_root.glob(f'*/src/{name}'))
+        digests = {}
+        for path in copies:
+            digests.setdefault(hashlib.sha256(path.read_bytes()).hexdigest(), []).append(path)
+        if len(digests) <= 1:
+            print(f"  {GREEN}{name}{NC}: {len(copies)} identical copies")
+            continue
+        failures += 1
+        print(f"  {RED}{name}: copies differ{NC}")
+        for digest, paths in sorted(digests.items(), key=lambda d: -len(d[1])):
+            for path in paths:
+                print(f"    {digest[:12]}  {path.relative_to(go_root.parent)}")
+    return failures
+
+def main():
+    print("Checking that shared Go sources are identical in every tool...")
+    go_root = Path(__file__).resolve().parent.parent / 'go'
+    failures = check_shared(go_root)
+    if failures:
+        print(f"{RED}{failures} shared file(s) out of sync.{NC}")
+        sys.exit(1)
+    print("All shared Go sources are in sync.")
+
+if __name__ == "__main__":
+    main()